@@ -1,16 +1,19 @@
 package apps
 
 import (
+	"context"
 	"fmt"
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/controllers"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/node/service"
+	"github.com/crawlab-team/crawlab-core/tracing"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/spf13/viper"
 	"net/http"
 	_ "net/http/pprof"
+	"time"
 )
 
 type Server struct {
@@ -26,7 +29,8 @@ type Server struct {
 	dck *Docker
 
 	// internals
-	quit chan int
+	quit            chan int
+	tracingShutdown func(ctx context.Context) error
 }
 
 func (app *Server) SetGrpcAddress(address interfaces.Address) {
@@ -45,6 +49,9 @@ func (app *Server) Init() {
 	// log node info
 	app.logNodeInfo()
 
+	// tracing
+	app.initTracing()
+
 	if utils.IsMaster() {
 
 		// initialize controllers
@@ -78,6 +85,7 @@ func (app *Server) Wait() {
 
 func (app *Server) Stop() {
 	app.api.Stop()
+	app.stopTracing()
 	app.quit <- 1
 }
 
@@ -88,6 +96,27 @@ func (app *Server) logNodeInfo() {
 	}
 }
 
+func (app *Server) initTracing() {
+	shutdown, err := tracing.Init()
+	if err != nil {
+		log.Errorf("failed to initialize tracing: %v", err)
+		return
+	}
+	app.tracingShutdown = shutdown
+}
+
+// stopTracing flushes spans not yet exported
+func (app *Server) stopTracing() {
+	if app.tracingShutdown == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.tracingShutdown(ctx); err != nil {
+		log.Errorf("failed to shut down tracing: %v", err)
+	}
+}
+
 func (app *Server) initPprof() {
 	if viper.GetBool("pprof") {
 		go func() {
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Log is an audit entry of a write API action. Entries are only inserted,
+// never updated or deleted.
+type Log struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id"`
+	UserId   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Username string             `json:"username" bson:"username"`
+	Method   string             `json:"method" bson:"method"`
+	Path     string             `json:"path" bson:"path"`                             // request path
+	Route    string             `json:"route" bson:"route"`                           // route pattern, e.g. /spiders/:id
+	Model    string             `json:"model" bson:"model"`                           // collection name of the model, empty if unknown
+	ModelId  primitive.ObjectID `json:"model_id,omitempty" bson:"model_id,omitempty"` // id of the model in the route
+	Status   int                `json:"status" bson:"status"`                         // http status code of the response
+	Payload  interface{}        `json:"payload,omitempty" bson:"payload,omitempty"`   // redacted JSON request body
+	Before   bson.M             `json:"before,omitempty" bson:"before,omitempty"`     // redacted model before the action
+	After    bson.M             `json:"after,omitempty" bson:"after,omitempty"`       // redacted model after the action
+	Ts       time.Time          `json:"ts" bson:"ts"`
+}
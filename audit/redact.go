@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+// DefaultRedactFields are substrings of field names whose values are redacted,
+// overridden by "audit.redactFields"
+var DefaultRedactFields = []string{
+	"password",
+	"secret",
+	"token",
+	"auth_key",
+	"api_key",
+	"access_key",
+	"private_key",
+	"webhook",
+}
+
+func getRedactFields() (fields []string) {
+	if viper.IsSet("audit.redactFields") {
+		return viper.GetStringSlice("audit.redactFields")
+	}
+	return DefaultRedactFields
+}
+
+// Redact returns a copy of the value with values of secret fields replaced by
+// constants.AuditRedactedValue. Field names are matched case-insensitively by
+// any of the given substrings. Nested documents and arrays are redacted too.
+func Redact(value interface{}, fields []string) (res interface{}) {
+	switch v := value.(type) {
+	case bson.M:
+		return bson.M(redactMap(v, fields))
+	case map[string]interface{}:
+		return redactMap(v, fields)
+	case primitive.D:
+		d := make(primitive.D, 0, len(v))
+		for _, e := range v {
+			if isRedactField(e.Key, fields) {
+				d = append(d, primitive.E{Key: e.Key, Value: constants.AuditRedactedValue})
+			} else {
+				d = append(d, primitive.E{Key: e.Key, Value: Redact(e.Value, fields)})
+			}
+		}
+		return d
+	case primitive.A:
+		return primitive.A(redactSlice(v, fields))
+	case []interface{}:
+		return redactSlice(v, fields)
+	default:
+		return value
+	}
+}
+
+func redactMap(m map[string]interface{}, fields []string) (res map[string]interface{}) {
+	res = make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if isRedactField(k, fields) {
+			res[k] = constants.AuditRedactedValue
+		} else {
+			res[k] = Redact(v, fields)
+		}
+	}
+	return res
+}
+
+func redactSlice(s []interface{}, fields []string) (res []interface{}) {
+	res = make([]interface{}, 0, len(s))
+	for _, v := range s {
+		res = append(res, Redact(v, fields))
+	}
+	return res
+}
+
+func isRedactField(key string, fields []string) (ok bool) {
+	key = strings.ToLower(key)
+	for _, f := range fields {
+		if f != "" && strings.Contains(key, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	doc := bson.M{
+		"name":     "spider",
+		"Password": "p",
+		"mail": primitive.D{
+			{Key: "server", Value: "smtp"},
+			{Key: "password", Value: "p"},
+		},
+		"envs": primitive.A{
+			bson.M{"name": "a", "api_key": "k"},
+		},
+	}
+	res := Redact(doc, DefaultRedactFields).(bson.M)
+	require.Equal(t, "spider", res["name"])
+	require.Equal(t, constants.AuditRedactedValue, res["Password"])
+	require.Equal(t, primitive.D{
+		{Key: "server", Value: "smtp"},
+		{Key: "password", Value: constants.AuditRedactedValue},
+	}, res["mail"])
+	require.Equal(t, primitive.A{
+		bson.M{"name": "a", "api_key": constants.AuditRedactedValue},
+	}, res["envs"])
+
+	// original is untouched
+	require.Equal(t, "p", doc["Password"])
+}
+
+func TestRedact_JSON(t *testing.T) {
+	payload := map[string]interface{}{
+		"user": map[string]interface{}{"username": "u", "password": "p"},
+		"ids":  []interface{}{"a", "b"},
+	}
+	res := Redact(payload, DefaultRedactFields).(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"username": "u", "password": constants.AuditRedactedValue}, res["user"])
+	require.Equal(t, []interface{}{"a", "b"}, res["ids"])
+}
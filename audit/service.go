@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+// routeModelColNames maps base paths of routes to collection names of models
+var routeModelColNames = map[string]string{
+	"/nodes":            interfaces.ModelColNameNode,
+	"/projects":         interfaces.ModelColNameProject,
+	"/users":            interfaces.ModelColNameUser,
+	"/spiders":          interfaces.ModelColNameSpider,
+	"/tasks":            interfaces.ModelColNameTask,
+	"/tags":             interfaces.ModelColNameTag,
+	"/settings":         interfaces.ModelColNameSetting,
+	"/data/collections": interfaces.ModelColNameDataCollection,
+	"/schedules":        interfaces.ModelColNameSchedule,
+	"/tokens":           interfaces.ModelColNameToken,
+	"/gits":             interfaces.ModelColNameGit,
+	"/roles":            interfaces.ModelColNameRole,
+	"/permissions":      interfaces.ModelColNamePermission,
+	"/data-sources":     interfaces.ModelColNameDataSource,
+	"/environments":     interfaces.ModelColNameEnvironment,
+}
+
+// IsEnabled returns whether write API actions are audited ("audit.enabled")
+func IsEnabled() (ok bool) {
+	return viper.GetBool("audit.enabled")
+}
+
+// GetModelColName returns the collection name of the model of the route, or
+// empty if the route does not belong to a model
+func GetModelColName(route string) (colName string) {
+	for basePath, colName := range routeModelColNames {
+		if route == basePath || strings.HasPrefix(route, basePath+"/") {
+			return colName
+		}
+	}
+	return ""
+}
+
+// GetModelDoc returns the redacted document of the model, or nil if not found
+func GetModelDoc(colName string, id primitive.ObjectID) (doc bson.M) {
+	if err := mongo.GetMongoCol(colName).FindId(id).One(&doc); err != nil {
+		return nil
+	}
+	return Redact(doc, getRedactFields()).(bson.M)
+}
+
+// RedactPayload returns the request payload with secret fields redacted
+func RedactPayload(payload interface{}) (res interface{}) {
+	return Redact(payload, getRedactFields())
+}
+
+// AddLog appends the entry to the audit logs
+func AddLog(l *Log) (err error) {
+	if l.Id.IsZero() {
+		l.Id = primitive.NewObjectID()
+	}
+	if l.Ts.IsZero() {
+		l.Ts = time.Now()
+	}
+	if _, err := mongo.GetMongoCol(constants.AuditLogColName).Insert(l); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// GetLogList returns audit entries matching the query, latest first
+func GetLogList(query bson.M, skip, limit int) (logs []Log, err error) {
+	if err := mongo.GetMongoCol(constants.AuditLogColName).Find(query, &mongo.FindOptions{
+		Sort:  bson.D{{"ts", -1}},
+		Skip:  skip,
+		Limit: limit,
+	}).All(&logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// CountLogs returns the number of audit entries matching the query
+func CountLogs(query bson.M) (total int, err error) {
+	return mongo.GetMongoCol(constants.AuditLogColName).Count(query)
+}
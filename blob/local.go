@@ -0,0 +1,79 @@
+package blob
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalBlobStore stores blobs as files under a directory on master
+type LocalBlobStore struct {
+	root string
+}
+
+func (s *LocalBlobStore) Put(key string, r io.Reader, size int64, contentType string) (err error) {
+	p, err := fs.ResolvePath(s.root, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), fs.WorkspaceDirPerm); err != nil {
+		return trace.TraceError(err)
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(p)
+		return trace.TraceError(err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(p)
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) Open(key string) (rc io.ReadCloser, err error) {
+	p, err := fs.ResolvePath(s.root, key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.TraceError(errors.ErrorBlobNotExists)
+		}
+		return nil, trace.TraceError(err)
+	}
+	return f, nil
+}
+
+func (s *LocalBlobStore) DeleteAll(prefix string) (err error) {
+	p, err := fs.ResolvePath(s.root, prefix)
+	if err != nil {
+		return err
+	}
+	if p == filepath.Clean(s.root) {
+		// never remove the whole store
+		return trace.TraceError(errors.ErrorFsPathOutsideRoot)
+	}
+	if err := os.RemoveAll(p); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// GetDownloadUrl returns an empty string as local blobs are served by master
+func (s *LocalBlobStore) GetDownloadUrl(key, filename string) (url string, err error) {
+	return "", nil
+}
+
+func NewLocalBlobStore(root string) (store interfaces.BlobStore) {
+	return &LocalBlobStore{root: root}
+}
@@ -0,0 +1,317 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3BlobStore stores blobs in a bucket of an S3-compatible object storage.
+// Requests are signed with AWS Signature Version 4, so that no SDK is needed.
+// The signer is checked against the examples of the AWS docs (see s3_test.go).
+type S3BlobStore struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyId     string
+	secretAccessKey string
+	pathStyle       bool
+	urlExpiry       time.Duration
+	client          *http.Client
+}
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+func (s *S3BlobStore) Put(key string, r io.Reader, size int64, contentType string) (err error) {
+	req, err := s.newRequest(http.MethodPut, key, nil, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	return nil
+}
+
+func (s *S3BlobStore) Open(key string) (rc io.ReadCloser, err error) {
+	req, err := s.newRequest(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3BlobStore) DeleteAll(prefix string) (err error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	token := ""
+	for {
+		keys, nextToken, err := s.list(prefix, token)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if key != prefix && !strings.HasPrefix(key, prefix+"/") {
+				continue
+			}
+			req, err := s.newRequest(http.MethodDelete, key, nil, nil)
+			if err != nil {
+				return err
+			}
+			res, err := s.do(req)
+			if err != nil {
+				return err
+			}
+			_ = res.Body.Close()
+		}
+		if nextToken == "" {
+			return nil
+		}
+		token = nextToken
+	}
+}
+
+func (s *S3BlobStore) GetDownloadUrl(key, filename string) (u string, err error) {
+	query := url.Values{}
+	if filename != "" {
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	return s.presign(http.MethodGet, key, query, time.Now().UTC()), nil
+}
+
+// list returns a page of keys starting with the prefix and the continuation
+// token of the next page, empty if there are no more keys
+func (s *S3BlobStore) list(prefix, token string) (keys []string, nextToken string, err error) {
+	query := url.Values{
+		"list-type": []string{"2"},
+		"prefix":    []string{prefix},
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	req, err := s.newRequest(http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	var result struct {
+		Contents []struct {
+			Key string `xml:"Key"`
+		} `xml:"Contents"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, "", trace.TraceError(err)
+	}
+	for _, c := range result.Contents {
+		keys = append(keys, c.Key)
+	}
+	if result.IsTruncated {
+		nextToken = result.NextContinuationToken
+	}
+	return keys, nextToken, nil
+}
+
+// getHostAndPath returns the host and the path of the key in the bucket,
+// under the path of the endpoint if any, e.g. of a reverse proxy
+func (s *S3BlobStore) getHostAndPath(key string) (host, path string) {
+	base := strings.TrimSuffix(s.endpoint.Path, "/")
+	if s.pathStyle {
+		return s.endpoint.Host, base + "/" + s.bucket + "/" + key
+	}
+	return s.bucket + "." + s.endpoint.Host, base + "/" + key
+}
+
+func (s *S3BlobStore) newRequest(method, key string, query url.Values, body io.Reader) (req *http.Request, err error) {
+	host, path := s.getHostAndPath(key)
+	u := &url.URL{
+		Scheme:   s.endpoint.Scheme,
+		Host:     host,
+		Path:     path,
+		RawPath:  s3EncodePath(path),
+		RawQuery: s3EncodeQuery(query),
+	}
+	req, err = http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	s.sign(req, path, query, time.Now().UTC())
+	return req, nil
+}
+
+func (s *S3BlobStore) do(req *http.Request) (res *http.Response, err error) {
+	res, err = s.client.Do(req)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if res.StatusCode == http.StatusNotFound && req.Method == http.MethodGet {
+		_ = res.Body.Close()
+		return nil, trace.TraceError(errors.ErrorBlobNotExists)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// error code and message of the response, e.g. SignatureDoesNotMatch
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		_ = xml.NewDecoder(io.LimitReader(res.Body, 64*1024)).Decode(&e)
+		_ = res.Body.Close()
+		if e.Code != "" {
+			return nil, trace.TraceError(fmt.Errorf("%w: %s %s: %s: %s", errors.ErrorBlobRequestFailed, req.Method, res.Status, e.Code, e.Message))
+		}
+		return nil, trace.TraceError(fmt.Errorf("%w: %s %s", errors.ErrorBlobRequestFailed, req.Method, res.Status))
+	}
+	return res, nil
+}
+
+// sign sets the authorization header of the request
+func (s *S3BlobStore) sign(req *http.Request, path string, query url.Values, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signature := s.getSignature(req.Method, path, s3EncodeQuery(query), canonicalHeaders, signedHeaders, s3UnsignedPayload, now)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyId, s.getScope(now), signedHeaders, signature))
+}
+
+// presign returns a url of the key authorized by query parameters
+func (s *S3BlobStore) presign(method, key string, query url.Values, now time.Time) (u string) {
+	host, path := s.getHostAndPath(key)
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.accessKeyId+"/"+s.getScope(now))
+	q.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(s.urlExpiry.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	signature := s.getSignature(method, path, s3EncodeQuery(q), "host:"+host+"\n", "host", s3UnsignedPayload, now)
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", s.endpoint.Scheme, host, s3EncodePath(path), s3EncodeQuery(q), signature)
+}
+
+func (s *S3BlobStore) getScope(now time.Time) (scope string) {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// getSignature returns the signature of the canonical request, whose headers
+// are lower-case, sorted and newline-terminated
+func (s *S3BlobStore) getSignature(method, path, canonicalQuery, canonicalHeaders, signedHeaders, payloadHash string, now time.Time) (signature string) {
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3EncodePath(path),
+		canonicalQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		s.getScope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+	key := s3Hmac([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	key = s3Hmac(key, s.region)
+	key = s3Hmac(key, "s3")
+	key = s3Hmac(key, "aws4_request")
+	return hex.EncodeToString(s3Hmac(key, stringToSign))
+}
+
+func s3Hmac(key []byte, data string) (res []byte) {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Encode percent-encodes all bytes except unreserved characters (RFC 3986),
+// and also "/" if keepSlash is true
+func s3Encode(s string, keepSlash bool) (res string) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
+
+func s3EncodePath(path string) (res string) {
+	return s3Encode(path, true)
+}
+
+// s3EncodeQuery returns the canonical query string, i.e. encoded parameters sorted by name
+func s3EncodeQuery(query url.Values) (res string) {
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Encode(k, false)+"="+s3Encode(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func NewS3BlobStore(endpoint, region, bucket, accessKeyId, secretAccessKey string, pathStyle bool, urlExpiry time.Duration) (store interfaces.BlobStore, err error) {
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3BlobStore{
+		endpoint:        u,
+		region:          region,
+		bucket:          bucket,
+		accessKeyId:     accessKeyId,
+		secretAccessKey: secretAccessKey,
+		pathStyle:       pathStyle,
+		urlExpiry:       urlExpiry,
+		client:          &http.Client{},
+	}, nil
+}
@@ -0,0 +1,67 @@
+package blob
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/go-trace"
+	"time"
+)
+
+// Settings of the blob store, stored in the setting constants.SettingKeyBlobStore
+type Settings struct {
+	Type            string `json:"type" bson:"type"`                           // constants.BlobStoreTypeLocal (default) or constants.BlobStoreTypeS3
+	Endpoint        string `json:"endpoint" bson:"endpoint"`                   // s3 endpoint, e.g. https://s3.amazonaws.com
+	Region          string `json:"region" bson:"region"`                       // s3 region, us-east-1 by default
+	Bucket          string `json:"bucket" bson:"bucket"`                       // s3 bucket
+	AccessKeyId     string `json:"access_key_id" bson:"access_key_id"`         // s3 access key id
+	SecretAccessKey string `json:"secret_access_key" bson:"secret_access_key"` // s3 secret access key
+	PathStyle       bool   `json:"path_style" bson:"path_style"`               // whether to use path-style urls, e.g. for minio
+	UrlExpiry       int    `json:"url_expiry" bson:"url_expiry"`               // expiry (seconds) of presigned download urls, 1 hour by default
+}
+
+// GetLocalRoot returns the root directory of the local blob store (see
+// paths.GetBlobPath)
+func GetLocalRoot() (root string) {
+	return paths.GetBlobPath()
+}
+
+// GetSettings returns the blob store settings, local by default
+func GetSettings() (s Settings) {
+	s = Settings{Type: constants.BlobStoreTypeLocal}
+	modelSvc, err := service.GetService()
+	if err != nil {
+		trace.PrintError(err)
+		return s
+	}
+	if _, err := modelSvc.GetSettingValue(constants.SettingKeyBlobStore, &s); err != nil {
+		trace.PrintError(err)
+		return Settings{Type: constants.BlobStoreTypeLocal}
+	}
+	return s
+}
+
+// NewBlobStore returns the blob store of the settings
+func NewBlobStore(s Settings) (store interfaces.BlobStore, err error) {
+	switch s.Type {
+	case constants.BlobStoreTypeLocal, "":
+		return NewLocalBlobStore(GetLocalRoot()), nil
+	case constants.BlobStoreTypeS3:
+		expiry := time.Hour
+		if s.UrlExpiry > 0 {
+			expiry = time.Duration(s.UrlExpiry) * time.Second
+		}
+		return NewS3BlobStore(s.Endpoint, s.Region, s.Bucket, s.AccessKeyId, s.SecretAccessKey, s.PathStyle, expiry)
+	default:
+		return nil, trace.TraceError(errors.ErrorBlobInvalidType)
+	}
+}
+
+// GetBlobStore returns the blob store of the current settings, which are read
+// on each call (see service.ModelService.GetSettingValue) so that changes take
+// effect without restarting master
+func GetBlobStore() (store interfaces.BlobStore, err error) {
+	return NewBlobStore(GetSettings())
+}
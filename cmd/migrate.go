@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	_ "github.com/crawlab-team/crawlab-core/config" // load config
+	"github.com/crawlab-team/crawlab-core/models/common"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var migrateDryRun bool
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Report pending migrations without applying them")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply database migrations",
+	Long:  `Apply pending collection/index migrations in order, each of which is recorded and applied only once.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pending, err := common.RunMigrations(migrateDryRun)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		if len(pending) == 0 {
+			fmt.Println("no pending migrations")
+			return
+		}
+		for _, m := range pending {
+			if migrateDryRun {
+				fmt.Printf("pending %s: %s\n", m.Version, m.Description)
+			} else {
+				fmt.Printf("applied %s: %s\n", m.Version, m.Description)
+			}
+		}
+	},
+}
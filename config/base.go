@@ -2,15 +2,15 @@ package config
 
 import (
 	"github.com/mitchellh/go-homedir"
-	"path"
+	"path/filepath"
 )
 
 var HomeDirPath, _ = homedir.Dir()
 
 var configDirName = ".crawlab"
 
-var DefaultConfigDirPath = path.Join(HomeDirPath, configDirName)
+var DefaultConfigDirPath = filepath.Join(HomeDirPath, configDirName)
 
 var ConfigName = "config.json"
 
-var DefaultConfigPath = path.Join(HomeDirPath, configDirName, ConfigName)
+var DefaultConfigPath = filepath.Join(HomeDirPath, configDirName, ConfigName)
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"github.com/spf13/viper"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Sources of effective config values, in ascending precedence
+const (
+	SourceDefault = "default" // DefaultConfigYaml
+	SourceFile    = "file"    // config file
+	SourceEnv     = "env"     // CRAWLAB_* environment variables
+	SourceRuntime = "runtime" // runtime settings, e.g. pushed by CONFIG_UPDATE
+)
+
+// RedactedValue replaces values of secret config keys
+const RedactedValue = "******"
+
+// secretKeyPatterns are lower-case substrings of config keys whose values
+// are redacted
+var secretKeyPatterns = []string{
+	"password",
+	"secret",
+	"token",
+	"authkey",
+	"apikey",
+	"accesskey",
+	"privatekey",
+	"webhook",
+}
+
+// runtimeApplied are config keys set at runtime by ApplyRuntimeConfig
+var runtimeApplied sync.Map
+
+// EffectiveConfigItem is a config value as the service sees it and the
+// source it comes from
+type EffectiveConfigItem struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// GetEffectiveConfig returns the effective config of all known keys sorted by
+// key, with secret values redacted. The source of each value is the layer of
+// the highest precedence which sets the key.
+func GetEffectiveConfig() (items []EffectiveConfigItem) {
+	defaults := readConfigKeys(func(v *viper.Viper) error {
+		return v.ReadConfig(bytes.NewBufferString(DefaultConfigYaml))
+	})
+	file := readConfigKeys(func(v *viper.Viper) error {
+		if viper.ConfigFileUsed() == "" {
+			return nil
+		}
+		v.SetConfigFile(viper.ConfigFileUsed())
+		return v.ReadInConfig()
+	})
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		item := EffectiveConfigItem{
+			Key:   key,
+			Value: redactConfigValue(key, viper.Get(key)),
+		}
+		switch {
+		case isRuntimeApplied(key):
+			item.Source = SourceRuntime
+		case isEnvSet(key):
+			item.Source = SourceEnv
+		case file[key]:
+			item.Source = SourceFile
+		case defaults[key]:
+			item.Source = SourceDefault
+		default:
+			// set in code, e.g. by viper.Set
+			item.Source = SourceRuntime
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// readConfigKeys returns keys of a config layer read into a separate viper
+// instance, empty if it cannot be read
+func readConfigKeys(read func(v *viper.Viper) error) (keys map[string]bool) {
+	keys = map[string]bool{}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := read(v); err != nil {
+		return keys
+	}
+	for _, key := range v.AllKeys() {
+		keys[key] = true
+	}
+	return keys
+}
+
+func isRuntimeApplied(key string) (ok bool) {
+	_, ok = runtimeApplied.Load(key)
+	return ok
+}
+
+// isEnvSet returns whether the environment variable of the key is set, e.g.
+// CRAWLAB_GRPC_ADDRESS of grpc.address
+func isEnvSet(key string) (ok bool) {
+	name := "CRAWLAB_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	_, ok = os.LookupEnv(name)
+	return ok
+}
+
+// redactConfigValue redacts the value of secret keys, and passwords of URLs,
+// e.g. mongo connection strings
+func redactConfigValue(key string, value interface{}) (res interface{}) {
+	lowerKey := strings.ToLower(key)
+	for _, p := range secretKeyPatterns {
+		if strings.Contains(lowerKey, p) {
+			return RedactedValue
+		}
+	}
+	if s, ok := value.(string); ok && strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), RedactedValue)
+				return u.String()
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/viper"
+	"sort"
+)
+
+// runtimeKeys are config keys which can be changed at runtime, e.g. by a
+// CONFIG_UPDATE pushed from master, mapped to the function applying the new
+// value. Keys with nil functions are read on each use and need no more than
+// being set in viper.
+var runtimeKeys = map[string]func(value string){
+	"log.level":            applyLogLevel,
+	"proxy.pool":           nil,
+	"proxy.providerUrl":    nil,
+	"proxy.strategy":       nil,
+	"sync.concurrency":     nil,
+	"task.install.timeout": nil,
+}
+
+// ApplyRuntimeConfig applies settings of supported runtime config keys and
+// returns the keys applied. Unsupported keys are ignored with a warning so
+// that nodes of other versions can receive the same update.
+func ApplyRuntimeConfig(settings map[string]string) (applied []string) {
+	for key, value := range settings {
+		apply, ok := runtimeKeys[key]
+		if !ok {
+			log.Warnf("ignored unsupported runtime config: %s", key)
+			continue
+		}
+		viper.Set(key, value)
+		runtimeApplied.Store(key, true)
+		if apply != nil {
+			apply(value)
+		}
+		applied = append(applied, key)
+	}
+	sort.Strings(applied)
+	return applied
+}
+
+func applyLogLevel(value string) {
+	l, err := log.ParseLevel(value)
+	if err != nil {
+		log.Warnf("ignored invalid log level: %s", value)
+		return
+	}
+	log.SetLevel(l)
+}
@@ -1,12 +1,70 @@
 package config
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 const Version = "v0.6.3"
 
+// build info, set via ldflags, e.g.
+// -X github.com/crawlab-team/crawlab-core/config.GitCommit=$(git rev-parse HEAD)
+// -X github.com/crawlab-team/crawlab-core/config.BuildTs=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+var (
+	GitCommit = ""
+	BuildTs   = ""
+)
+
+var startTs = time.Now()
+
 func GetVersion() (v string) {
 	if strings.HasPrefix(Version, "v") {
 		return Version
 	}
 	return "v" + Version
 }
+
+// GetStartTs returns the time when the process started
+func GetStartTs() (ts time.Time) {
+	return startTs
+}
+
+// IsVersionCompatible returns whether nodes of the two versions can work in
+// the same cluster. Following semver, versions are compatible if they have
+// the same major version, and also the same minor version for major version
+// 0, e.g. v0.6.3 and v0.6.0 are compatible but v0.6.3 and v0.7.0 are not.
+// Unparsable versions, e.g. reported by workers older than the check, are
+// not compatible.
+func IsVersionCompatible(v1, v2 string) (ok bool) {
+	major1, minor1, ok1 := parseVersion(v1)
+	major2, minor2, ok2 := parseVersion(v2)
+	if !ok1 || !ok2 || major1 != major2 {
+		return false
+	}
+	if major1 == 0 {
+		return minor1 == minor2
+	}
+	return true
+}
+
+// parseVersion returns the major and minor version of a version string such
+// as "v0.6.3" or "0.6.3-beta.1"
+func parseVersion(v string) (major, minor int, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	var err error
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, false
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
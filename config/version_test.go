@@ -0,0 +1,16 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsVersionCompatible(t *testing.T) {
+	require.True(t, IsVersionCompatible("v0.6.3", "v0.6.0"))
+	require.True(t, IsVersionCompatible("v0.6.3", "0.6.4-beta.1"))
+	require.True(t, IsVersionCompatible("v1.2.0", "v1.5.1"))
+	require.False(t, IsVersionCompatible("v0.6.3", "v0.7.0"))
+	require.False(t, IsVersionCompatible("v1.0.0", "v2.0.0"))
+	require.False(t, IsVersionCompatible("v0.6.3", ""))
+	require.False(t, IsVersionCompatible("v0.6.3", "latest"))
+}
@@ -0,0 +1,10 @@
+package constants
+
+const (
+	AuditLogColName = "audit_logs"
+)
+
+const (
+	// AuditRedactedValue replaces values of secret fields in audit logs
+	AuditRedactedValue = "******"
+)
@@ -0,0 +1,6 @@
+package constants
+
+const (
+	BlobStoreTypeLocal = "local"
+	BlobStoreTypeS3    = "s3"
+)
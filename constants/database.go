@@ -3,3 +3,8 @@ package constants
 const (
 	ColJob = "jobs"
 )
+
+const (
+	MigrationColName     = "migrations"
+	MigrationLockColName = "migration_locks"
+)
@@ -10,8 +10,32 @@ const (
 
 const (
 	GrpcHeaderAuthorization = "authorization"
+	GrpcHeaderNodeKey       = "node-key"
+	GrpcHeaderTaskAck       = "task-ack" // set in fetches by workers acknowledging dispatched tasks, and in responses requiring the acknowledgement
 )
 
 const (
 	GrpcSubscribeTypeNode = "node"
 )
+
+// Codes of stream messages below are added by crawlab-core on top of
+// grpc.StreamMessageCode of the proto, which is generated in the external
+// module crawlab-grpc and cannot be extended here. They are sent as raw enum
+// values and must not collide with codes defined in the proto; they are to be
+// moved into the proto when it is regenerated.
+
+// GrpcStreamMessageCodeConfigUpdate is the code of stream messages carrying
+// runtime config updates (CONFIG_UPDATE). It is not defined in the proto, as
+// proto3 enums are open the value passes through older peers, which ignore
+// messages of unknown codes.
+const GrpcStreamMessageCodeConfigUpdate = 100
+
+// GrpcStreamMessageCodeTaskAck is the code of task stream messages by which
+// workers accept or reject tasks dispatched to them (see
+// entity.TaskAckMessage), not defined in the proto as well
+const GrpcStreamMessageCodeTaskAck = 101
+
+// GrpcStreamMessageCodeSyncSpider is the code of stream messages by which
+// master requests worker nodes to sync files of a spider (see
+// entity.SpiderSyncMessage), not defined in the proto as well
+const GrpcStreamMessageCodeSyncSpider = 102
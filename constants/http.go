@@ -9,3 +9,7 @@ const (
 const (
 	HttpContentTypeApplicationJson = "application/json"
 )
+
+const (
+	HttpHeaderIdempotencyKey = "Idempotency-Key"
+)
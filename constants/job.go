@@ -0,0 +1,16 @@
+package constants
+
+const (
+	JobColName = "background_jobs" // not "jobs", which is used by models.Job
+)
+
+const (
+	JobStatusPending  = "pending"
+	JobStatusRunning  = "running"
+	JobStatusFinished = "finished"
+	JobStatusError    = "error"
+)
+
+const (
+	JobTypeSpiderWebhook = "spider_webhook" // delivery of the webhook of a spider whose task ended
+)
@@ -5,4 +5,22 @@ const (
 	NodeStatusRegistered   = "r"
 	NodeStatusOnline       = "on"
 	NodeStatusOffline      = "off"
+	NodeStatusDraining     = "dr"
+)
+
+const (
+	// NodeVersionPolicyWarn logs a warning when a worker of an incompatible
+	// version registers (default)
+	NodeVersionPolicyWarn = "warn"
+	// NodeVersionPolicyRefuse refuses registration of workers of incompatible versions
+	NodeVersionPolicyRefuse = "refuse"
+)
+
+// sort keys of the node list, see models.SortNodes
+const (
+	NodeSortByDefault  = ""          // masters first, then by status (online first) and name
+	NodeSortByName     = "name"      // by name
+	NodeSortByStatus   = "status"    // by status (online first), then by name
+	NodeSortByActiveTs = "active_ts" // by last active time
+	NodeSortByCreateTs = "create_ts" // by create time
 )
@@ -4,7 +4,24 @@ const (
 	HashKey = "_h"
 )
 
+const (
+	ResultColNamePrefix = "results_" // prefix of default result collections, followed by the spider id
+)
+
 const (
 	DedupTypeIgnore    = "ignore"
 	DedupTypeOverwrite = "overwrite"
 )
+
+const (
+	ResultFieldTypeString = "string"
+	ResultFieldTypeInt    = "int"
+	ResultFieldTypeFloat  = "float"
+	ResultFieldTypeBool   = "bool"
+)
+
+const (
+	ResultNumberModeFloat   = "float"   // numbers stored as doubles (default)
+	ResultNumberModeInt     = "int"     // integers stored as int64, or decimal128 if out of range, other numbers as doubles
+	ResultNumberModeDecimal = "decimal" // integers stored as int64, other numbers as decimal128
+)
@@ -8,3 +8,11 @@ const (
 	ScheduleStatusErrorNotFoundNode   = "Not Found Node"
 	ScheduleStatusErrorNotFoundSpider = "Not Found Spider"
 )
+
+// misfire policies of schedules, i.e. what to do with runs missed while the
+// scheduler was down
+const (
+	ScheduleMisfirePolicySkip     = "skip"      // skip missed runs (default)
+	ScheduleMisfirePolicyFireOnce = "fire_once" // fire one catch-up run
+	ScheduleMisfirePolicyFireAll  = "fire_all"  // fire all missed runs
+)
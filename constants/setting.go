@@ -0,0 +1,15 @@
+package constants
+
+const (
+	SettingKeyPasswordPolicy  = "password_policy"
+	SettingKeyBlobStore       = "blob_store"
+	SettingKeyPushgateway     = "pushgateway"
+	SettingKeyTaskConcurrency = "task_concurrency"
+	SettingKeySchedulerPause  = "scheduler_pause"
+	SettingKeyNodeConfig      = "node_config" // runtime config of nodes, overridable by node tags
+)
+
+const (
+	// SettingRedactedValue replaces values of secret fields of settings on read
+	SettingRedactedValue = "******"
+)
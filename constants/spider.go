@@ -0,0 +1,14 @@
+package constants
+
+// runtimes of tasks of spiders
+const (
+	SpiderRuntimeProcess = "process" // process on the node (default)
+	SpiderRuntimeDocker  = "docker"  // docker container on the node, see entity.SpiderContainer
+)
+
+// pull policies of container images
+const (
+	ContainerPullMissing = "missing" // pull if the image does not exist on the node (default)
+	ContainerPullAlways  = "always"  // pull before every task
+	ContainerPullNever   = "never"   // never pull, tasks fail if the image does not exist
+)
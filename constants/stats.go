@@ -0,0 +1,6 @@
+package constants
+
+const (
+	StatsIntervalHour = "hour"
+	StatsIntervalDay  = "day"
+)
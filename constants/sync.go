@@ -0,0 +1,19 @@
+package constants
+
+const (
+	// SyncStrategyLazy syncs spider files to a worker node only before a task of the spider runs on it
+	SyncStrategyLazy = "lazy"
+	// SyncStrategyEager additionally pushes spider files to worker nodes as soon as they change on master
+	SyncStrategyEager = "eager"
+)
+
+const (
+	SyncFileActionDownload = "download"
+	SyncFileActionDelete   = "delete"
+)
+
+const (
+	SyncHeaderAuthorization = "Authorization"
+	// SyncHeaderWorkspaceQuota is the header of the scan response carrying the workspace quota of the spider (bytes)
+	SyncHeaderWorkspaceQuota = "X-Workspace-Quota"
+)
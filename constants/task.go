@@ -37,3 +37,92 @@ const (
 const (
 	TaskKey = "_tid"
 )
+
+const (
+	TaskDispatchStrategyRoundRobin  = "round-robin"
+	TaskDispatchStrategyLeastLoaded = "least-loaded"
+	TaskDispatchStrategyRandom      = "random"
+)
+
+// default node of tasks without node tags or capabilities, which is configured
+// by "task.scheduler.defaultNode" as a node key or TaskDefaultNodeAnyWorker
+const (
+	TaskDefaultNodeAnyWorker       = "any-worker" // any online worker node, i.e. not master
+	TaskDefaultNodeFallbackAny     = "any"        // dispatch to any eligible node if the default node is unavailable
+	TaskDefaultNodeFallbackPending = "pending"    // leave the task pending until the default node is available
+)
+
+// handling of tasks whose node tags or capabilities no node satisfies on
+// creation, which is configured by "task.scheduler.feasibility"
+const (
+	TaskFeasibilityReject = "reject" // reject the task
+	TaskFeasibilityWarn   = "warn"   // create the task with a warning event if it is assigned to a node
+)
+
+const (
+	TaskCancelModeSoft  = "soft"  // terminate (SIGTERM) and wait for the grace period before killing
+	TaskCancelModeForce = "force" // kill (SIGKILL) immediately
+)
+
+const (
+	TaskEventCreated         = "created"
+	TaskEventDispatched      = "dispatched"
+	TaskEventStarted         = "started"
+	TaskEventInstalled       = "installed"
+	TaskEventInstallFailed   = "install_failed"
+	TaskEventRetried         = "retried"
+	TaskEventCancelRequested = "cancel_requested"
+	TaskEventCancelled       = "cancelled"
+	TaskEventFinished        = "finished"
+	TaskEventError           = "error"
+	TaskEventReassigned      = "reassigned"
+	TaskEventItemsCapped     = "items_capped"
+	TaskEventDeadLettered    = "dead_lettered"
+	TaskEventLogsTruncated   = "logs_truncated"
+	TaskEventRequeued        = "requeued"
+	TaskEventRejected        = "rejected"
+	TaskEventInfeasible      = "infeasible"
+)
+
+const (
+	TaskEventActorSystem = "system"
+)
+
+const (
+	TaskIdempotencyKeyColName = "task_idempotency_keys"
+	TaskArtifactColName       = "task_artifacts"
+	SpiderInputColName        = "spider_inputs"
+	TaskLockColName           = "task_locks"
+)
+
+const (
+	// TaskArtifactsDirEnv is the environment variable of the directory where
+	// the task process puts artifact files to be uploaded to master
+	TaskArtifactsDirEnv = "CRAWLAB_TASK_ARTIFACTS_DIR"
+
+	// TaskCheckpointEnv is the environment variable of the checkpoint token
+	// the task process resumes from, empty if it starts from scratch
+	TaskCheckpointEnv = "CRAWLAB_TASK_CHECKPOINT"
+
+	// TaskInputFileEnv is the environment variable of the path of the input
+	// file uploaded for the task, unset if the task has no input file
+	TaskInputFileEnv = "CRAWLAB_TASK_INPUT_FILE"
+
+	// TaskProfileEnv is the environment variable of the spider profile the
+	// task runs with, unset if the task has no profile
+	TaskProfileEnv = "CRAWLAB_PROFILE"
+
+	// TaskConcurrencyEnv is the environment variable of the concurrency of the
+	// task process, unset if the task has no concurrency
+	TaskConcurrencyEnv = "CRAWLAB_CONCURRENCY"
+
+	// TaskConcurrencyPlaceholder is replaced by the concurrency of the task in
+	// the cmd, e.g. scrapy crawl quotes -s CONCURRENT_REQUESTS={{concurrency}}
+	TaskConcurrencyPlaceholder = "{{concurrency}}"
+)
+
+const (
+	// TaskListViewLight is the view of the task list omitting large fields,
+	// e.g. cmd and param
+	TaskListViewLight = "light"
+)
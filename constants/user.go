@@ -13,3 +13,7 @@ const (
 const (
 	UserContextKey = "user"
 )
+
+const (
+	RefreshTokenColName = "refresh_tokens"
+)
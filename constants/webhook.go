@@ -0,0 +1,17 @@
+package constants
+
+const (
+	WebhookOnAll     = "all"     // all ended tasks
+	WebhookOnSuccess = "success" // finished tasks
+	WebhookOnFailure = "failure" // tasks ended with error or abnormal
+)
+
+const (
+	WebhookEventTaskEnded = "task.ended"
+)
+
+const (
+	WebhookHeaderEvent     = "X-Crawlab-Event"
+	WebhookHeaderDelivery  = "X-Crawlab-Delivery"  // id of the delivery, the same across retries
+	WebhookHeaderSignature = "X-Crawlab-Signature" // "sha256=<hex of HMAC-SHA256 of the body>"
+)
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"github.com/crawlab-team/crawlab-core/audit"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+// getAuditLogList returns audit logs filtered by query params "user_id",
+// "model", "model_id", "start" and "end" (RFC3339), latest first
+func getAuditLogList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	// query
+	query := bson.M{}
+	for _, key := range []string{"user_id", "model_id"} {
+		if value := c.Query(key); value != "" {
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				HandleErrorBadRequest(c, err)
+				return
+			}
+			query[key] = id
+		}
+	}
+	if model := c.Query("model"); model != "" {
+		query["model"] = model
+	}
+	ts := bson.M{}
+	for key, op := range map[string]string{"start": "$gte", "end": "$lt"} {
+		if value := c.Query(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				HandleErrorBadRequest(c, err)
+				return
+			}
+			ts[op] = t
+		}
+	}
+	if len(ts) > 0 {
+		query["ts"] = ts
+	}
+
+	// pagination
+	p := MustGetPagination(c)
+
+	// logs
+	logs, err := audit.GetLogList(query, (p.Page-1)*p.Size, p.Size)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	total, err := audit.CountLogs(query)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithListData(c, logs, total)
+}
+
+func getAuditLogActions() []Action {
+	return []Action{
+		{
+			Path:        "",
+			Method:      http.MethodGet,
+			HandlerFunc: getAuditLogList,
+		},
+	}
+}
+
+var AuditLogController ActionController
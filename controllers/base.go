@@ -33,6 +33,13 @@ const (
 	ControllerIdI18n
 	ControllerIdSystemInfo
 	ControllerIdDemo
+	ControllerIdHealth
+	ControllerIdSync
+	ControllerIdBuildInfo
+	ControllerIdMetrics
+	ControllerIdAuditLog
+	ControllerIdScheduler
+	ControllerIdConfig
 )
 
 type ControllerId int
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"github.com/crawlab-team/crawlab-core/config"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+func getBuildInfo(c *gin.Context) {
+	info := &entity.BuildInfo{
+		Version:   config.GetVersion(),
+		GitCommit: config.GitCommit,
+		BuildTs:   config.BuildTs,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		StartTs:   config.GetStartTs(),
+		Uptime:    int64(time.Since(config.GetStartTs()).Seconds()),
+	}
+
+	// online nodes
+	modelSvc, err := service.GetService()
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	info.OnlineNodes, err = modelSvc.GetBaseService(interfaces.ModelIdNode).Count(bson.M{
+		"status": constants.NodeStatusOnline,
+	})
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, info)
+}
+
+func getBuildInfoActions() []Action {
+	return []Action{
+		{
+			Path:        "",
+			Method:      http.MethodGet,
+			HandlerFunc: getBuildInfo,
+		},
+	}
+}
+
+var BuildInfoController ActionController
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"github.com/crawlab-team/crawlab-core/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// getEffectiveConfig returns the effective config as the service sees it,
+// i.e. defaults merged with the config file, environment variables and
+// runtime settings, with the source of each value and secrets redacted
+func getEffectiveConfig(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	HandleSuccessWithData(c, config.GetEffectiveConfig())
+}
+
+func getConfigActions() []Action {
+	return []Action{
+		{
+			Path:        "",
+			Method:      http.MethodGet,
+			HandlerFunc: getEffectiveConfig,
+		},
+	}
+}
+
+var ConfigController ActionController
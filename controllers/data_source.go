@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/crawlab-team/crawlab-core/ds"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	interfaces2 "github.com/crawlab-team/crawlab-core/interfaces"
@@ -15,6 +16,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"net/http"
+	"time"
 )
 
 var DataSourceController *dataSourceController
@@ -27,6 +29,11 @@ func getDataSourceActions() []Action {
 			Method:      http.MethodPost,
 			HandlerFunc: ctx.changePassword,
 		},
+		{
+			Path:        "/test",
+			Method:      http.MethodPost,
+			HandlerFunc: ctx.test,
+		},
 	}
 }
 
@@ -129,6 +136,35 @@ func (ctx *dataSourceContext) changePassword(c *gin.Context) {
 	HandleSuccess(c)
 }
 
+// test connects to the data source in the payload without saving it. The
+// stored password is used if the payload has an id but no password, and the
+// host, port and username of the stored data source.
+func (ctx *dataSourceContext) test(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	var _ds models.DataSource
+	if err := c.ShouldBindJSON(&_ds); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if _ds.Type == "" {
+		HandleErrorBadRequest(c, errors.ErrorDataSourceMissingRequiredFields)
+		return
+	}
+
+	tic := time.Now()
+	res := entity.DataSourceTestResult{Success: true}
+	if err := ctx.dsSvc.TestDataSource(&_ds); err != nil {
+		res.Success = false
+		res.Error = err.Error()
+	}
+	res.Duration = time.Since(tic).Milliseconds()
+
+	HandleSuccessWithData(c, res)
+}
+
 func newDataSourceController() *dataSourceController {
 	actions := getDataSourceActions()
 	modelSvc, err := service.GetService()
@@ -197,7 +197,7 @@ func (d *ListControllerDelegate) getList(c *gin.Context) (l interfaces.List, tot
 	sort := MustGetSortOption(c)
 
 	// get list
-	l, err = d.svc.GetList(query, &mongo.FindOptions{
+	l, err = getListWithContext(c, d.svc, query, &mongo.FindOptions{
 		Sort:  sort,
 		Skip:  pagination.Size * (pagination.Page - 1),
 		Limit: pagination.Size,
@@ -212,7 +212,7 @@ func (d *ListControllerDelegate) getList(c *gin.Context) (l interfaces.List, tot
 	}
 
 	// total count
-	total, err = d.svc.Count(query)
+	total, err = countWithContext(c, d.svc, query)
 	if err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
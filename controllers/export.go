@@ -103,11 +103,12 @@ func (ctx *exportContext) getExportDownload(c *gin.Context) {
 		return
 	}
 
+	var contentType string
 	switch exportType {
 	case constants.ExportTypeCsv:
-		c.Header("Content-Type", "text/csv")
+		contentType = "text/csv"
 	case constants.ExportTypeJson:
-		c.Header("Content-Type", "text/plain")
+		contentType = "text/plain"
 	default:
 		HandleErrorBadRequest(c, errors.New(fmt.Sprintf("invalid export type: %s", exportType)))
 	}
@@ -115,6 +116,14 @@ func (ctx *exportContext) getExportDownload(c *gin.Context) {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
+
+	// stored in blob store
+	if exp.GetBlobKey() != "" {
+		serveBlob(c, exp.GetBlobKey(), exp.GetFileName(), contentType, -1)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", exp.GetDownloadPath()))
 	c.File(exp.GetDownloadPath())
 }
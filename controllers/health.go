@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/inject"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func getHealth(c *gin.Context) {
+	health := &entity.Health{
+		Status:          "ok",
+		SchedulerPaused: task.IsSchedulerPaused(),
+	}
+
+	// node monitor stats of master
+	_ = inject.GetContainer().Invoke(func(masterSvc interfaces.NodeMasterService) {
+		stats := masterSvc.GetMonitorStats()
+		health.Monitor = &stats
+
+		// log stream stats of grpc server
+		if svr := masterSvc.GetServer(); svr != nil {
+			logStats := svr.GetLogStats()
+			health.Logs = &logStats
+		}
+	})
+
+	HandleSuccessWithData(c, health)
+}
+
+func getHealthActions() []Action {
+	return []Action{
+		{
+			Path:        "",
+			Method:      http.MethodGet,
+			HandlerFunc: getHealth,
+		},
+	}
+}
+
+var HealthController ActionController
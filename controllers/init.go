@@ -31,6 +31,13 @@ func InitControllers() (err error) {
 	I18nController = NewActionControllerDelegate(ControllerIdI18n, getI18nActions())
 	SystemInfoController = NewActionControllerDelegate(ControllerIdSystemInfo, getSystemInfoActions())
 	DemoController = NewActionControllerDelegate(ControllerIdDemo, getDemoActions())
+	HealthController = NewActionControllerDelegate(ControllerIdHealth, getHealthActions())
+	SyncController = NewActionControllerDelegate(ControllerIdSync, getSyncActions())
+	BuildInfoController = NewActionControllerDelegate(ControllerIdBuildInfo, getBuildInfoActions())
+	MetricsController = NewActionControllerDelegate(ControllerIdMetrics, getMetricsActions())
+	AuditLogController = NewActionControllerDelegate(ControllerIdAuditLog, getAuditLogActions())
+	SchedulerController = NewActionControllerDelegate(ControllerIdScheduler, getSchedulerActions())
+	ConfigController = NewActionControllerDelegate(ControllerIdConfig, getConfigActions())
 	RoleController = NewListControllerDelegate(ControllerIdRole, modelSvc.GetBaseService(interfaces.ModelIdRole))
 	PermissionController = NewListControllerDelegate(ControllerIdPermission, modelSvc.GetBaseService(interfaces.ModelIdPermission))
 	ExportController = NewActionControllerDelegate(ControllerIdExport, getExportActions())
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
@@ -18,6 +19,7 @@ func getLoginActions() []Action {
 	return []Action{
 		{Method: http.MethodPost, Path: "/login", HandlerFunc: loginCtx.login},
 		{Method: http.MethodPost, Path: "/logout", HandlerFunc: loginCtx.logout},
+		{Method: http.MethodPost, Path: "/token/refresh", HandlerFunc: loginCtx.refreshToken},
 	}
 }
 
@@ -34,20 +36,70 @@ func (ctx *loginContext) login(c *gin.Context) {
 	token, loggedInUser, err := ctx.userSvc.Login(&interfaces.UserLoginOptions{
 		Username: u.Username,
 		Password: u.Password,
+		Ip:       c.ClientIP(),
 	})
 	if err != nil {
-		HandleErrorUnauthorized(c, errors.ErrorUserUnauthorized)
+		switch err {
+		case errors.ErrorUserLocked:
+			HandleError(http.StatusLocked, c, err)
+		case errors.ErrorUserTooManyLoginAttempts:
+			HandleError(http.StatusTooManyRequests, c, err)
+		default:
+			HandleErrorUnauthorized(c, errors.ErrorUserUnauthorized)
+		}
+		return
+	}
+	refreshToken, err := ctx.userSvc.MakeRefreshToken(loggedInUser)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
 		return
 	}
 	c.Set(constants.UserContextKey, loggedInUser)
-	HandleSuccessWithData(c, token)
+	HandleSuccessWithData(c, entity.LoginResult{
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
 }
 
 func (ctx *loginContext) logout(c *gin.Context) {
+	var payload entity.RefreshTokenPayload
+	_ = c.ShouldBindJSON(&payload)
+	if payload.RefreshToken != "" {
+		if err := ctx.userSvc.RevokeRefreshToken(payload.RefreshToken); err != nil {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+	}
 	c.Set(constants.UserContextKey, nil)
 	HandleSuccess(c)
 }
 
+func (ctx *loginContext) refreshToken(c *gin.Context) {
+	var payload entity.RefreshTokenPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if payload.RefreshToken == "" {
+		HandleErrorBadRequest(c, errors.ErrorUserInvalidRefreshToken)
+		return
+	}
+	token, refreshToken, err := ctx.userSvc.RefreshToken(payload.RefreshToken)
+	if err != nil {
+		switch err {
+		case errors.ErrorUserInvalidRefreshToken, errors.ErrorUserRefreshTokenReused, errors.ErrorUserNotExists:
+			HandleErrorUnauthorized(c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
+		return
+	}
+	HandleSuccessWithData(c, entity.LoginResult{
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
+}
+
 func newLoginContext() *loginContext {
 	// context
 	ctx := &loginContext{}
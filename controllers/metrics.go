@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"bytes"
+	"github.com/crawlab-team/crawlab-core/metrics"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// getMetrics returns node and task metrics in the Prometheus text exposition format
+func getMetrics(c *gin.Context) {
+	data, err := metrics.Collect()
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf, data); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, metrics.ContentType, buf.Bytes())
+}
+
+func getMetricsActions() []Action {
+	return []Action{
+		{
+			Path:        "",
+			Method:      http.MethodGet,
+			HandlerFunc: getMetrics,
+		},
+	}
+}
+
+var MetricsController ActionController
@@ -1,25 +1,105 @@
 package controllers
 
 import (
+	"encoding/json"
+	errors2 "errors"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/inject"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-core/spider/admin"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/task/scheduler"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/dig"
+	"io"
+	"net/http"
 )
 
 var NodeController *nodeController
 
+func getNodeActions() []Action {
+	ctx := newNodeContext()
+	return []Action{
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/drain",
+			HandlerFunc: ctx.drain,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/undrain",
+			HandlerFunc: ctx.undrain,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/cordon",
+			HandlerFunc: ctx.cordon,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/uncordon",
+			HandlerFunc: ctx.uncordon,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/tasks/running",
+			HandlerFunc: ctx.getRunningTasks,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/tasks/cancel",
+			HandlerFunc: ctx.cancelTasks,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/bulk/delete",
+			HandlerFunc: ctx.bulkDelete,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/sync-progress",
+			HandlerFunc: ctx.getSyncProgress,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/resync",
+			HandlerFunc: ctx.resync,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/monitor",
+			HandlerFunc: ctx.runMonitor,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/config",
+			HandlerFunc: ctx.pushConfig,
+		},
+	}
+}
+
 type nodeController struct {
-	ListControllerDelegate
+	ListActionControllerDelegate
+	modelSvc service.ModelService
 }
 
 func (ctr *nodeController) Post(c *gin.Context) {
-	var n models.Node
+	n := newNode()
 	if err := c.ShouldBindJSON(&n); err != nil {
 		HandleErrorBadRequest(c, err)
 		return
@@ -33,13 +113,103 @@ func (ctr *nodeController) Post(c *gin.Context) {
 	HandleSuccess(c)
 }
 
+// GetList returns nodes sorted by the "sort_by" query (constants.NodeSortBy*),
+// descending if "sort_desc" is true, in the default order (masters first, then
+// online workers by name) if neither "sort_by" nor "sort" is set
+func (ctr *nodeController) GetList(c *gin.Context) {
+	sortBy := c.Query("sort_by")
+	if MustGetFilterAll(c) || (sortBy == "" && c.Query(constants.SortQueryField) != "") {
+		ctr.ListActionControllerDelegate.GetList(c)
+		return
+	}
+
+	// nodes
+	query := MustGetFilterQuery(c)
+	nodes, err := ctr.modelSvc.GetNodeList(query, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	// sort
+	if err := models.SortNodes(nodes, sortBy, c.Query("sort_desc") == "true"); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// paginate
+	p := MustGetPagination(c)
+	total := len(nodes)
+	start := (p.Page - 1) * p.Size
+	if start < 0 || start > total {
+		start = total
+	}
+	end := start + p.Size
+	if end > total {
+		end = total
+	}
+
+	HandleSuccessWithListData(c, nodes[start:end], total)
+}
+
+// Put updates only fields editable by users, so that status fields updated by
+// the node monitor in the meantime are not overwritten
+func (ctr *nodeController) Put(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	var payload models.Node
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if payload.Id != id {
+		HandleErrorBadRequest(c, errors.ErrorHttpBadRequest)
+		return
+	}
+
+	modelSvc, err := service.GetService()
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	n, err := modelSvc.GetNodeById(id)
+	if err != nil {
+		HandleErrorNotFound(c, err)
+		return
+	}
+
+	if err := delegate.NewModelDelegate(n, GetUserFromContext(c)).UpdateFields(bson.M{
+		"name":        payload.Name,
+		"description": payload.Description,
+		"enabled":     payload.Enabled,
+		"max_runners": payload.MaxRunners,
+	}); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, n)
+}
+
 func (ctr *nodeController) PostList(c *gin.Context) {
 	// bind
-	var docs []models.Node
-	if err := c.ShouldBindJSON(&docs); err != nil {
+	var payload []json.RawMessage
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		HandleErrorBadRequest(c, err)
 		return
 	}
+	var docs []models.Node
+	for _, data := range payload {
+		n := newNode()
+		if err := json.Unmarshal(data, &n); err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		docs = append(docs, n)
+	}
 
 	// success ids
 	var ids []primitive.ObjectID
@@ -57,6 +227,11 @@ func (ctr *nodeController) PostList(c *gin.Context) {
 	HandleSuccessWithData(c, docs)
 }
 
+// newNode returns a node with default values of fields missing in payloads
+func newNode() (n models.Node) {
+	return models.Node{Schedulable: true}
+}
+
 func (ctr *nodeController) _post(c *gin.Context, n *models.Node) (err error) {
 	// set default key
 	if n.Key == "" {
@@ -80,15 +255,411 @@ func (ctr *nodeController) _post(c *gin.Context, n *models.Node) (err error) {
 	return nil
 }
 
+type nodeContext struct {
+	modelSvc     service.ModelService
+	schedulerSvc interfaces.TaskSchedulerService
+	adminSvc     interfaces.SpiderAdminService
+}
+
+func (ctx *nodeContext) drain(c *gin.Context) {
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+
+	// stop dispatching new tasks to the node while running tasks keep running
+	if err := delegate.NewModelNodeDelegate(n).UpdateStatusDraining(); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, n)
+}
+
+func (ctx *nodeContext) runMonitor(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	// master service
+	var masterSvc interfaces.NodeMasterService
+	if err := inject.GetContainer().Invoke(func(svc interfaces.NodeMasterService) {
+		masterSvc = svc
+	}); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	// run a monitor cycle, failed and skipped nodes are reported in the summary
+	summary, err := masterSvc.RunMonitorOnce()
+	if err != nil && !errors2.Is(err, errors.ErrorNodeMonitorError) && !errors2.Is(err, errors.ErrorNodeMonitorCancelled) {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, summary)
+}
+
+// pushConfig applies runtime settings on master and pushes them to online
+// worker nodes. Settings not supported at runtime are ignored by the nodes.
+func (ctx *nodeContext) pushConfig(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	var payload entity.ConfigUpdateMessage
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if len(payload.Settings) == 0 {
+		HandleErrorBadRequest(c, errors.ErrorHttpBadRequest)
+		return
+	}
+
+	// master service
+	var masterSvc interfaces.NodeMasterService
+	if err := inject.GetContainer().Invoke(func(svc interfaces.NodeMasterService) {
+		masterSvc = svc
+	}); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, masterSvc.PushConfigUpdate(payload.Settings))
+}
+
+func (ctx *nodeContext) getRunningTasks(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+	tasks, err := ctx.schedulerSvc.GetRunningTasksByNode(n.Key)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithListData(c, tasks, len(tasks))
+}
+
+// cancelTasks cancels all running tasks on the node, soft or force by the
+// mode of the payload, and returns the result of cancelling each task
+func (ctx *nodeContext) cancelTasks(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+
+	// options
+	var opts interfaces.TaskCancelOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if opts.Mode != "" && opts.Mode != constants.TaskCancelModeSoft && opts.Mode != constants.TaskCancelModeForce {
+		HandleErrorBadRequest(c, errors.ErrorControllerRequestPayloadInvalid)
+		return
+	}
+
+	results, err := ctx.schedulerSvc.CancelTasksByNode(n.Key, GetUserFromContext(c), &opts)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, results)
+}
+
+// getSyncProgress returns the latest progress of syncing files of each spider
+// reported by the node since master started
+func (ctx *nodeContext) getSyncProgress(c *gin.Context) {
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+	HandleSuccessWithData(c, fs.GetSyncProgressList(n.Key))
+}
+
+// resync pushes sync requests of spider files to the node, e.g. after it was
+// offline during an update, and responds with results of files synced if the
+// node reports them in time. Otherwise the progress of each spider is
+// available by getSyncProgress.
+func (ctx *nodeContext) resync(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+
+	var payload entity.NodeResyncPayload
+	if err := c.ShouldBindJSON(&payload); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	results, err := ctx.adminSvc.ResyncNode(n.Key, payload.SpiderIds)
+	if err != nil {
+		if errors2.Is(err, errors.ErrorNodeInvalidStatus) {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, results)
+}
+
+func (ctx *nodeContext) undrain(c *gin.Context) {
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+
+	// set back online
+	n.SetDrained(false)
+	n.SetStatus(constants.NodeStatusOnline)
+	if err := delegate.NewModelNodeDelegate(n).UpdateStatusOnline(); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, n)
+}
+
+// cordon marks the node unschedulable, so that no new tasks are dispatched to
+// it. Unlike draining, neither running tasks nor the status of the node are
+// affected.
+func (ctx *nodeContext) cordon(c *gin.Context) {
+	ctx._setSchedulable(c, false)
+}
+
+func (ctx *nodeContext) uncordon(c *gin.Context) {
+	ctx._setSchedulable(c, true)
+}
+
+func (ctx *nodeContext) _setSchedulable(c *gin.Context, schedulable bool) {
+	n, err := ctx._getNode(c)
+	if err != nil {
+		return
+	}
+
+	if err := delegate.NewModelDelegate(n, GetUserFromContext(c)).UpdateFields(bson.M{
+		"schedulable": schedulable,
+	}); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, n)
+}
+
+// bulkDelete deletes each node of entity.NodeBulkDeletePayload and returns
+// per-node results. The live subscription of the node is closed, and its
+// pending tasks are unassigned so that they can be dispatched to other nodes.
+// Nodes running tasks are refused unless forced.
+func (ctx *nodeContext) bulkDelete(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	var payload entity.NodeBulkDeletePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// grpc server of master to close subscriptions
+	var svr interfaces.GrpcServer
+	if err := inject.GetContainer().Invoke(func(masterSvc interfaces.NodeMasterService) {
+		svr = masterSvc.GetServer()
+	}); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	var results []entity.NodeBulkResult
+	for _, id := range payload.Ids {
+		res := entity.NodeBulkResult{NodeId: id}
+		if err := ctx._deleteNode(c, svr, id, payload.Force, &res); err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+		}
+		results = append(results, res)
+	}
+
+	HandleSuccessWithData(c, results)
+}
+
+func (ctx *nodeContext) _deleteNode(c *gin.Context, svr interfaces.GrpcServer, id primitive.ObjectID, force bool, res *entity.NodeBulkResult) (err error) {
+	n, err := ctx.modelSvc.GetNodeById(id)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return errors.ErrorNodeNotExists
+		}
+		return err
+	}
+	res.NodeName = n.Name
+	if n.IsMaster {
+		return errors.ErrorNodeDeleteMaster
+	}
+
+	// running tasks
+	runningCount, err := task.CountRunningTasksByNode(n.Id)
+	if err != nil {
+		return err
+	}
+	if runningCount > 0 && !force {
+		return fmt.Errorf("%w: %d", errors.ErrorNodeHasRunningTasks, runningCount)
+	}
+
+	// tasks of the node
+	tasks, err := ctx.modelSvc.GetTaskList(bson.M{
+		"node_id": n.Id,
+		"status": bson.M{"$in": []string{
+			constants.TaskStatusPending,
+			constants.TaskStatusRunning,
+		}},
+	}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return err
+	}
+
+	// cancel running tasks on the node before closing its live subscription,
+	// so that they do not keep running on a deleted node
+	key := "node:" + n.Key
+	for _, t := range tasks {
+		if t.Status != constants.TaskStatusRunning {
+			continue
+		}
+		t.SetCancelMode(constants.TaskCancelModeForce)
+		if err := svr.SendStreamMessageWithData(key, grpc.StreamMessageCode_CANCEL_TASK, &t); err != nil {
+			trace.PrintError(err)
+		}
+	}
+
+	// close live subscription
+	if sub, err := svr.GetSubscribe(key); err == nil {
+		select {
+		case sub.GetFinished() <- true:
+		default:
+			// the stream has already been closed
+		}
+		svr.DeleteSubscribe(key)
+	}
+
+	// soft-delete, the artifact of the node is kept
+	if err := delegate.NewModelDelegate(n, GetUserFromContext(c)).Delete(); err != nil {
+		return err
+	}
+
+	// reconcile tasks of the node
+	for _, t := range tasks {
+		if err := ctx._reconcileNodeTask(n, &t, res); err != nil {
+			trace.PrintError(err)
+		}
+	}
+
+	return nil
+}
+
+// _reconcileNodeTask unassigns a pending task of the deleted node if it is
+// still in the task queue, otherwise the task cannot run anymore and is marked
+// abnormal
+func (ctx *nodeContext) _reconcileNodeTask(n *models.Node, t *models.Task, res *entity.NodeBulkResult) (err error) {
+	if t.Status == constants.TaskStatusPending {
+		_, err := ctx.modelSvc.GetTaskQueueItemById(t.Id)
+		if err == nil {
+			if err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).UpdateId(t.Id, bson.M{"$unset": bson.M{"nid": ""}}); err != nil {
+				return trace.TraceError(err)
+			}
+			t.NodeId = primitive.NilObjectID
+			if err := delegate.NewModelDelegate(t).Save(); err != nil {
+				return err
+			}
+			res.ReassignedIds = append(res.ReassignedIds, t.Id)
+			task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventReassigned, constants.TaskEventActorSystem, fmt.Sprintf("node %s deleted", n.Name)))
+			return nil
+		}
+		if err != mongo2.ErrNoDocuments {
+			return err
+		}
+	}
+
+	t.Status = constants.TaskStatusAbnormal
+	t.Error = fmt.Sprintf("node %s deleted", n.Name)
+	if err := delegate.NewModelDelegate(t).Save(); err != nil {
+		return err
+	}
+	res.AbnormalIds = append(res.AbnormalIds, t.Id)
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventError, constants.TaskEventActorSystem, t.Error))
+	return nil
+}
+
+func (ctx *nodeContext) _getNode(c *gin.Context) (n *models.Node, err error) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return nil, err
+	}
+
+	n, err = ctx.modelSvc.GetNodeById(id)
+	if err != nil {
+		HandleErrorNotFound(c, err)
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func newNodeContext() *nodeContext {
+	// context
+	ctx := &nodeContext{}
+
+	// dependency injection
+	c := dig.New()
+	if err := c.Provide(service.GetService); err != nil {
+		panic(err)
+	}
+	if err := c.Provide(scheduler.ProvideGetTaskSchedulerService(config.DefaultConfigPath)); err != nil {
+		panic(err)
+	}
+	if err := c.Provide(admin.NewSpiderAdminService); err != nil {
+		panic(err)
+	}
+	if err := c.Invoke(func(
+		modelSvc service.ModelService,
+		schedulerSvc interfaces.TaskSchedulerService,
+		adminSvc interfaces.SpiderAdminService,
+	) {
+		ctx.modelSvc = modelSvc
+		ctx.schedulerSvc = schedulerSvc
+		ctx.adminSvc = adminSvc
+	}); err != nil {
+		panic(err)
+	}
+
+	return ctx
+}
+
 func newNodeController() *nodeController {
 	modelSvc, err := service.GetService()
 	if err != nil {
 		panic(err)
 	}
 
-	ctr := NewListControllerDelegate(ControllerIdNode, modelSvc.GetBaseService(interfaces.ModelIdNode))
+	ctr := NewListPostActionControllerDelegate(ControllerIdNode, modelSvc.GetBaseService(interfaces.ModelIdNode), getNodeActions())
 
 	return &nodeController{
-		ListControllerDelegate: *ctr,
+		ListActionControllerDelegate: *ctr,
+		modelSvc:                     modelSvc,
 	}
 }
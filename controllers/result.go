@@ -67,15 +67,27 @@ func (ctx *resultContext) getList(c *gin.Context) {
 		}
 	}
 
-	// spider
+	// spider storing results in the collection, any of them if the collection
+	// is shared by spiders
 	sq := bson.M{
 		"col_id":         dc.Id,
 		"data_source_id": ds.Id,
 	}
 	s, err := ctx.modelSvc.GetSpider(sq, nil)
 	if err != nil {
-		HandleErrorInternalServerError(c, err)
-		return
+		if err.Error() != mongo2.ErrNoDocuments.Error() {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+		s, err = ctx.modelSvc.GetSpider(bson.M{"col_id": dc.Id}, nil)
+		if err != nil {
+			if err.Error() == mongo2.ErrNoDocuments.Error() {
+				HandleSuccessWithListData(c, nil, 0)
+				return
+			}
+			HandleErrorInternalServerError(c, err)
+			return
+		}
 	}
 
 	// service
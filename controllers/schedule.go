@@ -13,6 +13,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/dig"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 var ScheduleController *scheduleController
@@ -30,6 +32,16 @@ func getScheduleActions() []Action {
 			Path:        "/:id/disable",
 			HandlerFunc: scheduleCtx.disable,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/next",
+			HandlerFunc: scheduleCtx.getNext,
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/:id/next",
+			HandlerFunc: scheduleCtx.setNext,
+		},
 	}
 }
 
@@ -45,6 +57,14 @@ func (ctr *scheduleController) Post(c *gin.Context) {
 		HandleErrorBadRequest(c, err)
 		return
 	}
+	if err := schedule.ValidateTimezone(s.Timezone); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if err := schedule.ValidateMisfirePolicy(s.MisfirePolicy); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
 	if err := delegate.NewModelDelegate(&s, GetUserFromContext(c)).Add(); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
@@ -74,6 +94,14 @@ func (ctr *scheduleController) Put(c *gin.Context) {
 		HandleErrorBadRequest(c, errors.ErrorHttpBadRequest)
 		return
 	}
+	if err := schedule.ValidateTimezone(s.Timezone); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if err := schedule.ValidateMisfirePolicy(s.MisfirePolicy); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
 	if err := delegate.NewModelDelegate(&s).Save(); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
@@ -165,6 +193,50 @@ func (ctx *scheduleContext) disable(c *gin.Context) {
 	HandleSuccess(c)
 }
 
+func (ctx *scheduleContext) getNext(c *gin.Context) {
+	s, err := ctx._getSchedule(c)
+	if err != nil {
+		return
+	}
+	n, _ := strconv.Atoi(c.Query("n"))
+	if n <= 0 || n > 100 {
+		n = 5
+	}
+	res, err := ctx.scheduleSvc.GetNextRunTs(s, n)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, res)
+}
+
+// setNext overrides the next run of the schedule by a one-off run, or clears
+// the override if next_run_override_ts is empty
+func (ctx *scheduleContext) setNext(c *gin.Context) {
+	s, err := ctx._getSchedule(c)
+	if err != nil {
+		return
+	}
+	var payload struct {
+		NextRunOverrideTs time.Time `json:"next_run_override_ts"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if !payload.NextRunOverrideTs.IsZero() {
+		if err := schedule.ValidateNextRunOverrideTs(payload.NextRunOverrideTs, time.Now()); err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+	}
+	if err := ctx.scheduleSvc.SetNextRunOverride(s, payload.NextRunOverrideTs, GetUserFromContext(c)); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, s)
+}
+
 func (ctx *scheduleContext) _getSchedule(c *gin.Context) (s *models.Schedule, err error) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"github.com/crawlab-team/crawlab-core/config"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/task/scheduler"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+	"net/http"
+)
+
+func getSchedulerActions() []Action {
+	schedulerCtx := newSchedulerContext()
+	return []Action{
+		{
+			Method:      http.MethodGet,
+			Path:        "",
+			HandlerFunc: schedulerCtx.getState,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/pause",
+			HandlerFunc: schedulerCtx.pause,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/resume",
+			HandlerFunc: schedulerCtx.resume,
+		},
+	}
+}
+
+type schedulerContext struct {
+	schedulerSvc interfaces.TaskSchedulerService
+}
+
+// getState returns the pause state of the scheduler
+func (ctx *schedulerContext) getState(c *gin.Context) {
+	HandleSuccessWithData(c, task.GetPauseSettings())
+}
+
+// pause stops dispatching tasks cluster-wide, e.g. for upgrades, so that new
+// tasks are held pending while running tasks are unaffected
+func (ctx *schedulerContext) pause(c *gin.Context) {
+	ctx._setPaused(c, true)
+}
+
+func (ctx *schedulerContext) resume(c *gin.Context) {
+	ctx._setPaused(c, false)
+}
+
+func (ctx *schedulerContext) _setPaused(c *gin.Context, paused bool) {
+	if !RequireAdmin(c) {
+		return
+	}
+
+	var err error
+	if paused {
+		err = ctx.schedulerSvc.Pause(GetUserFromContext(c))
+	} else {
+		err = ctx.schedulerSvc.Resume(GetUserFromContext(c))
+	}
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, task.GetPauseSettings())
+}
+
+func newSchedulerContext() *schedulerContext {
+	// context
+	ctx := &schedulerContext{}
+
+	// dependency injection
+	c := dig.New()
+	if err := c.Provide(scheduler.ProvideGetTaskSchedulerService(config.DefaultConfigPath)); err != nil {
+		panic(err)
+	}
+	if err := c.Invoke(func(schedulerSvc interfaces.TaskSchedulerService) {
+		ctx.schedulerSvc = schedulerSvc
+	}); err != nil {
+		panic(err)
+	}
+
+	return ctx
+}
+
+var SchedulerController ActionController
@@ -1,15 +1,127 @@
 package controllers
 
 import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/inject"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"strings"
 )
 
 var SettingController *settingController
 
+// settingSecretFields are fields of values of settings which are redacted on
+// read, by setting key. Redacted or absent fields keep their stored values on
+// write, so that settings read by the client can be saved back as they are.
+var settingSecretFields = map[string][]string{
+	constants.SettingKeyBlobStore:   {"secret_access_key"},
+	constants.SettingKeyPushgateway: {"password"},
+}
+
+// adminSettingKeys are keys of settings which only admins can write, e.g. the
+// pause state of the scheduler, which is otherwise set by the admin-only
+// scheduler pause and resume
+var adminSettingKeys = map[string]bool{
+	constants.SettingKeyBlobStore:      true,
+	constants.SettingKeyPushgateway:    true,
+	constants.SettingKeySchedulerPause: true,
+}
+
+// redactSetting replaces values of secret fields of the setting with
+// constants.SettingRedactedValue
+func redactSetting(s *models.Setting) {
+	fields := settingSecretFields[s.Key]
+	if len(fields) == 0 {
+		return
+	}
+	redact := func(value bson.M) (res bson.M) {
+		if value == nil {
+			return nil
+		}
+		res = bson.M{}
+		for k, v := range value {
+			res[k] = v
+		}
+		for _, f := range fields {
+			if v, ok := res[f]; ok && v != "" {
+				res[f] = constants.SettingRedactedValue
+			}
+		}
+		return res
+	}
+	s.Value = redact(s.Value)
+	for i := range s.Overrides {
+		s.Overrides[i].Value = redact(s.Overrides[i].Value)
+	}
+}
+
+// restoreSettingSecrets sets secret fields of the value which are redacted or
+// absent to their stored values
+func restoreSettingSecrets(key string, value, stored bson.M) {
+	if value == nil || stored == nil {
+		return
+	}
+	for _, f := range settingSecretFields[key] {
+		if v, ok := value[f]; ok && v != constants.SettingRedactedValue {
+			continue
+		}
+		if v, ok := stored[f]; ok {
+			value[f] = v
+		} else {
+			delete(value, f)
+		}
+	}
+}
+
+// getSettingOverrideKey returns the key identifying an override of a setting
+// by its tags regardless of their order
+func getSettingOverrideKey(tags []string) (key string) {
+	tags2 := append([]string{}, tags...)
+	sort.Strings(tags2)
+	return strings.Join(tags2, ",")
+}
+
+// requireSettingWrite returns whether the user of the request can write the
+// setting, responding with forbidden otherwise
+func requireSettingWrite(c *gin.Context, key string) (ok bool) {
+	if adminSettingKeys[key] {
+		return RequireAdmin(c)
+	}
+	return true
+}
+
+// pushNodeConfigs pushes the runtime config to online worker nodes if the
+// setting of the node config is among the written keys, or the keys are not
+// known, e.g. of bulk writes
+func pushNodeConfigs(keys ...string) {
+	if len(keys) > 0 {
+		ok := false
+		for _, key := range keys {
+			if key == constants.SettingKeyNodeConfig {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return
+		}
+	}
+	if err := inject.GetContainer().Invoke(func(masterSvc interfaces.NodeMasterService) {
+		go masterSvc.PushNodeConfigs()
+	}); err != nil {
+		trace.PrintError(err)
+	}
+}
+
 type settingController struct {
 	ListControllerDelegate
 }
@@ -31,10 +143,59 @@ func (ctr *settingController) Get(c *gin.Context) {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
+	redactSetting(s)
 
 	HandleSuccessWithData(c, s)
 }
 
+func (ctr *settingController) GetList(c *gin.Context) {
+	var l interfaces.List
+	var total int
+	var err error
+	if MustGetFilterAll(c) {
+		l, err = ctr.svc.GetList(nil, &mongo.FindOptions{
+			Sort: bson.D{{"_id", -1}},
+		})
+		if err == nil {
+			total, err = ctr.svc.Count(nil)
+		}
+		if err != nil && err != mongo2.ErrNoDocuments {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+	} else {
+		l, total, err = ctr.getList(c)
+		if err != nil {
+			return
+		}
+	}
+	if settings, ok := l.(*models.SettingList); ok {
+		for i := range *settings {
+			redactSetting(&(*settings)[i])
+		}
+	}
+	HandleSuccessWithListData(c, l, total)
+}
+
+func (ctr *settingController) Post(c *gin.Context) {
+	var s models.Setting
+	if err := c.ShouldBindJSON(&s); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if !requireSettingWrite(c, s.Key) {
+		return
+	}
+	if err := delegate.NewModelDelegate(&s, GetUserFromContext(c)).Add(); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	service.ResetSettingValueCache(s.Key)
+	pushNodeConfigs(s.Key)
+	redactSetting(&s)
+	HandleSuccessWithData(c, s)
+}
+
 func (ctr *settingController) Put(c *gin.Context) {
 	// key
 	key := c.Param("id")
@@ -60,16 +221,90 @@ func (ctr *settingController) Put(c *gin.Context) {
 		return
 	}
 
-	// save
+	// permission
+	if !requireSettingWrite(c, key) {
+		return
+	}
+
+	// save (secrets redacted on read are kept)
+	restoreSettingSecrets(key, s.Value, _s.Value)
 	_s.Value = s.Value
+	if s.Overrides != nil {
+		// overrides are matched by their tags, as they may be reordered,
+		// added or removed
+		stored := map[string]bson.M{}
+		for _, o := range _s.Overrides {
+			stored[getSettingOverrideKey(o.Tags)] = o.Value
+		}
+		for i := range s.Overrides {
+			restoreSettingSecrets(key, s.Overrides[i].Value, stored[getSettingOverrideKey(s.Overrides[i].Tags)])
+		}
+		_s.Overrides = s.Overrides
+	}
 	if err := delegate.NewModelDelegate(_s).Save(); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
+	service.ResetSettingValueCache(key)
+
+	// push node config to online worker nodes
+	pushNodeConfigs(key)
 
 	HandleSuccess(c)
 }
 
+func (ctr *settingController) Delete(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	doc, err := ctr.svc.GetById(id)
+	if err != nil {
+		HandleErrorNotFound(c, err)
+		return
+	}
+	s, ok := doc.(*models.Setting)
+	if ok && !requireSettingWrite(c, s.Key) {
+		return
+	}
+	ctr.ListControllerDelegate.Delete(c)
+	if ok {
+		service.ResetSettingValueCache(s.Key)
+		pushNodeConfigs(s.Key)
+	}
+}
+
+// PostList, PutList and DeleteList write settings in bulk, which is only
+// allowed to admins so that settings of adminSettingKeys are not bypassed
+
+func (ctr *settingController) PostList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	ctr.ListControllerDelegate.PostList(c)
+	service.ResetSettingValueCache()
+	pushNodeConfigs()
+}
+
+func (ctr *settingController) PutList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	ctr.ListControllerDelegate.PutList(c)
+	service.ResetSettingValueCache()
+	pushNodeConfigs()
+}
+
+func (ctr *settingController) DeleteList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	ctr.ListControllerDelegate.DeleteList(c)
+	service.ResetSettingValueCache()
+	pushNodeConfigs()
+}
+
 func newSettingController() *settingController {
 	modelSvc, err := service.GetService()
 	if err != nil {
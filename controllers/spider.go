@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	errors2 "errors"
 	"fmt"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
@@ -11,7 +12,11 @@ import (
 	delegate2 "github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-core/result"
 	"github.com/crawlab-team/crawlab-core/spider/admin"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/task/log"
+	"github.com/crawlab-team/crawlab-core/tracing"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	vcs "github.com/crawlab-team/crawlab-vcs"
@@ -29,7 +34,9 @@ import (
 	"math"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var SpiderController *spiderController
@@ -77,6 +84,11 @@ func getSpiderActions() []Action {
 			Path:        "/:id/files/copy",
 			HandlerFunc: ctx.copyFile,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/files/size",
+			HandlerFunc: ctx.getFilesSize,
+		},
 		{
 			Path:        "/:id/files/export",
 			Method:      http.MethodPost,
@@ -87,6 +99,16 @@ func getSpiderActions() []Action {
 			Path:        "/:id/run",
 			HandlerFunc: ctx.run,
 		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/test-run",
+			HandlerFunc: ctx.testRun,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/validate",
+			HandlerFunc: ctx.validate,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/:id/git",
@@ -112,11 +134,76 @@ func getSpiderActions() []Action {
 			Path:        "/:id/git/commit",
 			HandlerFunc: ctx.gitCommit,
 		},
-		//{
-		//	Method:      http.MethodPost,
-		//	Path:        "/:id/clone",
-		//	HandlerFunc: ctx.clone,
-		//},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/clone",
+			HandlerFunc: ctx.clone,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/enable",
+			HandlerFunc: ctx.enable,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/disable",
+			HandlerFunc: ctx.disable,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/emergency-stop",
+			HandlerFunc: ctx.emergencyStop,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/stale",
+			HandlerFunc: ctx.getStale,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/bulk/run",
+			HandlerFunc: ctx.bulkRun,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/bulk/enable",
+			HandlerFunc: ctx.bulkEnable,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/bulk/disable",
+			HandlerFunc: ctx.bulkDisable,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/field-mappings/test",
+			HandlerFunc: ctx.testFieldMappings,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/tasks",
+			HandlerFunc: ctx.getTasks,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/inputs",
+			HandlerFunc: ctx.getInputs,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/inputs",
+			HandlerFunc: ctx.postInput,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/inputs/:input_id",
+			HandlerFunc: ctx.downloadInput,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/:id/inputs/:input_id",
+			HandlerFunc: ctx.deleteInput,
+		},
 		{
 			Path:        "/:id/data-source",
 			Method:      http.MethodGet,
@@ -130,6 +217,52 @@ func getSpiderActions() []Action {
 	}
 }
 
+// redactSpiderProfiles replaces values of variables of profiles of the spider
+// with constants.SettingRedactedValue on read, as they may hold credentials of
+// the environments. Nodes still read spiders with their variables over grpc.
+func redactSpiderProfiles(s *models.Spider) {
+	for i := range s.Profiles {
+		p := &s.Profiles[i]
+		if len(p.Variables) == 0 {
+			continue
+		}
+		variables := make(map[string]string, len(p.Variables))
+		for key, value := range p.Variables {
+			if value != "" {
+				value = constants.SettingRedactedValue
+			}
+			variables[key] = value
+		}
+		p.Variables = variables
+	}
+}
+
+// restoreSpiderProfileVariables sets variables of profiles of the spider which
+// are redacted to their values in the stored profile of the same name, so that
+// spiders read by the client can be saved back as they are. Redacted variables
+// not stored are removed.
+func restoreSpiderProfileVariables(s, stored *models.Spider) {
+	for i := range s.Profiles {
+		p := &s.Profiles[i]
+		var sp *entity.SpiderProfile
+		if stored != nil {
+			sp = stored.GetProfile(p.Name)
+		}
+		for key, value := range p.Variables {
+			if value != constants.SettingRedactedValue {
+				continue
+			}
+			if sp != nil {
+				if storedValue, ok := sp.Variables[key]; ok {
+					p.Variables[key] = storedValue
+					continue
+				}
+			}
+			delete(p.Variables, key)
+		}
+	}
+}
+
 type spiderController struct {
 	ListActionControllerDelegate
 	d   ListActionControllerDelegate
@@ -145,6 +278,7 @@ func (ctr *spiderController) Post(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	redactSpiderProfiles(s)
 	HandleSuccessWithData(c, s)
 }
 
@@ -153,6 +287,7 @@ func (ctr *spiderController) Put(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	redactSpiderProfiles(s)
 	HandleSuccessWithData(c, s)
 }
 
@@ -166,7 +301,7 @@ func (ctr *spiderController) Delete(c *gin.Context) {
 func (ctr *spiderController) GetList(c *gin.Context) {
 	withStats := c.Query("stats")
 	if withStats == "" {
-		ctr.d.GetList(c)
+		ctr.ctx._getList(c)
 		return
 	}
 	ctr.ctx._getListWithStats(c)
@@ -186,6 +321,7 @@ type spiderContext struct {
 	modelTaskSvc       interfaces.ModelBaseService
 	modelTaskStatSvc   interfaces.ModelBaseService
 	adminSvc           interfaces.SpiderAdminService
+	l                  log.Driver
 }
 
 func (ctx *spiderContext) listDir(c *gin.Context) {
@@ -211,102 +347,598 @@ func (ctx *spiderContext) getFile(c *gin.Context) {
 		return
 	}
 
-	data, err := fsSvc.GetFile(payload.Path)
+	data, err := fsSvc.GetFile(payload.Path)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	data = utils.TrimFileData(data)
+
+	HandleSuccessWithData(c, string(data))
+}
+
+func (ctx *spiderContext) getFileInfo(c *gin.Context) {
+	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodGet)
+	if err != nil {
+		return
+	}
+
+	info, err := fsSvc.GetFileInfo(payload.Path)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, info)
+}
+
+func (ctx *spiderContext) getFilesSize(c *gin.Context) {
+	_, _, fsSvc, err := ctx._processFileRequest(c, http.MethodGet)
+	if err != nil {
+		return
+	}
+
+	size, err := fsSvc.GetSize()
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, entity.FsWorkspaceSize{
+		Size:  size,
+		Quota: fsSvc.GetQuota(),
+	})
+}
+
+func (ctx *spiderContext) saveFile(c *gin.Context) {
+	id, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	if err != nil {
+		return
+	}
+
+	if err := fsSvc.Save(payload.Path, []byte(payload.Data)); err != nil {
+		ctx._handleFsError(c, err)
+		return
+	}
+
+	ctx._syncFiles(id)
+
+	HandleSuccess(c)
+}
+
+func (ctx *spiderContext) saveDir(c *gin.Context) {
+	id, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	if err != nil {
+		return
+	}
+
+	if err := fsSvc.CreateDir(payload.Path); err != nil {
+		ctx._handleFsError(c, err)
+		return
+	}
+
+	ctx._syncFiles(id)
+
+	HandleSuccess(c)
+}
+
+func (ctx *spiderContext) renameFile(c *gin.Context) {
+	id, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	if err != nil {
+		return
+	}
+
+	if err := fsSvc.Rename(payload.Path, payload.NewPath); err != nil {
+		ctx._handleFsError(c, err)
+		return
+	}
+
+	ctx._syncFiles(id)
+
+	HandleSuccess(c)
+}
+
+func (ctx *spiderContext) deleteFile(c *gin.Context) {
+	id, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	if err != nil {
+		return
+	}
+
+	if err := fsSvc.Delete(payload.Path); err != nil {
+		ctx._handleFsError(c, err)
+		return
+	}
+
+	ctx._syncFiles(id)
+
+	HandleSuccess(c)
+}
+
+func (ctx *spiderContext) copyFile(c *gin.Context) {
+	id, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	if err != nil {
+		return
+	}
+
+	if err := fsSvc.Copy(payload.Path, payload.NewPath); err != nil {
+		ctx._handleFsError(c, err)
+		return
+	}
+
+	ctx._syncFiles(id)
+
+	HandleSuccess(c)
+}
+
+func (ctx *spiderContext) run(c *gin.Context) {
+	// spider id
+	id, err := ctx._processActionRequest(c)
+	if err != nil {
+		return
+	}
+
+	// options
+	var opts interfaces.SpiderRunOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	// user
+	if u := GetUserFromContext(c); u != nil {
+		opts.UserId = u.GetId()
+	}
+
+	// trace
+	opts.TraceContext = tracing.Inject(c.Request.Context())
+
+	// schedule
+	taskIds, ok := scheduleIdempotent(c, "spider.run:"+id.Hex(), opts, func() ([]primitive.ObjectID, error) {
+		return ctx.adminSvc.Schedule(id, &opts)
+	})
+	if !ok {
+		return
+	}
+
+	HandleSuccessWithData(c, taskIds)
+}
+
+// testRun runs a test task of the spider on a single node and waits for it to
+// end for up to "task.test.waitTimeout" seconds (60 by default). Result items
+// of the test run are captured and returned instead of being stored, and the
+// task is excluded from the history and stats of the spider.
+func (ctx *spiderContext) testRun(c *gin.Context) {
+	// spider id
+	id, err := ctx._processActionRequest(c)
+	if err != nil {
+		return
+	}
+
+	// options
+	var opts interfaces.SpiderRunOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	opts.Test = true
+	if u := GetUserFromContext(c); u != nil {
+		opts.UserId = u.GetId()
+	}
+
+	// single task
+	switch opts.Mode {
+	case constants.RunTypeAllNodes:
+		opts.Mode = constants.RunTypeRandom
+	case constants.RunTypeSelectedNodes:
+		if len(opts.NodeIds) > 1 {
+			opts.NodeIds = opts.NodeIds[:1]
+		}
+	}
+
+	// schedule
+	taskIds, err := ctx.adminSvc.Schedule(id, &opts)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	if len(taskIds) == 0 {
+		HandleErrorInternalServerError(c, errors.ErrorTaskNotExists)
+		return
+	}
+
+	// wait for the task to end
+	waitTimeout := 60 * time.Second
+	if viper.IsSet("task.test.waitTimeout") {
+		waitTimeout = time.Duration(viper.GetInt("task.test.waitTimeout")) * time.Second
+	}
+	deadline := time.Now().Add(waitTimeout)
+	var res entity.SpiderTestRunResult
+	for {
+		t, err := ctx.modelSvc.GetTaskById(taskIds[0])
+		if err != nil {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+		res.Task = t
+		res.Results = t.TestResults
+		switch t.Status {
+		case constants.TaskStatusFinished, constants.TaskStatusError, constants.TaskStatusCancelled, constants.TaskStatusAbnormal:
+			res.Ended = true
+		}
+		if res.Ended || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+
+	// logs
+	maxLogLines := 1000
+	if viper.IsSet("task.test.maxLogLines") {
+		maxLogLines = viper.GetInt("task.test.maxLogLines")
+	}
+	res.Logs, err = ctx.l.Find(taskIds[0].Hex(), "", 0, maxLogLines)
+	if err != nil {
+		trace.PrintError(err)
+	}
+
+	HandleSuccessWithData(c, res)
+}
+
+func (ctx *spiderContext) enable(c *gin.Context) {
+	ctx._setEnabled(c, true)
+}
+
+func (ctx *spiderContext) disable(c *gin.Context) {
+	ctx._setEnabled(c, false)
+}
+
+// emergencyStop disables the spider and cancels all its running and pending
+// tasks, force by default or soft by the mode of the payload, and returns the
+// result of cancelling each task
+func (ctx *spiderContext) emergencyStop(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// options
+	var opts interfaces.TaskCancelOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if opts.Mode != "" && opts.Mode != constants.TaskCancelModeSoft && opts.Mode != constants.TaskCancelModeForce {
+		HandleErrorBadRequest(c, errors.ErrorControllerRequestPayloadInvalid)
+		return
+	}
+
+	res, err := ctx.adminSvc.EmergencyStop(id, GetUserFromContext(c), &opts)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			HandleErrorNotFound(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, res)
+}
+
+// bulkRun runs each spider selected by entity.SpiderBulkPayload and returns
+// per-spider results. Failures of some spiders do not stop the others.
+func (ctx *spiderContext) bulkRun(c *gin.Context) {
+	var payload entity.SpiderBulkPayload
+	spiders, err := ctx._getBulkSpiders(c, &payload)
+	if err != nil {
+		return
+	}
+
+	// options
+	var opts interfaces.SpiderRunOptions
+	if payload.Options != nil {
+		opts = *payload.Options
+	}
+
+	// user
+	if u := GetUserFromContext(c); u != nil {
+		opts.UserId = u.GetId()
+	}
+
+	// trace
+	opts.TraceContext = tracing.Inject(c.Request.Context())
+
+	var results []entity.SpiderBulkResult
+	for _, s := range spiders {
+		runOpts := opts
+		res := entity.SpiderBulkResult{SpiderId: s.Id, SpiderName: s.Name}
+		taskIds, err := ctx.adminSvc.Schedule(s.Id, &runOpts)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+			res.TaskIds = taskIds
+		}
+		results = append(results, res)
+	}
+
+	HandleSuccessWithData(c, results)
+}
+
+func (ctx *spiderContext) bulkEnable(c *gin.Context) {
+	ctx._bulkSetEnabled(c, true)
+}
+
+func (ctx *spiderContext) bulkDisable(c *gin.Context) {
+	ctx._bulkSetEnabled(c, false)
+}
+
+// getStale returns enabled spiders without a successful task within the
+// threshold (hours), including those created earlier that never succeeded
+func (ctx *spiderContext) getStale(c *gin.Context) {
+	// threshold
+	hours := 24 * 7
+	if h := viper.GetInt("spider.stale.threshold"); h > 0 {
+		hours = h
+	}
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		h, err := strconv.Atoi(hoursStr)
+		if err != nil || h <= 0 {
+			HandleErrorBadRequest(c, errors.ErrorSpiderInvalidStaleThreshold)
+			return
+		}
+		hours = h
+	}
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	query := bson.M{
+		"enabled": true,
+		"$or": []bson.M{
+			{"last_success_ts": bson.M{"$gt": time.Time{}, "$lt": cutoff}},
+			{
+				"last_success_ts": bson.M{"$in": []interface{}{nil, time.Time{}}},
+				"_id":             bson.M{"$lt": primitive.NewObjectIDFromTimestamp(cutoff)},
+			},
+		},
+	}
+	spiders, err := ctx.modelSvc.GetSpiderList(query, &mongo.FindOptions{
+		Sort: bson.D{{"last_success_ts", 1}},
+	})
+	if err != nil && err != mongo2.ErrNoDocuments {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	for i := range spiders {
+		redactSpiderProfiles(&spiders[i])
+	}
+
+	HandleSuccessWithData(c, spiders)
+}
+
+// testFieldMappings previews field mappings on a sample item without storing it
+func (ctx *spiderContext) testFieldMappings(c *gin.Context) {
+	id, err := ctx._processActionRequest(c)
+	if err != nil {
+		return
+	}
+
+	// payload
+	var payload entity.ResultFieldMappingTestPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// field mappings of the spider unless specified
+	mappings := payload.FieldMappings
+	if mappings == nil {
+		s, err := ctx.modelSvc.GetSpiderById(id)
+		if err != nil {
+			if err == mongo2.ErrNoDocuments {
+				HandleErrorNotFound(c, err)
+				return
+			}
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+		mappings = s.FieldMappings
+	}
+
+	// validate
+	if errs := result.ValidateFieldMappings(mappings); len(errs) > 0 {
+		HandleErrorValidation(c, errs)
+		return
+	}
+
+	// transform
+	var res entity.ResultFieldMappingTestResult
+	res.Item, err = result.ApplyFieldMappings(payload.Item, mappings)
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	HandleSuccessWithData(c, res)
+}
+
+func (ctx *spiderContext) validate(c *gin.Context) {
+	// spider id
+	id, err := ctx._processActionRequest(c)
+	if err != nil {
+		return
+	}
+
+	// validate
+	errs := errors.ValidationErrors{}
+	if err := ctx.adminSvc.ValidateSpider(id); err != nil {
+		var ok bool
+		errs, ok = err.(errors.ValidationErrors)
+		if !ok {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+	}
+
+	HandleSuccessWithData(c, errs)
+}
+
+func (ctx *spiderContext) getTasks(c *gin.Context) {
+	// spider id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// pagination
+	p := MustGetPagination(c)
+
+	// filters
+	var params entity.SpiderTaskQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	filters := &interfaces.SpiderTaskFilters{
+		Status:  params.Status,
+		StartTs: params.StartTs,
+		EndTs:   params.EndTs,
+	}
+	if params.NodeId != "" {
+		filters.NodeId, err = primitive.ObjectIDFromHex(params.NodeId)
+		if err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+	}
+
+	// execution history
+	items, total, err := ctx.adminSvc.GetSpiderTasks(id, p.Page, p.Size, filters)
 	if err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
-	data = utils.TrimFileData(data)
 
-	HandleSuccessWithData(c, string(data))
+	HandleSuccessWithListData(c, items, total)
 }
 
-func (ctx *spiderContext) getFileInfo(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodGet)
+func (ctx *spiderContext) getInputs(c *gin.Context) {
+	id, err := ctx._processActionRequest(c)
 	if err != nil {
 		return
 	}
-
-	info, err := fsSvc.GetFileInfo(payload.Path)
+	inputs, err := task.GetSpiderInputs(id)
 	if err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
-
-	HandleSuccessWithData(c, info)
+	HandleSuccessWithData(c, inputs)
 }
 
-func (ctx *spiderContext) saveFile(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+// postInput uploads an input file of the spider in the multipart form field
+// "file", to be passed to tasks run with its id as "input_id"
+func (ctx *spiderContext) postInput(c *gin.Context) {
+	id, err := ctx._processActionRequest(c)
 	if err != nil {
 		return
 	}
-
-	if err := fsSvc.Save(payload.Path, []byte(payload.Data)); err != nil {
+	if _, err := ctx.modelSvc.GetSpiderById(id); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			HandleErrorNotFound(c, err)
+			return
+		}
 		HandleErrorInternalServerError(c, err)
 		return
 	}
 
-	HandleSuccess(c)
-}
-
-func (ctx *spiderContext) saveDir(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	// file
+	fh, err := c.FormFile("file")
 	if err != nil {
+		HandleErrorBadRequest(c, err)
 		return
 	}
-
-	if err := fsSvc.CreateDir(payload.Path); err != nil {
-		HandleErrorInternalServerError(c, err)
+	if fh.Size > task.GetSpiderInputMaxSize() {
+		HandleError(http.StatusRequestEntityTooLarge, c, errors.ErrorTaskInputSizeExceeded)
 		return
 	}
-
-	HandleSuccess(c)
-}
-
-func (ctx *spiderContext) renameFile(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+	f, err := fh.Open()
 	if err != nil {
+		HandleErrorInternalServerError(c, err)
 		return
 	}
+	defer f.Close()
 
-	if err := fsSvc.Rename(payload.Path, payload.NewPath); err != nil {
-		HandleErrorInternalServerError(c, err)
+	// save
+	in, err := task.SaveSpiderInput(id, fh.Filename, fh.Header.Get("Content-Type"), f)
+	if err != nil {
+		switch {
+		case errors2.Is(err, errors.ErrorTaskInputInvalidName):
+			HandleErrorBadRequest(c, err)
+		case errors2.Is(err, errors.ErrorTaskInputInvalidType):
+			HandleError(http.StatusUnsupportedMediaType, c, err)
+		case errors2.Is(err, errors.ErrorTaskInputSizeExceeded):
+			HandleError(http.StatusRequestEntityTooLarge, c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
 		return
 	}
 
-	HandleSuccess(c)
+	HandleSuccessWithData(c, in)
 }
 
-func (ctx *spiderContext) deleteFile(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+func (ctx *spiderContext) downloadInput(c *gin.Context) {
+	in, err := ctx._getInput(c)
 	if err != nil {
 		return
 	}
+	serveBlob(c, task.GetSpiderInputKey(in), in.Filename, in.ContentType, in.Size)
+}
 
-	if err := fsSvc.Delete(payload.Path); err != nil {
+func (ctx *spiderContext) deleteInput(c *gin.Context) {
+	in, err := ctx._getInput(c)
+	if err != nil {
+		return
+	}
+	if err := task.DeleteSpiderInput(in.SpiderId, in.Id); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
-
 	HandleSuccess(c)
 }
 
-func (ctx *spiderContext) copyFile(c *gin.Context) {
-	_, payload, fsSvc, err := ctx._processFileRequest(c, http.MethodPost)
+func (ctx *spiderContext) _getInput(c *gin.Context) (in *task.SpiderInput, err error) {
+	id, err := ctx._processActionRequest(c)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	if err := fsSvc.Copy(payload.Path, payload.NewPath); err != nil {
+	inputId, err := primitive.ObjectIDFromHex(c.Param("input_id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return nil, err
+	}
+	in, err = task.GetSpiderInput(inputId)
+	if err == nil && in.SpiderId != id {
+		err = mongo2.ErrNoDocuments
+	}
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			HandleErrorNotFound(c, err)
+			return nil, err
+		}
 		HandleErrorInternalServerError(c, err)
-		return
+		return nil, err
 	}
-
-	HandleSuccess(c)
+	return in, nil
 }
 
-func (ctx *spiderContext) run(c *gin.Context) {
+func (ctx *spiderContext) clone(c *gin.Context) {
 	// spider id
 	id, err := ctx._processActionRequest(c)
 	if err != nil {
@@ -314,9 +946,9 @@ func (ctx *spiderContext) run(c *gin.Context) {
 	}
 
 	// options
-	var opts interfaces.SpiderRunOptions
-	if err := c.ShouldBindJSON(&opts); err != nil {
-		HandleErrorInternalServerError(c, err)
+	var opts interfaces.SpiderCloneOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
 		return
 	}
 
@@ -325,14 +957,24 @@ func (ctx *spiderContext) run(c *gin.Context) {
 		opts.UserId = u.GetId()
 	}
 
-	// schedule
-	taskIds, err := ctx.adminSvc.Schedule(id, &opts)
+	// clone
+	s, err := ctx.adminSvc.Clone(id, &opts)
 	if err != nil {
-		HandleErrorInternalServerError(c, err)
+		switch {
+		case errors2.Is(err, mongo2.ErrNoDocuments):
+			HandleErrorNotFound(c, err)
+		case errors2.Is(err, errors.ErrorSpiderAlreadyExists):
+			HandleErrorBadRequest(c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
 		return
 	}
+	if s, ok := s.(*models.Spider); ok {
+		redactSpiderProfiles(s)
+	}
 
-	HandleSuccessWithData(c, taskIds)
+	HandleSuccessWithData(c, s)
 }
 
 func (ctx *spiderContext) getGit(c *gin.Context) {
@@ -498,6 +1140,8 @@ func (ctx *spiderContext) gitCheckout(c *gin.Context) {
 		return
 	}
 
+	ctx._syncFiles(id)
+
 	HandleSuccess(c)
 }
 
@@ -525,6 +1169,8 @@ func (ctx *spiderContext) gitPull(c *gin.Context) {
 	// attempt to sync git
 	_ = ctx.adminSvc.SyncGitOne(g)
 
+	ctx._syncFiles(id)
+
 	HandleSuccess(c)
 }
 
@@ -705,19 +1351,41 @@ func (ctx *spiderContext) _get(c *gin.Context) {
 		}
 	}
 
+	// profile variables
+	redactSpiderProfiles(s)
+
 	HandleSuccessWithData(c, s)
 }
 
 func (ctx *spiderContext) _post(c *gin.Context) (s *models.Spider, err error) {
-	// bind
-	s = &models.Spider{}
+	// bind (enabled unless specified)
+	s = &models.Spider{Enabled: true}
 	if err := c.ShouldBindJSON(&s); err != nil {
 		HandleErrorBadRequest(c, err)
 		return nil, err
 	}
 
+	// validate
+	if err := ctx._validate(c, s); err != nil {
+		return nil, err
+	}
+
+	// webhook secret and profile variables
+	if s.Webhook != nil {
+		s.Webhook.ApplySecret(nil)
+	}
+	restoreSpiderProfileVariables(s, nil)
+
 	// upsert data collection
 	if err := ctx._upsertDataCollection(c, s); err != nil {
+		if errs, ok := err.(errors.ValidationErrors); ok {
+			HandleErrorValidation(c, errs)
+			return nil, err
+		}
+		if errors2.Is(err, errors.ErrorResultColNameCollision) {
+			HandleErrorBadRequest(c, err)
+			return nil, err
+		}
 		HandleErrorInternalServerError(c, err)
 		return nil, err
 	}
@@ -741,15 +1409,56 @@ func (ctx *spiderContext) _post(c *gin.Context) (s *models.Spider, err error) {
 }
 
 func (ctx *spiderContext) _put(c *gin.Context) (s *models.Spider, err error) {
-	// bind
-	s = &models.Spider{}
-	if err := c.ShouldBindJSON(&s); err != nil {
+	// bind (enabled is kept unless specified)
+	var payload struct {
+		models.Spider
+		Enabled *bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		HandleErrorBadRequest(c, err)
 		return nil, err
 	}
+	s = &payload.Spider
+	s.Enabled = true
+	if payload.Enabled != nil {
+		s.Enabled = *payload.Enabled
+	}
+
+	// validate
+	if err := ctx._validate(c, s); err != nil {
+		return nil, err
+	}
+
+	// last successful run and emergency stop are only updated by tasks and
+	// the emergency stop
+	if sOld, err := ctx.modelSvc.GetSpiderById(s.Id); err == nil {
+		s.LastSuccessTs = sOld.LastSuccessTs
+		s.EmergencyStopTs = sOld.EmergencyStopTs
+		s.EmergencyStopBy = sOld.EmergencyStopBy
+		if payload.Enabled == nil {
+			s.Enabled = sOld.Enabled
+		}
+		if s.Webhook != nil {
+			s.Webhook.ApplySecret(sOld.Webhook)
+		}
+		restoreSpiderProfileVariables(s, sOld)
+	} else {
+		if s.Webhook != nil {
+			s.Webhook.ApplySecret(nil)
+		}
+		restoreSpiderProfileVariables(s, nil)
+	}
 
 	// upsert data collection
 	if err := ctx._upsertDataCollection(c, s); err != nil {
+		if errs, ok := err.(errors.ValidationErrors); ok {
+			HandleErrorValidation(c, errs)
+			return nil, err
+		}
+		if errors2.Is(err, errors.ErrorResultColNameCollision) {
+			HandleErrorBadRequest(c, err)
+			return nil, err
+		}
 		HandleErrorInternalServerError(c, err)
 		return nil, err
 	}
@@ -821,6 +1530,37 @@ func (ctx *spiderContext) _delete(c *gin.Context) (err error) {
 	return nil
 }
 
+// _getList returns spiders with variables of their profiles redacted
+func (ctx *spiderContext) _getList(c *gin.Context) {
+	d := NewListControllerDelegate(ControllerIdSpider, ctx.modelSvc.GetBaseService(interfaces.ModelIdSpider))
+	var l interfaces.List
+	var total int
+	var err error
+	if MustGetFilterAll(c) {
+		l, err = d.svc.GetList(nil, &mongo.FindOptions{
+			Sort: bson.D{{"_id", -1}},
+		})
+		if err == nil {
+			total, err = d.svc.Count(nil)
+		}
+		if err != nil && err != mongo2.ErrNoDocuments {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+	} else {
+		l, total, err = d.getList(c)
+		if err != nil {
+			return
+		}
+	}
+	if spiders, ok := l.(*models.SpiderList); ok {
+		for i := range *spiders {
+			redactSpiderProfiles(&(*spiders)[i])
+		}
+	}
+	HandleSuccessWithListData(c, l, total)
+}
+
 func (ctx *spiderContext) _getListWithStats(c *gin.Context) {
 	// params
 	pagination := MustGetPagination(c)
@@ -947,6 +1687,9 @@ func (ctx *spiderContext) _getListWithStats(c *gin.Context) {
 			}
 		}
 
+		// profile variables
+		redactSpiderProfiles(s)
+
 		// add to list
 		data = append(data, *s)
 	}
@@ -1044,12 +1787,35 @@ func (ctx *spiderContext) _processFileRequest(c *gin.Context, method string) (id
 	}
 
 	// fs service
-	workspacePath := viper.GetString("workspace")
-	fsSvc = fs2.NewFsServiceV2(filepath.Join(workspacePath, id.Hex()))
+	workspacePath, err := fs2.EnsureSpiderWorkspacePath(id)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	fsSvc = fs2.NewFsServiceV2(workspacePath)
+
+	// workspace quota
+	s, err := ctx.modelSvc.GetSpiderById(id)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	fsSvc.SetQuota(s.WorkspaceQuota)
 
 	return
 }
 
+func (ctx *spiderContext) _handleFsError(c *gin.Context, err error) {
+	switch err {
+	case errors.ErrorFsQuotaExceeded:
+		HandleError(http.StatusRequestEntityTooLarge, c, err)
+	case errors.ErrorFsPathOutsideRoot:
+		HandleErrorBadRequest(c, err)
+	default:
+		HandleErrorInternalServerError(c, err)
+	}
+}
+
 func (ctx *spiderContext) _getFileRequestMultipartPayload(c *gin.Context) (payload entity.FileRequestPayload, err error) {
 	fh, err := c.FormFile("file")
 	if err != nil {
@@ -1079,45 +1845,209 @@ func (ctx *spiderContext) _processActionRequest(c *gin.Context) (id primitive.Ob
 	return
 }
 
-func (ctx *spiderContext) _upsertDataCollection(c *gin.Context, s *models.Spider) (err error) {
-	if s.ColId.IsZero() {
-		// validate
-		if s.ColName == "" {
-			return trace.TraceError(errors.ErrorControllerMissingRequestFields)
+// _setEnabled enables or disables scheduling of tasks of the spider. Tasks of
+// a disabled spider stay pending until the spider is enabled again.
+func (ctx *spiderContext) _setEnabled(c *gin.Context, enabled bool) {
+	id, err := ctx._processActionRequest(c)
+	if err != nil {
+		return
+	}
+	s, err := ctx.modelSvc.GetSpiderById(id)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			HandleErrorNotFound(c, err)
+			return
 		}
-		// no id
-		dc, err := ctx.modelSvc.GetDataCollectionByName(s.ColName, nil)
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	s.SetEnabled(enabled)
+	if err := delegate2.NewModelDelegate(s, GetUserFromContext(c)).Save(); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	redactSpiderProfiles(s)
+	HandleSuccessWithData(c, s)
+}
+
+// _getBulkSpiders binds the payload and returns the spiders selected by it,
+// at most "spider.bulk.maxSize" (100 by default). Errors are handled here.
+func (ctx *spiderContext) _getBulkSpiders(c *gin.Context, payload *entity.SpiderBulkPayload) (spiders []models.Spider, err error) {
+	if err := c.ShouldBindJSON(payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return nil, err
+	}
+
+	// filter query
+	query, err := GetFilterQuery(c)
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return nil, err
+	}
+	if query == nil {
+		query = bson.M{}
+	}
+	if len(query) == 0 && len(payload.Ids) == 0 && len(payload.Tags) == 0 {
+		// never operate on all spiders by accident
+		HandleErrorBadRequest(c, errors.ErrorSpiderBulkEmptySelector)
+		return nil, errors.ErrorSpiderBulkEmptySelector
+	}
+
+	// ids and tags
+	ids := payload.Ids
+	if len(payload.Tags) > 0 {
+		tagSpiderIds, err := ctx._getSpiderIdsByTags(payload.Tags)
 		if err != nil {
-			if err == mongo2.ErrNoDocuments {
-				// not exists, add new
-				dc = &models.DataCollection{Name: s.ColName}
-				if err := delegate2.NewModelDelegate(dc, GetUserFromContext(c)).Add(); err != nil {
-					return err
-				}
-			} else {
-				// error
-				return err
-			}
+			HandleErrorInternalServerError(c, err)
+			return nil, err
+		}
+		if len(payload.Ids) > 0 {
+			ids = intersectObjectIds(payload.Ids, tagSpiderIds)
+		} else {
+			ids = tagSpiderIds
+		}
+	}
+	if len(payload.Ids) > 0 || len(payload.Tags) > 0 {
+		if ids == nil {
+			ids = []primitive.ObjectID{}
 		}
-		s.ColId = dc.Id
+		query["_id"] = bson.M{"$in": ids}
+	}
 
-		// create index
-		_ = mongo.GetMongoCol(dc.Name).CreateIndex(mongo2.IndexModel{Keys: bson.M{constants.TaskKey: 1}})
-		_ = mongo.GetMongoCol(dc.Name).CreateIndex(mongo2.IndexModel{Keys: bson.M{constants.HashKey: 1}})
-	} else {
-		// with id
-		dc, err := ctx.modelSvc.GetDataCollectionById(s.ColId)
+	// batch size
+	maxSize := 100
+	if size := viper.GetInt("spider.bulk.maxSize"); size > 0 {
+		maxSize = size
+	}
+	total, err := ctx.modelSpiderSvc.Count(query)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return nil, err
+	}
+	if total > maxSize {
+		err = fmt.Errorf("%w: %d > %d", errors.ErrorSpiderBulkTooLarge, total, maxSize)
+		HandleErrorBadRequest(c, err)
+		return nil, err
+	}
+
+	spiders, err = ctx.modelSvc.GetSpiderList(query, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		HandleErrorInternalServerError(c, err)
+		return nil, err
+	}
+	return spiders, nil
+}
+
+// _getSpiderIdsByTags returns ids of spiders with any of the tags
+func (ctx *spiderContext) _getSpiderIdsByTags(tagNames []string) (ids []primitive.ObjectID, err error) {
+	tags, err := ctx.modelSvc.GetTagList(bson.M{
+		"col":  interfaces.ModelColNameSpider,
+		"name": bson.M{"$in": tagNames},
+	}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	var tagIds []primitive.ObjectID
+	for _, t := range tags {
+		tagIds = append(tagIds, t.Id)
+	}
+	var artifacts []models.Artifact
+	if err := mongo.GetMongoCol(interfaces.ModelColNameArtifact).Find(bson.M{
+		"_col": interfaces.ModelColNameSpider,
+		"_tid": bson.M{"$in": tagIds},
+	}, nil).All(&artifacts); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	for _, a := range artifacts {
+		ids = append(ids, a.Id)
+	}
+	return ids, nil
+}
+
+// _bulkSetEnabled enables or disables the selected spiders in a single update
+func (ctx *spiderContext) _bulkSetEnabled(c *gin.Context, enabled bool) {
+	var payload entity.SpiderBulkPayload
+	spiders, err := ctx._getBulkSpiders(c, &payload)
+	if err != nil {
+		return
+	}
+	var ids []primitive.ObjectID
+	for _, s := range spiders {
+		ids = append(ids, s.Id)
+	}
+	if len(ids) > 0 {
+		err = ctx.modelSpiderSvc.Update(bson.M{"_id": bson.M{"$in": ids}}, bson.M{"enabled": enabled}, []string{"enabled"}, GetUserFromContext(c))
+	}
+	var results []entity.SpiderBulkResult
+	for _, s := range spiders {
+		res := entity.SpiderBulkResult{SpiderId: s.Id, SpiderName: s.Name, Success: err == nil}
 		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	HandleSuccessWithData(c, results)
+}
+
+func (ctx *spiderContext) _validate(c *gin.Context, s *models.Spider) (err error) {
+	if err := ctx.adminSvc.ValidateSpiderModel(s); err != nil {
+		if errs, ok := err.(errors.ValidationErrors); ok {
+			HandleErrorValidation(c, errs)
+			return err
+		}
+		HandleErrorInternalServerError(c, err)
+		return err
+	}
+	return nil
+}
+
+// _syncFiles notifies worker nodes of changed spider files, errors are only logged
+// as worker nodes sync files before running tasks anyway
+func (ctx *spiderContext) _syncFiles(id primitive.ObjectID) {
+	if err := ctx.adminSvc.SyncFiles(id); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// _upsertDataCollection sets the data collection of the spider given its
+// col_name, which is added if not exists, or "results_<spider id>" if neither
+// col_name nor col_id is set
+func (ctx *spiderContext) _upsertDataCollection(c *gin.Context, s *models.Spider) (err error) {
+	// current data collection, kept unless another name is specified
+	if !s.ColId.IsZero() {
+		dc, err := ctx.modelSvc.GetDataCollectionById(s.ColId)
+		if err != nil && err != mongo2.ErrNoDocuments {
 			return err
 		}
-		s.ColId = dc.Id
+		if err == nil && (s.ColName == "" || s.ColName == dc.Name) {
+			s.ColName = dc.Name
+			return nil
+		}
+	}
+
+	// default
+	if s.ColName == "" {
+		if s.Id.IsZero() {
+			s.Id = primitive.NewObjectID()
+		}
+		s.ColName = result.GetDefaultColName(s.Id)
+	}
+
+	// upsert
+	dc, err := result.GetOrCreateDataCollection(s.ColName, GetUserFromContext(c))
+	if err != nil {
+		return err
 	}
+	s.ColId = dc.Id
+
 	return nil
 }
 
 func (ctx *spiderContext) _getGitIgnore(id primitive.ObjectID) (ignore []string, err error) {
-	workspacePath := viper.GetString("workspace")
-	filePath := filepath.Join(workspacePath, id.Hex(), ".gitignore")
+	filePath := filepath.Join(fs2.GetSpiderWorkspacePath(id), ".gitignore")
 	if !utils.Exists(filePath) {
 		return nil, nil
 	}
@@ -1166,8 +2096,7 @@ func (ctx *spiderContext) _getGitClient(id primitive.ObjectID) (gitClient *vcs.G
 	}
 
 	// git client
-	workspacePath := viper.GetString("workspace")
-	gitClient, err = vcs.NewGitClient(vcs.WithPath(filepath.Join(workspacePath, id.Hex())))
+	gitClient, err = vcs.NewGitClient(vcs.WithPath(fs2.GetSpiderWorkspacePath(id)))
 	if err != nil {
 		return nil, err
 	}
@@ -1317,6 +2246,13 @@ func newSpiderContext() *spiderContext {
 	// model task stat service
 	ctx.modelTaskStatSvc = ctx.modelSvc.GetBaseService(interfaces.ModelIdTaskStat)
 
+	// log driver
+	l, err := log.GetDefaultLogDriver()
+	if err != nil {
+		panic(err)
+	}
+	ctx.l = l
+
 	_spiderCtx = ctx
 
 	return ctx
@@ -1339,3 +2275,17 @@ func newSpiderController() *spiderController {
 		ctx:                          ctx,
 	}
 }
+
+// intersectObjectIds returns the ids in both a and b
+func intersectObjectIds(a, b []primitive.ObjectID) (res []primitive.ObjectID) {
+	m := map[primitive.ObjectID]bool{}
+	for _, id := range b {
+		m[id] = true
+	}
+	for _, id := range a {
+		if m[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
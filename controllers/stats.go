@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	errors2 "errors"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/stats"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/dig"
 	"net/http"
 	"time"
@@ -31,6 +34,11 @@ func getStatsActions() []Action {
 			Path:        "/tasks",
 			HandlerFunc: statsCtx.getTasks,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/spiders/:id/tasks",
+			HandlerFunc: statsCtx.getSpiderTaskTimeSeries,
+		},
 	}
 }
 
@@ -66,6 +74,58 @@ func (svc *statsContext) getTasks(c *gin.Context) {
 	HandleSuccessWithData(c, data)
 }
 
+// getSpiderTaskTimeSeries returns the numbers of tasks of the spider completed
+// in each bucket. Query params: interval ("hour" or "day"), start and end
+// (inclusive dates "YYYY-MM-DD", the last 30 days by default) and tz (IANA time
+// zone of buckets, UTC by default).
+func (svc *statsContext) getSpiderTaskTimeSeries(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	end := today.AddDate(0, 0, 1)
+	if endStr := c.Query("end"); endStr != "" {
+		end, err = time.ParseInLocation("2006-01-02", endStr, loc)
+		if err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -30)
+	if startStr := c.Query("start"); startStr != "" {
+		start, err = time.ParseInLocation("2006-01-02", startStr, loc)
+		if err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+	}
+
+	data, err := svc.statsSvc.GetSpiderTaskTimeSeries(id, c.Query("interval"), start, end)
+	if err != nil {
+		if errors2.Is(err, errors.ErrorStatsInvalidInterval) {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, data)
+}
+
 func newStatsContext() *statsContext {
 	// context
 	ctx := &statsContext{
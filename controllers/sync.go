@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	errors2 "errors"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func getSyncScan(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	files, err := fs.ScanDirectory(fs.GetSpiderWorkspacePath(id))
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	// workspace quota enforced by worker nodes on syncing
+	if modelSvc, err := service.GetService(); err == nil {
+		if s, err := modelSvc.GetSpiderById(id); err == nil && s.WorkspaceQuota > 0 {
+			c.Header(constants.SyncHeaderWorkspaceQuota, strconv.FormatInt(s.WorkspaceQuota, 10))
+		}
+	}
+
+	HandleSuccessWithData(c, files)
+}
+
+func getSyncDownload(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	filePath, err := fs.ResolvePath(fs.GetSpiderWorkspacePath(id), c.Query("path"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			HandleErrorNotFound(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	if !info.Mode().IsRegular() {
+		HandleErrorBadRequest(c, errors.ErrorFsInvalidType)
+		return
+	}
+	c.File(filePath)
+}
+
+// postSyncProgress receives the progress of syncing files of the spider
+// reported by a worker node
+func postSyncProgress(c *gin.Context) {
+	var p entity.FsSyncProgress
+	if err := c.ShouldBindJSON(&p); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if p.NodeKey == "" {
+		HandleErrorBadRequest(c, errors.ErrorGrpcInvalidNodeKey)
+		return
+	}
+	fs.SetSyncProgress(p)
+	HandleSuccess(c)
+}
+
+// postSyncTaskArtifact receives an artifact of the task uploaded by a node,
+// streamed in the request body
+func postSyncTaskArtifact(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	a, err := task.SaveTaskArtifact(id, c.Query("filename"), c.ContentType(), c.Request.Body)
+	if err != nil {
+		switch {
+		case errors2.Is(err, errors.ErrorTaskArtifactInvalidName):
+			HandleErrorBadRequest(c, err)
+		case errors2.Is(err, errors.ErrorTaskArtifactSizeExceeded):
+			HandleError(http.StatusRequestEntityTooLarge, c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
+		return
+	}
+	HandleSuccessWithData(c, a)
+}
+
+// getSyncInput serves an input file to the node running a task with it
+func getSyncInput(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	in, err := task.GetSpiderInput(id)
+	if err != nil {
+		if errors2.Is(err, mongo2.ErrNoDocuments) {
+			HandleErrorNotFound(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	serveBlob(c, task.GetSpiderInputKey(in), in.Filename, in.ContentType, in.Size)
+}
+
+func getSyncActions() []Action {
+	return []Action{
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/scan",
+			HandlerFunc: getSyncScan,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/download",
+			HandlerFunc: getSyncDownload,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/progress",
+			HandlerFunc: postSyncProgress,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/tasks/:id/artifacts",
+			HandlerFunc: postSyncTaskArtifact,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/inputs/:id",
+			HandlerFunc: getSyncInput,
+		},
+	}
+}
+
+var SyncController ActionController
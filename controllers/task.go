@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"compress/gzip"
+	errors2 "errors"
+	"fmt"
 	"github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	delegate2 "github.com/crawlab-team/crawlab-core/models/delegate"
@@ -10,17 +14,23 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/result"
 	"github.com/crawlab-team/crawlab-core/spider/admin"
+	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-core/task/log"
 	"github.com/crawlab-team/crawlab-core/task/scheduler"
+	"github.com/crawlab-team/crawlab-core/tracing"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/generic"
 	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
+	"io"
 	"net/http"
+	"regexp/syntax"
+	"strconv"
 	"strings"
 )
 
@@ -34,6 +44,16 @@ func getTaskActions() []Action {
 			Path:        "/run",
 			HandlerFunc: taskCtx.run,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/dead-letters",
+			HandlerFunc: taskCtx.getDeadLetters,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/requeue",
+			HandlerFunc: taskCtx.requeue,
+		},
 		{
 			Method:      http.MethodPost,
 			Path:        "/:id/restart",
@@ -44,16 +64,46 @@ func getTaskActions() []Action {
 			Path:        "/:id/cancel",
 			HandlerFunc: taskCtx.cancel,
 		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/reassign",
+			HandlerFunc: taskCtx.reassign,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/:id/logs",
 			HandlerFunc: taskCtx.getLogs,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/logs/download",
+			HandlerFunc: taskCtx.downloadLogs,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/logs/query",
+			HandlerFunc: taskCtx.queryLogs,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/:id/data",
 			HandlerFunc: taskCtx.getData,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/events",
+			HandlerFunc: taskCtx.getEvents,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/artifacts",
+			HandlerFunc: taskCtx.getArtifacts,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/artifacts/:artifact_id",
+			HandlerFunc: taskCtx.downloadArtifact,
+		},
 	}
 }
 
@@ -75,12 +125,11 @@ func (ctr *taskController) Delete(c *gin.Context) {
 }
 
 func (ctr *taskController) GetList(c *gin.Context) {
-	withStats := c.Query("stats")
-	if withStats == "" {
+	if MustGetFilterAll(c) {
 		ctr.d.GetList(c)
 		return
 	}
-	ctr.ctx.getListWithStats(c)
+	ctr.ctx.getList(c)
 }
 
 func (ctr *taskController) DeleteList(c *gin.Context) {
@@ -100,12 +149,17 @@ type taskContext struct {
 }
 
 func (ctx *taskContext) run(c *gin.Context) {
-	// task
-	var t models.Task
-	if err := c.ShouldBindJSON(&t); err != nil {
+	// task, with retry settings left to the defaults of the spider if absent
+	var payload struct {
+		models.Task
+		Timeout    *int `json:"timeout"`
+		MaxRetries *int `json:"max_retries"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		HandleErrorBadRequest(c, err)
 		return
 	}
+	t := payload.Task
 
 	// validate spider id
 	if t.GetSpiderId().IsZero() {
@@ -122,11 +176,19 @@ func (ctx *taskContext) run(c *gin.Context) {
 
 	// options
 	opts := &interfaces.SpiderRunOptions{
-		Mode:     t.Mode,
-		NodeIds:  t.NodeIds,
-		Cmd:      t.Cmd,
-		Param:    t.Param,
-		Priority: t.Priority,
+		Mode:         t.Mode,
+		NodeIds:      t.NodeIds,
+		Cmd:          t.Cmd,
+		Param:        t.Param,
+		Priority:     t.Priority,
+		Timeout:      payload.Timeout,
+		MaxRetries:   payload.MaxRetries,
+		MaxItems:     t.MaxItems,
+		NodeTags:     t.NodeTags,
+		Capabilities: t.Capabilities,
+		Profile:      t.Profile,
+		Concurrency:  t.Concurrency,
+		LockKey:      t.LockKey,
 	}
 
 	// user
@@ -134,10 +196,14 @@ func (ctx *taskContext) run(c *gin.Context) {
 		opts.UserId = u.GetId()
 	}
 
+	// trace
+	opts.TraceContext = tracing.Inject(c.Request.Context())
+
 	// run
-	taskIds, err := ctx.adminSvc.Schedule(s.GetId(), opts)
-	if err != nil {
-		HandleErrorInternalServerError(c, err)
+	taskIds, ok := scheduleIdempotent(c, "task.run:"+s.GetId().Hex(), opts, func() ([]primitive.ObjectID, error) {
+		return ctx.adminSvc.Schedule(s.GetId(), opts)
+	})
+	if !ok {
 		return
 	}
 
@@ -159,13 +225,32 @@ func (ctx *taskContext) restart(c *gin.Context) {
 		return
 	}
 
-	// options
+	// options, with the effective config of the restarted task
+	timeout, maxRetries := t.Timeout, t.MaxRetries
 	opts := &interfaces.SpiderRunOptions{
-		Mode:     t.Mode,
-		NodeIds:  t.NodeIds,
-		Cmd:      t.Cmd,
-		Param:    t.Param,
-		Priority: t.Priority,
+		Mode:         t.Mode,
+		NodeIds:      t.NodeIds,
+		Cmd:          t.Cmd,
+		Param:        t.Param,
+		Priority:     t.Priority,
+		Timeout:      &timeout,
+		MaxRetries:   &maxRetries,
+		MaxItems:     t.MaxItems,
+		NodeTags:     t.NodeTags,
+		Capabilities: t.Capabilities,
+		GitRef:       t.GitRef,
+		Profile:      t.Profile,
+		Concurrency:  t.Concurrency,
+		LockKey:      t.LockKey,
+	}
+	if t.GitCommit != "" {
+		// run the same commit as the restarted task
+		opts.GitRef = t.GitCommit
+	}
+
+	// resume from the last checkpoint of the task instead of from scratch
+	if c.Query("resume") != "" {
+		opts.Checkpoint = t.Checkpoint
 	}
 
 	// user
@@ -173,10 +258,26 @@ func (ctx *taskContext) restart(c *gin.Context) {
 		opts.UserId = u.GetId()
 	}
 
+	// trace
+	opts.TraceContext = tracing.Inject(c.Request.Context())
+
 	// run
-	taskIds, err := ctx.adminSvc.Schedule(t.SpiderId, opts)
-	if err != nil {
-		HandleErrorInternalServerError(c, err)
+	taskIds, ok := scheduleIdempotent(c, "task.restart:"+id.Hex(), opts, func() ([]primitive.ObjectID, error) {
+		taskIds, err := ctx.adminSvc.Schedule(t.SpiderId, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// task event
+		var newTaskIds []string
+		for _, taskId := range taskIds {
+			newTaskIds = append(newTaskIds, taskId.Hex())
+		}
+		task.AddTaskEvent(task.NewTaskEvent(id, constants.TaskEventRetried, task.GetUserActor(GetUserFromContext(c)), fmt.Sprintf("restarted as task %s", strings.Join(newTaskIds, ", "))))
+
+		return taskIds, nil
+	})
+	if !ok {
 		return
 	}
 
@@ -204,8 +305,19 @@ func (ctx *taskContext) cancel(c *gin.Context) {
 		return
 	}
 
+	// options
+	var opts interfaces.TaskCancelOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if opts.Mode != "" && opts.Mode != constants.TaskCancelModeSoft && opts.Mode != constants.TaskCancelModeForce {
+		HandleErrorBadRequest(c, errors.ErrorControllerRequestPayloadInvalid)
+		return
+	}
+
 	// cancel
-	if err := ctx.schedulerSvc.Cancel(id, GetUserFromContext(c)); err != nil {
+	if err := ctx.schedulerSvc.Cancel(id, GetUserFromContext(c), &opts); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
@@ -213,6 +325,143 @@ func (ctx *taskContext) cancel(c *gin.Context) {
 	HandleSuccess(c)
 }
 
+func (ctx *taskContext) reassign(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// options
+	var opts interfaces.TaskReassignOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// reassign
+	t, err := ctx.schedulerSvc.ReassignTask(id, opts.NodeKey, GetUserFromContext(c))
+	if err != nil {
+		switch {
+		case errors2.Is(err, errors.ErrorTaskAlreadyFinished),
+			errors2.Is(err, errors.ErrorTaskAlreadyDispatched),
+			errors2.Is(err, errors.ErrorTaskNodeNotEligible):
+			HandleErrorBadRequest(c, err)
+		case errors2.Is(err, errors.ErrorTaskNodeNotFound):
+			HandleErrorNotFound(c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
+		return
+	}
+
+	HandleSuccessWithData(c, t)
+}
+
+// getDeadLetters returns tasks failed after exhausting their retries, which
+// need attention, latest first
+func (ctx *taskContext) getDeadLetters(c *gin.Context) {
+	p := MustGetPagination(c)
+	tasks, total, err := ctx.schedulerSvc.GetDeadLetterTasks((p.Page-1)*p.Size, p.Size)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithListData(c, tasks, total)
+}
+
+// requeue runs again a dead-lettered task with its retries reset
+func (ctx *taskContext) requeue(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// requeue
+	t, err := ctx.schedulerSvc.RequeueDeadLetterTask(id, GetUserFromContext(c))
+	if err != nil {
+		switch {
+		case errors2.Is(err, errors.ErrorTaskNotDeadLettered):
+			HandleErrorBadRequest(c, err)
+		case errors2.Is(err, mongo2.ErrNoDocuments):
+			HandleErrorNotFound(c, err)
+		default:
+			HandleErrorInternalServerError(c, err)
+		}
+		return
+	}
+
+	HandleSuccessWithData(c, t)
+}
+
+func (ctx *taskContext) getEvents(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// events
+	events, err := ctx.modelSvc.GetTaskEventListByTaskId(id)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, events)
+}
+
+func (ctx *taskContext) getArtifacts(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// artifacts
+	artifacts, err := task.GetTaskArtifacts(id)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithData(c, artifacts)
+}
+
+func (ctx *taskContext) downloadArtifact(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// artifact id
+	artifactId, err := primitive.ObjectIDFromHex(c.Param("artifact_id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// artifact
+	a, err := task.GetTaskArtifact(id, artifactId)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			HandleErrorNotFound(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	serveBlob(c, task.GetTaskArtifactKey(a), a.Filename, a.ContentType, a.Size)
+}
+
 func (ctx *taskContext) getLogs(c *gin.Context) {
 	// id
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
@@ -247,14 +496,119 @@ func (ctx *taskContext) getLogs(c *gin.Context) {
 	HandleSuccessWithListData(c, logs, total)
 }
 
-func (ctx *taskContext) getListWithStats(c *gin.Context) {
+// downloadLogs streams the complete log of the task as a text file, gzipped
+// if "gzip" is set. Lines before the index "skip" are omitted, so that an
+// interrupted download of a large log can be resumed.
+func (ctx *taskContext) downloadLogs(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// skip
+	skip := 0
+	if s := c.Query("skip"); s != "" {
+		skip, err = strconv.Atoi(s)
+		if err != nil || skip < 0 {
+			HandleErrorBadRequest(c, errors.ErrorHttpBadRequest)
+			return
+		}
+	}
+
+	// headers
+	fileName := fmt.Sprintf("task_%s.log", id.Hex())
+	var w io.Writer = c.Writer
+	if c.Query("gzip") != "" {
+		c.Header("Content-Type", "application/gzip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.gz", fileName))
+		gw := gzip.NewWriter(c.Writer)
+		defer gw.Close()
+		w = gw
+	} else {
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	}
+	c.Status(http.StatusOK)
+
+	// stream, the status cannot be changed once written
+	if err := ctx.l.Stream(id.Hex(), skip, w); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+func (ctx *taskContext) queryLogs(c *gin.Context) {
+	// id
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// pagination
+	p, err := GetPagination(c)
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
 	// params
+	var params entity.LogQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+
+	// query
+	messages, total, err := ctx.l.Query(&log.Query{
+		Id:      id.Hex(),
+		Level:   params.Level,
+		StartTs: params.StartTs,
+		EndTs:   params.EndTs,
+		Pattern: params.Query,
+		Regex:   params.Regex,
+		Skip:    (p.Page - 1) * p.Size,
+		Limit:   p.Size,
+	})
+	if err != nil {
+		if err == log.ErrNotSupported {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		if _, ok := err.(*syntax.Error); ok {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	HandleSuccessWithListData(c, messages, total)
+}
+
+// getList returns tasks of the page, filtered by spider, node, schedule,
+// status and time range of creation in query params on top of the filter
+// query, with stats if "stats" is set
+func (ctx *taskContext) getList(c *gin.Context) {
+	// params
+	var params entity.TaskListQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
 	pagination := MustGetPagination(c)
-	query := MustGetFilterQuery(c)
+	query, err := getTaskListQuery(MustGetFilterQuery(c), &params)
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
 	sort := MustGetSortOption(c)
+	withStats := c.Query("stats") != ""
+	light := params.View == constants.TaskListViewLight
 
 	// get list
-	list, err := ctx.modelTaskSvc.GetList(query, &mongo.FindOptions{
+	list, err := getListWithContext(c, ctx.modelTaskSvc, query, &mongo.FindOptions{
 		Sort:  sort,
 		Skip:  pagination.Size * (pagination.Page - 1),
 		Limit: pagination.Size,
@@ -274,36 +628,35 @@ func (ctx *taskContext) getListWithStats(c *gin.Context) {
 		return
 	}
 
-	// ids
-	var ids []primitive.ObjectID
-	for _, d := range list.GetModels() {
-		t := d.(interfaces.Model)
-		ids = append(ids, t.GetId())
-	}
-
 	// total count
-	total, err := ctx.modelTaskSvc.Count(query)
+	total, err := countWithContext(c, ctx.modelTaskSvc, query)
 	if err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
 
-	// stat list
-	query = bson.M{
-		"_id": bson.M{
-			"$in": ids,
-		},
-	}
-	stats, err := ctx.modelSvc.GetTaskStatList(query, nil)
-	if err != nil {
-		HandleErrorInternalServerError(c, err)
-		return
+	// ids
+	var ids []primitive.ObjectID
+	for _, d := range list.GetModels() {
+		t := d.(interfaces.Model)
+		ids = append(ids, t.GetId())
 	}
 
 	// cache stat list to dict
 	dict := map[primitive.ObjectID]models.TaskStat{}
-	for _, s := range stats {
-		dict[s.GetId()] = s
+	if withStats {
+		stats, err := ctx.modelSvc.GetTaskStatList(bson.M{
+			"_id": bson.M{
+				"$in": ids,
+			},
+		}, nil)
+		if err != nil {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+		for _, s := range stats {
+			dict[s.GetId()] = s
+		}
 	}
 
 	// iterate list again
@@ -314,6 +667,9 @@ func (ctx *taskContext) getListWithStats(c *gin.Context) {
 		if ok {
 			t.Stat = &s
 		}
+		if light {
+			setTaskLightView(t)
+		}
 		data = append(data, *t)
 	}
 
@@ -321,6 +677,57 @@ func (ctx *taskContext) getListWithStats(c *gin.Context) {
 	HandleSuccessWithListData(c, data, total)
 }
 
+// getTaskListQuery merges filters of query params into the filter query
+func getTaskListQuery(query bson.M, params *entity.TaskListQueryParams) (q bson.M, err error) {
+	q = bson.M{}
+	for k, v := range query {
+		q[k] = v
+	}
+	for key, value := range map[string]string{
+		"spider_id":   params.SpiderId,
+		"node_id":     params.NodeId,
+		"schedule_id": params.ScheduleId,
+	} {
+		if value == "" {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, err
+		}
+		q[key] = id
+	}
+	if params.Status != "" {
+		q["status"] = bson.M{"$in": strings.Split(params.Status, ",")}
+	}
+	createTs := bson.M{}
+	if !params.StartTs.IsZero() {
+		createTs["$gte"] = params.StartTs
+	}
+	if !params.EndTs.IsZero() {
+		createTs["$lt"] = params.EndTs
+	}
+	if len(createTs) > 0 {
+		q["create_ts"] = createTs
+	}
+	if params.Test {
+		q["test"] = true
+	} else {
+		q["test"] = bson.M{"$ne": true}
+	}
+	return q, nil
+}
+
+// setTaskLightView clears large fields of the task for the light view
+func setTaskLightView(t *models.Task) {
+	t.Cmd = ""
+	t.Param = ""
+	t.Checkpoint = ""
+	t.NodeIds = nil
+	t.NodeTags = nil
+	t.Capabilities = nil
+}
+
 func (ctx *taskContext) getWithStatsSpider(c *gin.Context) {
 	// id
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
@@ -343,8 +750,12 @@ func (ctx *taskContext) getWithStatsSpider(c *gin.Context) {
 	// spider
 	t.Spider, _ = ctx.modelSvc.GetSpiderById(t.SpiderId)
 
-	// skip if task status is pending
+	// queue position and estimated wait if task status is pending
 	if t.Status == constants.TaskStatusPending {
+		t.Queue, err = ctx.schedulerSvc.GetQueueEstimate(t.Id)
+		if err != nil {
+			trace.PrintError(err)
+		}
 		HandleSuccessWithData(c, t)
 		return
 	}
@@ -447,6 +858,12 @@ func (ctx *taskContext) _delete(c *gin.Context) (err error) {
 		return err
 	}
 
+	// delete task artifacts
+	if err := task.DeleteTaskArtifacts(oid); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -482,6 +899,12 @@ func (ctx *taskContext) _deleteList(c *gin.Context) (err error) {
 		return err
 	}
 
+	// delete task artifacts
+	if err := task.DeleteTaskArtifacts(payload.Ids...); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -519,7 +942,7 @@ func newTaskContext() *taskContext {
 	ctx.modelTaskStatSvc = ctx.modelSvc.GetBaseService(interfaces.ModelIdTaskStat)
 
 	// log driver
-	l, err := log.GetLogDriver(log.DriverTypeFile)
+	l, err := log.GetDefaultLogDriver()
 	if err != nil {
 		panic(err)
 	}
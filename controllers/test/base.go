@@ -54,7 +54,7 @@ func (t *Test) NewExpect(t2 *testing.T) (e *httpexpect.Expect) {
 		"username": t.TestUsername,
 		"password": t.TestPassword,
 	}).Expect().JSON().Object()
-	t.TestToken = res.Path("$.data").String().Raw()
+	t.TestToken = res.Path("$.data.token").String().Raw()
 	require.NotEmpty(t2, t.TestToken)
 	return e
 }
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestSettingController_Secrets(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add setting with secret
+	res := T.WithAuth(e.POST("/settings")).
+		WithJSON(map[string]interface{}{
+			"key": constants.SettingKeyBlobStore,
+			"value": map[string]interface{}{
+				"type":              constants.BlobStoreTypeS3,
+				"access_key_id":     "id",
+				"secret_access_key": "secret",
+			},
+		}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.value.secret_access_key").Equal(constants.SettingRedactedValue)
+
+	// secret redacted on read
+	res = T.WithAuth(e.GET("/settings/" + constants.SettingKeyBlobStore)).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.value.access_key_id").Equal("id")
+	res.Path("$.data.value.secret_access_key").Equal(constants.SettingRedactedValue)
+
+	// redacted secret kept on write
+	T.WithAuth(e.PUT("/settings/" + constants.SettingKeyBlobStore)).
+		WithJSON(map[string]interface{}{
+			"value": map[string]interface{}{
+				"type":              constants.BlobStoreTypeS3,
+				"access_key_id":     "id2",
+				"secret_access_key": constants.SettingRedactedValue,
+			},
+		}).
+		Expect().Status(http.StatusOK)
+	s, err := T.modelSvc.GetSettingByKey(constants.SettingKeyBlobStore, nil)
+	require.Nil(t, err)
+	require.Equal(t, "id2", s.Value["access_key_id"])
+	require.Equal(t, "secret", s.Value["secret_access_key"])
+}
+
+func TestSettingController_PushgatewayPassword(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	T.WithAuth(e.POST("/settings")).
+		WithJSON(map[string]interface{}{
+			"key": constants.SettingKeyPushgateway,
+			"value": map[string]interface{}{
+				"enabled":  false,
+				"username": "user",
+				"password": "pass",
+			},
+		}).
+		Expect().Status(http.StatusOK)
+
+	// password redacted in the list
+	res := T.WithAuth(e.GET("/settings")).
+		WithQuery("all", true).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	for _, item := range res.Path("$.data").Array().Iter() {
+		if item.Object().Value("key").String().Raw() == constants.SettingKeyPushgateway {
+			item.Object().Path("$.value.password").Equal(constants.SettingRedactedValue)
+		}
+	}
+
+	// password kept if absent on write
+	T.WithAuth(e.PUT("/settings/" + constants.SettingKeyPushgateway)).
+		WithJSON(map[string]interface{}{
+			"value": map[string]interface{}{
+				"enabled":  false,
+				"username": "user2",
+			},
+		}).
+		Expect().Status(http.StatusOK)
+	s, err := T.modelSvc.GetSettingByKey(constants.SettingKeyPushgateway, nil)
+	require.Nil(t, err)
+	require.Equal(t, "pass", s.Value["password"])
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
@@ -93,6 +94,107 @@ func TestSpiderController_Delete(t *testing.T) {
 	require.Zero(t, taskStatCount)
 }
 
+func TestSpiderController_Put(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add disabled spider
+	res := T.WithAuth(e.POST("/spiders")).
+		WithJSON(map[string]interface{}{"name": "test spider", "enabled": false}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	id := res.Path("$.data._id").String().Raw()
+	res.Path("$.data.enabled").Boolean().False()
+
+	// update without enabled keeps it disabled
+	T.WithAuth(e.PUT("/spiders/" + id)).
+		WithJSON(map[string]interface{}{"_id": id, "name": "test spider 2"}).
+		Expect().Status(http.StatusOK)
+	res = T.WithAuth(e.GET("/spiders/" + id)).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.name").String().Equal("test spider 2")
+	res.Path("$.data.enabled").Boolean().False()
+
+	// update with enabled
+	T.WithAuth(e.PUT("/spiders/" + id)).
+		WithJSON(map[string]interface{}{"_id": id, "name": "test spider 2", "enabled": true}).
+		Expect().Status(http.StatusOK)
+	res = T.WithAuth(e.GET("/spiders/" + id)).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.enabled").Boolean().True()
+}
+
+func TestSpiderController_PutWebhookSecret(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add spider with webhook secret, which is not returned
+	webhook := map[string]interface{}{"url": "https://example.com/hook", "secret": "s3cret"}
+	res := T.WithAuth(e.POST("/spiders")).
+		WithJSON(map[string]interface{}{"name": "test spider", "webhook": webhook}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	id := res.Path("$.data._id").String().Raw()
+	res.Path("$.data.webhook").Object().NotContainsKey("secret")
+	oid, err := primitive.ObjectIDFromHex(id)
+	require.Nil(t, err)
+	s, err := T.modelSvc.GetSpiderById(oid)
+	require.Nil(t, err)
+	require.Equal(t, "s3cret", s.Webhook.Secret)
+
+	// update without secret keeps it
+	T.WithAuth(e.PUT("/spiders/" + id)).
+		WithJSON(map[string]interface{}{"_id": id, "name": "test spider", "webhook": map[string]interface{}{"url": "https://example.com/hook2"}}).
+		Expect().Status(http.StatusOK)
+	s, err = T.modelSvc.GetSpiderById(oid)
+	require.Nil(t, err)
+	require.Equal(t, "https://example.com/hook2", s.Webhook.Url)
+	require.Equal(t, "s3cret", s.Webhook.Secret)
+
+	// update with empty secret removes it
+	T.WithAuth(e.PUT("/spiders/" + id)).
+		WithJSON(map[string]interface{}{"_id": id, "name": "test spider", "webhook": map[string]interface{}{"url": "https://example.com/hook2", "secret": ""}}).
+		Expect().Status(http.StatusOK)
+	s, err = T.modelSvc.GetSpiderById(oid)
+	require.Nil(t, err)
+	require.Empty(t, s.Webhook.Secret)
+}
+
+func TestSpiderController_PutProfileVariables(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add spider with profile variables, which are redacted
+	profiles := []map[string]interface{}{
+		{"name": "prod", "variables": map[string]string{"DB_PASSWORD": "s3cret", "EMPTY": ""}},
+	}
+	res := T.WithAuth(e.POST("/spiders")).
+		WithJSON(map[string]interface{}{"name": "test spider", "profiles": profiles}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	id := res.Path("$.data._id").String().Raw()
+	res.Path("$.data.profiles[0].variables.DB_PASSWORD").String().Equal(constants.SettingRedactedValue)
+	res.Path("$.data.profiles[0].variables.EMPTY").String().Equal("")
+	res = T.WithAuth(e.GET("/spiders/" + id)).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.profiles[0].variables.DB_PASSWORD").String().Equal(constants.SettingRedactedValue)
+
+	// saving back redacted variables keeps them, new ones are added
+	oid, err := primitive.ObjectIDFromHex(id)
+	require.Nil(t, err)
+	T.WithAuth(e.PUT("/spiders/" + id)).
+		WithJSON(map[string]interface{}{"_id": id, "name": "test spider", "profiles": []map[string]interface{}{
+			{"name": "prod", "variables": map[string]string{"DB_PASSWORD": constants.SettingRedactedValue, "DB_HOST": "db", "UNKNOWN": constants.SettingRedactedValue}},
+		}}).
+		Expect().Status(http.StatusOK)
+	s, err := T.modelSvc.GetSpiderById(oid)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"DB_PASSWORD": "s3cret", "DB_HOST": "db"}, s.GetProfileVariables("prod"))
+}
+
 func TestSpiderController_DeleteList(t *testing.T) {
 	T.Setup(t)
 	e := T.NewExpect(t)
@@ -181,3 +283,109 @@ func TestSpiderController_DeleteList(t *testing.T) {
 	require.Nil(t, err)
 	require.Zero(t, taskStatCount)
 }
+
+func TestSpiderController_Clone(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add spider
+	res := T.WithAuth(e.POST("/spiders")).
+		WithJSON(models.Spider{Name: "test clone spider", ColName: "test clone col"}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	id := res.Path("$.data._id").String().Raw()
+
+	// clone with name
+	res = T.WithAuth(e.POST("/spiders/" + id + "/clone")).
+		WithJSON(map[string]string{"name": "test cloned spider"}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.name").Equal("test cloned spider")
+	cloneId := res.Path("$.data._id").String().Raw()
+	require.NotEqual(t, id, cloneId)
+	cloneOid, err := primitive.ObjectIDFromHex(cloneId)
+	require.Nil(t, err)
+	spiderStatCount, err := service.NewBaseService(interfaces.ModelIdSpiderStat).Count(bson.M{"_id": cloneOid})
+	require.Nil(t, err)
+	require.Equal(t, 1, spiderStatCount)
+
+	// clone with default name
+	T.WithAuth(e.POST("/spiders/" + id + "/clone")).
+		Expect().Status(http.StatusOK).
+		JSON().Object().
+		Path("$.data.name").Equal("test clone spider (copy)")
+
+	// name taken, nothing cloned
+	spiderSvc := service.NewBaseService(interfaces.ModelIdSpider)
+	spiderCount, err := spiderSvc.Count(bson.M{})
+	require.Nil(t, err)
+	T.WithAuth(e.POST("/spiders/" + id + "/clone")).
+		WithJSON(map[string]string{"name": "test cloned spider"}).
+		Expect().Status(http.StatusBadRequest)
+	spiderCount2, err := spiderSvc.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, spiderCount, spiderCount2)
+
+	// source not found
+	T.WithAuth(e.POST("/spiders/" + primitive.NewObjectID().Hex() + "/clone")).
+		Expect().Status(http.StatusNotFound)
+}
+
+func TestSpiderController_EmergencyStop(t *testing.T) {
+	T.Setup(t)
+	e := T.NewExpect(t)
+
+	// add spider with pending, running and finished tasks
+	res := T.WithAuth(e.POST("/spiders")).
+		WithJSON(models.Spider{Name: "test emergency stop spider", ColName: "test emergency stop col"}).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	id, err := primitive.ObjectIDFromHex(res.Path("$.data._id").String().Raw())
+	require.Nil(t, err)
+	tasks := map[string]*models.Task{
+		constants.TaskStatusPending:  {Id: primitive.NewObjectID(), SpiderId: id, Status: constants.TaskStatusPending},
+		constants.TaskStatusRunning:  {Id: primitive.NewObjectID(), SpiderId: id, Status: constants.TaskStatusRunning},
+		constants.TaskStatusFinished: {Id: primitive.NewObjectID(), SpiderId: id, Status: constants.TaskStatusFinished},
+	}
+	for _, task := range tasks {
+		require.Nil(t, delegate.NewModelDelegate(task).Add())
+	}
+	pendingId := tasks[constants.TaskStatusPending].Id
+	require.Nil(t, delegate.NewModelDelegate(&models.TaskQueueItem{Id: pendingId, SpiderId: id}).Add())
+
+	// invalid mode
+	T.WithAuth(e.POST("/spiders/" + id.Hex() + "/emergency-stop")).
+		WithJSON(map[string]string{"mode": "unknown"}).
+		Expect().Status(http.StatusBadRequest)
+
+	// pending and running tasks cancelled
+	res = T.WithAuth(e.POST("/spiders/" + id.Hex() + "/emergency-stop")).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	res.Path("$.data.tasks").Array().Length().Equal(2)
+	res.Path("$.data.tasks[0].success").Boolean().True()
+	res.Path("$.data.tasks[1].success").Boolean().True()
+	for status, task := range tasks {
+		task, err := T.modelSvc.GetTaskById(task.Id)
+		require.Nil(t, err)
+		if status == constants.TaskStatusFinished {
+			require.Equal(t, constants.TaskStatusFinished, task.Status)
+		} else {
+			require.Equal(t, constants.TaskStatusCancelled, task.Status)
+		}
+	}
+	_, err = T.modelSvc.GetTaskQueueItemById(pendingId)
+	require.NotNil(t, err)
+
+	// spider disabled with the user and the time of the stop
+	s, err := T.modelSvc.GetSpiderById(id)
+	require.Nil(t, err)
+	require.False(t, s.Enabled)
+	require.False(t, s.EmergencyStopTs.IsZero())
+	require.False(t, s.EmergencyStopBy.IsZero())
+	res.Path("$.data.stop_by").String().Equal(s.EmergencyStopBy.Hex())
+
+	// not found
+	T.WithAuth(e.POST("/spiders/" + primitive.NewObjectID().Hex() + "/emergency-stop")).
+		Expect().Status(http.StatusNotFound)
+}
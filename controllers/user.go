@@ -6,7 +6,6 @@ import (
 	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
-	delegate2 "github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/user"
@@ -29,6 +28,11 @@ func getUserActions() []Action {
 			Path:        "/:id/change-password",
 			HandlerFunc: userCtx.changePassword,
 		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/reset-password",
+			HandlerFunc: userCtx.resetPassword,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/me",
@@ -49,6 +53,9 @@ type userController struct {
 }
 
 func (ctr *userController) Post(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
 	var u models.User
 	if err := c.ShouldBindJSON(&u); err != nil {
 		HandleErrorBadRequest(c, err)
@@ -59,7 +66,61 @@ func (ctr *userController) Post(c *gin.Context) {
 		Password: u.Password,
 		Email:    u.Email,
 		Role:     u.Role,
-	}); err != nil {
+		Roles:    u.Roles,
+	}, GetUserFromContext(c)); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccess(c)
+}
+
+func (ctr *userController) Put(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	// fields absent in the payload are left unchanged
+	var payload struct {
+		Email    *string  `json:"email"`
+		Role     string   `json:"role"`
+		Roles    []string `json:"roles"`
+		Password string   `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if err := ctr.ctx.userSvc.Update(id, &interfaces.UserUpdateOptions{
+		Email: payload.Email,
+		Role:  payload.Role,
+		Roles: payload.Roles,
+	}, GetUserFromContext(c)); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	if payload.Password != "" {
+		if err := ctr.ctx.userSvc.ChangePassword(id, payload.Password, GetUserFromContext(c)); err != nil {
+			HandleErrorBadRequest(c, err)
+			return
+		}
+	}
+	HandleSuccess(c)
+}
+
+func (ctr *userController) Delete(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	if err := ctr.ctx.userSvc.Delete(id, GetUserFromContext(c)); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
@@ -67,6 +128,9 @@ func (ctr *userController) Post(c *gin.Context) {
 }
 
 func (ctr *userController) PostList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
 	// users
 	var users []models.User
 	if err := c.ShouldBindJSON(&users); err != nil {
@@ -80,7 +144,8 @@ func (ctr *userController) PostList(c *gin.Context) {
 			Password: u.Password,
 			Email:    u.Email,
 			Role:     u.Role,
-		}); err != nil {
+			Roles:    u.Roles,
+		}, GetUserFromContext(c)); err != nil {
 			trace.PrintError(err)
 		}
 	}
@@ -89,6 +154,9 @@ func (ctr *userController) PostList(c *gin.Context) {
 }
 
 func (ctr *userController) PutList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
 	// payload
 	var payload entity.BatchRequestPayloadWithStringData
 	if err := c.ShouldBindJSON(&payload); err != nil {
@@ -103,6 +171,15 @@ func (ctr *userController) PutList(c *gin.Context) {
 		return
 	}
 
+	// password hash is never updated directly
+	var fields []string
+	for _, f := range payload.Fields {
+		if f == "password" || f == "password_hash" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
 	// query
 	query := bson.M{
 		"_id": bson.M{
@@ -111,7 +188,7 @@ func (ctr *userController) PutList(c *gin.Context) {
 	}
 
 	// update users
-	if err := ctr.ctx.modelSvc.GetBaseService(interfaces.ModelIdUser).UpdateDoc(query, &doc, payload.Fields); err != nil {
+	if err := ctr.ctx.modelSvc.GetBaseService(interfaces.ModelIdUser).UpdateDoc(query, &doc, fields); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
@@ -119,7 +196,7 @@ func (ctr *userController) PutList(c *gin.Context) {
 	// update passwords
 	if utils.Contains(payload.Fields, "password") {
 		for _, id := range payload.Ids {
-			if err := ctr.ctx.userSvc.ChangePassword(id, doc.Password); err != nil {
+			if err := ctr.ctx.userSvc.ChangePassword(id, doc.Password, GetUserFromContext(c)); err != nil {
 				trace.PrintError(err)
 			}
 		}
@@ -128,6 +205,24 @@ func (ctr *userController) PutList(c *gin.Context) {
 	HandleSuccess(c)
 }
 
+func (ctr *userController) DeleteList(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	payload, err := NewJsonBinder(ControllerIdUser).BindBatchRequestPayload(c)
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	for _, id := range payload.Ids {
+		if err := ctr.ctx.userSvc.Delete(id, GetUserFromContext(c)); err != nil {
+			HandleErrorInternalServerError(c, err)
+			return
+		}
+	}
+	HandleSuccess(c)
+}
+
 type userContext struct {
 	modelSvc service.ModelService
 	userSvc  interfaces.UserService
@@ -139,6 +234,12 @@ func (ctx *userContext) changePassword(c *gin.Context) {
 		HandleErrorBadRequest(c, err)
 		return
 	}
+	// users change their own password, or admins that of others
+	if u := GetUserFromContext(c); u == nil || u.GetId() != id {
+		if !RequireAdmin(c) {
+			return
+		}
+	}
 	var payload map[string]string
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		HandleErrorBadRequest(c, err)
@@ -149,17 +250,34 @@ func (ctx *userContext) changePassword(c *gin.Context) {
 		HandleErrorBadRequest(c, errors.ErrorUserMissingRequiredFields)
 		return
 	}
-	if len(password) < 5 {
-		HandleErrorBadRequest(c, errors.ErrorUserInvalidPassword)
+	if err := ctx.userSvc.ValidatePassword(password); err != nil {
+		HandleErrorBadRequest(c, err)
 		return
 	}
-	if err := ctx.userSvc.ChangePassword(id, password); err != nil {
+	if err := ctx.userSvc.ChangePassword(id, password, GetUserFromContext(c)); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
 	HandleSuccess(c)
 }
 
+func (ctx *userContext) resetPassword(c *gin.Context) {
+	if !RequireAdmin(c) {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		HandleErrorBadRequest(c, err)
+		return
+	}
+	password, err := ctx.userSvc.ResetPassword(id, GetUserFromContext(c))
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	HandleSuccessWithData(c, bson.M{"password": password})
+}
+
 func (ctx *userContext) getMe(c *gin.Context) {
 	u, err := ctx._getMe(c)
 	if err != nil {
@@ -178,8 +296,8 @@ func (ctx *userContext) putMe(c *gin.Context) {
 	}
 
 	// payload
-	doc, err := NewJsonBinder(ControllerIdUser).Bind(c)
-	if err != nil {
+	var doc models.User
+	if err := c.ShouldBindJSON(&doc); err != nil {
 		HandleErrorBadRequest(c, err)
 		return
 	}
@@ -188,8 +306,10 @@ func (ctx *userContext) putMe(c *gin.Context) {
 		return
 	}
 
-	// save to db
-	if err := delegate2.NewModelDelegate(doc, GetUserFromContext(c)).Save(); err != nil {
+	// save to db (role is not changeable by the user itself)
+	if err := ctx.userSvc.Update(u.GetId(), &interfaces.UserUpdateOptions{
+		Email: &doc.Email,
+	}, GetUserFromContext(c)); err != nil {
 		HandleErrorInternalServerError(c, err)
 		return
 	}
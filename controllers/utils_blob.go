@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	errors2 "errors"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/blob"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// serveBlob redirects to the presigned download url of the blob if the blob
+// store supports it, or streams the blob from master otherwise. size is -1 if
+// unknown.
+func serveBlob(c *gin.Context, key, filename, contentType string, size int64) {
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+
+	// presigned url
+	u, err := store.GetDownloadUrl(key, filename)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	if u != "" {
+		c.Redirect(http.StatusFound, u)
+		return
+	}
+
+	// stream from master
+	rc, err := store.Open(key)
+	if err != nil {
+		if errors2.Is(err, errors.ErrorBlobNotExists) {
+			HandleErrorNotFound(c, err)
+			return
+		}
+		HandleErrorInternalServerError(c, err)
+		return
+	}
+	defer rc.Close()
+	c.DataFromReader(http.StatusOK, size, contentType, rc, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=\"%s\"", filename),
+	})
+}
@@ -2,8 +2,13 @@ package controllers
 
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
 )
 
 func GetUserFromContext(c *gin.Context) (u interfaces.User) {
@@ -17,3 +22,40 @@ func GetUserFromContext(c *gin.Context) (u interfaces.User) {
 	}
 	return u
 }
+
+// RequireAdmin aborts with 403 and returns false if the user of the context is not an admin
+func RequireAdmin(c *gin.Context) (ok bool) {
+	if !isAdminUser(GetUserFromContext(c)) {
+		HandleError(http.StatusForbidden, c, errors.ErrorControllerForbidden)
+		return false
+	}
+	return true
+}
+
+// isAdminUser returns whether the user has the admin role, either as its
+// legacy role or among its roles
+func isAdminUser(u interfaces.User) (ok bool) {
+	if u == nil {
+		return false
+	}
+	return u.GetRole() == constants.RoleAdmin || utils.Contains(u.GetRoles(), constants.RoleAdmin)
+}
+
+// getListWithContext gets the list with the context of the request if the
+// model service supports it, so that the query is cancelled along with the
+// request, e.g. on timeout (see middlewares.TimeoutMiddleware)
+func getListWithContext(c *gin.Context, svc interfaces.ModelBaseService, query bson.M, opts *mongo.FindOptions) (l interfaces.List, err error) {
+	if svcCtx, ok := svc.(interfaces.ModelBaseServiceWithContext); ok {
+		return svcCtx.GetListWithContext(c.Request.Context(), query, opts)
+	}
+	return svc.GetList(query, opts)
+}
+
+// countWithContext counts with the context of the request if the model
+// service supports it
+func countWithContext(c *gin.Context, svc interfaces.ModelBaseService, query bson.M) (total int, err error) {
+	if svcCtx, ok := svc.(interfaces.ModelBaseServiceWithContext); ok {
+		return svcCtx.CountWithContext(c.Request.Context(), query)
+	}
+	return svc.Count(query)
+}
@@ -1,14 +1,28 @@
 package controllers
 
 import (
+	"context"
+	errors2 "errors"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/go-trace"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
 func handleError(statusCode int, c *gin.Context, err error, print bool) {
+	// errors of queries cancelled by the request timeout
+	if c.Request != nil && c.Request.Context().Err() == context.DeadlineExceeded {
+		statusCode = http.StatusGatewayTimeout
+		err = errors.ErrorHttpRequestTimeout
+	}
+	// invalid fields, e.g. of models failing validation on add or save
+	var errs errors.ValidationErrors
+	if errors2.As(err, &errs) {
+		HandleErrorValidation(c, errs)
+		return
+	}
 	if print {
 		trace.PrintError(err)
 	}
@@ -47,6 +61,15 @@ func HandleErrorInternalServerError(c *gin.Context, err error) {
 	HandleError(http.StatusInternalServerError, c, err)
 }
 
+func HandleErrorValidation(c *gin.Context, errs errors.ValidationErrors) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, entity.Response{
+		Status:  constants.HttpResponseStatusOk,
+		Message: constants.HttpResponseMessageError,
+		Data:    errs,
+		Error:   errs.Error(),
+	})
+}
+
 func HandleSuccess(c *gin.Context) {
 	c.AbortWithStatusJSON(http.StatusOK, entity.Response{
 		Status:  constants.HttpResponseStatusOk,
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	errors2 "errors"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// scheduleIdempotent runs schedule once per "Idempotency-Key" header of the
+// user. Retries with the same key and payload return the task ids created by
+// the first request instead of creating new tasks. Without the header schedule
+// is always run. Errors are handled here and ok is false if so.
+func scheduleIdempotent(c *gin.Context, scope string, payload interface{}, schedule func() ([]primitive.ObjectID, error)) (taskIds []primitive.ObjectID, ok bool) {
+	key := c.GetHeader(constants.HttpHeaderIdempotencyKey)
+	if key == "" {
+		taskIds, err := schedule()
+		if err != nil {
+			handleScheduleError(c, err)
+			return nil, false
+		}
+		return taskIds, true
+	}
+
+	// keys are scoped by user
+	if u := GetUserFromContext(c); u != nil {
+		key = u.GetId().Hex() + ":" + key
+	}
+
+	hash, err := task.HashIdempotentRequest(scope, payload)
+	if err != nil {
+		HandleErrorInternalServerError(c, err)
+		return nil, false
+	}
+
+	// reserve key or replay
+	taskIds, done, err := task.BeginIdempotentRequest(key, hash)
+	if err != nil {
+		handleScheduleError(c, err)
+		return nil, false
+	}
+	if done {
+		return taskIds, true
+	}
+
+	// release the key unless completed, including on panics
+	completed := false
+	defer func() {
+		if !completed {
+			task.AbortIdempotentRequest(key)
+		}
+	}()
+
+	// schedule
+	taskIds, err = schedule()
+	if err != nil {
+		handleScheduleError(c, err)
+		return nil, false
+	}
+	if err := task.CompleteIdempotentRequest(key, taskIds); err != nil {
+		HandleErrorInternalServerError(c, err)
+		return nil, false
+	}
+	completed = true
+
+	return taskIds, true
+}
+
+func handleScheduleError(c *gin.Context, err error) {
+	if errs, ok := err.(errors.ValidationErrors); ok {
+		HandleErrorValidation(c, errs)
+		return
+	}
+	switch {
+	case errors2.Is(err, errors.ErrorTaskIdempotencyInProgress),
+		errors2.Is(err, errors.ErrorTaskIdempotencyUnknown):
+		HandleError(http.StatusConflict, c, err)
+	case errors2.Is(err, errors.ErrorTaskIdempotencyMismatch):
+		HandleError(http.StatusUnprocessableEntity, c, err)
+	case errors2.Is(err, errors.ErrorTaskNoEligibleNode):
+		// with the unmet node tag or capability
+		HandleErrorBadRequest(c, err)
+	default:
+		HandleErrorInternalServerError(c, err)
+	}
+}
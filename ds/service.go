@@ -1,9 +1,12 @@
 package ds
 
 import (
+	"context"
+	"fmt"
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/constants"
 	constants2 "github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
@@ -12,6 +15,7 @@ import (
 	"github.com/crawlab-team/crawlab-core/utils"
 	utils2 "github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/go-trace"
+	"github.com/upper/db/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
@@ -115,6 +119,33 @@ func (svc *Service) CheckStatus(id primitive.ObjectID) (err error) {
 	return svc.checkStatus(ds, true)
 }
 
+// TestDataSource connects to the data source and runs a trivial query without
+// saving its status. The stored password is used if the password is empty,
+// only if the data source connects to the stored address with the stored
+// username, so that the stored password cannot be sent elsewhere.
+func (svc *Service) TestDataSource(ds interfaces.DataSource) (err error) {
+	_ds, ok := ds.(*models.DataSource)
+	if !ok {
+		return trace.TraceError(errors.ErrorModelInvalidType)
+	}
+	if _ds.Password == "" && !_ds.Id.IsZero() {
+		stored, err := svc.modelSvc.GetDataSourceById(_ds.Id)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return trace.TraceError(errors.ErrorDataSourceNotExists)
+			}
+			return trace.TraceError(err)
+		}
+		if !isSameConnection(_ds, stored) {
+			return trace.TraceError(fmt.Errorf("%w: stored password is only used with the stored host, port and username", errors.ErrorDataSourceMismatch))
+		}
+	}
+	if err := svc.fillPassword(_ds); err != nil {
+		return err
+	}
+	return svc._checkStatus(_ds)
+}
+
 func (svc *Service) SetTimeout(duration time.Duration) {
 	svc.timeout = duration
 }
@@ -162,16 +193,8 @@ func (svc *Service) monitor() (err error) {
 
 func (svc *Service) checkStatus(ds *models.DataSource, save bool) (err error) {
 	// password
-	if ds.Password == "" {
-		pwd, err := svc.modelSvc.GetPasswordById(ds.Id)
-		if err == nil {
-			ds.Password, err = utils.DecryptAES(pwd.Password)
-			if err != nil {
-				return err
-			}
-		} else if err.Error() != mongo.ErrNoDocuments.Error() {
-			return trace.TraceError(err)
-		}
+	if err := svc.fillPassword(ds); err != nil {
+		return err
 	}
 
 	// check status
@@ -191,6 +214,41 @@ func (svc *Service) checkStatus(ds *models.DataSource, save bool) (err error) {
 	return nil
 }
 
+// isSameConnection returns whether the data sources connect to the same
+// address with the same username
+func isSameConnection(ds, ds2 *models.DataSource) (ok bool) {
+	if ds.Host != ds2.Host || ds.Port != ds2.Port || ds.Username != ds2.Username || ds.Url != ds2.Url {
+		return false
+	}
+	if len(ds.Hosts) != len(ds2.Hosts) {
+		return false
+	}
+	for i := range ds.Hosts {
+		if ds.Hosts[i] != ds2.Hosts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fillPassword sets the decrypted stored password of the data source if the
+// password is empty
+func (svc *Service) fillPassword(ds *models.DataSource) (err error) {
+	if ds.Password != "" || ds.Id.IsZero() {
+		return nil
+	}
+	pwd, err := svc.modelSvc.GetPasswordById(ds.Id)
+	if err == nil {
+		ds.Password, err = utils.DecryptAES(pwd.Password)
+		if err != nil {
+			return err
+		}
+	} else if err.Error() != mongo.ErrNoDocuments.Error() {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
 func (svc *Service) _save(ds *models.DataSource) (err error) {
 	log.Debugf("[DataSourceService] saving data source: name=%s, type=%s, status=%s, error=%s", ds.Name, ds.Type, ds.Status, ds.Error)
 	return delegate.NewModelDelegate(ds).Save()
@@ -199,69 +257,86 @@ func (svc *Service) _save(ds *models.DataSource) (err error) {
 func (svc *Service) _checkStatus(ds *models.DataSource) (err error) {
 	switch ds.Type {
 	case constants.DataSourceTypeMongo:
-		_, err := utils2.GetMongoClientWithTimeout(ds, svc.timeout)
+		c, err := utils2.GetMongoClientWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
+		// clients are cached and shared, so they are not disconnected
+		ctx, cancel := context.WithTimeout(context.Background(), svc.timeout)
+		defer cancel()
+		return c.Ping(ctx, nil)
 	case constants.DataSourceTypeMysql:
 		s, err := utils2.GetMysqlSessionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
-		if s != nil {
-			s.Close()
-		}
+		return svc._pingSession(s)
 	case constants.DataSourceTypePostgresql:
 		s, err := utils2.GetPostgresqlSessionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
-		if s != nil {
-			s.Close()
-		}
+		return svc._pingSession(s)
 	case constants.DataSourceTypeMssql:
 		s, err := utils2.GetMssqlSessionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
-		if s != nil {
-			s.Close()
-		}
+		return svc._pingSession(s)
 	case constants.DataSourceTypeSqlite:
 		s, err := utils2.GetSqliteSessionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
-		if s != nil {
-			s.Close()
-		}
+		return svc._pingSession(s)
 	case constants.DataSourceTypeCockroachdb:
 		s, err := utils2.GetCockroachdbSessionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
-		if s != nil {
-			s.Close()
-		}
+		return svc._pingSession(s)
 	case constants.DataSourceTypeElasticSearch:
-		_, err := utils2.GetElasticsearchClientWithTimeout(ds, svc.timeout)
+		c, err := utils2.GetElasticsearchClientWithTimeout(ds, svc.timeout)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), svc.timeout)
+		defer cancel()
+		res, err := c.Info(c.Info.WithContext(ctx))
 		if err != nil {
 			return err
 		}
+		defer res.Body.Close()
+		if res.IsError() {
+			return errors.NewDataSourceError(res.String())
+		}
 	case constants.DataSourceTypeKafka:
 		c, err := utils2.GetKafkaConnectionWithTimeout(ds, svc.timeout)
 		if err != nil {
 			return err
 		}
 		if c != nil {
-			c.Close()
+			defer c.Close()
+			if _, err := c.Brokers(); err != nil {
+				return err
+			}
 		}
 	default:
 		log.Warnf("[DataSourceService] invalid data source type: %s", ds.Type)
+		return errors.ErrorDataSourceInvalidType
 	}
 	return nil
 }
 
+// _pingSession pings the database of the sql session and closes it
+func (svc *Service) _pingSession(s db.Session) (err error) {
+	if s == nil {
+		return nil
+	}
+	defer s.Close()
+	return s.Ping()
+}
+
 func NewDataSourceService(opts ...DataSourceServiceOption) (svc2 interfaces.DataSourceService, err error) {
 	// service
 	svc := &Service{
@@ -0,0 +1,10 @@
+package entity
+
+type LoginResult struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
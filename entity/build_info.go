@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type BuildInfo struct {
+	Version     string    `json:"version"`      // version. e.g. v0.6.3
+	GitCommit   string    `json:"git_commit"`   // git commit of the build
+	BuildTs     string    `json:"build_ts"`     // time of the build
+	GoVersion   string    `json:"go_version"`   // go version of the build. e.g. go1.15
+	Platform    string    `json:"platform"`     // os and arch. e.g. linux/amd64
+	StartTs     time.Time `json:"start_ts"`     // time when the process started
+	Uptime      int64     `json:"uptime"`       // uptime (seconds)
+	OnlineNodes int       `json:"online_nodes"` // number of online nodes
+}
@@ -0,0 +1,7 @@
+package entity
+
+// ConfigUpdateMessage is sent from master to worker nodes to apply changed
+// runtime settings, e.g. {"log.level": "debug"}, without restart
+type ConfigUpdateMessage struct {
+	Settings map[string]string `json:"settings"`
+}
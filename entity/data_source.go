@@ -0,0 +1,8 @@
+package entity
+
+// DataSourceTestResult is the result of testing the connection of a data source
+type DataSourceTestResult struct {
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"` // in millisecond
+}
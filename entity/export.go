@@ -15,6 +15,7 @@ type Export struct {
 	EndTs        time.Time         `json:"end_ts"`
 	FileName     string            `json:"file_name"`
 	DownloadPath string            `json:"-"`
+	BlobKey      string            `json:"-"`
 	Limit        int               `json:"-"`
 }
 
@@ -49,3 +50,11 @@ func (e *Export) GetEndTs() time.Time {
 func (e *Export) GetDownloadPath() string {
 	return e.DownloadPath
 }
+
+func (e *Export) GetFileName() string {
+	return e.FileName
+}
+
+func (e *Export) GetBlobKey() string {
+	return e.BlobKey
+}
@@ -16,6 +16,7 @@ type FsFileInfo struct {
 	Children  []interfaces.FsFileInfo `json:"children"`  // children for sub-directory
 	ModTime   time.Time               `json:"mod_time"`  // modification time
 	Mode      os.FileMode             `json:"mode"`      // file mode
+	Hash      string                  `json:"hash"`      // file content hash (md5), only available when scanned for sync
 }
 
 func (f *FsFileInfo) GetName() string {
@@ -50,6 +51,15 @@ func (f *FsFileInfo) GetMode() os.FileMode {
 	return f.Mode
 }
 
+func (f *FsFileInfo) GetHash() string {
+	return f.Hash
+}
+
 func (f *FsFileInfo) GetChildren() []interfaces.FsFileInfo {
 	return f.Children
 }
+
+type FsWorkspaceSize struct {
+	Size  int64 `json:"size"`  // disk usage of workspace (bytes)
+	Quota int64 `json:"quota"` // max disk usage of workspace (bytes), 0 for unlimited
+}
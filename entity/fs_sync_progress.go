@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// FsSyncProgress is the progress of syncing files of a spider to a worker
+// node, reported by the node to master
+type FsSyncProgress struct {
+	NodeKey    string                            `json:"node_key"`
+	SpiderId   primitive.ObjectID                `json:"spider_id"`
+	Total      int                               `json:"total"`      // number of files to download
+	Downloaded int                               `json:"downloaded"` // number of files downloaded and verified
+	Failed     int                               `json:"failed"`     // number of files failed to download or verify
+	Deleted    int                               `json:"deleted"`    // number of local files removed as they are not on master
+	LastFile   string                            `json:"last_file"`  // path of the last file transferred
+	Finished   bool                              `json:"finished"`
+	Error      string                            `json:"error,omitempty"`
+	StartTs    time.Time                         `json:"start_ts"`
+	UpdateTs   time.Time                         `json:"update_ts"`
+	Files      []interfaces.SpiderSyncFileResult `json:"files,omitempty"` // results of files synced, reported on completion
+}
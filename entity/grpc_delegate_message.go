@@ -10,6 +10,7 @@ type GrpcDelegateMessage struct {
 	ModelId interfaces.ModelId             `json:"id"`
 	Method  interfaces.ModelDelegateMethod `json:"m"`
 	Data    []byte                         `json:"d"`
+	Fields  []byte                         `json:"f,omitempty"` // bson of fields to update of ModelDelegateMethodUpdateFields
 }
 
 func (msg *GrpcDelegateMessage) GetModelId() interfaces.ModelId {
@@ -24,6 +25,10 @@ func (msg *GrpcDelegateMessage) GetData() []byte {
 	return msg.Data
 }
 
+func (msg *GrpcDelegateMessage) GetFields() []byte {
+	return msg.Fields
+}
+
 func (msg *GrpcDelegateMessage) ToBytes() (data []byte) {
 	data, err := json.Marshal(*msg)
 	if err != nil {
@@ -0,0 +1,11 @@
+package entity
+
+import "github.com/crawlab-team/crawlab-core/interfaces"
+
+type Health struct {
+	Status  string                       `json:"status"`
+	Monitor *interfaces.NodeMonitorStats `json:"monitor,omitempty"`
+	Logs    *interfaces.GrpcLogStats     `json:"logs,omitempty"`
+
+	SchedulerPaused bool `json:"scheduler_paused"` // whether dispatching tasks is paused cluster-wide
+}
@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+type LogQueryParams struct {
+	Level   string    `form:"level" url:"level"`
+	StartTs time.Time `form:"start_ts" url:"start_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTs   time.Time `form:"end_ts" url:"end_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+	Query   string    `form:"q" url:"q"`
+	Regex   bool      `form:"regex" url:"regex"`
+}
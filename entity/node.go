@@ -1,5 +1,7 @@
 package entity
 
+import "github.com/crawlab-team/crawlab-core/interfaces"
+
 type NodeInfo struct {
 	Key         string `json:"key"`
 	IsMaster    bool   `json:"is_master"`
@@ -10,6 +12,9 @@ type NodeInfo struct {
 	Description string `json:"description"`
 	AuthKey     string `json:"auth_key"`
 	MaxRunners  int    `json:"max_runners"`
+
+	Capabilities *interfaces.NodeCapabilities `json:"capabilities,omitempty"`
+	Version      string                       `json:"version,omitempty"` // crawlab-core version of the node
 }
 
 func (n NodeInfo) Value() interface{} {
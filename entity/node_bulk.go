@@ -0,0 +1,27 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// NodeBulkDeletePayload selects nodes to delete by ids. Nodes running tasks
+// are refused unless Force is set, in which case their running tasks are
+// marked abnormal.
+type NodeBulkDeletePayload struct {
+	Ids   []primitive.ObjectID `json:"ids"`
+	Force bool                 `json:"force"`
+}
+
+// NodeBulkResult is the result of a bulk operation on a node
+type NodeBulkResult struct {
+	NodeId        primitive.ObjectID   `json:"node_id"`
+	NodeName      string               `json:"node_name"`
+	Success       bool                 `json:"success"`
+	ReassignedIds []primitive.ObjectID `json:"reassigned_ids,omitempty"` // pending tasks unassigned from the node
+	AbnormalIds   []primitive.ObjectID `json:"abnormal_ids,omitempty"`   // running tasks marked abnormal if forced
+	Error         string               `json:"error,omitempty"`
+}
+
+// NodeResyncPayload selects spiders whose files are resynced to a node, all
+// spiders the node may run if empty
+type NodeResyncPayload struct {
+	SpiderIds []primitive.ObjectID `json:"spider_ids"`
+}
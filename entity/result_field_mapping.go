@@ -0,0 +1,23 @@
+package entity
+
+// ResultFieldMapping transforms a field of result items before they are
+// stored. Mappings of a spider are applied in order.
+type ResultFieldMapping struct {
+	Field  string `json:"field" bson:"field"`                       // source field
+	Target string `json:"target,omitempty" bson:"target,omitempty"` // renamed field, empty to keep the name
+	Drop   bool   `json:"drop,omitempty" bson:"drop,omitempty"`     // whether to remove the field
+	Type   string `json:"type,omitempty" bson:"type,omitempty"`     // coerced type (constants.ResultFieldType*), empty to keep the value
+}
+
+// ResultFieldMappingTestPayload previews field mappings on a sample item.
+// Mappings of the spider are used if FieldMappings is nil.
+type ResultFieldMappingTestPayload struct {
+	FieldMappings []ResultFieldMapping `json:"field_mappings"`
+	Item          Result               `json:"item"`
+}
+
+// ResultFieldMappingTestResult is the transformed sample item
+type ResultFieldMappingTestResult struct {
+	Item  Result `json:"item"`
+	Error string `json:"error,omitempty"`
+}
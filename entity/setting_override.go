@@ -0,0 +1,10 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// SettingOverride overrides fields of the value of a setting on nodes having
+// all of its tags, e.g. a higher concurrency on nodes tagged "big"
+type SettingOverride struct {
+	Tags  []string `json:"tags" bson:"tags"`   // names of node tags, the override applies to nodes having all of them
+	Value bson.M   `json:"value" bson:"value"` // fields overriding those of the setting value
+}
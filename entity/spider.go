@@ -1,5 +1,10 @@
 package entity
 
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
 type SpiderType struct {
 	Type  string `json:"type" bson:"_id"`
 	Count int    `json:"count" bson:"count"`
@@ -15,3 +20,16 @@ type ScrapyItem struct {
 	Name   string   `json:"name"`
 	Fields []string `json:"fields"`
 }
+
+// SpiderSyncMessage is sent from master to worker nodes to sync files of the spider
+type SpiderSyncMessage struct {
+	SpiderId primitive.ObjectID `json:"spider_id"`
+}
+
+// SpiderTaskQueryParams are query params of the execution history of a spider
+type SpiderTaskQueryParams struct {
+	Status  string    `form:"status" url:"status"`
+	NodeId  string    `form:"node_id" url:"node_id"`
+	StartTs time.Time `form:"start_ts" url:"start_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTs   time.Time `form:"end_ts" url:"end_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SpiderBulkPayload selects spiders of a bulk operation by ids, tag names and
+// the filter query param "conditions". Selectors are combined with "and".
+type SpiderBulkPayload struct {
+	Ids     []primitive.ObjectID         `json:"ids"`
+	Tags    []string                     `json:"tags"`
+	Options *interfaces.SpiderRunOptions `json:"options"` // run options of the "run" operation
+}
+
+// SpiderBulkResult is the result of a bulk operation on a spider
+type SpiderBulkResult struct {
+	SpiderId   primitive.ObjectID   `json:"spider_id"`
+	SpiderName string               `json:"spider_name"`
+	Success    bool                 `json:"success"`
+	TaskIds    []primitive.ObjectID `json:"task_ids,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
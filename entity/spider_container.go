@@ -0,0 +1,12 @@
+package entity
+
+// SpiderContainer is the docker container of tasks of a spider of the docker
+// runtime (constants.SpiderRuntimeDocker). Empty limits fall back to the
+// config "task.container.*".
+type SpiderContainer struct {
+	Image   string `json:"image" bson:"image"`     // image, e.g. python:3.10
+	Pull    string `json:"pull" bson:"pull"`       // pull policy (constants.ContainerPull*), constants.ContainerPullMissing if empty
+	Memory  string `json:"memory" bson:"memory"`   // memory limit, e.g. 512m, unlimited if empty
+	Cpus    string `json:"cpus" bson:"cpus"`       // cpu limit, e.g. 1.5, unlimited if empty
+	Network string `json:"network" bson:"network"` // network, e.g. host to reach the grpc server of the node, default network of docker if empty
+}
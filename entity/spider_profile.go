@@ -0,0 +1,9 @@
+package entity
+
+// SpiderProfile is an environment of a spider, e.g. dev, staging or prod,
+// overriding the start command and variables of tasks run with it
+type SpiderProfile struct {
+	Name      string            `json:"name" bson:"name"`
+	Cmd       string            `json:"cmd" bson:"cmd"`             // start command, the cmd of the spider if empty
+	Variables map[string]string `json:"variables" bson:"variables"` // environment variables of the process, overriding global ones
+}
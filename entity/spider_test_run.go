@@ -0,0 +1,12 @@
+package entity
+
+import "github.com/crawlab-team/crawlab-core/interfaces"
+
+// SpiderTestRunResult is the result of a test run of a spider, whose result
+// items are captured instead of being stored
+type SpiderTestRunResult struct {
+	Task    interfaces.Task          `json:"task"`
+	Ended   bool                     `json:"ended"`   // whether the task ended within the wait timeout, poll the task otherwise
+	Results []map[string]interface{} `json:"results"` // captured result items
+	Logs    []string                 `json:"logs"`    // first lines of logs
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SpiderWebhook is called with a SpiderWebhookPayload when a task of the spider ends
+type SpiderWebhook struct {
+	Url       string  `json:"url" bson:"url"`                   // http(s) url receiving POST requests
+	Secret    string  `json:"-" bson:"secret"`                  // key signing payloads with HMAC-SHA256, no signature if empty, never returned
+	NewSecret *string `json:"secret,omitempty" bson:"-"`        // write-only secret, the stored one is kept if absent and removed if empty
+	On        string  `json:"on,omitempty" bson:"on,omitempty"` // statuses triggering the webhook (constants.WebhookOn*), all by default
+}
+
+// ApplySecret sets the secret of the webhook to the written secret if any,
+// otherwise to the secret of the stored webhook
+func (wh *SpiderWebhook) ApplySecret(stored *SpiderWebhook) {
+	switch {
+	case wh.NewSecret != nil:
+		wh.Secret = *wh.NewSecret
+	case stored != nil:
+		wh.Secret = stored.Secret
+	}
+	wh.NewSecret = nil
+}
+
+// SpiderWebhookPayload is the summary of an ended task sent to spider webhooks
+type SpiderWebhookPayload struct {
+	Event           string             `json:"event"` // constants.WebhookEventTaskEnded
+	TaskId          primitive.ObjectID `json:"task_id"`
+	SpiderId        primitive.ObjectID `json:"spider_id"`
+	SpiderName      string             `json:"spider_name"`
+	NodeId          primitive.ObjectID `json:"node_id"`
+	Status          string             `json:"status"`
+	Error           string             `json:"error"`
+	ResultCount     int64              `json:"result_count"`
+	ErrorLogCount   int64              `json:"error_log_count"`
+	StartTs         time.Time          `json:"start_ts"`
+	EndTs           time.Time          `json:"end_ts"`
+	RuntimeDuration int64              `json:"runtime_duration"` // in millisecond
+	TotalDuration   int64              `json:"total_duration"`   // in millisecond
+	Url             string             `json:"url"`              // link to the task
+	Ts              time.Time          `json:"ts"`               // time of sending
+}
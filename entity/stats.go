@@ -10,3 +10,11 @@ type StatsTasksByStatusItem struct {
 	Status string `json:"status" bson:"_id"`
 	Tasks  int64  `json:"tasks" bson:"tasks"`
 }
+
+// StatsTimeSeriesItem is the number of tasks completed in a time bucket
+type StatsTimeSeriesItem struct {
+	Time      string `json:"time" bson:"_id"`
+	Success   int64  `json:"success" bson:"success"`
+	Failure   int64  `json:"failure" bson:"failure"`
+	Cancelled int64  `json:"cancelled" bson:"cancelled"`
+}
@@ -3,6 +3,7 @@ package entity
 import (
 	"encoding/json"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type TaskMessage struct {
@@ -27,4 +28,19 @@ type StreamMessageTaskData struct {
 	TaskId  primitive.ObjectID `json:"task_id"`
 	Records []Result           `json:"data"`
 	Logs    []string           `json:"logs"`
+	Levels  []string           `json:"levels,omitempty"` // log levels of Logs by index
+
+	TraceContext map[string]string `json:"trace_context,omitempty"` // trace context of the span sending Records if traced
+}
+
+// TaskListQueryParams are query params of the task list
+type TaskListQueryParams struct {
+	SpiderId   string    `form:"spider_id" url:"spider_id"`
+	NodeId     string    `form:"node_id" url:"node_id"`
+	ScheduleId string    `form:"schedule_id" url:"schedule_id"`
+	Status     string    `form:"status" url:"status"` // comma-separated statuses
+	StartTs    time.Time `form:"start_ts" url:"start_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTs      time.Time `form:"end_ts" url:"end_ts" time_format:"2006-01-02T15:04:05Z07:00"`
+	View       string    `form:"view" url:"view"` // constants.TaskListViewLight to omit large fields
+	Test       bool      `form:"test" url:"test"` // whether to list test tasks instead of other tasks
 }
@@ -0,0 +1,11 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// TaskAckMessage is sent by a worker to accept or reject a task dispatched to
+// it, see constants.GrpcStreamMessageCodeTaskAck
+type TaskAckMessage struct {
+	TaskId   primitive.ObjectID `json:"task_id"`
+	Accepted bool               `json:"accepted"`
+	Reason   string             `json:"reason,omitempty"` // reason of rejection
+}
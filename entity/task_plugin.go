@@ -0,0 +1,31 @@
+package entity
+
+import "github.com/crawlab-team/crawlab-core/interfaces"
+
+type TaskPluginContext struct {
+	Task    interfaces.Task
+	Spider  interfaces.Spider
+	Status  string
+	Error   error
+	Records []interface{}
+}
+
+func (ctx *TaskPluginContext) GetTask() (t interfaces.Task) {
+	return ctx.Task
+}
+
+func (ctx *TaskPluginContext) GetSpider() (s interfaces.Spider) {
+	return ctx.Spider
+}
+
+func (ctx *TaskPluginContext) GetStatus() (status string) {
+	return ctx.Status
+}
+
+func (ctx *TaskPluginContext) GetError() (err error) {
+	return ctx.Error
+}
+
+func (ctx *TaskPluginContext) GetRecords() (records []interface{}) {
+	return ctx.Records
+}
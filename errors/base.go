@@ -24,6 +24,9 @@ const (
 	ErrorPrefixGit        = "git"
 	ErrorPrefixResult     = "result"
 	ErrorPrefixDataSource = "data_source"
+	ErrorPrefixBlob       = "blob"
+	ErrorPrefixJob        = "job"
+	ErrorPrefixProxy      = "proxy"
 )
 
 type ErrorPrefix string
@@ -0,0 +1,9 @@
+package errors
+
+func NewBlobError(msg string) (err error) {
+	return NewError(ErrorPrefixBlob, msg)
+}
+
+var ErrorBlobNotExists = NewBlobError("not exists")
+var ErrorBlobInvalidType = NewBlobError("invalid store type")
+var ErrorBlobRequestFailed = NewBlobError("request failed")
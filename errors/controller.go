@@ -17,3 +17,4 @@ var ErrorControllerNotCancellable = NewControllerError("not cancellable")
 var ErrorControllerMissingRequestFields = NewControllerError("missing request fields")
 var ErrorControllerEmptyResponse = NewControllerError("empty response")
 var ErrorControllerFilerNotFound = NewControllerError("filer not found")
+var ErrorControllerForbidden = NewControllerError("forbidden")
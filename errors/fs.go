@@ -9,3 +9,8 @@ var ErrorFsEmptyWorkspacePath = NewFsError("empty workspace path")
 var ErrorFsInvalidType = NewFsError("invalid type")
 var ErrorFsAlreadyExists = NewFsError("already exists")
 var ErrorFsInvalidContent = NewFsError("invalid content")
+var ErrorFsPathOutsideRoot = NewFsError("path outside root")
+var ErrorFsQuotaExceeded = NewFsError("quota exceeded")
+var ErrorFsSyncFailed = NewFsError("sync failed")
+var ErrorFsChecksumMismatch = NewFsError("checksum mismatch")
+var ErrorFsNotWritable = NewFsError("not writable")
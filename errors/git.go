@@ -7,4 +7,5 @@ func NewGitError(msg string) (err error) {
 var (
 	ErrorGitInvalidAuthType = NewGitError("invalid auth type")
 	ErrorGitNoMainBranch    = NewGitError("no main branch")
+	ErrorGitNoKnownHosts    = NewGitError("no known hosts")
 )
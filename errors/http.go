@@ -7,3 +7,5 @@ func NewHttpError(msg string) (err error) {
 var ErrorHttpBadRequest = NewHttpError("bad request")
 var ErrorHttpUnauthorized = NewHttpError("unauthorized")
 var ErrorHttpNotFound = NewHttpError("not found")
+var ErrorHttpRequestTimeout = NewHttpError("request timeout")
+var ErrorHttpAddressNotAllowed = NewHttpError("address not allowed")
@@ -0,0 +1,7 @@
+package errors
+
+func NewJobError(msg string) (err error) {
+	return NewError(ErrorPrefixJob, msg)
+}
+
+var ErrorJobInvalidType = NewJobError("invalid job type")
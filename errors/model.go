@@ -17,3 +17,5 @@ var ErrorModelMissingId = errors.New("missing _id")
 var ErrorModelNotAllowed = NewModelError("not allowed")
 var ErrorModelDeleteListError = NewModelError("delete list error")
 var ErrorModelNilPointer = NewModelError("nil pointer")
+var ErrorModelMigrationDuplicated = NewModelError("duplicated migration version")
+var ErrorModelMigrationFailed = NewModelError("migration failed")
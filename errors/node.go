@@ -11,4 +11,10 @@ var ErrorNodeInvalidStatus = NewNodeError("invalid status")
 var ErrorNodeInvalidCode = NewNodeError("invalid code")
 var ErrorNodeInvalidNodeKey = NewNodeError("invalid node key")
 var ErrorNodeMonitorError = NewNodeError("monitor error")
+var ErrorNodeMonitorCancelled = NewNodeError("monitor cancelled")
 var ErrorNodeNotExists = NewNodeError("not exists")
+var ErrorNodeVersionIncompatible = NewNodeError("version incompatible")
+var ErrorNodeHasRunningTasks = NewNodeError("has running tasks")
+var ErrorNodeDeleteMaster = NewNodeError("cannot delete master node")
+var ErrorNodeInvalidSort = NewNodeError("invalid sort")
+var ErrorNodePreflightFailed = NewNodeError("preflight failed")
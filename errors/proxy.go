@@ -0,0 +1,10 @@
+package errors
+
+func NewProxyError(msg string) (err error) {
+	return NewError(ErrorPrefixProxy, msg)
+}
+
+var (
+	ErrorProxyEmptyPool       = NewProxyError("empty proxy pool")
+	ErrorProxyInvalidStrategy = NewProxyError("invalid proxy strategy")
+)
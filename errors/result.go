@@ -3,3 +3,7 @@ package errors
 func NewResultError(msg string) (err error) {
 	return NewError(ErrorPrefixResult, msg)
 }
+
+var (
+	ErrorResultColNameCollision = NewResultError("collection name collides with an existing collection which is not a result collection")
+)
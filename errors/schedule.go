@@ -5,3 +5,7 @@ func NewScheduleError(msg string) (err error) {
 }
 
 //var ErrorSchedule = NewScheduleError("unregistered")
+
+var ErrorScheduleInvalidTimezone = NewScheduleError("invalid timezone")
+var ErrorScheduleInvalidMisfirePolicy = NewScheduleError("invalid misfire policy")
+var ErrorScheduleInvalidNextRunTs = NewScheduleError("invalid next run time")
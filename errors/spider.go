@@ -7,4 +7,10 @@ func NewSpiderError(msg string) (err error) {
 var (
 	ErrorSpiderMissingRequiredOption = NewSpiderError("missing required option")
 	ErrorSpiderForbidden             = NewSpiderError("forbidden")
+	ErrorSpiderInvalid               = NewSpiderError("invalid")
+	ErrorSpiderAlreadyExists         = NewSpiderError("already exists")
+	ErrorSpiderBulkEmptySelector     = NewSpiderError("no spider selector in bulk operation")
+	ErrorSpiderBulkTooLarge          = NewSpiderError("too many spiders in bulk operation")
+	ErrorSpiderInvalidStaleThreshold = NewSpiderError("invalid stale threshold")
+	ErrorSpiderProfileNotFound       = NewSpiderError("profile not found")
 )
@@ -5,3 +5,4 @@ func NewStatsError(msg string) (err error) {
 }
 
 var ErrorStatsInvalidType = NewStatsError("invalid type")
+var ErrorStatsInvalidInterval = NewStatsError("invalid interval")
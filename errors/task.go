@@ -15,5 +15,27 @@ var (
 	ErrorTaskEmptySpiderId         = NewTaskError("empty spider id")
 	ErrorTaskNoNodeId              = NewTaskError("no node id")
 	ErrorTaskNodeNotFound          = NewTaskError("node not found")
+	ErrorTaskNoEligibleNode        = NewTaskError("no online node has the required node tags and capabilities")
+	ErrorTaskTimeout               = NewTaskError("timeout")
+	ErrorTaskInstallFailed         = NewTaskError("dependency install failed")
 	ErrorTaskMissingRequiredOption = NewSpiderError("missing required option")
+	ErrorTaskIdempotencyInProgress = NewTaskError("request with the same idempotency key is in progress")
+	ErrorTaskIdempotencyMismatch   = NewTaskError("idempotency key already used by a different request")
+	ErrorTaskIdempotencyUnknown    = NewTaskError("request with the same idempotency key did not complete")
+	ErrorTaskArtifactInvalidName   = NewTaskError("invalid artifact name")
+	ErrorTaskArtifactSizeExceeded  = NewTaskError("artifact size limit exceeded")
+	ErrorTaskGitNotConfigured      = NewTaskError("git is not configured for the spider")
+	ErrorTaskGitRefNotFound        = NewTaskError("git ref not found")
+	ErrorTaskAlreadyFinished       = NewTaskError("already finished")
+	ErrorTaskAlreadyDispatched     = NewTaskError("already dispatched")
+	ErrorTaskNodeNotEligible       = NewTaskError("node is not eligible to run the task")
+	ErrorTaskInputInvalidName      = NewTaskError("invalid input file name")
+	ErrorTaskInputSizeExceeded     = NewTaskError("input file size limit exceeded")
+	ErrorTaskInputInvalidType      = NewTaskError("content type of input file not allowed")
+	ErrorTaskInputNotFound         = NewTaskError("input file not found")
+	ErrorTaskNotDeadLettered       = NewTaskError("not dead-lettered")
+	ErrorTaskAlreadyRetried        = NewTaskError("already retried")
+	ErrorTaskInvalidCmd            = NewTaskError("invalid cmd")
+	ErrorTaskExecutableNotFound    = NewTaskError("executable not found")
+	ErrorTaskContainerFailed       = NewTaskError("container failed")
 )
@@ -13,5 +13,10 @@ var (
 	ErrorUserMismatch              = NewUserError("mismatch")
 	ErrorUserMissingRequiredFields = NewUserError("missing required fields")
 	ErrorUserUnauthorized          = NewUserError("unauthorized")
-	ErrorUserInvalidPassword       = NewUserError("invalid password (length must be no less than 5)")
+	ErrorUserInvalidPassword       = NewUserError("invalid password")
+	ErrorUserNotAllowed            = NewUserError("not allowed")
+	ErrorUserLocked                = NewUserError("locked due to too many failed login attempts")
+	ErrorUserTooManyLoginAttempts  = NewUserError("too many failed login attempts")
+	ErrorUserInvalidRefreshToken   = NewUserError("invalid refresh token")
+	ErrorUserRefreshTokenReused    = NewUserError("refresh token reused")
 )
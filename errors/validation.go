@@ -0,0 +1,27 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a single invalid field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is a list of ValidationError that implements error
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("validation error: %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,72 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Events sent by operations in a transaction are deferred until the
+// transaction is committed, so that receivers do not see writes which may be
+// rolled back, and events of attempts of the transaction retried on transient
+// errors are not sent twice.
+
+type deferredEventsKey struct{}
+
+type deferredEvent struct {
+	eventName string
+	data      []interface{}
+}
+
+// DeferredEvents are events deferred in the context of a transaction
+type DeferredEvents struct {
+	mu     sync.Mutex
+	events []deferredEvent
+}
+
+// Reset discards the deferred events, e.g. when the transaction is retried
+func (d *DeferredEvents) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = nil
+}
+
+// Flush sends the deferred events in order and discards them
+func (d *DeferredEvents) Flush() {
+	d.mu.Lock()
+	events := d.events
+	d.events = nil
+	d.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	go func() {
+		for _, e := range events {
+			SendEvent(e.eventName, e.data...)
+		}
+	}()
+}
+
+func (d *DeferredEvents) add(eventName string, data ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, deferredEvent{eventName: eventName, data: data})
+}
+
+// WithDeferredEvents returns a context in which events sent by
+// SendEventContext are deferred until flushed
+func WithDeferredEvents(ctx context.Context) (ctx2 context.Context, d *DeferredEvents) {
+	d = &DeferredEvents{}
+	return context.WithValue(ctx, deferredEventsKey{}, d), d
+}
+
+// SendEventContext sends the event in the background, or defers it if the
+// context is of WithDeferredEvents
+func SendEventContext(ctx context.Context, eventName string, data ...interface{}) {
+	if ctx != nil {
+		if d, ok := ctx.Value(deferredEventsKey{}).(*DeferredEvents); ok {
+			d.add(eventName, data...)
+			return
+		}
+	}
+	go SendEvent(eventName, data...)
+}
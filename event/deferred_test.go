@@ -0,0 +1,62 @@
+package event
+
+import (
+	"context"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func registerTestEvents(t *testing.T) (ch chan interfaces.EventData) {
+	ch = make(chan interfaces.EventData, 10)
+	svc := NewEventService()
+	svc.Register("test", "^test:", "^$", &ch)
+	t.Cleanup(func() { svc.Unregister("test") })
+	return ch
+}
+
+func receiveTestEvent(t *testing.T, ch chan interfaces.EventData) (data interface{}) {
+	select {
+	case e := <-ch:
+		return e.GetData()
+	case <-time.After(time.Second):
+		require.FailNow(t, "event not sent")
+		return nil
+	}
+}
+
+func requireNoTestEvent(t *testing.T, ch chan interfaces.EventData) {
+	select {
+	case e := <-ch:
+		require.FailNow(t, "unexpected event", "%v", e.GetData())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSendEventContext(t *testing.T) {
+	ch := registerTestEvents(t)
+
+	// sent without deferred events
+	SendEventContext(context.Background(), "test:send", 1)
+	require.Equal(t, 1, receiveTestEvent(t, ch))
+
+	// deferred until flushed
+	ctx, d := WithDeferredEvents(context.Background())
+	SendEventContext(ctx, "test:send", 2)
+	requireNoTestEvent(t, ch)
+	d.Flush()
+	require.Equal(t, 2, receiveTestEvent(t, ch))
+
+	// discarded on reset, e.g. of failed attempts of transactions
+	SendEventContext(ctx, "test:send", 3)
+	d.Reset()
+	SendEventContext(ctx, "test:send", 4)
+	d.Flush()
+	require.Equal(t, 4, receiveTestEvent(t, ch))
+	requireNoTestEvent(t, ch)
+
+	// flushed once
+	d.Flush()
+	requireNoTestEvent(t, ch)
+}
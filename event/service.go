@@ -9,6 +9,7 @@ import (
 	"github.com/crawlab-team/go-trace"
 	"github.com/thoas/go-funk"
 	"regexp"
+	"sync"
 )
 
 var S interfaces.EventService
@@ -18,27 +19,38 @@ type Service struct {
 	includes []string
 	excludes []string
 	chs      []*chan interfaces.EventData
+	dones    []chan struct{} // closed on unregister, so that pending sends to the channel are dropped
+	mu       sync.RWMutex    // lock of handlers, registered and unregistered while events are sent
 }
 
 func (svc *Service) Register(key, include, exclude string, ch *chan interfaces.EventData) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
 	svc.keys = append(svc.keys, key)
 	svc.includes = append(svc.includes, include)
 	svc.excludes = append(svc.excludes, exclude)
 	svc.chs = append(svc.chs, ch)
+	svc.dones = append(svc.dones, make(chan struct{}))
 }
 
 func (svc *Service) Unregister(key string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
 	idx := funk.IndexOfString(svc.keys, key)
 	if idx != -1 {
+		close(svc.dones[idx])
 		svc.keys = append(svc.keys[:idx], svc.keys[(idx+1):]...)
 		svc.includes = append(svc.includes[:idx], svc.includes[(idx+1):]...)
 		svc.excludes = append(svc.excludes[:idx], svc.excludes[(idx+1):]...)
 		svc.chs = append(svc.chs[:idx], svc.chs[(idx+1):]...)
+		svc.dones = append(svc.dones[:idx], svc.dones[(idx+1):]...)
 		log.Infof("[EventService] unregistered %s", key)
 	}
 }
 
 func (svc *Service) SendEvent(eventName string, data ...interface{}) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
 	for i, key := range svc.keys {
 		// include
 		include := svc.includes[i]
@@ -65,28 +77,35 @@ func (svc *Service) SendEvent(eventName string, data ...interface{}) {
 		// send event
 		utils.LogDebug(fmt.Sprintf("key %s matches event %s", key, eventName))
 		ch := svc.chs[i]
+		done := svc.dones[i]
 		go func(ch *chan interfaces.EventData) {
 			for _, d := range data {
-				*ch <- &entity.EventData{
+				select {
+				case *ch <- &entity.EventData{
 					Event: eventName,
 					Data:  d,
+				}:
+				case <-done:
+					// unregistered, no longer received
+					return
 				}
 			}
 		}(ch)
 	}
 }
 
-func NewEventService() (svc interfaces.EventService) {
-	if S != nil {
-		return S
-	}
-
-	svc = &Service{
-		chs:  []*chan interfaces.EventData{},
-		keys: []string{},
-	}
+var initOnce sync.Once
 
-	S = svc
-
-	return svc
+func NewEventService() (svc interfaces.EventService) {
+	// handlers registered concurrently on start share the service
+	initOnce.Do(func() {
+		if S != nil {
+			return
+		}
+		S = &Service{
+			chs:  []*chan interfaces.EventData{},
+			keys: []string{},
+		}
+	})
+	return S
 }
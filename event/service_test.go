@@ -0,0 +1,57 @@
+package event
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestService_Concurrent(t *testing.T) {
+	svc := NewEventService()
+
+	// handlers registered and unregistered while events are sent
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("test-concurrent-%d", i)
+			ch := make(chan interfaces.EventData)
+			svc.Register(key, "^test:", "^$", &ch)
+			svc.Unregister(key)
+		}(i)
+		go func() {
+			defer wg.Done()
+			svc.SendEvent("test:concurrent", 1)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestService_Unregister(t *testing.T) {
+	svc := NewEventService()
+	ch := make(chan interfaces.EventData)
+	svc.Register("test-unregister", "^test:", "^$", &ch)
+
+	// received while registered
+	svc.SendEvent("test:unregister", 1)
+	select {
+	case e := <-ch:
+		require.Equal(t, 1, e.GetData())
+	case <-time.After(time.Second):
+		require.FailNow(t, "event not sent")
+	}
+
+	// pending sends dropped on unregister
+	svc.SendEvent("test:unregister", 2)
+	svc.Unregister("test-unregister")
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case e := <-ch:
+		require.FailNow(t, "unexpected event", "%v", e.GetData())
+	default:
+	}
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"github.com/ReneKroon/ttlcache"
+	"github.com/crawlab-team/crawlab-core/blob"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/go-trace"
+	"os"
+)
+
+// storeExport moves the file of the finished export to the blob store, from
+// which it is downloaded afterwards. The local file is kept if storing fails.
+func storeExport(cache *ttlcache.Cache, export *entity.Export, contentType string) {
+	if export.Status != constants.TaskStatusFinished {
+		return
+	}
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	f, err := os.Open(export.DownloadPath)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	key := "exports/" + export.Id + "/" + export.FileName
+	if err := store.Put(key, f, info.Size(), contentType); err != nil {
+		trace.PrintError(err)
+		return
+	}
+	export.BlobKey = key
+	cache.Set(export.Id, export)
+	_ = os.Remove(export.DownloadPath)
+}
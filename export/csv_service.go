@@ -10,6 +10,7 @@ import (
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/paths"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
@@ -18,7 +19,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"os"
-	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"time"
@@ -60,7 +61,10 @@ func (svc *CsvService) Export(exportType, target string, filter interfaces.Filte
 	svc.cache.Set(exportId, export)
 
 	// execute export
-	go svc.export(export)
+	go func() {
+		svc.export(export)
+		storeExport(svc.cache, export, "text/csv")
+	}()
 
 	return exportId, nil
 }
@@ -210,8 +214,7 @@ func (svc *CsvService) export(export *entity.Export) {
 }
 
 func (svc *CsvService) getExportDir() (dir string, err error) {
-	tempDir := os.TempDir()
-	exportDir := path.Join(tempDir, "export", "csv")
+	exportDir := paths.GetTempPath("export", "csv")
 	if !utils.Exists(exportDir) {
 		err := os.MkdirAll(exportDir, 0755)
 		if err != nil {
@@ -232,7 +235,7 @@ func (svc *CsvService) getDownloadPath(exportId string) (downloadPath string) {
 	if err != nil {
 		return ""
 	}
-	downloadPath = path.Join(exportDir, svc.getFileName(exportId))
+	downloadPath = filepath.Join(exportDir, svc.getFileName(exportId))
 	return downloadPath
 }
 
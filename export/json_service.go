@@ -9,13 +9,14 @@ import (
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/paths"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
 	"github.com/hashicorp/go-uuid"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"os"
-	"path"
+	"path/filepath"
 	"time"
 )
 
@@ -55,7 +56,10 @@ func (svc *JsonService) Export(exportType, target string, filter interfaces.Filt
 	svc.cache.Set(exportId, export)
 
 	// execute export
-	go svc.export(export)
+	go func() {
+		svc.export(export)
+		storeExport(svc.cache, export, "application/json")
+	}()
 
 	return exportId, nil
 }
@@ -178,8 +182,7 @@ func (svc *JsonService) export(export *entity.Export) {
 }
 
 func (svc *JsonService) getExportDir() (dir string, err error) {
-	tempDir := os.TempDir()
-	exportDir := path.Join(tempDir, "export", "json")
+	exportDir := paths.GetTempPath("export", "json")
 	if !utils.Exists(exportDir) {
 		err := os.MkdirAll(exportDir, 0755)
 		if err != nil {
@@ -200,7 +203,7 @@ func (svc *JsonService) getDownloadPath(exportId string) (downloadPath string) {
 	if err != nil {
 		return ""
 	}
-	downloadPath = path.Join(exportDir, svc.getFileName(exportId))
+	downloadPath = filepath.Join(exportDir, svc.getFileName(exportId))
 	return downloadPath
 }
 
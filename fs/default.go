@@ -1,24 +1,10 @@
 package fs
 
 import (
-	"github.com/apex/log"
-	"github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
-	"path"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"path/filepath"
 )
 
-func init() {
-	rootDir, err := homedir.Dir()
-	if err != nil {
-		log.Warnf("cannot find home directory: %v", err)
-		return
-	}
-	DefaultWorkspacePath = path.Join(rootDir, "crawlab_workspace")
-
-	workspacePath := viper.GetString("workspace")
-	if workspacePath == "" {
-		viper.Set("workspace", DefaultWorkspacePath)
-	}
-}
-
-var DefaultWorkspacePath string
+// DefaultWorkspacePath is the legacy default root directory of spider
+// workspaces, used if neither "workspace" nor "data.path" is configured
+var DefaultWorkspacePath = filepath.Join(paths.GetHomePath(), "crawlab_workspace")
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"io"
@@ -14,6 +15,7 @@ type ServiceV2 struct {
 	// settings
 	rootPath  string
 	skipNames []string
+	quota     int64 // max disk usage of root path (bytes), 0 for unlimited
 }
 
 func (svc *ServiceV2) List(path string) (files []interfaces.FsFileInfo, err error) {
@@ -22,7 +24,10 @@ func (svc *ServiceV2) List(path string) (files []interfaces.FsFileInfo, err erro
 	if normPath == "." {
 		normPath = ""
 	}
-	fullPath := filepath.Join(svc.rootPath, normPath)
+	fullPath, err := svc.getFullPath(normPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Temporary map to hold directory information and their children
 	dirMap := make(map[string]*entity.FsFileInfo)
@@ -78,18 +83,26 @@ func (svc *ServiceV2) List(path string) (files []interfaces.FsFileInfo, err erro
 }
 
 func (svc *ServiceV2) GetFile(path string) (data []byte, err error) {
-	return ioutil.ReadFile(filepath.Join(svc.rootPath, path))
+	fullPath, err := svc.getFullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(fullPath)
 }
 
 func (svc *ServiceV2) GetFileInfo(path string) (file interfaces.FsFileInfo, err error) {
-	f, err := os.Stat(filepath.Join(svc.rootPath, path))
+	fullPath, err := svc.getFullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Stat(fullPath)
 	if err != nil {
 		return nil, err
 	}
 	return &entity.FsFileInfo{
 		Name:      f.Name(),
 		Path:      path,
-		FullPath:  filepath.Join(svc.rootPath, path),
+		FullPath:  fullPath,
 		Extension: filepath.Ext(path),
 		IsDir:     f.IsDir(),
 		FileSize:  f.Size(),
@@ -100,36 +113,74 @@ func (svc *ServiceV2) GetFileInfo(path string) (file interfaces.FsFileInfo, err
 }
 
 func (svc *ServiceV2) Save(path string, data []byte) (err error) {
+	fullPath, err := svc.getFullPath(path)
+	if err != nil {
+		return err
+	}
+
+	// Check quota
+	var delta = int64(len(data))
+	if f, err := os.Stat(fullPath); err == nil {
+		delta -= f.Size()
+	}
+	if err := svc.checkQuota(delta); err != nil {
+		return err
+	}
+
 	// Create directories if not exist
-	dir := filepath.Dir(filepath.Join(svc.rootPath, path))
+	dir := filepath.Dir(fullPath)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := os.MkdirAll(dir, WorkspaceDirPerm); err != nil {
 			return err
 		}
 	}
 
 	// Write file
-	return ioutil.WriteFile(filepath.Join(svc.rootPath, path), data, 0644)
+	if err := ioutil.WriteFile(fullPath, data, 0644); err != nil {
+		return err
+	}
+	AddWorkspaceUsage(svc.rootPath, delta)
+	return nil
 }
 
 func (svc *ServiceV2) CreateDir(path string) (err error) {
-	return os.MkdirAll(filepath.Join(svc.rootPath, path), 0755)
+	fullPath, err := svc.getFullPath(path)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(fullPath, WorkspaceDirPerm)
 }
 
 func (svc *ServiceV2) Rename(path, newPath string) (err error) {
-	oldPath := filepath.Join(svc.rootPath, path)
-	newFullPath := filepath.Join(svc.rootPath, newPath)
+	oldPath, err := svc.getFullPath(path)
+	if err != nil {
+		return err
+	}
+	newFullPath, err := svc.getFullPath(newPath)
+	if err != nil {
+		return err
+	}
 	return os.Rename(oldPath, newFullPath)
 }
 
 func (svc *ServiceV2) Delete(path string) (err error) {
-	fullPath := filepath.Join(svc.rootPath, path)
+	fullPath, err := svc.getFullPath(path)
+	if err != nil {
+		return err
+	}
+	defer ResetWorkspaceUsage(svc.rootPath)
 	return os.RemoveAll(fullPath)
 }
 
 func (svc *ServiceV2) Copy(path, newPath string) (err error) {
-	srcPath := filepath.Join(svc.rootPath, path)
-	destPath := filepath.Join(svc.rootPath, newPath)
+	srcPath, err := svc.getFullPath(path)
+	if err != nil {
+		return err
+	}
+	destPath, err := svc.getFullPath(newPath)
+	if err != nil {
+		return err
+	}
 
 	// Get source info
 	srcInfo, err := os.Stat(srcPath)
@@ -137,6 +188,19 @@ func (svc *ServiceV2) Copy(path, newPath string) (err error) {
 		return err
 	}
 
+	// Check quota
+	srcSize := srcInfo.Size()
+	if srcInfo.IsDir() {
+		srcSize, err = utils.GetDirSize(srcPath)
+		if err != nil {
+			return err
+		}
+	}
+	if err := svc.checkQuota(srcSize); err != nil {
+		return err
+	}
+	defer ResetWorkspaceUsage(svc.rootPath)
+
 	// If source is file, copy it
 	if !srcInfo.IsDir() {
 		srcFile, err := os.Open(srcPath)
@@ -160,6 +224,38 @@ func (svc *ServiceV2) Copy(path, newPath string) (err error) {
 	}
 }
 
+func (svc *ServiceV2) GetSize() (size int64, err error) {
+	return utils.GetDirSize(svc.rootPath)
+}
+
+func (svc *ServiceV2) GetQuota() (quota int64) {
+	return svc.quota
+}
+
+func (svc *ServiceV2) SetQuota(quota int64) {
+	svc.quota = quota
+}
+
+// checkQuota returns errors.ErrorFsQuotaExceeded if adding delta bytes exceeds the quota
+func (svc *ServiceV2) checkQuota(delta int64) (err error) {
+	if svc.quota <= 0 || delta <= 0 {
+		return nil
+	}
+	size, err := GetWorkspaceUsage(svc.rootPath)
+	if err != nil {
+		return err
+	}
+	if size+delta > svc.quota {
+		return errors.ErrorFsQuotaExceeded
+	}
+	return nil
+}
+
+// getFullPath resolves the path relative to the root path and rejects paths escaping the root
+func (svc *ServiceV2) getFullPath(path string) (fullPath string, err error) {
+	return ResolvePath(svc.rootPath, path)
+}
+
 func NewFsServiceV2(path string) (svc interfaces.FsServiceV2) {
 	return &ServiceV2{
 		rootPath:  path,
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -236,3 +237,42 @@ func TestServiceV2_CopyDir(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Hello, world!", string(data))
 }
+
+func TestServiceV2_PathOutsideRoot(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	svc := NewFsServiceV2(filepath.Join(rootDir, "workspace"))
+
+	_, err = svc.GetFile("../outside.txt")
+	assert.Equal(t, errors.ErrorFsPathOutsideRoot, err)
+	err = svc.Save("../../outside.txt", []byte("hello"))
+	assert.Equal(t, errors.ErrorFsPathOutsideRoot, err)
+	err = svc.Rename("file.txt", "../file.txt")
+	assert.Equal(t, errors.ErrorFsPathOutsideRoot, err)
+	err = svc.Save("dir/../file.txt", []byte("hello"))
+	assert.Nil(t, err)
+}
+
+func TestServiceV2_Quota(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	svc := NewFsServiceV2(rootDir)
+	svc.SetQuota(10)
+
+	assert.Nil(t, svc.Save("a.txt", []byte("hello")))
+	assert.Nil(t, svc.Save("a.txt", []byte("hello world")[:10]))
+	assert.Equal(t, errors.ErrorFsQuotaExceeded, svc.Save("b.txt", []byte("x")))
+	assert.Equal(t, errors.ErrorFsQuotaExceeded, svc.Copy("a.txt", "c.txt"))
+
+	size, err := svc.GetSize()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), size)
+}
@@ -0,0 +1,566 @@
+package fs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Spider files are synced from master to worker nodes incrementally: the worker
+// fetches the file list with md5 hashes of the spider workspace on master
+// (GET /sync/:id/scan), downloads files missing or changed locally
+// (GET /sync/:id/download?path=) and removes local files previously synced
+// but no longer on master. Files created on the node, e.g. by tasks, are kept.
+// Paths of synced files are kept in a manifest beside the workspace.
+// Files are downloaded in parallel by up to "sync.concurrency" workers and
+// verified against their md5 hashes. Worker nodes report the progress to
+// master (POST /sync/:id/progress).
+//
+// Requests are authorized by the grpc auth key ("grpc.authKey").
+
+// GetSyncStrategy returns the sync strategy configured by "sync.strategy",
+// constants.SyncStrategyLazy by default
+func GetSyncStrategy() (strategy string) {
+	switch viper.GetString("sync.strategy") {
+	case constants.SyncStrategyEager:
+		return constants.SyncStrategyEager
+	default:
+		return constants.SyncStrategyLazy
+	}
+}
+
+// GetSyncConcurrency returns the max number of files downloaded in parallel,
+// which is configured by "sync.concurrency" (4 by default)
+func GetSyncConcurrency() (n int) {
+	if n = viper.GetInt("sync.concurrency"); n > 0 {
+		return n
+	}
+	return 4
+}
+
+var syncNodeKey string
+
+// SetSyncNodeKey sets the key of the current worker node, which is required
+// to report sync progress to master
+func SetSyncNodeKey(key string) {
+	syncNodeKey = key
+}
+
+// GetSyncAuthKey returns the auth key of sync requests
+func GetSyncAuthKey() (authKey string) {
+	authKey = viper.GetString("grpc.authKey")
+	if authKey == "" {
+		authKey = constants.DefaultGrpcAuthKey
+	}
+	return authKey
+}
+
+// ScanDirectory returns info of all regular files in the directory including
+// md5 hashes, keyed by slash-separated path relative to the directory
+func ScanDirectory(dir string) (files map[string]entity.FsFileInfo, err error) {
+	files = map[string]entity.FsFileInfo{}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hash, err := utils.GetFileHash(p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		files[relPath] = entity.FsFileInfo{
+			Name:      info.Name(),
+			Path:      relPath,
+			FullPath:  p,
+			Extension: filepath.Ext(p),
+			FileSize:  info.Size(),
+			ModTime:   info.ModTime(),
+			Mode:      info.Mode(),
+			Hash:      hash,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return files, nil
+}
+
+var syncLocks sync.Map // spider id -> *sync.Mutex
+
+// SyncSpiderFiles syncs workspace files of the spider from master to the
+// current node. Concurrent syncs of the same spider are serialized.
+func SyncSpiderFiles(id primitive.ObjectID) (err error) {
+	lock, _ := syncLocks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// files on master
+	masterFiles, quota, err := fetchMasterFiles(id)
+	if err != nil {
+		return err
+	}
+
+	// files on current node
+	workspacePath, err := EnsureSpiderWorkspacePath(id)
+	if err != nil {
+		return err
+	}
+	localFiles, err := ScanDirectory(workspacePath)
+	if err != nil {
+		return err
+	}
+
+	// new or changed files
+	var files []entity.FsFileInfo
+	for path, masterFile := range masterFiles {
+		if localFile, ok := localFiles[path]; ok && localFile.Hash == masterFile.Hash {
+			continue
+		}
+		files = append(files, masterFile)
+	}
+	p := newSyncProgressReporter(id, len(files))
+
+	// workspace quota
+	if err := checkSyncQuota(workspacePath, quota, localFiles, masterFiles); err != nil {
+		p.finish(err)
+		return err
+	}
+
+	// download in parallel
+	err = downloadMasterFiles(id, workspacePath, files, p)
+	ResetWorkspaceUsage(workspacePath)
+
+	// delete files previously synced but removed on master
+	if err == nil {
+		for path := range loadSyncManifest(id) {
+			if _, ok := masterFiles[path]; ok {
+				continue
+			}
+			if _, ok := localFiles[path]; !ok {
+				continue
+			}
+			if err2 := deleteSyncedFile(workspacePath, path); err2 != nil {
+				if err == nil {
+					err = err2
+				}
+				p.delete(path, err2)
+				continue
+			}
+			p.delete(path, nil)
+		}
+	}
+	if err == nil {
+		err = saveSyncManifest(id, masterFiles)
+	}
+
+	// report completion
+	p.finish(err)
+	if err != nil {
+		return err
+	}
+
+	if p.progress.Downloaded > 0 || p.progress.Deleted > 0 {
+		log.Infof("[fs] synced spider[%s] files: %d downloaded, %d deleted", id.Hex(), p.progress.Downloaded, p.progress.Deleted)
+	}
+
+	return nil
+}
+
+func deleteSyncedFile(workspacePath string, path string) (err error) {
+	fullPath, err := ResolvePath(workspacePath, path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// getSyncManifestPath returns the path of the manifest of files synced to the
+// spider workspace, which is kept beside the workspace so that it is neither
+// synced nor counted in the workspace usage
+func getSyncManifestPath(id primitive.ObjectID) (path string) {
+	return filepath.Join(GetWorkspaceRoot(), id.Hex()+".sync.json")
+}
+
+// loadSyncManifest returns paths of files synced to the spider workspace by
+// the last sync, which is empty if the spider has not been synced
+func loadSyncManifest(id primitive.ObjectID) (paths map[string]bool) {
+	paths = map[string]bool{}
+	data, err := os.ReadFile(getSyncManifestPath(id))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			trace.PrintError(err)
+		}
+		return paths
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Warnf("[fs] invalid sync manifest of spider[%s]: %v", id.Hex(), err)
+		return paths
+	}
+	for _, path := range list {
+		paths[path] = true
+	}
+	return paths
+}
+
+// saveSyncManifest saves paths of the files as synced to the spider workspace
+func saveSyncManifest(id primitive.ObjectID, files map[string]entity.FsFileInfo) (err error) {
+	list := make([]string, 0, len(files))
+	for path := range files {
+		list = append(list, path)
+	}
+	sort.Strings(list)
+	data, err := json.Marshal(list)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	manifestPath := getSyncManifestPath(id)
+	tmpPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, os.FileMode(0644)); err != nil {
+		return trace.TraceError(err)
+	}
+	if err := os.Rename(tmpPath, manifestPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// checkSyncQuota returns errors.ErrorFsQuotaExceeded if the workspace would
+// exceed the quota after syncing, i.e. with local files replaced by files on
+// master and files only on master added. Files are not downloaded at all in
+// that case, so that the workspace is left consistent.
+func checkSyncQuota(workspacePath string, quota int64, localFiles, masterFiles map[string]entity.FsFileInfo) (err error) {
+	if quota <= 0 {
+		return nil
+	}
+	size, err := utils.GetDirSize(workspacePath)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	for path, masterFile := range masterFiles {
+		size += masterFile.FileSize
+		if localFile, ok := localFiles[path]; ok {
+			size -= localFile.FileSize
+		}
+	}
+	if size > quota {
+		return fmt.Errorf("%w: %d bytes after sync, quota %d bytes", errors.ErrorFsQuotaExceeded, size, quota)
+	}
+	return nil
+}
+
+// downloadMasterFiles downloads the files by up to GetSyncConcurrency workers
+// and returns the first error, if any, after all workers are done
+func downloadMasterFiles(id primitive.ObjectID, workspacePath string, files []entity.FsFileInfo, p *syncProgressReporter) (err error) {
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	ch := make(chan entity.FsFileInfo)
+	for i := 0; i < GetSyncConcurrency() && i < len(files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range ch {
+				fileErr := downloadMasterFile(id, workspacePath, file)
+				if fileErr != nil {
+					errOnce.Do(func() { err = fileErr })
+				}
+				p.update(file.Path, fileErr)
+			}
+		}()
+	}
+	for _, file := range files {
+		ch <- file
+	}
+	close(ch)
+	wg.Wait()
+	return err
+}
+
+// syncProgressReporter keeps the progress of syncing files of a spider and
+// reports it to master, at most once per second except for completion
+type syncProgressReporter struct {
+	mu       sync.Mutex
+	progress entity.FsSyncProgress
+	reportTs time.Time
+}
+
+func newSyncProgressReporter(id primitive.ObjectID, total int) (p *syncProgressReporter) {
+	return &syncProgressReporter{
+		progress: entity.FsSyncProgress{
+			NodeKey:  syncNodeKey,
+			SpiderId: id,
+			Total:    total,
+			StartTs:  time.Now(),
+		},
+	}
+}
+
+func (p *syncProgressReporter) update(path string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.LastFile = path
+	p.addFile(path, constants.SyncFileActionDownload, err)
+	if err != nil {
+		p.progress.Failed++
+	} else {
+		p.progress.Downloaded++
+	}
+	if time.Since(p.reportTs) >= time.Second {
+		p.reportTs = time.Now()
+		p.report()
+	}
+}
+
+func (p *syncProgressReporter) delete(path string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addFile(path, constants.SyncFileActionDelete, err)
+	if err == nil {
+		p.progress.Deleted++
+	}
+}
+
+func (p *syncProgressReporter) addFile(path string, action string, err error) {
+	res := interfaces.SpiderSyncFileResult{Path: path, Action: action}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	p.progress.Files = append(p.progress.Files, res)
+}
+
+func (p *syncProgressReporter) finish(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.Finished = true
+	if err != nil {
+		p.progress.Error = err.Error()
+	}
+	p.report()
+}
+
+// report sends the progress to master. It is skipped on master, and failures
+// are only logged. Files synced are only sent on completion.
+func (p *syncProgressReporter) report() {
+	if syncNodeKey == "" {
+		return
+	}
+	p.progress.UpdateTs = time.Now()
+	progress := p.progress
+	if !progress.Finished {
+		progress.Files = nil
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	req, err := newSyncRequest(http.MethodPost, p.progress.SpiderId, "progress", nil, bytes.NewReader(data))
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := doSyncRequest(req)
+	if err != nil {
+		log.Warnf("[fs] failed to report sync progress of spider[%s]: %v", p.progress.SpiderId.Hex(), err)
+		return
+	}
+	_ = res.Body.Close()
+}
+
+var syncProgressMap sync.Map // "<node key>:<spider id>" -> entity.FsSyncProgress
+
+// SetSyncProgress records the sync progress reported by a worker node on master
+func SetSyncProgress(p entity.FsSyncProgress) {
+	syncProgressMap.Store(p.NodeKey+":"+p.SpiderId.Hex(), p)
+	if p.Finished {
+		if p.Error != "" {
+			log.Warnf("[fs] worker[%s] failed to sync spider[%s] files: %s", p.NodeKey, p.SpiderId.Hex(), p.Error)
+		} else if p.Downloaded > 0 || p.Deleted > 0 {
+			log.Infof("[fs] worker[%s] synced spider[%s] files: %d downloaded, %d deleted", p.NodeKey, p.SpiderId.Hex(), p.Downloaded, p.Deleted)
+		}
+	}
+}
+
+// GetSyncProgress returns the latest sync progress of the spider on the node
+func GetSyncProgress(nodeKey string, id primitive.ObjectID) (p entity.FsSyncProgress, ok bool) {
+	res, ok := syncProgressMap.Load(nodeKey + ":" + id.Hex())
+	if !ok {
+		return p, false
+	}
+	return res.(entity.FsSyncProgress), true
+}
+
+// DeleteSyncProgress drops the recorded sync progress of the spider on the node
+func DeleteSyncProgress(nodeKey string, id primitive.ObjectID) {
+	syncProgressMap.Delete(nodeKey + ":" + id.Hex())
+}
+
+// GetSyncProgressList returns the latest sync progress of each spider on the node
+func GetSyncProgressList(nodeKey string) (list []entity.FsSyncProgress) {
+	list = []entity.FsSyncProgress{}
+	syncProgressMap.Range(func(key, value interface{}) bool {
+		if p := value.(entity.FsSyncProgress); p.NodeKey == nodeKey {
+			list = append(list, p)
+		}
+		return true
+	})
+	return list
+}
+
+var syncHttpClient = &http.Client{Timeout: 5 * time.Minute}
+
+func newSyncRequest(method string, id primitive.ObjectID, action string, query url.Values, body io.Reader) (req *http.Request, err error) {
+	u := fmt.Sprintf("%s/sync/%s/%s", utils.GetApiAddress(), id.Hex(), action)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err = http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	req.Header.Set(constants.SyncHeaderAuthorization, GetSyncAuthKey())
+	return req, nil
+}
+
+func doSyncRequest(req *http.Request) (res *http.Response, err error) {
+	res, err = syncHttpClient.Do(req)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		_ = res.Body.Close()
+		return nil, trace.TraceError(fmt.Errorf("%w: %s %s", errors.ErrorFsSyncFailed, req.URL.Path, res.Status))
+	}
+	return res, nil
+}
+
+// fetchMasterFiles returns files of the spider workspace on master and the
+// workspace quota of the spider (bytes), 0 for unlimited
+func fetchMasterFiles(id primitive.ObjectID) (files map[string]entity.FsFileInfo, quota int64, err error) {
+	req, err := newSyncRequest(http.MethodGet, id, "scan", nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := doSyncRequest(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	var body struct {
+		Data map[string]entity.FsFileInfo `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, 0, trace.TraceError(err)
+	}
+	quota, _ = strconv.ParseInt(res.Header.Get(constants.SyncHeaderWorkspaceQuota), 10, 64)
+	return body.Data, quota, nil
+}
+
+func downloadMasterFile(id primitive.ObjectID, workspacePath string, file entity.FsFileInfo) (err error) {
+	fullPath, err := ResolvePath(workspacePath, file.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), WorkspaceDirPerm); err != nil {
+		return trace.TraceError(err)
+	}
+
+	req, err := newSyncRequest(http.MethodGet, id, "download", url.Values{"path": []string{file.Path}}, nil)
+	if err != nil {
+		return err
+	}
+	res, err := doSyncRequest(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// write to a temp file first so that running tasks never read partial files
+	tmpPath := fullPath + ".sync.tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode.Perm())
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	h := md5.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), res.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+
+	// verify checksum
+	if hash := hex.EncodeToString(h.Sum(nil)); file.Hash != "" && hash != file.Hash {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(fmt.Errorf("%w: %s", errors.ErrorFsChecksumMismatch, file.Path))
+	}
+
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+	return os.Chmod(fullPath, file.Mode.Perm())
+}
+
+// SyncAllSpiderFiles syncs files of all spiders having a workspace on the
+// current node, e.g. after the node reconnects to master
+func SyncAllSpiderFiles() {
+	entries, err := os.ReadDir(GetWorkspaceRoot())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			trace.PrintError(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(e.Name())
+		if err != nil {
+			continue
+		}
+		if err := SyncSpiderFiles(id); err != nil {
+			log.Warnf("[fs] failed to sync spider[%s] files: %v", id.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package fs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	errors2 "github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScanDirectory(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsSyncTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	os.MkdirAll(filepath.Join(rootDir, "subdir", "empty"), 0755)
+	ioutil.WriteFile(filepath.Join(rootDir, "main.py"), []byte("print('hello')"), 0644)
+	ioutil.WriteFile(filepath.Join(rootDir, "subdir", "a.txt"), []byte("hello world"), 0644)
+
+	files, err := ScanDirectory(rootDir)
+	assert.Nil(t, err)
+
+	// only regular files keyed by slash-separated relative path
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, "main.py")
+	assert.Contains(t, files, "subdir/a.txt")
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", files["subdir/a.txt"].Hash)
+
+	// non-existent directory yields no files
+	files, err = ScanDirectory(filepath.Join(rootDir, "missing"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestCheckSyncQuota(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsSyncTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	ioutil.WriteFile(filepath.Join(rootDir, "main.py"), []byte("12345"), 0644)
+	ioutil.WriteFile(filepath.Join(rootDir, "output.csv"), []byte("12345"), 0644)
+	localFiles, err := ScanDirectory(rootDir)
+	assert.Nil(t, err)
+
+	// main.py replaced by a larger file and a new file added: 10 + 5 + 5
+	masterFiles := map[string]entity.FsFileInfo{
+		"main.py": {Path: "main.py", FileSize: 10},
+		"lib.py":  {Path: "lib.py", FileSize: 5},
+	}
+	assert.Nil(t, checkSyncQuota(rootDir, 0, localFiles, masterFiles))
+	assert.Nil(t, checkSyncQuota(rootDir, 20, localFiles, masterFiles))
+	assert.True(t, errors.Is(checkSyncQuota(rootDir, 19, localFiles, masterFiles), errors2.ErrorFsQuotaExceeded))
+}
+
+func TestWorkspaceUsage(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsSyncTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	ioutil.WriteFile(filepath.Join(rootDir, "main.py"), []byte("12345"), 0644)
+	size, err := GetWorkspaceUsage(rootDir)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), size)
+
+	// adjusted incrementally without walking
+	ioutil.WriteFile(filepath.Join(rootDir, "lib.py"), []byte("123"), 0644)
+	AddWorkspaceUsage(rootDir, 3)
+	size, err = GetWorkspaceUsage(rootDir)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), size)
+
+	// recomputed after reset
+	os.Remove(filepath.Join(rootDir, "main.py"))
+	ResetWorkspaceUsage(rootDir)
+	size, err = GetWorkspaceUsage(rootDir)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), size)
+}
+
+func TestSyncManifest(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "fsSyncManifestTest")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+	viper.Set("workspace", rootDir)
+	defer viper.Set("workspace", nil)
+
+	id := primitive.NewObjectID()
+
+	// not synced yet
+	assert.Empty(t, loadSyncManifest(id))
+
+	// paths of synced files kept beside the workspace
+	assert.Nil(t, saveSyncManifest(id, map[string]entity.FsFileInfo{
+		"main.py":      {Path: "main.py"},
+		"subdir/a.txt": {Path: "subdir/a.txt"},
+	}))
+	assert.Equal(t, map[string]bool{"main.py": true, "subdir/a.txt": true}, loadSyncManifest(id))
+	assert.NotContains(t, getSyncManifestPath(id), GetSpiderWorkspacePath(id)+string(filepath.Separator))
+}
+
+func TestSyncProgressReporter(t *testing.T) {
+	p := newSyncProgressReporter(primitive.NewObjectID(), 2)
+	p.update("main.py", nil)
+	p.update("a.txt", errors.New("checksum mismatch"))
+	p.delete("old.py", nil)
+	p.finish(nil)
+
+	assert.Equal(t, 1, p.progress.Downloaded)
+	assert.Equal(t, 1, p.progress.Failed)
+	assert.Equal(t, 1, p.progress.Deleted)
+	assert.True(t, p.progress.Finished)
+	assert.ElementsMatch(t, []interfaces.SpiderSyncFileResult{
+		{Path: "main.py", Action: constants.SyncFileActionDownload},
+		{Path: "a.txt", Action: constants.SyncFileActionDownload, Error: "checksum mismatch"},
+		{Path: "old.py", Action: constants.SyncFileActionDelete},
+	}, p.progress.Files)
+}
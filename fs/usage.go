@@ -0,0 +1,66 @@
+package fs
+
+import (
+	"github.com/crawlab-team/crawlab-core/utils"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Disk usage of workspaces is tracked incrementally for quota checks, so that
+// writes do not walk the whole workspace each time. The usage is computed by
+// walking the workspace on first use and then adjusted by the size of each
+// write. It is recomputed after workspaceUsageTtl, as files may also be
+// written outside the file service, e.g. by running tasks.
+
+const workspaceUsageTtl = 5 * time.Minute
+
+type workspaceUsage struct {
+	mu   sync.Mutex
+	size int64
+	ts   time.Time // time of the last walk, zero if to be recomputed
+}
+
+var workspaceUsages sync.Map // root path -> *workspaceUsage
+
+func getWorkspaceUsage(root string) (u *workspaceUsage) {
+	res, _ := workspaceUsages.LoadOrStore(filepath.Clean(root), &workspaceUsage{})
+	return res.(*workspaceUsage)
+}
+
+// GetWorkspaceUsage returns the tracked disk usage of the workspace root
+func GetWorkspaceUsage(root string) (size int64, err error) {
+	u := getWorkspaceUsage(root)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.ts.IsZero() || time.Since(u.ts) > workspaceUsageTtl {
+		size, err := utils.GetDirSize(root)
+		if err != nil {
+			return 0, err
+		}
+		u.size = size
+		u.ts = time.Now()
+	}
+	return u.size, nil
+}
+
+// AddWorkspaceUsage adjusts the tracked disk usage of the workspace root by
+// delta bytes written (or removed if negative)
+func AddWorkspaceUsage(root string, delta int64) {
+	u := getWorkspaceUsage(root)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.ts.IsZero() {
+		return
+	}
+	u.size += delta
+}
+
+// ResetWorkspaceUsage makes the disk usage of the workspace root recomputed
+// on next use, e.g. after files are deleted or synced
+func ResetWorkspaceUsage(root string) {
+	u := getWorkspaceUsage(root)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ts = time.Time{}
+}
@@ -0,0 +1,59 @@
+package fs
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceDirPerm is the permission of workspace directories created on demand
+const WorkspaceDirPerm = paths.DirPerm
+
+// GetWorkspaceRoot returns the root directory of spider workspaces (see
+// paths.GetWorkspacePath)
+func GetWorkspaceRoot() (root string) {
+	return paths.GetWorkspacePath()
+}
+
+// GetSpiderWorkspacePath returns the workspace path of the spider, i.e. <root>/<spiderId>
+func GetSpiderWorkspacePath(id primitive.ObjectID) (path string) {
+	return filepath.Join(GetWorkspaceRoot(), id.Hex())
+}
+
+// EnsureSpiderWorkspacePath returns the workspace path of the spider and creates it if not exists
+func EnsureSpiderWorkspacePath(id primitive.ObjectID) (path string, err error) {
+	path = GetSpiderWorkspacePath(id)
+	if err := os.MkdirAll(path, WorkspaceDirPerm); err != nil {
+		return "", trace.TraceError(err)
+	}
+	return path, nil
+}
+
+// GetSpiderWorkspaceSize returns the disk usage of the spider workspace (bytes)
+func GetSpiderWorkspaceSize(id primitive.ObjectID) (size int64, err error) {
+	return utils.GetDirSize(GetSpiderWorkspacePath(id))
+}
+
+// GetWorkspaceSize returns the total disk usage of all spider workspaces (bytes)
+func GetWorkspaceSize() (size int64, err error) {
+	return utils.GetDirSize(GetWorkspaceRoot())
+}
+
+// ResolvePath joins the relative path to the root path and returns
+// errors.ErrorFsPathOutsideRoot if the result escapes the root
+func ResolvePath(root, path string) (fullPath string, err error) {
+	root = filepath.Clean(root)
+	fullPath = filepath.Join(root, path)
+	if fullPath == root {
+		return fullPath, nil
+	}
+	if !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return "", errors.ErrorFsPathOutsideRoot
+	}
+	return fullPath, nil
+}
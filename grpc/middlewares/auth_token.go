@@ -40,7 +40,10 @@ func GetAuthTokenFunc(nodeCfgSvc interfaces.NodeConfigService) grpc_auth.AuthFun
 
 func GetAuthTokenUnaryChainInterceptor(nodeCfgSvc interfaces.NodeConfigService) grpc.UnaryClientInterceptor {
 	// set auth key
-	md := metadata.Pairs(constants.GrpcHeaderAuthorization, nodeCfgSvc.GetAuthKey())
+	md := metadata.Pairs(
+		constants.GrpcHeaderAuthorization, nodeCfgSvc.GetAuthKey(),
+		constants.GrpcHeaderNodeKey, nodeCfgSvc.GetNodeKey(),
+	)
 	//header := metadata.MD{}
 	//header[constants.GrpcHeaderAuthorization] = []string{nodeCfgSvc.GetAuthKey()}
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
@@ -52,7 +55,10 @@ func GetAuthTokenUnaryChainInterceptor(nodeCfgSvc interfaces.NodeConfigService)
 
 func GetAuthTokenStreamChainInterceptor(nodeCfgSvc interfaces.NodeConfigService) grpc.StreamClientInterceptor {
 	// set auth key
-	md := metadata.Pairs(constants.GrpcHeaderAuthorization, nodeCfgSvc.GetAuthKey())
+	md := metadata.Pairs(
+		constants.GrpcHeaderAuthorization, nodeCfgSvc.GetAuthKey(),
+		constants.GrpcHeaderNodeKey, nodeCfgSvc.GetNodeKey(),
+	)
 	//header := metadata.MD{}
 	//header[constants.GrpcHeaderAuthorization] = []string{nodeCfgSvc.GetAuthKey()}
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
@@ -0,0 +1,174 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	grpc2 "github.com/crawlab-team/crawlab-grpc"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+const requestTracerName = "github.com/crawlab-team/crawlab-core/grpc"
+
+// RequestLogOptions of the request logging interceptors of grpc server
+type RequestLogOptions struct {
+	Level   string // log level of requests, "debug" or "info", empty to disable logging
+	Tracing bool   // whether to emit OpenTelemetry spans with the global tracer provider
+}
+
+// GetRequestLogOptions returns options configured by "grpc.server.log.level"
+// and "grpc.server.tracing"
+func GetRequestLogOptions() (opts RequestLogOptions) {
+	return RequestLogOptions{
+		Level:   viper.GetString("grpc.server.log.level"),
+		Tracing: viper.GetBool("grpc.server.tracing"),
+	}
+}
+
+// Enabled returns whether requests are logged or traced
+func (opts RequestLogOptions) Enabled() (ok bool) {
+	return opts.Level != "" || opts.Tracing
+}
+
+// GetRequestLogUnaryServerInterceptor logs each unary RPC with the method, key
+// of the calling node, duration and status code, and optionally traces it
+func GetRequestLogUnaryServerInterceptor(opts RequestLogOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		nodeKey := getNodeKeyFromContext(ctx)
+		if nodeKey == "" {
+			nodeKey = getNodeKeyFromRequest(req)
+		}
+		ctx, span := opts.startSpan(ctx, info.FullMethod, nodeKey)
+		start := time.Now()
+		res, err = handler(ctx, req)
+		opts.observe(span, info.FullMethod, nodeKey, start, err)
+		return res, err
+	}
+}
+
+// GetRequestLogStreamServerInterceptor logs each streaming RPC once it ends,
+// with the duration of the whole stream, and optionally traces it. The key of
+// the calling node is taken from the first received message if it is not in
+// the metadata.
+func GetRequestLogStreamServerInterceptor(opts RequestLogOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ws := &requestLogServerStream{
+			WrappedServerStream: grpc_middleware.WrapServerStream(ss),
+			nodeKey:             getNodeKeyFromContext(ss.Context()),
+		}
+		var span oteltrace.Span
+		ws.WrappedContext, span = opts.startSpan(ss.Context(), info.FullMethod, ws.nodeKey)
+		start := time.Now()
+		err = handler(srv, ws)
+		opts.observe(span, info.FullMethod, ws.getNodeKey(), start, err)
+		return err
+	}
+}
+
+func (opts RequestLogOptions) startSpan(ctx context.Context, method, nodeKey string) (ctx2 context.Context, span oteltrace.Span) {
+	if !opts.Tracing {
+		return ctx, nil
+	}
+	return otel.Tracer(requestTracerName).Start(ctx, method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+			attribute.String("crawlab.node_key", nodeKey),
+		),
+	)
+}
+
+func (opts RequestLogOptions) observe(span oteltrace.Span, method, nodeKey string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	// span
+	if span != nil {
+		span.SetAttributes(
+			attribute.String("crawlab.node_key", nodeKey),
+			attribute.Int("rpc.grpc.status_code", int(code)),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	// log
+	if opts.Level == "" {
+		return
+	}
+	entry := log.WithFields(log.Fields{
+		"method":   method,
+		"node_key": nodeKey,
+		"duration": duration.Milliseconds(),
+		"code":     code.String(),
+	})
+	switch {
+	case err != nil:
+		entry.WithError(err).Warn("[GrpcServer] request failed")
+	case opts.Level == "info":
+		entry.Info("[GrpcServer] request")
+	default:
+		entry.Debug("[GrpcServer] request")
+	}
+}
+
+// requestLogServerStream captures the node key from the first received message
+type requestLogServerStream struct {
+	*grpc_middleware.WrappedServerStream
+	nodeKey string
+	mu      sync.Mutex
+}
+
+func (s *requestLogServerStream) RecvMsg(m interface{}) (err error) {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.nodeKey == "" {
+		s.nodeKey = getNodeKeyFromRequest(m)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *requestLogServerStream) getNodeKey() (nodeKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nodeKey
+}
+
+func getNodeKeyFromContext(ctx context.Context) (nodeKey string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if res := md.Get(constants.GrpcHeaderNodeKey); len(res) > 0 {
+		return res[0]
+	}
+	return ""
+}
+
+func getNodeKeyFromRequest(req interface{}) (nodeKey string) {
+	switch r := req.(type) {
+	case *grpc2.Request:
+		return r.GetNodeKey()
+	case *grpc2.StreamMessage:
+		return r.GetNodeKey()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/spf13/viper"
+	"net"
+	"strings"
+)
+
+// Besides its address "grpc.server.address", the server listens to the
+// additional addresses "grpc.server.addresses" (a list, or comma-separated),
+// e.g. an internal interface for workers on the same host and an external
+// one. The same services are served on all of them, so subscriptions of
+// nodes are handled uniformly whichever address they connect to.
+
+// getConfigAdditionalAddresses returns the additional addresses of the server
+// configured by "grpc.server.addresses"
+func getConfigAdditionalAddresses() (addresses []interfaces.Address, err error) {
+	for _, item := range viper.GetStringSlice("grpc.server.addresses") {
+		for _, s := range strings.Split(item, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			address, err := entity.NewAddressFromString(s)
+			if err != nil {
+				return nil, err
+			}
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+// ValidateAddresses returns errors.ErrorGrpcServerAddressConflict if any two
+// of the addresses bind the same port on the same host, or on all interfaces
+// and any host
+func ValidateAddresses(addresses []interfaces.Address) (err error) {
+	type binding struct {
+		host string
+		port string
+	}
+	var bindings []binding
+	for _, address := range addresses {
+		host, port, err := net.SplitHostPort(address.String())
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", errors.ErrorGrpcServerAddressConflict, address.String(), err)
+		}
+		for _, b := range bindings {
+			if b.port != port {
+				continue
+			}
+			if b.host == host || isWildcardHost(b.host) || isWildcardHost(host) {
+				return fmt.Errorf("%w: %s and %s", errors.ErrorGrpcServerAddressConflict, net.JoinHostPort(b.host, b.port), address.String())
+			}
+		}
+		bindings = append(bindings, binding{host, port})
+	}
+	return nil
+}
+
+func isWildcardHost(host string) (ok bool) {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenBucket is a simple token bucket limiting the number of log lines per second.
+// A rate of 0 means unlimited.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// Take takes up to n tokens and returns the number of tokens taken
+func (b *tokenBucket) Take(n int) (taken int) {
+	if b == nil || b.rate <= 0 {
+		return n
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	taken = n
+	if float64(taken) > b.tokens {
+		taken = int(b.tokens)
+	}
+	b.tokens -= float64(taken)
+	return taken
+}
+
+// Return gives back tokens that were taken but not used
+func (b *tokenBucket) Return(n int) {
+	if b == nil || b.rate <= 0 || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += float64(n)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// newTokenBucket returns a bucket of the rate, whose burst is the rate rounded
+// up and at least 1, so that rates below 1 per second still let lines through
+func newTokenBucket(rate float64) (b *tokenBucket) {
+	burst := math.Max(1, math.Ceil(rate))
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// logRateLimiter limits incoming log lines of task streams with a global limit
+// shared by all streams and a limit for each stream. Lines exceeding the limits
+// are dropped and counted, and a marker line with the number of dropped lines
+// is inserted into the task log once lines of the task are accepted again.
+type logRateLimiter struct {
+	streamRate float64
+	global     *tokenBucket
+	received   int64
+	dropped    int64
+}
+
+func (l *logRateLimiter) NewStream() (s *streamLogRateLimiter) {
+	return &streamLogRateLimiter{
+		l:       l,
+		bucket:  newTokenBucket(l.streamRate),
+		pending: map[primitive.ObjectID]int{},
+	}
+}
+
+func (l *logRateLimiter) GetStats() (stats interfaces.GrpcLogStats) {
+	return interfaces.GrpcLogStats{
+		ReceivedLines:   atomic.LoadInt64(&l.received),
+		DroppedLines:    atomic.LoadInt64(&l.dropped),
+		StreamRateLimit: l.streamRate,
+		GlobalRateLimit: l.global.rate,
+	}
+}
+
+func newLogRateLimiter() (l *logRateLimiter) {
+	streamRate := 1000.0
+	globalRate := 10000.0
+	if viper.IsSet("grpc.server.logs.streamRateLimit") {
+		streamRate = viper.GetFloat64("grpc.server.logs.streamRateLimit")
+	}
+	if viper.IsSet("grpc.server.logs.globalRateLimit") {
+		globalRate = viper.GetFloat64("grpc.server.logs.globalRateLimit")
+	}
+	return &logRateLimiter{
+		streamRate: streamRate,
+		global:     newTokenBucket(globalRate),
+	}
+}
+
+// streamLogRateLimiter limits log lines of a single task stream
+type streamLogRateLimiter struct {
+	l       *logRateLimiter
+	bucket  *tokenBucket
+	pending map[primitive.ObjectID]int // dropped lines not yet reported by task
+}
+
+// Filter returns the log lines (and their levels) allowed to be inserted, prefixed
+// with a marker line if lines of the task were dropped before
+func (s *streamLogRateLimiter) Filter(taskId primitive.ObjectID, logs []string, levels []string) (res []string, resLevels []string) {
+	atomic.AddInt64(&s.l.received, int64(len(logs)))
+
+	// stream limit first, then global limit
+	n := s.bucket.Take(len(logs))
+	m := s.l.global.Take(n)
+	s.bucket.Return(n - m)
+
+	if dropped := len(logs) - m; dropped > 0 {
+		atomic.AddInt64(&s.l.dropped, int64(dropped))
+		s.pending[taskId] += dropped
+	}
+	if m == 0 {
+		return nil, nil
+	}
+
+	if marker := s.marker(taskId); marker != "" {
+		res = append(res, marker)
+		resLevels = append(resLevels, constants.LogLevelWarn)
+	}
+	res = append(res, logs[:m]...)
+	for i := 0; i < m; i++ {
+		level := constants.LogLevelInfo
+		if i < len(levels) && levels[i] != "" {
+			level = levels[i]
+		}
+		resLevels = append(resLevels, level)
+	}
+	return res, resLevels
+}
+
+// Flush returns marker lines of all tasks with unreported dropped lines
+func (s *streamLogRateLimiter) Flush() (res map[primitive.ObjectID]string) {
+	res = map[primitive.ObjectID]string{}
+	for taskId := range s.pending {
+		if marker := s.marker(taskId); marker != "" {
+			res[taskId] = marker
+		}
+	}
+	return res
+}
+
+func (s *streamLogRateLimiter) marker(taskId primitive.ObjectID) (line string) {
+	dropped := s.pending[taskId]
+	if dropped == 0 {
+		return ""
+	}
+	delete(s.pending, taskId)
+	return fmt.Sprintf("[crawlab] %d log lines dropped due to rate limiting", dropped)
+}
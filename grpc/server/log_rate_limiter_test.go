@@ -0,0 +1,21 @@
+package server
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTokenBucket_Burst(t *testing.T) {
+	// rates below 1 per second still let a line through
+	b := newTokenBucket(0.5)
+	require.Equal(t, 1, b.Take(5))
+	require.Equal(t, 0, b.Take(5))
+
+	// fractional rates are rounded up
+	b = newTokenBucket(2.5)
+	require.Equal(t, 3, b.Take(5))
+
+	// unlimited
+	b = newTokenBucket(0)
+	require.Equal(t, 5, b.Take(5))
+}
@@ -58,6 +58,9 @@ func (svr MessageServer) Connect(stream grpc.MessageService_ConnectServer) (err
 				return err
 			}
 			svr.redirectMessage(sub, msg)
+		default:
+			// e.g. sent by a newer worker
+			log.Warnf("[MessageServer] ignored message of unknown code %d from node[%s]", msg.Code, nodeKey)
 		}
 	}
 }
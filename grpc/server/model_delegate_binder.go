@@ -83,6 +83,8 @@ func (b *ModelDelegateBinder) Bind() (res interface{}, err error) {
 		return b.process(&m.Environment)
 	case interfaces.ModelIdDependencySetting:
 		return b.process(&m.DependencySetting)
+	case interfaces.ModelIdTaskEvent:
+		return b.process(&m.TaskEvent)
 	default:
 		return nil, errors.ErrorModelInvalidModelId
 	}
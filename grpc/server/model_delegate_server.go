@@ -6,6 +6,7 @@ import (
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	grpc "github.com/crawlab-team/crawlab-grpc"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type ModelDelegateServer struct {
@@ -35,6 +36,12 @@ func (svr ModelDelegateServer) Do(ctx context.Context, req *grpc.Request) (res *
 		err = d.Add()
 	case interfaces.ModelDelegateMethodSave:
 		err = d.Save()
+	case interfaces.ModelDelegateMethodUpdateFields:
+		var fields bson.M
+		if err := bson.Unmarshal(msg.GetFields(), &fields); err != nil {
+			return HandleError(err)
+		}
+		err = d.UpdateFields(fields)
 	case interfaces.ModelDelegateMethodDelete:
 		err = d.Delete()
 	case interfaces.ModelDelegateMethodGetArtifact, interfaces.ModelDelegateMethodRefresh:
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/apex/log"
+	config2 "github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
@@ -12,7 +13,11 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
+	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-grpc"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
 )
@@ -54,6 +59,11 @@ func (svr NodeServer) Register(ctx context.Context, req *grpc.Request) (res *grp
 		return HandleError(errors.ErrorModelMissingRequiredData)
 	}
 
+	// version compatibility
+	if err := checkNodeVersion(nodeKey, nodeInfo.Version); err != nil {
+		return HandleError(err)
+	}
+
 	// find in db
 	node, err := svr.modelSvc.GetNodeByKey(nodeKey, nil)
 	if err == nil {
@@ -61,11 +71,22 @@ func (svr NodeServer) Register(ctx context.Context, req *grpc.Request) (res *grp
 			// error: cannot register master node
 			return HandleError(errors.ErrorGrpcNotAllowed)
 		} else {
-			// register existing
-			node.Status = constants.NodeStatusRegistered
+			// register existing (draining nodes keep draining after reconnecting)
+			if node.Status != constants.NodeStatusDraining {
+				node.Status = constants.NodeStatusRegistered
+			}
 			node.Active = true
+			if nodeInfo.Capabilities != nil {
+				node.Capabilities = nodeInfo.Capabilities
+			}
+			node.Version = nodeInfo.Version
 			nodeD := delegate.NewModelNodeDelegate(node)
-			if err := nodeD.Save(); err != nil {
+			if err := nodeD.UpdateFields(bson.M{
+				"status":       node.Status,
+				"active":       node.Active,
+				"capabilities": node.Capabilities,
+				"version":      node.Version,
+			}); err != nil {
 				return HandleError(err)
 			}
 			var ok bool
@@ -78,15 +99,18 @@ func (svr NodeServer) Register(ctx context.Context, req *grpc.Request) (res *grp
 	} else if err == mongo.ErrNoDocuments {
 		// register new
 		node = &models.Node{
-			Key:         nodeKey,
-			Name:        nodeInfo.Name,
-			Ip:          nodeInfo.Ip,
-			Hostname:    nodeInfo.Hostname,
-			Description: nodeInfo.Description,
-			MaxRunners:  nodeInfo.MaxRunners,
-			Status:      constants.NodeStatusRegistered,
-			Active:      true,
-			Enabled:     true,
+			Key:          nodeKey,
+			Name:         nodeInfo.Name,
+			Ip:           nodeInfo.Ip,
+			Hostname:     nodeInfo.Hostname,
+			Description:  nodeInfo.Description,
+			MaxRunners:   nodeInfo.MaxRunners,
+			Capabilities: nodeInfo.Capabilities,
+			Version:      nodeInfo.Version,
+			Status:       constants.NodeStatusRegistered,
+			Active:       true,
+			Enabled:      true,
+			Schedulable:  true,
 		}
 		if node.Name == "" {
 			node.Name = nodeKey
@@ -111,6 +135,25 @@ func (svr NodeServer) Register(ctx context.Context, req *grpc.Request) (res *grp
 	return HandleSuccessWithData(node)
 }
 
+// checkNodeVersion logs a warning if the version of the worker is not
+// compatible with master (see config.IsVersionCompatible), and returns
+// errors.ErrorNodeVersionIncompatible if "node.master.versionPolicy" is
+// constants.NodeVersionPolicyRefuse
+func checkNodeVersion(nodeKey, version string) (err error) {
+	if config2.IsVersionCompatible(config2.GetVersion(), version) {
+		return nil
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	if viper.GetString("node.master.versionPolicy") == constants.NodeVersionPolicyRefuse {
+		log.Errorf("[NodeServer] refused worker[%s] of version %s incompatible with master version %s", nodeKey, version, config2.GetVersion())
+		return errors.ErrorNodeVersionIncompatible
+	}
+	log.Warnf("[NodeServer] worker[%s] of version %s may be incompatible with master version %s", nodeKey, version, config2.GetVersion())
+	return nil
+}
+
 // SendHeartbeat from worker to master
 func (svr NodeServer) SendHeartbeat(ctx context.Context, req *grpc.Request) (res *grpc.Response, err error) {
 	// find in db
@@ -133,6 +176,11 @@ func (svr NodeServer) SendHeartbeat(ctx context.Context, req *grpc.Request) (res
 		return HandleError(err)
 	}
 
+	// leases of locks of tasks dispatched to the node
+	if err := task.RenewTaskLocks(node.Key); err != nil {
+		trace.PrintError(err)
+	}
+
 	return HandleSuccessWithData(node)
 }
 
@@ -156,6 +204,9 @@ func (svr NodeServer) Subscribe(request *grpc.Request, stream grpc.NodeService_S
 
 	log.Infof("[NodeServer] master subscribed node[%s]", request.NodeKey)
 
+	// runtime config of the node resolved by its tags
+	go svr.pushNodeConfig(request.NodeKey)
+
 	// Keep this scope alive because once this scope exits - the stream is closed
 	for {
 		select {
@@ -169,6 +220,28 @@ func (svr NodeServer) Subscribe(request *grpc.Request, stream grpc.NodeService_S
 	}
 }
 
+// pushNodeConfig pushes to the subscribed worker node its runtime config in
+// the setting constants.SettingKeyNodeConfig, resolved by its tags
+func (svr NodeServer) pushNodeConfig(nodeKey string) {
+	n, err := svr.modelSvc.GetNodeByKey(nodeKey, nil)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	settings, err := svr.modelSvc.GetNodeConfig(n.Id)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	if len(settings) == 0 {
+		return
+	}
+	code := grpc.StreamMessageCode(constants.GrpcStreamMessageCodeConfigUpdate)
+	if err := svr.server.SendStreamMessageWithData("node:"+nodeKey, code, &entity.ConfigUpdateMessage{Settings: settings}); err != nil {
+		log.Warnf("[NodeServer] cannot push node config to node[%s]: %v", nodeKey, err)
+	}
+}
+
 func (svr NodeServer) Unsubscribe(ctx context.Context, req *grpc.Request) (res *grpc.Response, err error) {
 	sub, err := svr.server.GetSubscribe("node:" + req.NodeKey)
 	if err != nil {
@@ -180,7 +253,7 @@ func (svr NodeServer) Unsubscribe(ctx context.Context, req *grpc.Request) (res *
 	default:
 		// Default case is to avoid blocking in case client has already unsubscribed
 	}
-	svr.server.DeleteSubscribe(req.NodeKey)
+	svr.server.DeleteSubscribe("node:" + req.NodeKey)
 	return &grpc.Response{
 		Code:    grpc.ResponseCode_OK,
 		Message: "unsubscribed successfully",
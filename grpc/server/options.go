@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"time"
 )
 
 type Option func(svr interfaces.GrpcServer)
@@ -18,6 +19,18 @@ func WithAddress(address interfaces.Address) Option {
 	}
 }
 
+func WithAdditionalAddress(address interfaces.Address) Option {
+	return func(svr interfaces.GrpcServer) {
+		svr.AddAddress(address)
+	}
+}
+
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(svr interfaces.GrpcServer) {
+		svr.SetStopTimeout(timeout)
+	}
+}
+
 type NodeServerOption func(svr *NodeServer)
 
 func WithServerNodeServerService(server interfaces.GrpcServer) NodeServerOption {
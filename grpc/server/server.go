@@ -22,6 +22,7 @@ import (
 	"google.golang.org/grpc"
 	"net"
 	"sync"
+	"time"
 )
 
 var subs = sync.Map{}
@@ -36,12 +37,14 @@ type Server struct {
 	modelBaseServiceSvr *ModelBaseServiceServer
 
 	// settings
-	cfgPath string
-	address interfaces.Address
+	cfgPath             string
+	address             interfaces.Address
+	additionalAddresses []interfaces.Address // also listened to, e.g. internal and external interfaces
+	stopTimeout         time.Duration        // max wait of graceful stop before force stop
 
 	// internals
 	svr     *grpc.Server
-	l       net.Listener
+	ls      []net.Listener // listeners of all addresses
 	stopped bool
 }
 
@@ -55,44 +58,63 @@ func (svr *Server) Init() (err error) {
 }
 
 func (svr *Server) Start() (err error) {
-	// grpc server binding address
-	address := svr.address.String()
+	// grpc server binding addresses
+	addresses := svr.GetAddresses()
+	if err := ValidateAddresses(addresses); err != nil {
+		return trace.TraceError(err)
+	}
 
-	// listener
-	svr.l, err = net.Listen("tcp", address)
-	if err != nil {
-		_ = trace.TraceError(err)
-		return errors.ErrorGrpcServerFailedToListen
+	// listeners
+	for _, address := range addresses {
+		l, err := net.Listen("tcp", address.String())
+		if err != nil {
+			_ = trace.TraceError(err)
+			svr.closeListeners()
+			return errors.ErrorGrpcServerFailedToListen
+		}
+		svr.ls = append(svr.ls, l)
+		log.Infof("grpc server listens to %s", address.String())
 	}
-	log.Infof("grpc server listens to %s", address)
 
-	// start grpc server
-	go func() {
-		if err := svr.svr.Serve(svr.l); err != nil {
-			if err == grpc.ErrServerStopped {
-				return
+	// start grpc server on all listeners
+	for _, l := range svr.ls {
+		go func(l net.Listener) {
+			if err := svr.svr.Serve(l); err != nil {
+				if err == grpc.ErrServerStopped {
+					return
+				}
+				trace.PrintError(err)
+				log.Error(errors.ErrorGrpcServerFailedToServe.Error())
 			}
-			trace.PrintError(err)
-			log.Error(errors.ErrorGrpcServerFailedToServe.Error())
-		}
-	}()
+		}(l)
+	}
 
 	return nil
 }
 
 func (svr *Server) Stop() (err error) {
-	// skip if listener is nil
-	if svr.l == nil {
+	// skip if not listening
+	if len(svr.ls) == 0 {
 		return nil
 	}
 
-	// graceful stop
+	// graceful stop, force stop if not completed within stop timeout
 	log.Infof("grpc server stopping...")
-	svr.svr.Stop()
+	done := make(chan struct{})
+	go func() {
+		svr.svr.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(svr.stopTimeout):
+		log.Warnf("grpc server graceful stop not completed in %s, force stopping", svr.stopTimeout)
+		svr.svr.Stop()
+	}
 
-	// close listener
-	log.Infof("grpc server closing listener...")
-	_ = svr.l.Close()
+	// close listeners
+	log.Infof("grpc server closing listeners...")
+	svr.closeListeners()
 
 	// mark as stopped
 	svr.stopped = true
@@ -113,10 +135,27 @@ func (svr *Server) Register() (err error) {
 	return nil
 }
 
+func (svr *Server) GetAddress() (address interfaces.Address) {
+	return svr.address
+}
+
+// GetAddresses returns the address and the additional addresses
+func (svr *Server) GetAddresses() (addresses []interfaces.Address) {
+	return append([]interfaces.Address{svr.address}, svr.additionalAddresses...)
+}
+
+func (svr *Server) AddAddress(address interfaces.Address) {
+	svr.additionalAddresses = append(svr.additionalAddresses, address)
+}
+
 func (svr *Server) SetAddress(address interfaces.Address) {
 	svr.address = address
 }
 
+func (svr *Server) SetStopTimeout(timeout time.Duration) {
+	svr.stopTimeout = timeout
+}
+
 func (svr *Server) GetConfigPath() (path string) {
 	return svr.cfgPath
 }
@@ -179,6 +218,17 @@ func (svr *Server) IsStopped() (res bool) {
 	return svr.stopped
 }
 
+func (svr *Server) GetLogStats() (stats interfaces.GrpcLogStats) {
+	return svr.taskSvr.GetLogStats()
+}
+
+func (svr *Server) closeListeners() {
+	for _, l := range svr.ls {
+		_ = l.Close()
+	}
+	svr.ls = nil
+}
+
 func (svr *Server) recoveryHandlerFunc(p interface{}) (err error) {
 	err = errors.NewError(errors.ErrorPrefixGrpc, fmt.Sprintf("%v", p))
 	trace.PrintError(err)
@@ -194,6 +244,7 @@ func NewServer(opts ...Option) (svr2 interfaces.GrpcServer, err error) {
 			Host: constants.DefaultGrpcServerHost,
 			Port: constants.DefaultGrpcServerPort,
 		}),
+		stopTimeout: 15 * time.Second,
 	}
 
 	// options
@@ -244,16 +295,24 @@ func NewServer(opts ...Option) (svr2 interfaces.GrpcServer, err error) {
 		grpc_recovery.WithRecoveryHandler(svr.recoveryHandlerFunc),
 	}
 
+	// interceptors
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		grpc_recovery.UnaryServerInterceptor(recoveryOpts...),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		grpc_recovery.StreamServerInterceptor(recoveryOpts...),
+	}
+	if logOpts := middlewares.GetRequestLogOptions(); logOpts.Enabled() {
+		unaryInterceptors = append(unaryInterceptors, middlewares.GetRequestLogUnaryServerInterceptor(logOpts))
+		streamInterceptors = append(streamInterceptors, middlewares.GetRequestLogStreamServerInterceptor(logOpts))
+	}
+	unaryInterceptors = append(unaryInterceptors, grpc_auth.UnaryServerInterceptor(middlewares.GetAuthTokenFunc(svr.nodeCfgSvc)))
+	streamInterceptors = append(streamInterceptors, grpc_auth.StreamServerInterceptor(middlewares.GetAuthTokenFunc(svr.nodeCfgSvc)))
+
 	// grpc server
 	svr.svr = grpc.NewServer(
-		grpc_middleware.WithUnaryServerChain(
-			grpc_recovery.UnaryServerInterceptor(recoveryOpts...),
-			grpc_auth.UnaryServerInterceptor(middlewares.GetAuthTokenFunc(svr.nodeCfgSvc)),
-		),
-		grpc_middleware.WithStreamServerChain(
-			grpc_recovery.StreamServerInterceptor(recoveryOpts...),
-			grpc_auth.StreamServerInterceptor(middlewares.GetAuthTokenFunc(svr.nodeCfgSvc)),
-		),
+		grpc_middleware.WithUnaryServerChain(unaryInterceptors...),
+		grpc_middleware.WithStreamServerChain(streamInterceptors...),
 	)
 
 	// initialize
@@ -290,6 +349,17 @@ func GetServer(path string, opts ...Option) (svr interfaces.GrpcServer, err erro
 		}
 		opts = append(opts, WithAddress(address))
 	}
+	additionalAddresses, err := getConfigAdditionalAddresses()
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range additionalAddresses {
+		opts = append(opts, WithAdditionalAddress(address))
+	}
+
+	if stopTimeoutSeconds := viper.GetInt("grpc.server.stopTimeout"); stopTimeoutSeconds > 0 {
+		opts = append(opts, WithStopTimeout(time.Duration(stopTimeoutSeconds)*time.Second))
+	}
 
 	res, ok := serverStore.Load(path)
 	if ok {
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	grpc "github.com/crawlab-team/crawlab-grpc"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"sync"
+	"time"
+)
+
+// Workers declaring support by the header constants.GrpcHeaderTaskAck in
+// fetches accept or reject each task dispatched to them, e.g. reject if they
+// have reached their max runners in the meantime. Tasks rejected, or not
+// acknowledged within the ack timeout, are returned to the task queue to be
+// fetched again, by another node unless the task is pinned to the node.
+
+// isTaskAckEnabled returns whether dispatched tasks are to be acknowledged by
+// workers supporting it, which is configured by "task.dispatch.ack" (default
+// true)
+func isTaskAckEnabled() (ok bool) {
+	if !viper.IsSet("task.dispatch.ack") {
+		return true
+	}
+	return viper.GetBool("task.dispatch.ack")
+}
+
+// getTaskAckTimeout returns how long a dispatched task waits for the ack of
+// the worker, which is configured by "task.dispatch.ackTimeout" (seconds, 30
+// seconds by default)
+func getTaskAckTimeout() (timeout time.Duration) {
+	if seconds := viper.GetInt("task.dispatch.ackTimeout"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// isTaskAckSupported returns whether the worker fetching tasks acknowledges them
+func isTaskAckSupported(ctx context.Context) (ok bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(constants.GrpcHeaderTaskAck)) > 0
+}
+
+// pendingTaskAck is a task dispatched to a node awaiting its ack
+type pendingTaskAck struct {
+	tq    models.TaskQueueItem // dequeued item to re-queue on reject
+	node  *models.Node
+	timer *time.Timer
+}
+
+// taskAckTracker tracks tasks dispatched and awaiting acks of nodes
+type taskAckTracker struct {
+	pending map[primitive.ObjectID]*pendingTaskAck
+	mu      sync.Mutex
+}
+
+// Add tracks the dispatched task, calling onTimeout if it is not removed
+// within the timeout
+func (t *taskAckTracker) Add(tq models.TaskQueueItem, n *models.Node, timeout time.Duration, onTimeout func(p *pendingTaskAck)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := &pendingTaskAck{tq: tq, node: n}
+	p.timer = time.AfterFunc(timeout, func() {
+		if p, ok := t.Remove(tq.Id); ok {
+			onTimeout(p)
+		}
+	})
+	t.pending[tq.Id] = p
+}
+
+// Remove stops tracking the task, returning false if it is not tracked, e.g.
+// timed out already
+func (t *taskAckTracker) Remove(id primitive.ObjectID) (p *pendingTaskAck, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok = t.pending[id]
+	if !ok {
+		return nil, false
+	}
+	p.timer.Stop()
+	delete(t.pending, id)
+	return p, true
+}
+
+// RemoveOfNode stops tracking the task like Remove, but only if it has been
+// dispatched to the node of the key, returning false and keeping it tracked
+// otherwise
+func (t *taskAckTracker) RemoveOfNode(id primitive.ObjectID, nodeKey string) (p *pendingTaskAck, ok bool) {
+	t.mu.Lock()
+	p, ok = t.pending[id]
+	t.mu.Unlock()
+	if !ok || p.node == nil || p.node.Key != nodeKey {
+		return nil, false
+	}
+	return t.Remove(id)
+}
+
+func newTaskAckTracker() (t *taskAckTracker) {
+	return &taskAckTracker{
+		pending: map[primitive.ObjectID]*pendingTaskAck{},
+	}
+}
+
+// requireTaskAck tracks the task dispatched to the node and requires its ack
+// by the header of the response
+func (svr TaskServer) requireTaskAck(ctx context.Context, tq models.TaskQueueItem, n *models.Node) {
+	svr.acks.Add(tq, n, getTaskAckTimeout(), func(p *pendingTaskAck) {
+		svr.requeueTask(p, fmt.Sprintf("not acknowledged by node %s within %v", p.node.Name, getTaskAckTimeout()))
+	})
+	if err := grpc2.SetHeader(ctx, metadata.Pairs(constants.GrpcHeaderTaskAck, "1")); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// handleTaskAck handles the ack of a dispatched task and responds whether the
+// worker may run the task, i.e. false if it is accepted after the ack timeout,
+// as it has been returned to the task queue
+func (svr TaskServer) handleTaskAck(stream grpc.TaskService_SubscribeServer, msg *grpc.StreamMessage) (err error) {
+	var ack entity.TaskAckMessage
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		return trace.TraceError(err)
+	}
+	// only the node the task is dispatched to can ack it
+	p, ok := svr.acks.RemoveOfNode(ack.TaskId, msg.NodeKey)
+	if !ok {
+		log.Warnf("[TaskServer] task[%s] ack of node %s not accepted, as the task is not awaiting its ack", ack.TaskId.Hex(), msg.NodeKey)
+	}
+	if ok && !ack.Accepted {
+		svr.requeueTask(p, fmt.Sprintf("rejected by node %s: %s", p.node.Name, ack.Reason))
+	}
+	res, err := HandleSuccessWithData(ok && ack.Accepted)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(res)
+}
+
+// requeueTask returns the task rejected by the node to the task queue. The
+// task is fetched by any node unless pinned to the node by its mode.
+func (svr TaskServer) requeueTask(p *pendingTaskAck, reason string) {
+	log.Warnf("[TaskServer] task[%s] %s, returning to task queue", p.tq.Id.Hex(), reason)
+
+	// task
+	t, err := svr.modelSvc.GetTaskById(p.tq.Id)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	if t.Status != constants.TaskStatusPending {
+		// e.g. cancelled in the meantime
+		return
+	}
+
+	// queue item
+	tq := p.tq
+	if t.Mode == constants.RunTypeRandom {
+		tq.NodeId = primitive.NilObjectID
+	}
+	col := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue)
+	if _, err := col.GetCollection().InsertOne(col.GetContext(), &tq); err != nil {
+		trace.PrintError(err)
+		return
+	}
+
+	// node of task
+	if err := delegate.NewModelDelegate(t).UpdateFields(bson.M{"node_id": tq.NodeId}); err != nil {
+		trace.PrintError(err)
+	}
+
+	// no longer counted as dispatched
+	svr.concurrency.Lock()
+	svr.concurrency.RemoveDispatched(t.Id)
+	svr.concurrency.Unlock()
+
+	// task event
+	e := task.NewTaskEvent(t.Id, constants.TaskEventRejected, task.GetNodeActor(p.node), reason)
+	e.NodeId = p.node.Id
+	task.AddTaskEvent(e)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/metadata"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskAckTracker(t *testing.T) {
+	tr := newTaskAckTracker()
+	n := &models.Node{Id: primitive.NewObjectID(), Key: "worker-1", Name: "worker-1"}
+	var timeouts int32
+	onTimeout := func(p *pendingTaskAck) {
+		atomic.AddInt32(&timeouts, 1)
+	}
+
+	// acked in time
+	tq := models.TaskQueueItem{Id: primitive.NewObjectID()}
+	tr.Add(tq, n, 100*time.Millisecond, onTimeout)
+	p, ok := tr.Remove(tq.Id)
+	require.True(t, ok)
+	require.Equal(t, tq.Id, p.tq.Id)
+	require.Equal(t, n, p.node)
+	_, ok = tr.Remove(tq.Id)
+	require.False(t, ok)
+
+	// acked only by the node the task is dispatched to
+	tq = models.TaskQueueItem{Id: primitive.NewObjectID()}
+	tr.Add(tq, n, 100*time.Millisecond, onTimeout)
+	_, ok = tr.RemoveOfNode(tq.Id, "worker-2")
+	require.False(t, ok)
+	p, ok = tr.RemoveOfNode(tq.Id, n.Key)
+	require.True(t, ok)
+	require.Equal(t, tq.Id, p.tq.Id)
+
+	// timed out, not acked afterwards
+	tq = models.TaskQueueItem{Id: primitive.NewObjectID()}
+	tr.Add(tq, n, 50*time.Millisecond, onTimeout)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&timeouts) == 1
+	}, time.Second, 10*time.Millisecond)
+	_, ok = tr.Remove(tq.Id)
+	require.False(t, ok)
+
+	// timer of the task acked in time stopped
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&timeouts))
+}
+
+func TestIsTaskAckSupported(t *testing.T) {
+	require.False(t, isTaskAckSupported(context.Background()))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(constants.GrpcHeaderTaskAck, "1"))
+	require.True(t, isTaskAckSupported(ctx))
+}
+
+func TestTaskServer_requeueTask(t *testing.T) {
+	viper.Set("mongo.db", "crawlab_test")
+	cleanup := func() {
+		_ = mongo.GetMongoCol(interfaces.ModelColNameTask).Delete(nil)
+		_ = mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).Delete(nil)
+		_ = mongo.GetMongoCol(interfaces.ModelColNameTaskEvent).Delete(nil)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+	modelSvc, err := service.NewService()
+	require.Nil(t, err)
+	svr := TaskServer{
+		modelSvc:    modelSvc,
+		concurrency: newTaskConcurrencyLimiter(),
+		acks:        newTaskAckTracker(),
+	}
+	n := &models.Node{Id: primitive.NewObjectID(), Key: "worker-1", Name: "worker-1"}
+
+	// random mode, returned to the task queue for any node
+	tr := &models.Task{Id: primitive.NewObjectID(), Status: constants.TaskStatusPending, Mode: constants.RunTypeRandom, NodeId: n.Id}
+	require.Nil(t, delegate.NewModelDelegate(tr).Add())
+	svr.requeueTask(&pendingTaskAck{tq: models.TaskQueueItem{Id: tr.Id, NodeId: n.Id, Dispatched: true}, node: n}, "rejected")
+	tq, err := modelSvc.GetTaskQueueItemById(tr.Id)
+	require.Nil(t, err)
+	require.True(t, tq.NodeId.IsZero())
+	tr, err = modelSvc.GetTaskById(tr.Id)
+	require.Nil(t, err)
+	require.True(t, tr.NodeId.IsZero())
+
+	// pinned to the node
+	ts := &models.Task{Id: primitive.NewObjectID(), Status: constants.TaskStatusPending, Mode: constants.RunTypeSelectedNodes, NodeId: n.Id}
+	require.Nil(t, delegate.NewModelDelegate(ts).Add())
+	svr.requeueTask(&pendingTaskAck{tq: models.TaskQueueItem{Id: ts.Id, NodeId: n.Id}, node: n}, "rejected")
+	tq, err = modelSvc.GetTaskQueueItemById(ts.Id)
+	require.Nil(t, err)
+	require.Equal(t, n.Id, tq.NodeId)
+
+	// cancelled in the meantime, not returned
+	tc := &models.Task{Id: primitive.NewObjectID(), Status: constants.TaskStatusCancelled, NodeId: n.Id}
+	require.Nil(t, delegate.NewModelDelegate(tc).Add())
+	svr.requeueTask(&pendingTaskAck{tq: models.TaskQueueItem{Id: tc.Id, NodeId: n.Id}, node: n}, "rejected")
+	_, err = modelSvc.GetTaskQueueItemById(tc.Id)
+	require.NotNil(t, err)
+}
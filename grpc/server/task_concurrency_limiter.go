@@ -0,0 +1,100 @@
+package server
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// dispatchedTaskTtl is how long a dispatched task is counted as running while
+// it is still pending, i.e. before the runner on the worker starts it
+const dispatchedTaskTtl = time.Minute
+
+// throttleLogInterval is the min interval of logs of throttled dispatches
+const throttleLogInterval = 30 * time.Second
+
+// taskConcurrencyLimiter enforces the max number of concurrently running tasks
+// of the cluster (see task.GetMaxConcurrentTasks) when tasks are fetched by
+// workers. Tasks dispatched but not yet started are counted as well, so that
+// concurrent fetches do not exceed the limit.
+type taskConcurrencyLimiter struct {
+	dispatched map[primitive.ObjectID]time.Time
+	lastLogTs  time.Time
+	mu         sync.Mutex
+}
+
+// Lock serializes counting and dispatching of tasks
+func (l *taskConcurrencyLimiter) Lock() {
+	l.mu.Lock()
+}
+
+func (l *taskConcurrencyLimiter) Unlock() {
+	l.mu.Unlock()
+}
+
+// Count returns the number of running and recently dispatched tasks. It must
+// be called with the lock held. Running tasks are taken from the running task
+// counter if it is synced, otherwise counted in the database.
+func (l *taskConcurrencyLimiter) Count() (n int, err error) {
+	c := task.GetRunningTaskCounter()
+	synced := c.IsSynced()
+
+	var ids []primitive.ObjectID
+	for id, ts := range l.dispatched {
+		if time.Since(ts) > dispatchedTaskTtl || (synced && c.IsRunning(id)) {
+			// expired or already counted as running
+			delete(l.dispatched, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if synced {
+		return c.Total() + len(ids), nil
+	}
+
+	query := bson.M{"status": constants.TaskStatusRunning}
+	if len(ids) > 0 {
+		query = bson.M{"$or": bson.A{
+			query,
+			bson.M{"_id": bson.M{"$in": ids}, "status": constants.TaskStatusPending},
+		}}
+	}
+	n, err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(query)
+	if err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return n, nil
+}
+
+// AddDispatched records the dispatched task. It must be called with the lock held.
+func (l *taskConcurrencyLimiter) AddDispatched(id primitive.ObjectID) {
+	l.dispatched[id] = time.Now()
+}
+
+// RemoveDispatched stops counting the dispatched task, e.g. returned to the
+// task queue. It must be called with the lock held.
+func (l *taskConcurrencyLimiter) RemoveDispatched(id primitive.ObjectID) {
+	delete(l.dispatched, id)
+}
+
+// LogThrottle logs that dispatching is throttled, at most once per throttleLogInterval
+func (l *taskConcurrencyLimiter) LogThrottle(count, max int) {
+	if time.Since(l.lastLogTs) < throttleLogInterval {
+		return
+	}
+	l.lastLogTs = time.Now()
+	log.Infof("[TaskServer] task dispatch throttled: %d running tasks reached cluster limit %d", count, max)
+}
+
+func newTaskConcurrencyLimiter() (l *taskConcurrencyLimiter) {
+	return &taskConcurrencyLimiter{
+		dispatched: map[primitive.ObjectID]time.Time{},
+	}
+}
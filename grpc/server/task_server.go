@@ -2,7 +2,8 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	errors2 "errors"
+	"fmt"
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
@@ -12,7 +13,9 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
+	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-core/task/stats"
+	"github.com/crawlab-team/crawlab-core/tracing"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	grpc "github.com/crawlab-team/crawlab-grpc"
@@ -20,7 +23,12 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/dig"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"io"
 	"strings"
 )
@@ -34,11 +42,22 @@ type TaskServer struct {
 	statsSvc interfaces.TaskStatsService
 
 	// internals
-	server interfaces.GrpcServer
+	server      interfaces.GrpcServer
+	logLimiter  *logRateLimiter
+	concurrency *taskConcurrencyLimiter
+	acks        *taskAckTracker
+	skips       *taskSkipTracker
 }
 
 // Subscribe to task stream when a task runner in a node starts
 func (svr TaskServer) Subscribe(stream grpc.TaskService_SubscribeServer) (err error) {
+	logLimiter := svr.logLimiter.NewStream()
+	var tid primitive.ObjectID
+	defer func() {
+		svr.flushDroppedLogs(logLimiter)
+		svr.flushData(tid)
+		svr.statsSvc.ReleaseLogs(tid)
+	}()
 	for {
 		msg, err := stream.Recv()
 		utils.LogDebug(msg.String())
@@ -52,16 +71,23 @@ func (svr TaskServer) Subscribe(stream grpc.TaskService_SubscribeServer) (err er
 			trace.PrintError(err)
 			continue
 		}
+		var id primitive.ObjectID
 		switch msg.Code {
 		case grpc.StreamMessageCode_INSERT_DATA:
-			err = svr.handleInsertData(msg)
+			id, err = svr.handleInsertData(msg)
 		case grpc.StreamMessageCode_INSERT_LOGS:
-			err = svr.handleInsertLogs(msg)
+			id, err = svr.handleInsertLogs(msg, logLimiter)
+		case grpc.StreamMessageCode(constants.GrpcStreamMessageCodeTaskAck):
+			// the only message of the stream, responded on handling
+			return svr.handleTaskAck(stream, msg)
 		default:
-			err = errors.ErrorGrpcInvalidCode
-			log.Errorf("invalid stream message code: %d", msg.Code)
+			// e.g. sent by a newer worker
+			log.Warnf("ignored task stream message of unknown code: %d", msg.Code)
 			continue
 		}
+		if !id.IsZero() {
+			tid = id
+		}
 		if err != nil {
 			log.Errorf("grpc error[%d]: %v", msg.Code, err)
 		}
@@ -79,6 +105,34 @@ func (svr TaskServer) Fetch(ctx context.Context, request *grpc.Request) (respons
 		return nil, trace.TraceError(err)
 	}
 	var tid primitive.ObjectID
+	var tq models.TaskQueueItem
+	if n.Status == constants.NodeStatusDraining || !n.Schedulable {
+		// draining or cordoned nodes do not receive new tasks
+		return HandleSuccessWithData(tid)
+	}
+	if task.IsSchedulerPaused() {
+		// scheduler paused cluster-wide, leave tasks pending
+		return HandleSuccessWithData(tid)
+	}
+	// cluster-wide limit of running tasks
+	maxTasks := task.GetMaxConcurrentTasks()
+	if maxTasks > 0 {
+		svr.concurrency.Lock()
+		defer svr.concurrency.Unlock()
+		count, err := svr.concurrency.Count()
+		if err != nil {
+			return nil, err
+		}
+		if count >= maxTasks {
+			// leave tasks pending
+			svr.concurrency.LogThrottle(count, maxTasks)
+			return HandleSuccessWithData(tid)
+		}
+	}
+	disabledSpiderIds, err := svr.getDisabledSpiderIds()
+	if err != nil {
+		return nil, err
+	}
 	opts := &mongo.FindOptions{
 		Sort: bson.D{
 			{"p", 1},
@@ -86,19 +140,24 @@ func (svr TaskServer) Fetch(ctx context.Context, request *grpc.Request) (respons
 		},
 		Limit: 1,
 	}
-	if err := mongo.RunTransactionWithContext(ctx, func(sc mongo2.SessionContext) (err error) {
+	if err := svr.modelSvc.WithTransaction(ctx, func(sc mongo2.SessionContext) (err error) {
 		// get task queue item assigned to this node
-		tid, err = svr.getTaskQueueItemIdAndDequeue(bson.M{"nid": n.Id}, opts, n.Id)
+		tq, err = svr.getTaskQueueItemAndDequeue(sc, bson.M{"nid": n.Id, "sid": bson.M{"$nin": disabledSpiderIds}}, opts, n)
 		if err != nil {
 			return err
 		}
-		if !tid.IsZero() {
+		if !tq.Id.IsZero() {
 			return nil
 		}
 
-		// get task queue item assigned to any node (random mode)
-		tid, err = svr.getTaskQueueItemIdAndDequeue(bson.M{"nid": nil}, opts, n.Id)
-		if !tid.IsZero() {
+		// get task queue item assigned to any node (random mode), which are
+		// left for worker nodes if the default node is any worker, except
+		// those skipped by this node
+		if n.IsMaster && task.IsMasterExcludedFromUnassignedTasks() {
+			return nil
+		}
+		tq, err = svr.getTaskQueueItemAndDequeue(sc, bson.M{"_id": bson.M{"$nin": svr.skips.Get(n.Id)}, "nid": nil, "sid": bson.M{"$nin": disabledSpiderIds}}, opts, n)
+		if !tq.Id.IsZero() {
 			return nil
 		}
 		if err != nil {
@@ -108,13 +167,132 @@ func (svr TaskServer) Fetch(ctx context.Context, request *grpc.Request) (respons
 	}); err != nil {
 		return nil, err
 	}
+	tid = tq.Id
+	if !tid.IsZero() && task.IsCmdValidationEnabled() {
+		if ok, err := svr.validateTaskCmd(tq, n); err != nil {
+			return nil, err
+		} else if !ok {
+			// failed fast, no task for the node this time
+			tid = primitive.NilObjectID
+		}
+	}
+	if !tid.IsZero() {
+		if maxTasks > 0 {
+			svr.concurrency.AddDispatched(tid)
+		}
+		if isTaskAckEnabled() && isTaskAckSupported(ctx) {
+			svr.requireTaskAck(ctx, tq, n)
+		}
+		e := task.NewTaskEvent(tid, constants.TaskEventDispatched, task.GetNodeActor(n), fmt.Sprintf("dispatched to node %s", n.GetName()))
+		e.NodeId = n.Id
+		task.AddTaskEvent(e)
+		if tracing.IsEnabled() {
+			svr.traceDispatch(ctx, tid, n)
+		}
+	}
 	return HandleSuccessWithData(tid)
 }
 
-func (svr TaskServer) handleInsertData(msg *grpc.StreamMessage) (err error) {
+// validateTaskCmd validates the cmd of the dequeued task against the node. If
+// invalid, the task is set to error with the reason and not dispatched, so that
+// it does not spawn a process which errors immediately on the node. Tasks not
+// assigned to the node explicitly, whose executables are not found on the
+// node, are returned to the task queue for other nodes instead.
+func (svr TaskServer) validateTaskCmd(tq models.TaskQueueItem, n *models.Node) (ok bool, err error) {
+	tid := tq.Id
+	t, err := svr.modelSvc.GetTaskById(tid)
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	s, err := svr.modelSvc.GetSpiderById(t.SpiderId)
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	validationErr := task.ValidateTaskCmd(t, s, n)
+	if validationErr == nil {
+		return true, nil
+	}
+	if errors2.Is(validationErr, errors.ErrorTaskExecutableNotFound) && (tq.NodeId.IsZero() || tq.Dispatched) {
+		return false, svr.skipTask(tq, t, n, validationErr)
+	}
+
+	// set task error
+	log.Warnf("task[%s] not dispatched to node %s: %v", tid.Hex(), n.GetName(), validationErr)
+	if err := delegate.NewModelDelegate(t).UpdateFields(bson.M{
+		"status":  constants.TaskStatusError,
+		"error":   validationErr.Error(),
+		"node_id": n.Id,
+	}); err != nil {
+		return false, trace.TraceError(err)
+	}
+	if t.LockKey != "" {
+		if err := task.ReleaseTaskLock(t.LockKey, t.Id); err != nil {
+			trace.PrintError(err)
+		}
+	}
+	e := task.NewTaskEvent(tid, constants.TaskEventError, constants.TaskEventActorSystem, validationErr.Error())
+	e.NodeId = n.Id
+	task.AddTaskEvent(e)
+	return false, nil
+}
+
+// skipTask returns the task which the node is unable to run to the task queue
+// as unassigned, and skips it for the node, so that it is fetched by other nodes
+func (svr TaskServer) skipTask(tq models.TaskQueueItem, t *models.Task, n *models.Node, reason error) (err error) {
+	log.Infof("task[%s] skipped by node %s: %v", tq.Id.Hex(), n.GetName(), reason)
+	svr.skips.Add(n.Id, tq.Id)
+
+	// queue item
+	tq.NodeId = primitive.NilObjectID
+	tq.Dispatched = false
+	col := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue)
+	if _, err := col.GetCollection().InsertOne(col.GetContext(), &tq); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// node of task
+	if err := delegate.NewModelDelegate(t).UpdateFields(bson.M{"node_id": tq.NodeId}); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// lock key acquired on dequeue, acquired again by the node fetching it
+	if t.LockKey != "" {
+		if err := task.ReleaseTaskLock(t.LockKey, t.Id); err != nil {
+			trace.PrintError(err)
+		}
+	}
+	return nil
+}
+
+// traceDispatch records the dispatch of the task to the node as a span of the
+// trace of the task, and sends its trace context in the header of the
+// response for the node to continue the trace with the run of the task
+func (svr TaskServer) traceDispatch(ctx context.Context, tid primitive.ObjectID, n *models.Node) {
+	t, err := svr.modelSvc.GetTaskById(tid)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	spanCtx, span := tracing.Tracer().Start(tracing.Extract(context.Background(), t.TraceContext), "task.dispatch",
+		oteltrace.WithAttributes(
+			attribute.String("crawlab.task_id", tid.Hex()),
+			attribute.String("crawlab.node_key", n.Key),
+			attribute.String("crawlab.node_name", n.Name),
+		),
+		oteltrace.WithLinks(oteltrace.LinkFromContext(ctx)),
+	)
+	span.End()
+	md := metadata.MD{}
+	tracing.InjectMetadata(spanCtx, md)
+	if err := grpc2.SetHeader(ctx, md); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+func (svr TaskServer) handleInsertData(msg *grpc.StreamMessage) (tid primitive.ObjectID, err error) {
 	data, err := svr.deserialize(msg)
 	if err != nil {
-		return err
+		return tid, err
 	}
 	var records []interface{}
 	for _, d := range data.Records {
@@ -130,36 +308,132 @@ func (svr TaskServer) handleInsertData(msg *grpc.StreamMessage) (err error) {
 		}
 		records = append(records, d)
 	}
-	return svr.statsSvc.InsertData(data.TaskId, records...)
+	if len(data.TraceContext) > 0 {
+		_, span := tracing.Tracer().Start(tracing.Extract(context.Background(), data.TraceContext), "task.results.insert",
+			oteltrace.WithAttributes(attribute.Int("crawlab.result_count", len(records))),
+		)
+		defer func() { tracing.EndSpan(span, err) }()
+	}
+	// results of test runs are captured in the task instead of stored, e.g.
+	// sent by workers of earlier versions
+	if t, err := svr.modelSvc.GetTaskById(data.TaskId); err == nil && t.Test {
+		return data.TaskId, task.AppendTestResults(t.Id, records)
+	}
+	return data.TaskId, svr.statsSvc.InsertData(data.TaskId, records...)
 }
 
-func (svr TaskServer) handleInsertLogs(msg *grpc.StreamMessage) (err error) {
+// flushData inserts result records of the task still buffered for batches
+// once its stream ends, i.e. the task has finished
+func (svr TaskServer) flushData(tid primitive.ObjectID) {
+	if tid.IsZero() {
+		return
+	}
+	if err := svr.statsSvc.FlushData(tid); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+func (svr TaskServer) handleInsertLogs(msg *grpc.StreamMessage, logLimiter *streamLogRateLimiter) (tid primitive.ObjectID, err error) {
 	data, err := svr.deserialize(msg)
 	if err != nil {
-		return err
+		return tid, err
+	}
+	logs, levels := logLimiter.Filter(data.TaskId, data.Logs, data.Levels)
+	if len(logs) == 0 {
+		return data.TaskId, nil
 	}
-	return svr.statsSvc.InsertLogs(data.TaskId, data.Logs...)
+	return data.TaskId, svr.statsSvc.InsertLogsWithLevels(data.TaskId, logs, levels)
 }
 
-func (svr TaskServer) getTaskQueueItemIdAndDequeue(query bson.M, opts *mongo.FindOptions, nid primitive.ObjectID) (tid primitive.ObjectID, err error) {
-	var tq models.TaskQueueItem
-	if err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).Find(query, opts).One(&tq); err != nil {
-		if err == mongo2.ErrNoDocuments {
-			return tid, nil
+// flushDroppedLogs inserts markers of dropped log lines not yet reported when the stream ends
+func (svr TaskServer) flushDroppedLogs(logLimiter *streamLogRateLimiter) {
+	for taskId, marker := range logLimiter.Flush() {
+		if err := svr.statsSvc.InsertLogs(taskId, marker); err != nil {
+			trace.PrintError(err)
+		}
+	}
+}
+
+func (svr TaskServer) GetLogStats() (stats interfaces.GrpcLogStats) {
+	return svr.logLimiter.GetStats()
+}
+
+// getTaskQueueItemAndDequeue dequeues the first task queue item matching the
+// query and assigns the task to the node. Items whose lock keys are held by
+// other tasks are skipped and left pending, and the lock key of the dequeued
+// item is acquired by its task on the node. The item is zero if none is dequeued. Reads
+// and writes are within the transaction of sc, so that an item is dequeued by
+// one node only.
+func (svr TaskServer) getTaskQueueItemAndDequeue(sc mongo2.SessionContext, query bson.M, opts *mongo.FindOptions, n *models.Node) (tq models.TaskQueueItem, err error) {
+	holders, err := task.GetTaskLockHolders()
+	if err != nil {
+		return tq, err
+	}
+	for {
+		q := bson.M{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if len(holders) > 0 {
+			// skip items of held lock keys unless held by their own tasks,
+			// e.g. of tasks re-queued after being reassigned
+			var held bson.A
+			for key, holderId := range holders {
+				held = append(held, bson.M{"lk": key, "_id": bson.M{"$ne": holderId}})
+			}
+			q["$nor"] = held
+		}
+		tq = models.TaskQueueItem{}
+		if err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).GetCollection().FindOne(sc, q, options.FindOne().SetSort(opts.Sort)).Decode(&tq); err != nil {
+			if err == mongo2.ErrNoDocuments {
+				return models.TaskQueueItem{}, nil
+			}
+			return models.TaskQueueItem{}, trace.TraceError(err)
+		}
+		if tq.LockKey == "" {
+			break
+		}
+		ok, err := task.AcquireTaskLock(tq.LockKey, tq.Id, n.Key)
+		if err != nil {
+			return models.TaskQueueItem{}, err
 		}
-		return tid, trace.TraceError(err)
+		if ok {
+			break
+		}
+		// acquired by another task in the meantime
+		holders[tq.LockKey] = primitive.NilObjectID
+	}
+	var t models.Task
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTask).GetCollection().FindOne(sc, bson.M{"_id": tq.Id}).Decode(&t); err == nil {
+		t.NodeId = n.Id
+		if err := delegate.NewModelDelegate(&t, sc).Save(); err != nil {
+			return models.TaskQueueItem{}, err
+		}
+	}
+	if err := delegate.NewModelDelegate(&tq, sc).Delete(); err != nil {
+		return models.TaskQueueItem{}, err
 	}
-	t, err := svr.modelSvc.GetTaskById(tq.Id)
-	if err == nil {
-		t.NodeId = nid
-		_ = delegate.NewModelDelegate(t).Save()
+	return tq, nil
+}
+
+// getDisabledSpiderIds returns ids of disabled spiders, whose queued tasks are
+// left pending until the spiders are enabled again
+func (svr TaskServer) getDisabledSpiderIds() (ids []primitive.ObjectID, err error) {
+	spiders, err := svr.modelSvc.GetSpiderList(bson.M{"enabled": false}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
 	}
-	_ = delegate.NewModelDelegate(&tq).Delete()
-	return tq.Id, nil
+	ids = []primitive.ObjectID{}
+	for _, s := range spiders {
+		ids = append(ids, s.Id)
+	}
+	return ids, nil
 }
 
+// deserialize decodes the stream message, where numbers of records are kept as
+// json.Number to be converted in the number mode of the spider when stored
 func (svr TaskServer) deserialize(msg *grpc.StreamMessage) (data entity.StreamMessageTaskData, err error) {
-	if err := json.Unmarshal(msg.Data, &data); err != nil {
+	if err := utils.UnmarshalJsonUseNumber(msg.Data, &data); err != nil {
 		return data, trace.TraceError(err)
 	}
 	if data.TaskId.IsZero() {
@@ -170,7 +444,12 @@ func (svr TaskServer) deserialize(msg *grpc.StreamMessage) (data entity.StreamMe
 
 func NewTaskServer(opts ...TaskServerOption) (res *TaskServer, err error) {
 	// task server
-	svr := &TaskServer{}
+	svr := &TaskServer{
+		logLimiter:  newLogRateLimiter(),
+		concurrency: newTaskConcurrencyLimiter(),
+		acks:        newTaskAckTracker(),
+		skips:       newTaskSkipTracker(),
+	}
 
 	// apply options
 	for _, opt := range opts {
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// taskSkipTtl is how long an unassigned task skipped by a node is not fetched
+// by the node again, after which it may have become able to run the task, e.g.
+// re-registered with the executable installed
+const taskSkipTtl = 5 * time.Minute
+
+// taskSkipTracker tracks unassigned tasks skipped by nodes unable to run them,
+// e.g. without the executables of their cmds, which are left in the task queue
+// for other nodes
+type taskSkipTracker struct {
+	skips map[primitive.ObjectID]map[primitive.ObjectID]time.Time // expiry of skipped tasks by node
+	mu    sync.Mutex
+}
+
+// Add skips the task for the node
+func (t *taskSkipTracker) Add(nid, tid primitive.ObjectID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.skips[nid] == nil {
+		t.skips[nid] = map[primitive.ObjectID]time.Time{}
+	}
+	t.skips[nid][tid] = time.Now().Add(taskSkipTtl)
+}
+
+// Get returns ids of tasks skipped for the node, removing expired ones
+func (t *taskSkipTracker) Get(nid primitive.ObjectID) (ids []primitive.ObjectID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids = []primitive.ObjectID{}
+	now := time.Now()
+	for tid, expireTs := range t.skips[nid] {
+		if now.After(expireTs) {
+			delete(t.skips[nid], tid)
+			continue
+		}
+		ids = append(ids, tid)
+	}
+	if len(t.skips[nid]) == 0 {
+		delete(t.skips, nid)
+	}
+	return ids
+}
+
+func newTaskSkipTracker() (t *taskSkipTracker) {
+	return &taskSkipTracker{
+		skips: map[primitive.ObjectID]map[primitive.ObjectID]time.Time{},
+	}
+}
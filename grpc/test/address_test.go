@@ -0,0 +1,34 @@
+package test
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/grpc/server"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateAddresses(t *testing.T) {
+	address := func(host, port string) interfaces.Address {
+		return entity.NewAddress(&entity.AddressOptions{Host: host, Port: port})
+	}
+
+	// internal and external interfaces
+	require.Nil(t, server.ValidateAddresses([]interfaces.Address{
+		address("127.0.0.1", "9666"),
+		address("10.0.0.1", "9666"),
+		address("0.0.0.0", "9667"),
+	}))
+
+	// same address
+	require.NotNil(t, server.ValidateAddresses([]interfaces.Address{
+		address("127.0.0.1", "9666"),
+		address("127.0.0.1", "9666"),
+	}))
+
+	// all interfaces and a host on the same port
+	require.NotNil(t, server.ValidateAddresses([]interfaces.Address{
+		address("0.0.0.0", "9666"),
+		address("10.0.0.1", "9666"),
+	}))
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import "io"
+
+// BlobStore stores files such as task artifacts and exports by slash-separated
+// keys, e.g. "artifacts/<taskId>/<artifactId>"
+type BlobStore interface {
+	// Put stores size bytes read from r under the key
+	Put(key string, r io.Reader, size int64, contentType string) (err error)
+	// Open returns the content of the key, errors.ErrorBlobNotExists if not found
+	Open(key string) (rc io.ReadCloser, err error)
+	// DeleteAll removes the key and all keys under it, e.g. "artifacts/<taskId>"
+	DeleteAll(prefix string) (err error)
+	// GetDownloadUrl returns a presigned url to download the key as filename,
+	// or an empty string if downloads must be served by master
+	GetDownloadUrl(key, filename string) (url string, err error)
+}
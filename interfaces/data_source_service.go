@@ -9,6 +9,7 @@ type DataSourceService interface {
 	ChangePassword(id primitive.ObjectID, password string) (err error)
 	Monitor()
 	CheckStatus(id primitive.ObjectID) (err error)
+	TestDataSource(ds DataSource) (err error)
 	SetTimeout(duration time.Duration)
 	SetMonitorInterval(duration time.Duration)
 }
@@ -11,4 +11,6 @@ type Export interface {
 	GetStartTs() time.Time
 	GetEndTs() time.Time
 	GetDownloadPath() string
+	GetFileName() string
+	GetBlobKey() string
 }
@@ -9,4 +9,7 @@ type FsServiceV2 interface {
 	Rename(path, newPath string) (err error)
 	Delete(path string) (err error)
 	Copy(path, newPath string) (err error)
+	GetSize() (size int64, err error)
+	GetQuota() (quota int64)
+	SetQuota(quota int64)
 }
@@ -4,5 +4,6 @@ type GrpcModelDelegateMessage interface {
 	GetModelId() ModelId
 	GetMethod() ModelDelegateMethod
 	GetData() []byte
+	GetFields() []byte
 	ToBytes() (data []byte)
 }
@@ -2,15 +2,28 @@ package interfaces
 
 import (
 	grpc "github.com/crawlab-team/crawlab-grpc"
+	"time"
 )
 
 type GrpcServer interface {
 	GrpcBase
+	GetAddress() (address Address)
 	SetAddress(Address)
+	GetAddresses() (addresses []Address)
+	AddAddress(address Address)
+	SetStopTimeout(timeout time.Duration)
 	GetSubscribe(key string) (sub GrpcSubscribe, err error)
 	SetSubscribe(key string, sub GrpcSubscribe)
 	DeleteSubscribe(key string)
 	SendStreamMessage(key string, code grpc.StreamMessageCode) (err error)
 	SendStreamMessageWithData(nodeKey string, code grpc.StreamMessageCode, d interface{}) (err error)
 	IsStopped() (res bool)
+	GetLogStats() (stats GrpcLogStats)
+}
+
+type GrpcLogStats struct {
+	ReceivedLines   int64   `json:"received_lines"`    // log lines received from task streams
+	DroppedLines    int64   `json:"dropped_lines"`     // log lines dropped due to rate limiting
+	StreamRateLimit float64 `json:"stream_rate_limit"` // max log lines per second of each stream (0 for unlimited)
+	GlobalRateLimit float64 `json:"global_rate_limit"` // max log lines per second of all streams (0 for unlimited)
 }
@@ -0,0 +1,16 @@
+package interfaces
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// JobHandler processes the JSON payload of a job. A returned error makes the
+// job retried until its max attempts are reached.
+type JobHandler func(payload []byte) (err error)
+
+type JobService interface {
+	Module
+	// RegisterHandler sets the handler of jobs of the type. Jobs are only
+	// picked up by the service after the handler of their type is registered.
+	RegisterHandler(jobType string, handler JobHandler)
+	// Enqueue persists a job of the type with payload marshalled to JSON
+	Enqueue(jobType string, payload interface{}) (id primitive.ObjectID, err error)
+}
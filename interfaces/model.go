@@ -39,6 +39,7 @@ const (
 	ModelIdRolePermission
 	ModelIdEnvironment
 	ModelIdDependencySetting
+	ModelIdTaskEvent
 )
 
 const (
@@ -68,6 +69,7 @@ const (
 	ModelColNameRolePermission    = "role_permissions"
 	ModelColNameEnvironment       = "environments"
 	ModelColNameDependencySetting = "dependency_settings"
+	ModelColNameTaskEvent         = "task_events"
 )
 
 type ModelWithTags interface {
@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"context"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -23,6 +24,15 @@ type ModelBaseService interface {
 	Count(query bson.M) (total int, err error)
 }
 
+// ModelBaseServiceWithContext is implemented by model base services able to
+// query with a context, e.g. of an API request, so that queries are cancelled
+// along with the context
+type ModelBaseServiceWithContext interface {
+	ModelBaseService
+	GetListWithContext(ctx context.Context, query bson.M, opts *mongo.FindOptions) (res List, err error)
+	CountWithContext(ctx context.Context, query bson.M) (total int, err error)
+}
+
 type ModelService interface {
 	GetBaseService(id ModelId) (svc ModelBaseService)
 }
@@ -0,0 +1,7 @@
+package interfaces
+
+type DataSource interface {
+	Model
+	GetName() (name string)
+	GetType() (ty string)
+}
@@ -1,10 +1,16 @@
 package interfaces
 
+import "go.mongodb.org/mongo-driver/bson"
+
 type ModelDelegateMethod string
 
 type ModelDelegate interface {
 	Add() error
 	Save() error
+	// UpdateFields sets only the given fields of the model in the database
+	// and refreshes the model, so that concurrent updates of other fields
+	// are not overwritten as with Save
+	UpdateFields(fields bson.M) error
 	Delete() error
 	GetArtifact() (ModelArtifact, error)
 	GetModel() Model
@@ -13,10 +19,11 @@ type ModelDelegate interface {
 }
 
 const (
-	ModelDelegateMethodAdd         = "add"
-	ModelDelegateMethodSave        = "save"
-	ModelDelegateMethodDelete      = "delete"
-	ModelDelegateMethodGetArtifact = "get-artifact"
-	ModelDelegateMethodRefresh     = "refresh"
-	ModelDelegateMethodChange      = "change"
+	ModelDelegateMethodAdd          = "add"
+	ModelDelegateMethodSave         = "save"
+	ModelDelegateMethodUpdateFields = "update-fields"
+	ModelDelegateMethodDelete       = "delete"
+	ModelDelegateMethodGetArtifact  = "get-artifact"
+	ModelDelegateMethodRefresh      = "refresh"
+	ModelDelegateMethodChange       = "change"
 )
@@ -8,6 +8,7 @@ type Node interface {
 	GetIsMaster() (ok bool)
 	GetActive() (active bool)
 	SetActive(active bool)
+	GetActiveTs() (activeTs time.Time)
 	SetActiveTs(activeTs time.Time)
 	GetStatus() (status string)
 	SetStatus(status string)
@@ -17,6 +18,39 @@ type Node interface {
 	SetAvailableRunners(runners int)
 	GetMaxRunners() (runners int)
 	SetMaxRunners(runners int)
+	GetDrained() (drained bool)
+	SetDrained(drained bool)
+	GetSchedulable() (ok bool)
+	SetSchedulable(ok bool)
+	GetWorkspaceSize() (size int64)
+	SetWorkspaceSize(size int64)
+	GetWorkspaceReclaimed() (size int64)
+	SetWorkspaceReclaimed(size int64)
+	GetCapabilities() (capabilities *NodeCapabilities)
+	SetCapabilities(capabilities *NodeCapabilities)
+	GetVersion() (version string)
+	SetVersion(version string)
 	IncrementAvailableRunners()
 	DecrementAvailableRunners()
 }
+
+// NodeCapabilities is the capability manifest reported by a node on registration
+type NodeCapabilities struct {
+	Os          string            `json:"os" bson:"os"`                   // e.g. linux
+	Arch        string            `json:"arch" bson:"arch"`               // e.g. amd64
+	Executables map[string]string `json:"executables" bson:"executables"` // available executables by name with versions
+	Probed      []string          `json:"probed" bson:"probed"`           // names of executables probed for, available or not
+}
+
+// HasExecutables returns whether all the executables are available
+func (c *NodeCapabilities) HasExecutables(names []string) (ok bool) {
+	for _, name := range names {
+		if c == nil {
+			return false
+		}
+		if _, ok := c.Executables[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -7,4 +7,5 @@ type ModelNodeDelegate interface {
 	UpdateStatus(active bool, activeTs *time.Time, status string) (err error)
 	UpdateStatusOnline() (err error)
 	UpdateStatusOffline() (err error)
+	UpdateStatusDraining() (err error)
 }
@@ -3,6 +3,7 @@ package interfaces
 import (
 	"github.com/robfig/cron/v3"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type Schedule interface {
@@ -13,6 +14,8 @@ type Schedule interface {
 	SetEntryId(id cron.EntryID)
 	GetCron() (c string)
 	SetCron(c string)
+	GetTimezone() (tz string)
+	SetTimezone(tz string)
 	GetSpiderId() (id primitive.ObjectID)
 	SetSpiderId(id primitive.ObjectID)
 	GetMode() (mode string)
@@ -25,4 +28,12 @@ type Schedule interface {
 	SetParam(param string)
 	GetPriority() (p int)
 	SetPriority(p int)
+	GetMisfirePolicy() (policy string)
+	SetMisfirePolicy(policy string)
+	GetLastRunTs() (ts time.Time)
+	SetLastRunTs(ts time.Time)
+	GetNextRunOverrideTs() (ts time.Time)
+	SetNextRunOverrideTs(ts time.Time)
+	GetSkippedRunTs() (ts time.Time)
+	SetSkippedRunTs(ts time.Time)
 }
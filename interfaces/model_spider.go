@@ -1,6 +1,9 @@
 package interfaces
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
 
 type Spider interface {
 	ModelWithNameDescription
@@ -15,10 +18,33 @@ type Spider interface {
 	SetParam(param string)
 	GetPriority() (p int)
 	SetPriority(p int)
+	GetTimeout() (timeout int)
+	SetTimeout(timeout int)
+	GetMaxRetries() (maxRetries int)
+	SetMaxRetries(maxRetries int)
+	GetNodeTags() (tags []string)
+	SetNodeTags(tags []string)
+	GetCapabilities() (capabilities []string)
+	SetCapabilities(capabilities []string)
+	GetEnabled() (enabled bool)
+	SetEnabled(enabled bool)
 	GetColId() (id primitive.ObjectID)
 	SetColId(id primitive.ObjectID)
 	GetIncrementalSync() (incrementalSync bool)
+	GetCancelGracePeriod() (seconds int)
+	SetCancelGracePeriod(seconds int)
 	SetIncrementalSync(incrementalSync bool)
+	GetOutputEncoding() (name string)
+	SetOutputEncoding(name string)
 	GetAutoInstall() (autoInstall bool)
 	SetAutoInstall(autoInstall bool)
+	GetInstallCmd() (cmd string)
+	SetInstallCmd(cmd string)
+	GetRequirementsFile() (file string)
+	SetRequirementsFile(file string)
+	GetWorkspaceQuota() (quota int64)
+	SetWorkspaceQuota(quota int64)
+	GetProfileVariables(name string) (variables map[string]string)
+	GetLastSuccessTs() (ts time.Time)
+	SetLastSuccessTs(ts time.Time)
 }
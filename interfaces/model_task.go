@@ -13,11 +13,36 @@ type Task interface {
 	SetError(error string)
 	GetPid() (pid int)
 	SetPid(pid int)
+	GetCancelMode() (mode string)
+	SetCancelMode(mode string)
 	GetSpiderId() (id primitive.ObjectID)
 	GetType() (ty string)
 	GetCmd() (cmd string)
 	GetParam() (param string)
 	GetPriority() (p int)
+	GetTimeout() (timeout int)
+	GetMaxRetries() (maxRetries int)
+	GetRetries() (retries int)
+	GetNodeTags() (tags []string)
+	GetCapabilities() (capabilities []string)
 	GetUserId() (id primitive.ObjectID)
 	SetUserId(id primitive.ObjectID)
+	GetCheckpoint() (token string)
+	SetCheckpoint(token string)
+	SetLogsTruncated(truncated bool)
+	GetItemsCapped() (capped bool)
+	SetItemsCapped(capped bool)
+	GetProxy() (proxy string)
+	SetProxy(proxy string)
+	GetGitRef() (ref string)
+	GetInputId() (id primitive.ObjectID)
+	GetProfile() (profile string)
+	GetConcurrency() (concurrency int)
+	GetLockKey() (key string)
+	GetTest() (test bool)
+	SetTestResults(results []map[string]interface{}, capped bool)
+	GetGitCommit() (hash string)
+	SetGitCommit(hash string)
+	GetTraceContext() (carrier map[string]string)
+	SetTraceContext(carrier map[string]string)
 }
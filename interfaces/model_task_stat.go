@@ -20,4 +20,7 @@ type TaskStat interface {
 	SetResultCount(c int64)
 	GetErrorLogCount() (c int64)
 	SetErrorLogCount(c int64)
+	GetResultInsertedCount() (c int64)
+	GetResultUpdatedCount() (c int64)
+	GetResultSkippedCount() (c int64)
 }
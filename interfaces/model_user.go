@@ -4,6 +4,8 @@ type User interface {
 	Model
 	GetUsername() (name string)
 	GetPassword() (p string)
+	GetPasswordHash() (h string)
 	GetRole() (r string)
+	GetRoles() (roles []string)
 	GetEmail() (email string)
 }
@@ -0,0 +1,9 @@
+package interfaces
+
+type NodeHealthEvaluator interface {
+	// OnSuccess is called when the health check (subscribe and PING) of a worker node succeeds
+	OnSuccess(n Node)
+	// OnFailure is called when the health check of a worker node fails, and
+	// returns whether the node should be marked as offline
+	OnFailure(n Node, err error) (offline bool)
+}
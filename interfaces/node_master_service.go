@@ -8,7 +8,48 @@ type NodeMasterService interface {
 	NodeService
 	Monitor()
 	SetMonitorInterval(duration time.Duration)
+	SetMaxMonitorCycleDuration(duration time.Duration)
+	SetNodeHealthEvaluator(evaluator NodeHealthEvaluator)
+	SetMaxNodeFailures(failures int)
+	SetRegisterRetry(retries int, initialBackoff time.Duration)
+	GetMonitorStats() (stats NodeMonitorStats)
+	// RunMonitorOnce runs a monitor cycle immediately and returns its summary
+	RunMonitorOnce() (summary NodeMonitorCycleSummary, err error)
+	// PushConfigUpdate applies runtime settings on master and pushes them to
+	// online worker nodes
+	PushConfigUpdate(settings map[string]string) (results []ConfigUpdateResult)
+	// PushNodeConfigs pushes to online worker nodes their runtime config
+	// resolved by node tags
+	PushNodeConfigs() (results []ConfigUpdateResult)
 	Register() error
 	StopOnError()
 	GetServer() GrpcServer
 }
+
+type NodeMonitorStats struct {
+	LastCycleTs        time.Time `json:"last_cycle_ts"`        // start time of last monitor cycle
+	LastCycleDuration  int64     `json:"last_cycle_duration"`  // duration of last monitor cycle (ms)
+	MaxCycleDuration   int64     `json:"max_cycle_duration"`   // max duration of monitor cycles since start (ms)
+	CycleDurationLimit int64     `json:"cycle_duration_limit"` // threshold to trigger slow cycle alarm (ms)
+	Cycles             int64     `json:"cycles"`               // total monitor cycles
+	SlowCycles         int64     `json:"slow_cycles"`          // monitor cycles exceeding the threshold
+}
+
+type NodeMonitorCycleSummary struct {
+	StartTs      time.Time `json:"start_ts"`      // start time of the monitor cycle
+	Duration     int64     `json:"duration"`      // duration of the monitor cycle (ms)
+	NodesChecked int       `json:"nodes_checked"` // number of worker nodes checked
+	Failures     int       `json:"failures"`      // number of worker nodes failed in the cycle
+	FailedNodes  []string  `json:"failed_nodes"`  // keys of worker nodes failed in the cycle
+
+	SelfReportedNodes []string `json:"self_reported_nodes,omitempty"` // keys of worker nodes failed ping but kept online by self-report
+
+	Cancelled    bool `json:"cancelled"`     // whether the monitor cycle was cancelled before checking all worker nodes
+	NodesSkipped int  `json:"nodes_skipped"` // number of worker nodes not checked due to cancellation, whose status is unchanged
+}
+
+type ConfigUpdateResult struct {
+	NodeKey string `json:"node_key"`        // key of the worker node
+	Success bool   `json:"success"`         // whether the update was sent to the node
+	Error   string `json:"error,omitempty"` // error sending the update
+}
@@ -13,3 +13,16 @@ type ResultService interface {
 	SetTime(t time.Time)
 	GetTime() (t time.Time)
 }
+
+// ResultInsertStats counts inserted records by outcome
+type ResultInsertStats struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// ResultServiceWithStats is implemented by result services that report the
+// outcome of inserted records, e.g. when deduplicating them
+type ResultServiceWithStats interface {
+	InsertWithStats(records ...interface{}) (stats ResultInsertStats, err error)
+}
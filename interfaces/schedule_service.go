@@ -19,5 +19,11 @@ type ScheduleService interface {
 	Enable(s Schedule, args ...interface{}) (err error)
 	Disable(s Schedule, args ...interface{}) (err error)
 	Update()
+	// GetNextRunTs returns the next n run times of the schedule in its time zone
+	GetNextRunTs(s Schedule, n int) (res []time.Time, err error)
+	// SetNextRunOverride overrides the next run of the schedule by a one-off
+	// run at ts, after which the schedule reverts to its cron. Zero ts clears
+	// the override.
+	SetNextRunOverride(s Schedule, ts time.Time, args ...interface{}) (err error)
 	GetCron() (c *cron.Cron)
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type SpiderAdminService interface {
@@ -9,14 +10,55 @@ type SpiderAdminService interface {
 	Start() (err error)
 	// Schedule a new task of the spider
 	Schedule(id primitive.ObjectID, opts *SpiderRunOptions) (taskIds []primitive.ObjectID, err error)
-	// Clone the spider
-	Clone(id primitive.ObjectID, opts *SpiderCloneOptions) (err error)
+	// Clone the spider including its workspace files and return the cloned spider
+	Clone(id primitive.ObjectID, opts *SpiderCloneOptions) (s Spider, err error)
 	// Delete the spider
 	Delete(id primitive.ObjectID) (err error)
 	// SyncGit syncs all git repositories
 	SyncGit() (err error)
 	// SyncGitOne syncs one git repository
 	SyncGitOne(g Git) (err error)
+	// SyncFiles notifies worker nodes that files of the spider changed
+	SyncFiles(id primitive.ObjectID) (err error)
+	// ResyncNode pushes sync requests of files of the spiders, or of all spiders the node may run if empty, to the worker node of the key
+	ResyncNode(nodeKey string, spiderIds []primitive.ObjectID) (results []SpiderSyncResult, err error)
+	// ValidateSpider validates the spider and returns errors.ValidationErrors if invalid
+	ValidateSpider(id primitive.ObjectID) (err error)
+	// ValidateSpiderModel validates the given spider model
+	ValidateSpiderModel(s Spider) (err error)
+	// ValidateGitRef validates the git ref to run tasks of the spider with, if any
+	ValidateGitRef(id primitive.ObjectID, ref string) (err error)
+	// GetSpiderTasks returns a page of the execution history of the spider, latest first
+	GetSpiderTasks(id primitive.ObjectID, page, size int, filters *SpiderTaskFilters) (items []SpiderTaskHistoryItem, total int, err error)
 	// Export exports the spider and return zip file path
 	Export(id primitive.ObjectID) (filePath string, err error)
+	// EmergencyStop disables the spider and cancels all its running and pending tasks, args may include the user and *TaskCancelOptions
+	EmergencyStop(id primitive.ObjectID, args ...interface{}) (res *SpiderEmergencyStopResult, err error)
+}
+
+// SpiderEmergencyStopResult is the result of the emergency stop of a spider
+type SpiderEmergencyStopResult struct {
+	SpiderId primitive.ObjectID `json:"spider_id"`
+	StopTs   time.Time          `json:"stop_ts"`
+	StopBy   primitive.ObjectID `json:"stop_by"`
+	Tasks    []TaskCancelResult `json:"tasks"`
+}
+
+// SpiderSyncResult is the result of pushing a sync request of spider files to
+// a node, including results of files synced if the node reports completion in
+// time
+type SpiderSyncResult struct {
+	SpiderId primitive.ObjectID     `json:"spider_id"`
+	Success  bool                   `json:"success"`
+	Error    string                 `json:"error,omitempty"`
+	Finished bool                   `json:"finished"`
+	Files    []SpiderSyncFileResult `json:"files,omitempty"`
+}
+
+// SpiderSyncFileResult is the result of syncing a file of a spider to a node,
+// i.e. downloading it from master or deleting it as removed on master
+type SpiderSyncFileResult struct {
+	Path   string `json:"path"`
+	Action string `json:"action"` // constants.SyncFileActionDownload or constants.SyncFileActionDelete
+	Error  string `json:"error,omitempty"`
 }
@@ -1,17 +1,58 @@
 package interfaces
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
 
 type SpiderRunOptions struct {
-	Mode       string               `json:"mode"`
-	NodeIds    []primitive.ObjectID `json:"node_ids"`
-	Cmd        string               `json:"cmd"`
-	Param      string               `json:"param"`
-	ScheduleId primitive.ObjectID   `json:"schedule_id"`
-	Priority   int                  `json:"priority"`
-	UserId     primitive.ObjectID   `json:"-"`
+	Mode         string               `json:"mode"`
+	NodeIds      []primitive.ObjectID `json:"node_ids"`
+	Cmd          string               `json:"cmd"`
+	Param        string               `json:"param"`
+	ScheduleId   primitive.ObjectID   `json:"schedule_id"`
+	Priority     int                  `json:"priority"`
+	Timeout      *int                 `json:"timeout"`     // timeout (seconds), the default of the spider if nil
+	MaxRetries   *int                 `json:"max_retries"` // max number of retries, the default of the spider if nil
+	MaxItems     int                  `json:"max_items"`   // max number of result items, 0 for the default of the spider
+	NodeTags     []string             `json:"node_tags"`
+	Capabilities []string             `json:"capabilities"`
+	Checkpoint   string               `json:"checkpoint"`  // checkpoint token to resume from
+	GitRef       string               `json:"git_ref"`     // git branch, tag or commit to run
+	InputId      primitive.ObjectID   `json:"input_id"`    // input file of the spider to seed the crawl
+	Profile      string               `json:"profile"`     // profile of the spider, the default profile if empty
+	Concurrency  int                  `json:"concurrency"` // concurrency of the process, 0 for the default of the spider
+	LockKey      string               `json:"lock_key"`    // lock held while the task runs, the lock key of the spider if empty
+	Test         bool                 `json:"-"`           // whether to run a test task, see models.Task.Test
+	UserId       primitive.ObjectID   `json:"-"`
+	TraceContext map[string]string    `json:"-"` // trace context of the request scheduling the tasks
 }
 
 type SpiderCloneOptions struct {
-	Name string
+	Name   string             `json:"name"` // name of the cloned spider, "<name> (copy)" if empty
+	UserId primitive.ObjectID `json:"-"`
+}
+
+type SpiderTaskFilters struct {
+	Status  string             // task status, all if empty
+	NodeId  primitive.ObjectID // Node.Id, all if zero
+	StartTs time.Time          // min create time (inclusive), unbounded if zero
+	EndTs   time.Time          // max create time (exclusive), unbounded if zero
+}
+
+// SpiderTaskHistoryItem is an item of the execution history of a spider
+type SpiderTaskHistoryItem struct {
+	TaskId          primitive.ObjectID `json:"task_id"`
+	Status          string             `json:"status"`
+	Error           string             `json:"error"`
+	NodeId          primitive.ObjectID `json:"node_id"`
+	NodeName        string             `json:"node_name"`
+	CreateTs        time.Time          `json:"create_ts"`
+	StartTs         time.Time          `json:"start_ts"`
+	EndTs           time.Time          `json:"end_ts"`
+	RuntimeDuration int64              `json:"runtime_duration"` // in millisecond
+	ResultCount     int64              `json:"result_count"`
+	ErrorLogCount   int64              `json:"error_log_count"`
+	LogsUrl         string             `json:"logs_url"`    // api path of task logs
+	ResultsUrl      string             `json:"results_url"` // api path of task results
 }
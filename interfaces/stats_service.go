@@ -1,9 +1,16 @@
 package interfaces
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
 
 type StatsService interface {
 	GetOverviewStats(query bson.M) (data interface{}, err error)
 	GetDailyStats(query bson.M) (data interface{}, err error)
 	GetTaskStats(query bson.M) (data interface{}, err error)
+	// GetSpiderTaskTimeSeries returns the numbers of successful, failed and
+	// cancelled tasks of the spider completed in each hour or day in [start, end)
+	GetSpiderTaskTimeSeries(spiderId primitive.ObjectID, interval string, start, end time.Time) (data interface{}, err error)
 }
@@ -0,0 +1,8 @@
+package interfaces
+
+type TaskDispatchStrategy interface {
+	// Select returns the node to dispatch the task to among the eligible nodes,
+	// which are online, enabled, schedulable and not draining. It is only called when the task
+	// is not assigned to a node yet and there is at least one eligible node.
+	Select(t Task, nodes []Node) (n Node, err error)
+}
@@ -11,6 +11,8 @@ type TaskHandlerService interface {
 	Run(taskId primitive.ObjectID) (err error)
 	// Cancel task locally
 	Cancel(taskId primitive.ObjectID) (err error)
+	// CancelWithMode cancel task locally with the given constants.TaskCancelModeSoft or constants.TaskCancelModeForce
+	CancelWithMode(taskId primitive.ObjectID, mode string) (err error)
 	// Fetch tasks and run
 	Fetch()
 	// ReportStatus periodically report handler status to master
@@ -39,6 +41,10 @@ type TaskHandlerService interface {
 	GetCancelTimeout() (timeout time.Duration)
 	// SetCancelTimeout set report interval
 	SetCancelTimeout(timeout time.Duration)
+	// SetWorkspaceInterval set interval of computing workspace disk usage
+	SetWorkspaceInterval(interval time.Duration)
+	// SetCleanupInterval set interval of removing expired scratch directories of tasks
+	SetCleanupInterval(interval time.Duration)
 	// GetModelService get model service
 	GetModelService() (modelSvc GrpcClientModelService)
 	// GetModelSpiderService get model spider service
@@ -0,0 +1,32 @@
+package interfaces
+
+type TaskPluginContext interface {
+	GetTask() (t Task)
+	GetSpider() (s Spider)
+	GetStatus() (status string)
+	GetError() (err error)
+	GetRecords() (records []interface{})
+}
+
+type TaskPlugin interface {
+	// BeforeDispatch is called on master before the task is enqueued
+	BeforeDispatch(ctx TaskPluginContext) (err error)
+	// OnTaskStart is called on the executing node after the process started
+	OnTaskStart(ctx TaskPluginContext) (err error)
+	// OnTaskFinish is called on the executing node after the process ended
+	OnTaskFinish(ctx TaskPluginContext) (err error)
+	// OnResultStored is called on master after result items are inserted, errors are logged only even if strict
+	OnResultStored(ctx TaskPluginContext) (err error)
+}
+
+type TaskPluginRegistry interface {
+	Register(key string, p TaskPlugin)
+	Unregister(key string)
+	Get(key string) (p TaskPlugin)
+	GetAll() (plugins []TaskPlugin)
+	SetStrict(strict bool)
+	BeforeDispatch(ctx TaskPluginContext) (err error)
+	OnTaskStart(ctx TaskPluginContext) (err error)
+	OnTaskFinish(ctx TaskPluginContext) (err error)
+	OnResultStored(ctx TaskPluginContext) (err error)
+}
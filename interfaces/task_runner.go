@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"context"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
 )
@@ -9,7 +10,9 @@ type TaskRunner interface {
 	Init() (err error)
 	Run() (err error)
 	Cancel() (err error)
+	CancelWithMode(mode string) (err error)
 	SetSubscribeTimeout(timeout time.Duration)
+	SetTraceContext(ctx context.Context)
 	GetTaskId() (id primitive.ObjectID)
 	CleanUp() (err error)
 }
@@ -9,8 +9,56 @@ type TaskSchedulerService interface {
 	TaskBaseService
 	// Enqueue task into the task queue
 	Enqueue(t Task) (t2 Task, err error)
-	// Cancel task to corresponding node
+	// CheckFeasibility checks the node tags and capabilities of the task are satisfied by its node, or any online node if not assigned, returning the unmet requirement as error, or as warning if only warned about
+	CheckFeasibility(t Task) (warning string, err error)
+	// Cancel task to corresponding node, args may include the user and *TaskCancelOptions
 	Cancel(id primitive.ObjectID, args ...interface{}) (err error)
+	// ReassignTask moves a pending or running task to the node of targetNodeKey, or back to the pending pool if empty, args may include the user
+	ReassignTask(id primitive.ObjectID, targetNodeKey string, args ...interface{}) (t Task, err error)
+	// RedispatchTasksByNode dispatches again pending tasks pinned to the node by the dispatch strategy, e.g. when the node goes offline
+	RedispatchTasksByNode(nodeId primitive.ObjectID) (err error)
+	// GetRunningTasksByNode returns running tasks on the node of the key
+	GetRunningTasksByNode(nodeKey string) (tasks []Task, err error)
+	// CancelTasksByNode cancels all running tasks on the node of the key and reports the result of each, args may include the user and *TaskCancelOptions
+	CancelTasksByNode(nodeKey string, args ...interface{}) (results []TaskCancelResult, err error)
+	// CancelTasksBySpider cancels all running and pending tasks of the spider and reports the result of each, args may include the user and *TaskCancelOptions
+	CancelTasksBySpider(spiderId primitive.ObjectID, args ...interface{}) (results []TaskCancelResult, err error)
+	// GetDeadLetterTasks returns tasks failed after exhausting their retries, latest first, and the total count
+	GetDeadLetterTasks(skip, limit int) (tasks []Task, total int, err error)
+	// RequeueDeadLetterTask runs again the dead-lettered task with its retries reset and clears its dead-letter flag, args may include the user
+	RequeueDeadLetterTask(id primitive.ObjectID, args ...interface{}) (t2 Task, err error)
+	// Pause dispatching tasks cluster-wide, so that new tasks are held pending while running tasks are unaffected, args may include the user
+	Pause(args ...interface{}) (err error)
+	// Resume dispatching tasks paused by Pause, args may include the user
+	Resume(args ...interface{}) (err error)
+	// IsPaused returns whether dispatching tasks is paused
+	IsPaused() (paused bool)
 	// SetInterval set the interval or duration between two adjacent fetches
 	SetInterval(interval time.Duration)
+	// SetDispatchStrategy set the strategy to select nodes for tasks not assigned to a node
+	SetDispatchStrategy(strategy TaskDispatchStrategy)
+	// GetQueueEstimate returns the queue position and estimated wait of a pending task, nil if it is not queued
+	GetQueueEstimate(id primitive.ObjectID) (res *TaskQueueEstimate, err error)
+	// SetReconcileInterval set the interval of reconciling running task counts against the database
+	SetReconcileInterval(interval time.Duration)
+}
+
+// TaskCancelResult is the result of cancelling a task in bulk
+type TaskCancelResult struct {
+	TaskId  primitive.ObjectID `json:"task_id"`
+	Success bool               `json:"success"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// TaskQueueEstimate is the position of a pending task in the task queue and
+// an estimate of its wait before it runs. The wait is estimated from recent
+// task durations and the current capacity of the cluster, which may differ
+// a lot from the actual wait, hence Estimated is always true.
+type TaskQueueEstimate struct {
+	Position      int   `json:"position"`       // 1-based position among queued tasks the task competes with
+	Capacity      int   `json:"capacity"`       // max runners of nodes that can run the task
+	Available     int   `json:"available"`      // available runners of nodes that can run the task
+	AvgDuration   int64 `json:"avg_duration"`   // average runtime of recent tasks (ms), 0 if unknown
+	EstimatedWait int64 `json:"estimated_wait"` // estimated wait before running (ms), -1 if unknown
+	Estimated     bool  `json:"estimated"`      // always true to label the wait as an estimate
 }
@@ -0,0 +1,9 @@
+package interfaces
+
+type TaskCancelOptions struct {
+	Mode string `json:"mode"` // constants.TaskCancelModeSoft or constants.TaskCancelModeForce (default)
+}
+
+type TaskReassignOptions struct {
+	NodeKey string `json:"node_key"` // key of the target node, empty to let the scheduler repick
+}
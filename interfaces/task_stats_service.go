@@ -5,5 +5,10 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 type TaskStatsService interface {
 	TaskBaseService
 	InsertData(id primitive.ObjectID, records ...interface{}) (err error)
+	// FlushData inserts result records of the task buffered for batches, e.g. when the task ends
+	FlushData(id primitive.ObjectID) (err error)
 	InsertLogs(id primitive.ObjectID, logs ...string) (err error)
+	InsertLogsWithLevels(id primitive.ObjectID, logs []string, levels []string) (err error)
+	// ReleaseLogs releases the state kept for writing logs of the task, e.g. when the task ends
+	ReleaseLogs(id primitive.ObjectID)
 }
@@ -13,7 +13,14 @@ type UserService interface {
 	Create(opts *UserCreateOptions, args ...interface{}) (err error)
 	Login(opts *UserLoginOptions) (token string, u User, err error)
 	CheckToken(token string) (u User, err error)
+	Update(id primitive.ObjectID, opts *UserUpdateOptions, args ...interface{}) (err error)
+	Delete(id primitive.ObjectID, args ...interface{}) (err error)
 	ChangePassword(id primitive.ObjectID, password string, args ...interface{}) (err error)
+	ResetPassword(id primitive.ObjectID, args ...interface{}) (password string, err error)
+	ValidatePassword(password string) (err error)
 	MakeToken(user User) (tokenStr string, err error)
+	MakeRefreshToken(user User) (refreshTokenStr string, err error)
+	RefreshToken(refreshTokenStr string) (tokenStr string, newRefreshTokenStr string, err error)
+	RevokeRefreshToken(refreshTokenStr string) (err error)
 	GetCurrentUser(c *gin.Context) (u User, err error)
 }
@@ -5,9 +5,17 @@ type UserCreateOptions struct {
 	Password string
 	Email    string
 	Role     string
+	Roles    []string
+}
+
+type UserUpdateOptions struct {
+	Email *string  // unchanged if nil
+	Role  string   // unchanged if empty
+	Roles []string // unchanged if nil
 }
 
 type UserLoginOptions struct {
 	Username string
 	Password string
+	Ip       string
 }
@@ -0,0 +1,278 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"time"
+)
+
+// Jobs are background work items run on master, e.g. cleanups or
+// notifications, which should not be lost if master restarts. They are
+// persisted in constants.JobColName and picked up by a pool of workers, which
+// retry failed jobs with exponential backoff.
+
+// Job is a persisted background job
+type Job struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id"`
+	Type        string             `json:"type" bson:"type"`
+	Payload     string             `json:"payload" bson:"payload"`
+	Status      string             `json:"status" bson:"status"`
+	Attempts    int                `json:"attempts" bson:"attempts"`
+	MaxAttempts int                `json:"max_attempts" bson:"max_attempts"`
+	Error       string             `json:"error" bson:"error"`
+	RunTs       time.Time          `json:"run_ts" bson:"run_ts"`
+	CreateTs    time.Time          `json:"create_ts" bson:"create_ts"`
+	UpdateTs    time.Time          `json:"update_ts" bson:"update_ts"`
+}
+
+type Service struct {
+	// settings variables
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	retryBackoff time.Duration
+
+	// internals
+	col      *mongo.Col
+	handlers map[string]interfaces.JobHandler
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+}
+
+func (svc *Service) RegisterHandler(jobType string, handler interfaces.JobHandler) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.handlers[jobType] = handler
+}
+
+func (svc *Service) Enqueue(jobType string, payload interface{}) (id primitive.ObjectID, err error) {
+	if jobType == "" {
+		return id, trace.TraceError(errors.ErrorJobInvalidType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return id, trace.TraceError(err)
+	}
+	now := time.Now()
+	j := Job{
+		Id:          primitive.NewObjectID(),
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      constants.JobStatusPending,
+		MaxAttempts: svc.maxAttempts,
+		RunTs:       now,
+		CreateTs:    now,
+		UpdateTs:    now,
+	}
+	if _, err := svc.col.Insert(j); err != nil {
+		return id, trace.TraceError(err)
+	}
+	return j.Id, nil
+}
+
+func (svc *Service) Init() (err error) {
+	return nil
+}
+
+func (svc *Service) Start() {
+	// jobs left running were interrupted by a restart
+	if err := svc.col.Update(bson.M{"status": constants.JobStatusRunning}, bson.M{
+		"$set": bson.M{
+			"status":    constants.JobStatusPending,
+			"update_ts": time.Now(),
+		},
+	}); err != nil {
+		trace.PrintError(err)
+	}
+
+	for i := 0; i < svc.workers; i++ {
+		svc.wg.Add(1)
+		go svc.work()
+	}
+	log.Infof("job service started with %d workers", svc.workers)
+}
+
+func (svc *Service) Wait() {
+	svc.wg.Wait()
+}
+
+func (svc *Service) Stop() {
+	svc.stopOnce.Do(func() {
+		close(svc.stopCh)
+	})
+	svc.wg.Wait()
+}
+
+func (svc *Service) work() {
+	defer svc.wg.Done()
+	for {
+		select {
+		case <-svc.stopCh:
+			return
+		default:
+		}
+
+		j, err := svc.claim()
+		if err != nil {
+			if err != mongo2.ErrNoDocuments {
+				trace.PrintError(err)
+			}
+			select {
+			case <-svc.stopCh:
+				return
+			case <-time.After(svc.pollInterval):
+			}
+			continue
+		}
+
+		svc.run(j)
+	}
+}
+
+// claim atomically marks the earliest due pending job of a registered type as
+// running and returns it, or mongo.ErrNoDocuments if there is none
+func (svc *Service) claim() (j *Job, err error) {
+	svc.mu.RLock()
+	var types []string
+	for t := range svc.handlers {
+		types = append(types, t)
+	}
+	svc.mu.RUnlock()
+	if len(types) == 0 {
+		return nil, mongo2.ErrNoDocuments
+	}
+
+	now := time.Now()
+	query := bson.M{
+		"type":   bson.M{"$in": types},
+		"status": constants.JobStatusPending,
+		"run_ts": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    constants.JobStatusRunning,
+			"update_ts": now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{"run_ts", 1}}).
+		SetReturnDocument(options.After)
+	j = &Job{}
+	if err := svc.col.GetCollection().FindOneAndUpdate(svc.col.GetContext(), query, update, opts).Decode(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (svc *Service) run(j *Job) {
+	svc.mu.RLock()
+	handler := svc.handlers[j.Type]
+	svc.mu.RUnlock()
+
+	err := svc.handle(handler, j)
+	update := bson.M{"update_ts": time.Now()}
+	switch {
+	case err == nil:
+		update["status"] = constants.JobStatusFinished
+		update["error"] = ""
+	case j.Attempts < j.MaxAttempts:
+		log.Warnf("job[%s] %s attempt %d failed, retrying: %v", j.Id.Hex(), j.Type, j.Attempts, err)
+		update["status"] = constants.JobStatusPending
+		update["error"] = err.Error()
+		update["run_ts"] = time.Now().Add(getRetryDelay(svc.retryBackoff, j.Attempts))
+	default:
+		log.Errorf("job[%s] %s failed after %d attempts: %v", j.Id.Hex(), j.Type, j.Attempts, err)
+		update["status"] = constants.JobStatusError
+		update["error"] = err.Error()
+	}
+	if err := svc.col.UpdateId(j.Id, bson.M{"$set": update}); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// handle runs the handler and recovers from panics, which are treated as errors
+func (svc *Service) handle(handler interfaces.JobHandler, j *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = trace.TraceError(errors.NewJobError(fmt.Sprintf("panic: %v", r)))
+		}
+	}()
+	return handler([]byte(j.Payload))
+}
+
+// getRetryDelay returns the delay before the next attempt after the given
+// number of failed attempts, doubling from base and capped at an hour
+func getRetryDelay(base time.Duration, attempts int) (delay time.Duration) {
+	delay = base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= time.Hour {
+			return time.Hour
+		}
+	}
+	return delay
+}
+
+func NewService() (svc *Service) {
+	svc = &Service{
+		workers:      2,
+		maxAttempts:  3,
+		pollInterval: 1 * time.Second,
+		retryBackoff: 10 * time.Second,
+		col:          mongo.GetMongoCol(constants.JobColName),
+		handlers:     map[string]interfaces.JobHandler{},
+		stopCh:       make(chan struct{}),
+	}
+
+	// number of workers, max attempts, poll interval (seconds) and initial
+	// retry backoff (seconds) of jobs
+	if workers := viper.GetInt("job.workers"); workers > 0 {
+		svc.workers = workers
+	}
+	if maxAttempts := viper.GetInt("job.maxAttempts"); maxAttempts > 0 {
+		svc.maxAttempts = maxAttempts
+	}
+	if pollIntervalSeconds := viper.GetInt("job.pollInterval"); pollIntervalSeconds > 0 {
+		svc.pollInterval = time.Duration(pollIntervalSeconds) * time.Second
+	}
+	if retryBackoffSeconds := viper.GetInt("job.retryBackoff"); retryBackoffSeconds > 0 {
+		svc.retryBackoff = time.Duration(retryBackoffSeconds) * time.Second
+	}
+
+	return svc
+}
+
+var _service *Service
+var _serviceOnce sync.Once
+
+func GetService() (svc *Service) {
+	_serviceOnce.Do(func() {
+		_service = NewService()
+	})
+	return _service
+}
+
+// RegisterJobHandler registers the handler of the job type to the job service
+func RegisterJobHandler(jobType string, handler interfaces.JobHandler) {
+	GetService().RegisterHandler(jobType, handler)
+}
+
+// EnqueueJob persists a job to be run by the job service on master
+func EnqueueJob(jobType string, payload interface{}) (id primitive.ObjectID, err error) {
+	return GetService().Enqueue(jobType, payload)
+}
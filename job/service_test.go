@@ -0,0 +1,14 @@
+package job
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGetRetryDelay(t *testing.T) {
+	require.Equal(t, 10*time.Second, getRetryDelay(10*time.Second, 1))
+	require.Equal(t, 20*time.Second, getRetryDelay(10*time.Second, 2))
+	require.Equal(t, 40*time.Second, getRetryDelay(10*time.Second, 3))
+	require.Equal(t, time.Hour, getRetryDelay(10*time.Second, 20))
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/result"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"strconv"
+)
+
+// Collect returns the current node and task metrics of the cluster, which are
+// served by the scrape endpoint and pushed to the pushgateway
+func Collect() (metrics []Metric, err error) {
+	nodeMetrics, nodeKeys, err := collectNodeMetrics()
+	if err != nil {
+		return nil, err
+	}
+	metrics = append(metrics, nodeMetrics...)
+
+	taskMetrics, err := collectTaskMetrics(nodeKeys)
+	if err != nil {
+		return nil, err
+	}
+	metrics = append(metrics, taskMetrics...)
+
+	metrics = append(metrics, collectResultMetrics()...)
+
+	return metrics, nil
+}
+
+// collectResultMetrics returns metrics of result ingestion in batches of the
+// current node, if enabled
+func collectResultMetrics() (metrics []Metric) {
+	stats, ok := result.GetBatchStats()
+	if !ok {
+		return nil
+	}
+	gauge := func(name, help string, value float64) Metric {
+		return Metric{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+	}
+	counter := func(name, help string, value float64) Metric {
+		return Metric{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: value}}}
+	}
+	return []Metric{
+		gauge("crawlab_result_batch_pending_records", "Number of result records buffered and not yet inserted.", float64(stats.Pending)),
+		gauge("crawlab_result_batch_pending_tasks", "Number of tasks with buffered result records.", float64(stats.PendingTasks)),
+		gauge("crawlab_result_batch_lag_seconds", "Wait of the oldest buffered result record in seconds.", stats.Lag),
+		gauge("crawlab_result_batch_inserting", "Number of result batches being inserted.", float64(stats.Inserting)),
+		gauge("crawlab_result_batch_last_insert_seconds", "Duration of the last result batch insert in seconds.", stats.LastInsertTime),
+		counter("crawlab_result_batch_batches_total", "Total number of result batches inserted.", float64(stats.Batches)),
+		counter("crawlab_result_batch_records_total", "Total number of result records inserted in batches.", float64(stats.Records)),
+		counter("crawlab_result_batch_errors_total", "Total number of result batches failed to insert.", float64(stats.Errors)),
+	}
+}
+
+// collectNodeMetrics returns metrics of nodes and the keys of nodes by id
+func collectNodeMetrics() (metrics []Metric, nodeKeys map[primitive.ObjectID]string, err error) {
+	var nodes []models.Node
+	if err := mongo.GetMongoCol(interfaces.ModelColNameNode).Find(nil, nil).All(&nodes); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, nil, trace.TraceError(err)
+	}
+
+	up := Metric{Name: "crawlab_node_up", Help: "Whether the node is active (1) or not (0).", Type: TypeGauge}
+	enabled := Metric{Name: "crawlab_node_enabled", Help: "Whether the node is enabled (1) or not (0).", Type: TypeGauge}
+	maxRunners := Metric{Name: "crawlab_node_max_runners", Help: "Max number of concurrent tasks of the node.", Type: TypeGauge}
+	availableRunners := Metric{Name: "crawlab_node_available_runners", Help: "Number of tasks the node can still run concurrently.", Type: TypeGauge}
+	nodeKeys = map[primitive.ObjectID]string{}
+	for _, n := range nodes {
+		nodeKeys[n.Id] = n.Key
+		labels := map[string]string{
+			"node_key":  n.Key,
+			"node_name": n.Name,
+			"is_master": strconv.FormatBool(n.IsMaster),
+			"status":    n.Status,
+		}
+		up.Samples = append(up.Samples, Sample{Labels: labels, Value: boolToFloat(n.Active)})
+		enabled.Samples = append(enabled.Samples, Sample{Labels: labels, Value: boolToFloat(n.Enabled)})
+		maxRunners.Samples = append(maxRunners.Samples, Sample{Labels: labels, Value: float64(n.MaxRunners)})
+		availableRunners.Samples = append(availableRunners.Samples, Sample{Labels: labels, Value: float64(n.AvailableRunners)})
+	}
+	return []Metric{up, enabled, maxRunners, availableRunners}, nodeKeys, nil
+}
+
+func collectTaskMetrics(nodeKeys map[primitive.ObjectID]string) (metrics []Metric, err error) {
+	col := mongo.GetMongoCol(interfaces.ModelColNameTask)
+
+	// tasks by status
+	var byStatus []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := col.Aggregate(mongo2.Pipeline{
+		{{"$group", bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}, nil).All(&byStatus); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	tasks := Metric{Name: "crawlab_tasks", Help: "Number of tasks by status.", Type: TypeGauge}
+	for _, s := range byStatus {
+		tasks.Samples = append(tasks.Samples, Sample{Labels: map[string]string{"status": s.Status}, Value: float64(s.Count)})
+	}
+
+	// running tasks by node
+	runningByNode, err := countRunningTasksByNode(col)
+	if err != nil {
+		return nil, err
+	}
+	running := Metric{Name: "crawlab_node_running_tasks", Help: "Number of running tasks of the node.", Type: TypeGauge}
+	for nodeId, count := range runningByNode {
+		key, ok := nodeKeys[nodeId]
+		if !ok {
+			continue
+		}
+		running.Samples = append(running.Samples, Sample{Labels: map[string]string{"node_key": key}, Value: float64(count)})
+	}
+
+	// running tasks by spider
+	spiderRunning := Metric{Name: "crawlab_spider_running_tasks", Help: "Number of running tasks of the spider.", Type: TypeGauge}
+	if c := task.GetRunningTaskCounter(); c.IsSynced() {
+		for spiderId, count := range c.GetSpiderCounts() {
+			spiderRunning.Samples = append(spiderRunning.Samples, Sample{Labels: map[string]string{"spider_id": spiderId.Hex()}, Value: float64(count)})
+		}
+	}
+
+	// cluster-wide running tasks and limit
+	runningTotal, err := task.CountRunningTasks()
+	if err != nil {
+		return nil, err
+	}
+	clusterRunning := Metric{Name: "crawlab_running_tasks", Help: "Number of running tasks of the cluster.", Type: TypeGauge}
+	clusterRunning.Samples = []Sample{{Value: float64(runningTotal)}}
+	maxTasks := Metric{Name: "crawlab_max_concurrent_tasks", Help: "Max number of concurrently running tasks of the cluster, 0 if unlimited.", Type: TypeGauge}
+	maxTasks.Samples = []Sample{{Value: float64(task.GetMaxConcurrentTasks())}}
+	paused := Metric{Name: "crawlab_scheduler_paused", Help: "Whether dispatching tasks is paused cluster-wide (1) or not (0).", Type: TypeGauge}
+	paused.Samples = []Sample{{Value: boolToFloat(task.IsSchedulerPaused())}}
+
+	return []Metric{tasks, running, spiderRunning, clusterRunning, maxTasks, paused}, nil
+}
+
+// countRunningTasksByNode returns the number of running tasks by node, from the
+// running task counter if it is synced, otherwise from the database
+func countRunningTasksByNode(col *mongo.Col) (counts map[primitive.ObjectID]int, err error) {
+	if c := task.GetRunningTaskCounter(); c.IsSynced() {
+		return c.GetNodeCounts(), nil
+	}
+	var byNode []struct {
+		NodeId primitive.ObjectID `bson:"_id"`
+		Count  int                `bson:"count"`
+	}
+	if err := col.Aggregate(mongo2.Pipeline{
+		{{"$match", bson.M{"status": constants.TaskStatusRunning}}},
+		{{"$group", bson.M{"_id": "$node_id", "count": bson.M{"$sum": 1}}}},
+	}, nil).All(&byNode); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	counts = map[primitive.ObjectID]int{}
+	for _, n := range byNode {
+		counts[n.NodeId] = n.Count
+	}
+	return counts, nil
+}
+
+func boolToFloat(b bool) (v float64) {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric is a metric family in the Prometheus text exposition format
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string // "gauge" or "counter"
+	Samples []Sample
+}
+
+// Sample is a value of a metric with its labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes the metrics in the Prometheus text exposition format
+func WriteText(w io.Writer, metrics []Metric) (err error) {
+	var sb strings.Builder
+	for _, m := range metrics {
+		sb.WriteString("# HELP " + m.Name + " " + escapeHelp(m.Help) + "\n")
+		sb.WriteString("# TYPE " + m.Name + " " + m.Type + "\n")
+		for _, s := range m.Samples {
+			sb.WriteString(m.Name)
+			sb.WriteString(formatLabels(s.Labels))
+			sb.WriteString(" ")
+			sb.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+			sb.WriteString("\n")
+		}
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// formatLabels returns labels sorted by name, e.g. {node="a",status="on"}
+func formatLabels(labels map[string]string) (res string) {
+	if len(labels) == 0 {
+		return ""
+	}
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) (res string) {
+	return labelValueReplacer.Replace(v)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(v string) (res string) {
+	return helpReplacer.Replace(v)
+}
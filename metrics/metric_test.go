@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteText(&buf, []Metric{
+		{
+			Name: "crawlab_node_up",
+			Help: "Whether the node is active",
+			Type: TypeGauge,
+			Samples: []Sample{
+				{Labels: map[string]string{"node_name": `a "b"`, "node_key": "k1"}, Value: 1},
+				{Value: 0.5},
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, `# HELP crawlab_node_up Whether the node is active
+# TYPE crawlab_node_up gauge
+crawlab_node_up{node_key="k1",node_name="a \"b\""} 1
+crawlab_node_up 0.5
+`, buf.String())
+}
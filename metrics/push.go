@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/go-trace"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PushSettings of pushing metrics to a Prometheus Pushgateway, stored in the
+// setting constants.SettingKeyPushgateway
+type PushSettings struct {
+	Enabled  bool   `json:"enabled" bson:"enabled"`
+	Url      string `json:"url" bson:"url"`           // pushgateway url, e.g. http://pushgateway:9091
+	Job      string `json:"job" bson:"job"`           // job label, "crawlab" by default
+	Interval int    `json:"interval" bson:"interval"` // push interval (seconds), 15 seconds by default
+	Username string `json:"username" bson:"username"` // basic auth username, optional
+	Password string `json:"password" bson:"password"` // basic auth password, optional
+}
+
+const (
+	defaultPushJob      = "crawlab"
+	defaultPushInterval = 15 * time.Second
+	maxPushBackoff      = 5 * time.Minute
+)
+
+// GetPushSettings returns the pushgateway settings, disabled by default
+func GetPushSettings() (s PushSettings) {
+	modelSvc, err := service.GetService()
+	if err != nil {
+		trace.PrintError(err)
+		return s
+	}
+	if _, err := modelSvc.GetSettingValue(constants.SettingKeyPushgateway, &s); err != nil {
+		trace.PrintError(err)
+		return PushSettings{}
+	}
+	return s
+}
+
+func (s PushSettings) getInterval() (interval time.Duration) {
+	if s.Interval > 0 {
+		return time.Duration(s.Interval) * time.Second
+	}
+	return defaultPushInterval
+}
+
+// Pusher pushes metrics of the cluster to the pushgateway on master. Settings
+// are read before each push so that changes take effect without restarting
+// master. Failed pushes are retried with exponential backoff.
+type Pusher struct {
+	instance string
+	client   *http.Client
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func (p *Pusher) Start() {
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = maxPushBackoff
+	b.MaxElapsedTime = 0
+	failed := false
+	for {
+		s := GetPushSettings()
+		wait := s.getInterval()
+		if s.Enabled && s.Url != "" {
+			if err := p.push(s); err != nil {
+				if !failed {
+					b.Reset()
+					failed = true
+				}
+				wait = b.NextBackOff()
+				log.Warnf("push metrics to %s failed, retrying in %v: %v", s.Url, wait, err)
+			} else {
+				failed = false
+			}
+		}
+
+		select {
+		case <-p.stopCh:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (p *Pusher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+}
+
+// push replaces the metrics of the job and instance on the pushgateway
+func (p *Pusher) push(s PushSettings) (err error) {
+	metrics, err := Collect()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, metrics); err != nil {
+		return trace.TraceError(err)
+	}
+	job := s.Job
+	if job == "" {
+		job = defaultPushJob
+	}
+	u := strings.TrimSuffix(s.Url, "/") + "/metrics/job/" + url.PathEscape(job) + "/instance/" + url.PathEscape(p.instance)
+	req, err := http.NewRequest(http.MethodPut, u, &buf)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if s.Username != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return trace.TraceError(fmt.Errorf("pushgateway responded %s", res.Status))
+	}
+	return nil
+}
+
+// NewPusher returns a pusher of metrics labeled with the instance, i.e. the
+// key of the master node
+func NewPusher(instance string) (p *Pusher) {
+	return &Pusher{
+		instance: instance,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		stopCh:   make(chan struct{}),
+	}
+}
@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/crawlab-team/crawlab-core/audit"
+	"github.com/crawlab-team/crawlab-core/controllers"
+	"github.com/crawlab-team/go-trace"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// auditMaxPayloadSize is the max size of request bodies recorded in audit logs
+const auditMaxPayloadSize = 1024 * 1024
+
+// AuditMiddleware records write API actions (POST, PUT, PATCH and DELETE) in
+// audit logs if "audit.enabled" is set. Models in routes with an id are
+// recorded before and after the action.
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !audit.IsEnabled() || !isAuditMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		l := &audit.Log{
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Route:  c.FullPath(),
+			Model:  audit.GetModelColName(c.FullPath()),
+		}
+
+		// user
+		if u := controllers.GetUserFromContext(c); u != nil {
+			l.UserId = u.GetId()
+			l.Username = u.GetUsername()
+		}
+
+		// payload
+		l.Payload = getAuditPayload(c)
+
+		// model before the action
+		if l.Model != "" {
+			if id, err := primitive.ObjectIDFromHex(c.Param("id")); err == nil {
+				l.ModelId = id
+				l.Before = audit.GetModelDoc(l.Model, id)
+			}
+		}
+
+		c.Next()
+
+		// model after the action
+		l.Status = c.Writer.Status()
+		if !l.ModelId.IsZero() && l.Status < http.StatusBadRequest && c.Request.Method != http.MethodDelete {
+			l.After = audit.GetModelDoc(l.Model, l.ModelId)
+		}
+
+		if err := audit.AddLog(l); err != nil {
+			trace.PrintError(err)
+		}
+	}
+}
+
+func isAuditMethod(method string) (ok bool) {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// getAuditPayload returns the redacted JSON body of the request, which is
+// restored for the handlers. Other bodies, e.g. uploaded files, are omitted.
+func getAuditPayload(c *gin.Context) (payload interface{}) {
+	if c.Request.Body == nil ||
+		c.Request.ContentLength > auditMaxPayloadSize ||
+		!strings.Contains(c.ContentType(), "json") {
+		return nil
+	}
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if len(data) > auditMaxPayloadSize {
+		return nil
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+	return audit.RedactPayload(payload)
+}
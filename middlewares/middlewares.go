@@ -12,5 +12,11 @@ func InitMiddlewares(app *gin.Engine) (err error) {
 	// cors
 	app.Use(CORSMiddleware())
 
+	// tracing
+	app.Use(TracingMiddleware())
+
+	// request timeout
+	app.Use(TimeoutMiddleware())
+
 	return nil
 }
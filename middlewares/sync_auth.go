@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/controllers"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/gin-gonic/gin"
+)
+
+// SyncAuthorizationMiddleware authorizes file sync requests from worker nodes by the grpc auth key
+func SyncAuthorizationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(constants.SyncHeaderAuthorization) != fs.GetSyncAuthKey() {
+			controllers.HandleErrorUnauthorized(c, errors.ErrorHttpUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
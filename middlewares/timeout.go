@@ -0,0 +1,195 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeoutMiddleware runs each API request with a context timing out after the
+// timeout of the route (see GetRequestTimeout). Database queries made with the
+// context are cancelled on timeout, and a structured error is returned with
+// 504 if the handler has not responded yet, while whatever the handler writes
+// afterwards is discarded. Responses already being written, e.g. downloads,
+// are not interrupted.
+func TimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := GetRequestTimeout(c.Request.Method, c.FullPath())
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		w := newTimeoutWriter(c.Writer)
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+
+		// run handlers, whose panics are re-raised to be recovered as usual
+		done := make(chan struct{})
+		var p interface{}
+		go func() {
+			defer close(done)
+			defer func() {
+				p = recover()
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			w.timeout()
+			// the handler goroutine still uses the context of the request,
+			// which must not be released before it returns
+			<-done
+		}
+		if p != nil {
+			panic(p)
+		}
+	}
+}
+
+// GetRequestTimeout returns the timeout of requests of the route, which is
+// configured by "api.timeouts.<method> <route>" or "api.timeouts.<route>"
+// (seconds), e.g. "api.timeouts.post /spiders/:id/run", falling back to
+// "api.timeout". Requests do not time out if it is 0, which is the default.
+func GetRequestTimeout(method, route string) (timeout time.Duration) {
+	for _, key := range []string{
+		"api.timeouts." + strings.ToLower(method+" "+route),
+		"api.timeouts." + strings.ToLower(route),
+	} {
+		if route != "" && viper.IsSet(key) {
+			return time.Duration(viper.GetInt(key)) * time.Second
+		}
+	}
+	return time.Duration(viper.GetInt("api.timeout")) * time.Second
+}
+
+// timeoutWriter passes the response of the handler through until the request
+// times out. Headers are set by the handler on a copy, so that they do not
+// race with the timeout response.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	h        http.Header
+	code     int
+	written  bool // whether the handler has started writing the response
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.written {
+		return
+	}
+	w.code = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.writeHeader()
+}
+
+func (w *timeoutWriter) Write(data []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.writeHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (n int, err error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() (code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written && w.code != 0 {
+		return w.code
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutWriter) Written() (ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written || w.timedOut
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.writeHeader()
+	w.ResponseWriter.Flush()
+}
+
+// writeHeader copies headers of the handler and writes the status once
+func (w *timeoutWriter) writeHeader() {
+	if w.written {
+		return
+	}
+	w.written = true
+	dst := w.ResponseWriter.Header()
+	for k, v := range w.h {
+		dst[k] = v
+	}
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// timeout responds with the timeout error unless the handler has started
+// writing the response
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.written {
+		return
+	}
+	w.timedOut = true
+	data, _ := json.Marshal(entity.Response{
+		Status:  constants.HttpResponseStatusOk,
+		Message: constants.HttpResponseMessageError,
+		Error:   errors.ErrorHttpRequestTimeout.Error(),
+	})
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = w.ResponseWriter.Write(data)
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) (w2 *timeoutWriter) {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		h:              w.Header().Clone(),
+	}
+}
@@ -79,6 +79,8 @@ func (b *BasicBinder) Bind() (res interfaces.Model, err error) {
 		return b.Process(&m.Environment)
 	case interfaces.ModelIdDependencySetting:
 		return b.Process(&m.DependencySetting)
+	case interfaces.ModelIdTaskEvent:
+		return b.Process(&m.TaskEvent)
 	default:
 		return nil, errors.ErrorModelInvalidModelId
 	}
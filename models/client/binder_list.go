@@ -79,6 +79,8 @@ func (b *ListBinder) Bind() (l interfaces.List, err error) {
 		return b.Process(&m.Environments)
 	case interfaces.ModelIdDependencySetting:
 		return b.Process(&m.DependencySettings)
+	case interfaces.ModelIdTaskEvent:
+		return b.Process(&m.TaskEvents)
 	default:
 		return l, errors.ErrorModelInvalidModelId
 	}
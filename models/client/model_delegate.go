@@ -12,6 +12,7 @@ import (
 	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
 	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func NewModelDelegate(doc interfaces.Model, opts ...ModelDelegateOption) interfaces.GrpcClientModelDelegate {
@@ -68,6 +69,8 @@ func NewModelDelegate(doc interfaces.Model, opts ...ModelDelegateOption) interfa
 		return newModelDelegate(interfaces.ModelIdEnvironment, doc, opts...)
 	case *models.DependencySetting:
 		return newModelDelegate(interfaces.ModelIdDependencySetting, doc, opts...)
+	case *models.TaskEvent:
+		return newModelDelegate(interfaces.ModelIdTaskEvent, doc, opts...)
 	default:
 		_ = trace.TraceError(errors.ErrorModelInvalidType)
 		return nil
@@ -142,6 +145,29 @@ func (d *ModelDelegate) Save() (err error) {
 	return d.do(interfaces.ModelDelegateMethodSave)
 }
 
+func (d *ModelDelegate) UpdateFields(fields bson.M) (err error) {
+	fieldsData, err := bson.Marshal(fields)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	ctx, cancel := d.c.Context()
+	defer cancel()
+	method := interfaces.ModelDelegateMethod(interfaces.ModelDelegateMethodUpdateFields)
+	res, err := d.c.GetModelDelegateClient().Do(ctx, d.c.NewRequest(entity.GrpcDelegateMessage{
+		ModelId: d.id,
+		Method:  method,
+		Data:    d.mustGetData(),
+		Fields:  fieldsData,
+	}))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if err := d.deserialize(res, method); err != nil {
+		return err
+	}
+	return d.refreshArtifact()
+}
+
 func (d *ModelDelegate) Delete() (err error) {
 	return d.do(interfaces.ModelDelegateMethodDelete)
 }
@@ -9,6 +9,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// CreateIndexes creates indexes of collections on startup if not exist. Indexes
+// added or changed since are created by migrations (see migrations.go), so that
+// changes of existing indexes are applied once.
 func CreateIndexes() {
 	// artifacts
 	mongo.GetMongoCol(interfaces.ModelColNameArtifact).MustCreateIndexes([]mongo2.IndexModel{
@@ -72,9 +75,8 @@ func CreateIndexes() {
 		{Keys: bson.M{"enabled": 1}},
 	})
 
-	// users
+	// users (index of username is unique, see migrations)
 	mongo.GetMongoCol(interfaces.ModelColNameUser).MustCreateIndexes([]mongo2.IndexModel{
-		{Keys: bson.M{"username": 1}},
 		{Keys: bson.M{"role": 1}},
 		{Keys: bson.M{"email": 1}},
 	})
@@ -0,0 +1,322 @@
+package common
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Migration is a versioned change of collections or indexes, applied once on
+// startup in the order of versions and recorded in the migrations collection.
+type Migration struct {
+	Version     string       // version which orders migrations by numeric parts, e.g. "20221016.1" or "0.6.10"
+	Description string       // description
+	Up          func() error // apply the migration, should be safe to re-run if interrupted
+}
+
+type migrationRecord struct {
+	Id          primitive.ObjectID `bson:"_id"`
+	Version     string             `bson:"version"`
+	Description string             `bson:"description"`
+	AppliedTs   time.Time          `bson:"applied_ts"`
+}
+
+// migrationLock is a lease of the lock of migrations held by a master, so that
+// migrations are applied by one master at a time in the cluster. The lease
+// expires after "migration.lock.ttl" (seconds, 600 by default) unless renewed
+// by the holder, so that the lock of a crashed master is eventually released.
+type migrationLock struct {
+	Key      string             `bson:"_id"`
+	Owner    primitive.ObjectID `bson:"owner"`
+	CreateTs time.Time          `bson:"create_ts"`
+	ExpireTs time.Time          `bson:"expire_ts"`
+}
+
+const migrationLockKey = "migrations"
+
+var migrations []*Migration
+var migrationsLock sync.Mutex
+
+// compareMigrationVersions compares versions by their dot-separated parts,
+// numerically if both parts are numbers, so that "20221020.10" comes after
+// "20221020.2". It returns -1, 0 or 1 as a is less than, equal to or greater
+// than b.
+func compareMigrationVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.ParseUint(pa[i], 10, 64)
+		nb, errB := strconv.ParseUint(pb[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
+
+// RegisterMigration registers the migration. It panics if the version has been registered.
+func RegisterMigration(m *Migration) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	for _, m2 := range migrations {
+		if m2.Version == m.Version {
+			panic(fmt.Errorf("%w: %s", errors.ErrorModelMigrationDuplicated, m.Version))
+		}
+	}
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool {
+		return compareMigrationVersions(migrations[i].Version, migrations[j].Version) < 0
+	})
+}
+
+// NewIndexMigration returns a migration creating indexes of the collection
+func NewIndexMigration(version, description, colName string, indexes []mongo2.IndexModel) (m *Migration) {
+	return &Migration{
+		Version:     version,
+		Description: description,
+		Up: func() error {
+			return mongo.GetMongoCol(colName).CreateIndexes(indexes)
+		},
+	}
+}
+
+// NewDropIndexMigration returns a migration dropping the index of the
+// collection by name if it exists
+func NewDropIndexMigration(version, description, colName, name string) (m *Migration) {
+	return &Migration{
+		Version:     version,
+		Description: description,
+		Up: func() error {
+			col := mongo.GetMongoCol(colName)
+			if _, err := col.GetCollection().Indexes().DropOne(col.GetContext(), name); err != nil {
+				if e, ok := err.(mongo2.CommandError); ok && (e.Name == "IndexNotFound" || e.Name == "NamespaceNotFound") {
+					return nil
+				}
+				return trace.TraceError(err)
+			}
+			return nil
+		},
+	}
+}
+
+// NewBackfillMigration returns a migration setting the field of documents in
+// the collection without the field to the value
+func NewBackfillMigration(version, description, colName, field string, value interface{}) (m *Migration) {
+	return &Migration{
+		Version:     version,
+		Description: description,
+		Up: func() error {
+			_, err := mongo.GetMongoCol(colName).GetCollection().UpdateMany(
+				mongo.GetMongoCol(colName).GetContext(),
+				bson.M{field: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{field: value}},
+			)
+			if err != nil {
+				return trace.TraceError(err)
+			}
+			return nil
+		},
+	}
+}
+
+// GetPendingMigrations returns registered migrations not yet applied, in order
+func GetPendingMigrations() (res []*Migration, err error) {
+	col := mongo.GetMongoCol(constants.MigrationColName)
+	if err := col.CreateIndex(mongo2.IndexModel{
+		Keys:    bson.M{"version": 1},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, err
+	}
+
+	// applied versions
+	var records []migrationRecord
+	if err := col.Find(nil, nil).All(&records); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	applied := map[string]bool{}
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func getMigrationLockTtl() (ttl time.Duration) {
+	if seconds := viper.GetInt("migration.lock.ttl"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+// acquireMigrationLock acquires the lock of migrations for the owner. ok is
+// false if the lock is held by another owner.
+func acquireMigrationLock(owner primitive.ObjectID) (ok bool, err error) {
+	col := mongo.GetMongoCol(constants.MigrationLockColName)
+	now := time.Now()
+	query := bson.M{
+		"_id": migrationLockKey,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expire_ts": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":     owner,
+			"create_ts": now,
+			"expire_ts": now.Add(getMigrationLockTtl()),
+		},
+	}
+	// upsert fails with duplicate key if the lock is held by another owner
+	if _, err := col.GetCollection().UpdateOne(col.GetContext(), query, update, options.Update().SetUpsert(true)); err != nil {
+		if mongo2.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, trace.TraceError(err)
+	}
+	return true, nil
+}
+
+// renewMigrationLock extends the lease of the lock held by the owner
+func renewMigrationLock(owner primitive.ObjectID) (err error) {
+	if err := mongo.GetMongoCol(constants.MigrationLockColName).Update(bson.M{"_id": migrationLockKey, "owner": owner}, bson.M{
+		"$set": bson.M{"expire_ts": time.Now().Add(getMigrationLockTtl())},
+	}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// releaseMigrationLock releases the lock if it is held by the owner
+func releaseMigrationLock(owner primitive.ObjectID) (err error) {
+	if err := mongo.GetMongoCol(constants.MigrationLockColName).Delete(bson.M{"_id": migrationLockKey, "owner": owner}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// lockMigrations waits until the lock of migrations is acquired, and renews
+// it until unlock is called
+func lockMigrations() (unlock func(), err error) {
+	owner := primitive.NewObjectID()
+	for {
+		ok, err := acquireMigrationLock(owner)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		log.Infof("[migration] waiting for migrations applied by another master")
+		time.Sleep(time.Second)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(getMigrationLockTtl() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := renewMigrationLock(owner); err != nil {
+					trace.PrintError(err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := releaseMigrationLock(owner); err != nil {
+			trace.PrintError(err)
+		}
+	}, nil
+}
+
+// RunMigrations applies pending migrations in order and records each applied
+// one. It stops at the first failed migration. Migrations are applied under a
+// cluster-wide lock, so that masters starting at the same time wait for each
+// other and apply each migration once. With dryRun, pending migrations are
+// only reported.
+func RunMigrations(dryRun bool) (pending []*Migration, err error) {
+	if dryRun {
+		pending, err = GetPendingMigrations()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range pending {
+			log.Infof("[migration] pending %s: %s", m.Version, m.Description)
+		}
+		return pending, nil
+	}
+
+	unlock, err := lockMigrations()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// pending migrations are read under the lock, so that those applied by
+	// another master in the meantime are skipped
+	pending, err = GetPendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	col := mongo.GetMongoCol(constants.MigrationColName)
+	for _, m := range pending {
+		log.Infof("[migration] applying %s: %s", m.Version, m.Description)
+		if err := m.Up(); err != nil {
+			log.Errorf("[migration] failed to apply %s: %v", m.Version, err)
+			return pending, err
+		}
+		if _, err := col.Insert(&migrationRecord{
+			Id:          primitive.NewObjectID(),
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedTs:   time.Now(),
+		}); err != nil {
+			return pending, err
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,15 @@
+package common
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompareMigrationVersions(t *testing.T) {
+	require.Equal(t, -1, compareMigrationVersions("20221020.2", "20221020.10"))
+	require.Equal(t, 1, compareMigrationVersions("20221021.1", "20221020.10"))
+	require.Equal(t, 0, compareMigrationVersions("20221020.1", "20221020.1"))
+	require.Equal(t, -1, compareMigrationVersions("0.6.9", "v0.6.10"))
+	require.Equal(t, -1, compareMigrationVersions("0.6", "0.6.1"))
+	require.Equal(t, -1, compareMigrationVersions("0.6.0-beta", "0.6.0-rc"))
+}
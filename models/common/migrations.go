@@ -0,0 +1,111 @@
+package common
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+)
+
+func init() {
+	RegisterMigration(NewBackfillMigration("20221016.1", "backfill retries of tasks", interfaces.ModelColNameTask, "retries", 0))
+	RegisterMigration(NewBackfillMigration("20221016.2", "backfill max_retries of tasks", interfaces.ModelColNameTask, "max_retries", 0))
+	RegisterMigration(NewBackfillMigration("20221017.1", "backfill enabled of spiders", interfaces.ModelColNameSpider, "enabled", true))
+	RegisterMigration(NewBackfillMigration("20221018.1", "backfill schedulable of nodes", interfaces.ModelColNameNode, "schedulable", true))
+	RegisterMigration(NewBackfillMigration("20221020.2", "backfill dead_letter of tasks", interfaces.ModelColNameTask, "dead_letter", false))
+	RegisterMigration(NewIndexMigration("20221020.3", "index retry candidates of tasks", interfaces.ModelColNameTask, []mongo2.IndexModel{
+		{
+			// failed tasks to be retried or dead-lettered by the task scheduler
+			Keys: bson.D{{"retry_id", 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{
+				"status":      constants.TaskStatusError,
+				"max_retries": bson.M{"$gt": 0},
+				"dead_letter": false,
+			}),
+		},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.1", "index task events", interfaces.ModelColNameTaskEvent, []mongo2.IndexModel{
+		{Keys: bson.D{{"task_id", 1}, {"ts", 1}}},
+	}))
+	RegisterMigration(&Migration{
+		Version:     "20221021.2",
+		Description: "make index of usernames unique",
+		Up: func() error {
+			// duplicate usernames are to be resolved by admins, as the
+			// unique index cannot be created with them
+			usernames, err := getDuplicateUsernames()
+			if err != nil {
+				return err
+			}
+			if len(usernames) > 0 {
+				return fmt.Errorf("%w: duplicate usernames %s, rename or delete the duplicate users in the %s collection and restart master", errors.ErrorModelMigrationFailed, strings.Join(usernames, ", "), interfaces.ModelColNameUser)
+			}
+
+			// the index of usernames was not unique, so it is dropped first
+			if err := NewDropIndexMigration("", "", interfaces.ModelColNameUser, "username_1").Up(); err != nil {
+				return err
+			}
+			return mongo.GetMongoCol(interfaces.ModelColNameUser).CreateIndexes([]mongo2.IndexModel{
+				{Keys: bson.M{"username": 1}, Options: options.Index().SetUnique(true)},
+			})
+		},
+	})
+	RegisterMigration(NewIndexMigration("20221021.3", "index roles of users", interfaces.ModelColNameUser, []mongo2.IndexModel{
+		{Keys: bson.M{"roles": 1}},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.4", "index task idempotency keys", constants.TaskIdempotencyKeyColName, []mongo2.IndexModel{
+		{Keys: bson.M{"key": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"expire_ts": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.5", "index task artifacts", constants.TaskArtifactColName, []mongo2.IndexModel{
+		{Keys: bson.M{"task_id": 1}},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.6", "index task locks", constants.TaskLockColName, []mongo2.IndexModel{
+		{Keys: bson.M{"task_id": 1}},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.7", "index background jobs", constants.JobColName, []mongo2.IndexModel{
+		{Keys: bson.D{{"status", 1}, {"type", 1}, {"run_ts", 1}}},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.8", "index audit logs", constants.AuditLogColName, []mongo2.IndexModel{
+		{Keys: bson.M{"ts": -1}},
+		{Keys: bson.D{{"user_id", 1}, {"ts", -1}}},
+		{Keys: bson.D{{"model", 1}, {"model_id", 1}, {"ts", -1}}},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.9", "index refresh tokens", constants.RefreshTokenColName, []mongo2.IndexModel{
+		{Keys: bson.M{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"family_id": 1}},
+		{Keys: bson.M{"expire_ts": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}))
+	RegisterMigration(NewIndexMigration("20221021.10", "index nodes of task locks", constants.TaskLockColName, []mongo2.IndexModel{
+		{Keys: bson.M{"node_key": 1}},
+	}))
+}
+
+// getDuplicateUsernames returns usernames shared by more than one user
+func getDuplicateUsernames() (usernames []string, err error) {
+	col := mongo.GetMongoCol(interfaces.ModelColNameUser)
+	cur, err := col.GetCollection().Aggregate(col.GetContext(), mongo2.Pipeline{
+		{{"$group", bson.M{"_id": "$username", "count": bson.M{"$sum": 1}}}},
+		{{"$match", bson.M{"count": bson.M{"$gt": 1}}}},
+		{{"$sort", bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	var res []struct {
+		Username string `bson:"_id"`
+	}
+	if err := cur.All(col.GetContext(), &res); err != nil {
+		return nil, trace.TraceError(err)
+	}
+	for _, r := range res {
+		usernames = append(usernames, r.Username)
+	}
+	return usernames, nil
+}
@@ -73,6 +73,8 @@ func NewModelDelegate(doc interfaces.Model, args ...interface{}) interfaces.Mode
 		return newModelDelegate(interfaces.ModelIdEnvironment, doc, args...)
 	case *models.DependencySetting:
 		return newModelDelegate(interfaces.ModelIdDependencySetting, doc, args...)
+	case *models.TaskEvent:
+		return newModelDelegate(interfaces.ModelIdTaskEvent, doc, args...)
 	default:
 		_ = trace.TraceError(errors2.ErrorModelInvalidType)
 		return nil
@@ -83,6 +85,9 @@ func newModelDelegate(id interfaces.ModelId, doc interfaces.Model, args ...inter
 	// user
 	u := utils.GetUserFromArgs(args...)
 
+	// session context of the transaction
+	sc := utils.GetSessionContextFromArgs(args...)
+
 	// collection name
 	colName := models.GetModelColName(id)
 
@@ -94,7 +99,8 @@ func newModelDelegate(id interfaces.ModelId, doc interfaces.Model, args ...inter
 		a: &models.Artifact{
 			Col: colName,
 		},
-		u: u,
+		u:  u,
+		sc: sc,
 	}
 
 	return d
@@ -108,6 +114,7 @@ type ModelDelegate struct {
 	od      bson.M                   // original doc
 	a       interfaces.ModelArtifact // artifact
 	u       interfaces.User          // user
+	sc      mongo2.SessionContext    // session context of the transaction, nil if not in a transaction
 }
 
 // Add model
@@ -120,6 +127,19 @@ func (d *ModelDelegate) Save() (err error) {
 	return d.do(interfaces.ModelDelegateMethodSave)
 }
 
+// UpdateFields set fields of model
+func (d *ModelDelegate) UpdateFields(fields bson.M) (err error) {
+	if err := d.updateFields(fields); err != nil {
+		return err
+	}
+
+	// trigger event as a save
+	eventName := GetEventName(d, interfaces.ModelDelegateMethodSave)
+	d.sendEvent(eventName)
+
+	return nil
+}
+
 // Delete model
 func (d *ModelDelegate) Delete() (err error) {
 	return d.do(interfaces.ModelDelegateMethodDelete)
@@ -169,23 +189,36 @@ func (d *ModelDelegate) do(method interfaces.ModelDelegateMethod) (err error) {
 		return err
 	}
 
-	// trigger event
+	// trigger event, deferred until committed if in a transaction
 	eventName := GetEventName(d, method)
-	go event.SendEvent(eventName, d.doc)
+	d.sendEvent(eventName)
 
 	return nil
 }
 
+// sendEvent sends the event of the model in the background, or defers it
+// until the transaction is committed if in a transaction
+func (d *ModelDelegate) sendEvent(eventName string) {
+	if d.sc != nil {
+		event.SendEventContext(d.sc, eventName, d.doc)
+		return
+	}
+	go event.SendEvent(eventName, d.doc)
+}
+
 // add model
 func (d *ModelDelegate) add() (err error) {
 	if d.doc == nil {
 		return trace.TraceError(errors.ErrMissingValue)
 	}
+	if err := ValidateModel(d.doc); err != nil {
+		return err
+	}
 	if d.doc.GetId().IsZero() {
 		d.doc.SetId(primitive.NewObjectID())
 	}
 	col := mongo.GetMongoCol(d.colName)
-	if _, err = col.Insert(d.doc); err != nil {
+	if err := d.insert(col, d.doc); err != nil {
 		return trace.TraceError(err)
 	}
 	if err := d.upsertArtifact(); err != nil {
@@ -200,6 +233,9 @@ func (d *ModelDelegate) save() (err error) {
 	if d.doc == nil || d.doc.GetId().IsZero() {
 		return trace.TraceError(errors.ErrMissingValue)
 	}
+	if err := ValidateModel(d.doc); err != nil {
+		return err
+	}
 
 	// collection
 	col := mongo.GetMongoCol(d.colName)
@@ -215,12 +251,12 @@ func (d *ModelDelegate) save() (err error) {
 	}
 
 	// original doc
-	if err := col.FindId(d.doc.GetId()).One(&d.od); err != nil {
+	if err := d.findId(col, d.doc.GetId(), &d.od); err != nil {
 		trace.PrintError(err)
 	}
 
 	// replace
-	if err := col.ReplaceId(d.doc.GetId(), d.doc); err != nil {
+	if err := d.replaceId(col, d.doc.GetId(), d.doc); err != nil {
 		return trace.TraceError(err)
 	}
 
@@ -232,18 +268,63 @@ func (d *ModelDelegate) save() (err error) {
 	return d.refresh()
 }
 
+// updateFields of model with $set
+func (d *ModelDelegate) updateFields(fields bson.M) (err error) {
+	// validate
+	if d.doc == nil || d.doc.GetId().IsZero() {
+		return trace.TraceError(errors.ErrMissingValue)
+	}
+	if len(fields) == 0 {
+		return trace.TraceError(errors.ErrMissingValue)
+	}
+	if _, ok := fields["_id"]; ok {
+		return trace.TraceError(errors2.ErrorModelInvalidType)
+	}
+
+	// collection
+	col := mongo.GetMongoCol(d.colName)
+
+	// original doc
+	if err := col.FindId(d.doc.GetId()).One(&d.od); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// update
+	if err := col.UpdateId(d.doc.GetId(), bson.M{"$set": fields}); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// upsert artifact
+	if err := d.upsertArtifact(); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// current doc
+	if err := d.refresh(); err != nil {
+		return err
+	}
+	if err := col.FindId(d.doc.GetId()).One(&d.cd); err != nil {
+		return trace.TraceError(err)
+	}
+
+	return nil
+}
+
 // delete model
 func (d *ModelDelegate) delete() (err error) {
 	if d.doc.GetId().IsZero() {
 		return trace.TraceError(errors2.ErrorModelMissingId)
 	}
 	col := mongo.GetMongoCol(d.colName)
-	if err := col.FindId(d.doc.GetId()).One(d.doc); err != nil {
+	if err := d.findId(col, d.doc.GetId(), d.doc); err != nil {
 		return trace.TraceError(err)
 	}
-	if err := col.DeleteId(d.doc.GetId()); err != nil {
+	if err := d.deleteId(col, d.doc.GetId()); err != nil {
 		return trace.TraceError(err)
 	}
+	if d.id == interfaces.ModelIdNode {
+		forgetNode(d.doc.GetId())
+	}
 	return d.deleteArtifact()
 }
 
@@ -253,8 +334,7 @@ func (d *ModelDelegate) refresh() (err error) {
 		return trace.TraceError(errors2.ErrorModelMissingId)
 	}
 	col := mongo.GetMongoCol(d.colName)
-	fr := col.FindId(d.doc.GetId())
-	if err := fr.One(d.doc); err != nil {
+	if err := d.findId(col, d.doc.GetId(), d.doc); err != nil {
 		return trace.TraceError(err)
 	}
 	return d.refreshArtifact()
@@ -266,7 +346,7 @@ func (d *ModelDelegate) refreshArtifact() (err error) {
 		return trace.TraceError(errors2.ErrorModelMissingId)
 	}
 	col := mongo.GetMongoCol(interfaces.ModelColNameArtifact)
-	if err := col.FindId(d.doc.GetId()).One(d.a); err != nil {
+	if err := d.findId(col, d.doc.GetId(), d.a); err != nil {
 		return trace.TraceError(err)
 	}
 	return nil
@@ -291,7 +371,7 @@ func (d *ModelDelegate) upsertArtifact() (err error) {
 	d.a.SetId(d.doc.GetId())
 
 	// attempt to find artifact
-	if err := col.FindId(d.doc.GetId()).One(d.a); err != nil {
+	if err := d.findId(col, d.doc.GetId(), d.a); err != nil {
 		if err == mongo2.ErrNoDocuments {
 			// new artifact
 			d.a.GetSys().SetCreateTs(time.Now())
@@ -300,8 +380,7 @@ func (d *ModelDelegate) upsertArtifact() (err error) {
 				d.a.GetSys().SetCreateUid(d.u.GetId())
 				d.a.GetSys().SetUpdateUid(d.u.GetId())
 			}
-			_, err = col.Insert(d.a)
-			if err != nil {
+			if err := d.insert(col, d.a); err != nil {
 				return trace.TraceError(err)
 			}
 			return nil
@@ -318,7 +397,7 @@ func (d *ModelDelegate) upsertArtifact() (err error) {
 	}
 
 	// save new artifact
-	return col.ReplaceId(d.a.GetId(), d.a)
+	return d.replaceId(col, d.a.GetId(), d.a)
 }
 
 // deleteArtifact
@@ -339,13 +418,52 @@ func (d *ModelDelegate) deleteArtifact() (err error) {
 	if d.u != nil {
 		d.a.GetSys().SetDeleteUid(d.u.GetId())
 	}
-	return col.ReplaceId(d.doc.GetId(), d.a)
+	return d.replaceId(col, d.doc.GetId(), d.a)
 }
 
 func (d *ModelDelegate) hasChange() (ok bool) {
 	return !utils.BsonMEqual(d.cd, d.od)
 }
 
+// insert doc into the collection, within the transaction if any
+func (d *ModelDelegate) insert(col *mongo.Col, doc interface{}) (err error) {
+	if d.sc != nil {
+		_, err = col.GetCollection().InsertOne(d.sc, doc)
+		return err
+	}
+	_, err = col.Insert(doc)
+	return err
+}
+
+// replaceId replaces the doc of the id in the collection, within the
+// transaction if any
+func (d *ModelDelegate) replaceId(col *mongo.Col, id primitive.ObjectID, doc interface{}) (err error) {
+	if d.sc != nil {
+		_, err = col.GetCollection().ReplaceOne(d.sc, bson.M{"_id": id}, doc)
+		return err
+	}
+	return col.ReplaceId(id, doc)
+}
+
+// deleteId deletes the doc of the id from the collection, within the
+// transaction if any
+func (d *ModelDelegate) deleteId(col *mongo.Col, id primitive.ObjectID) (err error) {
+	if d.sc != nil {
+		_, err = col.GetCollection().DeleteOne(d.sc, bson.M{"_id": id})
+		return err
+	}
+	return col.DeleteId(id)
+}
+
+// findId finds the doc of the id in the collection, within the transaction
+// if any, so that docs written in the transaction are visible
+func (d *ModelDelegate) findId(col *mongo.Col, id primitive.ObjectID, res interface{}) (err error) {
+	if d.sc != nil {
+		return col.GetCollection().FindOne(d.sc, bson.M{"_id": id}).Decode(res)
+	}
+	return col.FindId(id).One(res)
+}
+
 func (d *ModelDelegate) _skip() (ok bool) {
 	switch d.id {
 	case
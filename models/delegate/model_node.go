@@ -3,6 +3,7 @@ package delegate
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/bson"
 	"time"
 )
 
@@ -11,24 +12,59 @@ type ModelNodeDelegate struct {
 	interfaces.ModelDelegate
 }
 
+// UpdateStatus sets only status fields of the node, so that concurrent edits
+// of other fields, e.g. the name, are not overwritten. Writes only updating
+// active_ts are coalesced to at most one per "node.activeTs.minInterval"
+// seconds, while the last seen time is always kept in memory, see
+// GetNodeLastSeen.
 func (d *ModelNodeDelegate) UpdateStatus(active bool, activeTs *time.Time, status string) (err error) {
+	unchanged := d.n.GetActive() == active && d.n.GetStatus() == status
 	d.n.SetActive(active)
+	fields := bson.M{
+		"active":  active,
+		"status":  status,
+		"drained": d.n.GetDrained(),
+	}
 	if activeTs != nil {
 		d.n.SetActiveTs(*activeTs)
+		if !seeNode(d.n.GetId(), *activeTs) && unchanged {
+			return nil
+		}
+		fields["active_ts"] = *activeTs
 	}
 	d.n.SetStatus(status)
-	return d.Save()
+	if err := d.UpdateFields(fields); err != nil {
+		return err
+	}
+	if activeTs != nil {
+		markNodeActiveTsWritten(d.n.GetId(), *activeTs)
+	}
+	return nil
 }
 
 func (d *ModelNodeDelegate) UpdateStatusOnline() (err error) {
 	now := time.Now()
+	// draining nodes stay draining until they are explicitly set back online
+	if d.n.GetStatus() == constants.NodeStatusDraining {
+		return d.UpdateStatus(true, &now, constants.NodeStatusDraining)
+	}
 	return d.UpdateStatus(true, &now, constants.NodeStatusOnline)
 }
 
 func (d *ModelNodeDelegate) UpdateStatusOffline() (err error) {
+	// draining nodes stay draining while offline
+	if d.n.GetStatus() == constants.NodeStatusDraining {
+		return d.UpdateStatus(false, nil, constants.NodeStatusDraining)
+	}
 	return d.UpdateStatus(false, nil, constants.NodeStatusOffline)
 }
 
+func (d *ModelNodeDelegate) UpdateStatusDraining() (err error) {
+	now := time.Now()
+	d.n.SetDrained(false)
+	return d.UpdateStatus(d.n.GetActive(), &now, constants.NodeStatusDraining)
+}
+
 func NewModelNodeDelegate(n interfaces.Node) interfaces.ModelNodeDelegate {
 	return &ModelNodeDelegate{
 		n:             n,
@@ -0,0 +1,69 @@
+package delegate
+
+import (
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// nodeActive is the last seen time of a node, and the time last written as
+// its active_ts
+type nodeActive struct {
+	seenTs    time.Time
+	writtenTs time.Time
+}
+
+var nodeActives = map[primitive.ObjectID]*nodeActive{}
+var nodeActivesMu sync.Mutex
+
+// GetNodeLastSeen returns the time the node was last seen active by this
+// process, which is always current, unlike active_ts in the database whose
+// writes are coalesced by "node.activeTs.minInterval". ok is false if the node
+// has not been seen.
+func GetNodeLastSeen(id primitive.ObjectID) (ts time.Time, ok bool) {
+	nodeActivesMu.Lock()
+	defer nodeActivesMu.Unlock()
+	a, ok := nodeActives[id]
+	if !ok {
+		return ts, false
+	}
+	return a.seenTs, true
+}
+
+// seeNode records the node as seen at ts and returns whether active_ts should
+// be written, i.e. "node.activeTs.minInterval" seconds (0 by default, writing
+// every time) have passed since it was last written
+func seeNode(id primitive.ObjectID, ts time.Time) (write bool) {
+	nodeActivesMu.Lock()
+	defer nodeActivesMu.Unlock()
+	a, ok := nodeActives[id]
+	if !ok {
+		a = &nodeActive{}
+		nodeActives[id] = a
+	}
+	if ts.After(a.seenTs) {
+		a.seenTs = ts
+	}
+	minInterval := time.Duration(viper.GetInt("node.activeTs.minInterval")) * time.Second
+	return minInterval <= 0 || ts.Sub(a.writtenTs) >= minInterval
+}
+
+// markNodeActiveTsWritten records ts as the active_ts last written of the node
+func markNodeActiveTsWritten(id primitive.ObjectID, ts time.Time) {
+	nodeActivesMu.Lock()
+	defer nodeActivesMu.Unlock()
+	a, ok := nodeActives[id]
+	if !ok {
+		a = &nodeActive{seenTs: ts}
+		nodeActives[id] = a
+	}
+	a.writtenTs = ts
+}
+
+// forgetNode drops the last seen time of the node once it is deleted
+func forgetNode(id primitive.ObjectID) {
+	nodeActivesMu.Lock()
+	defer nodeActivesMu.Unlock()
+	delete(nodeActives, id)
+}
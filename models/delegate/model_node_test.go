@@ -5,8 +5,10 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	models2 "github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestNode_Add(t *testing.T) {
@@ -63,3 +65,39 @@ func TestNode_Delete(t *testing.T) {
 	require.NotNil(t, a.Obj)
 	require.True(t, a.Del)
 }
+
+func TestNode_UpdateStatusOnline_CoalesceActiveTs(t *testing.T) {
+	SetupTest(t)
+	viper.Set("node.activeTs.minInterval", 60)
+	t.Cleanup(func() { viper.Set("node.activeTs.minInterval", nil) })
+
+	n := &models2.Node{Name: "test_node"}
+	err := delegate.NewModelDelegate(n).Add()
+	require.Nil(t, err)
+
+	// first heartbeat is written
+	err = delegate.NewModelNodeDelegate(n).UpdateStatusOnline()
+	require.Nil(t, err)
+	var n2 models2.Node
+	err = mongo.GetMongoCol(interfaces.ModelColNameNode).FindId(n.Id).One(&n2)
+	require.Nil(t, err)
+	writtenTs := n2.ActiveTs
+
+	// following heartbeat within the interval is kept in memory only
+	time.Sleep(10 * time.Millisecond)
+	err = delegate.NewModelNodeDelegate(&n2).UpdateStatusOnline()
+	require.Nil(t, err)
+	var n3 models2.Node
+	err = mongo.GetMongoCol(interfaces.ModelColNameNode).FindId(n.Id).One(&n3)
+	require.Nil(t, err)
+	require.True(t, n3.ActiveTs.Equal(writtenTs))
+	seenTs, ok := delegate.GetNodeLastSeen(n.Id)
+	require.True(t, ok)
+	require.True(t, seenTs.After(writtenTs))
+
+	// last seen time is dropped with the node
+	err = delegate.NewModelDelegate(&n3).Delete()
+	require.Nil(t, err)
+	_, ok = delegate.GetNodeLastSeen(n.Id)
+	require.False(t, ok)
+}
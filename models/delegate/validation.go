@@ -0,0 +1,103 @@
+package delegate
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/robfig/cron/v3"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Models are validated by their "validate" struct tags (see
+// github.com/go-playground/validator) when added or saved. Violations are
+// returned as errors.ValidationErrors with fields named by their json paths,
+// e.g. "webhook.url", so that they can be shown along with form fields.
+
+var (
+	_validate     *validator.Validate
+	_validateOnce sync.Once
+)
+
+func getValidator() (v *validator.Validate) {
+	_validateOnce.Do(func() {
+		v := validator.New()
+
+		// field names by json tags
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+
+		// standard cron expressions
+		_ = v.RegisterValidation("cron", func(fl validator.FieldLevel) bool {
+			_, err := cron.ParseStandard(fl.Field().String())
+			return err == nil
+		})
+
+		_validate = v
+	})
+	return _validate
+}
+
+// ValidateModel validates the model by its struct tags, returning
+// errors.ValidationErrors of the invalid fields or nil if valid
+func ValidateModel(doc interface{}) (err error) {
+	if err := getValidator().Struct(doc); err != nil {
+		fieldErrs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			// not a struct
+			return nil
+		}
+		return NewValidationErrors(fieldErrs)
+	}
+	return nil
+}
+
+// NewValidationErrors converts violations of struct tags to
+// errors.ValidationErrors
+func NewValidationErrors(fieldErrs validator.ValidationErrors) (errs errors.ValidationErrors) {
+	for _, fe := range fieldErrs {
+		errs = append(errs, errors.ValidationError{
+			Field:   getValidationFieldPath(fe),
+			Message: getValidationMessage(fe),
+		})
+	}
+	return errs
+}
+
+// getValidationFieldPath returns the path of the field without the name of
+// the model, e.g. "webhook.url" of "Spider.webhook.url"
+func getValidationFieldPath(fe validator.FieldError) (path string) {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func getValidationMessage(fe validator.FieldError) (msg string) {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "cron":
+		return fmt.Sprintf("invalid cron expression %v", fe.Value())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "url":
+		return "must be a valid url"
+	default:
+		return fmt.Sprintf("failed on %s", fe.Tag())
+	}
+}
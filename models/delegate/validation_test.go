@@ -0,0 +1,37 @@
+package delegate_test
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestValidateModel(t *testing.T) {
+	// valid
+	s := &models2.Schedule{SpiderId: primitive.NewObjectID(), Cron: "0 * * * *"}
+	require.Nil(t, delegate.ValidateModel(s))
+
+	// invalid
+	s = &models2.Schedule{Cron: "invalid", Mode: "unknown"}
+	err := delegate.ValidateModel(s)
+	errs, ok := err.(errors.ValidationErrors)
+	require.True(t, ok)
+	fields := map[string]string{}
+	for _, e := range errs {
+		fields[e.Field] = e.Message
+	}
+	require.Equal(t, "is required", fields["spider_id"])
+	require.Contains(t, fields["cron"], "invalid cron expression")
+	require.Contains(t, fields["mode"], "must be one of")
+
+	// spider
+	sp := &models2.Spider{Name: "test", Timeout: -1}
+	err = delegate.ValidateModel(sp)
+	errs, ok = err.(errors.ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, errs, 1)
+	require.Equal(t, "timeout", errs[0].Field)
+}
@@ -31,6 +31,14 @@ func (ds *DataSource) SetId(id primitive.ObjectID) {
 	ds.Id = id
 }
 
+func (ds *DataSource) GetName() (name string) {
+	return ds.Name
+}
+
+func (ds *DataSource) GetType() (ty string) {
+	return ds.Type
+}
+
 type DataSourceList []DataSource
 
 func (l *DataSourceList) GetModels() (res []interfaces.Model) {
@@ -7,21 +7,27 @@ import (
 )
 
 type Node struct {
-	Id               primitive.ObjectID `json:"_id" bson:"_id"`
-	Key              string             `json:"key" bson:"key"`
-	Name             string             `json:"name" bson:"name"`
-	Ip               string             `json:"ip" bson:"ip"`
-	Port             string             `json:"port" bson:"port"`
-	Mac              string             `json:"mac" bson:"mac"`
-	Hostname         string             `json:"hostname" bson:"hostname"`
-	Description      string             `json:"description" bson:"description"`
-	IsMaster         bool               `json:"is_master" bson:"is_master"`
-	Status           string             `json:"status" bson:"status"`
-	Enabled          bool               `json:"enabled" bson:"enabled"`
-	Active           bool               `json:"active" bson:"active"`
-	ActiveTs         time.Time          `json:"active_ts" bson:"active_ts"`
-	AvailableRunners int                `json:"available_runners" bson:"available_runners"`
-	MaxRunners       int                `json:"max_runners" bson:"max_runners"`
+	Id                 primitive.ObjectID           `json:"_id" bson:"_id"`
+	Key                string                       `json:"key" bson:"key"`
+	Name               string                       `json:"name" bson:"name"`
+	Ip                 string                       `json:"ip" bson:"ip"`
+	Port               string                       `json:"port" bson:"port"`
+	Mac                string                       `json:"mac" bson:"mac"`
+	Hostname           string                       `json:"hostname" bson:"hostname"`
+	Description        string                       `json:"description" bson:"description"`
+	IsMaster           bool                         `json:"is_master" bson:"is_master"`
+	Status             string                       `json:"status" bson:"status"`
+	Enabled            bool                         `json:"enabled" bson:"enabled"`
+	Active             bool                         `json:"active" bson:"active"`
+	ActiveTs           time.Time                    `json:"active_ts" bson:"active_ts"`
+	AvailableRunners   int                          `json:"available_runners" bson:"available_runners"`
+	MaxRunners         int                          `json:"max_runners" bson:"max_runners"`
+	Drained            bool                         `json:"drained" bson:"drained"`                         // whether a draining node has no running tasks
+	Schedulable        bool                         `json:"schedulable" bson:"schedulable"`                 // whether new tasks can be dispatched to the node, false if cordoned
+	WorkspaceSize      int64                        `json:"workspace_size" bson:"workspace_size"`           // disk usage of spider workspaces on the node (bytes)
+	WorkspaceReclaimed int64                        `json:"workspace_reclaimed" bson:"workspace_reclaimed"` // disk space reclaimed by cleanup of task scratch directories since the node started (bytes)
+	Capabilities       *interfaces.NodeCapabilities `json:"capabilities" bson:"capabilities"`               // capability manifest reported on registration
+	Version            string                       `json:"version" bson:"version"`                         // crawlab-core version reported on registration
 }
 
 func (n *Node) GetId() (id primitive.ObjectID) {
@@ -64,6 +70,10 @@ func (n *Node) SetActive(active bool) {
 	n.Active = active
 }
 
+func (n *Node) GetActiveTs() (activeTs time.Time) {
+	return n.ActiveTs
+}
+
 func (n *Node) SetActiveTs(activeTs time.Time) {
 	n.ActiveTs = activeTs
 }
@@ -100,6 +110,54 @@ func (n *Node) SetMaxRunners(runners int) {
 	n.MaxRunners = runners
 }
 
+func (n *Node) GetDrained() (drained bool) {
+	return n.Drained
+}
+
+func (n *Node) SetDrained(drained bool) {
+	n.Drained = drained
+}
+
+func (n *Node) GetSchedulable() (ok bool) {
+	return n.Schedulable
+}
+
+func (n *Node) SetSchedulable(ok bool) {
+	n.Schedulable = ok
+}
+
+func (n *Node) GetWorkspaceSize() (size int64) {
+	return n.WorkspaceSize
+}
+
+func (n *Node) SetWorkspaceSize(size int64) {
+	n.WorkspaceSize = size
+}
+
+func (n *Node) GetWorkspaceReclaimed() (size int64) {
+	return n.WorkspaceReclaimed
+}
+
+func (n *Node) SetWorkspaceReclaimed(size int64) {
+	n.WorkspaceReclaimed = size
+}
+
+func (n *Node) GetCapabilities() (capabilities *interfaces.NodeCapabilities) {
+	return n.Capabilities
+}
+
+func (n *Node) SetCapabilities(capabilities *interfaces.NodeCapabilities) {
+	n.Capabilities = capabilities
+}
+
+func (n *Node) GetVersion() (version string) {
+	return n.Version
+}
+
+func (n *Node) SetVersion(version string) {
+	n.Version = version
+}
+
 func (n *Node) IncrementAvailableRunners() {
 	n.AvailableRunners++
 }
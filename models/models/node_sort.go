@@ -0,0 +1,70 @@
+package models
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"sort"
+	"strings"
+)
+
+// nodeStatusRanks are ranks of node statuses in the node list, lower first
+var nodeStatusRanks = map[string]int{
+	constants.NodeStatusOnline:       0,
+	constants.NodeStatusDraining:     1,
+	constants.NodeStatusRegistered:   2,
+	constants.NodeStatusUnregistered: 3,
+	constants.NodeStatusOffline:      4,
+}
+
+func getNodeStatusRank(status string) (rank int) {
+	rank, ok := nodeStatusRanks[status]
+	if !ok {
+		return len(nodeStatusRanks)
+	}
+	return rank
+}
+
+// SortNodes sorts the nodes in place by the sort key (constants.NodeSortBy*),
+// descending if desc is true. Nodes of equal keys are ordered by name
+// (ascending), so that the order is deterministic.
+func SortNodes(nodes []Node, sortBy string, desc bool) (err error) {
+	var less func(a, b *Node) (ok bool)
+	switch sortBy {
+	case constants.NodeSortByDefault:
+		less = func(a, b *Node) bool {
+			if a.IsMaster != b.IsMaster {
+				return a.IsMaster
+			}
+			return getNodeStatusRank(a.Status) < getNodeStatusRank(b.Status)
+		}
+	case constants.NodeSortByName:
+		less = func(a, b *Node) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case constants.NodeSortByStatus:
+		less = func(a, b *Node) bool { return getNodeStatusRank(a.Status) < getNodeStatusRank(b.Status) }
+	case constants.NodeSortByActiveTs:
+		less = func(a, b *Node) bool { return a.ActiveTs.Before(b.ActiveTs) }
+	case constants.NodeSortByCreateTs:
+		less = func(a, b *Node) bool { return a.Id.Timestamp().Before(b.Id.Timestamp()) }
+	default:
+		return errors.ErrorNodeInvalidSort
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := &nodes[i], &nodes[j]
+		x, y := a, b
+		if desc {
+			x, y = b, a
+		}
+		if less(x, y) {
+			return true
+		}
+		if less(y, x) {
+			return false
+		}
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c < 0
+		}
+		return a.Id.Hex() < b.Id.Hex()
+	})
+	return nil
+}
@@ -0,0 +1,50 @@
+package models_test
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSortNodes(t *testing.T) {
+	now := time.Now()
+	newNodes := func() []models2.Node {
+		return []models2.Node{
+			{Name: "worker-c", Status: constants.NodeStatusOffline, ActiveTs: now.Add(-3 * time.Minute)},
+			{Name: "worker-b", Status: constants.NodeStatusOnline, ActiveTs: now.Add(-1 * time.Minute)},
+			{Name: "master", Status: constants.NodeStatusOnline, IsMaster: true, ActiveTs: now},
+			{Name: "worker-a", Status: constants.NodeStatusOnline, ActiveTs: now.Add(-2 * time.Minute)},
+		}
+	}
+	names := func(nodes []models2.Node) (res []string) {
+		for _, n := range nodes {
+			res = append(res, n.Name)
+		}
+		return res
+	}
+
+	// default
+	nodes := newNodes()
+	require.Nil(t, models2.SortNodes(nodes, constants.NodeSortByDefault, false))
+	require.Equal(t, []string{"master", "worker-a", "worker-b", "worker-c"}, names(nodes))
+
+	// name descending
+	nodes = newNodes()
+	require.Nil(t, models2.SortNodes(nodes, constants.NodeSortByName, true))
+	require.Equal(t, []string{"worker-c", "worker-b", "worker-a", "master"}, names(nodes))
+
+	// status, ties by name
+	nodes = newNodes()
+	require.Nil(t, models2.SortNodes(nodes, constants.NodeSortByStatus, false))
+	require.Equal(t, []string{"master", "worker-a", "worker-b", "worker-c"}, names(nodes))
+
+	// last active descending
+	nodes = newNodes()
+	require.Nil(t, models2.SortNodes(nodes, constants.NodeSortByActiveTs, true))
+	require.Equal(t, []string{"master", "worker-b", "worker-a", "worker-c"}, names(nodes))
+
+	// invalid
+	require.NotNil(t, models2.SortNodes(newNodes(), "invalid", false))
+}
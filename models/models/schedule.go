@@ -4,22 +4,35 @@ import (
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/robfig/cron/v3"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type Schedule struct {
 	Id          primitive.ObjectID   `json:"_id" bson:"_id"`
 	Name        string               `json:"name" bson:"name"`
 	Description string               `json:"description" bson:"description"`
-	SpiderId    primitive.ObjectID   `json:"spider_id" bson:"spider_id"`
-	Cron        string               `json:"cron" bson:"cron"`
+	SpiderId    primitive.ObjectID   `json:"spider_id" bson:"spider_id" validate:"required"`
+	Cron        string               `json:"cron" bson:"cron" validate:"required,cron"`
+	Timezone    string               `json:"timezone" bson:"timezone"` // IANA time zone of Cron, e.g. Asia/Shanghai, server default if empty
 	EntryId     cron.EntryID         `json:"entry_id" bson:"entry_id"`
 	Cmd         string               `json:"cmd" bson:"cmd"`
 	Param       string               `json:"param" bson:"param"`
-	Mode        string               `json:"mode" bson:"mode"`
+	Mode        string               `json:"mode" bson:"mode" validate:"omitempty,oneof=all-nodes random selected-nodes"`
 	NodeIds     []primitive.ObjectID `json:"node_ids" bson:"node_ids"`
 	Priority    int                  `json:"priority" bson:"priority"`
+	Profile     string               `json:"profile" bson:"profile"` // profile of the spider, the default profile if empty
 	Enabled     bool                 `json:"enabled" bson:"enabled"`
 	UserId      primitive.ObjectID   `json:"user_id" bson:"user_id"`
+
+	// misfire
+	MisfirePolicy   string    `json:"misfire_policy" bson:"misfire_policy" validate:"omitempty,oneof=skip fire_once fire_all"` // constants.ScheduleMisfirePolicy*, skip if empty
+	LastRunTs       time.Time `json:"last_run_ts" bson:"last_run_ts"`                                                          // time of the last run fired by the schedule
+	LastMisfireTs   time.Time `json:"last_misfire_ts" bson:"last_misfire_ts"`                                                  // time of the last catch-up of missed runs
+	LastMisfireRuns int       `json:"last_misfire_runs" bson:"last_misfire_runs"`                                              // number of catch-up runs fired at the last catch-up
+
+	// one-off override of the next run
+	NextRunOverrideTs time.Time `json:"next_run_override_ts" bson:"next_run_override_ts"` // one-off run replacing the next cron run, zero if not overridden
+	SkippedRunTs      time.Time `json:"skipped_run_ts" bson:"skipped_run_ts"`             // cron run replaced by the one-off run
 }
 
 func (s *Schedule) GetId() (id primitive.ObjectID) {
@@ -54,6 +67,14 @@ func (s *Schedule) SetCron(c string) {
 	s.Cron = c
 }
 
+func (s *Schedule) GetTimezone() (tz string) {
+	return s.Timezone
+}
+
+func (s *Schedule) SetTimezone(tz string) {
+	s.Timezone = tz
+}
+
 func (s *Schedule) GetSpiderId() (id primitive.ObjectID) {
 	return s.SpiderId
 }
@@ -102,6 +123,38 @@ func (s *Schedule) SetPriority(p int) {
 	s.Priority = p
 }
 
+func (s *Schedule) GetMisfirePolicy() (policy string) {
+	return s.MisfirePolicy
+}
+
+func (s *Schedule) SetMisfirePolicy(policy string) {
+	s.MisfirePolicy = policy
+}
+
+func (s *Schedule) GetLastRunTs() (ts time.Time) {
+	return s.LastRunTs
+}
+
+func (s *Schedule) SetLastRunTs(ts time.Time) {
+	s.LastRunTs = ts
+}
+
+func (s *Schedule) GetNextRunOverrideTs() (ts time.Time) {
+	return s.NextRunOverrideTs
+}
+
+func (s *Schedule) SetNextRunOverrideTs(ts time.Time) {
+	s.NextRunOverrideTs = ts
+}
+
+func (s *Schedule) GetSkippedRunTs() (ts time.Time) {
+	return s.SkippedRunTs
+}
+
+func (s *Schedule) SetSkippedRunTs(ts time.Time) {
+	s.SkippedRunTs = ts
+}
+
 type ScheduleList []Schedule
 
 func (l *ScheduleList) GetModels() (res []interfaces.Model) {
@@ -1,15 +1,18 @@
 package models
 
 import (
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
 )
 
 type Setting struct {
-	Id    primitive.ObjectID `json:"_id" bson:"_id"`
-	Key   string             `json:"key" bson:"key"`
-	Value bson.M             `json:"value" bson:"value"`
+	Id        primitive.ObjectID       `json:"_id" bson:"_id"`
+	Key       string                   `json:"key" bson:"key"`
+	Value     bson.M                   `json:"value" bson:"value"`
+	Overrides []entity.SettingOverride `json:"overrides" bson:"overrides"` // overrides of the value by node tags
 }
 
 func (s *Setting) GetId() (id primitive.ObjectID) {
@@ -20,6 +23,53 @@ func (s *Setting) SetId(id primitive.ObjectID) {
 	s.Id = id
 }
 
+// ResolveValue returns the value of the setting on a node with the tags. The
+// fields of overrides applicable to the node, i.e. the node has all of their
+// tags, are merged onto the value from the least to the most specific, so
+// that the override with the most tags takes precedence. Of overrides with
+// the same number of tags, the latter takes precedence. Overrides without
+// tags are ignored.
+func (s *Setting) ResolveValue(tags []string) (value bson.M) {
+	value = bson.M{}
+	for k, v := range s.Value {
+		value[k] = v
+	}
+
+	// applicable overrides
+	hasTag := map[string]bool{}
+	for _, tag := range tags {
+		hasTag[tag] = true
+	}
+	var overrides []entity.SettingOverride
+	for _, o := range s.Overrides {
+		if len(o.Tags) == 0 {
+			continue
+		}
+		applicable := true
+		for _, tag := range o.Tags {
+			if !hasTag[tag] {
+				applicable = false
+				break
+			}
+		}
+		if applicable {
+			overrides = append(overrides, o)
+		}
+	}
+
+	// merge from the least to the most specific
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return len(overrides[i].Tags) < len(overrides[j].Tags)
+	})
+	for _, o := range overrides {
+		for k, v := range o.Value {
+			value[k] = v
+		}
+	}
+
+	return value
+}
+
 type SettingList []Setting
 
 func (l *SettingList) GetModels() (res []interfaces.Model) {
@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestSetting_ResolveValue(t *testing.T) {
+	s := &models2.Setting{
+		Value: bson.M{"sync.concurrency": 4, "task.install.timeout": 600},
+		Overrides: []entity.SettingOverride{
+			{Tags: []string{"big", "gpu"}, Value: bson.M{"sync.concurrency": 32}},
+			{Tags: []string{"big"}, Value: bson.M{"sync.concurrency": 16, "task.install.timeout": 1200}},
+			{Tags: []string{"slow"}, Value: bson.M{"task.install.timeout": 3600}},
+			{Value: bson.M{"sync.concurrency": 1}},
+		},
+	}
+
+	// no tags
+	require.Equal(t, bson.M{"sync.concurrency": 4, "task.install.timeout": 600}, s.ResolveValue(nil))
+
+	// single override
+	require.Equal(t, bson.M{"sync.concurrency": 16, "task.install.timeout": 1200}, s.ResolveValue([]string{"big"}))
+
+	// most specific override takes precedence regardless of order
+	require.Equal(t, bson.M{"sync.concurrency": 32, "task.install.timeout": 1200}, s.ResolveValue([]string{"gpu", "big"}))
+
+	// override of the same specificity declared later takes precedence
+	require.Equal(t, bson.M{"sync.concurrency": 16, "task.install.timeout": 3600}, s.ResolveValue([]string{"big", "slow"}))
+
+	// not all tags of the override
+	require.Equal(t, bson.M{"sync.concurrency": 4, "task.install.timeout": 600}, s.ResolveValue([]string{"gpu"}))
+
+	// base value is not modified
+	require.Equal(t, 4, s.Value["sync.concurrency"])
+}
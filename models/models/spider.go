@@ -1,8 +1,11 @@
 package models
 
 import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type Env struct {
@@ -11,27 +14,66 @@ type Env struct {
 }
 
 type Spider struct {
-	Id           primitive.ObjectID   `json:"_id" bson:"_id"`                       // spider id
-	Name         string               `json:"name" bson:"name"`                     // spider name
-	Type         string               `json:"type" bson:"type"`                     // spider type
-	ColId        primitive.ObjectID   `json:"col_id" bson:"col_id"`                 // data collection id
-	ColName      string               `json:"col_name,omitempty" bson:"-"`          // data collection name
-	DataSourceId primitive.ObjectID   `json:"data_source_id" bson:"data_source_id"` // data source id
-	DataSource   *DataSource          `json:"data_source,omitempty" bson:"-"`       // data source
-	Description  string               `json:"description" bson:"description"`       // description
-	ProjectId    primitive.ObjectID   `json:"project_id" bson:"project_id"`         // Project.Id
-	Mode         string               `json:"mode" bson:"mode"`                     // default Task.Mode
-	NodeIds      []primitive.ObjectID `json:"node_ids" bson:"node_ids"`             // default Task.NodeIds
+	Id           primitive.ObjectID   `json:"_id" bson:"_id"`                                                              // spider id
+	Name         string               `json:"name" bson:"name" validate:"required"`                                        // spider name
+	Type         string               `json:"type" bson:"type"`                                                            // spider type
+	ColId        primitive.ObjectID   `json:"col_id" bson:"col_id"`                                                        // data collection id
+	ColName      string               `json:"col_name,omitempty" bson:"-"`                                                 // data collection name
+	DataSourceId primitive.ObjectID   `json:"data_source_id" bson:"data_source_id"`                                        // data source id
+	DataSource   *DataSource          `json:"data_source,omitempty" bson:"-"`                                              // data source
+	Description  string               `json:"description" bson:"description"`                                              // description
+	ProjectId    primitive.ObjectID   `json:"project_id" bson:"project_id"`                                                // Project.Id
+	Mode         string               `json:"mode" bson:"mode" validate:"omitempty,oneof=all-nodes random selected-nodes"` // default Task.Mode
+	NodeIds      []primitive.ObjectID `json:"node_ids" bson:"node_ids"`                                                    // default Task.NodeIds
 	Stat         *SpiderStat          `json:"stat,omitempty" bson:"-"`
 
 	// execution
-	Cmd         string `json:"cmd" bson:"cmd"`     // execute command
-	Param       string `json:"param" bson:"param"` // default task param
-	Priority    int    `json:"priority" bson:"priority"`
-	AutoInstall bool   `json:"auto_install" bson:"auto_install"`
+	Cmd            string `json:"cmd" bson:"cmd"`     // execute command
+	Param          string `json:"param" bson:"param"` // default task param
+	Priority       int    `json:"priority" bson:"priority"`
+	AutoInstall    bool   `json:"auto_install" bson:"auto_install"`       // run "pip install -r <requirements file>" if install_cmd is empty
+	OutputEncoding string `json:"output_encoding" bson:"output_encoding"` // encoding of stdout and stderr of the process, e.g. gbk, utf-8 if empty
+
+	// runtime
+	Runtime   string                  `json:"runtime" bson:"runtime"`                         // runtime of tasks (constants.SpiderRuntime*), constants.SpiderRuntimeProcess if empty
+	Container *entity.SpiderContainer `json:"container,omitempty" bson:"container,omitempty"` // container of tasks of the docker runtime
+
+	// dependencies
+	InstallCmd       string `json:"install_cmd" bson:"install_cmd"`             // command installing dependencies before running tasks, e.g. pip install -r requirements.txt
+	RequirementsFile string `json:"requirements_file" bson:"requirements_file"` // file whose hash decides whether to re-run install, requirements.txt by default
+
+	// task defaults
+	Timeout          int      `json:"timeout" bson:"timeout" validate:"min=0"`         // default Task.Timeout
+	MaxRetries       int      `json:"max_retries" bson:"max_retries" validate:"min=0"` // default Task.MaxRetries
+	NodeTags         []string `json:"node_tags" bson:"node_tags"`                      // default Task.NodeTags
+	Capabilities     []string `json:"capabilities" bson:"capabilities"`                // default Task.Capabilities
+	MaxItems         int      `json:"max_items" bson:"max_items" validate:"min=0"`     // default Task.MaxItems
+	CancelOnMaxItems bool     `json:"cancel_on_max_items" bson:"cancel_on_max_items"`  // default Task.CancelOnMaxItems
+	Concurrency      int      `json:"concurrency" bson:"concurrency" validate:"min=0"` // default Task.Concurrency
+	LockKey          string   `json:"lock_key" bson:"lock_key"`                        // default Task.LockKey
+
+	// profiles
+	Profiles       []entity.SpiderProfile `json:"profiles" bson:"profiles"`               // environments with overrides of the cmd and variables
+	DefaultProfile string                 `json:"default_profile" bson:"default_profile"` // profile of tasks not selecting one, none if empty
+
+	// results
+	FieldMappings    []entity.ResultFieldMapping `json:"field_mappings" bson:"field_mappings"`         // transforms of result fields before they are stored
+	ResultNumberMode string                      `json:"result_number_mode" bson:"result_number_mode"` // how numbers of results are stored, constants.ResultNumberModeFloat if empty
+
+	// webhook
+	Webhook *entity.SpiderWebhook `json:"webhook,omitempty" bson:"webhook,omitempty"` // called when a task of the spider ends
 
 	// settings
-	IncrementalSync bool `json:"incremental_sync" bson:"incremental_sync"` // whether to incrementally sync files
+	Enabled           bool   `json:"enabled" bson:"enabled"`                                          // whether tasks of the spider are scheduled
+	IncrementalSync   bool   `json:"incremental_sync" bson:"incremental_sync"`                        // whether to incrementally sync files
+	WorkspaceQuota    int64  `json:"workspace_quota" bson:"workspace_quota" validate:"min=0"`         // max disk usage of workspace (bytes), 0 for unlimited
+	CancelGracePeriod int    `json:"cancel_grace_period" bson:"cancel_grace_period" validate:"min=0"` // grace period (seconds) of soft cancel before the process is killed, 0 for default
+	LogTtl            string `json:"log_ttl" bson:"log_ttl"`                                          // retention of task logs, e.g. 90d, global "log.ttl" if empty
+
+	// status
+	LastSuccessTs   time.Time          `json:"last_success_ts" bson:"last_success_ts"`     // finish time of the last successful task, zero if never succeeded
+	EmergencyStopTs time.Time          `json:"emergency_stop_ts" bson:"emergency_stop_ts"` // time of the last emergency stop, zero if never stopped
+	EmergencyStopBy primitive.ObjectID `json:"emergency_stop_by" bson:"emergency_stop_by"` // User.Id of the user triggering the last emergency stop
 }
 
 func (s *Spider) GetId() (id primitive.ObjectID) {
@@ -102,6 +144,62 @@ func (s *Spider) SetPriority(p int) {
 	s.Priority = p
 }
 
+func (s *Spider) GetTimeout() (timeout int) {
+	return s.Timeout
+}
+
+func (s *Spider) SetTimeout(timeout int) {
+	s.Timeout = timeout
+}
+
+func (s *Spider) GetMaxRetries() (maxRetries int) {
+	return s.MaxRetries
+}
+
+func (s *Spider) SetMaxRetries(maxRetries int) {
+	s.MaxRetries = maxRetries
+}
+
+func (s *Spider) GetNodeTags() (tags []string) {
+	return s.NodeTags
+}
+
+func (s *Spider) SetNodeTags(tags []string) {
+	s.NodeTags = tags
+}
+
+func (s *Spider) GetCapabilities() (capabilities []string) {
+	return s.Capabilities
+}
+
+func (s *Spider) SetCapabilities(capabilities []string) {
+	s.Capabilities = capabilities
+}
+
+func (s *Spider) GetInstallCmd() (cmd string) {
+	return s.InstallCmd
+}
+
+func (s *Spider) SetInstallCmd(cmd string) {
+	s.InstallCmd = cmd
+}
+
+func (s *Spider) GetRequirementsFile() (file string) {
+	return s.RequirementsFile
+}
+
+func (s *Spider) SetRequirementsFile(file string) {
+	s.RequirementsFile = file
+}
+
+func (s *Spider) GetEnabled() (enabled bool) {
+	return s.Enabled
+}
+
+func (s *Spider) SetEnabled(enabled bool) {
+	s.Enabled = enabled
+}
+
 func (s *Spider) GetColId() (id primitive.ObjectID) {
 	return s.ColId
 }
@@ -110,6 +208,22 @@ func (s *Spider) SetColId(id primitive.ObjectID) {
 	s.ColId = id
 }
 
+func (s *Spider) GetCancelGracePeriod() (seconds int) {
+	return s.CancelGracePeriod
+}
+
+func (s *Spider) SetCancelGracePeriod(seconds int) {
+	s.CancelGracePeriod = seconds
+}
+
+func (s *Spider) GetOutputEncoding() (name string) {
+	return s.OutputEncoding
+}
+
+func (s *Spider) SetOutputEncoding(name string) {
+	s.OutputEncoding = name
+}
+
 func (s *Spider) GetIncrementalSync() (incrementalSync bool) {
 	return s.IncrementalSync
 }
@@ -126,6 +240,35 @@ func (s *Spider) SetAutoInstall(autoInstall bool) {
 	s.AutoInstall = autoInstall
 }
 
+func (s *Spider) GetWorkspaceQuota() (quota int64) {
+	return s.WorkspaceQuota
+}
+
+func (s *Spider) SetWorkspaceQuota(quota int64) {
+	s.WorkspaceQuota = quota
+}
+
+func (s *Spider) GetFieldMappings() (mappings []entity.ResultFieldMapping) {
+	return s.FieldMappings
+}
+
+func (s *Spider) SetFieldMappings(mappings []entity.ResultFieldMapping) {
+	s.FieldMappings = mappings
+}
+
+func (s *Spider) GetLastSuccessTs() (ts time.Time) {
+	return s.LastSuccessTs
+}
+
+func (s *Spider) SetLastSuccessTs(ts time.Time) {
+	s.LastSuccessTs = ts
+}
+
+// IsContainerRuntime returns whether tasks of the spider run in containers
+func (s *Spider) IsContainerRuntime() (ok bool) {
+	return s.Runtime == constants.SpiderRuntimeDocker
+}
+
 type SpiderList []Spider
 
 func (l *SpiderList) GetModels() (res []interfaces.Model) {
@@ -135,3 +278,23 @@ func (l *SpiderList) GetModels() (res []interfaces.Model) {
 	}
 	return res
 }
+
+// GetProfile returns the profile of the name, nil if not found
+func (s *Spider) GetProfile(name string) (p *entity.SpiderProfile) {
+	if name == "" {
+		return nil
+	}
+	for i := range s.Profiles {
+		if s.Profiles[i].Name == name {
+			return &s.Profiles[i]
+		}
+	}
+	return nil
+}
+
+func (s *Spider) GetProfileVariables(name string) (variables map[string]string) {
+	if p := s.GetProfile(name); p != nil {
+		return p.Variables
+	}
+	return nil
+}
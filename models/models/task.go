@@ -7,26 +7,51 @@ import (
 )
 
 type Task struct {
-	Id         primitive.ObjectID   `json:"_id" bson:"_id"`
-	SpiderId   primitive.ObjectID   `json:"spider_id" bson:"spider_id"`
-	Status     string               `json:"status" bson:"status"`
-	NodeId     primitive.ObjectID   `json:"node_id" bson:"node_id"`
-	Cmd        string               `json:"cmd" bson:"cmd"`
-	Param      string               `json:"param" bson:"param"`
-	Error      string               `json:"error" bson:"error"`
-	Pid        int                  `json:"pid" bson:"pid"`
-	ScheduleId primitive.ObjectID   `json:"schedule_id" bson:"schedule_id"` // Schedule.Id
-	Type       string               `json:"type" bson:"type"`
-	Mode       string               `json:"mode" bson:"mode"`           // running mode of Task
-	NodeIds    []primitive.ObjectID `json:"node_ids" bson:"node_ids"`   // list of Node.Id
-	ParentId   primitive.ObjectID   `json:"parent_id" bson:"parent_id"` // parent Task.Id if it'Spider a sub-task
-	Priority   int                  `json:"priority" bson:"priority"`
-	Stat       *TaskStat            `json:"stat,omitempty" bson:"-"`
-	HasSub     bool                 `json:"has_sub" json:"has_sub"` // whether to have sub-tasks
-	SubTasks   []Task               `json:"sub_tasks,omitempty" bson:"-"`
-	Spider     *Spider              `json:"spider,omitempty" bson:"-"`
-	UserId     primitive.ObjectID   `json:"-" bson:"-"`
-	CreateTs   time.Time            `json:"create_ts" bson:"create_ts"`
+	Id               primitive.ObjectID            `json:"_id" bson:"_id"`
+	SpiderId         primitive.ObjectID            `json:"spider_id" bson:"spider_id"`
+	Status           string                        `json:"status" bson:"status"`
+	NodeId           primitive.ObjectID            `json:"node_id" bson:"node_id"`
+	Cmd              string                        `json:"cmd" bson:"cmd"`
+	Param            string                        `json:"param" bson:"param"`
+	Error            string                        `json:"error" bson:"error"`
+	Pid              int                           `json:"pid" bson:"pid"`
+	CancelMode       string                        `json:"cancel_mode" bson:"cancel_mode"` // requested cancel mode, updated to the mode ultimately taken
+	ScheduleId       primitive.ObjectID            `json:"schedule_id" bson:"schedule_id"` // Schedule.Id
+	Type             string                        `json:"type" bson:"type"`
+	Mode             string                        `json:"mode" bson:"mode" validate:"omitempty,oneof=all-nodes random selected-nodes"` // running mode of Task
+	NodeIds          []primitive.ObjectID          `json:"node_ids" bson:"node_ids"`                                                    // list of Node.Id
+	ParentId         primitive.ObjectID            `json:"parent_id" bson:"parent_id"`                                                  // parent Task.Id if it'Spider a sub-task
+	Priority         int                           `json:"priority" bson:"priority"`
+	Timeout          int                           `json:"timeout" bson:"timeout" validate:"min=0"`         // timeout (seconds) of the process, 0 for no timeout
+	MaxRetries       int                           `json:"max_retries" bson:"max_retries" validate:"min=0"` // max number of automatic retries if the task fails
+	MaxItems         int                           `json:"max_items" bson:"max_items" validate:"min=0"`     // max number of result items stored, 0 for unlimited
+	CancelOnMaxItems bool                          `json:"cancel_on_max_items" bson:"cancel_on_max_items"`  // whether to cancel the task when MaxItems is reached
+	ItemsCapped      bool                          `json:"items_capped" bson:"items_capped"`                // whether result items have been dropped as MaxItems is reached
+	LogsTruncated    bool                          `json:"logs_truncated" bson:"logs_truncated"`            // whether logs have been discarded as the max log size is reached
+	Retries          int                           `json:"retries" bson:"retries"`                          // number of retries before this attempt
+	RetryId          primitive.ObjectID            `json:"retry_id" bson:"retry_id"`                        // Task.Id of the retry if the task has been retried
+	DeadLetter       bool                          `json:"dead_letter" bson:"dead_letter"`                  // whether the task failed after exhausting its retries and needs attention
+	DeadLetterTs     time.Time                     `json:"dead_letter_ts" bson:"dead_letter_ts"`            // time when the task was dead-lettered
+	NodeTags         []string                      `json:"node_tags" bson:"node_tags"`                      // names of tags a node must have to run the task
+	Capabilities     []string                      `json:"capabilities" bson:"capabilities"`                // names of executables a node must have to run the task
+	Checkpoint       string                        `json:"checkpoint" bson:"checkpoint"`                    // last checkpoint token reported by the process, inherited when retried or resumed
+	Proxy            string                        `json:"proxy" bson:"proxy"`                              // proxy passed to the process, with the password redacted
+	GitRef           string                        `json:"git_ref" bson:"git_ref"`                          // git branch, tag or commit to run, currently synced files if empty
+	GitCommit        string                        `json:"git_commit" bson:"git_commit"`                    // commit hash resolved from GitRef
+	InputId          primitive.ObjectID            `json:"input_id" bson:"input_id"`                        // input file of the spider passed to the process, none if zero
+	Profile          string                        `json:"profile" bson:"profile"`                          // profile of the spider whose variables are passed to the process, none if empty
+	Concurrency      int                           `json:"concurrency" bson:"concurrency" validate:"min=0"` // concurrency passed to the process by env and the cmd placeholder, unset if 0
+	LockKey          string                        `json:"lock_key" bson:"lock_key"`                        // name of the lock held while the task runs, so that at most one task of the lock runs at the same time, none if empty
+	Test             bool                          `json:"test" bson:"test"`                                // whether it is a test run whose results are captured in TestResults instead of stored, excluded from history and stats
+	TestResults      []map[string]interface{}      `json:"test_results,omitempty" bson:"test_results"`      // result items captured by the test run, capped by "task.test.maxResults"
+	TraceContext     map[string]string             `json:"-" bson:"trace_context,omitempty"`                // propagated trace context of the task if traced
+	Stat             *TaskStat                     `json:"stat,omitempty" bson:"-"`
+	Queue            *interfaces.TaskQueueEstimate `json:"queue,omitempty" bson:"-"` // queue position and estimated wait if pending
+	HasSub           bool                          `json:"has_sub" json:"has_sub"`   // whether to have sub-tasks
+	SubTasks         []Task                        `json:"sub_tasks,omitempty" bson:"-"`
+	Spider           *Spider                       `json:"spider,omitempty" bson:"-" validate:"-"`
+	UserId           primitive.ObjectID            `json:"-" bson:"-"`
+	CreateTs         time.Time                     `json:"create_ts" bson:"create_ts"`
 }
 
 func (t *Task) GetId() (id primitive.ObjectID) {
@@ -73,6 +98,14 @@ func (t *Task) SetPid(pid int) {
 	t.Pid = pid
 }
 
+func (t *Task) GetCancelMode() (mode string) {
+	return t.CancelMode
+}
+
+func (t *Task) SetCancelMode(mode string) {
+	t.CancelMode = mode
+}
+
 func (t *Task) GetSpiderId() (id primitive.ObjectID) {
 	return t.SpiderId
 }
@@ -93,6 +126,26 @@ func (t *Task) GetPriority() (p int) {
 	return t.Priority
 }
 
+func (t *Task) GetTimeout() (timeout int) {
+	return t.Timeout
+}
+
+func (t *Task) GetMaxRetries() (maxRetries int) {
+	return t.MaxRetries
+}
+
+func (t *Task) GetRetries() (retries int) {
+	return t.Retries
+}
+
+func (t *Task) GetNodeTags() (tags []string) {
+	return t.NodeTags
+}
+
+func (t *Task) GetCapabilities() (capabilities []string) {
+	return t.Capabilities
+}
+
 func (t *Task) GetUserId() (id primitive.ObjectID) {
 	return t.UserId
 }
@@ -101,6 +154,83 @@ func (t *Task) SetUserId(id primitive.ObjectID) {
 	t.UserId = id
 }
 
+func (t *Task) GetCheckpoint() (token string) {
+	return t.Checkpoint
+}
+
+func (t *Task) SetCheckpoint(token string) {
+	t.Checkpoint = token
+}
+
+func (t *Task) SetLogsTruncated(truncated bool) {
+	t.LogsTruncated = truncated
+}
+
+func (t *Task) GetItemsCapped() (capped bool) {
+	return t.ItemsCapped
+}
+
+func (t *Task) SetItemsCapped(capped bool) {
+	t.ItemsCapped = capped
+}
+
+func (t *Task) GetConcurrency() (concurrency int) {
+	return t.Concurrency
+}
+
+func (t *Task) GetLockKey() (key string) {
+	return t.LockKey
+}
+
+func (t *Task) GetTest() (test bool) {
+	return t.Test
+}
+
+// SetTestResults sets result items captured by the test run, and whether
+// items have been dropped as the max number of captured items is reached
+func (t *Task) SetTestResults(results []map[string]interface{}, capped bool) {
+	t.TestResults = results
+	if capped {
+		t.ItemsCapped = true
+	}
+}
+
+func (t *Task) GetProfile() (profile string) {
+	return t.Profile
+}
+
+func (t *Task) GetProxy() (proxy string) {
+	return t.Proxy
+}
+
+func (t *Task) SetProxy(proxy string) {
+	t.Proxy = proxy
+}
+
+func (t *Task) GetGitRef() (ref string) {
+	return t.GitRef
+}
+
+func (t *Task) GetInputId() (id primitive.ObjectID) {
+	return t.InputId
+}
+
+func (t *Task) GetGitCommit() (hash string) {
+	return t.GitCommit
+}
+
+func (t *Task) SetGitCommit(hash string) {
+	t.GitCommit = hash
+}
+
+func (t *Task) GetTraceContext() (carrier map[string]string) {
+	return t.TraceContext
+}
+
+func (t *Task) SetTraceContext(carrier map[string]string) {
+	t.TraceContext = carrier
+}
+
 type TaskList []Task
 
 func (l *TaskList) GetModels() (res []interfaces.Model) {
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type TaskEvent struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id"`
+	TaskId  primitive.ObjectID `json:"task_id" bson:"task_id"`
+	Type    string             `json:"type" bson:"type"`       // event type, e.g. constants.TaskEventCreated
+	Actor   string             `json:"actor" bson:"actor"`     // who triggered the event, e.g. "user:admin", "node:<key>" or "system"
+	NodeId  primitive.ObjectID `json:"node_id" bson:"node_id"` // node related to the event if any
+	Message string             `json:"message" bson:"message"`
+	Ts      time.Time          `json:"ts" bson:"ts"`
+}
+
+func (e *TaskEvent) GetId() (id primitive.ObjectID) {
+	return e.Id
+}
+
+func (e *TaskEvent) SetId(id primitive.ObjectID) {
+	e.Id = id
+}
+
+type TaskEventList []TaskEvent
+
+func (l *TaskEventList) GetModels() (res []interfaces.Model) {
+	for i := range *l {
+		d := (*l)[i]
+		res = append(res, &d)
+	}
+	return res
+}
@@ -9,6 +9,12 @@ type TaskQueueItem struct {
 	Id       primitive.ObjectID `json:"_id" bson:"_id"`
 	Priority int                `json:"p" bson:"p"`
 	NodeId   primitive.ObjectID `json:"nid,omitempty" bson:"nid,omitempty"`
+	SpiderId primitive.ObjectID `json:"sid,omitempty" bson:"sid,omitempty"`
+	LockKey  string             `json:"lk,omitempty" bson:"lk,omitempty"` // Task.LockKey
+	// whether the node is selected by the dispatch strategy rather than
+	// assigned explicitly, in which case the task is dispatched again if the
+	// node goes offline
+	Dispatched bool `json:"dp,omitempty" bson:"dp,omitempty"`
 }
 
 func (t *TaskQueueItem) GetId() (id primitive.ObjectID) {
@@ -16,6 +16,11 @@ type TaskStat struct {
 	TotalDuration   int64              `json:"total_duration" bson:"total_duration,omitempty"`     // in millisecond
 	ResultCount     int64              `json:"result_count" bson:"result_count"`
 	ErrorLogCount   int64              `json:"error_log_count" bson:"error_log_count"`
+
+	// outcome of stored results, which differ from result_count if dedup is enabled
+	ResultInsertedCount int64 `json:"result_inserted_count" bson:"result_inserted_count"`
+	ResultUpdatedCount  int64 `json:"result_updated_count" bson:"result_updated_count"`
+	ResultSkippedCount  int64 `json:"result_skipped_count" bson:"result_skipped_count"`
 }
 
 func (s *TaskStat) GetId() (id primitive.ObjectID) {
@@ -90,6 +95,18 @@ func (s *TaskStat) SetErrorLogCount(c int64) {
 	s.ErrorLogCount = c
 }
 
+func (s *TaskStat) GetResultInsertedCount() (c int64) {
+	return s.ResultInsertedCount
+}
+
+func (s *TaskStat) GetResultUpdatedCount() (c int64) {
+	return s.ResultUpdatedCount
+}
+
+func (s *TaskStat) GetResultSkippedCount() (c int64) {
+	return s.ResultSkippedCount
+}
+
 type TaskStatList []TaskStat
 
 func (l *TaskStatList) GetModels() (res []interfaces.Model) {
@@ -6,11 +6,13 @@ import (
 )
 
 type User struct {
-	Id       primitive.ObjectID `json:"_id" bson:"_id"`
-	Username string             `json:"username" bson:"username"`
-	Password string             `json:"password,omitempty" bson:"-"`
-	Role     string             `json:"role" bson:"role"`
-	Email    string             `json:"email" bson:"email"`
+	Id           primitive.ObjectID `json:"_id" bson:"_id"`
+	Username     string             `json:"username" bson:"username"`
+	Password     string             `json:"password,omitempty" bson:"-"`
+	PasswordHash string             `json:"-" bson:"password_hash"`
+	Role         string             `json:"role" bson:"role"`
+	Roles        []string           `json:"roles" bson:"roles"`
+	Email        string             `json:"email" bson:"email"`
 	//Setting  UserSetting        `json:"setting" bson:"setting"`
 }
 
@@ -30,10 +32,26 @@ func (u *User) GetPassword() (p string) {
 	return u.Password
 }
 
+func (u *User) GetPasswordHash() (h string) {
+	return u.PasswordHash
+}
+
+func (u *User) SetPasswordHash(h string) {
+	u.PasswordHash = h
+}
+
 func (u *User) GetRole() (r string) {
 	return u.Role
 }
 
+func (u *User) GetRoles() (roles []string) {
+	return u.Roles
+}
+
+func (u *User) SetRoles(roles []string) {
+	u.Roles = roles
+}
+
 func (u *User) GetEmail() (email string) {
 	return u.Email
 }
@@ -28,6 +28,7 @@ type ModelMap struct {
 	RolePermission    RolePermission
 	Environment       Environment
 	DependencySetting DependencySetting
+	TaskEvent         TaskEvent
 }
 
 type ModelListMap struct {
@@ -58,6 +59,7 @@ type ModelListMap struct {
 	RolePermissionList RolePermissionList
 	Environments       EnvironmentList
 	DependencySettings DependencySettingList
+	TaskEvents         TaskEventList
 }
 
 func NewModelMap() (m *ModelMap) {
@@ -91,5 +93,6 @@ func NewModelListMap() (m *ModelListMap) {
 		PermissionList:     PermissionList{},
 		RolePermissionList: RolePermissionList{},
 		Environments:       EnvironmentList{},
+		TaskEvents:         TaskEventList{},
 	}
 }
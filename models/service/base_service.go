@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/constants"
@@ -13,6 +14,7 @@ import (
 	"github.com/crawlab-team/go-trace"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"reflect"
 	"strings"
 	"sync"
@@ -71,6 +73,30 @@ func (svc *BaseService) GetList(query bson.M, opts *mongo.FindOptions) (l interf
 	return NewListBinder(svc.id, fr).Bind()
 }
 
+// GetListWithContext is GetList with the query cancelled along with ctx
+func (svc *BaseService) GetListWithContext(ctx context.Context, query bson.M, opts *mongo.FindOptions) (l interfaces.List, err error) {
+	if svc.col == nil {
+		return nil, trace.TraceError(constants.ErrMissingCol)
+	}
+	_opts := &options.FindOptions{}
+	if opts != nil {
+		if opts.Skip != 0 {
+			_opts.SetSkip(int64(opts.Skip))
+		}
+		if opts.Limit != 0 {
+			_opts.SetLimit(int64(opts.Limit))
+		}
+		if opts.Sort != nil {
+			_opts.SetSort(opts.Sort)
+		}
+	}
+	cur, err := svc.col.GetCollection().Find(ctx, query, _opts)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return NewListBinderWithCursor(ctx, svc.id, cur).Bind()
+}
+
 func (svc *BaseService) DeleteById(id primitive.ObjectID, args ...interface{}) (err error) {
 	return svc.deleteId(id, args...)
 }
@@ -109,6 +135,21 @@ func (svc *BaseService) Count(query bson.M) (total int, err error) {
 	return svc.count(query)
 }
 
+// CountWithContext is Count with the query cancelled along with ctx
+func (svc *BaseService) CountWithContext(ctx context.Context, query bson.M) (total int, err error) {
+	if svc.col == nil {
+		return total, trace.TraceError(constants.ErrMissingCol)
+	}
+	if query == nil {
+		query = bson.M{}
+	}
+	n, err := svc.col.GetCollection().CountDocuments(ctx, query)
+	if err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return int(n), nil
+}
+
 func (svc *BaseService) findId(id primitive.ObjectID) (fr *mongo.FindResult) {
 	if svc.col == nil {
 		return mongo.NewFindResultWithError(constants.ErrMissingCol)
@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
 )
 
 func NewListBinder(id interfaces.ModelId, fr *mongo.FindResult) (b interfaces.ModelListBinder) {
@@ -17,11 +19,24 @@ func NewListBinder(id interfaces.ModelId, fr *mongo.FindResult) (b interfaces.Mo
 	}
 }
 
+// NewListBinderWithCursor returns a list binder decoding documents from the
+// cursor with ctx, e.g. of BaseService.GetListWithContext
+func NewListBinderWithCursor(ctx context.Context, id interfaces.ModelId, cur *mongo2.Cursor) (b interfaces.ModelListBinder) {
+	return &ListBinder{
+		id:  id,
+		m:   models.NewModelListMap(),
+		ctx: ctx,
+		cur: cur,
+	}
+}
+
 type ListBinder struct {
-	id interfaces.ModelId
-	m  *models.ModelListMap
-	fr *mongo.FindResult
-	b  interfaces.ModelBinder
+	id  interfaces.ModelId
+	m   *models.ModelListMap
+	fr  *mongo.FindResult
+	b   interfaces.ModelBinder
+	ctx context.Context
+	cur *mongo2.Cursor
 }
 
 func (b *ListBinder) Bind() (l interfaces.List, err error) {
@@ -80,12 +95,20 @@ func (b *ListBinder) Bind() (l interfaces.List, err error) {
 		return b.Process(&m.Environments)
 	case interfaces.ModelIdDependencySetting:
 		return b.Process(&m.DependencySettings)
+	case interfaces.ModelIdTaskEvent:
+		return b.Process(&m.TaskEvents)
 	default:
 		return l, errors.ErrorModelInvalidModelId
 	}
 }
 
 func (b *ListBinder) Process(d interface{}) (l interfaces.List, err error) {
+	if b.cur != nil {
+		if err := b.cur.All(b.ctx, d); err != nil {
+			return l, trace.TraceError(err)
+		}
+		return d.(interfaces.List), nil
+	}
 	if err := b.fr.All(d); err != nil {
 		return l, trace.TraceError(err)
 	}
@@ -1,16 +1,19 @@
 package service
 
 import (
+	"context"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
 )
 
 type ModelService interface {
 	interfaces.ModelService
 	DropAll() (err error)
+	WithTransaction(ctx context.Context, fn func(sessCtx mongo2.SessionContext) error) (err error)
 	GetNodeById(id primitive.ObjectID) (res *models.Node, err error)
 	GetNode(query bson.M, opts *mongo.FindOptions) (res *models.Node, err error)
 	GetNodeList(query bson.M, opts *mongo.FindOptions) (res []models.Node, err error)
@@ -42,6 +45,9 @@ type ModelService interface {
 	GetSetting(query bson.M, opts *mongo.FindOptions) (res *models.Setting, err error)
 	GetSettingList(query bson.M, opts *mongo.FindOptions) (res []models.Setting, err error)
 	GetSettingByKey(key string, opts *mongo.FindOptions) (res *models.Setting, err error)
+	GetSettingByKeyForNode(key string, nodeId primitive.ObjectID, opts *mongo.FindOptions) (res *models.Setting, err error)
+	GetNodeConfig(nodeId primitive.ObjectID) (settings map[string]string, err error)
+	GetSettingValue(key string, out interface{}) (ok bool, err error)
 	GetSpiderById(id primitive.ObjectID) (res *models.Spider, err error)
 	GetSpider(query bson.M, opts *mongo.FindOptions) (res *models.Spider, err error)
 	GetSpiderList(query bson.M, opts *mongo.FindOptions) (res []models.Spider, err error)
@@ -102,4 +108,6 @@ type ModelService interface {
 	GetEnvironmentById(id primitive.ObjectID) (res *models.Environment, err error)
 	GetEnvironment(query bson.M, opts *mongo.FindOptions) (res *models.Environment, err error)
 	GetEnvironmentList(query bson.M, opts *mongo.FindOptions) (res []models.Environment, err error)
+	GetTaskEventList(query bson.M, opts *mongo.FindOptions) (res []models.TaskEvent, err error)
+	GetTaskEventListByTaskId(id primitive.ObjectID) (res []models.TaskEvent, err error)
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	models2 "github.com/crawlab-team/crawlab-core/models/models"
@@ -30,12 +31,17 @@ func (svc *Service) GetNode(query bson.M, opts *mongo.FindOptions) (res *models2
 	return convertTypeNode(d, err)
 }
 
+// GetNodeList returns nodes of the query. Nodes are in the default order (see
+// models.SortNodes) unless sorted by opts.
 func (svc *Service) GetNodeList(query bson.M, opts *mongo.FindOptions) (res []models2.Node, err error) {
 	l, err := svc.GetBaseService(interfaces.ModelIdNode).GetList(query, opts)
 	for _, doc := range l.GetModels() {
 		d := doc.(*models2.Node)
 		res = append(res, *d)
 	}
+	if opts == nil || (opts.Sort == nil && opts.Skip == 0 && opts.Limit == 0) {
+		_ = models2.SortNodes(res, constants.NodeSortByDefault, false)
+	}
 	return res, nil
 }
 
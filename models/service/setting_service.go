@@ -1,12 +1,19 @@
 package service
 
 import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	models2 "github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"time"
 )
 
 func convertTypeSetting(d interface{}, err error) (res *models2.Setting, err2 error) {
@@ -43,3 +50,123 @@ func (svc *Service) GetSettingByKey(key string, opts *mongo.FindOptions) (res *m
 	query := bson.M{"key": key}
 	return svc.GetSetting(query, opts)
 }
+
+// GetSettingByKeyForNode returns the setting of the key with the value
+// resolved for the node by its tags, see models.Setting.ResolveValue
+func (svc *Service) GetSettingByKeyForNode(key string, nodeId primitive.ObjectID, opts *mongo.FindOptions) (res *models2.Setting, err error) {
+	res, err = svc.GetSettingByKey(key, opts)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := svc.getNodeTagNames(nodeId)
+	if err != nil {
+		return nil, err
+	}
+	res.Value = res.ResolveValue(tags)
+	return res, nil
+}
+
+// GetNodeConfig returns the runtime config of the node in the setting
+// constants.SettingKeyNodeConfig resolved by its tags, empty if not set
+func (svc *Service) GetNodeConfig(nodeId primitive.ObjectID) (settings map[string]string, err error) {
+	s, err := svc.GetSettingByKeyForNode(constants.SettingKeyNodeConfig, nodeId, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	settings = map[string]string{}
+	for key, value := range s.Value {
+		settings[key] = fmt.Sprint(value)
+	}
+	return settings, nil
+}
+
+// settingValueCacheItem is a cached value of a setting, nil if the setting
+// does not exist
+type settingValueCacheItem struct {
+	value bson.M
+	ts    time.Time
+}
+
+var settingValueCache sync.Map // key -> settingValueCacheItem
+
+func getSettingCacheTtl() (ttl time.Duration) {
+	if seconds := viper.GetInt("setting.cacheTtl"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Second
+}
+
+// ResetSettingValueCache drops cached values of settings of the keys, or of
+// all settings if no keys, so that changes made on this master take effect at
+// once. Changes made on other masters take effect when cached values expire.
+func ResetSettingValueCache(keys ...string) {
+	if len(keys) == 0 {
+		settingValueCache.Range(func(key, _ interface{}) bool {
+			settingValueCache.Delete(key)
+			return true
+		})
+		return
+	}
+	for _, key := range keys {
+		settingValueCache.Delete(key)
+	}
+}
+
+// GetSettingValue decodes the value of the setting of the key into out. Fields
+// absent in the value are left unchanged, so that out may be set to defaults
+// beforehand. ok is false if the setting does not exist or has no value.
+// Values are cached for "setting.cacheTtl" (seconds, 5 by default), so that
+// settings read on hot paths do not query the database each time.
+func (svc *Service) GetSettingValue(key string, out interface{}) (ok bool, err error) {
+	var value bson.M
+	if item, ok := settingValueCache.Load(key); ok && time.Since(item.(settingValueCacheItem).ts) < getSettingCacheTtl() {
+		value = item.(settingValueCacheItem).value
+	} else {
+		s, err := svc.GetSettingByKey(key, nil)
+		if err != nil && err != mongo2.ErrNoDocuments {
+			return false, err
+		}
+		if err == nil {
+			value = s.Value
+		}
+		settingValueCache.Store(key, settingValueCacheItem{value: value, ts: time.Now()})
+	}
+	if value == nil {
+		return false, nil
+	}
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	if err := bson.Unmarshal(data, out); err != nil {
+		return false, trace.TraceError(err)
+	}
+	return true, nil
+}
+
+func (svc *Service) getNodeTagNames(nodeId primitive.ObjectID) (names []string, err error) {
+	a, err := svc.GetArtifactById(nodeId)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(a.TagIds) == 0 {
+		return nil, nil
+	}
+	tags, err := svc.GetTagList(bson.M{"_id": bson.M{"$in": a.TagIds}}, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestSettingService_GetSettingValue(t *testing.T) {
+	SetupTest(t)
+	defer service.ResetSettingValueCache()
+
+	modelSvc, err := service.NewService()
+	require.Nil(t, err)
+
+	type value struct {
+		A int    `bson:"a"`
+		B string `bson:"b"`
+	}
+
+	// not exists
+	v := value{B: "default"}
+	ok, err := modelSvc.GetSettingValue("test", &v)
+	require.Nil(t, err)
+	require.False(t, ok)
+	require.Equal(t, "default", v.B)
+
+	// absent fields keep defaults
+	s := &models2.Setting{Key: "test", Value: bson.M{"a": 1}}
+	require.Nil(t, delegate.NewModelDelegate(s).Add())
+	service.ResetSettingValueCache("test")
+	ok, err = modelSvc.GetSettingValue("test", &v)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, value{A: 1, B: "default"}, v)
+
+	// cached until reset
+	s.Value = bson.M{"a": 2}
+	require.Nil(t, delegate.NewModelDelegate(s).Save())
+	v = value{}
+	_, err = modelSvc.GetSettingValue("test", &v)
+	require.Nil(t, err)
+	require.Equal(t, 1, v.A)
+	service.ResetSettingValueCache("test")
+	_, err = modelSvc.GetSettingValue("test", &v)
+	require.Nil(t, err)
+	require.Equal(t, 2, v.A)
+}
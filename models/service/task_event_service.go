@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (svc *Service) GetTaskEventList(query bson.M, opts *mongo.FindOptions) (res []models2.TaskEvent, err error) {
+	l, err := svc.GetBaseService(interfaces.ModelIdTaskEvent).GetList(query, opts)
+	for _, doc := range l.GetModels() {
+		d := doc.(*models2.TaskEvent)
+		res = append(res, *d)
+	}
+	return res, nil
+}
+
+func (svc *Service) GetTaskEventListByTaskId(id primitive.ObjectID) (res []models2.TaskEvent, err error) {
+	return svc.GetTaskEventList(bson.M{"task_id": id}, &mongo.FindOptions{
+		Sort: bson.D{{"ts", 1}, {"_id", 1}},
+	})
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/event"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"sync"
+)
+
+var transactionSupported bool
+var transactionSupportedOnce sync.Once
+
+func (svc *Service) WithTransaction(ctx context.Context, fn func(sessCtx mongo2.SessionContext) error) (err error) {
+	return WithTransaction(ctx, fn)
+}
+
+// WithTransaction runs fn in a transaction, so that the operations performed
+// with sessCtx are committed or aborted together. fn may be retried on
+// transient errors and must be safe to run again. Events of models written
+// with sessCtx are sent only after the transaction is committed, once.
+//
+// Transactions require mongo to run as a replica set or sharded cluster. On a
+// standalone mongo fn runs in a session without transaction, i.e. operations
+// already performed are not rolled back if fn fails.
+func WithTransaction(ctx context.Context, fn func(sessCtx mongo2.SessionContext) error) (err error) {
+	c, err := mongo.GetMongoClient()
+	if err != nil {
+		return err
+	}
+	s, err := c.StartSession()
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer s.EndSession(ctx)
+
+	// events deferred until committed
+	ctx, events := event.WithDeferredEvents(ctx)
+
+	// standalone
+	if !IsTransactionSupported() {
+		if err := mongo2.WithSession(ctx, s, fn); err != nil {
+			return err
+		}
+		events.Flush()
+		return nil
+	}
+
+	// transaction
+	if _, err = s.WithTransaction(ctx, func(sc mongo2.SessionContext) (interface{}, error) {
+		// events of failed attempts are discarded
+		events.Reset()
+		return nil, fn(sc)
+	}); err != nil {
+		return err
+	}
+	events.Flush()
+	return nil
+}
+
+// IsTransactionSupported returns whether mongo supports transactions, i.e. it
+// runs as a replica set or sharded cluster. It is checked once and cached.
+func IsTransactionSupported() (ok bool) {
+	transactionSupportedOnce.Do(func() {
+		var res bson.M
+		if err := mongo.GetMongoDb("").RunCommand(context.Background(), bson.M{"isMaster": 1}).Decode(&res); err != nil {
+			trace.PrintError(err)
+			log.Warnf("unable to check whether mongo supports transactions, multi-document operations are not atomic")
+			return
+		}
+		_, isReplicaSet := res["setName"]
+		isSharded := res["msg"] == "isdbgrid"
+		transactionSupported = isReplicaSet || isSharded
+		if !transactionSupported {
+			log.Warnf("mongo is standalone and does not support transactions, multi-document operations are not atomic. run mongo as a replica set to enable transactions")
+		}
+	})
+	return transactionSupported
+}
@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/crawlab-team/crawlab-core/event"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	models2 "github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestWithTransaction(t *testing.T) {
+	SetupTest(t)
+
+	ch := make(chan interfaces.EventData, 10)
+	eventSvc := event.NewEventService()
+	eventSvc.Register("test-transaction", "^model:tags:add$", "^$", &ch)
+	defer eventSvc.Unregister("test-transaction")
+
+	// committed, with events sent after commit
+	tag := &models2.Tag{Name: "committed"}
+	require.Nil(t, service.WithTransaction(context.Background(), func(sc mongo2.SessionContext) (err error) {
+		if err := delegate.NewModelDelegate(tag, sc).Add(); err != nil {
+			return err
+		}
+		select {
+		case <-ch:
+			require.FailNow(t, "event sent before commit")
+		case <-time.After(100 * time.Millisecond):
+		}
+		return nil
+	}))
+	modelSvc, err := service.NewService()
+	require.Nil(t, err)
+	_, err = modelSvc.GetBaseService(interfaces.ModelIdTag).GetById(tag.Id)
+	require.Nil(t, err)
+	select {
+	case e := <-ch:
+		require.Equal(t, tag.Id, e.GetData().(*models2.Tag).Id)
+	case <-time.After(time.Second):
+		require.FailNow(t, "event not sent after commit")
+	}
+
+	// failed, without events, and rolled back if supported
+	errFailed := errors.New("failed")
+	tag = &models2.Tag{Name: "failed"}
+	err = service.WithTransaction(context.Background(), func(sc mongo2.SessionContext) (err error) {
+		if err := delegate.NewModelDelegate(tag, sc).Add(); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	if service.IsTransactionSupported() {
+		_, err = modelSvc.GetBaseService(interfaces.ModelIdTag).Get(bson.M{"name": "failed"}, nil)
+		require.Equal(t, mongo2.ErrNoDocuments, err)
+	}
+	select {
+	case e := <-ch:
+		require.FailNow(t, "event sent of failed transaction", "%v", e.GetData())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -63,6 +63,7 @@ func (svc *Service) GetBasicNodeInfo() (res interfaces.Entity) {
 		IsMaster:   svc.IsMaster(),
 		AuthKey:    svc.GetAuthKey(),
 		MaxRunners: svc.GetMaxRunners(),
+		Version:    config.GetVersion(),
 	}
 }
 
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"sync"
+)
+
+// SingleFailureNodeHealthEvaluator marks a worker node offline
+// as soon as one health check fails
+type SingleFailureNodeHealthEvaluator struct {
+}
+
+func (e *SingleFailureNodeHealthEvaluator) OnSuccess(n interfaces.Node) {
+}
+
+func (e *SingleFailureNodeHealthEvaluator) OnFailure(n interfaces.Node, err error) (offline bool) {
+	return true
+}
+
+func NewSingleFailureNodeHealthEvaluator() interfaces.NodeHealthEvaluator {
+	return &SingleFailureNodeHealthEvaluator{}
+}
+
+// ConsecutiveFailuresNodeHealthEvaluator marks a worker node offline once its
+// health checks failed a number of times in a row and the node is unhealthy by
+// the wrapped evaluator, so that a single missed PING, e.g. due to a network
+// blip, does not set the node offline if configured so. With one failure, the
+// wrapped evaluator decides alone.
+type ConsecutiveFailuresNodeHealthEvaluator struct {
+	evaluator   interfaces.NodeHealthEvaluator
+	maxFailures int
+	failures    map[string]int // consecutive failures by node key
+	mu          sync.Mutex
+}
+
+func (e *ConsecutiveFailuresNodeHealthEvaluator) OnSuccess(n interfaces.Node) {
+	e.mu.Lock()
+	delete(e.failures, n.GetKey())
+	evaluator := e.evaluator
+	e.mu.Unlock()
+	evaluator.OnSuccess(n)
+}
+
+func (e *ConsecutiveFailuresNodeHealthEvaluator) OnFailure(n interfaces.Node, err error) (offline bool) {
+	e.mu.Lock()
+	e.failures[n.GetKey()]++
+	failures := e.failures[n.GetKey()]
+	maxFailures := e.maxFailures
+	evaluator := e.evaluator
+	e.mu.Unlock()
+
+	// the wrapped evaluator sees every failure to keep its own state
+	offline = evaluator.OnFailure(n, err)
+	if !offline || failures < maxFailures {
+		return false
+	}
+	if maxFailures > 1 {
+		log.Warnf("worker node[%s] failed %d consecutive health checks", n.GetKey(), failures)
+	}
+	e.mu.Lock()
+	delete(e.failures, n.GetKey())
+	e.mu.Unlock()
+	return true
+}
+
+func (e *ConsecutiveFailuresNodeHealthEvaluator) SetMaxFailures(failures int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxFailures = failures
+}
+
+// SetEvaluator sets the wrapped evaluator, e.g. provided by integrators
+func (e *ConsecutiveFailuresNodeHealthEvaluator) SetEvaluator(evaluator interfaces.NodeHealthEvaluator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evaluator = evaluator
+}
+
+// Retain drops failures of nodes other than those of the keys, e.g. deleted
+// nodes, as well as state of the wrapped evaluator
+func (e *ConsecutiveFailuresNodeHealthEvaluator) Retain(keys []string) {
+	e.mu.Lock()
+	retained := map[string]int{}
+	for _, key := range keys {
+		if failures, ok := e.failures[key]; ok {
+			retained[key] = failures
+		}
+	}
+	e.failures = retained
+	evaluator := e.evaluator
+	e.mu.Unlock()
+	if r, ok := evaluator.(nodeHealthEvaluatorRetainer); ok {
+		r.Retain(keys)
+	}
+}
+
+func NewConsecutiveFailuresNodeHealthEvaluator(maxFailures int, evaluator interfaces.NodeHealthEvaluator) *ConsecutiveFailuresNodeHealthEvaluator {
+	return &ConsecutiveFailuresNodeHealthEvaluator{
+		evaluator:   evaluator,
+		maxFailures: maxFailures,
+		failures:    map[string]int{},
+	}
+}
+
+// nodeHealthEvaluatorRetainer is implemented by evaluators keeping state of
+// nodes, which is dropped for nodes no longer existing
+type nodeHealthEvaluatorRetainer interface {
+	Retain(keys []string)
+}
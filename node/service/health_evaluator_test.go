@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// neverOfflineNodeHealthEvaluator keeps worker nodes online whatever fails
+type neverOfflineNodeHealthEvaluator struct {
+	failures int
+}
+
+func (e *neverOfflineNodeHealthEvaluator) OnSuccess(n interfaces.Node) {
+}
+
+func (e *neverOfflineNodeHealthEvaluator) OnFailure(n interfaces.Node, err error) (offline bool) {
+	e.failures++
+	return false
+}
+
+func TestConsecutiveFailuresNodeHealthEvaluator(t *testing.T) {
+	n := &models.Node{Key: "worker-1"}
+	err := errors.ErrorNodeNotExists
+
+	// single failure by default
+	e := NewConsecutiveFailuresNodeHealthEvaluator(1, NewSingleFailureNodeHealthEvaluator())
+	require.True(t, e.OnFailure(n, err))
+
+	// consecutive failures reset on success
+	e.SetMaxFailures(3)
+	require.False(t, e.OnFailure(n, err))
+	require.False(t, e.OnFailure(n, err))
+	e.OnSuccess(n)
+	require.False(t, e.OnFailure(n, err))
+	require.False(t, e.OnFailure(n, err))
+	require.True(t, e.OnFailure(n, err))
+
+	// custom evaluator sees every failure and decides once the max is reached
+	custom := &neverOfflineNodeHealthEvaluator{}
+	e.SetEvaluator(custom)
+	for i := 0; i < 5; i++ {
+		require.False(t, e.OnFailure(n, err))
+	}
+	require.Equal(t, 5, custom.failures)
+
+	// failures of nodes no longer existing are dropped
+	e.Retain(nil)
+	require.Empty(t, e.failures)
+}
@@ -1,24 +1,35 @@
 package service
 
 import (
+	"context"
+	errors2 "errors"
+	"fmt"
 	"github.com/apex/log"
+	"github.com/cenkalti/backoff/v4"
 	config2 "github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/grpc/server"
+	"github.com/crawlab-team/crawlab-core/inject"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/job"
+	"github.com/crawlab-team/crawlab-core/metrics"
 	"github.com/crawlab-team/crawlab-core/models/common"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
 	"github.com/crawlab-team/crawlab-core/notification"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/crawlab-core/result"
 	"github.com/crawlab-team/crawlab-core/schedule"
 	"github.com/crawlab-team/crawlab-core/spider/admin"
 	"github.com/crawlab-team/crawlab-core/system"
 	"github.com/crawlab-team/crawlab-core/task/handler"
 	"github.com/crawlab-team/crawlab-core/task/scheduler"
 	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-core/webhook"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
@@ -26,6 +37,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
+	"sync"
 	"time"
 )
 
@@ -38,14 +50,29 @@ type MasterService struct {
 	handlerSvc      interfaces.TaskHandlerService
 	scheduleSvc     interfaces.ScheduleService
 	notificationSvc *notification.Service
+	webhookSvc      *webhook.Service
 	spiderAdminSvc  interfaces.SpiderAdminService
 	systemSvc       *system.Service
+	jobSvc          interfaces.JobService
+	metricsPusher   *metrics.Pusher
+	healthEvaluator *ConsecutiveFailuresNodeHealthEvaluator
 
 	// settings
-	cfgPath         string
-	address         interfaces.Address
-	monitorInterval time.Duration
-	stopOnError     bool
+	cfgPath                 string
+	address                 interfaces.Address
+	monitorInterval         time.Duration
+	maxMonitorCycleDuration time.Duration
+	heartbeatTimeout        time.Duration // max age of the last heartbeat of a node failing ping to be kept online, 0 to disable
+	stopOnError             bool
+	registerRetries         int           // max retries of initial db registration
+	registerBackoff         time.Duration // initial backoff interval between registration retries
+
+	// internals
+	monitorStats   interfaces.NodeMonitorStats
+	monitorStatsMu sync.RWMutex
+	monitorMu      sync.Mutex // serializes monitor cycles
+	ctx            context.Context
+	cancel         context.CancelFunc // cancels monitor cycles on stop
 }
 
 func (svc *MasterService) Init() (err error) {
@@ -54,16 +81,41 @@ func (svc *MasterService) Init() (err error) {
 }
 
 func (svc *MasterService) Start() {
+	// wait for db to be ready
+	if err := svc.retryRegister("connect db", svc.pingDatabase); err != nil {
+		panic(err)
+	}
+
+	// preflight (if enabled) once db is ready, aborting the start if any
+	// check fails
+	if isPreflightEnabled() {
+		if err := svc.preflight(); err != nil {
+			log.Fatalf("master[%s] %v", svc.GetConfigService().GetNodeKey(), err)
+		}
+	}
+
+	// check data directories
+	if err := paths.CheckDataPaths(); err != nil {
+		panic(err)
+	}
+
 	// create indexes
 	common.CreateIndexes()
 
+	// run migrations (only report pending ones in dry run). Failed ones do
+	// not stop the start, so that admins can fix the data, and they are
+	// applied again on the next start.
+	if _, err := common.RunMigrations(viper.GetBool("migration.dryRun")); err != nil {
+		log.Errorf("master[%s] migrations not applied: %v", svc.GetConfigService().GetNodeKey(), err)
+	}
+
 	// start grpc server
 	if err := svc.server.Start(); err != nil {
 		panic(err)
 	}
 
 	// register to db
-	if err := svc.Register(); err != nil {
+	if err := svc.retryRegister("register master", svc.Register); err != nil {
 		panic(err)
 	}
 
@@ -73,6 +125,10 @@ func (svc *MasterService) Start() {
 	// start task handler
 	go svc.handlerSvc.Start()
 
+	// start spider webhook service, before the task scheduler which ends tasks
+	// interrupted by restart
+	go svc.webhookSvc.Start()
+
 	// start task scheduler
 	go svc.schedulerSvc.Start()
 
@@ -85,6 +141,12 @@ func (svc *MasterService) Start() {
 	// start spider admin service
 	go svc.spiderAdminSvc.Start()
 
+	// start background job workers
+	svc.jobSvc.Start()
+
+	// start pushing metrics to pushgateway (if enabled in settings)
+	go svc.metricsPusher.Start()
+
 	// wait for quit signal
 	svc.Wait()
 
@@ -97,14 +159,24 @@ func (svc *MasterService) Wait() {
 }
 
 func (svc *MasterService) Stop() {
+	svc.cancel()
+	svc.webhookSvc.Stop()
+	svc.jobSvc.Stop()
+	svc.metricsPusher.Stop()
 	_ = svc.server.Stop()
+	result.StopBatchers()
 	log.Infof("master[%s] service has stopped", svc.GetConfigService().GetNodeKey())
 }
 
 func (svc *MasterService) Monitor() {
 	log.Infof("master[%s] monitoring started", svc.GetConfigService().GetNodeKey())
 	for {
-		if err := svc.monitor(); err != nil {
+		_, err := svc.runMonitorCycle(svc.ctx)
+		if errors2.Is(err, errors.ErrorNodeMonitorCancelled) {
+			log.Infof("master[%s] monitoring stopped", svc.GetConfigService().GetNodeKey())
+			return
+		}
+		if err != nil {
 			trace.PrintError(err)
 			if svc.stopOnError {
 				log.Errorf("master[%s] monitor error, now stopping...", svc.GetConfigService().GetNodeKey())
@@ -113,7 +185,12 @@ func (svc *MasterService) Monitor() {
 			}
 		}
 
-		time.Sleep(svc.monitorInterval)
+		select {
+		case <-svc.ctx.Done():
+			log.Infof("master[%s] monitoring stopped", svc.GetConfigService().GetNodeKey())
+			return
+		case <-time.After(svc.monitorInterval):
+		}
 	}
 }
 
@@ -141,6 +218,118 @@ func (svc *MasterService) SetMonitorInterval(duration time.Duration) {
 	svc.monitorInterval = duration
 }
 
+// SetNodeHealthEvaluator sets the evaluator deciding whether a worker node
+// failing health checks is offline, once it failed the max consecutive
+// failures (see SetMaxNodeFailures)
+func (svc *MasterService) SetNodeHealthEvaluator(evaluator interfaces.NodeHealthEvaluator) {
+	svc.healthEvaluator.SetEvaluator(evaluator)
+}
+
+// SetMaxNodeFailures sets the consecutive failures of health checks before a
+// worker node can be set offline, whichever the health evaluator
+func (svc *MasterService) SetMaxNodeFailures(failures int) {
+	svc.healthEvaluator.SetMaxFailures(failures)
+}
+
+func (svc *MasterService) SetRegisterRetry(retries int, initialBackoff time.Duration) {
+	svc.registerRetries = retries
+	svc.registerBackoff = initialBackoff
+}
+
+func (svc *MasterService) SetMaxMonitorCycleDuration(duration time.Duration) {
+	svc.maxMonitorCycleDuration = duration
+}
+
+func (svc *MasterService) GetMonitorStats() (stats interfaces.NodeMonitorStats) {
+	svc.monitorStatsMu.RLock()
+	defer svc.monitorStatsMu.RUnlock()
+	return svc.monitorStats
+}
+
+func (svc *MasterService) RunMonitorOnce() (summary interfaces.NodeMonitorCycleSummary, err error) {
+	log.Infof("master[%s] running monitor cycle on demand", svc.GetConfigService().GetNodeKey())
+	return svc.runMonitorCycle(svc.ctx)
+}
+
+// runMonitorCycle runs a monitor cycle and records its duration. Cycles of the
+// monitor loop and on-demand cycles do not overlap.
+func (svc *MasterService) runMonitorCycle(ctx context.Context) (summary interfaces.NodeMonitorCycleSummary, err error) {
+	svc.monitorMu.Lock()
+	defer svc.monitorMu.Unlock()
+
+	// start time of the cycle
+	startTs := time.Now()
+
+	summary, err = svc.monitor(ctx)
+
+	// record cycle duration
+	duration := time.Since(startTs)
+	svc.recordMonitorCycle(startTs, duration)
+	summary.StartTs = startTs
+	summary.Duration = duration.Milliseconds()
+
+	return summary, err
+}
+
+// PushConfigUpdate applies the runtime settings on master and pushes them to
+// online worker nodes by CONFIG_UPDATE stream messages. Failures of sending to
+// a node are reported in its result and do not stop pushing to other nodes.
+func (svc *MasterService) PushConfigUpdate(settings map[string]string) (results []interfaces.ConfigUpdateResult) {
+	config2.ApplyRuntimeConfig(settings)
+
+	nodes, err := svc.getAllWorkerNodes()
+	if err != nil {
+		trace.PrintError(err)
+		return nil
+	}
+	msg := &entity.ConfigUpdateMessage{Settings: settings}
+	code := grpc.StreamMessageCode(constants.GrpcStreamMessageCodeConfigUpdate)
+	for _, n := range nodes {
+		if n.Status != constants.NodeStatusOnline {
+			continue
+		}
+		res := interfaces.ConfigUpdateResult{NodeKey: n.Key}
+		if err := svc.server.SendStreamMessageWithData("node:"+n.Key, code, msg); err != nil {
+			log.Warnf("cannot push config update to worker node[%s]: %v", n.Key, err)
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// PushNodeConfigs pushes to online worker nodes their runtime config in the
+// setting constants.SettingKeyNodeConfig, resolved by tags of each node, by
+// CONFIG_UPDATE stream messages
+func (svc *MasterService) PushNodeConfigs() (results []interfaces.ConfigUpdateResult) {
+	nodes, err := svc.getAllWorkerNodes()
+	if err != nil {
+		trace.PrintError(err)
+		return nil
+	}
+	code := grpc.StreamMessageCode(constants.GrpcStreamMessageCodeConfigUpdate)
+	for _, n := range nodes {
+		if n.Status != constants.NodeStatusOnline {
+			continue
+		}
+		res := interfaces.ConfigUpdateResult{NodeKey: n.Key}
+		settings, err := svc.modelSvc.GetNodeConfig(n.Id)
+		if err == nil && len(settings) > 0 {
+			err = svc.server.SendStreamMessageWithData("node:"+n.Key, code, &entity.ConfigUpdateMessage{Settings: settings})
+		}
+		if err != nil {
+			log.Warnf("cannot push node config to worker node[%s]: %v", n.Key, err)
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
 func (svc *MasterService) Register() (err error) {
 	nodeKey := svc.GetConfigService().GetNodeKey()
 	nodeName := svc.GetConfigService().GetNodeName()
@@ -149,15 +338,18 @@ func (svc *MasterService) Register() (err error) {
 		// not exists
 		log.Infof("master[%s] does not exist in db", nodeKey)
 		node := &models.Node{
-			Key:        nodeKey,
-			Name:       nodeName,
-			MaxRunners: config.DefaultConfigOptions.MaxRunners,
-			IsMaster:   true,
-			Status:     constants.NodeStatusOnline,
-			Enabled:    true,
-			Active:     true,
-			ActiveTs:   time.Now(),
+			Key:         nodeKey,
+			Name:        nodeName,
+			MaxRunners:  config.DefaultConfigOptions.MaxRunners,
+			IsMaster:    true,
+			Status:      constants.NodeStatusOnline,
+			Enabled:     true,
+			Schedulable: true,
+			Active:      true,
+			ActiveTs:    time.Now(),
 		}
+		node.Capabilities = utils.GetNodeCapabilities()
+		node.Version = config2.GetVersion()
 		if viper.GetInt("task.handler.maxRunners") > 0 {
 			node.MaxRunners = viper.GetInt("task.handler.maxRunners")
 		}
@@ -170,7 +362,15 @@ func (svc *MasterService) Register() (err error) {
 	} else if err == nil {
 		// exists
 		log.Infof("master[%s] exists in db", nodeKey)
+		node.Capabilities = utils.GetNodeCapabilities()
+		node.Version = config2.GetVersion()
 		nodeD := delegate.NewModelNodeDelegate(node)
+		if err := nodeD.UpdateFields(bson.M{
+			"capabilities": node.Capabilities,
+			"version":      node.Version,
+		}); err != nil {
+			return err
+		}
 		if err := nodeD.UpdateStatusOnline(); err != nil {
 			return err
 		}
@@ -182,6 +382,27 @@ func (svc *MasterService) Register() (err error) {
 	}
 }
 
+// retryRegister runs op with exponential backoff until it succeeds or the
+// registration retries are exhausted, in which case the last error is returned
+func (svc *MasterService) retryRegister(prefix string, op func() error) (err error) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = svc.registerBackoff
+	b.MaxElapsedTime = 0
+	return backoff.RetryNotify(op, backoff.WithMaxRetries(b, uint64(svc.registerRetries)), utils.BackoffErrorNotify(prefix))
+}
+
+// pingDatabase checks if mongo is reachable
+func (svc *MasterService) pingDatabase() (err error) {
+	c, err := mongo.GetMongoClient()
+	if err != nil {
+		return err
+	}
+	if err := c.Ping(context.Background(), nil); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
 func (svc *MasterService) StopOnError() {
 	svc.stopOnError = true
 }
@@ -190,50 +411,106 @@ func (svc *MasterService) GetServer() (svr interfaces.GrpcServer) {
 	return svc.server
 }
 
-func (svc *MasterService) monitor() (err error) {
+// monitor checks the health of all worker nodes. If the context is cancelled,
+// e.g. on shutdown, it stops checking the remaining nodes, whose status is
+// left unchanged, and returns the partial summary with
+// errors.ErrorNodeMonitorCancelled.
+func (svc *MasterService) monitor(ctx context.Context) (summary interfaces.NodeMonitorCycleSummary, err error) {
 	// update master node status in db
 	if err := svc.updateMasterNodeStatus(); err != nil {
 		if err.Error() == mongo2.ErrNoDocuments.Error() {
-			return nil
+			return summary, nil
 		}
-		return err
+		return summary, err
 	}
 
 	// all worker nodes
 	nodes, err := svc.getAllWorkerNodes()
 	if err != nil {
-		return err
+		return summary, err
 	}
-
-	// error flag
-	isErr := false
+	svc.retainNodeHealth(nodes)
 
 	// iterate all nodes
-	for _, n := range nodes {
-		// subscribe
-		if err := svc.subscribeNode(&n); err != nil {
-			isErr = true
-			continue
+	for i, n := range nodes {
+		// stop issuing pings if cancelled
+		if ctx.Err() != nil {
+			summary.Cancelled = true
+			summary.NodesSkipped = len(nodes) - i
+			return summary, errors.ErrorNodeMonitorCancelled
 		}
 
-		// ping client
-		if err := svc.pingNodeClient(&n); err != nil {
-			isErr = true
+		// health check (subscribe and ping client)
+		selfReported, err := svc.checkNode(ctx, &n)
+		if errors2.Is(err, errors.ErrorNodeMonitorCancelled) {
+			summary.Cancelled = true
+			summary.NodesSkipped = len(nodes) - i
+			return summary, err
+		}
+		summary.NodesChecked++
+		if err != nil {
+			summary.Failures++
+			summary.FailedNodes = append(summary.FailedNodes, n.Key)
 			continue
 		}
+		if selfReported {
+			summary.SelfReportedNodes = append(summary.SelfReportedNodes, n.Key)
+		}
 
 		// update node available runners
 		if err := svc.updateNodeAvailableRunners(&n); err != nil {
-			isErr = true
+			summary.Failures++
+			summary.FailedNodes = append(summary.FailedNodes, n.Key)
 			continue
 		}
 	}
 
-	if isErr {
-		return trace.TraceError(errors.ErrorNodeMonitorError)
+	if summary.Failures > 0 {
+		return summary, trace.TraceError(errors.ErrorNodeMonitorError)
 	}
 
-	return nil
+	return summary, nil
+}
+
+func (svc *MasterService) recordMonitorCycle(startTs time.Time, duration time.Duration) {
+	// threshold (default to monitor interval)
+	limit := svc.maxMonitorCycleDuration
+	if limit <= 0 {
+		limit = svc.monitorInterval
+	}
+
+	// update stats
+	svc.monitorStatsMu.Lock()
+	svc.monitorStats.LastCycleTs = startTs
+	svc.monitorStats.LastCycleDuration = duration.Milliseconds()
+	svc.monitorStats.CycleDurationLimit = limit.Milliseconds()
+	svc.monitorStats.Cycles++
+	if svc.monitorStats.LastCycleDuration > svc.monitorStats.MaxCycleDuration {
+		svc.monitorStats.MaxCycleDuration = svc.monitorStats.LastCycleDuration
+	}
+	isSlow := duration > limit
+	if isSlow {
+		svc.monitorStats.SlowCycles++
+	}
+	svc.monitorStatsMu.Unlock()
+
+	// alarm
+	if isSlow {
+		log.Warnf("master[%s] monitor cycle took %v, exceeding %v", svc.GetConfigService().GetNodeKey(), duration, limit)
+		go svc.sendMonitorAlarm(duration, limit)
+	}
+}
+
+func (svc *MasterService) sendMonitorAlarm(duration time.Duration, limit time.Duration) {
+	webhook := viper.GetString("node.monitor.alarmWebhook")
+	if webhook == "" {
+		return
+	}
+	title := "[Crawlab] Slow Node Monitor Cycle"
+	content := fmt.Sprintf("Monitor cycle of master[%s] took %v, exceeding the threshold %v.", svc.GetConfigService().GetNodeKey(), duration, limit)
+	if err := notification.SendMobileNotification(webhook, title, content); err != nil {
+		trace.PrintError(err)
+	}
 }
 
 func (svc *MasterService) getAllWorkerNodes() (nodes []models.Node, err error) {
@@ -262,27 +539,104 @@ func (svc *MasterService) updateMasterNodeStatus() (err error) {
 }
 
 func (svc *MasterService) setWorkerNodeOffline(n interfaces.Node) (err error) {
-	return delegate.NewModelNodeDelegate(n).UpdateStatusOffline()
+	if err := delegate.NewModelNodeDelegate(n).UpdateStatusOffline(); err != nil {
+		return err
+	}
+
+	// pending tasks pinned to the node by the dispatch strategy
+	if err := svc.schedulerSvc.RedispatchTasksByNode(n.GetId()); err != nil {
+		trace.PrintError(err)
+	}
+
+	return nil
 }
 
-func (svc *MasterService) subscribeNode(n interfaces.Node) (err error) {
-	_, err = svc.server.GetSubscribe("node:" + n.GetKey())
-	if err != nil {
-		log.Errorf("cannot subscribe worker node[%s]: %v", n.GetKey(), err)
+// checkNode checks the health of the worker node by the PING through its
+// subscription stream. If the PING fails, e.g. the master cannot reach a node
+// behind NAT, while the stream is still alive and the node has sent a
+// heartbeat recently, the node is kept online by its self-report, which is
+// returned as selfReported. A failure after the context is cancelled is not
+// counted and errors.ErrorNodeMonitorCancelled is returned, since the PING
+// may fail due to the shutdown of master rather than the node.
+func (svc *MasterService) checkNode(ctx context.Context, n interfaces.Node) (selfReported bool, err error) {
+	// subscribe
+	sub, err := svc.subscribeNode(n)
+
+	// ping client
+	if err == nil {
+		err = svc.pingNodeClient(n)
+		if err != nil && svc.isNodeSelfReporting(n, sub) {
+			log.Debugf("worker node[%s] failed ping but is alive by self-report, last heartbeat at %s", n.GetKey(), svc.getNodeLastSeen(n).Format(time.RFC3339))
+			selfReported = true
+			err = nil
+		}
+	}
+
+	// success
+	if err == nil {
+		svc.healthEvaluator.OnSuccess(n)
+		return selfReported, nil
+	}
+
+	// cancelled
+	if ctx.Err() != nil {
+		return false, errors.ErrorNodeMonitorCancelled
+	}
+
+	// failure, the node is set offline by the policy of the health evaluator
+	if svc.healthEvaluator.OnFailure(n, err) {
+		log.Warnf("worker node[%s] is unhealthy, setting offline", n.GetKey())
 		if err := svc.setWorkerNodeOffline(n); err != nil {
-			return trace.TraceError(err)
+			return false, trace.TraceError(err)
 		}
-		return trace.TraceError(err)
 	}
-	return nil
+	return false, err
+}
+
+// isNodeSelfReporting returns whether the subscription stream of the node is
+// alive and the node has sent a heartbeat within the heartbeat timeout
+func (svc *MasterService) isNodeSelfReporting(n interfaces.Node, sub interfaces.GrpcSubscribe) (ok bool) {
+	if svc.heartbeatTimeout <= 0 || sub == nil {
+		return false
+	}
+	if stream, ok := sub.GetStream().(interface{ Context() context.Context }); ok && stream.Context().Err() != nil {
+		return false
+	}
+	return time.Since(svc.getNodeLastSeen(n)) <= svc.heartbeatTimeout
+}
+
+// getNodeLastSeen returns the time of the last heartbeat of the node, from
+// memory if seen by master since active_ts in the database may lag behind
+func (svc *MasterService) getNodeLastSeen(n interfaces.Node) (ts time.Time) {
+	ts = n.GetActiveTs()
+	if seenTs, ok := delegate.GetNodeLastSeen(n.GetId()); ok && seenTs.After(ts) {
+		return seenTs
+	}
+	return ts
+}
+
+// retainNodeHealth drops state of the health evaluator of nodes other than
+// the nodes, e.g. deleted nodes
+func (svc *MasterService) retainNodeHealth(nodes []models.Node) {
+	var keys []string
+	for _, n := range nodes {
+		keys = append(keys, n.Key)
+	}
+	svc.healthEvaluator.Retain(keys)
+}
+
+func (svc *MasterService) subscribeNode(n interfaces.Node) (sub interfaces.GrpcSubscribe, err error) {
+	sub, err = svc.server.GetSubscribe("node:" + n.GetKey())
+	if err != nil {
+		log.Errorf("cannot subscribe worker node[%s]: %v", n.GetKey(), err)
+		return nil, trace.TraceError(err)
+	}
+	return sub, nil
 }
 
 func (svc *MasterService) pingNodeClient(n interfaces.Node) (err error) {
 	if err := svc.server.SendStreamMessage("node:"+n.GetKey(), grpc.StreamMessageCode_PING); err != nil {
 		log.Errorf("cannot ping worker node client[%s]: %v", n.GetKey(), err)
-		if err := svc.setWorkerNodeOffline(n); err != nil {
-			return trace.TraceError(err)
-		}
 		return trace.TraceError(err)
 	}
 	return nil
@@ -297,18 +651,54 @@ func (svc *MasterService) updateNodeAvailableRunners(n interfaces.Node) (err err
 	if err != nil {
 		return trace.TraceError(err)
 	}
-	n.SetAvailableRunners(n.GetMaxRunners() - runningTasksCount)
-	return delegate.NewModelDelegate(n).Save()
+	return delegate.NewModelDelegate(n).UpdateFields(bson.M{
+		"available_runners": n.GetMaxRunners() - runningTasksCount,
+	})
 }
 
 func NewMasterService(opts ...Option) (res interfaces.NodeMasterService, err error) {
 	// master service
 	svc := &MasterService{
-		cfgPath:         config2.DefaultConfigPath,
-		monitorInterval: 15 * time.Second,
-		stopOnError:     false,
+		cfgPath:          config2.DefaultConfigPath,
+		monitorInterval:  15 * time.Second,
+		stopOnError:      false,
+		heartbeatTimeout: 45 * time.Second,
+		healthEvaluator:  NewConsecutiveFailuresNodeHealthEvaluator(1, NewSingleFailureNodeHealthEvaluator()),
+		registerRetries:  5,
+		registerBackoff:  1 * time.Second,
+	}
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	// retries and initial backoff interval of initial db registration
+	if registerRetries := viper.GetInt("node.master.registerRetries"); registerRetries > 0 {
+		svc.registerRetries = registerRetries
+	}
+	if registerBackoffSeconds := viper.GetInt("node.master.registerBackoff"); registerBackoffSeconds > 0 {
+		svc.registerBackoff = time.Duration(registerBackoffSeconds) * time.Second
+	}
+
+	// max consecutive failures before setting a worker node offline (1 by
+	// default, i.e. on the first failure)
+	if maxNodeFailures := viper.GetInt("node.monitor.maxFailures"); maxNodeFailures > 0 {
+		svc.SetMaxNodeFailures(maxNodeFailures)
 	}
 
+	// max age of the last heartbeat of a worker node failing ping to be kept
+	// online by self-report, 0 to disable
+	if viper.IsSet("node.monitor.heartbeatTimeout") {
+		svc.heartbeatTimeout = time.Duration(viper.GetInt("node.monitor.heartbeatTimeout")) * time.Second
+	}
+
+	// max monitor cycle duration
+	if maxMonitorCycleDurationSeconds := viper.GetInt("node.monitor.maxCycleDuration"); maxMonitorCycleDurationSeconds > 0 {
+		svc.maxMonitorCycleDuration = time.Duration(maxMonitorCycleDurationSeconds) * time.Second
+	}
+
+	// custom node health evaluator provided by integrators
+	_ = inject.GetContainer().Invoke(func(evaluator interfaces.NodeHealthEvaluator) {
+		svc.SetNodeHealthEvaluator(evaluator)
+	})
+
 	// apply options
 	for _, opt := range opts {
 		opt(svc)
@@ -366,9 +756,26 @@ func NewMasterService(opts ...Option) (res interfaces.NodeMasterService, err err
 	// notification service
 	svc.notificationSvc = notification.GetService()
 
+	// spider webhook service
+	svc.webhookSvc, err = webhook.GetService()
+	if err != nil {
+		return nil, err
+	}
+
 	// system service
 	svc.systemSvc = system.GetService()
 
+	// job service
+	svc.jobSvc = job.GetService()
+
+	// metrics pusher
+	svc.metricsPusher = metrics.NewPusher(svc.GetConfigService().GetNodeKey())
+
+	// expose master service to other modules (e.g. health check)
+	if err := provideMasterService(svc); err != nil {
+		return nil, err
+	}
+
 	// init
 	if err := svc.Init(); err != nil {
 		return nil, err
@@ -377,6 +784,31 @@ func NewMasterService(opts ...Option) (res interfaces.NodeMasterService, err err
 	return svc, nil
 }
 
+var provideMasterServiceOnce sync.Once
+var provideMasterServiceErr error
+
+// provideMasterService exposes the master service and its task scheduler to
+// other modules, e.g. the health check, through the global container. The
+// container holds single instances, so only the first master service of the
+// process is provided, e.g. when tests create several.
+func provideMasterService(svc *MasterService) (err error) {
+	provideMasterServiceOnce.Do(func() {
+		c := inject.GetContainer()
+		if err := c.Provide(func() interfaces.NodeMasterService {
+			return svc
+		}); err != nil {
+			provideMasterServiceErr = err
+			return
+		}
+		if err := c.Provide(func() interfaces.TaskSchedulerService {
+			return svc.schedulerSvc
+		}); err != nil {
+			provideMasterServiceErr = err
+		}
+	})
+	return provideMasterServiceErr
+}
+
 func ProvideMasterService(path string, opts ...Option) func() (interfaces.NodeMasterService, error) {
 	// path
 	if path == "" || path == config2.DefaultConfigPath {
@@ -45,3 +45,39 @@ func WithHeartbeatInterval(duration time.Duration) Option {
 		}
 	}
 }
+
+func WithMaxMonitorCycleDuration(duration time.Duration) Option {
+	return func(svc interfaces.NodeService) {
+		svc2, ok := svc.(interfaces.NodeMasterService)
+		if ok {
+			svc2.SetMaxMonitorCycleDuration(duration)
+		}
+	}
+}
+
+func WithNodeHealthEvaluator(evaluator interfaces.NodeHealthEvaluator) Option {
+	return func(svc interfaces.NodeService) {
+		svc2, ok := svc.(interfaces.NodeMasterService)
+		if ok {
+			svc2.SetNodeHealthEvaluator(evaluator)
+		}
+	}
+}
+
+func WithMaxNodeFailures(failures int) Option {
+	return func(svc interfaces.NodeService) {
+		svc2, ok := svc.(interfaces.NodeMasterService)
+		if ok {
+			svc2.SetMaxNodeFailures(failures)
+		}
+	}
+}
+
+func WithRegisterRetry(retries int, initialBackoff time.Duration) Option {
+	return func(svc interfaces.NodeService) {
+		svc2, ok := svc.(interfaces.NodeMasterService)
+		if ok {
+			svc2.SetRegisterRetry(retries, initialBackoff)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/grpc/server"
+	"github.com/crawlab-team/crawlab-core/paths"
+	log2 "github.com/crawlab-team/crawlab-core/task/log"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The preflight runs on start of nodes before serving, if enabled by
+// "preflight.enabled" (default false). It runs all checks, logs a report of
+// passed and failed checks and aborts the start if any of them fails, instead
+// of failing later on the first broken dependency in a half-started state.
+
+// preflightCheck is a named check of the preflight
+type preflightCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// preflightResult is the result of a check of the preflight
+type preflightResult struct {
+	name     string
+	err      error
+	duration time.Duration
+}
+
+func isPreflightEnabled() (ok bool) {
+	return viper.GetBool("preflight.enabled")
+}
+
+// getPreflightTimeout returns the timeout of each check, which is configured
+// by "preflight.timeout" (seconds, 10 seconds by default)
+func getPreflightTimeout() (timeout time.Duration) {
+	if seconds := viper.GetInt("preflight.timeout"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// runPreflight runs the checks, logs the report and returns
+// errors.ErrorNodePreflightFailed with names of failed checks if any fails
+func runPreflight(prefix string, checks []preflightCheck) (results []preflightResult, err error) {
+	var failed []string
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), getPreflightTimeout())
+		start := time.Now()
+		res := preflightResult{name: c.name, err: c.fn(ctx)}
+		res.duration = time.Since(start)
+		cancel()
+		results = append(results, res)
+		if res.err != nil {
+			failed = append(failed, c.name)
+		}
+	}
+
+	// report
+	log.Infof("%s preflight report:", prefix)
+	for _, res := range results {
+		if res.err != nil {
+			log.Errorf("%s preflight [FAIL] %s (%v): %v", prefix, res.name, res.duration.Round(time.Millisecond), res.err)
+		} else {
+			log.Infof("%s preflight [PASS] %s (%v)", prefix, res.name, res.duration.Round(time.Millisecond))
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%w: %s", errors.ErrorNodePreflightFailed, strings.Join(failed, ", "))
+	}
+	log.Infof("%s preflight passed", prefix)
+	return results, nil
+}
+
+// checkPreflightConfig checks settings which would otherwise fail later
+func checkPreflightConfig(nodeKey string) (err error) {
+	var errs []string
+	if nodeKey == "" {
+		errs = append(errs, "node key is empty")
+	}
+	if utils.IsMaster() {
+		if err := checkPreflightPort(viper.GetString("server.port")); err != nil {
+			errs = append(errs, fmt.Sprintf("server.port: %v", err))
+		}
+	}
+	if s := viper.GetString("log.ttl"); s != "" {
+		if _, ok := log2.ParseLogTtl(s); !ok {
+			errs = append(errs, fmt.Sprintf("log.ttl: invalid duration %s", s))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func checkPreflightPort(port string) (err error) {
+	n, err := strconv.Atoi(port)
+	if err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid port %s", port)
+	}
+	return nil
+}
+
+// checkPreflightDataPaths checks the data directories and the log directory
+// are writable
+func checkPreflightDataPaths(ctx context.Context) (err error) {
+	if err := paths.CheckDataPaths(); err != nil {
+		return err
+	}
+	return paths.EnsureWritable(paths.GetLogPath())
+}
+
+// checkPreflightDatabase checks mongo is reachable. The master runs it after
+// waiting for db to be ready, so that it only fails if db is still unusable.
+func checkPreflightDatabase(ctx context.Context) (err error) {
+	c, err := mongo.GetMongoClient()
+	if err != nil {
+		return err
+	}
+	if err := c.Ping(ctx, nil); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// checkPreflightListen checks the address can be bound, releasing it right
+// away for the server to listen
+func checkPreflightListen(address string) (err error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
+
+// preflight runs the preflight checks of the master
+func (svc *MasterService) preflight() (err error) {
+	checks := []preflightCheck{
+		{"config", func(ctx context.Context) error {
+			return checkPreflightConfig(svc.GetConfigService().GetNodeKey())
+		}},
+		{"data directory", checkPreflightDataPaths},
+		{"mongo", checkPreflightDatabase},
+		{"grpc listen", func(ctx context.Context) error {
+			addresses := svc.server.GetAddresses()
+			if err := server.ValidateAddresses(addresses); err != nil {
+				return err
+			}
+			for _, address := range addresses {
+				if err := checkPreflightListen(address.String()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+	_, err = runPreflight(fmt.Sprintf("master[%s]", svc.GetConfigService().GetNodeKey()), checks)
+	return err
+}
+
+// preflight runs the preflight checks of the worker
+func (svc *WorkerService) preflight() (err error) {
+	checks := []preflightCheck{
+		{"config", func(ctx context.Context) error {
+			return checkPreflightConfig(svc.GetConfigService().GetNodeKey())
+		}},
+		{"data directory", checkPreflightDataPaths},
+	}
+	_, err = runPreflight(fmt.Sprintf("worker[%s]", svc.GetConfigService().GetNodeKey()), checks)
+	return err
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	errors2 "errors"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRunPreflight(t *testing.T) {
+	viper.Set("preflight.timeout", 1)
+	defer viper.Set("preflight.timeout", nil)
+
+	// all checks run, failed ones reported
+	var ran []string
+	check := func(name string, err error) preflightCheck {
+		return preflightCheck{name, func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	results, err := runPreflight("test", []preflightCheck{
+		check("a", nil),
+		check("b", errors2.New("broken")),
+		check("c", nil),
+		check("d", errors2.New("broken")),
+	})
+	require.ErrorIs(t, err, errors.ErrorNodePreflightFailed)
+	require.Contains(t, err.Error(), "b, d")
+	require.Equal(t, []string{"a", "b", "c", "d"}, ran)
+	require.Len(t, results, 4)
+	require.Nil(t, results[0].err)
+	require.NotNil(t, results[1].err)
+
+	// passed
+	_, err = runPreflight("test", []preflightCheck{check("a", nil)})
+	require.Nil(t, err)
+
+	// checks time out
+	_, err = runPreflight("test", []preflightCheck{{"slow", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}}})
+	require.ErrorIs(t, err, errors.ErrorNodePreflightFailed)
+}
+
+func TestCheckPreflightConfig(t *testing.T) {
+	viper.Set("node.master", "N")
+	defer viper.Set("node.master", nil)
+
+	require.Nil(t, checkPreflightConfig("node-1"))
+	require.NotNil(t, checkPreflightConfig(""))
+
+	// log ttl
+	viper.Set("log.ttl", "later")
+	require.NotNil(t, checkPreflightConfig("node-1"))
+	viper.Set("log.ttl", "90d")
+	require.Nil(t, checkPreflightConfig("node-1"))
+	viper.Set("log.ttl", nil)
+
+	// server port of master
+	viper.Set("node.master", "Y")
+	viper.Set("server.port", "8000")
+	defer viper.Set("server.port", nil)
+	require.Nil(t, checkPreflightConfig("node-1"))
+	viper.Set("server.port", "80000")
+	require.NotNil(t, checkPreflightConfig("node-1"))
+}
+
+func TestCheckPreflightListen(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	address := l.Addr().String()
+
+	// in use
+	require.NotNil(t, checkPreflightListen(address))
+
+	// released for the server
+	require.Nil(t, l.Close())
+	require.Nil(t, checkPreflightListen(address))
+	require.Nil(t, checkPreflightListen(address))
+}
@@ -3,12 +3,17 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/apex/log"
 	config2 "github.com/crawlab-team/crawlab-core/config"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/fs"
 	"github.com/crawlab-team/crawlab-core/grpc/client"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/node/config"
+	"github.com/crawlab-team/crawlab-core/paths"
 	"github.com/crawlab-team/crawlab-core/task/handler"
 	"github.com/crawlab-team/crawlab-core/utils"
 	grpc "github.com/crawlab-team/crawlab-grpc"
@@ -30,8 +35,9 @@ type WorkerService struct {
 	heartbeatInterval time.Duration
 
 	// internals
-	n interfaces.Node
-	s grpc.NodeService_SubscribeClient
+	n              interfaces.Node
+	s              grpc.NodeService_SubscribeClient
+	streamHandlers map[grpc.StreamMessageCode]streamMessageHandler
 }
 
 func (svc *WorkerService) Init() (err error) {
@@ -40,6 +46,18 @@ func (svc *WorkerService) Init() (err error) {
 }
 
 func (svc *WorkerService) Start() {
+	// preflight (if enabled), aborting the start if any check fails
+	if isPreflightEnabled() {
+		if err := svc.preflight(); err != nil {
+			log.Fatalf("worker[%s] %v", svc.GetConfigService().GetNodeKey(), err)
+		}
+	}
+
+	// check data directories
+	if err := paths.CheckDataPaths(); err != nil {
+		panic(err)
+	}
+
 	// start grpc client
 	if err := svc.client.Start(); err != nil {
 		panic(err)
@@ -48,6 +66,14 @@ func (svc *WorkerService) Start() {
 	// register to master
 	svc.Register()
 
+	// identify the node in sync progress reports
+	fs.SetSyncNodeKey(svc.GetConfigService().GetNodeKey())
+
+	// sync spider files possibly changed while disconnected from master
+	if fs.GetSyncStrategy() == constants.SyncStrategyEager {
+		go fs.SyncAllSpiderFiles()
+	}
+
 	// start receiving stream messages
 	go svc.Recv()
 
@@ -76,7 +102,12 @@ func (svc *WorkerService) Stop() {
 func (svc *WorkerService) Register() {
 	ctx, cancel := svc.client.Context()
 	defer cancel()
-	req := svc.client.NewRequest(svc.GetConfigService().GetBasicNodeInfo())
+	nodeInfo := svc.GetConfigService().GetBasicNodeInfo()
+	if info, ok := nodeInfo.(*entity.NodeInfo); ok {
+		// capability manifest
+		info.Capabilities = utils.GetNodeCapabilities()
+	}
+	req := svc.client.NewRequest(nodeInfo)
 	res, err := svc.client.GetNodeClient().Register(ctx, req)
 	if err != nil {
 		panic(err)
@@ -106,31 +137,98 @@ func (svc *WorkerService) Recv() {
 	}
 }
 
+// streamMessageHandler handles a stream message of a code sent by master
+type streamMessageHandler func(msg *grpc.StreamMessage) (err error)
+
+// registerStreamMessageHandlers sets the handlers of stream message codes
+// understood by the worker
+func (svc *WorkerService) registerStreamMessageHandlers() {
+	svc.streamHandlers = map[grpc.StreamMessageCode]streamMessageHandler{
+		grpc.StreamMessageCode_PING:        svc.handlePing,
+		grpc.StreamMessageCode_RUN_TASK:    svc.handleRunTask,
+		grpc.StreamMessageCode_CANCEL_TASK: svc.handleCancelTask,
+
+		grpc.StreamMessageCode(constants.GrpcStreamMessageCodeConfigUpdate): svc.handleConfigUpdate,
+		grpc.StreamMessageCode(constants.GrpcStreamMessageCodeSyncSpider):   svc.handleSyncSpider,
+	}
+}
+
+// handleStreamMessage dispatches the message to the handler of its code.
+// Messages of unknown codes, e.g. sent by a newer master, are logged and
+// ignored so that version skew does not break the stream.
 func (svc *WorkerService) handleStreamMessage(msg *grpc.StreamMessage) (err error) {
 	log.Debugf("[WorkerService] handle msg: %v", msg)
-	switch msg.Code {
-	case grpc.StreamMessageCode_PING:
-		if _, err := svc.client.GetNodeClient().SendHeartbeat(context.Background(), svc.client.NewRequest(svc.cfgSvc.GetBasicNodeInfo())); err != nil {
-			return trace.TraceError(err)
-		}
-	case grpc.StreamMessageCode_RUN_TASK:
-		var t models.Task
-		if err := json.Unmarshal(msg.Data, &t); err != nil {
-			return trace.TraceError(err)
-		}
-		if err := svc.handlerSvc.Run(t.Id); err != nil {
-			return trace.TraceError(err)
-		}
-	case grpc.StreamMessageCode_CANCEL_TASK:
-		var t models.Task
-		if err := json.Unmarshal(msg.Data, &t); err != nil {
-			return trace.TraceError(err)
+	handler, ok := svc.streamHandlers[msg.Code]
+	if !ok {
+		log.Warnf("[WorkerService] ignored stream message of unknown code: %d", msg.Code)
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = trace.TraceError(fmt.Errorf("panic handling stream message of code %d: %v", msg.Code, r))
 		}
-		if err := svc.handlerSvc.Cancel(t.Id); err != nil {
-			return trace.TraceError(err)
+	}()
+	return handler(msg)
+}
+
+func (svc *WorkerService) handlePing(msg *grpc.StreamMessage) (err error) {
+	if _, err := svc.client.GetNodeClient().SendHeartbeat(context.Background(), svc.client.NewRequest(svc.cfgSvc.GetBasicNodeInfo())); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func (svc *WorkerService) handleRunTask(msg *grpc.StreamMessage) (err error) {
+	var t models.Task
+	if err := json.Unmarshal(msg.Data, &t); err != nil {
+		return trace.TraceError(err)
+	}
+	if err := svc.handlerSvc.Run(t.Id); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// handleCancelTask cancels the task in the background, since a soft cancel
+// waits for the task to exit, which would block receiving stream messages
+func (svc *WorkerService) handleCancelTask(msg *grpc.StreamMessage) (err error) {
+	var t models.Task
+	if err := json.Unmarshal(msg.Data, &t); err != nil {
+		return trace.TraceError(err)
+	}
+	go func() {
+		if err := svc.handlerSvc.CancelWithMode(t.Id, t.CancelMode); err != nil {
+			trace.PrintError(err)
 		}
+	}()
+	return nil
+}
+
+func (svc *WorkerService) handleSyncSpider(msg *grpc.StreamMessage) (err error) {
+	var m entity.SpiderSyncMessage
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		return trace.TraceError(err)
+	}
+	if m.SpiderId.IsZero() {
+		return nil
 	}
+	go func() {
+		if err := fs.SyncSpiderFiles(m.SpiderId); err != nil {
+			trace.PrintError(err)
+		}
+	}()
+	return nil
+}
 
+// handleConfigUpdate applies runtime settings pushed by master. Settings not
+// supported at runtime are ignored.
+func (svc *WorkerService) handleConfigUpdate(msg *grpc.StreamMessage) (err error) {
+	var m entity.ConfigUpdateMessage
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		return trace.TraceError(err)
+	}
+	applied := config2.ApplyRuntimeConfig(m.Settings)
+	log.Infof("[WorkerService] applied runtime config: %v", applied)
 	return nil
 }
 
@@ -190,6 +288,7 @@ func NewWorkerService(opts ...Option) (res *WorkerService, err error) {
 		heartbeatInterval: 15 * time.Second,
 		n:                 &models.Node{},
 	}
+	svc.registerStreamMessageHandlers()
 
 	// apply options
 	for _, opt := range opts {
@@ -2,6 +2,7 @@ package test
 
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -65,3 +66,29 @@ func TestNodeServices_Monitor(t *testing.T) {
 
 	stopMasterWorkerMonitor()
 }
+
+func TestNodeServices_Monitor_Cancelled(t *testing.T) {
+	T, _ = NewTest()
+	T.Setup(t)
+	startMasterWorker()
+
+	// stop master, which cancels monitor cycles
+	T.MasterSvc.Stop()
+	time.Sleep(1 * time.Second)
+
+	// run a monitor cycle after cancellation
+	summary, err := T.MasterSvc.RunMonitorOnce()
+	require.Equal(t, errors.ErrorNodeMonitorCancelled, err)
+	require.True(t, summary.Cancelled)
+	require.Equal(t, 0, summary.NodesChecked)
+	require.Equal(t, 1, summary.NodesSkipped)
+
+	// validate worker not set offline
+	workerNodeKey := T.WorkerSvc.GetConfigService().GetNodeKey()
+	workerNode, err := T.ModelSvc.GetNodeByKey(workerNodeKey, nil)
+	require.Nil(t, err)
+	require.Equal(t, constants.NodeStatusOnline, workerNode.Status)
+
+	go T.WorkerSvc.Stop()
+	time.Sleep(1 * time.Second)
+}
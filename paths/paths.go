@@ -0,0 +1,129 @@
+package paths
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/go-trace"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+// All files written by a node, e.g. spider workspaces, task logs, blobs and
+// temporary files, are put under the data root configured by "data.path"
+// (env CRAWLAB_DATA_PATH). Paths are composed with filepath.Join so that they
+// are valid on both Windows and Linux. If the data root is not configured,
+// the legacy locations are used, i.e. the home directory for workspaces and
+// the system temporary directory for temporary files. Each location can also
+// be configured individually, e.g. "workspace" or "log.path".
+
+// DirPerm is the permission of data directories created on demand
+const DirPerm = 0755
+
+const (
+	workspaceDirName = "workspace"
+	logsDirName      = "logs"
+	blobsDirName     = "blobs"
+	tmpDirName       = "tmp"
+)
+
+// GetHomePath returns the home directory of the current user
+func GetHomePath() (p string) {
+	p, err := homedir.Dir()
+	if err != nil {
+		log.Warnf("cannot find home directory: %v", err)
+		return ""
+	}
+	return p
+}
+
+// GetDataRoot returns the absolute path of the data root, or an empty string
+// if it is not configured
+func GetDataRoot() (root string) {
+	return getAbsPath(viper.GetString("data.path"))
+}
+
+// GetWorkspacePath returns the root directory of spider workspaces, which is
+// configured by "workspace", <data root>/workspace by default
+func GetWorkspacePath() (p string) {
+	if p = viper.GetString("workspace"); p != "" {
+		return getAbsPath(p)
+	}
+	if root := GetDataRoot(); root != "" {
+		return filepath.Join(root, workspaceDirName)
+	}
+	return getAbsPath(filepath.Join(GetHomePath(), "crawlab_workspace"))
+}
+
+// GetLogPath returns the directory of task logs, which is configured by
+// "log.path", <data root>/logs by default
+func GetLogPath() (p string) {
+	if p = viper.GetString("log.path"); p != "" {
+		return p
+	}
+	if root := GetDataRoot(); root != "" {
+		return filepath.Join(root, logsDirName)
+	}
+	return ""
+}
+
+// GetBlobPath returns the root directory of the local blob store, which is
+// configured by "blob.local.path", <data root>/blobs by default
+func GetBlobPath() (p string) {
+	if p = viper.GetString("blob.local.path"); p != "" {
+		return getAbsPath(p)
+	}
+	if root := GetDataRoot(); root != "" {
+		return filepath.Join(root, blobsDirName)
+	}
+	return filepath.Join(filepath.Dir(GetWorkspacePath()), "crawlab_blobs")
+}
+
+// GetTempPath returns the path of the elements in the temporary directory,
+// i.e. <data root>/tmp, or the system temporary directory by default
+func GetTempPath(elem ...string) (p string) {
+	root := os.TempDir()
+	if dataRoot := GetDataRoot(); dataRoot != "" {
+		root = filepath.Join(dataRoot, tmpDirName)
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// EnsureWritable creates the directory if not exists and checks that files
+// can be written in it, or returns errors.ErrorFsNotWritable
+func EnsureWritable(dir string) (err error) {
+	if err := os.MkdirAll(dir, DirPerm); err != nil {
+		log.Errorf("cannot create directory %s: %v", dir, err)
+		return trace.TraceError(errors.ErrorFsNotWritable)
+	}
+	f, err := os.CreateTemp(dir, ".write_test_*")
+	if err != nil {
+		log.Errorf("cannot write to directory %s: %v", dir, err)
+		return trace.TraceError(errors.ErrorFsNotWritable)
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return nil
+}
+
+// CheckDataPaths ensures the data root, if configured, and the workspace are
+// writable. It is called when a node starts.
+func CheckDataPaths() (err error) {
+	if root := GetDataRoot(); root != "" {
+		if err := EnsureWritable(root); err != nil {
+			return err
+		}
+	}
+	return EnsureWritable(GetWorkspacePath())
+}
+
+func getAbsPath(p string) (absPath string) {
+	if p == "" {
+		return ""
+	}
+	if absPath, err := filepath.Abs(p); err == nil {
+		return absPath
+	}
+	return p
+}
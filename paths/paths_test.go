@@ -0,0 +1,32 @@
+package paths
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataRoot(t *testing.T) {
+	root := t.TempDir()
+	viper.Set("data.path", root)
+	defer viper.Set("data.path", "")
+
+	require.Equal(t, filepath.Join(root, "workspace"), GetWorkspacePath())
+	require.Equal(t, filepath.Join(root, "logs"), GetLogPath())
+	require.Equal(t, filepath.Join(root, "blobs"), GetBlobPath())
+	require.Equal(t, filepath.Join(root, "tmp", "export", "csv"), GetTempPath("export", "csv"))
+	require.Nil(t, CheckDataPaths())
+
+	// individually configured
+	workspace := filepath.Join(t.TempDir(), "ws")
+	viper.Set("workspace", workspace)
+	defer viper.Set("workspace", "")
+	require.Equal(t, workspace, GetWorkspacePath())
+}
+
+func TestDataRoot_Default(t *testing.T) {
+	require.Equal(t, "", GetDataRoot())
+	require.Equal(t, filepath.Join(os.TempDir(), "export"), GetTempPath("export"))
+}
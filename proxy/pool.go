@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool selects proxies passed to task processes, either from a static list
+// rotated by a strategy, or from a provider which returns a proxy per request
+type Pool struct {
+	proxies     []string
+	strategy    string
+	providerUrl string
+	client      *http.Client
+	next        uint64 // index of the next proxy of round robin (atomic)
+}
+
+// Enabled returns whether there are proxies to select from
+func (p *Pool) Enabled() (ok bool) {
+	return len(p.proxies) > 0 || p.providerUrl != ""
+}
+
+// Get returns a proxy for a task
+func (p *Pool) Get() (proxy string, err error) {
+	if p.providerUrl != "" {
+		return p.getFromProvider()
+	}
+	if len(p.proxies) == 0 {
+		return "", trace.TraceError(errors.ErrorProxyEmptyPool)
+	}
+	switch p.strategy {
+	case constants.ProxyStrategyRandom:
+		return p.proxies[rand.Intn(len(p.proxies))], nil
+	case constants.ProxyStrategyRoundRobin, "":
+		i := atomic.AddUint64(&p.next, 1) - 1
+		return p.proxies[i%uint64(len(p.proxies))], nil
+	default:
+		return "", trace.TraceError(errors.ErrorProxyInvalidStrategy)
+	}
+}
+
+// getFromProvider requests a proxy from the provider, whose response body is
+// the proxy address in plain text
+func (p *Pool) getFromProvider() (proxy string, err error) {
+	res, err := p.client.Get(p.providerUrl)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", trace.TraceError(errors.NewProxyError(fmt.Sprintf("provider responded with status %d", res.StatusCode)))
+	}
+	proxy = strings.TrimSpace(string(data))
+	if proxy == "" {
+		return "", trace.TraceError(errors.ErrorProxyEmptyPool)
+	}
+	return proxy, nil
+}
+
+// Redact returns the proxy with the password replaced, which can be recorded
+// on tasks for debugging. Proxies without scheme, e.g. user:pass@host:port,
+// are parsed as http proxies.
+func Redact(proxy string) (res string) {
+	if !strings.Contains(proxy, "://") {
+		return strings.TrimPrefix(Redact("http://"+proxy), "http://")
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+func NewPool(proxies []string, strategy, providerUrl string) (p *Pool, err error) {
+	switch strategy {
+	case constants.ProxyStrategyRoundRobin, constants.ProxyStrategyRandom, "":
+	default:
+		return nil, trace.TraceError(errors.ErrorProxyInvalidStrategy)
+	}
+	p = &Pool{
+		strategy:    strategy,
+		providerUrl: providerUrl,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, proxy := range proxies {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			p.proxies = append(p.proxies, proxy)
+		}
+	}
+	return p, nil
+}
+
+var _pool = &Pool{}
+var _poolConfig string
+var _poolMu sync.Mutex
+
+// GetPool returns the proxy pool of the node configured by "proxy.pool" (list
+// of proxies), "proxy.strategy" (round_robin by default, or random) and
+// "proxy.providerUrl", which takes precedence over the list. The pool is
+// reloaded once the config changes, e.g. by a CONFIG_UPDATE pushed from
+// master. Invalid config is logged and the previous pool is kept.
+func GetPool() (p *Pool) {
+	proxies := getPoolProxies()
+	strategy := viper.GetString("proxy.strategy")
+	providerUrl := viper.GetString("proxy.providerUrl")
+	cfg := strings.Join([]string{strings.Join(proxies, ","), strategy, providerUrl}, "|")
+
+	_poolMu.Lock()
+	defer _poolMu.Unlock()
+	if cfg == _poolConfig {
+		return _pool
+	}
+	_poolConfig = cfg
+	p, err := NewPool(proxies, strategy, providerUrl)
+	if err != nil {
+		trace.PrintError(err)
+		return _pool
+	}
+	_pool = p
+	return _pool
+}
+
+// getPoolProxies returns the proxies of "proxy.pool", which is either a list
+// or comma-separated, e.g. if set by a CONFIG_UPDATE
+func getPoolProxies() (proxies []string) {
+	for _, item := range viper.GetStringSlice("proxy.pool") {
+		proxies = append(proxies, strings.Split(item, ",")...)
+	}
+	return proxies
+}
@@ -0,0 +1,333 @@
+package result
+
+import (
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// Result records of high-throughput spiders are buffered per task by the
+// Batcher, if enabled by "result.batch.enabled" (default false), and inserted
+// in batches of up to "result.batch.size" records (default 500), once a batch
+// is full or its oldest record has waited "result.batch.interval" (ms, 1000
+// by default). At most "result.batch.concurrency" batches (default 4) are
+// inserted at the same time. Records of a task are flushed when the task
+// ends, and records of all tasks when the batcher stops. Batches failed to
+// insert are returned to the buffers of their tasks, to be inserted again
+// after the batch interval. If "result.batch.maxPending" records (default
+// 50000) are buffered, records are inserted synchronously, slowing down the
+// senders until the batches catch up, and errors of the inserts are returned
+// to the senders instead.
+
+const (
+	DefaultBatchSize        = 500
+	DefaultBatchInterval    = time.Second
+	DefaultBatchConcurrency = 4
+	DefaultBatchMaxPending  = 50000
+)
+
+// IsBatchEnabled returns whether result records are inserted in batches
+func IsBatchEnabled() (ok bool) {
+	return viper.GetBool("result.batch.enabled")
+}
+
+// BatchStats are the ingestion stats of result batches
+type BatchStats struct {
+	Pending        int     `json:"pending"`          // records buffered and not yet inserted
+	PendingTasks   int     `json:"pending_tasks"`    // tasks with buffered records
+	Lag            float64 `json:"lag"`              // wait of the oldest buffered record (seconds)
+	Inserting      int     `json:"inserting"`        // batches being inserted
+	Batches        int64   `json:"batches"`          // batches inserted
+	Records        int64   `json:"records"`          // records inserted in batches
+	Errors         int64   `json:"errors"`           // batches failed to insert
+	LastInsertTime float64 `json:"last_insert_time"` // duration of the last batch insert (seconds)
+}
+
+// BatchInsertFunc inserts the records of the task
+type BatchInsertFunc func(id primitive.ObjectID, records []interface{}) (err error)
+
+type batchBuffer struct {
+	records []interface{}
+	ts      time.Time // time the oldest record was buffered
+}
+
+// Batcher buffers records of tasks and inserts them in batches by insert
+type Batcher struct {
+	// settings
+	size       int
+	interval   time.Duration
+	maxPending int
+
+	// internals
+	insert  BatchInsertFunc
+	sem     chan struct{} // slots of concurrent inserts
+	buffers map[primitive.ObjectID]*batchBuffer
+	pending int
+	stats   BatchStats
+	mu      sync.Mutex
+	stopped chan struct{}
+	done    chan struct{} // closed when batches are no longer flushed by interval
+}
+
+// Add buffers the records of the task, inserting a batch in the background
+// when it is full
+func (b *Batcher) Add(id primitive.ObjectID, records ...interface{}) (err error) {
+	if len(records) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	buf, ok := b.buffers[id]
+	if !ok {
+		buf = &batchBuffer{ts: time.Now()}
+		b.buffers[id] = buf
+	}
+	buf.records = append(buf.records, records...)
+	b.pending += len(records)
+	overflow := b.pending >= b.maxPending
+	var batches [][]interface{}
+	if len(buf.records) >= b.size || overflow {
+		batches = b.take(id)
+	}
+	b.mu.Unlock()
+
+	if overflow {
+		// back pressure
+		return b.insertBatches(id, batches, false)
+	}
+	for _, batch := range batches {
+		b.sem <- struct{}{}
+		go func(batch []interface{}) {
+			defer func() { <-b.sem }()
+			if err := b.insertBatch(id, batch); err != nil {
+				trace.PrintError(err)
+				b.requeue(id, batch)
+			}
+		}(batch)
+	}
+	return nil
+}
+
+// Flush inserts the buffered records of the task, e.g. when it ends. Batches
+// failed to insert are returned to the buffer of the task with the error.
+func (b *Batcher) Flush(id primitive.ObjectID) (err error) {
+	b.mu.Lock()
+	batches := b.take(id)
+	b.mu.Unlock()
+	return b.insertBatches(id, batches, true)
+}
+
+// GetStats returns the ingestion stats of the batcher
+func (b *Batcher) GetStats() (stats BatchStats) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats = b.stats
+	stats.Pending = b.pending
+	stats.PendingTasks = len(b.buffers)
+	stats.Inserting = len(b.sem)
+	now := time.Now()
+	for _, buf := range b.buffers {
+		if lag := now.Sub(buf.ts).Seconds(); lag > stats.Lag {
+			stats.Lag = lag
+		}
+	}
+	return stats
+}
+
+// Stop stops flushing batches by interval, and inserts the buffered records
+// of all tasks once the batches being inserted are done, returning the first
+// error
+func (b *Batcher) Stop() (err error) {
+	close(b.stopped)
+	<-b.done
+	defer batchers.Delete(b)
+
+	// wait for batches being inserted, which may return to the buffers
+	for i := 0; i < cap(b.sem); i++ {
+		b.sem <- struct{}{}
+	}
+	for i := 0; i < cap(b.sem); i++ {
+		<-b.sem
+	}
+
+	b.mu.Lock()
+	all := map[primitive.ObjectID][][]interface{}{}
+	for id := range b.buffers {
+		all[id] = b.take(id)
+	}
+	b.mu.Unlock()
+	for id, batches := range all {
+		if e := b.insertBatches(id, batches, false); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// take removes the buffered records of the task and returns them in batches
+// of the batch size. It is called with the lock held.
+func (b *Batcher) take(id primitive.ObjectID) (batches [][]interface{}) {
+	buf, ok := b.buffers[id]
+	if !ok {
+		return nil
+	}
+	delete(b.buffers, id)
+	b.pending -= len(buf.records)
+	for i := 0; i < len(buf.records); i += b.size {
+		j := i + b.size
+		if j > len(buf.records) {
+			j = len(buf.records)
+		}
+		batches = append(batches, buf.records[i:j])
+	}
+	return batches
+}
+
+// insertBatches inserts the batches synchronously, returning the first error.
+// Failed batches are returned to the buffer of the task if requeue.
+func (b *Batcher) insertBatches(id primitive.ObjectID, batches [][]interface{}, requeue bool) (err error) {
+	for _, batch := range batches {
+		b.sem <- struct{}{}
+		e := b.insertBatch(id, batch)
+		<-b.sem
+		if e == nil {
+			continue
+		}
+		if requeue {
+			b.requeue(id, batch)
+		}
+		if err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// requeue returns the failed batch to the front of the buffer of the task, to
+// be inserted again after the batch interval
+func (b *Batcher) requeue(id primitive.ObjectID, batch []interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	buf, ok := b.buffers[id]
+	if !ok {
+		buf = &batchBuffer{}
+		b.buffers[id] = buf
+	}
+	buf.records = append(append([]interface{}{}, batch...), buf.records...)
+	buf.ts = time.Now()
+	b.pending += len(batch)
+}
+
+func (b *Batcher) insertBatch(id primitive.ObjectID, batch []interface{}) (err error) {
+	start := time.Now()
+	err = b.insert(id, batch)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.LastInsertTime = time.Since(start).Seconds()
+	if err != nil {
+		b.stats.Errors++
+		return err
+	}
+	b.stats.Batches++
+	b.stats.Records += int64(len(batch))
+	return nil
+}
+
+// flushExpired inserts batches of tasks whose oldest record has waited for
+// the batch interval
+func (b *Batcher) flushExpired() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopped:
+			return
+		case <-ticker.C:
+		}
+		b.mu.Lock()
+		expired := map[primitive.ObjectID][][]interface{}{}
+		for id, buf := range b.buffers {
+			if time.Since(buf.ts) >= b.interval {
+				expired[id] = b.take(id)
+			}
+		}
+		b.mu.Unlock()
+		for id, batches := range expired {
+			if err := b.insertBatches(id, batches, true); err != nil {
+				trace.PrintError(err)
+			}
+		}
+	}
+}
+
+// NewBatcher returns the batcher inserting records by insert, with settings
+// of "result.batch"
+func NewBatcher(insert BatchInsertFunc) (b *Batcher) {
+	b = &Batcher{
+		size:       DefaultBatchSize,
+		interval:   DefaultBatchInterval,
+		maxPending: DefaultBatchMaxPending,
+		insert:     insert,
+		buffers:    map[primitive.ObjectID]*batchBuffer{},
+		stopped:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if n := viper.GetInt("result.batch.size"); n > 0 {
+		b.size = n
+	}
+	if ms := viper.GetInt("result.batch.interval"); ms > 0 {
+		b.interval = time.Duration(ms) * time.Millisecond
+	}
+	if n := viper.GetInt("result.batch.maxPending"); n > 0 {
+		b.maxPending = n
+	}
+	concurrency := DefaultBatchConcurrency
+	if n := viper.GetInt("result.batch.concurrency"); n > 0 {
+		concurrency = n
+	}
+	b.sem = make(chan struct{}, concurrency)
+	go b.flushExpired()
+	batchers.Store(b, true)
+	return b
+}
+
+// batchers are the running batchers, whose stats are summed up by
+// GetBatchStats
+var batchers sync.Map
+
+// StopBatchers stops all running batchers, inserting their buffered records,
+// e.g. on shutdown
+func StopBatchers() {
+	batchers.Range(func(key, value interface{}) bool {
+		if err := key.(*Batcher).Stop(); err != nil {
+			trace.PrintError(err)
+		}
+		return true
+	})
+}
+
+// GetBatchStats returns the ingestion stats of all running batchers, with
+// the max lag among them, and false if there is none
+func GetBatchStats() (stats BatchStats, ok bool) {
+	batchers.Range(func(key, value interface{}) bool {
+		ok = true
+		s := key.(*Batcher).GetStats()
+		stats.Pending += s.Pending
+		stats.PendingTasks += s.PendingTasks
+		stats.Inserting += s.Inserting
+		stats.Batches += s.Batches
+		stats.Records += s.Records
+		stats.Errors += s.Errors
+		if s.Lag > stats.Lag {
+			stats.Lag = s.Lag
+		}
+		if s.LastInsertTime > stats.LastInsertTime {
+			stats.LastInsertTime = s.LastInsertTime
+		}
+		return true
+	})
+	return stats, ok
+}
@@ -0,0 +1,122 @@
+package result
+
+import (
+	"errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	viper.Set("result.batch.size", 3)
+	viper.Set("result.batch.interval", 60000)
+	defer viper.Set("result.batch.size", nil)
+	defer viper.Set("result.batch.interval", nil)
+
+	var mu sync.Mutex
+	inserted := map[primitive.ObjectID][]int{}
+	b := NewBatcher(func(id primitive.ObjectID, records []interface{}) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		inserted[id] = append(inserted[id], len(records))
+		return nil
+	})
+	defer b.Stop()
+
+	// batch inserted when full
+	id := primitive.NewObjectID()
+	require.Nil(t, b.Add(id, 1, 2))
+	require.Equal(t, 2, b.GetStats().Pending)
+	require.Nil(t, b.Add(id, 3, 4))
+	require.Eventually(t, func() bool {
+		return b.GetStats().Batches == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, b.GetStats().Pending)
+
+	// remaining records inserted on flush
+	require.Nil(t, b.Add(id, 5))
+	require.Equal(t, 1, b.GetStats().PendingTasks)
+	require.Nil(t, b.Flush(id))
+	stats := b.GetStats()
+	require.Equal(t, 0, stats.PendingTasks)
+	require.Equal(t, int64(5), stats.Records)
+	mu.Lock()
+	require.ElementsMatch(t, []int{3, 1, 1}, inserted[id])
+	mu.Unlock()
+
+	// stats of running batchers
+	_, ok := GetBatchStats()
+	require.True(t, ok)
+}
+
+func TestBatcher_Retry(t *testing.T) {
+	viper.Set("result.batch.size", 2)
+	viper.Set("result.batch.interval", 300)
+	defer viper.Set("result.batch.size", nil)
+	defer viper.Set("result.batch.interval", nil)
+
+	var mu sync.Mutex
+	fail := true
+	var inserted []interface{}
+	b := NewBatcher(func(id primitive.ObjectID, records []interface{}) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New("unavailable")
+		}
+		inserted = append(inserted, records...)
+		return nil
+	})
+	defer b.Stop()
+
+	// failed batches returned to the buffer of the task
+	id := primitive.NewObjectID()
+	require.Nil(t, b.Add(id, 1, 2))
+	require.Eventually(t, func() bool {
+		return b.GetStats().Errors == 1 && b.GetStats().Pending == 2
+	}, time.Second, 10*time.Millisecond)
+	require.NotNil(t, b.Flush(id))
+	require.Equal(t, int64(2), b.GetStats().Errors)
+	require.Equal(t, 2, b.GetStats().Pending)
+
+	// inserted again after the batch interval
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	require.Eventually(t, func() bool {
+		return b.GetStats().Pending == 0
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	require.Equal(t, []interface{}{1, 2}, inserted)
+	mu.Unlock()
+}
+
+func TestBatcher_Stop(t *testing.T) {
+	viper.Set("result.batch.interval", 60000)
+	defer viper.Set("result.batch.interval", nil)
+
+	var mu sync.Mutex
+	inserted := map[primitive.ObjectID]int{}
+	b := NewBatcher(func(id primitive.ObjectID, records []interface{}) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		inserted[id] += len(records)
+		return nil
+	})
+
+	// buffered records of all tasks inserted on stop
+	id1 := primitive.NewObjectID()
+	id2 := primitive.NewObjectID()
+	require.Nil(t, b.Add(id1, 1, 2))
+	require.Nil(t, b.Add(id2, 3))
+	require.Nil(t, b.Stop())
+	require.Equal(t, map[primitive.ObjectID]int{id1: 2, id2: 1}, inserted)
+	require.Equal(t, 0, b.GetStats().Pending)
+
+	// no longer running
+	_, ok := GetBatchStats()
+	require.False(t, ok)
+}
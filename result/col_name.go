@@ -0,0 +1,139 @@
+package result
+
+import (
+	"context"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"regexp"
+	"strings"
+)
+
+// Results of a spider are stored in its result collection, i.e. the data
+// collection of the spider. The name is specified per spider, defaulting to
+// "results_<spider id>", and may be shared by related spiders. Names of
+// collections used by crawlab itself are not allowed.
+
+// colNameMaxLength is the max length of result collection names, which keeps
+// the namespace (<db>.<collection>) within the limit of MongoDB
+const colNameMaxLength = 100
+
+var colNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+([.\-][A-Za-z0-9_]+)*$`)
+
+// reservedColNames are names of collections used by crawlab
+var reservedColNames = map[string]bool{
+	interfaces.ModelColNameArtifact:          true,
+	interfaces.ModelColNameTag:               true,
+	interfaces.ModelColNameNode:              true,
+	interfaces.ModelColNameProject:           true,
+	interfaces.ModelColNameSpider:            true,
+	interfaces.ModelColNameTask:              true,
+	interfaces.ModelColNameJob:               true,
+	interfaces.ModelColNameSchedule:          true,
+	interfaces.ModelColNameUser:              true,
+	interfaces.ModelColNameSetting:           true,
+	interfaces.ModelColNameToken:             true,
+	interfaces.ModelColNameVariable:          true,
+	interfaces.ModelColNameTaskQueue:         true,
+	interfaces.ModelColNameTaskStat:          true,
+	interfaces.ModelColNameSpiderStat:        true,
+	interfaces.ModelColNameDataSource:        true,
+	interfaces.ModelColNameDataCollection:    true,
+	interfaces.ModelColNamePasswords:         true,
+	interfaces.ModelColNameExtraValues:       true,
+	interfaces.ModelColNameGit:               true,
+	interfaces.ModelColNameRole:              true,
+	interfaces.ModelColNameUserRole:          true,
+	interfaces.ModelColNamePermission:        true,
+	interfaces.ModelColNameRolePermission:    true,
+	interfaces.ModelColNameEnvironment:       true,
+	interfaces.ModelColNameDependencySetting: true,
+	interfaces.ModelColNameTaskEvent:         true,
+	constants.RefreshTokenColName:            true,
+	constants.MigrationColName:               true,
+	constants.JobColName:                     true,
+	constants.TaskIdempotencyKeyColName:      true,
+	constants.TaskArtifactColName:            true,
+	constants.SpiderInputColName:             true,
+	constants.TaskLockColName:                true,
+	constants.CacheColName:                   true,
+	constants.AuditLogColName:                true,
+}
+
+// GetDefaultColName returns the name of the result collection of the spider
+// if not specified
+func GetDefaultColName(spiderId primitive.ObjectID) (name string) {
+	return constants.ResultColNamePrefix + spiderId.Hex()
+}
+
+// ValidateColName checks the name of a result collection, which consists of
+// letters, digits and "_", optionally separated by "." or "-", and is not
+// the name of a collection used by crawlab
+func ValidateColName(name string) (errs errors.ValidationErrors) {
+	switch {
+	case name == "":
+		return nil
+	case len(name) > colNameMaxLength:
+		errs = append(errs, errors.ValidationError{Field: "col_name", Message: fmt.Sprintf("collection name must not exceed %d characters", colNameMaxLength)})
+	case !colNameRegexp.MatchString(name):
+		errs = append(errs, errors.ValidationError{Field: "col_name", Message: fmt.Sprintf("invalid collection name %s", name)})
+	case strings.HasPrefix(name, "system.") || reservedColNames[name]:
+		errs = append(errs, errors.ValidationError{Field: "col_name", Message: fmt.Sprintf("collection name %s is reserved", name)})
+	}
+	return errs
+}
+
+// GetOrCreateDataCollection returns the data collection of the name, which is
+// added along with indexes of the collection if not exists. It returns
+// errors.ErrorResultColNameCollision if a collection of the name exists but
+// is not a data collection. args may include the user.
+func GetOrCreateDataCollection(name string, args ...interface{}) (dc *models.DataCollection, err error) {
+	modelSvc, err := service.GetService()
+	if err != nil {
+		return nil, err
+	}
+
+	// existing
+	dc, err = modelSvc.GetDataCollectionByName(name, nil)
+	if err == nil {
+		return dc, nil
+	}
+	if err != mongo2.ErrNoDocuments {
+		return nil, err
+	}
+
+	// collision
+	if errs := ValidateColName(name); len(errs) > 0 {
+		return nil, errs
+	}
+	db := mongo.GetMongoDb("")
+	names, err := db.ListCollectionNames(context.Background(), bson.M{"name": name})
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if len(names) > 0 {
+		return nil, trace.TraceError(errors.ErrorResultColNameCollision)
+	}
+
+	// add
+	dc = &models.DataCollection{Name: name}
+	if err := delegate.NewModelDelegate(dc, args...).Add(); err != nil {
+		return nil, err
+	}
+
+	// indexes
+	col := mongo.GetMongoCol(dc.Name)
+	_ = col.CreateIndex(mongo2.IndexModel{Keys: bson.M{constants.TaskKey: 1}})
+	_ = col.CreateIndex(mongo2.IndexModel{Keys: bson.M{constants.HashKey: 1}})
+
+	return dc, nil
+}
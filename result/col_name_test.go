@@ -0,0 +1,19 @@
+package result
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"testing"
+)
+
+func TestValidateColName(t *testing.T) {
+	id := primitive.NewObjectID()
+	for _, name := range []string{"", "results", "results_shop", "shop.items", "shop-items_2", GetDefaultColName(id)} {
+		require.Empty(t, ValidateColName(name), name)
+	}
+	for _, name := range []string{"a b", "items$", ".items", "items.", "a..b", "system.profile", interfaces.ModelColNameTask, strings.Repeat("a", colNameMaxLength+1)} {
+		require.Len(t, ValidateColName(name), 1, name)
+	}
+}
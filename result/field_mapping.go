@@ -0,0 +1,194 @@
+package result
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValidateFieldMappings checks field mappings of a spider. System fields,
+// i.e. those prefixed with "_", can be neither mapped nor overwritten.
+func ValidateFieldMappings(mappings []entity.ResultFieldMapping) (errs errors.ValidationErrors) {
+	for i, m := range mappings {
+		prefix := fmt.Sprintf("field_mappings.%d", i)
+		if m.Field == "" {
+			errs = append(errs, errors.ValidationError{Field: prefix + ".field", Message: "field is required"})
+		} else if strings.HasPrefix(m.Field, "_") {
+			errs = append(errs, errors.ValidationError{Field: prefix + ".field", Message: fmt.Sprintf("system field %s cannot be mapped", m.Field)})
+		}
+		if strings.HasPrefix(m.Target, "_") {
+			errs = append(errs, errors.ValidationError{Field: prefix + ".target", Message: fmt.Sprintf("system field %s cannot be overwritten", m.Target)})
+		}
+		switch m.Type {
+		case "",
+			constants.ResultFieldTypeString,
+			constants.ResultFieldTypeInt,
+			constants.ResultFieldTypeFloat,
+			constants.ResultFieldTypeBool:
+		default:
+			errs = append(errs, errors.ValidationError{Field: prefix + ".type", Message: fmt.Sprintf("invalid type %s", m.Type)})
+		}
+	}
+	return errs
+}
+
+// ApplyFieldMappings returns a copy of the result with the field mappings
+// applied in order. Values that cannot be coerced are kept as they are and
+// reported in the returned error, so that no data is lost.
+func ApplyFieldMappings(r entity.Result, mappings []entity.ResultFieldMapping) (res entity.Result, err error) {
+	res = entity.Result{}
+	for k, v := range r {
+		res[k] = v
+	}
+
+	var msgs []string
+	for _, m := range mappings {
+		v, ok := res[m.Field]
+		if !ok {
+			continue
+		}
+		if m.Drop {
+			delete(res, m.Field)
+			continue
+		}
+		if m.Type != "" {
+			cv, err := coerceFieldValue(v, m.Type)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: %v", m.Field, err))
+			} else {
+				v = cv
+			}
+		}
+		if m.Target != "" && m.Target != m.Field {
+			delete(res, m.Field)
+			res[m.Target] = v
+		} else {
+			res[m.Field] = v
+		}
+	}
+
+	if len(msgs) > 0 {
+		return res, errors.NewResultError(strings.Join(msgs, "; "))
+	}
+	return res, nil
+}
+
+func coerceFieldValue(v interface{}, fieldType string) (res interface{}, err error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch fieldType {
+	case constants.ResultFieldTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case constants.ResultFieldTypeInt:
+		switch v := v.(type) {
+		case string:
+			s := strings.TrimSpace(v)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert \"%s\" to int", v)
+			}
+			return int64(f), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		default:
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert %T to int", v)
+			}
+			return int64(f), nil
+		}
+	case constants.ResultFieldTypeFloat:
+		switch v := v.(type) {
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert \"%s\" to float", v)
+			}
+			return f, nil
+		case bool:
+			if v {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		default:
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert %T to float", v)
+			}
+			return f, nil
+		}
+	case constants.ResultFieldTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert \"%s\" to bool", v)
+			}
+			return b, nil
+		default:
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert %T to bool", v)
+			}
+			return f != 0, nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %s", fieldType)
+	}
+}
+
+func toFloat64(v interface{}) (f float64, ok bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		if math.IsNaN(v) {
+			return 0, false
+		}
+		return v, true
+	default:
+		return 0, false
+	}
+}
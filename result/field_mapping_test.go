@@ -0,0 +1,59 @@
+package result
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestApplyFieldMappings(t *testing.T) {
+	r := entity.Result{
+		"title": "hello",
+		"price": "12.5",
+		"count": 3.0,
+		"debug": "x",
+		"ok":    "true",
+	}
+	mappings := []entity.ResultFieldMapping{
+		{Field: "title", Target: "name"},
+		{Field: "price", Type: constants.ResultFieldTypeFloat},
+		{Field: "count", Target: "qty", Type: constants.ResultFieldTypeInt},
+		{Field: "debug", Drop: true},
+		{Field: "ok", Type: constants.ResultFieldTypeBool},
+		{Field: "missing", Type: constants.ResultFieldTypeInt},
+	}
+	res, err := ApplyFieldMappings(r, mappings)
+	require.Nil(t, err)
+	require.Equal(t, entity.Result{
+		"name":  "hello",
+		"price": 12.5,
+		"qty":   int64(3),
+		"ok":    true,
+	}, res)
+
+	// original result is untouched
+	require.Equal(t, "hello", r["title"])
+}
+
+func TestApplyFieldMappings_CoerceError(t *testing.T) {
+	r := entity.Result{"price": "n/a"}
+	res, err := ApplyFieldMappings(r, []entity.ResultFieldMapping{
+		{Field: "price", Target: "cost", Type: constants.ResultFieldTypeFloat},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, entity.Result{"cost": "n/a"}, res)
+}
+
+func TestValidateFieldMappings(t *testing.T) {
+	require.Empty(t, ValidateFieldMappings([]entity.ResultFieldMapping{
+		{Field: "a", Target: "b", Type: constants.ResultFieldTypeString},
+	}))
+	errs := ValidateFieldMappings([]entity.ResultFieldMapping{
+		{Field: ""},
+		{Field: "_tid"},
+		{Field: "a", Target: "_id"},
+		{Field: "a", Type: "date"},
+	})
+	require.Len(t, errs, 4)
+}
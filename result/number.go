@@ -0,0 +1,76 @@
+package result
+
+import (
+	"encoding/json"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"strings"
+)
+
+// Numbers of result items are decoded as json.Number on both the node and
+// master, so that no precision is lost before they are stored. They are then
+// converted according to the number mode of the spider (constants.ResultNumberMode*),
+// doubles by default as before.
+
+// ConvertNumber returns the value of the json.Number in the number mode. Values
+// that cannot be represented by the BSON type of the mode are kept as strings,
+// so that no data is corrupted.
+func ConvertNumber(n json.Number, mode string) (v interface{}) {
+	s := n.String()
+	isInt := !strings.ContainsAny(s, ".eE")
+	switch mode {
+	case constants.ResultNumberModeInt, constants.ResultNumberModeDecimal:
+		if isInt {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i
+			}
+			if d, err := primitive.ParseDecimal128(s); err == nil {
+				return d
+			}
+			return s
+		}
+		if mode == constants.ResultNumberModeDecimal {
+			if d, err := primitive.ParseDecimal128(s); err == nil {
+				return d
+			}
+			return s
+		}
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return s
+	}
+	return f
+}
+
+// ConvertNumbers replaces json.Number values in the result, including those in
+// nested objects and arrays, with values of the number mode in place
+func ConvertNumbers(r entity.Result, mode string) {
+	for k, v := range r {
+		r[k] = convertNumbers(v, mode)
+	}
+}
+
+func convertNumbers(v interface{}, mode string) (res interface{}) {
+	switch v := v.(type) {
+	case json.Number:
+		return ConvertNumber(v, mode)
+	case map[string]interface{}:
+		for k, v2 := range v {
+			v[k] = convertNumbers(v2, mode)
+		}
+		return v
+	case entity.Result:
+		ConvertNumbers(v, mode)
+		return v
+	case []interface{}:
+		for i, v2 := range v {
+			v[i] = convertNumbers(v2, mode)
+		}
+		return v
+	default:
+		return v
+	}
+}
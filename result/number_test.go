@@ -0,0 +1,40 @@
+package result
+
+import (
+	"encoding/json"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestConvertNumber(t *testing.T) {
+	// float (default)
+	require.Equal(t, float64(1), ConvertNumber("1", ""))
+	require.Equal(t, 1.5, ConvertNumber("1.5", constants.ResultNumberModeFloat))
+
+	// int
+	require.Equal(t, int64(9007199254740993), ConvertNumber("9007199254740993", constants.ResultNumberModeInt))
+	d, _ := primitive.ParseDecimal128("123456789012345678901234567890")
+	require.Equal(t, d, ConvertNumber("123456789012345678901234567890", constants.ResultNumberModeInt))
+	require.Equal(t, 1.5, ConvertNumber("1.5", constants.ResultNumberModeInt))
+
+	// decimal
+	require.Equal(t, int64(2), ConvertNumber("2", constants.ResultNumberModeDecimal))
+	d, _ = primitive.ParseDecimal128("19.99")
+	require.Equal(t, d, ConvertNumber("19.99", constants.ResultNumberModeDecimal))
+}
+
+func TestConvertNumbers(t *testing.T) {
+	var r entity.Result
+	require.Nil(t, utils.UnmarshalJsonUseNumber([]byte(`{"id":9007199254740993,"tags":[1,{"n":2}],"name":"a"}`), &r))
+	require.Equal(t, json.Number("9007199254740993"), r["id"])
+
+	ConvertNumbers(r, constants.ResultNumberModeInt)
+	require.Equal(t, int64(9007199254740993), r["id"])
+	require.Equal(t, int64(1), r["tags"].([]interface{})[0])
+	require.Equal(t, int64(2), r["tags"].([]interface{})[1].(map[string]interface{})["n"])
+	require.Equal(t, "a", r["name"])
+}
@@ -6,7 +6,9 @@ import (
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"sync"
 )
@@ -57,6 +59,13 @@ func GetResultService(spiderId primitive.ObjectID, opts ...Option) (svc2 interfa
 		opt(_opts)
 	}
 
+	// default result collection of spiders without one
+	if s.ColId.IsZero() {
+		if err := setDefaultDataCollection(s); err != nil {
+			return nil, err
+		}
+	}
+
 	// store key
 	storeKey := s.ColId.Hex() + ":" + s.DataSourceId.Hex()
 
@@ -87,3 +96,17 @@ func GetResultService(spiderId primitive.ObjectID, opts ...Option) (svc2 interfa
 
 	return svc, nil
 }
+
+// setDefaultDataCollection sets the spider to store results in the default
+// result collection
+func setDefaultDataCollection(s *models.Spider) (err error) {
+	dc, err := GetOrCreateDataCollection(GetDefaultColName(s.Id))
+	if err != nil {
+		return err
+	}
+	s.ColId = dc.Id
+	if err := mongo.GetMongoCol(interfaces.ModelColNameSpider).UpdateId(s.Id, bson.M{"$set": bson.M{"col_id": dc.Id}}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
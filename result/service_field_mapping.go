@@ -0,0 +1,53 @@
+package result
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+)
+
+// ServiceFieldMapping applies field mappings of a spider to records before
+// inserting them with the underlying result service
+type ServiceFieldMapping struct {
+	interfaces.ResultService
+	mappings []entity.ResultFieldMapping
+}
+
+func (svc *ServiceFieldMapping) Insert(records ...interface{}) (err error) {
+	return svc.ResultService.Insert(svc.apply(records)...)
+}
+
+func (svc *ServiceFieldMapping) InsertWithStats(records ...interface{}) (stats interfaces.ResultInsertStats, err error) {
+	mapped := svc.apply(records)
+	if rs, ok := svc.ResultService.(interfaces.ResultServiceWithStats); ok {
+		return rs.InsertWithStats(mapped...)
+	}
+	if err := svc.ResultService.Insert(mapped...); err != nil {
+		return stats, err
+	}
+	stats.Inserted = len(mapped)
+	return stats, nil
+}
+
+func (svc *ServiceFieldMapping) apply(records []interface{}) (mapped []interface{}) {
+	for _, rec := range records {
+		r, ok := rec.(entity.Result)
+		if !ok {
+			mapped = append(mapped, rec)
+			continue
+		}
+		res, err := ApplyFieldMappings(r, svc.mappings)
+		if err != nil {
+			log.Warnf("field mapping error: %v", err)
+		}
+		mapped = append(mapped, res)
+	}
+	return mapped
+}
+
+func NewResultServiceFieldMapping(svc interfaces.ResultService, mappings []entity.ResultFieldMapping) (svc2 interfaces.ResultService) {
+	return &ServiceFieldMapping{
+		ResultService: svc,
+		mappings:      mappings,
+	}
+}
@@ -0,0 +1,85 @@
+package result
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"sync"
+)
+
+// ServiceMaxItems caps the number of result items of a task, protecting the
+// result store from runaway crawls. Records beyond the cap are dropped
+// (reported as skipped), and onCapped is called once when the cap is first
+// reached.
+type ServiceMaxItems struct {
+	interfaces.ResultService
+	max      int
+	count    int // records accepted so far
+	capped   bool
+	onCapped func()
+	mu       sync.Mutex
+}
+
+func (svc *ServiceMaxItems) Insert(records ...interface{}) (err error) {
+	records, _ = svc.limit(records)
+	if len(records) == 0 {
+		return nil
+	}
+	return svc.ResultService.Insert(records...)
+}
+
+func (svc *ServiceMaxItems) InsertWithStats(records ...interface{}) (stats interfaces.ResultInsertStats, err error) {
+	records, dropped := svc.limit(records)
+	if len(records) > 0 {
+		if rs, ok := svc.ResultService.(interfaces.ResultServiceWithStats); ok {
+			stats, err = rs.InsertWithStats(records...)
+		} else {
+			err = svc.ResultService.Insert(records...)
+			stats.Inserted = len(records)
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+	stats.Skipped += dropped
+	return stats, nil
+}
+
+// IsCapped returns whether the cap has been reached
+func (svc *ServiceMaxItems) IsCapped() (ok bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.capped
+}
+
+// limit returns the records within the cap and the number of dropped ones
+func (svc *ServiceMaxItems) limit(records []interface{}) (res []interface{}, dropped int) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	n := svc.max - svc.count
+	if n < 0 {
+		n = 0
+	}
+	if len(records) > n {
+		dropped = len(records) - n
+		records = records[:n]
+	}
+	svc.count += len(records)
+	if (dropped > 0 || svc.count >= svc.max) && !svc.capped {
+		svc.capped = true
+		if svc.onCapped != nil {
+			go svc.onCapped()
+		}
+	}
+	return records, dropped
+}
+
+// NewResultServiceMaxItems returns the result service accepting at most max
+// records in total, count of which have been accepted before, e.g. prior to
+// a restart of master
+func NewResultServiceMaxItems(svc interfaces.ResultService, max, count int, onCapped func()) (svc2 *ServiceMaxItems) {
+	return &ServiceMaxItems{
+		ResultService: svc,
+		max:           max,
+		count:         count,
+		onCapped:      onCapped,
+	}
+}
@@ -0,0 +1,43 @@
+package result
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testCountResultService struct {
+	interfaces.ResultService
+	n int
+}
+
+func (svc *testCountResultService) Insert(records ...interface{}) (err error) {
+	svc.n += len(records)
+	return nil
+}
+
+func TestServiceMaxItems_InsertWithStats(t *testing.T) {
+	rs := &testCountResultService{}
+	cappedCh := make(chan struct{}, 2)
+	svc := NewResultServiceMaxItems(rs, 5, 2, func() { cappedCh <- struct{}{} })
+
+	// within the cap
+	stats, err := svc.InsertWithStats(entity.Result{}, entity.Result{})
+	require.Nil(t, err)
+	require.Equal(t, 2, stats.Inserted)
+	require.False(t, svc.IsCapped())
+
+	// beyond the cap
+	stats, err = svc.InsertWithStats(entity.Result{}, entity.Result{}, entity.Result{})
+	require.Nil(t, err)
+	require.Equal(t, 1, stats.Inserted)
+	require.Equal(t, 2, stats.Skipped)
+	require.True(t, svc.IsCapped())
+	<-cappedCh
+
+	// capped
+	require.Nil(t, svc.Insert(entity.Result{}))
+	require.Equal(t, 3, rs.n)
+	require.Len(t, cappedCh, 0)
+}
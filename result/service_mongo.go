@@ -1,6 +1,7 @@
 package result
 
 import (
+	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
@@ -13,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
 	"time"
 )
 
@@ -22,9 +24,12 @@ type ServiceMongo struct {
 	modelColSvc interfaces.ModelBaseService
 
 	// internals
-	colId primitive.ObjectID     // _id of models.DataCollection
-	dc    *models.DataCollection // models.DataCollection
-	t     time.Time
+	colId         primitive.ObjectID     // _id of models.DataCollection
+	dc            *models.DataCollection // models.DataCollection
+	t             time.Time
+	mu            sync.Mutex
+	dedupIndexCol string // collection of the last attempt to create the dedup index
+	dedupIndexOk  bool   // whether the dedup index exists in dedupIndexCol
 }
 
 func (svc *ServiceMongo) List(query generic.ListQuery, opts *generic.ListOptions) (results []interface{}, err error) {
@@ -39,45 +44,145 @@ func (svc *ServiceMongo) Count(query generic.ListQuery) (n int, err error) {
 }
 
 func (svc *ServiceMongo) Insert(docs ...interface{}) (err error) {
-	if svc.dc.Dedup.Enabled {
-		for _, doc := range docs {
-			hash, err := utils.GetResultHash(doc, svc.dc.Dedup.Keys)
-			if err != nil {
-				return err
-			}
-			doc.(interfaces.Result).SetValue(constants.HashKey, hash)
-			query := bson.M{constants.HashKey: hash}
-			switch svc.dc.Dedup.Type {
-			case constants.DedupTypeOverwrite:
-				err = mongo.GetMongoCol(svc.dc.Name).ReplaceWithOptions(query, doc, &options.ReplaceOptions{Upsert: &[]bool{true}[0]})
-				if err != nil {
-					return trace.TraceError(err)
-				}
-			default:
-				var o bson.M
-				err := mongo.GetMongoCol(svc.dc.Name).Find(query, &mongo.FindOptions{Limit: 1}).One(&o)
-				if err == nil {
-					// exists, ignore
-					continue
-				}
-				if err != mongo2.ErrNoDocuments {
-					// error
-					return trace.TraceError(err)
-				}
-				// not exists, insert
-				_, err = mongo.GetMongoCol(svc.dc.Name).Insert(doc)
-				if err != nil {
-					return trace.TraceError(err)
-				}
-			}
+	_, err = svc.InsertWithStats(docs...)
+	return err
+}
+
+// InsertWithStats inserts records and counts them by outcome. If dedup is
+// enabled for the data collection, records are hashed by the dedup keys and
+// duplicates are skipped or overwritten according to the dedup type.
+func (svc *ServiceMongo) InsertWithStats(docs ...interface{}) (stats interfaces.ResultInsertStats, err error) {
+	if len(docs) == 0 {
+		return stats, nil
+	}
+
+	dc := svc.dc
+	if !dc.Dedup.Enabled {
+		_, err = mongo.GetMongoCol(dc.Name).InsertMany(docs)
+		if err != nil {
+			return stats, trace.TraceError(err)
 		}
-	} else {
-		_, err = mongo.GetMongoCol(svc.dc.Name).InsertMany(docs)
+		stats.Inserted = len(docs)
+		return stats, nil
+	}
+
+	// hash of dedup keys
+	for _, doc := range docs {
+		hash, err := utils.GetResultHash(doc, dc.Dedup.Keys)
 		if err != nil {
-			return trace.TraceError(err)
+			return stats, err
 		}
+		doc.(interfaces.Result).SetValue(constants.HashKey, hash)
+	}
+
+	// unique index of hash
+	indexed := svc.ensureDedupIndex(dc.Name)
+
+	switch dc.Dedup.Type {
+	case constants.DedupTypeOverwrite:
+		return svc.upsert(dc.Name, docs)
+	default:
+		if !indexed {
+			return svc.insertIgnoreDuplicatesByQuery(dc.Name, docs)
+		}
+		return svc.insertIgnoreDuplicates(dc.Name, docs)
+	}
+}
+
+// ensureDedupIndex creates the unique index of the dedup hash once per
+// collection and returns whether it exists. Records inserted before dedup
+// was enabled have no hash and are excluded from the index. Creation fails if
+// the collection already has duplicates, which are then checked per record.
+func (svc *ServiceMongo) ensureDedupIndex(colName string) (ok bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.dedupIndexCol == colName {
+		return svc.dedupIndexOk
+	}
+	err := mongo.GetMongoCol(colName).CreateIndex(mongo2.IndexModel{
+		Keys: bson.M{constants.HashKey: 1},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{constants.HashKey: bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		log.Warnf("failed to create dedup index of %s: %v", colName, err)
+	}
+	svc.dedupIndexCol = colName
+	svc.dedupIndexOk = err == nil
+	return svc.dedupIndexOk
+}
+
+// insertIgnoreDuplicates inserts records in an unordered batch so that
+// duplicate key errors of some records do not stop the others
+func (svc *ServiceMongo) insertIgnoreDuplicates(colName string, docs []interface{}) (stats interfaces.ResultInsertStats, err error) {
+	col := mongo.GetMongoCol(colName)
+	_, err = col.GetCollection().InsertMany(col.GetContext(), docs, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		stats.Inserted = len(docs)
+		return stats, nil
 	}
-	return nil
+	bwe, ok := err.(mongo2.BulkWriteException)
+	if !ok || bwe.WriteConcernError != nil {
+		return stats, trace.TraceError(err)
+	}
+	for _, we := range bwe.WriteErrors {
+		if !isDuplicateKeyCode(we.Code) {
+			return stats, trace.TraceError(err)
+		}
+		stats.Skipped++
+	}
+	stats.Inserted = len(docs) - stats.Skipped
+	return stats, nil
+}
+
+// insertIgnoreDuplicatesByQuery inserts records whose hash does not exist
+func (svc *ServiceMongo) insertIgnoreDuplicatesByQuery(colName string, docs []interface{}) (stats interfaces.ResultInsertStats, err error) {
+	col := mongo.GetMongoCol(colName)
+	for _, doc := range docs {
+		query := bson.M{constants.HashKey: doc.(interfaces.Result).GetValue(constants.HashKey)}
+		var o bson.M
+		err := col.Find(query, &mongo.FindOptions{Limit: 1}).One(&o)
+		if err == nil {
+			stats.Skipped++
+			continue
+		}
+		if err != mongo2.ErrNoDocuments {
+			return stats, trace.TraceError(err)
+		}
+		if _, err := col.Insert(doc); err != nil {
+			return stats, trace.TraceError(err)
+		}
+		stats.Inserted++
+	}
+	return stats, nil
+}
+
+// upsert replaces records with the same hash or inserts them if not exist
+func (svc *ServiceMongo) upsert(colName string, docs []interface{}) (stats interfaces.ResultInsertStats, err error) {
+	col := mongo.GetMongoCol(colName)
+	opts := options.Replace().SetUpsert(true)
+	for _, doc := range docs {
+		query := bson.M{constants.HashKey: doc.(interfaces.Result).GetValue(constants.HashKey)}
+		res, err := col.GetCollection().ReplaceOne(col.GetContext(), query, doc, opts)
+		if mongo2.IsDuplicateKeyError(err) {
+			// concurrent upsert of the same hash, which exists now
+			res, err = col.GetCollection().ReplaceOne(col.GetContext(), query, doc, opts)
+		}
+		if err != nil {
+			return stats, trace.TraceError(err)
+		}
+		if res.UpsertedCount > 0 {
+			stats.Inserted++
+		} else {
+			stats.Updated++
+		}
+	}
+	return stats, nil
+}
+
+func isDuplicateKeyCode(code int) bool {
+	return code == 11000 || code == 11001 || code == 12582
 }
 
 func (svc *ServiceMongo) Index(fields []string) {
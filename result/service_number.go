@@ -0,0 +1,47 @@
+package result
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+)
+
+// ServiceNumber converts json.Number values of records in the number mode of
+// a spider before inserting them with the underlying result service. Records
+// are converted in place, so that later consumers, e.g. plugins, see the
+// stored values.
+type ServiceNumber struct {
+	interfaces.ResultService
+	mode string
+}
+
+func (svc *ServiceNumber) Insert(records ...interface{}) (err error) {
+	return svc.ResultService.Insert(svc.convert(records)...)
+}
+
+func (svc *ServiceNumber) InsertWithStats(records ...interface{}) (stats interfaces.ResultInsertStats, err error) {
+	records = svc.convert(records)
+	if rs, ok := svc.ResultService.(interfaces.ResultServiceWithStats); ok {
+		return rs.InsertWithStats(records...)
+	}
+	if err := svc.ResultService.Insert(records...); err != nil {
+		return stats, err
+	}
+	stats.Inserted = len(records)
+	return stats, nil
+}
+
+func (svc *ServiceNumber) convert(records []interface{}) (res []interface{}) {
+	for _, rec := range records {
+		if r, ok := rec.(entity.Result); ok {
+			ConvertNumbers(r, svc.mode)
+		}
+	}
+	return records
+}
+
+func NewResultServiceNumber(svc interfaces.ResultService, mode string) (svc2 interfaces.ResultService) {
+	return &ServiceNumber{
+		ResultService: svc,
+		mode:          mode,
+	}
+}
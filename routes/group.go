@@ -9,12 +9,14 @@ type RouterGroups struct {
 	AuthGroup      *gin.RouterGroup
 	AnonymousGroup *gin.RouterGroup
 	FilerGroup     *gin.RouterGroup
+	SyncGroup      *gin.RouterGroup
 }
 
 func NewRouterGroups(app *gin.Engine) (groups *RouterGroups) {
 	return &RouterGroups{
-		AuthGroup:      app.Group("/", middlewares.AuthorizationMiddleware()),
+		AuthGroup:      app.Group("/", middlewares.AuthorizationMiddleware(), middlewares.AuditMiddleware()),
 		AnonymousGroup: app.Group("/"),
 		FilerGroup:     app.Group("/filer", middlewares.FilerAuthorizationMiddleware()),
+		SyncGroup:      app.Group("/sync", middlewares.SyncAuthorizationMiddleware()),
 	}
 }
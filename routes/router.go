@@ -5,6 +5,7 @@ import (
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/controllers"
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"net/http"
 	"path"
 )
@@ -52,6 +53,8 @@ func (svc *RouterService) RegisterActionControllerToGroup(group *gin.RouterGroup
 			group.POST(routerPath, action.HandlerFunc)
 		case http.MethodPut:
 			group.PUT(routerPath, action.HandlerFunc)
+		case http.MethodPatch:
+			group.PATCH(routerPath, action.HandlerFunc)
 		case http.MethodDelete:
 			group.DELETE(routerPath, action.HandlerFunc)
 		}
@@ -71,6 +74,8 @@ func (svc *RouterService) RegisterHandlerToGroup(group *gin.RouterGroup, path st
 		group.POST(path, handler)
 	case http.MethodPut:
 		group.PUT(path, handler)
+	case http.MethodPatch:
+		group.PATCH(path, handler)
 	case http.MethodDelete:
 		group.DELETE(path, handler)
 	default:
@@ -89,6 +94,7 @@ func InitRoutes(app *gin.Engine) (err error) {
 	registerRoutesAnonymousGroup(svc, groups)
 	registerRoutesAuthGroup(svc, groups)
 	registerRoutesFilterGroup(svc, groups)
+	registerRoutesSyncGroup(svc, groups)
 
 	return nil
 }
@@ -108,11 +114,19 @@ func registerRoutesAnonymousGroup(svc *RouterService, groups *RouterGroups) {
 
 	// demo
 	svc.RegisterActionControllerToGroup(groups.AnonymousGroup, "/demo", controllers.DemoController)
+
+	// health
+	svc.RegisterActionControllerToGroup(groups.AnonymousGroup, "/health", controllers.HealthController)
+
+	// build info (anonymous if enabled)
+	if viper.GetBool("api.buildInfo.anonymous") {
+		svc.RegisterActionControllerToGroup(groups.AnonymousGroup, "/build-info", controllers.BuildInfoController)
+	}
 }
 
 func registerRoutesAuthGroup(svc *RouterService, groups *RouterGroups) {
 	// node
-	svc.RegisterListControllerToGroup(groups.AuthGroup, "/nodes", controllers.NodeController)
+	svc.RegisterListActionControllerToGroup(groups.AuthGroup, "/nodes", controllers.NodeController)
 
 	// project
 	svc.RegisterListControllerToGroup(groups.AuthGroup, "/projects", controllers.ProjectController)
@@ -147,6 +161,18 @@ func registerRoutesAuthGroup(svc *RouterService, groups *RouterGroups) {
 	// stats
 	svc.RegisterActionControllerToGroup(groups.AuthGroup, "/stats", controllers.StatsController)
 
+	// metrics (prometheus)
+	svc.RegisterActionControllerToGroup(groups.AuthGroup, "/metrics", controllers.MetricsController)
+
+	// scheduler
+	svc.RegisterActionControllerToGroup(groups.AuthGroup, "/scheduler", controllers.SchedulerController)
+
+	// effective config
+	svc.RegisterActionControllerToGroup(groups.AuthGroup, "/config", controllers.ConfigController)
+
+	// audit logs
+	svc.RegisterActionControllerToGroup(groups.AuthGroup, "/audit-logs", controllers.AuditLogController)
+
 	// token
 	svc.RegisterListControllerToGroup(groups.AuthGroup, "/tokens", controllers.TokenController)
 
@@ -173,9 +199,19 @@ func registerRoutesAuthGroup(svc *RouterService, groups *RouterGroups) {
 
 	// environments
 	svc.RegisterListActionControllerToGroup(groups.AuthGroup, "/environments", controllers.EnvironmentController)
+
+	// build info
+	if !viper.GetBool("api.buildInfo.anonymous") {
+		svc.RegisterActionControllerToGroup(groups.AuthGroup, "/build-info", controllers.BuildInfoController)
+	}
 }
 
 func registerRoutesFilterGroup(svc *RouterService, groups *RouterGroups) {
 	// filer
 	svc.RegisterActionControllerToGroup(groups.FilerGroup, "", controllers.FilerController)
 }
+
+func registerRoutesSyncGroup(svc *RouterService, groups *RouterGroups) {
+	// sync
+	svc.RegisterActionControllerToGroup(groups.SyncGroup, "", controllers.SyncController)
+}
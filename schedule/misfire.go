@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/go-trace"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// Runs of a schedule due while the scheduler was down, i.e. after the last
+// run of the schedule and before it is added to the cron again on start, are
+// misfires. They are handled by the misfire policy of the schedule when the
+// schedule service starts: skipped (default), caught up by one run, or caught
+// up by all missed runs, up to GetMisfireMaxRuns.
+
+// defaultMisfireMaxRuns is the max number of catch-up runs of a schedule if
+// "schedule.misfire.maxRuns" is not set
+const defaultMisfireMaxRuns = 100
+
+// ValidateMisfirePolicy returns errors.ErrorScheduleInvalidMisfirePolicy if
+// the policy is neither empty nor one of constants.ScheduleMisfirePolicy*
+func ValidateMisfirePolicy(policy string) (err error) {
+	switch policy {
+	case "",
+		constants.ScheduleMisfirePolicySkip,
+		constants.ScheduleMisfirePolicyFireOnce,
+		constants.ScheduleMisfirePolicyFireAll:
+		return nil
+	default:
+		return trace.TraceError(errors.ErrorScheduleInvalidMisfirePolicy)
+	}
+}
+
+// GetMisfireMaxRuns returns the max number of catch-up runs of a schedule
+// with constants.ScheduleMisfirePolicyFireAll, which is configured by
+// "schedule.misfire.maxRuns"
+func GetMisfireMaxRuns() (n int) {
+	if n = viper.GetInt("schedule.misfire.maxRuns"); n > 0 {
+		return n
+	}
+	return defaultMisfireMaxRuns
+}
+
+// GetMissedRunTs returns times the schedule was due after lastRunTs and up to
+// now, at most max of them. Nothing was missed if lastRunTs is zero, i.e. the
+// schedule has never run.
+func GetMissedRunTs(sched cron.Schedule, lastRunTs, now time.Time, max int) (res []time.Time) {
+	if lastRunTs.IsZero() {
+		return nil
+	}
+	for ts := sched.Next(lastRunTs); !ts.IsZero() && !ts.After(now) && len(res) < max; ts = sched.Next(ts) {
+		res = append(res, ts)
+	}
+	return res
+}
+
+// GetMisfireRuns returns the number of catch-up runs of the missed runs by
+// the misfire policy
+func GetMisfireRuns(policy string, missed int) (n int) {
+	if missed == 0 {
+		return 0
+	}
+	switch policy {
+	case constants.ScheduleMisfirePolicyFireOnce:
+		return 1
+	case constants.ScheduleMisfirePolicyFireAll:
+		return missed
+	default:
+		return 0
+	}
+}
+
+// getCatchUpRuns returns the number of catch-up runs of the schedule for runs
+// missed before now by its misfire policy. Schedules without time zone run in
+// the location of the service, as they do in the cron of the service. now must
+// not be after the schedule is added to the cron, whose runs are after the
+// time added, so that no run is fired both by the cron and as a catch-up.
+func (svc *Service) getCatchUpRuns(s *models.Schedule, now time.Time) (missed, n int) {
+	if s.MisfirePolicy == "" || s.MisfirePolicy == constants.ScheduleMisfirePolicySkip {
+		return 0, 0
+	}
+	spec, err := GetCronSpec(s)
+	if err != nil {
+		trace.PrintError(err)
+		return 0, 0
+	}
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		trace.PrintError(err)
+		return 0, 0
+	}
+	missedTs := GetMissedRunTs(sched, s.LastRunTs.In(svc.loc), now.In(svc.loc), GetMisfireMaxRuns())
+	return len(missedTs), GetMisfireRuns(s.MisfirePolicy, len(missedTs))
+}
+
+// catchUp fires n catch-up runs of the schedule missed while the scheduler was
+// down, and records the catch-up in the schedule
+func (svc *Service) catchUp(s *models.Schedule, missed, n int, now time.Time) {
+	// record
+	log.Infof("[ScheduleService] schedule[%s] missed %d runs since %s, firing %d catch-up runs (%s)", s.Id.Hex(), missed, s.LastRunTs.Format(time.RFC3339), n, s.MisfirePolicy)
+	if err := svc.modelSvc.GetBaseService(interfaces.ModelIdSchedule).UpdateById(s.Id, bson.M{
+		"$set": bson.M{
+			"last_misfire_ts":   now,
+			"last_misfire_runs": n,
+		},
+	}); err != nil {
+		trace.PrintError(err)
+	}
+
+	// fire
+	fn := svc.schedule(s.Id)
+	for i := 0; i < n; i++ {
+		fn()
+	}
+}
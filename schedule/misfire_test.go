@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGetMissedRunTs(t *testing.T) {
+	sched, err := cron.ParseStandard("0 * * * *")
+	require.Nil(t, err)
+	last := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2022, 1, 1, 13, 30, 0, 0, time.UTC)
+
+	// hourly runs at 11:00, 12:00 and 13:00 missed
+	res := GetMissedRunTs(sched, last, now, 100)
+	require.Len(t, res, 3)
+	require.Equal(t, time.Date(2022, 1, 1, 11, 0, 0, 0, time.UTC), res[0])
+	require.Equal(t, time.Date(2022, 1, 1, 13, 0, 0, 0, time.UTC), res[2])
+
+	// max
+	require.Len(t, GetMissedRunTs(sched, last, now, 2), 2)
+
+	// never run
+	require.Empty(t, GetMissedRunTs(sched, time.Time{}, now, 100))
+
+	// nothing missed
+	require.Empty(t, GetMissedRunTs(sched, last, last.Add(30*time.Minute), 100))
+}
+
+func TestGetMisfireRuns(t *testing.T) {
+	require.Equal(t, 0, GetMisfireRuns("", 3))
+	require.Equal(t, 0, GetMisfireRuns(constants.ScheduleMisfirePolicySkip, 3))
+	require.Equal(t, 1, GetMisfireRuns(constants.ScheduleMisfirePolicyFireOnce, 3))
+	require.Equal(t, 3, GetMisfireRuns(constants.ScheduleMisfirePolicyFireAll, 3))
+	require.Equal(t, 0, GetMisfireRuns(constants.ScheduleMisfirePolicyFireOnce, 0))
+}
+
+func TestValidateMisfirePolicy(t *testing.T) {
+	require.Nil(t, ValidateMisfirePolicy(""))
+	require.Nil(t, ValidateMisfirePolicy(constants.ScheduleMisfirePolicyFireAll))
+	require.NotNil(t, ValidateMisfirePolicy("unknown"))
+}
+
+func TestService_getCatchUpRuns(t *testing.T) {
+	// daily at 09:00 in the location of the service (UTC+8), last run at
+	// 09:00 on Jan 1 read from the database in UTC
+	svc := &Service{loc: time.FixedZone("UTC+8", 8*3600)}
+	s := &models.Schedule{
+		Cron:          "0 9 * * *",
+		MisfirePolicy: constants.ScheduleMisfirePolicyFireAll,
+		LastRunTs:     time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+
+	// 18:00 on Jan 1 in UTC+8, i.e. after 09:00 in UTC but nothing missed
+	missed, n := svc.getCatchUpRuns(s, time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC))
+	require.Equal(t, 0, missed)
+	require.Equal(t, 0, n)
+
+	// 10:00 on Jan 3 in UTC+8, runs on Jan 2 and Jan 3 missed
+	missed, n = svc.getCatchUpRuns(s, time.Date(2022, 1, 3, 2, 0, 0, 0, time.UTC))
+	require.Equal(t, 2, missed)
+	require.Equal(t, 2, n)
+
+	// skipped by default
+	s.MisfirePolicy = ""
+	missed, n = svc.getCatchUpRuns(s, time.Date(2022, 1, 3, 2, 0, 0, 0, time.UTC))
+	require.Equal(t, 0, n)
+}
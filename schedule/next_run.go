@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// The next run of a schedule can be overridden by a one-off run, e.g. to run
+// the next one early or late, without editing the cron. The one-off run
+// replaces the next cron run at the time of the override, which is skipped,
+// and the schedule reverts to its cron afterwards.
+
+// overrideSchedule is the cron schedule with the one-off run replacing the
+// skipped cron run
+type overrideSchedule struct {
+	cron.Schedule
+	nextRunTs time.Time // one-off run
+	skippedTs time.Time // cron run replaced by the one-off run
+}
+
+func (o *overrideSchedule) Next(t time.Time) (next time.Time) {
+	next = o.Schedule.Next(t)
+	if !o.skippedTs.IsZero() && next.Equal(o.skippedTs) {
+		next = o.Schedule.Next(next)
+	}
+	if !o.nextRunTs.IsZero() && o.nextRunTs.After(t) && (next.IsZero() || o.nextRunTs.Before(next)) {
+		return o.nextRunTs
+	}
+	return next
+}
+
+// GetCronSchedule returns the cron schedule of the schedule with its time
+// zone, honoring the override of its next run if any
+func GetCronSchedule(s interfaces.Schedule) (sched cron.Schedule, err error) {
+	spec, err := GetCronSpec(s)
+	if err != nil {
+		return nil, err
+	}
+	sched, err = cron.ParseStandard(spec)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if s.GetNextRunOverrideTs().IsZero() {
+		return sched, nil
+	}
+	return &overrideSchedule{
+		Schedule:  sched,
+		nextRunTs: s.GetNextRunOverrideTs(),
+		skippedTs: s.GetSkippedRunTs(),
+	}, nil
+}
+
+// ValidateNextRunOverrideTs returns errors.ErrorScheduleInvalidNextRunTs if ts
+// is not after now
+func ValidateNextRunOverrideTs(ts, now time.Time) (err error) {
+	if !ts.After(now) {
+		return fmt.Errorf("%w: %s is not in the future", errors.ErrorScheduleInvalidNextRunTs, ts.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (svc *Service) SetNextRunOverride(s interfaces.Schedule, ts time.Time, args ...interface{}) (err error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	// override
+	if ts.IsZero() {
+		s.SetNextRunOverrideTs(time.Time{})
+		s.SetSkippedRunTs(time.Time{})
+	} else {
+		now := time.Now()
+		if err := ValidateNextRunOverrideTs(ts, now); err != nil {
+			return err
+		}
+		skippedTs, err := svc.getNextCronRunTs(s, now)
+		if err != nil {
+			return err
+		}
+		s.SetNextRunOverrideTs(ts)
+		s.SetSkippedRunTs(skippedTs)
+	}
+
+	// replace the cron entry
+	if s.GetEnabled() {
+		sched, err := GetCronSchedule(s)
+		if err != nil {
+			return err
+		}
+		svc.cron.Remove(s.GetEntryId())
+		s.SetEntryId(svc.cron.Schedule(sched, cron.FuncJob(svc.schedule(s.GetId()))))
+	}
+
+	u := utils.GetUserFromArgs(args...)
+	return delegate.NewModelDelegate(s, u).Save()
+}
+
+// getNextCronRunTs returns the next run of the cron of the schedule after now,
+// ignoring its override. Schedules without time zone run in the location of the
+// service, as they do in the cron of the service.
+func (svc *Service) getNextCronRunTs(s interfaces.Schedule, now time.Time) (ts time.Time, err error) {
+	spec, err := GetCronSpec(s)
+	if err != nil {
+		return ts, err
+	}
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return ts, trace.TraceError(err)
+	}
+	return sched.Next(now.In(svc.loc)), nil
+}
+
+// clearNextRunOverride clears the override of the next run of the schedule
+// once both the one-off run and the skipped cron run have passed
+func (svc *Service) clearNextRunOverride(s interfaces.Schedule, now time.Time) {
+	if s.GetNextRunOverrideTs().IsZero() || s.GetNextRunOverrideTs().After(now) || s.GetSkippedRunTs().After(now) {
+		return
+	}
+	if err := svc.modelSvc.GetBaseService(interfaces.ModelIdSchedule).UpdateById(s.GetId(), bson.M{
+		"$set": bson.M{
+			"next_run_override_ts": time.Time{},
+			"skipped_run_ts":       time.Time{},
+		},
+	}); err != nil {
+		trace.PrintError(err)
+	}
+}
@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGetCronSchedule_Override(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 30, 0, 0, time.UTC)
+	s := &models.Schedule{Cron: "0 * * * *"}
+
+	// cron
+	sched, err := GetCronSchedule(s)
+	require.Nil(t, err)
+	require.Equal(t, time.Date(2022, 1, 1, 11, 0, 0, 0, time.UTC), sched.Next(now))
+
+	// early, replacing the run at 11:00
+	s.NextRunOverrideTs = time.Date(2022, 1, 1, 10, 45, 0, 0, time.UTC)
+	s.SkippedRunTs = time.Date(2022, 1, 1, 11, 0, 0, 0, time.UTC)
+	sched, err = GetCronSchedule(s)
+	require.Nil(t, err)
+	next := sched.Next(now)
+	require.Equal(t, s.NextRunOverrideTs, next)
+	require.Equal(t, time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC), sched.Next(next))
+
+	// late, replacing the run at 11:00
+	s.NextRunOverrideTs = time.Date(2022, 1, 1, 11, 15, 0, 0, time.UTC)
+	sched, err = GetCronSchedule(s)
+	require.Nil(t, err)
+	next = sched.Next(now)
+	require.Equal(t, s.NextRunOverrideTs, next)
+	require.Equal(t, time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC), sched.Next(next))
+}
+
+func TestService_getNextCronRunTs(t *testing.T) {
+	svc := &Service{loc: time.FixedZone("UTC+8", 8*3600)}
+	now := time.Date(2022, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	// daily at 09:00 in the location of the service, i.e. 01:00 in utc
+	ts, err := svc.getNextCronRunTs(&models.Schedule{Cron: "0 9 * * *"}, now)
+	require.Nil(t, err)
+	require.True(t, time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC).Equal(ts))
+
+	// in the time zone of the schedule
+	ts, err = svc.getNextCronRunTs(&models.Schedule{Cron: "0 9 * * *", Timezone: "UTC"}, now)
+	require.Nil(t, err)
+	require.True(t, time.Date(2022, 1, 1, 9, 0, 0, 0, time.UTC).Equal(ts))
+}
+
+func TestValidateNextRunOverrideTs(t *testing.T) {
+	now := time.Now()
+	require.Nil(t, ValidateNextRunOverrideTs(now.Add(time.Minute), now))
+	require.NotNil(t, ValidateNextRunOverrideTs(now, now))
+	require.NotNil(t, ValidateNextRunOverrideTs(now.Add(-time.Minute), now))
+}
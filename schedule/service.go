@@ -1,7 +1,9 @@
 package schedule
 
 import (
+	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/config"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
@@ -10,6 +12,7 @@ import (
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/go-trace"
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/dig"
@@ -92,10 +95,11 @@ func (svc *Service) Enable(s interfaces.Schedule, args ...interface{}) (err erro
 	svc.mu.Lock()
 	defer svc.mu.Unlock()
 
-	id, err := svc.cron.AddFunc(s.GetCron(), svc.schedule(s.GetId()))
+	sched, err := GetCronSchedule(s)
 	if err != nil {
-		return trace.TraceError(err)
+		return err
 	}
+	id := svc.cron.Schedule(sched, cron.FuncJob(svc.schedule(s.GetId())))
 	s.SetEnabled(true)
 	s.SetEntryId(id)
 	u := utils.GetUserFromArgs(args...)
@@ -109,6 +113,8 @@ func (svc *Service) Disable(s interfaces.Schedule, args ...interface{}) (err err
 	svc.cron.Remove(s.GetEntryId())
 	s.SetEnabled(false)
 	s.SetEntryId(-1)
+	// runs while disabled are not missed
+	s.SetLastRunTs(time.Time{})
 	u := utils.GetUserFromArgs(args...)
 	return delegate.NewModelDelegate(s, u).Save()
 }
@@ -125,6 +131,31 @@ func (svc *Service) Update() {
 	}
 }
 
+func (svc *Service) GetNextRunTs(s interfaces.Schedule, n int) (res []time.Time, err error) {
+	sched, err := GetCronSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+	loc := svc.loc
+	if s.GetTimezone() != "" {
+		loc, err = time.LoadLocation(s.GetTimezone())
+		if err != nil {
+			return nil, trace.TraceError(errors.ErrorScheduleInvalidTimezone)
+		}
+	}
+	// schedules without time zone run in the location of the service, as
+	// they do in the cron of the service
+	ts := time.Now().In(svc.loc)
+	for i := 0; i < n; i++ {
+		ts = sched.Next(ts)
+		if ts.IsZero() {
+			break
+		}
+		res = append(res, ts.In(loc))
+	}
+	return res, nil
+}
+
 func (svc *Service) GetCron() (c *cron.Cron) {
 	return svc.cron
 }
@@ -145,10 +176,22 @@ func (svc *Service) update() {
 		if ok {
 			entryIdsMap[s.EntryId] = true
 		} else {
+			s := s
+
+			// runs missed while not in the cron, e.g. master was down, which
+			// are counted before adding to the cron so that none of them is
+			// also fired by the cron
+			now := time.Now()
+			missed, n := svc.getCatchUpRuns(&s, now)
+
 			if err := svc.Enable(&s); err != nil {
 				trace.PrintError(err)
 				continue
 			}
+
+			if n > 0 {
+				go svc.catchUp(&s, missed, n, now)
+			}
 		}
 	}
 
@@ -188,6 +231,16 @@ func (svc *Service) schedule(id primitive.ObjectID) (fn func()) {
 			return
 		}
 
+		// last run
+		if err := svc.modelSvc.GetBaseService(interfaces.ModelIdSchedule).UpdateById(id, bson.M{
+			"$set": bson.M{"last_run_ts": time.Now()},
+		}); err != nil {
+			trace.PrintError(err)
+		}
+
+		// revert to the cron after the one-off run
+		svc.clearNextRunOverride(s, time.Now())
+
 		// spider
 		spider, err := svc.modelSvc.GetSpiderById(s.GetSpiderId())
 		if err != nil {
@@ -195,6 +248,12 @@ func (svc *Service) schedule(id primitive.ObjectID) (fn func()) {
 			return
 		}
 
+		// skip if spider is disabled
+		if !spider.Enabled {
+			log.Infof("[ScheduleService] skipped schedule[%s] as spider[%s] is disabled", s.GetId().Hex(), spider.Id.Hex())
+			return
+		}
+
 		// options
 		opts := &interfaces.SpiderRunOptions{
 			Mode:       s.GetMode(),
@@ -202,6 +261,7 @@ func (svc *Service) schedule(id primitive.ObjectID) (fn func()) {
 			Cmd:        s.GetCmd(),
 			Param:      s.GetParam(),
 			Priority:   s.GetPriority(),
+			Profile:    s.Profile,
 			ScheduleId: s.GetId(),
 			UserId:     s.UserId,
 		}
@@ -213,9 +273,6 @@ func (svc *Service) schedule(id primitive.ObjectID) (fn func()) {
 		if len(opts.NodeIds) == 0 {
 			opts.NodeIds = spider.NodeIds
 		}
-		if opts.Cmd == "" {
-			opts.Cmd = spider.Cmd
-		}
 		if opts.Param == "" {
 			opts.Param = spider.Param
 		}
@@ -300,6 +357,14 @@ func GetScheduleService(path string, opts ...Option) (svc interfaces.ScheduleSer
 		path = config.DefaultConfigPath
 	}
 	opts = append(opts, WithConfigPath(path))
+	if tz := viper.GetString("schedule.timezone"); tz != "" {
+		// server default time zone
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, trace.TraceError(errors.ErrorScheduleInvalidTimezone)
+		}
+		opts = append(opts, WithLocation(loc))
+	}
 	res, ok := store.Load(path)
 	if ok {
 		svc, ok = res.(interfaces.ScheduleService)
@@ -0,0 +1,33 @@
+package schedule
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"time"
+)
+
+// ValidateTimezone returns errors.ErrorScheduleInvalidTimezone if the time zone
+// is neither empty nor a valid IANA time zone name
+func ValidateTimezone(tz string) (err error) {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return trace.TraceError(errors.ErrorScheduleInvalidTimezone)
+	}
+	return nil
+}
+
+// GetCronSpec returns the cron spec of the schedule with its time zone, so that
+// next run times are computed in that time zone including DST transitions.
+// Schedules without time zone follow the location of the schedule service.
+func GetCronSpec(s interfaces.Schedule) (spec string, err error) {
+	if s.GetTimezone() == "" {
+		return s.GetCron(), nil
+	}
+	if err := ValidateTimezone(s.GetTimezone()); err != nil {
+		return "", err
+	}
+	return "CRON_TZ=" + s.GetTimezone() + " " + s.GetCron(), nil
+}
@@ -0,0 +1,167 @@
+package admin
+
+import (
+	errors2 "errors"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"os"
+)
+
+// Clone copies the spider document, its git settings and its workspace files
+// to a new spider. Stats, tasks and schedules of the source spider are not
+// copied, so the cloned spider starts with no task history and nothing running.
+func (svc *Service) Clone(id primitive.ObjectID, opts *interfaces.SpiderCloneOptions) (s2 interfaces.Spider, err error) {
+	// source spider
+	src, err := svc.modelSvc.GetSpiderById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// name
+	name, err := svc.getCloneName(src, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// user
+	var u interfaces.User
+	if !opts.UserId.IsZero() {
+		u, err = svc.modelSvc.GetUserById(opts.UserId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// cloned spider
+	s := *src
+	s.Id = primitive.NewObjectID()
+	s.Name = name
+	s.Stat = nil
+	s.DataSource = nil
+
+	// workspace files
+	if err := svc.cloneWorkspace(src.Id, s.Id); err != nil {
+		return nil, err
+	}
+
+	// remove what has been cloned if any step fails, so that no partial
+	// clone is left
+	defer func() {
+		if err != nil {
+			svc.removeClone(s.Id)
+		}
+	}()
+
+	// add spider
+	if err := delegate.NewModelDelegate(&s, u).Add(); err != nil {
+		return nil, err
+	}
+
+	// add stat
+	st := &models.SpiderStat{Id: s.Id}
+	if err := delegate.NewModelDelegate(st, u).Add(); err != nil {
+		return nil, err
+	}
+
+	// git settings
+	g, err := svc.modelSvc.GetGitById(src.Id)
+	if err == nil {
+		g2 := *g
+		g2.Id = s.Id
+		if err := delegate.NewModelDelegate(&g2, u).Add(); err != nil {
+			return nil, err
+		}
+	} else if err != mongo2.ErrNoDocuments {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// removeClone removes the spider, its stat, its git settings and its workspace
+// files added by a failed clone
+func (svc *Service) removeClone(id primitive.ObjectID) {
+	for _, modelId := range []interfaces.ModelId{
+		interfaces.ModelIdGit,
+		interfaces.ModelIdSpiderStat,
+		interfaces.ModelIdSpider,
+	} {
+		if err := svc.modelSvc.GetBaseService(modelId).DeleteById(id); err != nil && !errors2.Is(err, mongo2.ErrNoDocuments) {
+			trace.PrintError(err)
+		}
+	}
+	if err := os.RemoveAll(fs.GetSpiderWorkspacePath(id)); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// getCloneName returns the name of the cloned spider. If name is empty, a name
+// like "<name> (copy)" or "<name> (copy 2)" not taken by any spider is returned.
+func (svc *Service) getCloneName(src *models.Spider, name string) (res string, err error) {
+	if name != "" {
+		exists, err := svc.spiderNameExists(name)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", trace.TraceError(errors.ErrorSpiderAlreadyExists)
+		}
+		return name, nil
+	}
+
+	for i := 1; ; i++ {
+		if i == 1 {
+			res = fmt.Sprintf("%s (copy)", src.Name)
+		} else {
+			res = fmt.Sprintf("%s (copy %d)", src.Name, i)
+		}
+		exists, err := svc.spiderNameExists(res)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return res, nil
+		}
+	}
+}
+
+func (svc *Service) spiderNameExists(name string) (ok bool, err error) {
+	_, err = svc.modelSvc.GetSpider(bson.M{"name": name}, nil)
+	if err == nil {
+		return true, nil
+	}
+	if err == mongo2.ErrNoDocuments {
+		return false, nil
+	}
+	return false, err
+}
+
+// cloneWorkspace copies workspace files of the source spider to the workspace
+// of the cloned spider. Nothing is copied if the source workspace does not exist.
+func (svc *Service) cloneWorkspace(srcId, dstId primitive.ObjectID) (err error) {
+	srcPath := fs.GetSpiderWorkspacePath(srcId)
+	if _, err := os.Stat(srcPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return trace.TraceError(err)
+	}
+	dstPath, err := fs.EnsureSpiderWorkspacePath(dstId)
+	if err != nil {
+		return err
+	}
+	if err := utils.CopyDir(srcPath, dstPath); err != nil {
+		_ = os.RemoveAll(dstPath)
+		return trace.TraceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// EmergencyStop stops a misbehaving spider in one action: the spider is
+// disabled, so that its schedules are skipped and its new tasks are held
+// pending until it is enabled again, and all its running and pending tasks are
+// cancelled. The user and the time of the stop are recorded on the spider.
+func (svc *Service) EmergencyStop(id primitive.ObjectID, args ...interface{}) (res *interfaces.SpiderEmergencyStopResult, err error) {
+	// spider
+	s, err := svc.modelSvc.GetSpiderById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// user
+	u := utils.GetUserFromArgs(args...)
+	res = &interfaces.SpiderEmergencyStopResult{
+		SpiderId: s.Id,
+		StopTs:   time.Now(),
+	}
+	if u != nil {
+		res.StopBy = u.GetId()
+	}
+
+	// disable the spider before cancelling tasks, so that no task is started
+	// in the meantime
+	if err := svc.modelSvc.GetBaseService(interfaces.ModelIdSpider).Update(bson.M{"_id": s.Id}, bson.M{
+		"enabled":           false,
+		"emergency_stop_ts": res.StopTs,
+		"emergency_stop_by": res.StopBy,
+	}, []string{"enabled", "emergency_stop_ts", "emergency_stop_by"}, args...); err != nil {
+		return nil, err
+	}
+	log.Warnf("[SpiderAdminService] emergency stop of spider[%s] triggered by user[%s]", s.Id.Hex(), res.StopBy.Hex())
+
+	// cancel tasks
+	res.Tasks, err = svc.schedulerSvc.CancelTasksBySpider(s.Id, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -6,10 +6,14 @@ import (
 	config2 "github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/grpc/server"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-core/task/scheduler"
 	"github.com/crawlab-team/crawlab-core/utils"
 	vcs "github.com/crawlab-team/crawlab-vcs"
@@ -19,9 +23,9 @@ import (
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
 	"os"
-	"path"
 	"path/filepath"
 	"sync"
 	"time"
@@ -32,6 +36,7 @@ type Service struct {
 	nodeCfgSvc   interfaces.NodeConfigService
 	modelSvc     service.ModelService
 	schedulerSvc interfaces.TaskSchedulerService
+	svr          interfaces.GrpcServer
 	cron         *cron.Cron
 	syncLock     bool
 
@@ -58,15 +63,46 @@ func (svc *Service) Schedule(id primitive.ObjectID, opts *interfaces.SpiderRunOp
 		return nil, err
 	}
 
+	// profile
+	profile := opts.Profile
+	if profile == "" {
+		profile = s.DefaultProfile
+	}
+	p := s.GetProfile(profile)
+	if profile != "" && p == nil {
+		return nil, errors.ErrorSpiderProfileNotFound
+	}
+
+	// validate
+	validateSpider := *s
+	if opts.Cmd != "" {
+		validateSpider.Cmd = opts.Cmd
+	} else if p != nil && p.Cmd != "" {
+		validateSpider.Cmd = p.Cmd
+	}
+	if opts.Concurrency != 0 {
+		validateSpider.Concurrency = opts.Concurrency
+	}
+	if err := svc.ValidateSpiderModel(&validateSpider); err != nil {
+		return nil, err
+	}
+	if err := svc.ValidateGitRef(id, opts.GitRef); err != nil {
+		return nil, err
+	}
+	if !opts.InputId.IsZero() {
+		in, err := task.GetSpiderInput(opts.InputId)
+		if err != nil && err != mongo2.ErrNoDocuments {
+			return nil, trace.TraceError(err)
+		}
+		if in == nil || in.SpiderId != id {
+			return nil, errors.ErrorTaskInputNotFound
+		}
+	}
+
 	// assign tasks
 	return svc.scheduleTasks(s, opts)
 }
 
-func (svc *Service) Clone(id primitive.ObjectID, opts *interfaces.SpiderCloneOptions) (err error) {
-	// TODO: implement
-	return nil
-}
-
 func (svc *Service) Delete(id primitive.ObjectID) (err error) {
 	panic("implement me")
 }
@@ -86,12 +122,14 @@ func (svc *Service) SyncGitOne(g interfaces.Git) (err error) {
 
 func (svc *Service) Export(id primitive.ObjectID) (filePath string, err error) {
 	// spider fs
-	workspacePath := viper.GetString("workspace")
-	spiderFolderPath := filepath.Join(workspacePath, id.Hex())
+	spiderFolderPath := fs.GetSpiderWorkspacePath(id)
 
 	// zip files in workspace
 	dirPath := spiderFolderPath
-	zipFilePath := path.Join(os.TempDir(), uuid.New().String()+".zip")
+	zipFilePath := paths.GetTempPath(uuid.New().String() + ".zip")
+	if err := os.MkdirAll(filepath.Dir(zipFilePath), paths.DirPerm); err != nil {
+		return "", trace.TraceError(err)
+	}
 	if err := utils.ZipDirectory(dirPath, zipFilePath); err != nil {
 		return "", trace.TraceError(err)
 	}
@@ -100,36 +138,7 @@ func (svc *Service) Export(id primitive.ObjectID) (filePath string, err error) {
 }
 
 func (svc *Service) scheduleTasks(s *models.Spider, opts *interfaces.SpiderRunOptions) (taskIds []primitive.ObjectID, err error) {
-	// main task
-	mainTask := &models.Task{
-		SpiderId:   s.Id,
-		Mode:       opts.Mode,
-		NodeIds:    opts.NodeIds,
-		Cmd:        opts.Cmd,
-		Param:      opts.Param,
-		ScheduleId: opts.ScheduleId,
-		Priority:   opts.Priority,
-		UserId:     opts.UserId,
-		CreateTs:   time.Now(),
-	}
-
-	// normalize
-	if mainTask.Mode == "" {
-		mainTask.Mode = s.Mode
-	}
-	if mainTask.NodeIds == nil {
-		mainTask.NodeIds = s.NodeIds
-	}
-	if mainTask.Cmd == "" {
-		mainTask.Cmd = s.Cmd
-	}
-	if mainTask.Param == "" {
-		mainTask.Param = s.Param
-	}
-	if mainTask.Priority == 0 {
-		mainTask.Priority = s.Priority
-	}
-
+	var tasks []*models.Task
 	if svc.isMultiTask(opts) {
 		// multi tasks
 		nodeIds, err := svc.getNodeIds(opts)
@@ -137,25 +146,13 @@ func (svc *Service) scheduleTasks(s *models.Spider, opts *interfaces.SpiderRunOp
 			return nil, err
 		}
 		for _, nodeId := range nodeIds {
-			t := &models.Task{
-				SpiderId:   s.Id,
-				Mode:       opts.Mode,
-				Cmd:        opts.Cmd,
-				Param:      opts.Param,
-				NodeId:     nodeId,
-				ScheduleId: opts.ScheduleId,
-				Priority:   opts.Priority,
-				UserId:     opts.UserId,
-				CreateTs:   time.Now(),
-			}
-			t2, err := svc.schedulerSvc.Enqueue(t)
-			if err != nil {
-				return nil, err
-			}
-			taskIds = append(taskIds, t2.GetId())
+			t := svc.newTask(s, opts)
+			t.NodeId = nodeId
+			tasks = append(tasks, t)
 		}
 	} else {
 		// single task
+		mainTask := svc.newTask(s, opts)
 		nodeIds, err := svc.getNodeIds(opts)
 		if err != nil {
 			return nil, err
@@ -163,22 +160,125 @@ func (svc *Service) scheduleTasks(s *models.Spider, opts *interfaces.SpiderRunOp
 		if len(nodeIds) > 0 {
 			mainTask.NodeId = nodeIds[0]
 		}
-		t2, err := svc.schedulerSvc.Enqueue(mainTask)
+		tasks = append(tasks, mainTask)
+	}
+
+	// node tags and capabilities satisfied, checked before any task is added
+	warnings := make([]string, len(tasks))
+	for i, t := range tasks {
+		warnings[i], err = svc.schedulerSvc.CheckFeasibility(t)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	for i, t := range tasks {
+		t2, err := svc.schedulerSvc.Enqueue(t)
+		if err != nil {
+			return nil, err
+		}
+		if warnings[i] != "" {
+			log.Warnf("[SpiderAdminService] task[%s] %s", t2.GetId().Hex(), warnings[i])
+			e := task.NewTaskEvent(t2.GetId(), constants.TaskEventInfeasible, "", warnings[i])
+			e.NodeId = t2.GetNodeId()
+			task.AddTaskEvent(e)
+		}
 		taskIds = append(taskIds, t2.GetId())
 	}
 
 	return taskIds, nil
 }
 
+// newTask returns a task of the spider with the effective config resolved from
+// run options, falling back to the defaults of the spider. The config is recorded
+// on the task so that later changes of the spider do not affect the task.
+func (svc *Service) newTask(s *models.Spider, opts *interfaces.SpiderRunOptions) (t *models.Task) {
+	t = &models.Task{
+		SpiderId:     s.Id,
+		Mode:         opts.Mode,
+		NodeIds:      opts.NodeIds,
+		Cmd:          opts.Cmd,
+		Param:        opts.Param,
+		ScheduleId:   opts.ScheduleId,
+		Priority:     opts.Priority,
+		Timeout:      s.Timeout,
+		MaxRetries:   s.MaxRetries,
+		MaxItems:     opts.MaxItems,
+		NodeTags:     opts.NodeTags,
+		Capabilities: opts.Capabilities,
+		Checkpoint:   opts.Checkpoint,
+		GitRef:       opts.GitRef,
+		InputId:      opts.InputId,
+		Profile:      opts.Profile,
+		Concurrency:  opts.Concurrency,
+		LockKey:      opts.LockKey,
+		Test:         opts.Test,
+		UserId:       opts.UserId,
+		TraceContext: opts.TraceContext,
+		CreateTs:     time.Now(),
+	}
+
+	// normalize
+	if t.Mode == "" {
+		t.Mode = s.Mode
+	}
+	if t.NodeIds == nil {
+		t.NodeIds = s.NodeIds
+	}
+	if t.Profile == "" {
+		t.Profile = s.DefaultProfile
+	}
+	if t.Cmd == "" {
+		if p := s.GetProfile(t.Profile); p != nil && p.Cmd != "" {
+			t.Cmd = p.Cmd
+		} else {
+			t.Cmd = s.Cmd
+		}
+	}
+	if t.Param == "" {
+		t.Param = s.Param
+	}
+	if t.Priority == 0 {
+		t.Priority = s.Priority
+	}
+	if opts.Timeout != nil {
+		t.Timeout = *opts.Timeout
+	}
+	if opts.MaxRetries != nil {
+		t.MaxRetries = *opts.MaxRetries
+	}
+	if t.MaxItems == 0 {
+		t.MaxItems = s.MaxItems
+	}
+	t.CancelOnMaxItems = s.CancelOnMaxItems
+	if t.Concurrency == 0 {
+		t.Concurrency = s.Concurrency
+	}
+	if t.NodeTags == nil {
+		t.NodeTags = s.NodeTags
+	}
+	if t.Capabilities == nil {
+		t.Capabilities = s.Capabilities
+	}
+	if t.LockKey == "" {
+		t.LockKey = s.LockKey
+	}
+
+	// test tasks are not retried
+	if t.Test {
+		t.MaxRetries = 0
+	}
+
+	return t
+}
+
 func (svc *Service) getNodeIds(opts *interfaces.SpiderRunOptions) (nodeIds []primitive.ObjectID, err error) {
 	if opts.Mode == constants.RunTypeAllNodes {
 		query := bson.M{
-			"active":  true,
-			"enabled": true,
-			"status":  constants.NodeStatusOnline,
+			"active":      true,
+			"enabled":     true,
+			"schedulable": true,
+			"status":      constants.NodeStatusOnline,
 		}
 		nodes, err := svc.modelSvc.GetNodeList(query, nil)
 		if err != nil {
@@ -274,8 +374,7 @@ func (svc *Service) syncGitOne(g interfaces.Git) {
 	defer cancel()
 
 	// git client
-	workspacePath := viper.GetString("workspace")
-	gitClient, err := vcs.NewGitClient(vcs.WithPath(filepath.Join(workspacePath, g.GetId().Hex())))
+	gitClient, err := vcs.NewGitClient(vcs.WithPath(fs.GetSpiderWorkspacePath(g.GetId())))
 	if err != nil {
 		return
 	}
@@ -329,10 +428,14 @@ func NewSpiderAdminService(opts ...Option) (svc2 interfaces.SpiderAdminService,
 	if err := c.Provide(scheduler.ProvideGetTaskSchedulerService(svc.cfgPath)); err != nil {
 		return nil, trace.TraceError(err)
 	}
-	if err := c.Invoke(func(nodeCfgSvc interfaces.NodeConfigService, modelSvc service.ModelService, schedulerSvc interfaces.TaskSchedulerService) {
+	if err := c.Provide(server.ProvideGetServer(svc.cfgPath)); err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if err := c.Invoke(func(nodeCfgSvc interfaces.NodeConfigService, modelSvc service.ModelService, schedulerSvc interfaces.TaskSchedulerService, svr interfaces.GrpcServer) {
 		svc.nodeCfgSvc = nodeCfgSvc
 		svc.modelSvc = modelSvc
 		svc.schedulerSvc = schedulerSvc
+		svc.svr = svr
 	}); err != nil {
 		return nil, trace.TraceError(err)
 	}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	grpc "github.com/crawlab-team/crawlab-grpc"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// SyncFiles pushes a sync request of the spider files to online worker nodes
+// which may run the spider if the sync strategy is eager. With the lazy strategy
+// it does nothing as files are synced by worker nodes before running tasks.
+// Worker nodes offline at the moment sync when they reconnect.
+func (svc *Service) SyncFiles(id primitive.ObjectID) (err error) {
+	if fs.GetSyncStrategy() != constants.SyncStrategyEager {
+		return nil
+	}
+
+	// spider
+	s, err := svc.modelSvc.GetSpiderById(id)
+	if err != nil {
+		return err
+	}
+
+	// online worker nodes which may run the spider
+	query := bson.M{
+		"is_master": false,
+		"active":    true,
+		"enabled":   true,
+		"status":    constants.NodeStatusOnline,
+	}
+	if s.Mode == constants.RunTypeSelectedNodes && len(s.NodeIds) > 0 {
+		query["_id"] = bson.M{"$in": s.NodeIds}
+	}
+	nodes, err := svc.modelSvc.GetNodeList(query, nil)
+	if err != nil {
+		return err
+	}
+
+	// push
+	msg := &entity.SpiderSyncMessage{SpiderId: id}
+	for _, n := range nodes {
+		if err := svc.svr.SendStreamMessageWithData("node:"+n.GetKey(), grpc.StreamMessageCode(constants.GrpcStreamMessageCodeSyncSpider), msg); err != nil {
+			log.Warnf("[SpiderAdminService] failed to push sync of spider[%s] to node[%s]: %v", id.Hex(), n.GetKey(), err)
+		}
+	}
+
+	return nil
+}
+
+// ResyncNode pushes sync requests of files of the spiders to the online worker
+// node of the key regardless of the sync strategy, e.g. after the node was
+// offline during an update or a sync partially failed. If no spider ids are
+// given, all spiders which may run on the node are synced. Syncing is
+// idempotent as the node only downloads files whose md5 hashes differ. Results
+// of files synced are included for spiders whose sync the node reports as
+// finished within resyncWaitTimeout, otherwise the progress is available as in
+// other syncs.
+func (svc *Service) ResyncNode(nodeKey string, spiderIds []primitive.ObjectID) (results []interfaces.SpiderSyncResult, err error) {
+	// node
+	n, err := svc.modelSvc.GetNodeByKey(nodeKey, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, errors.ErrorNodeNotExists
+		}
+		return nil, err
+	}
+	if n.IsMaster || n.Status != constants.NodeStatusOnline {
+		return nil, errors.ErrorNodeInvalidStatus
+	}
+
+	// spiders
+	query := bson.M{
+		"$or": []bson.M{
+			{"mode": bson.M{"$ne": constants.RunTypeSelectedNodes}},
+			{"node_ids": bson.M{"$size": 0}},
+			{"node_ids": n.Id},
+		},
+	}
+	if len(spiderIds) > 0 {
+		query = bson.M{"_id": bson.M{"$in": spiderIds}}
+	}
+	spiders, err := svc.modelSvc.GetSpiderList(query, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, err
+	}
+
+	// push
+	results = []interfaces.SpiderSyncResult{}
+	for _, s := range spiders {
+		res := interfaces.SpiderSyncResult{SpiderId: s.Id}
+		// progress of earlier syncs is dropped, so that the progress waited
+		// for is reported by syncs from now on
+		fs.DeleteSyncProgress(n.Key, s.Id)
+		msg := &entity.SpiderSyncMessage{SpiderId: s.Id}
+		if err := svc.svr.SendStreamMessageWithData("node:"+n.Key, grpc.StreamMessageCode(constants.GrpcStreamMessageCodeSyncSpider), msg); err != nil {
+			log.Warnf("[SpiderAdminService] failed to push resync of spider[%s] to node[%s]: %v", s.Id.Hex(), n.Key, err)
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+		}
+		results = append(results, res)
+	}
+	log.Infof("[SpiderAdminService] pushed resync of %d spiders to node[%s]", len(results), n.Key)
+
+	// results of files reported by the node
+	svc.waitResyncResults(n.Key, results)
+
+	return results, nil
+}
+
+// resyncWaitTimeout is the max duration waiting for a node to report results
+// of files synced by a resync
+var resyncWaitTimeout = 30 * time.Second
+
+// waitResyncResults fills results of files of the pushed syncs whose
+// completion is reported by the node, until all are finished or
+// resyncWaitTimeout elapses
+func (svc *Service) waitResyncResults(nodeKey string, results []interfaces.SpiderSyncResult) {
+	deadline := time.Now().Add(resyncWaitTimeout)
+	for {
+		pending := 0
+		for i := range results {
+			res := &results[i]
+			if !res.Success || res.Finished {
+				continue
+			}
+			p, ok := fs.GetSyncProgress(nodeKey, res.SpiderId)
+			if !ok || !p.Finished {
+				pending++
+				continue
+			}
+			res.Finished = true
+			res.Files = p.Files
+			if p.Error != "" {
+				res.Success = false
+				res.Error = p.Error
+			}
+		}
+		if pending == 0 || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
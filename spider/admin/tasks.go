@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetSpiderTasks returns a page of tasks of the spider joined with their stats
+// and nodes, latest first
+func (svc *Service) GetSpiderTasks(id primitive.ObjectID, page, size int, filters *interfaces.SpiderTaskFilters) (items []interfaces.SpiderTaskHistoryItem, total int, err error) {
+	// query
+	query := bson.M{"spider_id": id, "test": bson.M{"$ne": true}}
+	if filters != nil {
+		if filters.Status != "" {
+			query["status"] = filters.Status
+		}
+		if !filters.NodeId.IsZero() {
+			query["node_id"] = filters.NodeId
+		}
+		createTs := bson.M{}
+		if !filters.StartTs.IsZero() {
+			createTs["$gte"] = filters.StartTs
+		}
+		if !filters.EndTs.IsZero() {
+			createTs["$lt"] = filters.EndTs
+		}
+		if len(createTs) > 0 {
+			query["create_ts"] = createTs
+		}
+	}
+
+	// total
+	total, err = svc.modelSvc.GetBaseService(interfaces.ModelIdTask).Count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	// tasks
+	tasks, err := svc.modelSvc.GetTaskList(query, &mongo.FindOptions{
+		Sort:  bson.D{{"_id", -1}},
+		Skip:  size * (page - 1),
+		Limit: size,
+	})
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, total, nil
+		}
+		return nil, 0, err
+	}
+	var taskIds, nodeIds []primitive.ObjectID
+	for _, t := range tasks {
+		taskIds = append(taskIds, t.Id)
+		if !t.NodeId.IsZero() {
+			nodeIds = append(nodeIds, t.NodeId)
+		}
+	}
+
+	// stats
+	stats, err := svc.modelSvc.GetTaskStatList(bson.M{"_id": bson.M{"$in": taskIds}}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, 0, err
+	}
+	statsDict := map[primitive.ObjectID]models.TaskStat{}
+	for _, st := range stats {
+		statsDict[st.Id] = st
+	}
+
+	// nodes
+	nodesDict := map[primitive.ObjectID]models.Node{}
+	if len(nodeIds) > 0 {
+		nodes, err := svc.modelSvc.GetNodeList(bson.M{"_id": bson.M{"$in": nodeIds}}, nil)
+		if err != nil && err != mongo2.ErrNoDocuments {
+			return nil, 0, err
+		}
+		for _, n := range nodes {
+			nodesDict[n.Id] = n
+		}
+	}
+
+	// items
+	for _, t := range tasks {
+		item := interfaces.SpiderTaskHistoryItem{
+			TaskId:     t.Id,
+			Status:     t.Status,
+			Error:      t.Error,
+			NodeId:     t.NodeId,
+			CreateTs:   t.CreateTs,
+			LogsUrl:    fmt.Sprintf("/tasks/%s/logs", t.Id.Hex()),
+			ResultsUrl: fmt.Sprintf("/tasks/%s/data", t.Id.Hex()),
+		}
+		if st, ok := statsDict[t.Id]; ok {
+			item.StartTs = st.StartTs
+			item.EndTs = st.EndTs
+			item.RuntimeDuration = st.RuntimeDuration
+			item.ResultCount = st.ResultCount
+			item.ErrorLogCount = st.ErrorLogCount
+			if item.CreateTs.IsZero() {
+				item.CreateTs = st.CreateTs
+			}
+		}
+		if n, ok := nodesDict[t.NodeId]; ok {
+			item.NodeName = n.Name
+		}
+		items = append(items, item)
+	}
+
+	return items, total, nil
+}
@@ -0,0 +1,230 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/result"
+	"github.com/crawlab-team/crawlab-core/task/log"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-core/webhook"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+func (svc *Service) ValidateSpider(id primitive.ObjectID) (err error) {
+	s, err := svc.modelSvc.GetSpiderById(id)
+	if err != nil {
+		return err
+	}
+	return svc.ValidateSpiderModel(s)
+}
+
+func (svc *Service) ValidateSpiderModel(s interfaces.Spider) (err error) {
+	var errs errors.ValidationErrors
+
+	// cmd
+	cmd := strings.TrimSpace(s.GetCmd())
+	if cmd == "" {
+		errs = append(errs, errors.ValidationError{
+			Field:   "cmd",
+			Message: "cmd is required",
+		})
+	} else if entrypoint := getCmdEntrypoint(cmd); entrypoint != "" {
+		// entrypoint is only checked when the workspace exists
+		workspaceDir := fs.GetSpiderWorkspacePath(s.GetId())
+		if utils.Exists(workspaceDir) && !utils.Exists(filepath.Join(workspaceDir, entrypoint)) {
+			errs = append(errs, errors.ValidationError{
+				Field:   "cmd",
+				Message: fmt.Sprintf("entrypoint %s does not exist in workspace", entrypoint),
+			})
+		}
+	}
+
+	// output encoding
+	if _, err := utils.GetEncoding(s.GetOutputEncoding()); err != nil {
+		errs = append(errs, errors.ValidationError{
+			Field:   "output_encoding",
+			Message: fmt.Sprintf("invalid output encoding %s", s.GetOutputEncoding()),
+		})
+	}
+
+	// schedules
+	if !s.GetId().IsZero() {
+		schedules, err := svc.modelSvc.GetScheduleList(bson.M{"spider_id": s.GetId()}, nil)
+		if err != nil {
+			return err
+		}
+		sm, _ := s.(*models.Spider)
+		for _, sch := range schedules {
+			if _, err := cron.ParseStandard(sch.Cron); err != nil {
+				errs = append(errs, errors.ValidationError{
+					Field:   "schedules." + sch.Id.Hex() + ".cron",
+					Message: fmt.Sprintf("invalid cron expression \"%s\": %v", sch.Cron, err),
+				})
+			}
+			if sm != nil && sch.Profile != "" && sm.GetProfile(sch.Profile) == nil {
+				errs = append(errs, errors.ValidationError{
+					Field:   "schedules." + sch.Id.Hex() + ".profile",
+					Message: fmt.Sprintf("profile %s does not exist", sch.Profile),
+				})
+			}
+		}
+	}
+
+	// field mappings, number mode and webhook
+	if sm, ok := s.(*models.Spider); ok {
+		errs = append(errs, result.ValidateFieldMappings(sm.FieldMappings)...)
+		switch sm.ResultNumberMode {
+		case "", constants.ResultNumberModeFloat, constants.ResultNumberModeInt, constants.ResultNumberModeDecimal:
+		default:
+			errs = append(errs, errors.ValidationError{Field: "result_number_mode", Message: fmt.Sprintf("invalid result number mode %s", sm.ResultNumberMode)})
+		}
+		errs = append(errs, validateProfiles(sm)...)
+		errs = append(errs, validateWebhook(sm.Webhook)...)
+		errs = append(errs, validateConcurrency(sm)...)
+		errs = append(errs, validateRuntime(sm)...)
+		if sm.LogTtl != "" {
+			if _, ok := log.ParseLogTtl(sm.LogTtl); !ok {
+				errs = append(errs, errors.ValidationError{Field: "log_ttl", Message: fmt.Sprintf("invalid log ttl %s, expected e.g. 90d", sm.LogTtl)})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateGitRef checks the git ref to run tasks of the spider with, if any,
+// and that git is configured for the spider
+func (svc *Service) ValidateGitRef(id primitive.ObjectID, ref string) (err error) {
+	if ref == "" {
+		return nil
+	}
+	if !utils.IsValidGitRef(ref) {
+		return errors.ValidationErrors{{Field: "git_ref", Message: fmt.Sprintf("invalid git ref %s", ref)}}
+	}
+	if _, err := svc.modelSvc.GetGitById(id); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return errors.ErrorTaskGitNotConfigured
+		}
+		return err
+	}
+	return nil
+}
+
+// validateProfiles checks names of the profiles are unique, their cmd and
+// variables, and the default profile exists
+func validateProfiles(s *models.Spider) (errs errors.ValidationErrors) {
+	names := map[string]bool{}
+	for i, p := range s.Profiles {
+		field := fmt.Sprintf("profiles.%d", i)
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			errs = append(errs, errors.ValidationError{Field: field + ".name", Message: "name is required"})
+		} else if names[name] {
+			errs = append(errs, errors.ValidationError{Field: field + ".name", Message: fmt.Sprintf("duplicate profile %s", name)})
+		}
+		names[name] = true
+		if entrypoint := getCmdEntrypoint(strings.TrimSpace(p.Cmd)); entrypoint != "" {
+			workspaceDir := fs.GetSpiderWorkspacePath(s.GetId())
+			if utils.Exists(workspaceDir) && !utils.Exists(filepath.Join(workspaceDir, entrypoint)) {
+				errs = append(errs, errors.ValidationError{Field: field + ".cmd", Message: fmt.Sprintf("entrypoint %s does not exist in workspace", entrypoint)})
+			}
+		}
+		for k := range p.Variables {
+			if k == "" || strings.ContainsAny(k, "=\x00") {
+				errs = append(errs, errors.ValidationError{Field: field + ".variables", Message: fmt.Sprintf("invalid variable name \"%s\"", k)})
+			}
+		}
+	}
+	if s.DefaultProfile != "" && s.GetProfile(s.DefaultProfile) == nil {
+		errs = append(errs, errors.ValidationError{Field: "default_profile", Message: fmt.Sprintf("profile %s does not exist", s.DefaultProfile)})
+	}
+	return errs
+}
+
+// validateConcurrency checks the concurrency is positive if set, and is set if
+// the cmd has the concurrency placeholder
+func validateConcurrency(s *models.Spider) (errs errors.ValidationErrors) {
+	if s.Concurrency < 0 {
+		errs = append(errs, errors.ValidationError{Field: "concurrency", Message: "concurrency must be positive"})
+	} else if s.Concurrency == 0 && strings.Contains(s.Cmd, constants.TaskConcurrencyPlaceholder) {
+		errs = append(errs, errors.ValidationError{Field: "concurrency", Message: fmt.Sprintf("concurrency is required by %s in cmd", constants.TaskConcurrencyPlaceholder)})
+	}
+	return errs
+}
+
+// validateRuntime checks the runtime of the spider and its container
+func validateRuntime(s *models.Spider) (errs errors.ValidationErrors) {
+	switch s.Runtime {
+	case "", constants.SpiderRuntimeProcess:
+		return nil
+	case constants.SpiderRuntimeDocker:
+	default:
+		return append(errs, errors.ValidationError{Field: "runtime", Message: fmt.Sprintf("invalid runtime %s", s.Runtime)})
+	}
+	if s.Container == nil || strings.TrimSpace(s.Container.Image) == "" {
+		return append(errs, errors.ValidationError{Field: "container.image", Message: "image is required by the docker runtime"})
+	}
+	if !utils.IsValidDockerImage(s.Container.Image) {
+		errs = append(errs, errors.ValidationError{Field: "container.image", Message: fmt.Sprintf("invalid image %s", s.Container.Image)})
+	}
+	if s.Container.Network != "" && !utils.IsValidDockerNetwork(s.Container.Network) {
+		errs = append(errs, errors.ValidationError{Field: "container.network", Message: fmt.Sprintf("invalid network %s", s.Container.Network)})
+	} else if s.Container.Network != "" && !utils.IsAllowedDockerNetwork(s.Container.Network) {
+		errs = append(errs, errors.ValidationError{Field: "container.network", Message: fmt.Sprintf("network %s is not allowed, see task.container.allowedNetworks", s.Container.Network)})
+	}
+	switch s.Container.Pull {
+	case "", constants.ContainerPullMissing, constants.ContainerPullAlways, constants.ContainerPullNever:
+	default:
+		errs = append(errs, errors.ValidationError{Field: "container.pull", Message: fmt.Sprintf("invalid pull policy %s", s.Container.Pull)})
+	}
+	return errs
+}
+
+// validateWebhook checks the url and the status filter of the spider webhook
+func validateWebhook(wh *entity.SpiderWebhook) (errs errors.ValidationErrors) {
+	if wh == nil || wh.Url == "" {
+		return nil
+	}
+	if u, err := url.Parse(wh.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.ValidationError{Field: "webhook.url", Message: fmt.Sprintf("invalid webhook url %s", wh.Url)})
+	} else if !webhook.IsHostAllowed(u.Hostname()) {
+		errs = append(errs, errors.ValidationError{Field: "webhook.url", Message: fmt.Sprintf("address of webhook url %s is not allowed", wh.Url)})
+	}
+	switch wh.On {
+	case "", constants.WebhookOnAll, constants.WebhookOnSuccess, constants.WebhookOnFailure:
+	default:
+		errs = append(errs, errors.ValidationError{Field: "webhook.on", Message: fmt.Sprintf("invalid webhook trigger %s", wh.On)})
+	}
+	return errs
+}
+
+// getCmdEntrypoint returns the first relative file path argument of the command
+func getCmdEntrypoint(cmd string) (entrypoint string) {
+	for _, arg := range strings.Fields(cmd)[1:] {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if filepath.IsAbs(arg) {
+			return ""
+		}
+		if filepath.Ext(arg) != "" || strings.Contains(arg, "/") {
+			return arg
+		}
+		return ""
+	}
+	return ""
+}
@@ -3,10 +3,14 @@ package stats
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
 )
 
 type Service struct {
@@ -52,7 +56,7 @@ func (svc *Service) GetOverviewStats(query bson.M) (data interface{}, err error)
 	}
 
 	// tasks
-	stats["tasks"], err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(nil)
+	stats["tasks"], err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(bson.M{"test": bson.M{"$ne": true}})
 	if err != nil {
 		if err.Error() != mongo2.ErrNoDocuments.Error() {
 			return nil, err
@@ -61,7 +65,7 @@ func (svc *Service) GetOverviewStats(query bson.M) (data interface{}, err error)
 	}
 
 	// error tasks
-	stats["error_tasks"], err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(bson.M{"status": constants.TaskStatusError})
+	stats["error_tasks"], err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(bson.M{"status": constants.TaskStatusError, "test": bson.M{"$ne": true}})
 	if err != nil {
 		if err.Error() != mongo2.ErrNoDocuments.Error() {
 			return nil, err
@@ -122,6 +126,118 @@ func (svc *Service) GetTaskStats(query bson.M) (data interface{}, err error) {
 	return stats, nil
 }
 
+func (svc *Service) GetSpiderTaskTimeSeries(spiderId primitive.ObjectID, interval string, start, end time.Time) (data interface{}, err error) {
+	format, step, err := getTimeSeriesFormat(interval)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo2.Pipeline{
+		{{
+			"$match",
+			bson.M{
+				"spider_id": spiderId,
+				"test":      bson.M{"$ne": true},
+				"status": bson.M{"$in": bson.A{
+					constants.TaskStatusFinished,
+					constants.TaskStatusError,
+					constants.TaskStatusAbnormal,
+					constants.TaskStatusCancelled,
+				}},
+			},
+		}},
+		{{
+			"$lookup",
+			bson.M{
+				"from":         interfaces.ModelColNameTaskStat,
+				"localField":   "_id",
+				"foreignField": "_id",
+				"as":           "_ts",
+			},
+		}},
+		{{
+			"$addFields",
+			bson.M{
+				"end_ts": bson.M{"$arrayElemAt": bson.A{"$_ts.end_ts", 0}},
+			},
+		}},
+		{{
+			"$match",
+			bson.M{
+				"end_ts": bson.M{
+					"$gte": start,
+					"$lt":  end,
+				},
+			},
+		}},
+		{{
+			"$group",
+			bson.M{
+				"_id": bson.M{
+					"$dateToString": bson.M{
+						"date":     "$end_ts",
+						"format":   format,
+						"timezone": start.Location().String(),
+					},
+				},
+				"success":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", constants.TaskStatusFinished}}, 1, 0}}},
+				"failure":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$status", bson.A{constants.TaskStatusError, constants.TaskStatusAbnormal}}}, 1, 0}}},
+				"cancelled": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", constants.TaskStatusCancelled}}, 1, 0}}},
+			},
+		}},
+	}
+	var results []entity.StatsTimeSeriesItem
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTask).Aggregate(pipeline, nil).All(&results); err != nil {
+		return nil, err
+	}
+	return fillTimeSeries(results, start, end, step), nil
+}
+
+// getTimeSeriesFormat returns the $dateToString format and the duration of
+// buckets of the interval
+func getTimeSeriesFormat(interval string) (format string, step time.Duration, err error) {
+	switch interval {
+	case constants.StatsIntervalHour:
+		return "%Y-%m-%d %H:00", time.Hour, nil
+	case constants.StatsIntervalDay, "":
+		return "%Y-%m-%d", 24 * time.Hour, nil
+	default:
+		return "", 0, trace.TraceError(errors.ErrorStatsInvalidInterval)
+	}
+}
+
+// fillTimeSeries returns an item for each bucket in [start, end), with zero
+// counts for buckets without completed tasks, so that charts are continuous
+func fillTimeSeries(items []entity.StatsTimeSeriesItem, start, end time.Time, step time.Duration) (res []entity.StatsTimeSeriesItem) {
+	itemsMap := map[string]entity.StatsTimeSeriesItem{}
+	for _, item := range items {
+		itemsMap[item.Time] = item
+	}
+	layout := "2006-01-02"
+	t := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if step == time.Hour {
+		layout = "2006-01-02 15:00"
+		t = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, start.Location())
+	}
+	for ; t.Before(end); t = nextBucket(t, step) {
+		key := t.Format(layout)
+		item, ok := itemsMap[key]
+		if !ok {
+			item = entity.StatsTimeSeriesItem{Time: key}
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// nextBucket returns the start of the next bucket, stepping days by calendar
+// so that buckets stay aligned across daylight saving changes
+func nextBucket(t time.Time, step time.Duration) (next time.Time) {
+	if step == time.Hour {
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
 func (svc *Service) getDailyTasksStats(query bson.M) (data interface{}, err error) {
 	pipeline := mongo2.Pipeline{
 		{{
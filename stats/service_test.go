@@ -0,0 +1,31 @@
+package stats
+
+import (
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFillTimeSeries_Day(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.Nil(t, err)
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 3)
+	items := []entity.StatsTimeSeriesItem{{Time: "2022-10-02", Success: 2, Failure: 1}}
+	res := fillTimeSeries(items, start, end, 24*time.Hour)
+	require.Len(t, res, 3)
+	require.Equal(t, "2022-10-01", res[0].Time)
+	require.Equal(t, int64(0), res[0].Success)
+	require.Equal(t, items[0], res[1])
+	require.Equal(t, "2022-10-03", res[2].Time)
+}
+
+func TestFillTimeSeries_Hour(t *testing.T) {
+	start := time.Date(2022, 10, 1, 22, 30, 0, 0, time.UTC)
+	end := time.Date(2022, 10, 2, 1, 0, 0, 0, time.UTC)
+	res := fillTimeSeries(nil, start, end, time.Hour)
+	require.Len(t, res, 3)
+	require.Equal(t, "2022-10-01 22:00", res[0].Time)
+	require.Equal(t, "2022-10-02 00:00", res[2].Time)
+}
@@ -0,0 +1,273 @@
+package task
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/blob"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Task artifacts are files produced by a task process, e.g. screenshots or
+// downloaded documents. The process puts them in the directory given by the
+// environment variable constants.TaskArtifactsDirEnv, and the node uploads them
+// to master (POST /sync/tasks/:id/artifacts) after the process exits. Master
+// stores the files in the blob store (see blob.GetBlobStore) and their metadata
+// in constants.TaskArtifactColName.
+
+// TaskArtifact is the metadata of a file uploaded by a task
+type TaskArtifact struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id"`
+	TaskId      primitive.ObjectID `json:"task_id" bson:"task_id"`
+	Filename    string             `json:"filename" bson:"filename"`
+	Size        int64              `json:"size" bson:"size"`
+	ContentType string             `json:"content_type" bson:"content_type"`
+	CreateTs    time.Time          `json:"create_ts" bson:"create_ts"`
+}
+
+// GetTaskArtifactMaxSize returns the max total size (bytes) of artifacts of a
+// task, which is configured by "task.artifacts.maxSize" (1 GB by default)
+func GetTaskArtifactMaxSize() (size int64) {
+	if size = viper.GetInt64("task.artifacts.maxSize"); size > 0 {
+		return size
+	}
+	return 1 << 30
+}
+
+// GetTaskArtifactKey returns the blob key of the artifact
+func GetTaskArtifactKey(a *TaskArtifact) (key string) {
+	return getTaskArtifactsPrefix(a.TaskId) + "/" + a.Id.Hex()
+}
+
+func getTaskArtifactsPrefix(taskId primitive.ObjectID) (prefix string) {
+	return "artifacts/" + taskId.Hex()
+}
+
+// artifactLock serializes uploads of artifacts of a task. It is removed from
+// artifactLocks once no upload holds or waits for it.
+type artifactLock struct {
+	sync.Mutex
+	refs int
+}
+
+var artifactLocks = map[primitive.ObjectID]*artifactLock{}
+var artifactLocksMu sync.Mutex
+
+// lockTaskArtifacts locks uploads of artifacts of the task
+func lockTaskArtifacts(taskId primitive.ObjectID) (unlock func()) {
+	artifactLocksMu.Lock()
+	l, ok := artifactLocks[taskId]
+	if !ok {
+		l = &artifactLock{}
+		artifactLocks[taskId] = l
+	}
+	l.refs++
+	artifactLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		artifactLocksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(artifactLocks, taskId)
+		}
+		artifactLocksMu.Unlock()
+	}
+}
+
+// SaveTaskArtifact stores the artifact read from r on master. It returns
+// errors.ErrorTaskArtifactSizeExceeded without storing anything if the total
+// size of artifacts of the task would exceed GetTaskArtifactMaxSize.
+func SaveTaskArtifact(taskId primitive.ObjectID, filename, contentType string, r io.Reader) (a *TaskArtifact, err error) {
+	filename = path.Base(filepath.ToSlash(filename))
+	if filename == "." || filename == "/" || filename == ".." {
+		return nil, trace.TraceError(errors.ErrorTaskArtifactInvalidName)
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// uploads of the same task are serialized to enforce the size limit
+	unlock := lockTaskArtifacts(taskId)
+	defer unlock()
+
+	// remaining size
+	artifacts, err := GetTaskArtifacts(taskId)
+	if err != nil {
+		return nil, err
+	}
+	remaining := GetTaskArtifactMaxSize()
+	for _, a := range artifacts {
+		remaining -= a.Size
+	}
+	if remaining <= 0 {
+		return nil, trace.TraceError(errors.ErrorTaskArtifactSizeExceeded)
+	}
+
+	a = &TaskArtifact{
+		Id:          primitive.NewObjectID(),
+		TaskId:      taskId,
+		Filename:    filename,
+		ContentType: contentType,
+		CreateTs:    time.Now(),
+	}
+
+	// buffer to a temp file first to check the size
+	f, err := os.CreateTemp("", "crawlab_artifact_")
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	a.Size, err = io.Copy(f, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if a.Size > remaining {
+		return nil, trace.TraceError(errors.ErrorTaskArtifactSizeExceeded)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	// store file
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(GetTaskArtifactKey(a), f, a.Size, a.ContentType); err != nil {
+		return nil, err
+	}
+
+	// add metadata
+	if _, err := mongo.GetMongoCol(constants.TaskArtifactColName).Insert(a); err != nil {
+		_ = store.DeleteAll(GetTaskArtifactKey(a))
+		return nil, trace.TraceError(err)
+	}
+
+	return a, nil
+}
+
+// GetTaskArtifacts returns metadata of artifacts of the task in upload order
+func GetTaskArtifacts(taskId primitive.ObjectID) (artifacts []TaskArtifact, err error) {
+	artifacts = []TaskArtifact{}
+	if err := mongo.GetMongoCol(constants.TaskArtifactColName).Find(bson.M{"task_id": taskId}, &mongo.FindOptions{
+		Sort: bson.D{{"_id", 1}},
+	}).All(&artifacts); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	return artifacts, nil
+}
+
+// GetTaskArtifact returns metadata of the artifact of the task
+func GetTaskArtifact(taskId, id primitive.ObjectID) (a *TaskArtifact, err error) {
+	a = &TaskArtifact{}
+	if err := mongo.GetMongoCol(constants.TaskArtifactColName).Find(bson.M{"_id": id, "task_id": taskId}, nil).One(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// DeleteTaskArtifacts removes artifacts of the tasks and their metadata
+func DeleteTaskArtifacts(taskIds ...primitive.ObjectID) (err error) {
+	if len(taskIds) == 0 {
+		return nil
+	}
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		return err
+	}
+	for _, id := range taskIds {
+		if err := store.DeleteAll(getTaskArtifactsPrefix(id)); err != nil {
+			return err
+		}
+	}
+	if err := mongo.GetMongoCol(constants.TaskArtifactColName).Delete(bson.M{"task_id": bson.M{"$in": taskIds}}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+var artifactHttpClient = &http.Client{Timeout: 30 * time.Minute}
+
+// UploadTaskArtifact uploads the file as an artifact of the task to master.
+// The file is streamed in the request body, so large files are not loaded
+// into memory.
+func UploadTaskArtifact(taskId primitive.ObjectID, filePath string) (err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return trace.TraceError(err)
+	}
+
+	u := fmt.Sprintf("%s/sync/tasks/%s/artifacts?%s", utils.GetApiAddress(), taskId.Hex(), url.Values{
+		"filename": []string{filepath.Base(filePath)},
+	}.Encode())
+	req, err := http.NewRequest(http.MethodPost, u, f)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set(constants.SyncHeaderAuthorization, fs.GetSyncAuthKey())
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := artifactHttpClient.Do(req)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return trace.TraceError(fmt.Errorf("upload artifact %s failed: %s", filepath.Base(filePath), res.Status))
+	}
+	return nil
+}
+
+// UploadTaskArtifacts uploads all regular files in the directory (not
+// recursively) as artifacts of the task. Files failed to upload are logged and
+// skipped.
+func UploadTaskArtifacts(taskId primitive.ObjectID, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			trace.PrintError(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := UploadTaskArtifact(taskId, filepath.Join(dir, e.Name())); err != nil {
+			log.Warnf("task[%s] failed to upload artifact %s: %v", taskId.Hex(), e.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package task
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLockTaskArtifacts(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	// uploads of the same task are serialized
+	var wg sync.WaitGroup
+	var running int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := lockTaskArtifacts(id)
+			defer unlock()
+			require.Equal(t, int32(1), atomic.AddInt32(&running, 1))
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	// lock removed once released
+	artifactLocksMu.Lock()
+	defer artifactLocksMu.Unlock()
+	_, ok := artifactLocks[id]
+	require.False(t, ok)
+}
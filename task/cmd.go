@@ -0,0 +1,107 @@
+package task
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/spf13/viper"
+	"regexp"
+	"strings"
+)
+
+var cmdPlaceholderRegexp = regexp.MustCompile(`{{\s*[\w.-]+\s*}}`)
+
+// GetTaskCmd returns the cmd of the task with its parameters, or those of the
+// spider if the task does not customize them
+func GetTaskCmd(t interfaces.Task, s interfaces.Spider) (cmd string) {
+	// customized spider
+	if t.GetCmd() == "" {
+		cmd = s.GetCmd()
+	} else {
+		cmd = t.GetCmd()
+	}
+
+	// parameters
+	if t.GetParam() != "" {
+		cmd += " " + t.GetParam()
+	} else if s.GetParam() != "" {
+		cmd += " " + s.GetParam()
+	}
+
+	return cmd
+}
+
+// IsCmdValidationEnabled returns whether cmds of tasks are validated before
+// they are dispatched, which is configured by "task.cmdValidation.enabled"
+// (default true)
+func IsCmdValidationEnabled() (ok bool) {
+	if !viper.IsSet("task.cmdValidation.enabled") {
+		return true
+	}
+	return viper.GetBool("task.cmdValidation.enabled")
+}
+
+// ValidateTaskCmd statically checks the cmd of the task before it is
+// dispatched to the node, so that it fails fast instead of spawning a process
+// which errors immediately. It checks that all placeholders in the cmd are
+// provided, and that the executable is available on the node by its
+// capability manifest, or the docker cli if the task runs in a container,
+// returning errors.ErrorTaskExecutableNotFound otherwise. Executables not
+// probed for in the manifest of the node, e.g. paths or shell builtins, are
+// not checked.
+func ValidateTaskCmd(t interfaces.Task, s interfaces.Spider, n interfaces.Node) (err error) {
+	cmd := strings.TrimSpace(GetTaskCmd(t, s))
+	if cmd == "" {
+		return fmt.Errorf("%w: cmd is empty", errors.ErrorTaskInvalidCmd)
+	}
+
+	// placeholders
+	for _, placeholder := range cmdPlaceholderRegexp.FindAllString(cmd, -1) {
+		switch placeholder {
+		case constants.TaskConcurrencyPlaceholder:
+			if t.GetConcurrency() > 0 {
+				continue
+			}
+			return fmt.Errorf("%w: concurrency of %s is not provided", errors.ErrorTaskInvalidCmd, placeholder)
+		default:
+			return fmt.Errorf("%w: unknown placeholder %s", errors.ErrorTaskInvalidCmd, placeholder)
+		}
+	}
+
+	// executable, the docker cli for containers whose executables are in images
+	executable := getCmdExecutable(cmd)
+	if sm, ok := s.(*models.Spider); ok && sm.IsContainerRuntime() {
+		executable = "docker"
+	}
+	capabilities := n.GetCapabilities()
+	if executable == "" || capabilities == nil {
+		// unknown
+		return nil
+	}
+	if !utils.Contains(capabilities.Probed, executable) {
+		// not probed for by the node, e.g. reported by older nodes
+		return nil
+	}
+	if !capabilities.HasExecutables([]string{executable}) {
+		return fmt.Errorf("%w: %s on node %s", errors.ErrorTaskExecutableNotFound, executable, n.GetName())
+	}
+	return nil
+}
+
+// getCmdExecutable returns the name of the executable of the cmd, skipping
+// environment variable assignments, or empty if it is a path
+func getCmdExecutable(cmd string) (name string) {
+	for _, field := range strings.Fields(cmd) {
+		if strings.Contains(field, "=") {
+			continue
+		}
+		if strings.ContainsAny(field, `/\`) {
+			return ""
+		}
+		return field
+	}
+	return ""
+}
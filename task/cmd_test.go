@@ -0,0 +1,54 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateTaskCmd(t *testing.T) {
+	s := &models.Spider{Cmd: "scrapy crawl quotes", Param: "-a page=1"}
+	n := &models.Node{
+		Name: "worker-1",
+		Capabilities: &interfaces.NodeCapabilities{
+			Executables: map[string]string{"python": "Python 3.9.0"},
+			Probed:      []string{"python", "scrapy"},
+		},
+	}
+
+	// cmd of the spider with its params
+	require.Equal(t, "scrapy crawl quotes -a page=1", GetTaskCmd(&models.Task{}, s))
+	require.Equal(t, "python main.py -x", GetTaskCmd(&models.Task{Cmd: "python main.py", Param: "-x"}, s))
+
+	// executable not found on the node
+	err := ValidateTaskCmd(&models.Task{}, s, n)
+	require.ErrorIs(t, err, errors.ErrorTaskExecutableNotFound)
+	require.Contains(t, err.Error(), "scrapy")
+
+	// executable found, env assignments skipped
+	require.Nil(t, ValidateTaskCmd(&models.Task{Cmd: "FOO=bar python main.py"}, s, n))
+
+	// paths and executables not probed for by the node are not checked,
+	// regardless of executables to detect configured on master
+	viper.Set("node.capabilities.executables", []string{"bash"})
+	defer viper.Set("node.capabilities.executables", nil)
+	require.Nil(t, ValidateTaskCmd(&models.Task{Cmd: "./run.sh"}, s, n))
+	require.Nil(t, ValidateTaskCmd(&models.Task{Cmd: "bash run.sh"}, s, n))
+
+	// nodes without manifests, or with manifests of older nodes not reporting
+	// executables probed for, are not checked
+	require.Nil(t, ValidateTaskCmd(&models.Task{}, s, &models.Node{}))
+	require.Nil(t, ValidateTaskCmd(&models.Task{}, s, &models.Node{
+		Capabilities: &interfaces.NodeCapabilities{Executables: map[string]string{"python": "Python 3.9.0"}},
+	}))
+
+	// placeholders
+	tc := &models.Task{Cmd: "python main.py -c {{concurrency}}"}
+	require.ErrorIs(t, ValidateTaskCmd(tc, s, n), errors.ErrorTaskInvalidCmd)
+	tc.Concurrency = 4
+	require.Nil(t, ValidateTaskCmd(tc, s, n))
+	require.ErrorIs(t, ValidateTaskCmd(&models.Task{Cmd: "python main.py {{token}}"}, s, n), errors.ErrorTaskInvalidCmd)
+}
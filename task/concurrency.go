@@ -0,0 +1,67 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConcurrencySettings of tasks of the cluster, stored in the setting
+// constants.SettingKeyTaskConcurrency
+type ConcurrencySettings struct {
+	MaxConcurrentTasks int `json:"max_concurrent_tasks" bson:"max_concurrent_tasks"` // 0 to follow the config
+}
+
+// GetMaxConcurrentTasks returns the max number of tasks running at the same
+// time in the whole cluster, 0 if unlimited. The setting
+// constants.SettingKeyTaskConcurrency takes precedence over the config
+// "task.maxConcurrentTasks" if set above 0. The setting is cached (see
+// service.ModelService.GetSettingValue), as it is read on each fetch of tasks.
+func GetMaxConcurrentTasks() (n int) {
+	n = viper.GetInt("task.maxConcurrentTasks")
+	modelSvc, err := service.GetService()
+	if err != nil {
+		trace.PrintError(err)
+		return n
+	}
+	var s ConcurrencySettings
+	if _, err := modelSvc.GetSettingValue(constants.SettingKeyTaskConcurrency, &s); err != nil {
+		trace.PrintError(err)
+		return n
+	}
+	if s.MaxConcurrentTasks > 0 {
+		return s.MaxConcurrentTasks
+	}
+	return n
+}
+
+// CountRunningTasks returns the number of running tasks in the cluster, from
+// the running task counter if it is synced, otherwise from the database
+func CountRunningTasks() (n int, err error) {
+	if c := GetRunningTaskCounter(); c.IsSynced() {
+		return c.Total(), nil
+	}
+	n, err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(bson.M{"status": constants.TaskStatusRunning})
+	if err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return n, nil
+}
+
+// CountRunningTasksByNode returns the number of running tasks on the node,
+// from the running task counter if it is synced, otherwise from the database
+func CountRunningTasksByNode(nodeId primitive.ObjectID) (n int, err error) {
+	if c := GetRunningTaskCounter(); c.IsSynced() {
+		return c.CountByNode(nodeId), nil
+	}
+	n, err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(bson.M{"node_id": nodeId, "status": constants.TaskStatusRunning})
+	if err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,35 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/spf13/viper"
+)
+
+// Tasks requiring neither node tags nor capabilities may run on any node. If
+// "task.scheduler.defaultNode" is set, such tasks are dispatched to the node
+// of the key, or any online worker node if constants.TaskDefaultNodeAnyWorker,
+// instead of any eligible node. If the default node is unavailable, i.e. not
+// online and schedulable, "task.scheduler.defaultNodeFallback" decides whether
+// to dispatch to any eligible node (constants.TaskDefaultNodeFallbackAny, the
+// default) or to leave the task pending (constants.TaskDefaultNodeFallbackPending).
+
+// GetDefaultNode returns the default node of tasks, empty if not set
+func GetDefaultNode() (key string) {
+	return viper.GetString("task.scheduler.defaultNode")
+}
+
+// GetDefaultNodeFallback returns the fallback if the default node is unavailable
+func GetDefaultNodeFallback() (fallback string) {
+	if fallback = viper.GetString("task.scheduler.defaultNodeFallback"); fallback == constants.TaskDefaultNodeFallbackPending {
+		return fallback
+	}
+	return constants.TaskDefaultNodeFallbackAny
+}
+
+// IsMasterExcludedFromUnassignedTasks returns whether master is not to fetch
+// unassigned tasks, which are left pending for any worker node by the default
+// node fallback
+func IsMasterExcludedFromUnassignedTasks() (ok bool) {
+	return GetDefaultNode() == constants.TaskDefaultNodeAnyWorker &&
+		GetDefaultNodeFallback() == constants.TaskDefaultNodeFallbackPending
+}
@@ -0,0 +1,48 @@
+package task
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// NewTaskEvent returns a task event of the given type happening now
+func NewTaskEvent(taskId primitive.ObjectID, eventType, actor, message string) (e *models.TaskEvent) {
+	if actor == "" {
+		actor = constants.TaskEventActorSystem
+	}
+	return &models.TaskEvent{
+		TaskId:  taskId,
+		Type:    eventType,
+		Actor:   actor,
+		Message: message,
+		Ts:      time.Now(),
+	}
+}
+
+// AddTaskEvent adds an event to the timeline of the task. It should only be
+// called on master; errors are printed only as events are for diagnosis and
+// must not interrupt the task lifecycle.
+func AddTaskEvent(e *models.TaskEvent) {
+	if err := delegate.NewModelDelegate(e).Add(); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// GetUserActor returns the event actor of the user, or system if the user is nil
+func GetUserActor(u interfaces.User) (actor string) {
+	if u == nil {
+		return constants.TaskEventActorSystem
+	}
+	return fmt.Sprintf("user:%s", u.GetUsername())
+}
+
+// GetNodeActor returns the event actor of the node
+func GetNodeActor(n interfaces.Node) (actor string) {
+	return fmt.Sprintf("node:%s", n.GetKey())
+}
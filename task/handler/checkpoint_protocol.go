@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spiders can make long crawls resumable by printing checkpoint lines to stdout
+// in the format below:
+//
+//	<prefix><token>
+//
+// where <prefix> is "CRAWLAB_CHECKPOINT:" by default (configurable by
+// "task.checkpoint.prefix") and <token> is an opaque string such as a page
+// number or a cursor, e.g.
+//
+//	CRAWLAB_CHECKPOINT:page=42
+//
+// The last token is persisted as Task.Checkpoint, at most once per
+// "task.checkpoint.interval" (seconds, 5 by default) and when the task ends,
+// so that spiders printing checkpoints often do not flood master with updates.
+// A task retried or resumed
+// from a failed task inherits its checkpoint, which is passed to the process
+// by the environment variable constants.TaskCheckpointEnv, so that the spider
+// can continue from where the failed task stopped.
+const DefaultCheckpointPrefix = "CRAWLAB_CHECKPOINT:"
+
+// parseCheckpointLine returns the checkpoint token if the line is a checkpoint
+// line with the given prefix
+func parseCheckpointLine(line, prefix string) (token string, ok bool) {
+	if prefix == "" || !strings.HasPrefix(line, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, prefix)), true
+}
+
+func truncatedCheckpointLogLine(size int) (line string) {
+	return fmt.Sprintf("[crawlab] checkpoint dropped: line exceeds max size of %d bytes", size)
+}
+
+func getCheckpointInterval() (interval time.Duration) {
+	if seconds := viper.GetInt("task.checkpoint.interval"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Second
+}
+
+// checkpointWriter debounces saving checkpoint tokens. A token is saved at
+// once if none has been saved within the interval, otherwise it is saved when
+// the interval elapses, unless superseded by a later token.
+type checkpointWriter struct {
+	interval time.Duration
+	save     func(token string)
+
+	mu      sync.Mutex
+	token   string      // last token
+	saved   string      // last saved token
+	savedTs time.Time   // time of saving the last saved token
+	timer   *time.Timer // timer of saving the last token, nil if not scheduled
+}
+
+// Set records the token and saves it now or later
+func (w *checkpointWriter) Set(token string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.token = token
+	if w.timer != nil {
+		// saved when the timer fires
+		return
+	}
+	wait := w.interval - time.Since(w.savedTs)
+	if wait <= 0 {
+		w.saveLocked()
+		return
+	}
+	w.timer = time.AfterFunc(wait, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.timer = nil
+		w.saveLocked()
+	})
+}
+
+// Get returns the last token
+func (w *checkpointWriter) Get() (token string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.token
+}
+
+// Flush saves the last token if not saved yet, e.g. when the task ends
+func (w *checkpointWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.saveLocked()
+}
+
+func (w *checkpointWriter) saveLocked() {
+	if w.token == w.saved {
+		return
+	}
+	w.save(w.token)
+	w.saved = w.token
+	w.savedTs = time.Now()
+}
+
+func newCheckpointWriter(interval time.Duration, save func(token string)) (w *checkpointWriter) {
+	return &checkpointWriter{
+		interval: interval,
+		save:     save,
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseCheckpointLine(t *testing.T) {
+	token, ok := parseCheckpointLine("CRAWLAB_CHECKPOINT: page=42 ", DefaultCheckpointPrefix)
+	require.True(t, ok)
+	require.Equal(t, "page=42", token)
+
+	token, ok = parseCheckpointLine("CRAWLAB_CHECKPOINT:", DefaultCheckpointPrefix)
+	require.True(t, ok)
+	require.Empty(t, token)
+
+	_, ok = parseCheckpointLine("page=42", DefaultCheckpointPrefix)
+	require.False(t, ok)
+
+	_, ok = parseCheckpointLine("CRAWLAB_CHECKPOINT:page=42", "")
+	require.False(t, ok)
+}
+
+func TestCheckpointWriter(t *testing.T) {
+	var mu sync.Mutex
+	var saved []string
+	w := newCheckpointWriter(100*time.Millisecond, func(token string) {
+		mu.Lock()
+		defer mu.Unlock()
+		saved = append(saved, token)
+	})
+	getSaved := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, saved...)
+	}
+
+	// first token saved at once, later ones within the interval debounced
+	w.Set("1")
+	w.Set("2")
+	w.Set("3")
+	require.Equal(t, []string{"1"}, getSaved())
+	require.Equal(t, "3", w.Get())
+
+	// last token saved when the interval elapses
+	require.Eventually(t, func() bool {
+		return len(getSaved()) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"1", "3"}, getSaved())
+
+	// pending token saved on flush, saved token not saved again
+	w.Set("4")
+	w.Set("5")
+	w.Flush()
+	w.Flush()
+	res := getSaved()
+	require.Equal(t, "5", res[len(res)-1])
+	require.LessOrEqual(t, len(res), 4)
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Tasks of spiders of the docker runtime run in docker containers by the
+// docker cli on the node, instead of processes. The workspace, artifacts and
+// input directories are mounted at the same paths as on the node, so that
+// paths passed by environment variables stay valid in the container, and logs
+// are captured from the stdout and stderr of the container as of processes.
+// Containers are labelled with the task id and removed after tasks end.
+
+const (
+	containerNamePrefix  = "crawlab_task_"
+	containerLabelTaskId = "crawlab.task_id"
+)
+
+func getContainerName(tid primitive.ObjectID) (name string) {
+	return containerNamePrefix + tid.Hex()
+}
+
+// getContainerPullTimeout returns the timeout of image pulls, which is
+// configured by "task.container.pullTimeout" (seconds, 10 minutes by default)
+func getContainerPullTimeout() (timeout time.Duration) {
+	if seconds := viper.GetInt("task.container.pullTimeout"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+// getContainer returns the container of tasks of the spider, nil if the
+// spider does not run tasks in containers
+func (r *Runner) getContainer() (c *entity.SpiderContainer) {
+	s, ok := r.s.(*models.Spider)
+	if !ok || !s.IsContainerRuntime() {
+		return nil
+	}
+	c = &entity.SpiderContainer{}
+	if s.Container != nil {
+		*c = *s.Container
+	}
+	if c.Memory == "" {
+		c.Memory = viper.GetString("task.container.memory")
+	}
+	if c.Cpus == "" {
+		c.Cpus = viper.GetString("task.container.cpus")
+	}
+	if c.Network == "" {
+		c.Network = viper.GetString("task.container.network")
+	}
+	return c
+}
+
+// configureContainerCmd replaces the command of the process with the docker
+// cli running the cmd in the container. Environment variables of the task are
+// passed by name, so that their values are not exposed in the arguments.
+func (r *Runner) configureContainerCmd(c *entity.SpiderContainer) {
+	args := []string{
+		"run",
+		"--rm",
+		"--name", getContainerName(r.tid),
+		"--label", containerLabelTaskId + "=" + r.tid.Hex(),
+		"-v", r.cwd + ":" + r.cwd,
+		"-w", r.cwd,
+		"-v", r.adir + ":" + r.adir,
+	}
+	if r.idir != "" {
+		args = append(args, "-v", r.idir+":"+r.idir)
+	}
+
+	// resource limits
+	if c.Memory != "" {
+		args = append(args, "--memory", c.Memory)
+	}
+	if c.Cpus != "" {
+		args = append(args, "--cpus", c.Cpus)
+	}
+	if c.Network != "" {
+		args = append(args, "--network", c.Network)
+	}
+
+	// environment variables of the task, following those of the node
+	env := r.cmd.Env
+	for _, kv := range env[r.nenv:] {
+		if i := strings.Index(kv, "="); i > 0 {
+			args = append(args, "-e", kv[:i])
+		}
+	}
+
+	args = append(args, c.Image, "sh", "-c", r.getCmdStr())
+	r.cmd = exec.Command("docker", args...)
+	r.cmd.Dir = r.cwd
+	r.cmd.Env = env
+}
+
+// pullContainerImage pulls the image of the container by its pull policy.
+// Output is written to the task logs. It runs in place of the install of
+// dependencies, which are expected to be in the image. The image and the
+// network are checked again before they are passed to the docker cli, e.g. of
+// spiders saved before they were validated, or of nodes allowing other
+// networks than master.
+func (r *Runner) pullContainerImage(c *entity.SpiderContainer) (err error) {
+	if c.Image == "" {
+		return fmt.Errorf("%w: container image is empty", errors.ErrorTaskContainerFailed)
+	}
+	if !utils.IsValidDockerImage(c.Image) {
+		return fmt.Errorf("%w: invalid container image %s", errors.ErrorTaskContainerFailed, c.Image)
+	}
+	if c.Network != "" && !utils.IsValidDockerNetwork(c.Network) {
+		return fmt.Errorf("%w: invalid container network %s", errors.ErrorTaskContainerFailed, c.Network)
+	}
+	if c.Network != viper.GetString("task.container.network") && !utils.IsAllowedDockerNetwork(c.Network) {
+		// privileged networks of spiders, unlike the default of the node
+		return fmt.Errorf("%w: container network %s is not allowed", errors.ErrorTaskContainerFailed, c.Network)
+	}
+	switch c.Pull {
+	case constants.ContainerPullNever:
+		return nil
+	case constants.ContainerPullAlways:
+	default:
+		if err := exec.Command("docker", "image", "inspect", c.Image).Run(); err == nil {
+			// image exists
+			return nil
+		}
+	}
+
+	log.Infof("task[%s] pulling image %s", r.tid.Hex(), c.Image)
+	r.writeLogLines([]string{"[pull] " + c.Image})
+	ctx, cancel := context.WithTimeout(context.Background(), getContainerPullTimeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "docker", "pull", c.Image)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			r.writeLogLines([]string{"[pull] " + scanner.Text()})
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	r.icmd.Store(cmd)
+	err = cmd.Run()
+	_ = pw.Close()
+	<-done
+	if err != nil {
+		return fmt.Errorf("%w: pull image %s: %v", errors.ErrorTaskContainerFailed, c.Image, err)
+	}
+	return nil
+}
+
+// killContainer kills the container of the task, which is not killed along
+// with the docker cli when the cli is killed by force
+func (r *Runner) killContainer() {
+	if out, err := exec.Command("docker", "kill", getContainerName(r.tid)).CombinedOutput(); err != nil && !isNoSuchContainer(out) {
+		log.Warnf("task[%s] failed to kill container: %s", r.tid.Hex(), strings.TrimSpace(string(out)))
+	}
+}
+
+// removeContainer removes the container if it exists
+func removeContainer(name string) (err error) {
+	out, err := exec.Command("docker", "rm", "-f", name).CombinedOutput()
+	if err != nil && !isNoSuchContainer(out) {
+		return trace.TraceError(fmt.Errorf("remove container %s: %s", name, strings.TrimSpace(string(out))))
+	}
+	return nil
+}
+
+func isNoSuchContainer(out []byte) (ok bool) {
+	return bytes.Contains(bytes.ToLower(out), []byte("no such container"))
+}
+
+// pruneTaskContainers removes stopped containers of tasks which are not
+// running on the node, e.g. left behind by a crash of the node, and returns
+// the number of removed containers. It does nothing if docker is unavailable.
+func pruneTaskContainers(isRunning func(id primitive.ObjectID) bool) (count int) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return 0
+	}
+	out, err := exec.Command("docker", "ps", "-a",
+		"--filter", "label="+containerLabelTaskId,
+		"--filter", "status=created",
+		"--filter", "status=exited",
+		"--filter", "status=dead",
+		"--format", fmt.Sprintf(`{{.Label "%s"}}`, containerLabelTaskId),
+	).Output()
+	if err != nil {
+		trace.PrintError(err)
+		return 0
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(line))
+		if err != nil || isRunning(id) {
+			continue
+		}
+		if err := removeContainer(getContainerName(id)); err != nil {
+			trace.PrintError(err)
+			continue
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os/exec"
+	"testing"
+)
+
+func TestRunner_ConfigureContainerCmd(t *testing.T) {
+	viper.Set("task.container.memory", "1g")
+	defer viper.Set("task.container.memory", nil)
+
+	r := &Runner{
+		tid:  primitive.NewObjectID(),
+		t:    &models.Task{Concurrency: 4},
+		cwd:  "/workspace/spider",
+		adir: "/artifacts/task",
+		cmd:  exec.Command("sh"),
+	}
+
+	// process runtime
+	r.s = &models.Spider{Cmd: "python main.py"}
+	require.Nil(t, r.getContainer())
+
+	// docker runtime
+	r.s = &models.Spider{
+		Cmd:       "python main.py -c {{concurrency}}",
+		Runtime:   constants.SpiderRuntimeDocker,
+		Container: &entity.SpiderContainer{Image: "python:3.10", Cpus: "2"},
+	}
+	c := r.getContainer()
+	require.NotNil(t, c)
+	require.Equal(t, "1g", c.Memory)
+
+	// environment variables of the task are passed by name
+	r.cmd.Env = []string{"PATH=/usr/bin", "CRAWLAB_TASK_ID=" + r.tid.Hex()}
+	r.nenv = 1
+	r.configureContainerCmd(c)
+	require.Equal(t, []string{
+		"docker", "run", "--rm",
+		"--name", getContainerName(r.tid),
+		"--label", containerLabelTaskId + "=" + r.tid.Hex(),
+		"-v", "/workspace/spider:/workspace/spider",
+		"-w", "/workspace/spider",
+		"-v", "/artifacts/task:/artifacts/task",
+		"--memory", "1g",
+		"--cpus", "2",
+		"-e", "CRAWLAB_TASK_ID",
+		"python:3.10", "sh", "-c", "python main.py -c 4",
+	}, r.cmd.Args)
+	require.Equal(t, []string{"PATH=/usr/bin", "CRAWLAB_TASK_ID=" + r.tid.Hex()}, r.cmd.Env)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/errors"
+	fs2 "github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	vcs "github.com/crawlab-team/crawlab-vcs"
+	"github.com/crawlab-team/go-trace"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"os"
+	"path/filepath"
+)
+
+// getGitWorkspaceRoot returns the root directory of per-task git checkouts
+func getGitWorkspaceRoot() (root string) {
+	return paths.GetTempPath("crawlab_task_git")
+}
+
+// checkoutGitRef clones the git repo of the spider into an isolated workspace
+// of the task and checks out the git ref of the task, so that the process does
+// not run whatever is currently synced. The resolved commit is recorded on the
+// task.
+//
+// Master clones from the remote with the git credentials. Workers never see
+// the credentials and clone from the repo synced from master instead.
+func (r *Runner) checkoutGitRef() (err error) {
+	// source
+	isMaster := r.svc.GetNodeConfigService().IsMaster()
+	var url string
+	var auth transport.AuthMethod
+	if isMaster {
+		g, err := r.getGit()
+		if err != nil {
+			return err
+		}
+		auth, err = utils.GetGitAuth(g)
+		if err != nil {
+			return err
+		}
+		url = g.GetUrl()
+	} else {
+		url = fs2.GetSpiderWorkspacePath(r.s.GetId())
+		if _, err := os.Stat(filepath.Join(url, git.GitDirName)); err != nil {
+			return errors.ErrorTaskGitNotConfigured
+		}
+	}
+
+	// clone
+	dir := filepath.Join(getGitWorkspaceRoot(), r.tid.Hex())
+	if err := os.RemoveAll(dir); err != nil {
+		return trace.TraceError(err)
+	}
+	_ = os.Remove(dir + scratchDoneSuffix)
+	if err := os.MkdirAll(filepath.Dir(dir), fs2.WorkspaceDirPerm); err != nil {
+		return trace.TraceError(err)
+	}
+	var opts []vcs.GitCloneOption
+	if auth != nil {
+		opts = append(opts, vcs.WithAuthClone(auth))
+	}
+	gitClient, err := vcs.CloneGitRepo(dir, url, opts...)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	r.gdir = dir
+	if !isMaster {
+		if err := fetchSyncedGitRefs(gitClient.GetRepository()); err != nil {
+			return err
+		}
+	}
+
+	// resolve and checkout
+	hash, err := resolveGitRef(gitClient.GetRepository(), r.t.GetGitRef())
+	if err != nil {
+		return err
+	}
+	if err := gitClient.CheckoutHash(hash.String()); err != nil {
+		return err
+	}
+	r.t.SetGitCommit(hash.String())
+	r.cwd = dir
+
+	log.Infof("task[%s] checked out git ref %s (%s)", r.tid.Hex(), r.t.GetGitRef(), hash.String())
+
+	return nil
+}
+
+// getGit returns the git settings of the spider. Only available on master.
+func (r *Runner) getGit() (g interfaces.Git, err error) {
+	var _g models.Git
+	if err := mongo.GetMongoCol(interfaces.ModelColNameGit).FindId(r.s.GetId()).One(&_g); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, errors.ErrorTaskGitNotConfigured
+		}
+		return nil, trace.TraceError(err)
+	}
+	return &_g, nil
+}
+
+// fetchSyncedGitRefs fetches the remote branches and tags of the repo synced
+// from master, as a local clone only gets its local branches.
+func fetchSyncedGitRefs(repo *git.Repository) (err error) {
+	if err := repo.Fetch(&git.FetchOptions{
+		RemoteName: vcs.GitRemoteNameOrigin,
+		RefSpecs: []config.RefSpec{
+			"+refs/remotes/origin/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// removeGitWorkspace releases the git checkout of the task once it ends
+func (r *Runner) removeGitWorkspace() (err error) {
+	return releaseScratchDir(r.gdir)
+}
+
+// resolveGitRef resolves a branch, tag or (abbreviated) commit hash to the
+// commit hash. Branches other than the default one only exist as remote
+// branches in a fresh clone.
+func resolveGitRef(repo *git.Repository, ref string) (hash plumbing.Hash, err error) {
+	for _, rev := range []string{ref, vcs.GitRemoteNameOrigin + "/" + ref} {
+		h, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err == nil {
+			return *h, nil
+		}
+	}
+	return hash, fmt.Errorf("%w: %s", errors.ErrorTaskGitRefNotFound, ref)
+}
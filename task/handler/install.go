@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	fs2 "github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/sys_exec"
+	"github.com/crawlab-team/crawlab-core/tracing"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Dependencies of a spider are installed by its install command before the
+// first task of the spider runs on a node. The hash of the command and the
+// requirements file is recorded on the node after a successful install, and
+// install runs again only when the hash changes.
+
+const defaultRequirementsFile = "requirements.txt"
+
+// getInstallMarkerPath returns the file recording the install hash of the
+// spider on the current node. It is kept outside the spider workspace, which
+// is overwritten by file sync.
+func getInstallMarkerPath(spiderId primitive.ObjectID) (p string) {
+	return filepath.Join(fs2.GetWorkspaceRoot(), ".install", spiderId.Hex())
+}
+
+// getInstallTimeout returns the timeout of install commands, which is
+// configured by "task.install.timeout" (seconds, 30 minutes by default)
+func getInstallTimeout() (timeout time.Duration) {
+	if seconds := viper.GetInt("task.install.timeout"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 30 * time.Minute
+}
+
+// getInstallCmd returns the install command of the spider, or an empty string
+// if nothing is to be installed
+func (r *Runner) getInstallCmd() (cmdStr string) {
+	if r.s.GetInstallCmd() != "" {
+		return r.s.GetInstallCmd()
+	}
+	if r.s.GetAutoInstall() {
+		if _, err := os.Stat(filepath.Join(r.cwd, r.getRequirementsFile())); err == nil {
+			return "pip install -r " + r.getRequirementsFile()
+		}
+	}
+	return ""
+}
+
+func (r *Runner) getRequirementsFile() (file string) {
+	if r.s.GetRequirementsFile() != "" {
+		return r.s.GetRequirementsFile()
+	}
+	return defaultRequirementsFile
+}
+
+// getInstallHash returns the hash of the install command and the content of
+// the requirements file, if it exists
+func (r *Runner) getInstallHash(cmdStr string) (hash string, err error) {
+	h := sha256.New()
+	h.Write([]byte(cmdStr))
+	h.Write([]byte{0})
+	p, err := fs2.ResolvePath(r.cwd, r.getRequirementsFile())
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(p)
+	if err != nil && !os.IsNotExist(err) {
+		return "", trace.TraceError(err)
+	}
+	if err == nil {
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", trace.TraceError(err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// install runs the install command of the spider if it has not run on the
+// current node with the same hash. Output is written to the task logs.
+// Failures are wrapped in errors.ErrorTaskInstallFailed. For tasks run in
+// containers, it pulls the image instead.
+func (r *Runner) install() (err error) {
+	// dependencies of containers are in their images
+	if c := r.getContainer(); c != nil {
+		return r.pullContainerImage(c)
+	}
+
+	cmdStr := r.getInstallCmd()
+	if cmdStr == "" {
+		return nil
+	}
+
+	// skip if installed
+	hash, err := r.getInstallHash(cmdStr)
+	if err != nil {
+		return err
+	}
+	markerPath := getInstallMarkerPath(r.s.GetId())
+	if data, err := os.ReadFile(markerPath); err == nil && string(data) == hash {
+		return nil
+	}
+
+	// run install command
+	_, span := tracing.Tracer().Start(r.traceCtx, "task.install")
+	defer func() { tracing.EndSpan(span, err) }()
+	log.Infof("task[%s] installing dependencies: %s", r.tid.Hex(), cmdStr)
+	r.writeLogLines([]string{"[install] " + cmdStr})
+	cmd := sys_exec.BuildCmd(cmdStr)
+	cmd.Dir = r.cwd
+	cmd.Env = r.cmd.Env
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			r.writeLogLines([]string{"[install] " + scanner.Text()})
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	r.icmd.Store(cmd)
+	err = cmd.Start()
+	if err == nil {
+		timer := time.AfterFunc(getInstallTimeout(), func() {
+			log.Warnf("task[%s] install exceeded timeout, killing", r.tid.Hex())
+			_ = sys_exec.KillProcess(cmd, &sys_exec.KillProcessOptions{Force: true})
+		})
+		err = cmd.Wait()
+		timer.Stop()
+	}
+	_ = pw.Close()
+	<-done
+	if err != nil {
+		err = fmt.Errorf("%w: %v", errors.ErrorTaskInstallFailed, err)
+		r.addEvent(constants.TaskEventInstallFailed, err.Error())
+		return err
+	}
+	r.addEvent(constants.TaskEventInstalled, cmdStr)
+
+	// record hash
+	if err := os.MkdirAll(filepath.Dir(markerPath), fs2.WorkspaceDirPerm); err != nil {
+		return trace.TraceError(err)
+	}
+	if err := os.WriteFile(markerPath, []byte(hash), 0644); err != nil {
+		return trace.TraceError(err)
+	}
+
+	return nil
+}
+
+// killInstall kills the running install command, if any
+func (r *Runner) killInstall() {
+	cmd, ok := r.icmd.Load().(*exec.Cmd)
+	if !ok || cmd == nil || cmd.Process == nil {
+		return
+	}
+	if err := sys_exec.KillProcess(cmd, &sys_exec.KillProcessOptions{Force: true}); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// isInstalling returns whether the task process has not started yet
+func (r *Runner) isInstalling() (ok bool) {
+	return atomic.LoadInt32(&r.started) == 0
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"strings"
+)
+
+// Result items can be reported by spiders of any language by printing lines
+// to stdout in the format below:
+//
+//	<prefix><json>
+//
+// where <prefix> is "CRAWLAB_ITEM:" by default (configurable by "task.item.prefix")
+// and <json> is either a JSON object or a JSON array of objects, e.g.
+//
+//	CRAWLAB_ITEM:{"title":"hello","url":"https://example.com"}
+//	CRAWLAB_ITEM:[{"title":"a"},{"title":"b"}]
+//
+// Item lines are parsed and sent to the result service instead of the task log.
+// Numbers are kept as json.Number, so that large integers are sent to master
+// without losing precision.
+// Lines exceeding the read buffer size are truncated; truncated item lines are
+// dropped with a warning in the task log since the JSON would be incomplete.
+const DefaultItemPrefix = "CRAWLAB_ITEM:"
+
+// parseItemLine returns result items if the line is an item line with the given prefix
+func parseItemLine(line, prefix string) (records []entity.Result, ok bool, err error) {
+	if prefix == "" || !strings.HasPrefix(line, prefix) {
+		return nil, false, nil
+	}
+	data := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	if strings.HasPrefix(data, "[") {
+		if err := utils.UnmarshalJsonUseNumber([]byte(data), &records); err != nil {
+			return nil, true, err
+		}
+	} else {
+		var record entity.Result
+		if err := utils.UnmarshalJsonUseNumber([]byte(data), &record); err != nil {
+			return nil, true, err
+		}
+		records = append(records, record)
+	}
+	return records, true, nil
+}
+
+// readLine reads a line without the trailing "\n" from the reader. Lines longer
+// than the buffer size of the reader are truncated and the rest of the line is
+// discarded. A partial line before EOF is returned along with the error.
+func readLine(reader *bufio.Reader) (line string, truncated bool, err error) {
+	chunk, err := reader.ReadSlice('\n')
+	line = string(chunk)
+	for err == bufio.ErrBufferFull {
+		truncated = true
+		_, err = reader.ReadSlice('\n')
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return line, truncated, err
+}
+
+func truncatedItemLogLine(size int) (line string) {
+	return fmt.Sprintf("[crawlab] item dropped: line exceeds max size of %d bytes", size)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseItemLine(t *testing.T) {
+	records, ok, err := parseItemLine(`CRAWLAB_ITEM:{"title":"a"}`, DefaultItemPrefix)
+	require.True(t, ok)
+	require.Nil(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "a", records[0]["title"])
+
+	records, ok, err = parseItemLine(`CRAWLAB_ITEM: [{"title":"a"},{"title":"b"}]`, DefaultItemPrefix)
+	require.True(t, ok)
+	require.Nil(t, err)
+	require.Len(t, records, 2)
+
+	_, ok, err = parseItemLine(`CRAWLAB_ITEM:{"title":`, DefaultItemPrefix)
+	require.True(t, ok)
+	require.NotNil(t, err)
+
+	_, ok, _ = parseItemLine(`normal log line`, DefaultItemPrefix)
+	require.False(t, ok)
+
+	_, ok, _ = parseItemLine(`CRAWLAB_ITEM:{}`, "")
+	require.False(t, ok)
+}
+
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("x", 64)
+	reader := bufio.NewReaderSize(strings.NewReader("short\n"+long+"\npartial"), 16)
+
+	line, truncated, err := readLine(reader)
+	require.Nil(t, err)
+	require.False(t, truncated)
+	require.Equal(t, "short", line)
+
+	line, truncated, err = readLine(reader)
+	require.Nil(t, err)
+	require.True(t, truncated)
+	require.Equal(t, long[:16], line)
+
+	line, truncated, err = readLine(reader)
+	require.Equal(t, io.EOF, err)
+	require.False(t, truncated)
+	require.Equal(t, "partial", line)
+}
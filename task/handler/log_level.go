@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/spf13/viper"
+	"regexp"
+	"strings"
+)
+
+// Log levels of task log lines are detected on the worker by matching each line
+// against the level patterns below in order, i.e. ERROR, WARN, DEBUG. Lines not
+// matching any pattern default to INFO.
+//
+// Patterns can be overridden by "task.log.levels", e.g.
+//
+//	task:
+//	  log:
+//	    levels:
+//	      error: "^\\[E\\]"
+//	      warn: "^\\[W\\]"
+var defaultLogLevelPatterns = map[string]string{
+	constants.LogLevelError: `\b(ERROR|CRITICAL|FATAL)\b`,
+	constants.LogLevelWarn:  `\bWARN(ING)?\b`,
+	constants.LogLevelDebug: `\bDEBUG\b`,
+}
+
+// logLevelOrder is the order in which level patterns are matched
+var logLevelOrder = []string{
+	constants.LogLevelError,
+	constants.LogLevelWarn,
+	constants.LogLevelDebug,
+}
+
+type logLevelPattern struct {
+	level string
+	re    *regexp.Regexp
+}
+
+type logLevelDetector struct {
+	patterns []logLevelPattern
+}
+
+// Detect returns the log level of the given line
+func (d *logLevelDetector) Detect(line string) (level string) {
+	for _, p := range d.patterns {
+		if p.re.MatchString(line) {
+			return p.level
+		}
+	}
+	return constants.LogLevelInfo
+}
+
+// DetectLines returns log levels of the given lines by index
+func (d *logLevelDetector) DetectLines(lines []string) (levels []string) {
+	levels = make([]string, len(lines))
+	for i, line := range lines {
+		levels[i] = d.Detect(line)
+	}
+	return levels
+}
+
+// newLogLevelDetector returns a detector with default patterns overridden by
+// the given ones (keyed by case-insensitive level). Invalid patterns are ignored
+// with a warning and the default pattern is kept.
+func newLogLevelDetector(overrides map[string]string) (d *logLevelDetector) {
+	d = &logLevelDetector{}
+	for _, level := range logLevelOrder {
+		pattern := defaultLogLevelPatterns[level]
+		for k, v := range overrides {
+			if strings.ToUpper(k) == level && v != "" {
+				if _, err := regexp.Compile(v); err != nil {
+					log.Warnf("invalid log level pattern for %s: %v", level, err)
+					break
+				}
+				pattern = v
+				break
+			}
+		}
+		d.patterns = append(d.patterns, logLevelPattern{
+			level: level,
+			re:    regexp.MustCompile(pattern),
+		})
+	}
+	return d
+}
+
+// newLogLevelDetectorFromConfig returns a detector with patterns overridden
+// by "task.log.levels"
+func newLogLevelDetectorFromConfig() (d *logLevelDetector) {
+	return newLogLevelDetector(viper.GetStringMapString("task.log.levels"))
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLogLevelDetector_Detect(t *testing.T) {
+	d := newLogLevelDetector(nil)
+	require.Equal(t, constants.LogLevelError, d.Detect("2022-01-01 ERROR something failed"))
+	require.Equal(t, constants.LogLevelError, d.Detect("CRITICAL: disk full"))
+	require.Equal(t, constants.LogLevelWarn, d.Detect("[WARNING] retrying"))
+	require.Equal(t, constants.LogLevelWarn, d.Detect("WARN slow response"))
+	require.Equal(t, constants.LogLevelDebug, d.Detect("DEBUG request sent"))
+	require.Equal(t, constants.LogLevelInfo, d.Detect("INFO crawled 10 pages"))
+	require.Equal(t, constants.LogLevelInfo, d.Detect("no level here"))
+	require.Equal(t, constants.LogLevelInfo, d.Detect("ERRORS_COUNT=0"))
+}
+
+func TestLogLevelDetector_Overrides(t *testing.T) {
+	d := newLogLevelDetector(map[string]string{
+		"error": `^\[E\]`,
+		"warn":  `(invalid`,
+	})
+	require.Equal(t, constants.LogLevelError, d.Detect("[E] failed"))
+	require.Equal(t, constants.LogLevelInfo, d.Detect("ERROR failed"))
+	require.Equal(t, constants.LogLevelWarn, d.Detect("WARN kept default"))
+
+	levels := d.DetectLines([]string{"[E] a", "b"})
+	require.Equal(t, []string{constants.LogLevelError, constants.LogLevelInfo}, levels)
+}
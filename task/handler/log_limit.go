@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"sync"
+	"unicode/utf8"
+)
+
+// Logs of a task are limited on the worker before being sent to master, so
+// that pathological output does not flood the network or the log store.
+// Lines longer than "task.log.maxLineLength" (bytes) are truncated with
+// logLineTruncatedMarker, and logs beyond "task.log.maxSize" (bytes) in total
+// are discarded. Both are unlimited if not positive, e.g.
+//
+//	task:
+//	  log:
+//	    maxLineLength: 10000
+//	    maxSize: 104857600
+const logLineTruncatedMarker = "...[truncated]"
+
+type logLimiter struct {
+	maxLineLength int   // max bytes of a line, 0 for unlimited
+	maxSize       int64 // max total bytes of logs, 0 for unlimited
+	size          int64 // total bytes of logs passed
+	capped        bool  // whether maxSize has been reached
+	mu            sync.Mutex
+}
+
+// Limit returns the lines to send with too long lines truncated. Lines beyond
+// the max size are dropped, and capped is true for the call reaching it, in
+// which case a notice line is appended.
+func (l *logLimiter) Limit(lines []string) (res []string, capped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.capped {
+		return nil, false
+	}
+	res = make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = l.truncateLine(line)
+		if l.maxSize > 0 && l.size+int64(len(line)) > l.maxSize {
+			l.capped = true
+			res = append(res, fmt.Sprintf("[crawlab] log size limit of %d bytes reached, further logs are discarded", l.maxSize))
+			return res, true
+		}
+		l.size += int64(len(line))
+		res = append(res, line)
+	}
+	return res, false
+}
+
+// truncateLine cuts the line to the max line length at a character boundary
+// and appends the truncated marker
+func (l *logLimiter) truncateLine(line string) (res string) {
+	if l.maxLineLength <= 0 || len(line) <= l.maxLineLength {
+		return line
+	}
+	n := l.maxLineLength
+	for n > 0 && !utf8.RuneStart(line[n]) {
+		n--
+	}
+	return line[:n] + logLineTruncatedMarker
+}
+
+func newLogLimiter(maxLineLength int, maxSize int64) (l *logLimiter) {
+	return &logLimiter{
+		maxLineLength: maxLineLength,
+		maxSize:       maxSize,
+	}
+}
+
+// newLogLimiterFromConfig returns a log limiter of the limits configured by
+// "task.log.maxLineLength" and "task.log.maxSize"
+func newLogLimiterFromConfig() (l *logLimiter) {
+	return newLogLimiter(viper.GetInt("task.log.maxLineLength"), viper.GetInt64("task.log.maxSize"))
+}
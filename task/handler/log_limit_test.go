@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestLogLimiter_LineLength(t *testing.T) {
+	l := newLogLimiter(5, 0)
+	res, capped := l.Limit([]string{"short", "too long line", "日本語"})
+	require.False(t, capped)
+	require.Equal(t, []string{"short", "too l" + logLineTruncatedMarker, "日" + logLineTruncatedMarker}, res)
+}
+
+func TestLogLimiter_MaxSize(t *testing.T) {
+	l := newLogLimiter(0, 10)
+	res, capped := l.Limit([]string{"12345", "1234"})
+	require.False(t, capped)
+	require.Equal(t, []string{"12345", "1234"}, res)
+
+	res, capped = l.Limit([]string{"1", "12"})
+	require.True(t, capped)
+	require.Len(t, res, 2)
+	require.Equal(t, "1", res[0])
+	require.True(t, strings.Contains(res[1], "log size limit"))
+
+	res, capped = l.Limit([]string{"1"})
+	require.False(t, capped)
+	require.Empty(t, res)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"time"
 )
@@ -31,6 +32,18 @@ func WithCancelTimeout(timeout time.Duration) Option {
 	}
 }
 
+func WithWorkspaceInterval(interval time.Duration) Option {
+	return func(svc interfaces.TaskHandlerService) {
+		svc.SetWorkspaceInterval(interval)
+	}
+}
+
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(svc interfaces.TaskHandlerService) {
+		svc.SetCleanupInterval(interval)
+	}
+}
+
 type RunnerOption func(r interfaces.TaskRunner)
 
 func WithSubscribeTimeout(timeout time.Duration) RunnerOption {
@@ -38,3 +51,11 @@ func WithSubscribeTimeout(timeout time.Duration) RunnerOption {
 		r.SetSubscribeTimeout(timeout)
 	}
 }
+
+// WithTraceContext sets the context carrying the trace context of the
+// dispatch of the task, continued by spans of the run
+func WithTraceContext(ctx context.Context) RunnerOption {
+	return func(r interfaces.TaskRunner) {
+		r.SetTraceContext(ctx)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/apex/log"
 	"github.com/cenkalti/backoff/v4"
 	"github.com/crawlab-team/crawlab-core/constants"
@@ -16,7 +17,12 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/client"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/proxy"
 	"github.com/crawlab-team/crawlab-core/sys_exec"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/task/plugin"
+	"github.com/crawlab-team/crawlab-core/tracing"
+	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
@@ -24,11 +30,18 @@ import (
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/dig"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -41,24 +54,58 @@ type Runner struct {
 	// settings
 	subscribeTimeout time.Duration
 	bufferSize       int
+	itemPrefix       string
+	checkpointPrefix string
+	logLevels        *logLevelDetector
+	logLimiter       *logLimiter
+	maxTestResults   int // max number of result items captured by a test run
 
 	// internals
-	cmd  *exec.Cmd                        // process command instance
-	pid  int                              // process id
-	tid  primitive.ObjectID               // task id
-	t    interfaces.Task                  // task model.Task
-	s    interfaces.Spider                // spider model.Spider
-	ch   chan constants.TaskSignal        // channel to communicate between Service and Runner
-	err  error                            // standard process error
-	envs []models.Env                     // environment variables
-	cwd  string                           // working directory
-	c    interfaces.GrpcClient            // grpc client
-	sub  grpc.TaskService_SubscribeClient // grpc task service stream client
+	cmd   *exec.Cmd                        // process command instance
+	pid   int                              // process id
+	tid   primitive.ObjectID               // task id
+	t     interfaces.Task                  // task model.Task
+	s     interfaces.Spider                // spider model.Spider
+	ch    chan constants.TaskSignal        // channel to communicate between Service and Runner
+	err   error                            // standard process error
+	envs  []models.Env                     // environment variables
+	nenv  int                              // number of environment variables of the node, followed by those of the task in cmd.Env
+	cwd   string                           // working directory
+	adir  string                           // artifacts directory
+	gdir  string                           // git checkout directory if the task runs a git ref
+	idir  string                           // input file directory if the task has an input file
+	ipath string                           // path of the downloaded input file
+	c     interfaces.GrpcClient            // grpc client
+	sub   grpc.TaskService_SubscribeClient // grpc task service stream client
+	subMu sync.Mutex                       // serializes sends on sub, which are written by readers of stdout and stderr
+
+	// trace internals
+	traceCtx context.Context // context of the span of the run, or of the dispatch before the run
+
+	// cancel internals
+	cancelling  int32 // whether the task is being cancelled (atomic)
+	forceKilled int32 // whether the process is killed by force when cancelled (atomic)
+	timedOut    int32 // whether the process is killed because of task timeout (atomic)
+
+	// log limit internals
+	logsTruncated int32 // whether logs have been discarded as the max log size is reached (atomic)
+
+	// install internals
+	started int32        // whether the process has started, i.e. install has finished (atomic)
+	icmd    atomic.Value // running install command (*exec.Cmd)
 
 	// log internals
 	scannerStdout *bufio.Reader
 	scannerStderr *bufio.Reader
 	logBatchSize  int
+
+	// checkpoint internals
+	checkpoint *checkpointWriter // debounced writer of checkpoint tokens reported by the process
+
+	// test internals
+	testResults       []map[string]interface{} // result items captured by a test run
+	testResultsCapped bool                     // whether result items have been dropped as maxTestResults is reached
+	testResultsMu     sync.Mutex               // lock of testResults
 }
 
 func (r *Runner) Init() (err error) {
@@ -72,9 +119,50 @@ func (r *Runner) Init() (err error) {
 		r.c.Start()
 	}
 
+	// sync spider files from master
+	if !r.svc.GetNodeConfigService().IsMaster() {
+		if err := fs2.SyncSpiderFiles(r.s.GetId()); err != nil {
+			return err
+		}
+	}
+
 	// working directory
-	workspacePath := viper.GetString("workspace")
-	r.cwd = filepath.Join(workspacePath, r.s.GetId().Hex())
+	r.cwd, err = fs2.EnsureSpiderWorkspacePath(r.s.GetId())
+	if err != nil {
+		return err
+	}
+
+	// isolated checkout of the git ref as working directory
+	if r.t.GetGitRef() != "" {
+		if err := r.checkoutGitRef(); err != nil {
+			if r.gdir != "" {
+				_ = os.RemoveAll(r.gdir)
+			}
+			_ = r.updateTask(constants.TaskStatusError, err)
+			return err
+		}
+		if err := r.updateTask("", nil); err != nil {
+			return err
+		}
+	}
+
+	// artifacts directory
+	r.adir = filepath.Join(getArtifactsRoot(), r.tid.Hex())
+	_ = os.Remove(r.adir + scratchDoneSuffix)
+	if err := os.MkdirAll(r.adir, fs2.WorkspaceDirPerm); err != nil {
+		return trace.TraceError(err)
+	}
+
+	// input file downloaded from master
+	if inputId := r.t.GetInputId(); !inputId.IsZero() {
+		r.idir = filepath.Join(getInputsRoot(), r.tid.Hex())
+		_ = os.Remove(r.idir + scratchDoneSuffix)
+		r.ipath, err = task.DownloadSpiderInput(inputId, r.idir)
+		if err != nil {
+			_ = r.updateTask(constants.TaskStatusError, err)
+			return err
+		}
+	}
 
 	// grpc task service stream client
 	if err := r.initSub(); err != nil {
@@ -95,12 +183,42 @@ func (r *Runner) Run() (err error) {
 	// log task started
 	log.Infof("task[%s] started", r.tid.Hex())
 
+	// trace
+	var span oteltrace.Span
+	r.traceCtx, span = tracing.Tracer().Start(r.traceCtx, "task.run",
+		oteltrace.WithAttributes(
+			attribute.String("crawlab.task_id", r.tid.Hex()),
+			attribute.String("crawlab.spider_id", r.s.GetId().Hex()),
+			attribute.String("crawlab.node_key", r.svc.GetNodeConfigService().GetNodeKey()),
+		),
+	)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	// configure cmd
 	r.configureCmd()
 
 	// configure environment variables
 	r.configureEnv()
 
+	// run in container
+	if c := r.getContainer(); c != nil {
+		r.configureContainerCmd(c)
+	}
+
+	// install dependencies
+	if err := r.install(); err != nil || atomic.LoadInt32(&r.cancelling) == 1 {
+		status := constants.TaskStatusError
+		if atomic.LoadInt32(&r.cancelling) == 1 {
+			status = constants.TaskStatusCancelled
+			err = constants.ErrTaskCancelled
+		}
+		if err := r.updateTask(status, err); err != nil {
+			return err
+		}
+		r.addStatusEvent(status, err)
+		return err
+	}
+
 	// configure logging
 	r.configureLogging()
 
@@ -108,6 +226,7 @@ func (r *Runner) Run() (err error) {
 	if err := r.cmd.Start(); err != nil {
 		return r.updateTask(constants.TaskStatusError, err)
 	}
+	atomic.StoreInt32(&r.started, 1)
 
 	// start logging
 	go r.startLogging()
@@ -123,6 +242,16 @@ func (r *Runner) Run() (err error) {
 	if err := r.updateTask(constants.TaskStatusRunning, nil); err != nil {
 		return err
 	}
+	r.addEvent(constants.TaskEventStarted, fmt.Sprintf("pid: %d", r.pid))
+
+	// plugins (task start)
+	if err := plugin.GetRegistry().OnTaskStart(&entity.TaskPluginContext{
+		Task:   r.t,
+		Spider: r.s,
+		Status: constants.TaskStatusRunning,
+	}); err != nil {
+		trace.PrintError(err)
+	}
 
 	// wait for process to finish
 	go r.wait()
@@ -130,11 +259,30 @@ func (r *Runner) Run() (err error) {
 	// start health check
 	go r.startHealthCheck()
 
+	// kill process on timeout
+	if r.t.GetTimeout() > 0 {
+		timer := time.AfterFunc(time.Duration(r.t.GetTimeout())*time.Second, r.handleTimeout)
+		defer timer.Stop()
+	}
+
 	// declare task status
 	status := ""
 
 	// wait for signal
 	signal := <-r.ch
+	if atomic.LoadInt32(&r.timedOut) == 1 {
+		// process exited because of timeout
+		signal = constants.TaskSignalError
+		r.err = errors.ErrorTaskTimeout
+	} else if atomic.LoadInt32(&r.cancelling) == 1 {
+		// process exited because of cancel, regardless of its exit code
+		signal = constants.TaskSignalCancel
+		if atomic.LoadInt32(&r.forceKilled) == 1 {
+			r.t.SetCancelMode(constants.TaskCancelModeForce)
+		} else {
+			r.t.SetCancelMode(constants.TaskCancelModeSoft)
+		}
+	}
 	switch signal {
 	case constants.TaskSignalFinish:
 		err = nil
@@ -153,10 +301,24 @@ func (r *Runner) Run() (err error) {
 		status = constants.TaskStatusError
 	}
 
+	// upload artifacts to master
+	task.UploadTaskArtifacts(r.tid, r.adir)
+
 	// update task status
 	if err := r.updateTask(status, err); err != nil {
 		return err
 	}
+	r.addStatusEvent(status, err)
+
+	// plugins (task finish)
+	if err := plugin.GetRegistry().OnTaskFinish(&entity.TaskPluginContext{
+		Task:   r.t,
+		Spider: r.s,
+		Status: status,
+		Error:  err,
+	}); err != nil {
+		trace.PrintError(err)
+	}
 
 	// post actions
 	if r.hookSvc != nil {
@@ -169,7 +331,38 @@ func (r *Runner) Run() (err error) {
 }
 
 func (r *Runner) Cancel() (err error) {
+	return r.CancelWithMode(constants.TaskCancelModeForce)
+}
+
+// CancelWithMode cancels the task. With constants.TaskCancelModeSoft, the process
+// is terminated (SIGTERM) and killed (SIGKILL) only if it does not exit within the
+// grace period of the spider. With constants.TaskCancelModeForce, it is killed at once.
+// The mode ultimately taken is recorded in the task.
+func (r *Runner) CancelWithMode(mode string) (err error) {
+	atomic.StoreInt32(&r.cancelling, 1)
+
+	// process not started yet, kill install command if running
+	if r.isInstalling() {
+		r.killInstall()
+		return nil
+	}
+
+	// terminate process and wait for it to exit
+	if mode == constants.TaskCancelModeSoft {
+		if err := sys_exec.KillProcess(r.cmd, &sys_exec.KillProcessOptions{Force: false}); err != nil {
+			trace.PrintError(err)
+		}
+		if r.waitProcessExit(r.getCancelGracePeriod()) {
+			return nil
+		}
+		log.Warnf("task[%s] did not exit within grace period, killing", r.tid.Hex())
+	}
+
 	// kill process
+	atomic.StoreInt32(&r.forceKilled, 1)
+	if r.getContainer() != nil {
+		r.killContainer()
+	}
 	opts := &sys_exec.KillProcessOptions{
 		Timeout: r.svc.GetCancelTimeout(),
 		Force:   true,
@@ -195,8 +388,65 @@ func (r *Runner) Cancel() (err error) {
 	return nil
 }
 
+// handleTimeout kills the process when the task exceeds its timeout
+func (r *Runner) handleTimeout() {
+	if atomic.LoadInt32(&r.cancelling) == 1 {
+		return
+	}
+	atomic.StoreInt32(&r.timedOut, 1)
+	log.Warnf("task[%s] exceeded timeout of %d seconds, killing", r.tid.Hex(), r.t.GetTimeout())
+	if err := r.CancelWithMode(constants.TaskCancelModeForce); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// getCancelGracePeriod returns the grace period of soft cancel, which is configured
+// per spider and falls back to the cancel timeout of the handler service
+func (r *Runner) getCancelGracePeriod() (duration time.Duration) {
+	if r.s != nil && r.s.GetCancelGracePeriod() > 0 {
+		return time.Duration(r.s.GetCancelGracePeriod()) * time.Second
+	}
+	return r.svc.GetCancelTimeout()
+}
+
+// waitProcessExit returns whether the process exits within the timeout
+func (r *Runner) waitProcessExit(timeout time.Duration) (ok bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if exists, _ := process.PidExists(int32(r.pid)); !exists {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
 // CleanUp clean up task runner
 func (r *Runner) CleanUp() (err error) {
+	// release artifacts directory, whose files have been uploaded
+	if err := releaseScratchDir(r.adir); err != nil {
+		return err
+	}
+
+	// release input file directory
+	if err := releaseScratchDir(r.idir); err != nil {
+		return err
+	}
+
+	// release git checkout directory
+	if err := r.removeGitWorkspace(); err != nil {
+		return err
+	}
+
+	// remove container if not removed on exit
+	if r.getContainer() != nil {
+		if err := removeContainer(getContainerName(r.tid)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -204,29 +454,17 @@ func (r *Runner) SetSubscribeTimeout(timeout time.Duration) {
 	r.subscribeTimeout = timeout
 }
 
+func (r *Runner) SetTraceContext(ctx context.Context) {
+	r.traceCtx = ctx
+}
+
 func (r *Runner) GetTaskId() (id primitive.ObjectID) {
 	return r.tid
 }
 
 func (r *Runner) configureCmd() {
-	var cmdStr string
-
-	// customized spider
-	if r.t.GetCmd() == "" {
-		cmdStr = r.s.GetCmd()
-	} else {
-		cmdStr = r.t.GetCmd()
-	}
-
-	// parameters
-	if r.t.GetParam() != "" {
-		cmdStr += " " + r.t.GetParam()
-	} else if r.s.GetParam() != "" {
-		cmdStr += " " + r.s.GetParam()
-	}
-
 	// get cmd instance
-	r.cmd = sys_exec.BuildCmd(cmdStr)
+	r.cmd = sys_exec.BuildCmd(r.getCmdStr())
 
 	// set working directory
 	r.cmd.Dir = r.cwd
@@ -235,14 +473,43 @@ func (r *Runner) configureCmd() {
 	//sys_exec.SetPgid(r.cmd)
 }
 
+// getCmdStr returns the cmd of the task with its parameters and placeholders
+// replaced
+func (r *Runner) getCmdStr() (cmdStr string) {
+	// cmd with parameters
+	cmdStr = task.GetTaskCmd(r.t, r.s)
+
+	// concurrency
+	if concurrency := r.t.GetConcurrency(); concurrency > 0 {
+		cmdStr = strings.ReplaceAll(cmdStr, constants.TaskConcurrencyPlaceholder, strconv.Itoa(concurrency))
+	}
+
+	return cmdStr
+}
+
 func (r *Runner) configureLogging() {
+	// output encoding
+	enc := r.getOutputEncoding()
+
 	// set stdout reader
 	stdout, _ := r.cmd.StdoutPipe()
-	r.scannerStdout = bufio.NewReaderSize(stdout, r.bufferSize)
+	r.scannerStdout = bufio.NewReaderSize(utils.NewUtf8Reader(stdout, enc), r.bufferSize)
 
 	// set stderr reader
 	stderr, _ := r.cmd.StderrPipe()
-	r.scannerStderr = bufio.NewReaderSize(stderr, r.bufferSize)
+	r.scannerStderr = bufio.NewReaderSize(utils.NewUtf8Reader(stderr, enc), r.bufferSize)
+}
+
+// getOutputEncoding returns the encoding of the process output declared by the
+// spider, which is transcoded to UTF-8 before being persisted. It falls back to
+// UTF-8 if the encoding is unknown.
+func (r *Runner) getOutputEncoding() (enc encoding.Encoding) {
+	enc, err := utils.GetEncoding(r.s.GetOutputEncoding())
+	if err != nil {
+		log.Warnf("task[%s] unknown output encoding %s, fallback to utf-8", r.tid.Hex(), r.s.GetOutputEncoding())
+		return unicode.UTF8
+	}
+	return enc
 }
 
 func (r *Runner) startLogging() {
@@ -255,24 +522,55 @@ func (r *Runner) startLogging() {
 
 func (r *Runner) startLoggingReaderStdout() {
 	for {
-		line, err := r.scannerStdout.ReadString(byte('\n'))
+		line, truncated, err := readLine(r.scannerStdout)
+		if err == nil || line != "" {
+			r.handleStdoutLine(line, truncated)
+		}
 		if err != nil {
 			break
 		}
-		line = strings.TrimSuffix(line, "\n")
-		r.writeLogLines([]string{line})
 	}
 }
 
 func (r *Runner) startLoggingReaderStderr() {
 	for {
-		line, err := r.scannerStderr.ReadString(byte('\n'))
+		line, _, err := readLine(r.scannerStderr)
+		if err == nil || line != "" {
+			r.writeLogLines([]string{line})
+		}
 		if err != nil {
 			break
 		}
-		line = strings.TrimSuffix(line, "\n")
+	}
+}
+
+// handleStdoutLine sends result items of item lines, persists checkpoints of
+// checkpoint lines and writes other lines to logs
+func (r *Runner) handleStdoutLine(line string, truncated bool) {
+	if token, ok := parseCheckpointLine(line, r.checkpointPrefix); ok {
+		if truncated {
+			r.writeLogLines([]string{truncatedCheckpointLogLine(r.bufferSize)})
+			return
+		}
+		r.updateCheckpoint(token)
+		return
+	}
+
+	records, ok, err := parseItemLine(line, r.itemPrefix)
+	if !ok {
 		r.writeLogLines([]string{line})
+		return
 	}
+	if truncated {
+		r.writeLogLines([]string{truncatedItemLogLine(r.bufferSize)})
+		return
+	}
+	if err != nil {
+		// invalid item is passed through as log
+		r.writeLogLines([]string{line, fmt.Sprintf("[crawlab] invalid item: %v", err)})
+		return
+	}
+	r.writeItems(records)
 }
 
 func (r *Runner) startHealthCheck() {
@@ -300,7 +598,30 @@ func (r *Runner) configureEnv() {
 	_ = os.Setenv("NODE_PATH", nodePath)
 
 	// default envs
-	r.cmd.Env = append(os.Environ(), "CRAWLAB_TASK_ID="+r.tid.Hex())
+	r.cmd.Env = os.Environ()
+	r.nenv = len(r.cmd.Env)
+	r.cmd.Env = append(r.cmd.Env, "CRAWLAB_TASK_ID="+r.tid.Hex())
+	r.cmd.Env = append(r.cmd.Env, constants.TaskArtifactsDirEnv+"="+r.adir)
+	if checkpoint := r.t.GetCheckpoint(); checkpoint != "" {
+		r.cmd.Env = append(r.cmd.Env, constants.TaskCheckpointEnv+"="+checkpoint)
+	}
+	if r.ipath != "" {
+		r.cmd.Env = append(r.cmd.Env, constants.TaskInputFileEnv+"="+r.ipath)
+	}
+	if concurrency := r.t.GetConcurrency(); concurrency > 0 {
+		r.cmd.Env = append(r.cmd.Env, constants.TaskConcurrencyEnv+"="+strconv.Itoa(concurrency))
+	}
+
+	// proxy selected from the proxy pool of the node, recorded on the task
+	if pool := proxy.GetPool(); pool.Enabled() {
+		p, err := pool.Get()
+		if err != nil {
+			trace.PrintError(err)
+		} else {
+			r.cmd.Env = append(r.cmd.Env, constants.TaskProxyEnv+"="+p)
+			r.t.SetProxy(proxy.Redact(p))
+		}
+	}
 	if viper.GetString("grpc.address") != "" {
 		r.cmd.Env = append(r.cmd.Env, "CRAWLAB_GRPC_ADDRESS="+viper.GetString("grpc.address"))
 	}
@@ -314,11 +635,18 @@ func (r *Runner) configureEnv() {
 	envs, err := r.svc.GetModelEnvironmentService().GetEnvironmentList(nil, nil)
 	if err != nil {
 		trace.PrintError(err)
-		return
 	}
 	for _, env := range envs {
 		r.cmd.Env = append(r.cmd.Env, env.GetKey()+"="+env.GetValue())
 	}
+
+	// variables of the spider profile, overriding global ones
+	if profile := r.t.GetProfile(); profile != "" {
+		r.cmd.Env = append(r.cmd.Env, constants.TaskProfileEnv+"="+profile)
+		for key, value := range r.s.GetProfileVariables(profile) {
+			r.cmd.Env = append(r.cmd.Env, key+"="+value)
+		}
+	}
 }
 
 // wait for process to finish and send task signal (constants.TaskSignal)
@@ -356,6 +684,31 @@ func (r *Runner) updateTask(status string, e error) (err error) {
 		if e != nil {
 			r.t.SetError(e.Error())
 		}
+
+		// keep checkpoint persisted during the run, pending one included
+		r.checkpoint.Flush()
+		if checkpoint := r.checkpoint.Get(); checkpoint != "" {
+			r.t.SetCheckpoint(checkpoint)
+		}
+
+		// keep logs truncated flag
+		if atomic.LoadInt32(&r.logsTruncated) == 1 {
+			r.t.SetLogsTruncated(true)
+		}
+
+		// keep items capped flag, which is set by master on inserting results
+		if t, err := r.svc.GetTaskById(r.tid); err == nil && t.GetItemsCapped() {
+			r.t.SetItemsCapped(true)
+		}
+
+		// keep result items captured by a test run
+		test := r.t.GetTest()
+		if test {
+			r.testResultsMu.Lock()
+			r.t.SetTestResults(r.testResults, r.testResultsCapped)
+			r.testResultsMu.Unlock()
+		}
+
 		if r.svc.GetNodeConfigService().IsMaster() {
 			if err := delegate.NewModelDelegate(r.t).Save(); err != nil {
 				return err
@@ -366,10 +719,16 @@ func (r *Runner) updateTask(status string, e error) (err error) {
 			}
 		}
 
-		// update stats
+		// update stats, test runs are excluded from stats of the spider
 		go func() {
 			r._updateTaskStat(status)
+			if test {
+				return
+			}
 			r._updateSpiderStat(status)
+			if status == constants.TaskStatusFinished {
+				r._updateSpiderLastSuccessTs()
+			}
 		}()
 	}
 
@@ -382,6 +741,102 @@ func (r *Runner) updateTask(status string, e error) (err error) {
 	return nil
 }
 
+// addEvent adds an event to the timeline of the task
+func (r *Runner) addEvent(eventType, message string) {
+	e := task.NewTaskEvent(r.tid, eventType, "", message)
+	if n, err := r.svc.GetCurrentNode(); err == nil {
+		e.Actor = task.GetNodeActor(n)
+		e.NodeId = n.GetId()
+	}
+	if r.svc.GetNodeConfigService().IsMaster() {
+		task.AddTaskEvent(e)
+	} else {
+		if err := client.NewModelDelegate(e, client.WithDelegateConfigPath(r.svc.GetConfigPath())).Add(); err != nil {
+			trace.PrintError(err)
+		}
+	}
+}
+
+// addStatusEvent adds the event of the final task status
+func (r *Runner) addStatusEvent(status string, e error) {
+	switch status {
+	case constants.TaskStatusFinished:
+		r.addEvent(constants.TaskEventFinished, r.getResultStatsMessage())
+	case constants.TaskStatusCancelled:
+		r.addEvent(constants.TaskEventCancelled, fmt.Sprintf("mode: %s", r.t.GetCancelMode()))
+	default:
+		var message string
+		if e != nil {
+			message = e.Error()
+		}
+		r.addEvent(constants.TaskEventError, message)
+	}
+}
+
+// updateCheckpoint persists the checkpoint token reported by the process,
+// debounced by the checkpoint writer
+func (r *Runner) updateCheckpoint(token string) {
+	r.checkpoint.Set(token)
+}
+
+// saveCheckpoint saves the checkpoint token to the task
+func (r *Runner) saveCheckpoint(token string) {
+	r.updateTaskFields(bson.M{
+		"$set": bson.M{
+			"checkpoint": token,
+		},
+	})
+}
+
+// onLogsTruncated records that logs of the task have been truncated as the
+// max log size is reached
+func (r *Runner) onLogsTruncated() {
+	atomic.StoreInt32(&r.logsTruncated, 1)
+	log.Warnf("task[%s] reached max log size %d bytes, further logs are discarded", r.tid.Hex(), r.logLimiter.maxSize)
+	r.updateTaskFields(bson.M{
+		"$set": bson.M{
+			"logs_truncated": true,
+		},
+	})
+	r.addEvent(constants.TaskEventLogsTruncated, fmt.Sprintf("reached max log size %d bytes", r.logLimiter.maxSize))
+}
+
+// updateTaskFields updates fields of the task directly on master, or through
+// the model service of master on worker nodes
+func (r *Runner) updateTaskFields(update bson.M) {
+	if r.svc.GetNodeConfigService().IsMaster() {
+		if err := mongo.GetMongoCol(interfaces.ModelColNameTask).UpdateId(r.tid, update); err != nil {
+			trace.PrintError(err)
+		}
+		return
+	}
+	modelSvc, err := client.NewBaseServiceDelegate(
+		client.WithBaseServiceModelId(interfaces.ModelIdTask),
+		client.WithBaseServiceConfigPath(r.svc.GetConfigPath()),
+	)
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	if err := modelSvc.UpdateById(r.tid, update); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// getResultStatsMessage returns counts of stored results if some of them were
+// deduplicated, or empty otherwise
+func (r *Runner) getResultStatsMessage() (message string) {
+	ts, err := r.svc.GetModelTaskStatService().GetTaskStatById(r.tid)
+	if err != nil {
+		trace.PrintError(err)
+		return ""
+	}
+	if ts.GetResultUpdatedCount() == 0 && ts.GetResultSkippedCount() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("results: %d inserted, %d updated, %d skipped", ts.GetResultInsertedCount(), ts.GetResultUpdatedCount(), ts.GetResultSkippedCount())
+}
+
 func (r *Runner) initSub() (err error) {
 	r.sub, err = r.c.GetTaskClient().Subscribe(context.Background())
 	if err != nil {
@@ -391,9 +846,17 @@ func (r *Runner) initSub() (err error) {
 }
 
 func (r *Runner) writeLogLines(lines []string) {
+	lines, capped := r.logLimiter.Limit(lines)
+	if capped {
+		go r.onLogsTruncated()
+	}
+	if len(lines) == 0 {
+		return
+	}
 	data, err := json.Marshal(&entity.StreamMessageTaskData{
 		TaskId: r.tid,
 		Logs:   lines,
+		Levels: r.logLevels.DetectLines(lines),
 	})
 	if err != nil {
 		trace.PrintError(err)
@@ -403,12 +866,67 @@ func (r *Runner) writeLogLines(lines []string) {
 		Code: grpc.StreamMessageCode_INSERT_LOGS,
 		Data: data,
 	}
-	if err := r.sub.Send(msg); err != nil {
+	if err := r.send(msg); err != nil {
 		trace.PrintError(err)
 		return
 	}
 }
 
+func (r *Runner) writeItems(records []entity.Result) {
+	// test run captures result items instead of storing them
+	if r.t.GetTest() {
+		r.captureTestResults(records)
+		return
+	}
+
+	ctx, span := tracing.Tracer().Start(r.traceCtx, "task.results.send",
+		oteltrace.WithAttributes(attribute.Int("crawlab.result_count", len(records))),
+	)
+	var err error
+	defer func() { tracing.EndSpan(span, err) }()
+	for _, record := range records {
+		record[constants.TaskKey] = r.tid
+	}
+	data, err := json.Marshal(&entity.StreamMessageTaskData{
+		TaskId:       r.tid,
+		Records:      records,
+		TraceContext: tracing.Inject(ctx),
+	})
+	if err != nil {
+		trace.PrintError(err)
+		return
+	}
+	msg := &grpc.StreamMessage{
+		Code: grpc.StreamMessageCode_INSERT_DATA,
+		Data: data,
+	}
+	if err = r.send(msg); err != nil {
+		trace.PrintError(err)
+		return
+	}
+}
+
+// send sends the message on the task stream, one at a time as required by grpc
+func (r *Runner) send(msg *grpc.StreamMessage) (err error) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+	return r.sub.Send(msg)
+}
+
+// captureTestResults keeps result items of a test run in memory up to
+// maxTestResults, which are saved on the task when its status is updated
+func (r *Runner) captureTestResults(records []entity.Result) {
+	r.testResultsMu.Lock()
+	defer r.testResultsMu.Unlock()
+	for _, record := range records {
+		if len(r.testResults) >= r.maxTestResults {
+			r.testResultsCapped = true
+			return
+		}
+		r.testResults = append(r.testResults, record)
+	}
+}
+
 func (r *Runner) _updateTaskStat(status string) {
 	ts, err := r.svc.GetModelTaskStatService().GetTaskStatById(r.tid)
 	if err != nil {
@@ -496,6 +1014,35 @@ func (r *Runner) _updateSpiderStat(status string) {
 
 }
 
+// _updateSpiderLastSuccessTs records the current time as the last successful
+// run of the spider, which is used to detect stale spiders
+func (r *Runner) _updateSpiderLastSuccessTs() {
+	update := bson.M{
+		"$set": bson.M{
+			"last_success_ts": time.Now(),
+		},
+	}
+	if r.svc.GetNodeConfigService().IsMaster() {
+		if err := mongo.GetMongoCol(interfaces.ModelColNameSpider).UpdateId(r.s.GetId(), update); err != nil {
+			trace.PrintError(err)
+			return
+		}
+	} else {
+		modelSvc, err := client.NewBaseServiceDelegate(
+			client.WithBaseServiceModelId(interfaces.ModelIdSpider),
+			client.WithBaseServiceConfigPath(r.svc.GetConfigPath()),
+		)
+		if err != nil {
+			trace.PrintError(err)
+			return
+		}
+		if err := modelSvc.UpdateById(r.s.GetId(), update); err != nil {
+			trace.PrintError(err)
+			return
+		}
+	}
+}
+
 func NewTaskRunner(id primitive.ObjectID, svc interfaces.TaskHandlerService, opts ...RunnerOption) (r2 interfaces.TaskRunner, err error) {
 	// validate options
 	if id.IsZero() {
@@ -506,12 +1053,33 @@ func NewTaskRunner(id primitive.ObjectID, svc interfaces.TaskHandlerService, opt
 	r := &Runner{
 		subscribeTimeout: 30 * time.Second,
 		bufferSize:       1024 * 1024,
+		itemPrefix:       DefaultItemPrefix,
+		checkpointPrefix: DefaultCheckpointPrefix,
+		logLevels:        newLogLevelDetectorFromConfig(),
+		logLimiter:       newLogLimiterFromConfig(),
+		maxTestResults:   100,
 		svc:              svc,
 		tid:              id,
 		ch:               make(chan constants.TaskSignal),
 		logBatchSize:     20,
 	}
 
+	// item prefix
+	if viper.IsSet("task.item.prefix") {
+		r.itemPrefix = viper.GetString("task.item.prefix")
+	}
+
+	// checkpoint prefix
+	if viper.IsSet("task.checkpoint.prefix") {
+		r.checkpointPrefix = viper.GetString("task.checkpoint.prefix")
+	}
+
+	// checkpoint writer
+	r.checkpoint = newCheckpointWriter(getCheckpointInterval(), r.saveCheckpoint)
+
+	// max result items captured by a test run
+	r.maxTestResults = task.GetMaxTestResults()
+
 	// apply options
 	for _, opt := range opts {
 		opt(r)
@@ -523,6 +1091,14 @@ func NewTaskRunner(id primitive.ObjectID, svc interfaces.TaskHandlerService, opt
 		return nil, err
 	}
 
+	// trace context, from the dispatch or else stored in the task
+	if r.traceCtx == nil {
+		r.traceCtx = context.Background()
+	}
+	if !oteltrace.SpanContextFromContext(r.traceCtx).IsValid() {
+		r.traceCtx = tracing.Extract(r.traceCtx, r.t.GetTraceContext())
+	}
+
 	// spider
 	r.s, err = svc.GetSpiderById(r.t.GetSpiderId())
 	if err != nil {
@@ -530,7 +1106,8 @@ func NewTaskRunner(id primitive.ObjectID, svc interfaces.TaskHandlerService, opt
 	}
 
 	// task fs service
-	r.fsSvc = fs2.NewFsServiceV2(filepath.Join(viper.GetString("workspace"), r.s.GetId().Hex()))
+	r.fsSvc = fs2.NewFsServiceV2(fs2.GetSpiderWorkspacePath(r.s.GetId()))
+	r.fsSvc.SetQuota(r.s.GetWorkspaceQuota())
 
 	// dependency injection
 	c := dig.New()
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"github.com/crawlab-team/crawlab-core/paths"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Scratch directories are created per task on the node, i.e. git checkouts of
+// tasks with a git ref, artifacts directories and directories of downloaded
+// input files, and named after the task id. Once the task ends, its scratch
+// directory is removed right away, or marked as done if retained and removed
+// by the cleanup job of the task handler service once expired. Artifacts
+// uploaded to master are stored in the blob store and not affected.
+
+// scratchDoneSuffix is the suffix of the file marking the scratch directory of
+// an ended task
+const scratchDoneSuffix = ".done"
+
+// scratchOrphanMinAge is the min age of scratch directories of tasks not run
+// by the node (e.g. left over by a crash) to be removed
+const scratchOrphanMinAge = time.Hour
+
+// getScratchRoots returns the root directories of per-task scratch directories
+func getScratchRoots() (roots []string) {
+	return []string{
+		getGitWorkspaceRoot(),
+		getArtifactsRoot(),
+		getInputsRoot(),
+	}
+}
+
+func getArtifactsRoot() (root string) {
+	return paths.GetTempPath("crawlab_task_artifacts")
+}
+
+func getInputsRoot() (root string) {
+	return paths.GetTempPath("crawlab_task_inputs")
+}
+
+// getScratchRetention returns how long scratch directories are kept after the
+// task ends, 0 to remove them right away. It is configured by
+// "task.cleanup.retention" (seconds), falling back to "task.git.retention".
+func getScratchRetention() (retention time.Duration) {
+	seconds := viper.GetInt("task.cleanup.retention")
+	if seconds <= 0 {
+		seconds = viper.GetInt("task.git.retention")
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// releaseScratchDir removes the scratch directory of an ended task, or marks
+// it as done if scratch directories are retained
+func releaseScratchDir(dir string) (err error) {
+	if dir == "" {
+		return nil
+	}
+	if getScratchRetention() > 0 {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return nil
+		}
+		if err := os.WriteFile(dir+scratchDoneSuffix, nil, os.FileMode(0644)); err != nil {
+			return trace.TraceError(err)
+		}
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// pruneScratchDirs removes scratch directories of tasks that ended earlier
+// than the retention, as well as orphaned ones of tasks not running on the
+// node. It returns the number of directories removed and the disk space
+// reclaimed (bytes).
+func pruneScratchDirs(retention time.Duration, isRunning func(id primitive.ObjectID) bool) (count int, reclaimed int64) {
+	orphanMinAge := retention
+	if orphanMinAge < scratchOrphanMinAge {
+		orphanMinAge = scratchOrphanMinAge
+	}
+	for _, root := range getScratchRoots() {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), scratchDoneSuffix)
+			done := name != e.Name()
+			if !done && !e.IsDir() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			dir := filepath.Join(root, name)
+			if done {
+				// ended task
+				if time.Since(info.ModTime()) < retention {
+					continue
+				}
+			} else {
+				// directory without marker, which is removed only if orphaned
+				if _, err := os.Stat(dir + scratchDoneSuffix); err == nil {
+					continue
+				}
+				id, err := primitive.ObjectIDFromHex(name)
+				if err != nil || isRunning(id) || time.Since(info.ModTime()) < orphanMinAge {
+					continue
+				}
+			}
+			size, _ := utils.GetDirSize(dir)
+			if err := os.RemoveAll(dir); err != nil {
+				trace.PrintError(err)
+				continue
+			}
+			_ = os.Remove(dir + scratchDoneSuffix)
+			count++
+			reclaimed += size
+		}
+	}
+	return count, reclaimed
+}
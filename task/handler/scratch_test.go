@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneScratchDirs(t *testing.T) {
+	viper.Set("data.path", t.TempDir())
+	defer viper.Set("data.path", nil)
+
+	root := getArtifactsRoot()
+	old := time.Now().Add(-2 * time.Hour)
+	newDir := func(id primitive.ObjectID, done bool, mtime time.Time) (dir string) {
+		dir = filepath.Join(root, id.Hex())
+		require.Nil(t, os.MkdirAll(dir, os.ModePerm))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), os.ModePerm))
+		require.Nil(t, os.Chtimes(dir, mtime, mtime))
+		if done {
+			require.Nil(t, os.WriteFile(dir+scratchDoneSuffix, nil, os.ModePerm))
+			require.Nil(t, os.Chtimes(dir+scratchDoneSuffix, mtime, mtime))
+		}
+		return dir
+	}
+
+	runningId := primitive.NewObjectID()
+	expired := newDir(primitive.NewObjectID(), true, old)
+	retained := newDir(primitive.NewObjectID(), true, time.Now())
+	orphan := newDir(primitive.NewObjectID(), false, old)
+	running := newDir(runningId, false, old)
+	starting := newDir(primitive.NewObjectID(), false, time.Now())
+
+	count, reclaimed := pruneScratchDirs(time.Minute, func(id primitive.ObjectID) bool {
+		return id == runningId
+	})
+	require.Equal(t, 2, count)
+	require.Equal(t, int64(10), reclaimed)
+	require.NoDirExists(t, expired)
+	require.NoFileExists(t, expired+scratchDoneSuffix)
+	require.NoDirExists(t, orphan)
+	require.DirExists(t, retained)
+	require.DirExists(t, running)
+	require.DirExists(t, starting)
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/apex/log"
 	config2 "github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	errors2 "github.com/crawlab-team/crawlab-core/errors"
+	fs2 "github.com/crawlab-team/crawlab-core/fs"
 	client2 "github.com/crawlab-team/crawlab-core/grpc/client"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/client"
@@ -15,13 +18,18 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
 	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/tracing"
+	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -45,12 +53,17 @@ type Service struct {
 	fetchInterval     time.Duration
 	fetchTimeout      time.Duration
 	cancelTimeout     time.Duration
+	workspaceInterval time.Duration // interval of computing workspace disk usage
+	cleanupInterval   time.Duration // interval of removing expired scratch directories of tasks
 
 	// internals variables
-	stopped   bool
-	mu        sync.Mutex
-	runners   sync.Map // pool of task runners started
-	syncLocks sync.Map // files sync locks map of task runners
+	stopped         bool
+	mu              sync.Mutex
+	runners         sync.Map  // pool of task runners started
+	syncLocks       sync.Map  // files sync locks map of task runners
+	workspaceSize   int64     // last computed workspace disk usage
+	workspaceSizeTs time.Time // last time of computing workspace disk usage
+	reclaimedSize   int64     // disk space reclaimed by cleanup since start (atomic)
 }
 
 func (svc *Service) Start() {
@@ -61,10 +74,11 @@ func (svc *Service) Start() {
 
 	go svc.ReportStatus()
 	go svc.Fetch()
+	go svc.CleanupScratchDirs()
 }
 
 func (svc *Service) Run(taskId primitive.ObjectID) (err error) {
-	return svc.run(taskId)
+	return svc.run(context.Background(), taskId)
 }
 
 func (svc *Service) Reset() {
@@ -73,11 +87,15 @@ func (svc *Service) Reset() {
 }
 
 func (svc *Service) Cancel(taskId primitive.ObjectID) (err error) {
+	return svc.CancelWithMode(taskId, constants.TaskCancelModeForce)
+}
+
+func (svc *Service) CancelWithMode(taskId primitive.ObjectID, mode string) (err error) {
 	r, err := svc.getRunner(taskId)
 	if err != nil {
 		return err
 	}
-	if err := r.Cancel(); err != nil {
+	if err := r.CancelWithMode(mode); err != nil {
 		return err
 	}
 	return nil
@@ -99,6 +117,11 @@ func (svc *Service) Fetch() {
 			continue
 		}
 
+		// skip if node is draining or cordoned
+		if n.GetStatus() == constants.NodeStatusDraining || !n.GetSchedulable() {
+			continue
+		}
+
 		// validate if there are available runners
 		if svc.getRunnerCount() >= n.GetMaxRunners() {
 			continue
@@ -110,7 +133,7 @@ func (svc *Service) Fetch() {
 		}
 
 		// fetch task
-		tid, err := svc.fetch()
+		tid, traceCtx, ackRequired, err := svc.fetch()
 		if err != nil {
 			trace.PrintError(err)
 			continue
@@ -121,8 +144,14 @@ func (svc *Service) Fetch() {
 			continue
 		}
 
+		// accept or reject task, skip if not to run, in which case master
+		// returns it to the task queue
+		if ackRequired && !svc.ackTask(tid, n) {
+			continue
+		}
+
 		// run task
-		if err := svc.run(tid); err != nil {
+		if err := svc.run(traceCtx, tid); err != nil {
 			trace.PrintError(err)
 			t, err := svc.GetTaskById(tid)
 			if err == nil && t.GetStatus() != constants.TaskStatusCancelled {
@@ -151,6 +180,23 @@ func (svc *Service) ReportStatus() {
 	}
 }
 
+// CleanupScratchDirs periodically removes scratch directories of ended tasks
+// after the retention, so that they do not exhaust the disk of long-lived nodes.
+// Stopped containers of tasks not running on the node are removed as well.
+func (svc *Service) CleanupScratchDirs() {
+	for {
+		if svc.stopped {
+			return
+		}
+
+		svc.cleanupScratchDirs()
+		svc.cleanupContainers()
+
+		// wait
+		time.Sleep(svc.cleanupInterval)
+	}
+}
+
 func (svc *Service) IsSyncLocked(path string) (ok bool) {
 	_, ok = svc.syncLocks.Load(path)
 	return ok
@@ -200,6 +246,14 @@ func (svc *Service) GetCancelTimeout() (timeout time.Duration) {
 	return svc.cancelTimeout
 }
 
+func (svc *Service) SetWorkspaceInterval(interval time.Duration) {
+	svc.workspaceInterval = interval
+}
+
+func (svc *Service) SetCleanupInterval(interval time.Duration) {
+	svc.cleanupInterval = interval
+}
+
 func (svc *Service) SetCancelTimeout(timeout time.Duration) {
 	svc.cancelTimeout = timeout
 }
@@ -275,8 +329,9 @@ func (svc *Service) getRunners() (runners []*Runner) {
 	svc.mu.Lock()
 	defer svc.mu.Unlock()
 	svc.runners.Range(func(key, value interface{}) bool {
-		r := value.(Runner)
-		runners = append(runners, &r)
+		if r, ok := value.(*Runner); ok {
+			runners = append(runners, r)
+		}
 		return true
 	})
 	return runners
@@ -370,17 +425,40 @@ func (svc *Service) reportStatus() (err error) {
 		return err
 	}
 
+	// running tasks count
+	runnerCount := svc.getRunnerCount()
+
 	// available runners of handler
-	ar := n.GetMaxRunners() - svc.getRunnerCount()
+	ar := n.GetMaxRunners() - runnerCount
 
 	// set available runners
 	n.SetAvailableRunners(ar)
 
-	// save node
+	// report idle if node is draining
+	if n.GetStatus() == constants.NodeStatusDraining {
+		drained := runnerCount == 0
+		if drained && !n.GetDrained() {
+			log.Infof("[TaskHandlerService] node[%s] is drained and safe to stop", n.GetKey())
+		}
+		n.SetDrained(drained)
+	}
+
+	// workspace disk usage
+	n.SetWorkspaceSize(svc.getWorkspaceSize())
+	n.SetWorkspaceReclaimed(atomic.LoadInt64(&svc.reclaimedSize))
+
+	// save reported fields only, so that concurrent updates of the node by
+	// master, e.g. its status, are not overwritten
+	fields := bson.M{
+		"available_runners":   n.GetAvailableRunners(),
+		"drained":             n.GetDrained(),
+		"workspace_size":      n.GetWorkspaceSize(),
+		"workspace_reclaimed": n.GetWorkspaceReclaimed(),
+	}
 	if svc.cfgSvc.IsMaster() {
-		err = delegate.NewModelDelegate(n).Save()
+		err = delegate.NewModelDelegate(n).UpdateFields(fields)
 	} else {
-		err = client.NewModelDelegate(n, client.WithDelegateConfigPath(svc.GetConfigPath())).Save()
+		err = client.NewModelDelegate(n, client.WithDelegateConfigPath(svc.GetConfigPath())).UpdateFields(fields)
 	}
 	if err != nil {
 		return err
@@ -389,20 +467,119 @@ func (svc *Service) reportStatus() (err error) {
 	return nil
 }
 
-func (svc *Service) fetch() (tid primitive.ObjectID, err error) {
+// getWorkspaceSize returns disk usage of workspaces, which is recomputed
+// at most once per workspaceInterval as walking the workspace is expensive
+func (svc *Service) getWorkspaceSize() (size int64) {
+	if time.Since(svc.workspaceSizeTs) < svc.workspaceInterval {
+		return svc.workspaceSize
+	}
+	size, err := fs2.GetWorkspaceSize()
+	if err != nil {
+		trace.PrintError(err)
+		return svc.workspaceSize
+	}
+	svc.workspaceSize = size
+	svc.workspaceSizeTs = time.Now()
+	return size
+}
+
+func (svc *Service) cleanupScratchDirs() {
+	count, reclaimed := pruneScratchDirs(getScratchRetention(), func(id primitive.ObjectID) bool {
+		_, ok := svc.runners.Load(id)
+		return ok
+	})
+	if count == 0 {
+		return
+	}
+	total := atomic.AddInt64(&svc.reclaimedSize, reclaimed)
+	log.Infof("[TaskHandlerService] removed %d task scratch directories, reclaimed %d bytes (%d bytes since start)", count, reclaimed, total)
+}
+
+func (svc *Service) cleanupContainers() {
+	count := pruneTaskContainers(func(id primitive.ObjectID) bool {
+		_, ok := svc.runners.Load(id)
+		return ok
+	})
+	if count > 0 {
+		log.Infof("[TaskHandlerService] removed %d stopped task containers", count)
+	}
+}
+
+// fetch fetches a task dispatched to the node, along with the context carrying
+// the trace context of the dispatch sent in the response header, and whether
+// master requires the node to acknowledge the task
+func (svc *Service) fetch() (tid primitive.ObjectID, traceCtx context.Context, ackRequired bool, err error) {
+	traceCtx = context.Background()
 	ctx, cancel := context.WithTimeout(context.Background(), svc.fetchTimeout)
 	defer cancel()
-	res, err := svc.c.GetTaskClient().Fetch(ctx, svc.c.NewRequest(nil))
+	ctx = metadata.AppendToOutgoingContext(ctx, constants.GrpcHeaderTaskAck, "1")
+	var md metadata.MD
+	res, err := svc.c.GetTaskClient().Fetch(ctx, svc.c.NewRequest(nil), grpc2.Header(&md))
 	if err != nil {
-		return tid, trace.TraceError(err)
+		return tid, traceCtx, false, trace.TraceError(err)
 	}
 	if err := json.Unmarshal(res.Data, &tid); err != nil {
-		return tid, trace.TraceError(err)
+		return tid, traceCtx, false, trace.TraceError(err)
 	}
-	return tid, nil
+	ackRequired = len(md.Get(constants.GrpcHeaderTaskAck)) > 0
+	return tid, tracing.ExtractMetadata(traceCtx, md), ackRequired, nil
 }
 
-func (svc *Service) run(taskId primitive.ObjectID) (err error) {
+// ackTask accepts the dispatched task if the node is able to run it, or else
+// rejects it, and returns whether to run the task. The task is not run if
+// master does not confirm the acceptance, e.g. it has returned the task to the
+// task queue on ack timeout.
+func (svc *Service) ackTask(tid primitive.ObjectID, n interfaces.Node) (ok bool) {
+	ack := entity.TaskAckMessage{TaskId: tid, Accepted: true}
+	if svc.stopped {
+		ack.Accepted, ack.Reason = false, "node is stopping"
+	} else if count := svc.getRunnerCount(); count >= n.GetMaxRunners() {
+		ack.Accepted, ack.Reason = false, fmt.Sprintf("max runners reached (%d/%d)", count, n.GetMaxRunners())
+	} else if _, exists := svc.runners.Load(tid); exists {
+		ack.Accepted, ack.Reason = false, "task is already running"
+	}
+
+	confirmed, err := svc.sendTaskAck(ack)
+	if err != nil {
+		trace.PrintError(err)
+		return false
+	}
+	if ack.Accepted && !confirmed {
+		log.Warnf("task[%s] accepted after ack timeout, skipped", tid.Hex())
+	}
+	return ack.Accepted && confirmed
+}
+
+// sendTaskAck sends the ack to master and returns whether it is confirmed
+func (svc *Service) sendTaskAck(ack entity.TaskAckMessage) (confirmed bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), svc.fetchTimeout)
+	defer cancel()
+	data, err := json.Marshal(ack)
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	stream, err := svc.c.GetTaskClient().Subscribe(ctx)
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	if err := stream.Send(&grpc.StreamMessage{
+		Code:    grpc.StreamMessageCode(constants.GrpcStreamMessageCodeTaskAck),
+		NodeKey: svc.cfgSvc.GetNodeKey(),
+		Data:    data,
+	}); err != nil {
+		return false, trace.TraceError(err)
+	}
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return false, trace.TraceError(err)
+	}
+	if err := json.Unmarshal(res.Data, &confirmed); err != nil {
+		return false, trace.TraceError(err)
+	}
+	return confirmed, nil
+}
+
+func (svc *Service) run(traceCtx context.Context, taskId primitive.ObjectID) (err error) {
 	// attempt to get runner from pool
 	_, ok := svc.runners.Load(taskId)
 	if ok {
@@ -410,7 +587,7 @@ func (svc *Service) run(taskId primitive.ObjectID) (err error) {
 	}
 
 	// create a new task runner
-	r, err := NewTaskRunner(taskId, svc)
+	r, err := NewTaskRunner(taskId, svc, WithTraceContext(traceCtx))
 	if err != nil {
 		return trace.TraceError(err)
 	}
@@ -461,6 +638,8 @@ func NewTaskHandlerService(opts ...Option) (svc2 interfaces.TaskHandlerService,
 		fetchTimeout:      15 * time.Second,
 		reportInterval:    5 * time.Second,
 		cancelTimeout:     5 * time.Second,
+		workspaceInterval: 60 * time.Second,
+		cleanupInterval:   10 * time.Minute,
 		mu:                sync.Mutex{},
 		runners:           sync.Map{},
 		syncLocks:         sync.Map{},
@@ -573,6 +752,16 @@ func ProvideGetTaskHandlerService(path string, opts ...Option) func() (svr inter
 	if cancelTimeoutSeconds > 0 {
 		opts = append(opts, WithCancelTimeout(time.Duration(cancelTimeoutSeconds)*time.Second))
 	}
+	// workspace interval
+	workspaceIntervalSeconds := viper.GetInt("task.handler.workspaceInterval")
+	if workspaceIntervalSeconds > 0 {
+		opts = append(opts, WithWorkspaceInterval(time.Duration(workspaceIntervalSeconds)*time.Second))
+	}
+	// cleanup interval
+	cleanupIntervalSeconds := viper.GetInt("task.handler.cleanupInterval")
+	if cleanupIntervalSeconds > 0 {
+		opts = append(opts, WithCleanupInterval(time.Duration(cleanupIntervalSeconds)*time.Second))
+	}
 	return func() (svr interfaces.TaskHandlerService, err error) {
 		return GetTaskHandlerService(path, opts...)
 	}
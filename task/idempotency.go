@@ -0,0 +1,124 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// idempotencyRecord maps an idempotency key to the tasks created by the first
+// request carrying it. Records expire after "task.idempotency.ttl" (seconds,
+// 24 hours by default) and are removed by the TTL index on expire_ts. Records
+// of requests in progress for longer than "task.idempotency.lease" (seconds,
+// 60 by default), e.g. left by a crash before completion, are not taken over
+// since their tasks may have been created, and requests carrying the key fail
+// until the record expires.
+type idempotencyRecord struct {
+	Id          primitive.ObjectID   `bson:"_id"`
+	Key         string               `bson:"key"`
+	RequestHash string               `bson:"request_hash"`
+	TaskIds     []primitive.ObjectID `bson:"task_ids"`
+	Done        bool                 `bson:"done"`
+	CreateTs    time.Time            `bson:"create_ts"`
+	ExpireTs    time.Time            `bson:"expire_ts"`
+}
+
+func getIdempotencyTtl() (ttl time.Duration) {
+	if seconds := viper.GetInt("task.idempotency.ttl"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 24 * time.Hour
+}
+
+func getIdempotencyLease() (lease time.Duration) {
+	if seconds := viper.GetInt("task.idempotency.lease"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Minute
+}
+
+// HashIdempotentRequest returns the hash identifying a request by its scope
+// (e.g. endpoint and resource id) and payload
+func HashIdempotentRequest(scope string, payload interface{}) (hash string, err error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	h := sha256.New()
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BeginIdempotentRequest reserves the idempotency key for the request. If the
+// key has been used by an identical request which has completed, the task ids
+// created by it are returned with done set to true. The caller must either
+// complete or abort the reserved key.
+func BeginIdempotentRequest(key, requestHash string) (taskIds []primitive.ObjectID, done bool, err error) {
+	col := mongo.GetMongoCol(constants.TaskIdempotencyKeyColName)
+	now := time.Now()
+
+	// remove expired record not yet removed by the TTL index
+	if err := col.Delete(bson.M{"key": key, "expire_ts": bson.M{"$lt": now}}); err != nil {
+		return nil, false, trace.TraceError(err)
+	}
+
+	// reserve
+	r := idempotencyRecord{
+		Id:          primitive.NewObjectID(),
+		Key:         key,
+		RequestHash: requestHash,
+		CreateTs:    now,
+		ExpireTs:    now.Add(getIdempotencyTtl()),
+	}
+	if _, err := col.Insert(r); err == nil {
+		return nil, false, nil
+	} else if !mongo2.IsDuplicateKeyError(err) {
+		return nil, false, trace.TraceError(err)
+	}
+
+	// already used
+	if err := col.Find(bson.M{"key": key}, nil).One(&r); err != nil {
+		return nil, false, trace.TraceError(err)
+	}
+	if r.RequestHash != requestHash {
+		return nil, false, errors.ErrorTaskIdempotencyMismatch
+	}
+	if !r.Done {
+		if now.Sub(r.CreateTs) < getIdempotencyLease() {
+			return nil, false, errors.ErrorTaskIdempotencyInProgress
+		}
+		return nil, false, errors.ErrorTaskIdempotencyUnknown
+	}
+	return r.TaskIds, true, nil
+}
+
+// CompleteIdempotentRequest records the task ids created by the request of the reserved key
+func CompleteIdempotentRequest(key string, taskIds []primitive.ObjectID) (err error) {
+	if err := mongo.GetMongoCol(constants.TaskIdempotencyKeyColName).Update(bson.M{"key": key}, bson.M{
+		"$set": bson.M{
+			"task_ids": taskIds,
+			"done":     true,
+		},
+	}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// AbortIdempotentRequest releases the reserved key so that the request can be retried
+func AbortIdempotentRequest(key string) {
+	if err := mongo.GetMongoCol(constants.TaskIdempotencyKeyColName).Delete(bson.M{"key": key}); err != nil {
+		trace.PrintError(err)
+	}
+}
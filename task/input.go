@@ -0,0 +1,270 @@
+package task
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/blob"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/fs"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// Spider inputs are files uploaded for a spider to seed its crawls, e.g. a
+// list of urls. Master stores the files in the blob store (see
+// blob.GetBlobStore) and their metadata in constants.SpiderInputColName. A
+// task run with an input file downloads it from master (GET
+// /sync/inputs/:id) before the process starts, and passes its path to the
+// process in the environment variable constants.TaskInputFileEnv.
+
+// SpiderInput is the metadata of an input file of a spider
+type SpiderInput struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id"`
+	SpiderId    primitive.ObjectID `json:"spider_id" bson:"spider_id"`
+	Filename    string             `json:"filename" bson:"filename"`
+	Size        int64              `json:"size" bson:"size"`
+	ContentType string             `json:"content_type" bson:"content_type"`
+	CreateTs    time.Time          `json:"create_ts" bson:"create_ts"`
+}
+
+// defaultSpiderInputContentTypes are content types of input files allowed if
+// "spider.inputs.contentTypes" is not set. Binary files, which are sniffed as
+// application/octet-stream, are not allowed by default.
+var defaultSpiderInputContentTypes = []string{
+	"text/plain",
+	"text/csv",
+	"text/tab-separated-values",
+	"application/json",
+	"application/x-ndjson",
+}
+
+// GetSpiderInputMaxSize returns the max size (bytes) of an input file, which
+// is configured by "spider.inputs.maxSize" (100 MB by default)
+func GetSpiderInputMaxSize() (size int64) {
+	if size = viper.GetInt64("spider.inputs.maxSize"); size > 0 {
+		return size
+	}
+	return 100 << 20
+}
+
+// IsSpiderInputContentTypeAllowed returns whether input files of the content
+// type are allowed, i.e. listed in "spider.inputs.contentTypes" or
+// defaultSpiderInputContentTypes if not set. Parameters of the content type,
+// e.g. charset, are ignored.
+func IsSpiderInputContentTypeAllowed(contentType string) (ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	allowed := viper.GetStringSlice("spider.inputs.contentTypes")
+	if len(allowed) == 0 {
+		allowed = defaultSpiderInputContentTypes
+	}
+	for _, t := range allowed {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSpiderInputKey returns the blob key of the input file
+func GetSpiderInputKey(in *SpiderInput) (key string) {
+	return getSpiderInputsPrefix(in.SpiderId) + "/" + in.Id.Hex()
+}
+
+func getSpiderInputsPrefix(spiderId primitive.ObjectID) (prefix string) {
+	return "inputs/" + spiderId.Hex()
+}
+
+// DetectSpiderInputContentType returns the content type of an input file
+// sniffed from its head (see http.DetectContentType), so that the declared
+// content type cannot smuggle other files in. Text files are all sniffed as
+// text/plain, which is refined by the declared content type if it is allowed,
+// e.g. text/csv.
+func DetectSpiderInputContentType(declared string, head []byte) (contentType string) {
+	contentType = http.DetectContentType(head)
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "text/plain" {
+		return contentType
+	}
+	if declared != "" && IsSpiderInputContentTypeAllowed(declared) {
+		return declared
+	}
+	return contentType
+}
+
+// SaveSpiderInput stores the input file read from r on master. It returns
+// errors.ErrorTaskInputSizeExceeded without storing anything if the file is
+// larger than GetSpiderInputMaxSize, and errors.ErrorTaskInputInvalidType if
+// the declared or the sniffed content type is not allowed.
+func SaveSpiderInput(spiderId primitive.ObjectID, filename, contentType string, r io.Reader) (in *SpiderInput, err error) {
+	filename = path.Base(filepath.ToSlash(filename))
+	if filename == "." || filename == "/" || filename == ".." {
+		return nil, trace.TraceError(errors.ErrorTaskInputInvalidName)
+	}
+	if contentType == "application/octet-stream" {
+		// generic type of clients for unknown files, which are sniffed
+		contentType = ""
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(filename))
+	}
+	if contentType != "" && !IsSpiderInputContentTypeAllowed(contentType) {
+		return nil, trace.TraceError(errors.ErrorTaskInputInvalidType)
+	}
+
+	in = &SpiderInput{
+		Id:       primitive.NewObjectID(),
+		SpiderId: spiderId,
+		Filename: filename,
+		CreateTs: time.Now(),
+	}
+
+	// buffer to a temp file first to check the size
+	f, err := os.CreateTemp("", "crawlab_input_")
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	maxSize := GetSpiderInputMaxSize()
+	in.Size, err = io.Copy(f, io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if in.Size > maxSize {
+		return nil, trace.TraceError(errors.ErrorTaskInputSizeExceeded)
+	}
+
+	// sniff content type
+	head := make([]byte, 512)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, trace.TraceError(err)
+	}
+	in.ContentType = DetectSpiderInputContentType(contentType, head[:n])
+	if !IsSpiderInputContentTypeAllowed(in.ContentType) {
+		return nil, trace.TraceError(errors.ErrorTaskInputInvalidType)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	// store file
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(GetSpiderInputKey(in), f, in.Size, in.ContentType); err != nil {
+		return nil, err
+	}
+
+	// add metadata
+	if _, err := mongo.GetMongoCol(constants.SpiderInputColName).Insert(in); err != nil {
+		_ = store.DeleteAll(GetSpiderInputKey(in))
+		return nil, trace.TraceError(err)
+	}
+
+	return in, nil
+}
+
+// GetSpiderInputs returns metadata of input files of the spider, latest first
+func GetSpiderInputs(spiderId primitive.ObjectID) (inputs []SpiderInput, err error) {
+	inputs = []SpiderInput{}
+	if err := mongo.GetMongoCol(constants.SpiderInputColName).Find(bson.M{"spider_id": spiderId}, &mongo.FindOptions{
+		Sort: bson.D{{"_id", -1}},
+	}).All(&inputs); err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	return inputs, nil
+}
+
+// GetSpiderInput returns metadata of the input file
+func GetSpiderInput(id primitive.ObjectID) (in *SpiderInput, err error) {
+	in = &SpiderInput{}
+	if err := mongo.GetMongoCol(constants.SpiderInputColName).FindId(id).One(in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// DeleteSpiderInput removes the input file of the spider and its metadata
+func DeleteSpiderInput(spiderId, id primitive.ObjectID) (err error) {
+	in, err := GetSpiderInput(id)
+	if err != nil {
+		return err
+	}
+	if in.SpiderId != spiderId {
+		return mongo2.ErrNoDocuments
+	}
+	store, err := blob.GetBlobStore()
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteAll(GetSpiderInputKey(in)); err != nil {
+		return err
+	}
+	if err := mongo.GetMongoCol(constants.SpiderInputColName).DeleteId(id); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// DownloadSpiderInput downloads the input file from master into the directory
+// and returns the path of the downloaded file
+func DownloadSpiderInput(id primitive.ObjectID, dir string) (filePath string, err error) {
+	u := fmt.Sprintf("%s/sync/inputs/%s", utils.GetApiAddress(), id.Hex())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	req.Header.Set(constants.SyncHeaderAuthorization, fs.GetSyncAuthKey())
+	res, err := artifactHttpClient.Do(req)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return "", trace.TraceError(errors.ErrorTaskInputNotFound)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", trace.TraceError(fmt.Errorf("download input file %s failed: %s", id.Hex(), res.Status))
+	}
+
+	// file name from the response, falling back to the id
+	filename := id.Hex()
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = path.Base(filepath.ToSlash(params["filename"]))
+	}
+
+	if err := os.MkdirAll(dir, fs.WorkspaceDirPerm); err != nil {
+		return "", trace.TraceError(err)
+	}
+	filePath = filepath.Join(dir, filename)
+	f, err := os.Create(filePath)
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		_ = f.Close()
+		return "", trace.TraceError(err)
+	}
+	if err := f.Close(); err != nil {
+		return "", trace.TraceError(err)
+	}
+	return filePath, nil
+}
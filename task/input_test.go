@@ -0,0 +1,37 @@
+package task
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsSpiderInputContentTypeAllowed(t *testing.T) {
+	require.True(t, IsSpiderInputContentTypeAllowed("text/plain"))
+	require.True(t, IsSpiderInputContentTypeAllowed("text/csv; charset=utf-8"))
+	require.False(t, IsSpiderInputContentTypeAllowed("application/x-msdownload"))
+	require.False(t, IsSpiderInputContentTypeAllowed("application/octet-stream"))
+	require.False(t, IsSpiderInputContentTypeAllowed(""))
+
+	viper.Set("spider.inputs.contentTypes", []string{"application/x-msdownload"})
+	defer viper.Set("spider.inputs.contentTypes", nil)
+	require.True(t, IsSpiderInputContentTypeAllowed("application/x-msdownload"))
+	require.False(t, IsSpiderInputContentTypeAllowed("text/plain"))
+}
+
+func TestDetectSpiderInputContentType(t *testing.T) {
+	// text refined by the declared content type
+	require.Equal(t, "text/csv", DetectSpiderInputContentType("text/csv", []byte("url,name\nhttps://example.com,example\n")))
+	require.Equal(t, "application/json", DetectSpiderInputContentType("application/json", []byte(`{"url": "https://example.com"}`)))
+	require.Equal(t, "text/plain; charset=utf-8", DetectSpiderInputContentType("", []byte("https://example.com\n")))
+	require.Equal(t, "text/plain; charset=utf-8", DetectSpiderInputContentType("application/x-msdownload", []byte("https://example.com\n")))
+
+	// binary files are sniffed regardless of the declared content type
+	exe := append([]byte("MZ\x90\x00"), make([]byte, 64)...)
+	require.Equal(t, "application/octet-stream", DetectSpiderInputContentType("text/csv", exe))
+	require.False(t, IsSpiderInputContentTypeAllowed(DetectSpiderInputContentType("text/csv", exe)))
+	zip := []byte("PK\x03\x04rest of the archive")
+	require.Equal(t, "application/zip", DetectSpiderInputContentType("text/plain", zip))
+	html := []byte("<html><script>alert(1)</script></html>")
+	require.Equal(t, "text/html; charset=utf-8", DetectSpiderInputContentType("text/csv", html))
+}
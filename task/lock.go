@@ -0,0 +1,138 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// taskLock is a lease of a lock key held by a dispatched or running task, so
+// that at most one task of the lock key runs at the same time in the cluster.
+// The lease expires after "task.lock.ttl" (seconds, 60 by default) unless
+// renewed by RenewTaskLocks for the node the task is dispatched to, so that
+// locks of tasks lost with their nodes or master are eventually released.
+type taskLock struct {
+	Key      string             `bson:"_id"`
+	TaskId   primitive.ObjectID `bson:"task_id"`
+	NodeKey  string             `bson:"node_key"`
+	CreateTs time.Time          `bson:"create_ts"`
+	ExpireTs time.Time          `bson:"expire_ts"`
+}
+
+func getTaskLockTtl() (ttl time.Duration) {
+	if seconds := viper.GetInt("task.lock.ttl"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Minute
+}
+
+// GetTaskLockRenewInterval returns the interval of renewing locks, a third of
+// the lease, so that a lease survives a missed renewal
+func GetTaskLockRenewInterval() (interval time.Duration) {
+	return getTaskLockTtl() / 3
+}
+
+// AcquireTaskLock acquires the lock key for the task dispatched to the node of
+// the key. ok is false if the key is held by another task.
+func AcquireTaskLock(key string, taskId primitive.ObjectID, nodeKey string) (ok bool, err error) {
+	col := mongo.GetMongoCol(constants.TaskLockColName)
+	now := time.Now()
+	query := bson.M{
+		"_id": key,
+		"$or": bson.A{
+			bson.M{"task_id": taskId},
+			bson.M{"expire_ts": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"task_id":   taskId,
+			"node_key":  nodeKey,
+			"create_ts": now,
+			"expire_ts": now.Add(getTaskLockTtl()),
+		},
+	}
+	// upsert fails with duplicate key if the key is held by another task
+	if _, err := col.GetCollection().UpdateOne(col.GetContext(), query, update, options.Update().SetUpsert(true)); err != nil {
+		if mongo2.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, trace.TraceError(err)
+	}
+	return true, nil
+}
+
+// ReleaseTaskLock releases the lock key if it is held by the task
+func ReleaseTaskLock(key string, taskId primitive.ObjectID) (err error) {
+	if err := mongo.GetMongoCol(constants.TaskLockColName).Delete(bson.M{"_id": key, "task_id": taskId}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// GetTaskLockHolders returns lock keys whose leases have not expired, mapped
+// to ids of the tasks holding them
+func GetTaskLockHolders() (holders map[string]primitive.ObjectID, err error) {
+	var locks []taskLock
+	if err := mongo.GetMongoCol(constants.TaskLockColName).Find(bson.M{"expire_ts": bson.M{"$gt": time.Now()}}, nil).All(&locks); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, trace.TraceError(err)
+	}
+	holders = map[string]primitive.ObjectID{}
+	for _, l := range locks {
+		holders[l.Key] = l.TaskId
+	}
+	return holders, nil
+}
+
+// RenewTaskLocks extends leases of locks held by tasks dispatched to the node
+// of the key. Master renews them on heartbeats of worker nodes and periodically
+// for its own tasks.
+func RenewTaskLocks(nodeKey string) (err error) {
+	col := mongo.GetMongoCol(constants.TaskLockColName)
+	if _, err := col.GetCollection().UpdateMany(col.GetContext(), bson.M{"node_key": nodeKey}, bson.M{
+		"$set": bson.M{"expire_ts": time.Now().Add(getTaskLockTtl())},
+	}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// ReleaseEndedTaskLocks releases locks of tasks which have ended or no longer
+// exist, e.g. if releasing them on the end of the tasks failed
+func ReleaseEndedTaskLocks() (err error) {
+	col := mongo.GetMongoCol(constants.TaskLockColName)
+	taskIds, err := col.GetCollection().Distinct(col.GetContext(), "task_id", bson.M{})
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if len(taskIds) == 0 {
+		return nil
+	}
+	tasksCol := mongo.GetMongoCol(interfaces.ModelColNameTask)
+	activeIds, err := tasksCol.GetCollection().Distinct(tasksCol.GetContext(), "_id", bson.M{
+		"_id":    bson.M{"$in": taskIds},
+		"status": bson.M{"$in": bson.A{constants.TaskStatusPending, constants.TaskStatusRunning}},
+	})
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if activeIds == nil {
+		activeIds = []interface{}{}
+	}
+	if _, err := col.GetCollection().DeleteMany(col.GetContext(), bson.M{
+		"task_id": bson.M{"$nin": activeIds},
+	}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
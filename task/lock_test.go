@@ -0,0 +1,150 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func setupTaskLockTest(t *testing.T) {
+	viper.Set("mongo.db", "crawlab_test")
+	cleanup := func() {
+		_ = mongo.GetMongoCol(constants.TaskLockColName).Delete(nil)
+		_ = mongo.GetMongoCol(interfaces.ModelColNameTask).Delete(nil)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+}
+
+func addTaskLockTestTask(t *testing.T, status string) (id primitive.ObjectID) {
+	task := &models.Task{Id: primitive.NewObjectID(), Status: status}
+	_, err := mongo.GetMongoCol(interfaces.ModelColNameTask).Insert(task)
+	require.Nil(t, err)
+	return task.Id
+}
+
+func TestAcquireTaskLock(t *testing.T) {
+	setupTaskLockTest(t)
+	t1 := primitive.NewObjectID()
+	t2 := primitive.NewObjectID()
+
+	// acquired, again by the same task, e.g. re-queued after reassignment
+	ok, err := AcquireTaskLock("key", t1, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = AcquireTaskLock("key", t1, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// held by another task
+	ok, err = AcquireTaskLock("key", t2, "node-1")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// other keys are independent
+	ok, err = AcquireTaskLock("other", t2, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// released only by the holder
+	require.Nil(t, ReleaseTaskLock("key", t2))
+	ok, err = AcquireTaskLock("key", t2, "node-1")
+	require.Nil(t, err)
+	require.False(t, ok)
+	require.Nil(t, ReleaseTaskLock("key", t1))
+	ok, err = AcquireTaskLock("key", t2, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+}
+
+func TestGetTaskLockHolders(t *testing.T) {
+	setupTaskLockTest(t)
+	t1 := primitive.NewObjectID()
+	t2 := primitive.NewObjectID()
+
+	// held keys are skipped by dispatching unless held by their own tasks
+	_, err := AcquireTaskLock("a", t1, "node-1")
+	require.Nil(t, err)
+	_, err = AcquireTaskLock("b", t2, "node-1")
+	require.Nil(t, err)
+	holders, err := GetTaskLockHolders()
+	require.Nil(t, err)
+	require.Equal(t, map[string]primitive.ObjectID{"a": t1, "b": t2}, holders)
+
+	// expired leases are not held
+	require.Nil(t, mongo.GetMongoCol(constants.TaskLockColName).Update(bson.M{"_id": "a"}, bson.M{
+		"$set": bson.M{"expire_ts": time.Now().Add(-time.Second)},
+	}))
+	holders, err = GetTaskLockHolders()
+	require.Nil(t, err)
+	require.Equal(t, map[string]primitive.ObjectID{"b": t2}, holders)
+}
+
+func TestAcquireTaskLock_Expired(t *testing.T) {
+	setupTaskLockTest(t)
+	t1 := primitive.NewObjectID()
+	t2 := primitive.NewObjectID()
+
+	viper.Set("task.lock.ttl", 1)
+	defer viper.Set("task.lock.ttl", nil)
+	ok, err := AcquireTaskLock("key", t1, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// lease of the holder lost with master expires
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = AcquireTaskLock("key", t2, "node-1")
+	require.Nil(t, err)
+	require.True(t, ok)
+	holders, err := GetTaskLockHolders()
+	require.Nil(t, err)
+	require.Equal(t, t2, holders["key"])
+}
+
+func TestRenewTaskLocks(t *testing.T) {
+	setupTaskLockTest(t)
+	t1 := primitive.NewObjectID()
+	t2 := primitive.NewObjectID()
+	_, err := AcquireTaskLock("a", t1, "node-1")
+	require.Nil(t, err)
+	_, err = AcquireTaskLock("b", t2, "node-2")
+	require.Nil(t, err)
+	require.Nil(t, mongo.GetMongoCol(constants.TaskLockColName).Update(bson.M{}, bson.M{
+		"$set": bson.M{"expire_ts": time.Now().Add(time.Second)},
+	}))
+
+	// locks of tasks of the node renewed only
+	require.Nil(t, RenewTaskLocks("node-1"))
+	var locks []taskLock
+	require.Nil(t, mongo.GetMongoCol(constants.TaskLockColName).Find(nil, &mongo.FindOptions{Sort: bson.D{{"_id", 1}}}).All(&locks))
+	require.Len(t, locks, 2)
+	require.True(t, locks[0].ExpireTs.After(time.Now().Add(getTaskLockTtl()/2)))
+	require.False(t, locks[1].ExpireTs.After(time.Now().Add(getTaskLockTtl()/2)))
+}
+
+func TestReleaseEndedTaskLocks(t *testing.T) {
+	setupTaskLockTest(t)
+	running := addTaskLockTestTask(t, constants.TaskStatusRunning)
+	ended := addTaskLockTestTask(t, constants.TaskStatusFinished)
+	missing := primitive.NewObjectID()
+
+	for key, id := range map[string]primitive.ObjectID{"running": running, "ended": ended, "missing": missing} {
+		ok, err := AcquireTaskLock(key, id, "node-1")
+		require.Nil(t, err)
+		require.True(t, ok)
+	}
+
+	// locks of ended or missing tasks released
+	require.Nil(t, ReleaseEndedTaskLocks())
+	var locks []taskLock
+	require.Nil(t, mongo.GetMongoCol(constants.TaskLockColName).Find(nil, nil).All(&locks))
+	require.Len(t, locks, 1)
+	require.Equal(t, "running", locks[0].Key)
+}
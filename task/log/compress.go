@@ -0,0 +1,158 @@
+package log
+
+import (
+	"compress/gzip"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"time"
+)
+
+// Log files are kept in two tiers if "log.compress.enabled" is set: recent
+// logs are stored raw, and logs not written for "log.compress.after" (see
+// DefaultLogCompressAfter) are compressed with gzip by the cleanup of the
+// file log driver. Reading, counting and searching decompress compressed logs
+// on the fly, and lines written to a compressed log are appended as new gzip
+// members, which readers decompress as a single stream.
+
+// compressedLogFileExt is the extension of compressed log files
+const compressedLogFileExt = ".gz"
+
+func isLogCompressEnabled() (ok bool) {
+	return viper.GetBool("log.compress.enabled")
+}
+
+func getLogCompressAfter() (d time.Duration) {
+	return parseLogDuration(viper.GetString("log.compress.after"), DefaultLogCompressAfter)
+}
+
+func (d *FileLogDriver) getCompressedLogFilePath(id string) (filePath string) {
+	return d.getLogFilePath(id, d.logFileName+compressedLogFileExt)
+}
+
+// openLogFile opens the log file of the task for reading, which is
+// decompressed if compressed. It returns nil if the task has no log file.
+func (d *FileLogDriver) openLogFile(id string) (r io.ReadCloser, err error) {
+	if filePath := d.getCompressedLogFilePath(id); utils.Exists(filePath) {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, trace.TraceError(err)
+		}
+		return &gzipFileReader{Reader: gr, f: f}, nil
+	}
+	if filePath := d.getLogFilePath(id, d.logFileName); utils.Exists(filePath) {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// appendCompressed appends the text to the compressed log file of the task as
+// a new gzip member. The caller must hold the lock of the driver.
+func (d *FileLogDriver) appendCompressed(id string, text string) (err error) {
+	f, err := os.OpenFile(d.getCompressedLogFilePath(id), os.O_WRONLY|os.O_APPEND, os.FileMode(0760))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	if _, err := gw.Write([]byte(text)); err != nil {
+		return trace.TraceError(err)
+	}
+	if err := gw.Close(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// compress compresses the raw log file of the task if it has not been written
+// for the duration. Modification times of the log file and the log directory
+// are kept, so that the ttl of logs is not affected.
+func (d *FileLogDriver) compress(id string, after time.Duration) (err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	filePath := d.getLogFilePath(id, d.logFileName)
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		// not exists or already compressed
+		return nil
+	}
+	if time.Now().Before(fi.ModTime().Add(after)) {
+		return nil
+	}
+	dirInfo, err := os.Stat(d.getBasePath(id))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+
+	// compress into a temp file first, which is renamed when done
+	gzPath := d.getCompressedLogFilePath(id)
+	tmpPath := gzPath + ".tmp"
+	if err := d.compressFile(filePath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chtimes(tmpPath, fi.ModTime(), fi.ModTime()); err != nil {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+	if err := os.Rename(tmpPath, gzPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return trace.TraceError(err)
+	}
+	if err := os.Remove(filePath); err != nil {
+		return trace.TraceError(err)
+	}
+	_ = os.Chtimes(d.getBasePath(id), dirInfo.ModTime(), dirInfo.ModTime())
+
+	log.Debugf("compressed log file: %s", gzPath)
+	return nil
+}
+
+func (d *FileLogDriver) compressFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0760))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		_ = out.Close()
+		return trace.TraceError(err)
+	}
+	if err := gw.Close(); err != nil {
+		_ = out.Close()
+		return trace.TraceError(err)
+	}
+	if err := out.Close(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// gzipFileReader closes the file along with the gzip reader
+type gzipFileReader struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (r *gzipFileReader) Close() (err error) {
+	_ = r.Reader.Close()
+	return r.f.Close()
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseLogDuration(t *testing.T) {
+	def := 7 * 24 * time.Hour
+	require.Equal(t, 30*time.Second, parseLogDuration("30s", def))
+	require.Equal(t, 5*time.Minute, parseLogDuration("5m", def))
+	require.Equal(t, 2*time.Hour, parseLogDuration("2h", def))
+	require.Equal(t, 3*24*time.Hour, parseLogDuration("3d", def))
+
+	for _, s := range []string{"", "30", "d", "1w", "ad", "1.5h"} {
+		require.Equal(t, def, parseLogDuration(s, def), s)
+	}
+}
+
+func TestFileDriver_Compress(t *testing.T) {
+	setupFileDriverTest()
+	t.Cleanup(cleanupFileDriverTest)
+
+	driver, err := newFileLogDriver()
+	require.Nil(t, err)
+	defer driver.Close()
+	d := driver.(*FileLogDriver)
+
+	id := primitive.NewObjectID().Hex()
+	require.Nil(t, d.WriteLines(id, []string{"line 1", "line 2"}))
+	rawPath := d.getLogFilePath(id, d.logFileName)
+	gzPath := d.getCompressedLogFilePath(id)
+	t.Cleanup(func() { _ = os.RemoveAll(d.getBasePath(id)) })
+
+	// recently written logs are kept raw
+	require.Nil(t, d.compress(id, time.Hour))
+	require.FileExists(t, rawPath)
+	require.NoFileExists(t, gzPath)
+
+	// logs not written for the duration are compressed, keeping the
+	// modification time
+	modTime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	require.Nil(t, os.Chtimes(rawPath, modTime, modTime))
+	require.Nil(t, d.compress(id, time.Hour))
+	require.NoFileExists(t, rawPath)
+	require.FileExists(t, gzPath)
+	fi, err := os.Stat(gzPath)
+	require.Nil(t, err)
+	require.True(t, fi.ModTime().Equal(modTime))
+
+	// compressed logs are read on the fly
+	lines, err := d.Find(id, "", 0, 10)
+	require.Nil(t, err)
+	require.Equal(t, []string{"line 1", "line 2"}, lines)
+
+	// lines written to compressed logs are appended as new gzip members,
+	// which are read as a single stream
+	require.Nil(t, d.WriteLine(id, "line 3"))
+	require.NoFileExists(t, rawPath)
+	n, err := d.Count(id, "")
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	lines, err = d.Find(id, "", 1, 10)
+	require.Nil(t, err)
+	require.Equal(t, []string{"line 2", "line 3"}, lines)
+
+	// the first member is the compressed raw log
+	f, err := os.Open(gzPath)
+	require.Nil(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.Nil(t, err)
+	gr.Multistream(false)
+	data, err := ioutil.ReadAll(gr)
+	require.Nil(t, err)
+	require.Equal(t, "line 1\nline 2\n", string(data))
+
+	// already compressed
+	require.Nil(t, d.compress(id, 0))
+}
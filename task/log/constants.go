@@ -1,7 +1,8 @@
 package log
 
 const (
-	MetadataName = "metadata.json"
+	MetadataName  = "metadata.json"
+	LineIndexName = "index.txt"
 )
 
 const (
@@ -10,3 +11,7 @@ const (
 	DriverTypeMongo = "mongo" // mongodb
 	DriverTypeEs    = "es"    // elastic search
 )
+
+const (
+	MongoLogColName = "task_logs"
+)
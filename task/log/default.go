@@ -3,3 +3,7 @@ package log
 import "time"
 
 var DefaultLogTtl = 30 * 24 * time.Hour
+
+// DefaultLogCompressAfter is how long logs are kept uncompressed since last
+// written if "log.compress.after" is not set
+var DefaultLogCompressAfter = 24 * time.Hour
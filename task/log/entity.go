@@ -3,9 +3,22 @@ package log
 import "time"
 
 type Message struct {
-	Id  int64     `json:"id" bson:"id"`
-	Msg string    `json:"msg" bson:"msg"`
-	Ts  time.Time `json:"ts" bson:"ts"`
+	Id    int64     `json:"id" bson:"id"`
+	Msg   string    `json:"msg" bson:"msg"`
+	Level string    `json:"level,omitempty" bson:"level,omitempty"`
+	Ts    time.Time `json:"ts" bson:"ts"`
+}
+
+// Query is the filter of log messages of a task
+type Query struct {
+	Id      string    // task id
+	Level   string    // log level, empty for all
+	StartTs time.Time // start time (inclusive), zero for unbounded
+	EndTs   time.Time // end time (exclusive), zero for unbounded
+	Pattern string    // substring to match, or regular expression if Regex is true
+	Regex   bool      // whether Pattern is a regular expression
+	Skip    int
+	Limit   int
 }
 
 type Metadata struct {
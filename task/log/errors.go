@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrInvalidType    = errors.New("invalid type")
 	ErrNotImplemented = errors.New("not implemented")
+	ErrNotSupported   = errors.New("not supported by log driver")
 )
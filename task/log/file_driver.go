@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"errors"
 	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/paths"
 	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/go-trace"
 	"github.com/spf13/viper"
@@ -37,10 +38,44 @@ func (d *FileLogDriver) Close() (err error) {
 }
 
 func (d *FileLogDriver) WriteLine(id string, line string) (err error) {
+	return d.WriteLinesWithLevels(id, []string{line}, nil)
+}
+
+func (d *FileLogDriver) WriteLines(id string, lines []string) (err error) {
+	return d.WriteLinesWithLevels(id, lines, nil)
+}
+
+// WriteLinesWithLevels writes lines to the log file of the task, and their
+// levels and time to the line index of the task, by which Query filters lines
+func (d *FileLogDriver) WriteLinesWithLevels(id string, lines []string, levels []string) (err error) {
+	if len(lines) == 0 {
+		// an empty line as written by earlier versions
+		lines = []string{""}
+	}
+
 	d.initDir(id)
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
+
+	pad, err := d.getLineIndexPadding(id)
+	if err != nil {
+		return err
+	}
+	if err := d.appendLog(id, strings.Join(lines, "\n")+"\n"); err != nil {
+		return err
+	}
+	return d.appendLineIndex(id, pad, lines, levels, time.Now())
+}
+
+// appendLog appends the text to the log file of the task. The caller must
+// hold the lock of the driver.
+func (d *FileLogDriver) appendLog(id string, text string) (err error) {
+	// append to the compressed log if it has been compressed
+	if utils.Exists(d.getCompressedLogFilePath(id)) {
+		return d.appendCompressed(id, text)
+	}
+
 	filePath := d.getLogFilePath(id, d.logFileName)
 
 	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0760))
@@ -54,7 +89,7 @@ func (d *FileLogDriver) WriteLine(id string, line string) (err error) {
 		}
 	}(f)
 
-	_, err = f.WriteString(line + "\n")
+	_, err = f.WriteString(text)
 	if err != nil {
 		return trace.TraceError(err)
 	}
@@ -62,25 +97,17 @@ func (d *FileLogDriver) WriteLine(id string, line string) (err error) {
 	return nil
 }
 
-func (d *FileLogDriver) WriteLines(id string, lines []string) (err error) {
-	linesString := strings.Join(lines, "\n")
-	if err := d.WriteLine(id, linesString); err != nil {
-		return err
-	}
-	return nil
-}
-
 func (d *FileLogDriver) Find(id string, pattern string, skip int, limit int) (lines []string, err error) {
 	if pattern != "" {
 		return lines, errors.New("not implemented")
 	}
-	if !utils.Exists(d.getLogFilePath(id, d.logFileName)) {
-		return nil, nil
-	}
 
-	f, err := os.Open(d.getLogFilePath(id, d.logFileName))
+	f, err := d.openLogFile(id)
 	if err != nil {
-		return nil, trace.TraceError(err)
+		return nil, err
+	}
+	if f == nil {
+		return nil, nil
 	}
 	defer f.Close()
 
@@ -114,15 +141,120 @@ func (d *FileLogDriver) Count(id string, pattern string) (n int, err error) {
 	if pattern != "" {
 		return n, errors.New("not implemented")
 	}
-	if !utils.Exists(d.getLogFilePath(id, d.logFileName)) {
+
+	f, err := d.openLogFile(id)
+	if err != nil {
+		return n, err
+	}
+	if f == nil {
 		return 0, nil
 	}
+	defer f.Close()
+	return d.lineCounter(f)
+}
+
+// Query scans the log file of the task along with its line index, by which
+// lines are filtered by level and time range. Lines written by earlier
+// versions have no level or time, which are excluded by these filters.
+func (d *FileLogDriver) Query(q *Query) (messages []Message, total int, err error) {
+	match, err := newPatternMatcher(q)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	f, err := os.Open(d.getLogFilePath(id, d.logFileName))
+	f, err := d.openLogFile(q.Id)
 	if err != nil {
-		return n, trace.TraceError(err)
+		return nil, 0, err
 	}
-	return d.lineCounter(f)
+	if f == nil {
+		return nil, 0, nil
+	}
+	defer f.Close()
+
+	var index *bufio.Reader
+	if fi, err := os.Open(d.getLineIndexPath(q.Id)); err == nil {
+		defer fi.Close()
+		index = bufio.NewReader(fi)
+	}
+
+	sc := bufio.NewReaderSize(f, 1024*1024*10)
+
+	var i int64 = -1
+	for {
+		line, err := sc.ReadString(byte('\n'))
+		if err != nil {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		i++
+
+		var level string
+		var ts time.Time
+		if index != nil {
+			level, ts = readLineIndexEntry(index)
+		}
+
+		if q.Level != "" && level != q.Level {
+			continue
+		}
+		if !q.StartTs.IsZero() || !q.EndTs.IsZero() {
+			if ts.IsZero() ||
+				(!q.StartTs.IsZero() && ts.Before(q.StartTs)) ||
+				(!q.EndTs.IsZero() && !ts.Before(q.EndTs)) {
+				continue
+			}
+		}
+		if !match(line) {
+			continue
+		}
+
+		total++
+
+		if total <= q.Skip || (q.Limit > 0 && total > q.Skip+q.Limit) {
+			continue
+		}
+
+		messages = append(messages, Message{
+			Id:    i,
+			Msg:   line,
+			Level: level,
+			Ts:    ts,
+		})
+	}
+
+	return messages, total, nil
+}
+
+// Stream copies the log file of the task to w, skipping the first skip lines
+func (d *FileLogDriver) Stream(id string, skip int, w io.Writer) (err error) {
+	f, err := d.openLogFile(id)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return nil
+	}
+	defer f.Close()
+
+	sc := bufio.NewReaderSize(f, 1024*1024)
+	for skipped := 0; skipped < skip; {
+		_, err := sc.ReadSlice('\n')
+		switch err {
+		case nil:
+			skipped++
+		case bufio.ErrBufferFull:
+			// the rest of a long line is read next
+		case io.EOF:
+			return nil
+		default:
+			return trace.TraceError(err)
+		}
+	}
+	if _, err := io.Copy(w, sc); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
 }
 
 func (d *FileLogDriver) Flush() (err error) {
@@ -130,7 +262,7 @@ func (d *FileLogDriver) Flush() (err error) {
 }
 
 func (d *FileLogDriver) getLogPath() (logPath string) {
-	return viper.GetString("log.path")
+	return paths.GetLogPath()
 }
 
 func (d *FileLogDriver) getBasePath(id string) (filePath string) {
@@ -145,6 +277,78 @@ func (d *FileLogDriver) getLogFilePath(id, fileName string) (filePath string) {
 	return filepath.Join(d.getBasePath(id), fileName)
 }
 
+func (d *FileLogDriver) getLineIndexPath(id string) (filePath string) {
+	return filepath.Join(d.getBasePath(id), LineIndexName)
+}
+
+// getLineIndexPadding returns the number of lines written to the log file of
+// the task before its line index is created, i.e. by earlier versions, which
+// are padded with empty entries to keep the index aligned with the log file
+func (d *FileLogDriver) getLineIndexPadding(id string) (n int, err error) {
+	if utils.Exists(d.getLineIndexPath(id)) {
+		return 0, nil
+	}
+	f, err := d.openLogFile(id)
+	if err != nil {
+		return 0, err
+	}
+	if f == nil {
+		return 0, nil
+	}
+	defer f.Close()
+	return d.lineCounter(f)
+}
+
+// appendLineIndex appends an entry "<ts>\t<level>" of each line of the log
+// file to the line index of the task, after pad empty entries. The caller
+// must hold the lock of the driver.
+func (d *FileLogDriver) appendLineIndex(id string, pad int, lines []string, levels []string, ts time.Time) (err error) {
+	var sb strings.Builder
+	for i := 0; i < pad; i++ {
+		sb.WriteString("0\t\n")
+	}
+	tsStr := strconv.FormatInt(ts.UnixMilli(), 10)
+	for i, line := range lines {
+		var level string
+		if i < len(levels) {
+			level = lineIndexLevelReplacer.Replace(levels[i])
+		}
+		// lines containing line breaks take multiple lines of the log file
+		for j := 0; j <= strings.Count(line, "\n"); j++ {
+			sb.WriteString(tsStr + "\t" + level + "\n")
+		}
+	}
+
+	f, err := os.OpenFile(d.getLineIndexPath(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0760))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(sb.String()); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+var lineIndexLevelReplacer = strings.NewReplacer("\t", "", "\n", "")
+
+// readLineIndexEntry reads the level and time of the next line from the line
+// index, which are empty if the line has no entry
+func readLineIndexEntry(r *bufio.Reader) (level string, ts time.Time) {
+	entry, err := r.ReadString('\n')
+	if err != nil {
+		return "", ts
+	}
+	parts := strings.SplitN(strings.TrimSuffix(entry, "\n"), "\t", 2)
+	if len(parts) < 2 {
+		return "", ts
+	}
+	if ms, err := strconv.ParseInt(parts[0], 10, 64); err == nil && ms > 0 {
+		ts = time.UnixMilli(ms)
+	}
+	return parts[1], ts
+}
+
 func (d *FileLogDriver) getLogFiles(id string) (files []os.FileInfo) {
 	// 增加了对返回异常的捕获
 	files, err := utils.ListDir(d.getBasePath(id))
@@ -182,44 +386,40 @@ func (d *FileLogDriver) lineCounter(r io.Reader) (n int, err error) {
 	}
 }
 
-func (d *FileLogDriver) getTtl() time.Duration {
-	ttl := viper.GetString("log.ttl")
-	if ttl == "" {
-		return DefaultLogTtl
-	}
+// getTtl returns the log ttl of the task, i.e. of its spider if overridden
+func (d *FileLogDriver) getTtl(id string) time.Duration {
+	return getTaskLogTtl(id)
+}
 
-	if strings.HasSuffix(ttl, "s") {
-		ttl = strings.TrimSuffix(ttl, "s")
-		n, err := strconv.Atoi(ttl)
-		if err != nil {
-			return DefaultLogTtl
-		}
-		return time.Duration(n) * time.Second
-	} else if strings.HasSuffix(ttl, "m") {
-		ttl = strings.TrimSuffix(ttl, "m")
-		n, err := strconv.Atoi(ttl)
-		if err != nil {
-			return DefaultLogTtl
-		}
-		return time.Duration(n) * time.Minute
-	} else if strings.HasSuffix(ttl, "h") {
-		ttl = strings.TrimSuffix(ttl, "h")
-		n, err := strconv.Atoi(ttl)
-		if err != nil {
-			return DefaultLogTtl
-		}
-		return time.Duration(n) * time.Hour
+func getLogTtl() time.Duration {
+	return parseLogDuration(viper.GetString("log.ttl"), DefaultLogTtl)
+}
 
-	} else if strings.HasSuffix(ttl, "d") {
-		ttl = strings.TrimSuffix(ttl, "d")
-		n, err := strconv.Atoi(ttl)
-		if err != nil {
-			return DefaultLogTtl
-		}
-		return time.Duration(n) * 24 * time.Hour
-	} else {
-		return DefaultLogTtl
+// parseLogDuration parses durations of logs in seconds, minutes, hours or
+// days, e.g. "30d", returning def if empty or invalid
+func parseLogDuration(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
 	}
+
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "s"):
+		unit = time.Second
+	case strings.HasSuffix(s, "m"):
+		unit = time.Minute
+	case strings.HasSuffix(s, "h"):
+		unit = time.Hour
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	default:
+		return def
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil {
+		return def
+	}
+	return time.Duration(n) * unit
 }
 
 func (d *FileLogDriver) cleanup() {
@@ -232,12 +432,20 @@ func (d *FileLogDriver) cleanup() {
 			continue
 		}
 		for _, dir := range dirs {
-			if time.Now().After(dir.ModTime().Add(d.getTtl())) {
+			if time.Now().After(dir.ModTime().Add(d.getTtl(dir.Name()))) {
 				if err := os.RemoveAll(d.getBasePath(dir.Name())); err != nil {
 					trace.PrintError(err)
 					continue
 				}
 				log.Infof("removed outdated log directory: %s", d.getBasePath(dir.Name()))
+				continue
+			}
+
+			// compress logs not written for a while
+			if isLogCompressEnabled() {
+				if err := d.compress(dir.Name(), getLogCompressAfter()); err != nil {
+					trace.PrintError(err)
+				}
 			}
 		}
 
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func setupFileDriverTest() {
@@ -23,7 +24,7 @@ func TestFileDriver_WriteLine(t *testing.T) {
 	setupFileDriverTest()
 	t.Cleanup(cleanupFileDriverTest)
 
-	d, err := newFileLogDriver(nil)
+	d, err := newFileLogDriver()
 	require.Nil(t, err)
 	defer d.Close()
 
@@ -43,7 +44,7 @@ func TestFileDriver_WriteLines(t *testing.T) {
 	setupFileDriverTest()
 	t.Cleanup(cleanupFileDriverTest)
 
-	d, err := newFileLogDriver(nil)
+	d, err := newFileLogDriver()
 	require.Nil(t, err)
 	defer d.Close()
 
@@ -67,7 +68,7 @@ func TestFileDriver_Find(t *testing.T) {
 	setupFileDriverTest()
 	t.Cleanup(cleanupFileDriverTest)
 
-	d, err := newFileLogDriver(nil)
+	d, err := newFileLogDriver()
 	require.Nil(t, err)
 	defer d.Close()
 
@@ -131,3 +132,54 @@ func TestFileDriver_Find(t *testing.T) {
 
 	cleanupFileDriverTest()
 }
+
+func TestFileDriver_Query(t *testing.T) {
+	setupFileDriverTest()
+	t.Cleanup(cleanupFileDriverTest)
+
+	d, err := newFileLogDriver()
+	require.Nil(t, err)
+	defer d.Close()
+
+	id := primitive.NewObjectID()
+
+	// lines written by earlier versions have no entries in the line index
+	require.Nil(t, os.MkdirAll(fmt.Sprintf("/var/log/crawlab/%s", id.Hex()), os.ModePerm))
+	require.Nil(t, ioutil.WriteFile(fmt.Sprintf("/var/log/crawlab/%s/log.txt", id.Hex()), []byte("old 1\nold 2\n"), os.ModePerm))
+
+	start := time.Now()
+	require.Nil(t, d.WriteLinesWithLevels(id.Hex(), []string{"info 1", "error 1\nerror 2", "info 2"}, []string{"info", "error", "info"}))
+	require.Nil(t, d.WriteLines(id.Hex(), []string{"plain"}))
+
+	// all lines
+	messages, total, err := d.Query(&Query{Id: id.Hex()})
+	require.Nil(t, err)
+	require.Equal(t, 7, total)
+	require.Equal(t, "old 1", messages[0].Msg)
+	require.True(t, messages[0].Ts.IsZero())
+	require.Equal(t, "info", messages[2].Level)
+	require.False(t, messages[2].Ts.IsZero())
+
+	// by level, with lines containing line breaks
+	messages, total, err = d.Query(&Query{Id: id.Hex(), Level: "error"})
+	require.Nil(t, err)
+	require.Equal(t, 2, total)
+	require.Equal(t, "error 1", messages[0].Msg)
+	require.Equal(t, int64(3), messages[0].Id)
+	require.Equal(t, "error 2", messages[1].Msg)
+
+	// by level and pattern
+	messages, total, err = d.Query(&Query{Id: id.Hex(), Level: "info", Pattern: "2"})
+	require.Nil(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "info 2", messages[0].Msg)
+
+	// by time range, which excludes lines without time
+	messages, total, err = d.Query(&Query{Id: id.Hex(), StartTs: start.Add(-time.Second)})
+	require.Nil(t, err)
+	require.Equal(t, 5, total)
+	require.Equal(t, "info 1", messages[0].Msg)
+	_, total, err = d.Query(&Query{Id: id.Hex(), EndTs: start.Add(-time.Second)})
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+}
@@ -1,10 +1,24 @@
 package log
 
+import "io"
+
 type Driver interface {
 	Init() (err error)
 	Close() (err error)
 	WriteLine(id string, line string) (err error)
 	WriteLines(id string, lines []string) (err error)
+	WriteLinesWithLevels(id string, lines []string, levels []string) (err error)
 	Find(id string, pattern string, skip int, limit int) (lines []string, err error)
 	Count(id string, pattern string) (n int, err error)
+	Query(q *Query) (messages []Message, total int, err error)
+	// Stream writes all lines of the task to w from the line of index skip
+	// without loading them into memory
+	Stream(id string, skip int, w io.Writer) (err error)
+}
+
+// DriverWithRelease is implemented by drivers keeping state of tasks for
+// writing their logs, which is released by Release when the task ends
+type DriverWithRelease interface {
+	Driver
+	Release(id string)
 }
@@ -0,0 +1,297 @@
+package log
+
+import (
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// MongoLogDriver stores each log line as a document in MongoLogColName,
+// which makes it possible to query logs by level, time range and pattern.
+// Lines expire at their expiry time, which is set by the log ttl of the task
+// when they are written.
+type MongoLogDriver struct {
+	// internals
+	mu   sync.Mutex
+	seqs map[string]int64         // next line number of tasks
+	ttls map[string]time.Duration // log ttl of tasks
+}
+
+type mongoLogLine struct {
+	TaskId string    `bson:"tid"`
+	Id     int64     `bson:"i"`
+	Msg    string    `bson:"msg"`
+	Level  string    `bson:"level,omitempty"`
+	Ts     time.Time `bson:"ts"`
+	Exp    time.Time `bson:"exp"`
+}
+
+func (d *MongoLogDriver) Init() (err error) {
+	// the ttl index on ts of earlier versions expires lines by the global log
+	// ttl, which is replaced by the ttl index on exp
+	d.dropIndex("ts_1")
+	d.getCol().MustCreateIndexes([]mongo2.IndexModel{
+		{Keys: bson.D{{"tid", 1}, {"i", 1}}},
+		{Keys: bson.D{{"tid", 1}, {"level", 1}, {"i", 1}}},
+		{Keys: bson.D{{"tid", 1}, {"ts", 1}}},
+		{Keys: bson.M{"exp": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return d.backfillExp()
+}
+
+func (d *MongoLogDriver) Close() (err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seqs = map[string]int64{}
+	d.ttls = map[string]time.Duration{}
+	return nil
+}
+
+// Release drops the line number and the log ttl of the task kept for writing,
+// which are loaded again if the task is written afterwards
+func (d *MongoLogDriver) Release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seqs, id)
+	delete(d.ttls, id)
+}
+
+func (d *MongoLogDriver) WriteLine(id string, line string) (err error) {
+	return d.WriteLines(id, []string{line})
+}
+
+func (d *MongoLogDriver) WriteLines(id string, lines []string) (err error) {
+	return d.WriteLinesWithLevels(id, lines, nil)
+}
+
+func (d *MongoLogDriver) WriteLinesWithLevels(id string, lines []string, levels []string) (err error) {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seq, err := d.getSeq(id)
+	if err != nil {
+		return err
+	}
+
+	ts := time.Now()
+	exp := ts.Add(d.getTtl(id))
+	var docs []interface{}
+	for i, line := range lines {
+		var level string
+		if i < len(levels) {
+			level = levels[i]
+		}
+		docs = append(docs, mongoLogLine{
+			TaskId: id,
+			Id:     seq,
+			Msg:    line,
+			Level:  level,
+			Ts:     ts,
+			Exp:    exp,
+		})
+		seq++
+	}
+	if _, err := d.getCol().InsertMany(docs); err != nil {
+		return trace.TraceError(err)
+	}
+	d.seqs[id] = seq
+
+	return nil
+}
+
+func (d *MongoLogDriver) Find(id string, pattern string, skip int, limit int) (lines []string, err error) {
+	messages, _, err := d.Query(&Query{Id: id, Pattern: pattern, Regex: pattern != "", Skip: skip, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		lines = append(lines, msg.Msg)
+	}
+	return lines, nil
+}
+
+func (d *MongoLogDriver) Count(id string, pattern string) (n int, err error) {
+	query, err := d.getQuery(&Query{Id: id, Pattern: pattern, Regex: pattern != ""})
+	if err != nil {
+		return 0, err
+	}
+	return d.getCol().Count(query)
+}
+
+func (d *MongoLogDriver) Query(q *Query) (messages []Message, total int, err error) {
+	query, err := d.getQuery(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err = d.getCol().Count(query)
+	if err != nil {
+		return nil, 0, trace.TraceError(err)
+	}
+	var lines []mongoLogLine
+	if err := d.getCol().Find(query, &mongo.FindOptions{
+		Sort:  bson.D{{"i", 1}},
+		Skip:  q.Skip,
+		Limit: q.Limit,
+	}).All(&lines); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, total, nil
+		}
+		return nil, 0, trace.TraceError(err)
+	}
+	for _, l := range lines {
+		messages = append(messages, Message{
+			Id:    l.Id,
+			Msg:   l.Msg,
+			Level: l.Level,
+			Ts:    l.Ts,
+		})
+	}
+	return messages, total, nil
+}
+
+// Stream writes lines of the task to w in order through a cursor, starting
+// from the line of index skip
+func (d *MongoLogDriver) Stream(id string, skip int, w io.Writer) (err error) {
+	col := d.getCol()
+	cur, err := col.GetCollection().Find(col.GetContext(), bson.M{
+		"tid": id,
+		"i":   bson.M{"$gte": skip},
+	}, options.Find().SetSort(bson.D{{"i", 1}}))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer cur.Close(col.GetContext())
+	for cur.Next(col.GetContext()) {
+		var l mongoLogLine
+		if err := cur.Decode(&l); err != nil {
+			return trace.TraceError(err)
+		}
+		if _, err := io.WriteString(w, l.Msg+"\n"); err != nil {
+			return trace.TraceError(err)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func (d *MongoLogDriver) getQuery(q *Query) (query bson.M, err error) {
+	query = bson.M{"tid": q.Id}
+	if q.Level != "" {
+		query["level"] = q.Level
+	}
+	if !q.StartTs.IsZero() || !q.EndTs.IsZero() {
+		ts := bson.M{}
+		if !q.StartTs.IsZero() {
+			ts["$gte"] = q.StartTs
+		}
+		if !q.EndTs.IsZero() {
+			ts["$lt"] = q.EndTs
+		}
+		query["ts"] = ts
+	}
+	if q.Pattern != "" {
+		pattern := q.Pattern
+		if q.Regex {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		} else {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		query["msg"] = bson.M{"$regex": pattern}
+	}
+	return query, nil
+}
+
+// getSeq returns the next line number of the task, which is loaded from
+// the last stored line when the task is written for the first time
+func (d *MongoLogDriver) getSeq(id string) (seq int64, err error) {
+	if seq, ok := d.seqs[id]; ok {
+		return seq, nil
+	}
+	var last mongoLogLine
+	if err := d.getCol().Find(bson.M{"tid": id}, &mongo.FindOptions{
+		Sort:  bson.D{{"i", -1}},
+		Limit: 1,
+	}).One(&last); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, trace.TraceError(err)
+	}
+	return last.Id + 1, nil
+}
+
+// getTtl returns the log ttl of the task, i.e. of its spider if overridden,
+// which is resolved when the task is written for the first time
+func (d *MongoLogDriver) getTtl(id string) (ttl time.Duration) {
+	if ttl, ok := d.ttls[id]; ok {
+		return ttl
+	}
+	ttl = getTaskLogTtl(id)
+	d.ttls[id] = ttl
+	return ttl
+}
+
+// backfillExp sets the expiry time of lines written by earlier versions by
+// the global log ttl
+func (d *MongoLogDriver) backfillExp() (err error) {
+	col := d.getCol()
+	if _, err := col.GetCollection().UpdateMany(col.GetContext(), bson.M{
+		"exp": bson.M{"$exists": false},
+	}, mongo2.Pipeline{
+		{{"$set", bson.M{"exp": bson.M{"$add": bson.A{"$ts", getLogTtl().Milliseconds()}}}}},
+	}); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// dropIndex drops the index by name if it exists
+func (d *MongoLogDriver) dropIndex(name string) {
+	col := d.getCol()
+	if _, err := col.GetCollection().Indexes().DropOne(col.GetContext(), name); err != nil {
+		if e, ok := err.(mongo2.CommandError); ok && (e.Name == "IndexNotFound" || e.Name == "NamespaceNotFound") {
+			return
+		}
+		trace.PrintError(err)
+	}
+}
+
+func (d *MongoLogDriver) getCol() (col *mongo.Col) {
+	return mongo.GetMongoCol(MongoLogColName)
+}
+
+var mongoLogDriver Driver
+var mongoLogDriverErr error
+var mongoLogDriverOnce sync.Once
+
+func GetMongoLogDriver() (driver Driver, err error) {
+	mongoLogDriverOnce.Do(func() {
+		d := &MongoLogDriver{
+			seqs: map[string]int64{},
+			ttls: map[string]time.Duration{},
+		}
+		if err := d.Init(); err != nil {
+			mongoLogDriverErr = err
+			return
+		}
+		mongoLogDriver = d
+	})
+	if mongoLogDriverErr != nil {
+		return nil, mongoLogDriverErr
+	}
+	return mongoLogDriver, nil
+}
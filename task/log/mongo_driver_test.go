@@ -0,0 +1,26 @@
+package log
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMongoLogDriver_Release(t *testing.T) {
+	d := &MongoLogDriver{
+		seqs: map[string]int64{"a": 10, "b": 20},
+		ttls: map[string]time.Duration{"a": time.Hour, "b": time.Hour},
+	}
+
+	// released when the task ends
+	d.Release("a")
+	require.NotContains(t, d.seqs, "a")
+	require.NotContains(t, d.ttls, "a")
+	require.Contains(t, d.seqs, "b")
+	require.Contains(t, d.ttls, "b")
+
+	// released on close
+	require.Nil(t, d.Close())
+	require.Empty(t, d.seqs)
+	require.Empty(t, d.ttls)
+}
@@ -0,0 +1,23 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+)
+
+// newPatternMatcher returns a function matching lines against the pattern of the query
+func newPatternMatcher(q *Query) (match func(line string) bool, err error) {
+	if q.Pattern == "" {
+		return func(line string) bool { return true }, nil
+	}
+	if q.Regex {
+		re, err := regexp.Compile(q.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	return func(line string) bool {
+		return strings.Contains(line, q.Pattern)
+	}, nil
+}
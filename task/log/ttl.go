@@ -0,0 +1,36 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TtlResolver returns the log ttl of the task, false if the task does not
+// override the global log ttl "log.ttl", e.g. its spider has no log ttl
+type TtlResolver func(id string) (ttl time.Duration, ok bool)
+
+var ttlResolver atomic.Value
+
+// SetTtlResolver sets the resolver of log ttl of tasks, which is consulted by
+// drivers when logs are purged (file) or written (mongo)
+func SetTtlResolver(r TtlResolver) {
+	ttlResolver.Store(r)
+}
+
+// ParseLogTtl parses the log ttl in seconds, minutes, hours or days, e.g.
+// "90d", returning false if it is invalid
+func ParseLogTtl(s string) (ttl time.Duration, ok bool) {
+	ttl = parseLogDuration(s, 0)
+	return ttl, ttl > 0
+}
+
+// getTaskLogTtl returns the log ttl of the task, falling back to the global
+// log ttl if it is not resolved
+func getTaskLogTtl(id string) (ttl time.Duration) {
+	if r, ok := ttlResolver.Load().(TtlResolver); ok && r != nil {
+		if ttl, ok := r(id); ok && ttl > 0 {
+			return ttl
+		}
+	}
+	return getLogTtl()
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseLogTtl(t *testing.T) {
+	ttl, ok := ParseLogTtl("90d")
+	require.True(t, ok)
+	require.Equal(t, 90*24*time.Hour, ttl)
+
+	ttl, ok = ParseLogTtl("12h")
+	require.True(t, ok)
+	require.Equal(t, 12*time.Hour, ttl)
+
+	for _, s := range []string{"", "90", "d", "-1d", "0h", "1w"} {
+		_, ok = ParseLogTtl(s)
+		require.False(t, ok, s)
+	}
+}
+
+func TestGetTaskLogTtl(t *testing.T) {
+	t.Cleanup(func() { SetTtlResolver(nil) })
+
+	// global
+	require.Equal(t, getLogTtl(), getTaskLogTtl("a"))
+
+	// overridden by the resolver
+	SetTtlResolver(func(id string) (ttl time.Duration, ok bool) {
+		if id == "a" {
+			return time.Hour, true
+		}
+		return 0, false
+	})
+	require.Equal(t, time.Hour, getTaskLogTtl("a"))
+	require.Equal(t, getLogTtl(), getTaskLogTtl("b"))
+}
@@ -0,0 +1,87 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// PauseSettings of the scheduler, stored in the setting
+// constants.SettingKeySchedulerPause. While paused, no tasks are dispatched
+// to nodes, i.e. new tasks are held pending, and running tasks are unaffected.
+type PauseSettings struct {
+	Paused   bool      `json:"paused" bson:"paused"`
+	UpdateTs time.Time `json:"update_ts,omitempty" bson:"update_ts,omitempty"`
+	UpdateBy string    `json:"update_by,omitempty" bson:"update_by,omitempty"` // username of the user pausing or resuming
+}
+
+// GetPauseSettings returns the pause state of the scheduler. The setting
+// constants.SettingKeySchedulerPause takes precedence over the config
+// "scheduler.paused".
+func GetPauseSettings() (s PauseSettings) {
+	s.Paused = viper.GetBool("scheduler.paused")
+	modelSvc, err := service.GetService()
+	if err != nil {
+		trace.PrintError(err)
+		return s
+	}
+	var s2 PauseSettings
+	ok, err := modelSvc.GetSettingValue(constants.SettingKeySchedulerPause, &s2)
+	if err != nil {
+		trace.PrintError(err)
+		return s
+	}
+	if !ok {
+		return s
+	}
+	return s2
+}
+
+// IsSchedulerPaused returns whether dispatching tasks is paused cluster-wide
+func IsSchedulerPaused() (paused bool) {
+	return GetPauseSettings().Paused
+}
+
+// SetSchedulerPaused saves the pause state of the scheduler to the setting
+// constants.SettingKeySchedulerPause, u is the user pausing or resuming
+func SetSchedulerPaused(paused bool, u interfaces.User) (s PauseSettings, err error) {
+	s = PauseSettings{
+		Paused:   paused,
+		UpdateTs: time.Now(),
+	}
+	if u != nil {
+		s.UpdateBy = u.GetUsername()
+	}
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return s, trace.TraceError(err)
+	}
+	var value bson.M
+	if err := bson.Unmarshal(data, &value); err != nil {
+		return s, trace.TraceError(err)
+	}
+
+	modelSvc, err := service.GetService()
+	if err != nil {
+		return s, err
+	}
+	setting, err := modelSvc.GetSettingByKey(constants.SettingKeySchedulerPause, nil)
+	if err == mongo2.ErrNoDocuments {
+		setting = &models.Setting{Key: constants.SettingKeySchedulerPause, Value: value}
+		defer service.ResetSettingValueCache(constants.SettingKeySchedulerPause)
+		return s, delegate.NewModelDelegate(setting, u).Add()
+	}
+	if err != nil {
+		return s, trace.TraceError(err)
+	}
+	setting.Value = value
+	defer service.ResetSettingValueCache(constants.SettingKeySchedulerPause)
+	return s, delegate.NewModelDelegate(setting, u).Save()
+}
@@ -0,0 +1,24 @@
+package plugin
+
+import "github.com/crawlab-team/crawlab-core/interfaces"
+
+// BasePlugin implements interfaces.TaskPlugin with no-op hooks so that
+// custom plugins only need to override the hooks they are interested in.
+type BasePlugin struct {
+}
+
+func (p *BasePlugin) BeforeDispatch(ctx interfaces.TaskPluginContext) (err error) {
+	return nil
+}
+
+func (p *BasePlugin) OnTaskStart(ctx interfaces.TaskPluginContext) (err error) {
+	return nil
+}
+
+func (p *BasePlugin) OnTaskFinish(ctx interfaces.TaskPluginContext) (err error) {
+	return nil
+}
+
+func (p *BasePlugin) OnResultStored(ctx interfaces.TaskPluginContext) (err error) {
+	return nil
+}
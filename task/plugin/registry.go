@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"sync"
+)
+
+type Registry struct {
+	// settings
+	strict bool
+
+	// internals
+	mu      sync.RWMutex
+	keys    []string
+	plugins map[string]interfaces.TaskPlugin
+}
+
+func (r *Registry) Register(key string, p interfaces.TaskPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.plugins[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.plugins[key] = p
+	log.Infof("[TaskPluginRegistry] registered %s", key)
+}
+
+func (r *Registry) Unregister(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.plugins[key]; !ok {
+		return
+	}
+	delete(r.plugins, key)
+	for i, k := range r.keys {
+		if k == key {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			break
+		}
+	}
+	log.Infof("[TaskPluginRegistry] unregistered %s", key)
+}
+
+func (r *Registry) Get(key string) (p interfaces.TaskPlugin) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.plugins[key]
+}
+
+// GetAll returns registered plugins in the order of registration
+func (r *Registry) GetAll() (plugins []interfaces.TaskPlugin) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		plugins = append(plugins, r.plugins[key])
+	}
+	return plugins
+}
+
+// SetStrict sets whether hook errors abort the current operation.
+// By default errors are only logged.
+func (r *Registry) SetStrict(strict bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strict = strict
+}
+
+func (r *Registry) isStrict() (strict bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.strict
+}
+
+func (r *Registry) BeforeDispatch(ctx interfaces.TaskPluginContext) (err error) {
+	return r.call(func(p interfaces.TaskPlugin) error {
+		return p.BeforeDispatch(ctx)
+	})
+}
+
+func (r *Registry) OnTaskStart(ctx interfaces.TaskPluginContext) (err error) {
+	return r.call(func(p interfaces.TaskPlugin) error {
+		return p.OnTaskStart(ctx)
+	})
+}
+
+func (r *Registry) OnTaskFinish(ctx interfaces.TaskPluginContext) (err error) {
+	return r.call(func(p interfaces.TaskPlugin) error {
+		return p.OnTaskFinish(ctx)
+	})
+}
+
+func (r *Registry) OnResultStored(ctx interfaces.TaskPluginContext) (err error) {
+	return r.call(func(p interfaces.TaskPlugin) error {
+		return p.OnResultStored(ctx)
+	})
+}
+
+func (r *Registry) call(fn func(p interfaces.TaskPlugin) error) (err error) {
+	for _, p := range r.GetAll() {
+		if err := fn(p); err != nil {
+			if r.isStrict() {
+				return trace.TraceError(err)
+			}
+			trace.PrintError(err)
+		}
+	}
+	return nil
+}
+
+func NewRegistry() (r interfaces.TaskPluginRegistry) {
+	return &Registry{
+		plugins: map[string]interfaces.TaskPlugin{},
+	}
+}
+
+var _registry interfaces.TaskPluginRegistry
+var _registryOnce sync.Once
+
+func GetRegistry() (r interfaces.TaskPluginRegistry) {
+	_registryOnce.Do(func() {
+		_registry = NewRegistry()
+		_registry.SetStrict(viper.GetBool("task.plugin.strict"))
+	})
+	return _registry
+}
@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"errors"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testPlugin struct {
+	BasePlugin
+	calls []string
+	err   error
+}
+
+func (p *testPlugin) OnTaskFinish(ctx interfaces.TaskPluginContext) (err error) {
+	p.calls = append(p.calls, ctx.GetStatus())
+	return p.err
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	p1 := &testPlugin{}
+	p2 := &testPlugin{}
+	r.Register("p1", p1)
+	r.Register("p2", p2)
+	r.Register("p1", p1)
+	require.Len(t, r.GetAll(), 2)
+	require.Equal(t, p1, r.Get("p1"))
+
+	r.Unregister("p1")
+	require.Len(t, r.GetAll(), 1)
+	require.Nil(t, r.Get("p1"))
+}
+
+func TestRegistry_OnTaskFinish(t *testing.T) {
+	r := NewRegistry()
+	p1 := &testPlugin{err: errors.New("failed")}
+	p2 := &testPlugin{}
+	r.Register("p1", p1)
+	r.Register("p2", p2)
+
+	// errors are non-fatal by default
+	err := r.OnTaskFinish(&entity.TaskPluginContext{Status: "finished"})
+	require.Nil(t, err)
+	require.Equal(t, []string{"finished"}, p1.calls)
+	require.Equal(t, []string{"finished"}, p2.calls)
+
+	// strict mode stops at the first error
+	r.SetStrict(true)
+	err = r.OnTaskFinish(&entity.TaskPluginContext{Status: "error"})
+	require.NotNil(t, err)
+	require.Len(t, p1.calls, 2)
+	require.Len(t, p2.calls, 1)
+}
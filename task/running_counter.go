@@ -0,0 +1,174 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"sync/atomic"
+)
+
+// RunningTaskCounter counts running tasks of the cluster by node and spider in
+// memory, so that scheduling decisions do not need to query the database. It
+// is kept in sync with status changes of tasks and reconciled against the
+// database periodically by the task scheduler on master.
+type RunningTaskCounter struct {
+	total   int64    // number of running tasks (atomic)
+	synced  int32    // whether reconciled at least once (atomic)
+	nodes   sync.Map // Node.Id -> *int64
+	spiders sync.Map // Spider.Id -> *int64
+	tasks   map[primitive.ObjectID]runningTaskKey
+	mu      sync.Mutex // lock of tasks and changes of counts
+}
+
+type runningTaskKey struct {
+	nodeId   primitive.ObjectID
+	spiderId primitive.ObjectID
+}
+
+// Update counts the task as running if its status is running, otherwise it
+// stops counting the task. It is idempotent.
+func (c *RunningTaskCounter) Update(t interfaces.Task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(t.GetId())
+	if t.GetStatus() == constants.TaskStatusRunning {
+		c.add(t.GetId(), runningTaskKey{nodeId: t.GetNodeId(), spiderId: t.GetSpiderId()})
+	}
+}
+
+// Remove stops counting the task, e.g. if it is deleted
+func (c *RunningTaskCounter) Remove(id primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(id)
+}
+
+// Reconcile resets the counts to the given running tasks read from the
+// database, and returns the number of tasks which were counted wrongly
+func (c *RunningTaskCounter) Reconcile(tasks []interfaces.Task) (drift int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	running := map[primitive.ObjectID]runningTaskKey{}
+	for _, t := range tasks {
+		if t.GetStatus() != constants.TaskStatusRunning {
+			continue
+		}
+		running[t.GetId()] = runningTaskKey{nodeId: t.GetNodeId(), spiderId: t.GetSpiderId()}
+	}
+	for id, key := range c.tasks {
+		if k, ok := running[id]; !ok || k != key {
+			c.remove(id)
+			drift++
+		}
+	}
+	for id, key := range running {
+		if _, ok := c.tasks[id]; !ok {
+			c.add(id, key)
+			drift++
+		}
+	}
+
+	atomic.StoreInt32(&c.synced, 1)
+
+	return drift
+}
+
+// IsSynced returns whether the counts have been reconciled against the
+// database, i.e. whether they can be relied on
+func (c *RunningTaskCounter) IsSynced() (ok bool) {
+	return atomic.LoadInt32(&c.synced) == 1
+}
+
+// IsRunning returns whether the task is counted as running
+func (c *RunningTaskCounter) IsRunning(id primitive.ObjectID) (ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok = c.tasks[id]
+	return ok
+}
+
+// Total returns the number of running tasks of the cluster
+func (c *RunningTaskCounter) Total() (n int) {
+	return int(atomic.LoadInt64(&c.total))
+}
+
+// CountByNode returns the number of running tasks of the node
+func (c *RunningTaskCounter) CountByNode(id primitive.ObjectID) (n int) {
+	return loadRunningTaskCount(&c.nodes, id)
+}
+
+// CountBySpider returns the number of running tasks of the spider
+func (c *RunningTaskCounter) CountBySpider(id primitive.ObjectID) (n int) {
+	return loadRunningTaskCount(&c.spiders, id)
+}
+
+// GetNodeCounts returns the number of running tasks by node, excluding nodes
+// without running tasks
+func (c *RunningTaskCounter) GetNodeCounts() (counts map[primitive.ObjectID]int) {
+	return rangeRunningTaskCounts(&c.nodes)
+}
+
+// GetSpiderCounts returns the number of running tasks by spider, excluding
+// spiders without running tasks
+func (c *RunningTaskCounter) GetSpiderCounts() (counts map[primitive.ObjectID]int) {
+	return rangeRunningTaskCounts(&c.spiders)
+}
+
+// add must be called with the lock held
+func (c *RunningTaskCounter) add(id primitive.ObjectID, key runningTaskKey) {
+	c.tasks[id] = key
+	atomic.AddInt64(&c.total, 1)
+	addRunningTaskCount(&c.nodes, key.nodeId, 1)
+	addRunningTaskCount(&c.spiders, key.spiderId, 1)
+}
+
+// remove must be called with the lock held
+func (c *RunningTaskCounter) remove(id primitive.ObjectID) {
+	key, ok := c.tasks[id]
+	if !ok {
+		return
+	}
+	delete(c.tasks, id)
+	atomic.AddInt64(&c.total, -1)
+	addRunningTaskCount(&c.nodes, key.nodeId, -1)
+	addRunningTaskCount(&c.spiders, key.spiderId, -1)
+}
+
+func addRunningTaskCount(m *sync.Map, id primitive.ObjectID, delta int64) {
+	v, _ := m.LoadOrStore(id, new(int64))
+	atomic.AddInt64(v.(*int64), delta)
+}
+
+func loadRunningTaskCount(m *sync.Map, id primitive.ObjectID) (n int) {
+	v, ok := m.Load(id)
+	if !ok {
+		return 0
+	}
+	return int(atomic.LoadInt64(v.(*int64)))
+}
+
+func rangeRunningTaskCounts(m *sync.Map) (counts map[primitive.ObjectID]int) {
+	counts = map[primitive.ObjectID]int{}
+	m.Range(func(key, value interface{}) bool {
+		if n := atomic.LoadInt64(value.(*int64)); n > 0 {
+			counts[key.(primitive.ObjectID)] = int(n)
+		}
+		return true
+	})
+	return counts
+}
+
+func newRunningTaskCounter() (c *RunningTaskCounter) {
+	return &RunningTaskCounter{
+		tasks: map[primitive.ObjectID]runningTaskKey{},
+	}
+}
+
+var runningTaskCounter = newRunningTaskCounter()
+
+// GetRunningTaskCounter returns the running task counter of the cluster
+func GetRunningTaskCounter() (c *RunningTaskCounter) {
+	return runningTaskCounter
+}
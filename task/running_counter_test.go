@@ -0,0 +1,59 @@
+package task
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestRunningTaskCounter_Update(t *testing.T) {
+	c := newRunningTaskCounter()
+	nodeId := primitive.NewObjectID()
+	spiderId := primitive.NewObjectID()
+	t1 := &models.Task{Id: primitive.NewObjectID(), NodeId: nodeId, SpiderId: spiderId, Status: constants.TaskStatusRunning}
+	t2 := &models.Task{Id: primitive.NewObjectID(), NodeId: nodeId, SpiderId: spiderId, Status: constants.TaskStatusRunning}
+
+	c.Update(t1)
+	c.Update(t1)
+	c.Update(t2)
+	require.Equal(t, 2, c.Total())
+	require.Equal(t, 2, c.CountByNode(nodeId))
+	require.Equal(t, 2, c.CountBySpider(spiderId))
+	require.True(t, c.IsRunning(t1.Id))
+
+	t1.Status = constants.TaskStatusFinished
+	c.Update(t1)
+	c.Update(t1)
+	require.Equal(t, 1, c.Total())
+	require.Equal(t, 1, c.CountByNode(nodeId))
+	require.False(t, c.IsRunning(t1.Id))
+
+	c.Remove(t2.Id)
+	require.Equal(t, 0, c.Total())
+	require.Equal(t, 0, c.CountBySpider(spiderId))
+	require.Empty(t, c.GetNodeCounts())
+}
+
+func TestRunningTaskCounter_Reconcile(t *testing.T) {
+	c := newRunningTaskCounter()
+	require.False(t, c.IsSynced())
+
+	nodeId := primitive.NewObjectID()
+	t1 := &models.Task{Id: primitive.NewObjectID(), NodeId: nodeId, Status: constants.TaskStatusRunning}
+	t2 := &models.Task{Id: primitive.NewObjectID(), NodeId: nodeId, Status: constants.TaskStatusRunning}
+	c.Update(t1)
+
+	// t1 has ended without its status change being counted, t2 has started
+	drift := c.Reconcile([]interfaces.Task{t2})
+	require.Equal(t, 2, drift)
+	require.True(t, c.IsSynced())
+	require.Equal(t, 1, c.Total())
+	require.False(t, c.IsRunning(t1.Id))
+	require.True(t, c.IsRunning(t2.Id))
+	require.Equal(t, map[primitive.ObjectID]int{nodeId: 1}, c.GetNodeCounts())
+
+	require.Equal(t, 0, c.Reconcile([]interfaces.Task{t2}))
+}
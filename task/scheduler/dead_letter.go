@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	errors2 "errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// deadLetterTasks flags failed tasks which have exhausted their max retries
+// as dead-lettered, so that they can be told apart from ordinary failures and
+// triaged. The last error, attempt count and node are kept on the task.
+// Tasks without retries configured are not dead-lettered.
+func (svc *Service) deadLetterTasks() {
+	query := getRetryCandidateQuery()
+	query["$expr"] = bson.M{"$gte": bson.A{"$retries", "$max_retries"}}
+	tasks, err := svc.modelSvc.GetTaskList(query, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		trace.PrintError(err)
+		return
+	}
+	for i := range tasks {
+		t := &tasks[i]
+		if err := delegate.NewModelDelegate(t).UpdateFields(bson.M{
+			"dead_letter":    true,
+			"dead_letter_ts": time.Now(),
+		}); err != nil {
+			trace.PrintError(err)
+			continue
+		}
+		log.Warnf("[TaskScheduler] task[%s] dead-lettered after %d attempts: %s", t.Id.Hex(), t.Retries+1, t.Error)
+		task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventDeadLettered, constants.TaskEventActorSystem, fmt.Sprintf("failed after %d attempts: %s", t.Retries+1, t.Error)))
+	}
+}
+
+// GetDeadLetterTasks returns dead-lettered tasks, latest first, and the total
+// count of them
+func (svc *Service) GetDeadLetterTasks(skip, limit int) (tasks []interfaces.Task, total int, err error) {
+	query := bson.M{"dead_letter": true}
+	list, err := svc.modelSvc.GetTaskList(query, &mongo.FindOptions{
+		Sort:  bson.D{{"dead_letter_ts", -1}},
+		Skip:  skip,
+		Limit: limit,
+	})
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, 0, trace.TraceError(err)
+	}
+	total, err = mongo.GetMongoCol(interfaces.ModelColNameTask).Count(query)
+	if err != nil {
+		return nil, 0, trace.TraceError(err)
+	}
+	tasks = []interfaces.Task{}
+	for i := range list {
+		tasks = append(tasks, &list[i])
+	}
+	return tasks, total, nil
+}
+
+// RequeueDeadLetterTask runs again the dead-lettered task with the config
+// recorded on it and its retries reset. The task is no longer dead-lettered
+// and links to the new task by its retry id. It returns
+// errors.ErrorTaskNotDeadLettered if the task is not dead-lettered.
+func (svc *Service) RequeueDeadLetterTask(id primitive.ObjectID, args ...interface{}) (t2 interfaces.Task, err error) {
+	t, err := svc.modelSvc.GetTaskById(id)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if !t.DeadLetter {
+		return nil, trace.TraceError(errors.ErrorTaskNotDeadLettered)
+	}
+
+	// user
+	u := utils.GetUserFromArgs(args...)
+
+	// enqueue
+	t3 := newTaskAttempt(t)
+	t3.Retries = 0
+	if t.Mode != constants.RunTypeRandom {
+		// keep the node selected by the user
+		t3.NodeId = t.NodeId
+	}
+
+	// enqueue and clear dead-letter flag in a transaction, so that the task
+	// is requeued only once
+	t2, err = svc.enqueue(t3, func(sc mongo2.SessionContext) error {
+		if err := setTaskRetryId(sc, t.Id, t3.Id, bson.M{"dead_letter": true}); err != nil {
+			if errors2.Is(err, errors.ErrorTaskAlreadyRetried) {
+				return errors.ErrorTaskNotDeadLettered
+			}
+			return err
+		}
+		col := mongo.GetMongoCol(interfaces.ModelColNameTask)
+		_, err := col.GetCollection().UpdateOne(sc, bson.M{"_id": t.Id}, bson.M{
+			"$set": bson.M{"dead_letter": false},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// task event
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventRequeued, task.GetUserActor(u), fmt.Sprintf("requeued as task %s", t2.GetId().Hex())))
+
+	return t2, nil
+}
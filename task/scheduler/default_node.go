@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/task"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// selectDefaultNodes returns the nodes among the eligible nodes matching the
+// default node, which are none if the default node is unavailable
+func selectDefaultNodes(defaultNode string, nodes []interfaces.Node) (res []interfaces.Node) {
+	for _, n := range nodes {
+		switch {
+		case defaultNode == constants.TaskDefaultNodeAnyWorker && !n.GetIsMaster(),
+			defaultNode == n.GetKey():
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// applyDefaultNode narrows the eligible nodes of the task down to the default
+// node. It returns done as true if the task has been handled without
+// selecting a node, i.e. it is to be left pending by the fallback.
+func (svc *Service) applyDefaultNode(t interfaces.Task, nodes []interfaces.Node) (res []interfaces.Node, done bool, err error) {
+	defaultNode := task.GetDefaultNode()
+	if defaultNode == "" || len(t.GetNodeTags()) > 0 || len(t.GetCapabilities()) > 0 {
+		return nodes, false, nil
+	}
+
+	// default node available
+	if res = selectDefaultNodes(defaultNode, nodes); len(res) > 0 {
+		return res, false, nil
+	}
+
+	// fallback to any eligible node
+	if task.GetDefaultNodeFallback() == constants.TaskDefaultNodeFallbackAny {
+		return nodes, false, nil
+	}
+
+	// leave pending
+	if defaultNode == constants.TaskDefaultNodeAnyWorker {
+		// unassigned, to be fetched by the first worker node asking for
+		// tasks, which master does not fetch (see task.IsMasterExcludedFromUnassignedTasks)
+		return nil, true, nil
+	}
+	n, err := svc.modelSvc.GetNodeByKey(defaultNode, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			log.Warnf("[TaskScheduler] default node[%s] not found, dispatching to any eligible node", defaultNode)
+			return nodes, false, nil
+		}
+		return nil, false, err
+	}
+	// assigned to the default node, which fetches it when available
+	t.SetNodeId(n.Id)
+	return nil, true, nil
+}
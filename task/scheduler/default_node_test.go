@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestSelectDefaultNodes(t *testing.T) {
+	nodes := []interfaces.Node{
+		&models.Node{Id: primitive.NewObjectID(), Key: "master", IsMaster: true},
+		&models.Node{Id: primitive.NewObjectID(), Key: "w1"},
+		&models.Node{Id: primitive.NewObjectID(), Key: "w2"},
+	}
+
+	// node key
+	res := selectDefaultNodes("w2", nodes)
+	require.Len(t, res, 1)
+	require.Equal(t, "w2", res[0].GetKey())
+
+	// any worker
+	res = selectDefaultNodes(constants.TaskDefaultNodeAnyWorker, nodes)
+	require.Len(t, res, 2)
+	require.Equal(t, "w1", res[0].GetKey())
+
+	// unavailable
+	require.Empty(t, selectDefaultNodes("w3", nodes))
+	require.Empty(t, selectDefaultNodes(constants.TaskDefaultNodeAnyWorker, nodes[:1]))
+}
@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RoundRobinTaskDispatchStrategy dispatches tasks to eligible nodes in turn
+type RoundRobinTaskDispatchStrategy struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (s *RoundRobinTaskDispatchStrategy) Select(t interfaces.Task, nodes []interfaces.Node) (n interfaces.Node, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n = nodes[s.next%len(nodes)]
+	s.next = (s.next + 1) % len(nodes)
+	return n, nil
+}
+
+func NewRoundRobinTaskDispatchStrategy() interfaces.TaskDispatchStrategy {
+	return &RoundRobinTaskDispatchStrategy{}
+}
+
+// LeastLoadedTaskDispatchStrategy dispatches tasks to the eligible node with the
+// lowest load, i.e. number of running and queued tasks assigned to the node
+// relative to its max runners
+type LeastLoadedTaskDispatchStrategy struct {
+	getLoads func(nodes []interfaces.Node) (loads map[primitive.ObjectID]int, err error)
+}
+
+func (s *LeastLoadedTaskDispatchStrategy) Select(t interfaces.Task, nodes []interfaces.Node) (n interfaces.Node, err error) {
+	loads, err := s.getLoads(nodes)
+	if err != nil {
+		return nil, err
+	}
+	var minRatio float64
+	for _, node := range nodes {
+		ratio := float64(loads[node.GetId()])
+		if node.GetMaxRunners() > 0 {
+			ratio /= float64(node.GetMaxRunners())
+		}
+		if n == nil || ratio < minRatio {
+			n = node
+			minRatio = ratio
+		}
+	}
+	return n, nil
+}
+
+func NewLeastLoadedTaskDispatchStrategy() interfaces.TaskDispatchStrategy {
+	return &LeastLoadedTaskDispatchStrategy{
+		getLoads: getNodeTaskLoads,
+	}
+}
+
+// getNodeTaskLoads returns the number of running tasks and queued tasks
+// assigned to each of the nodes. Running tasks are taken from the running task
+// counter if it is synced, otherwise they are counted for all nodes at once
+// like queued tasks.
+func getNodeTaskLoads(nodes []interfaces.Node) (loads map[primitive.ObjectID]int, err error) {
+	var nodeIds []primitive.ObjectID
+	for _, n := range nodes {
+		nodeIds = append(nodeIds, n.GetId())
+	}
+	loads = map[primitive.ObjectID]int{}
+	if c := task.GetRunningTaskCounter(); c.IsSynced() {
+		for _, id := range nodeIds {
+			loads[id] = c.CountByNode(id)
+		}
+	} else if err := addNodeTaskCounts(loads, interfaces.ModelColNameTask, "node_id", bson.M{
+		"node_id": bson.M{"$in": nodeIds},
+		"status":  constants.TaskStatusRunning,
+	}); err != nil {
+		return nil, err
+	}
+	if err := addNodeTaskCounts(loads, interfaces.ModelColNameTaskQueue, "nid", bson.M{
+		"nid": bson.M{"$in": nodeIds},
+	}); err != nil {
+		return nil, err
+	}
+	return loads, nil
+}
+
+// addNodeTaskCounts adds the number of documents of the collection matching
+// the query to counts, grouped by the node id field
+func addNodeTaskCounts(counts map[primitive.ObjectID]int, colName string, field string, query bson.M) (err error) {
+	pipeline := mongo2.Pipeline{
+		{{"$match", query}},
+		{{"$group", bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+	}
+	var results []struct {
+		Id    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err := mongo.GetMongoCol(colName).Aggregate(pipeline, nil).All(&results); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil
+		}
+		return trace.TraceError(err)
+	}
+	for _, res := range results {
+		counts[res.Id] += res.Count
+	}
+	return nil
+}
+
+// RandomTaskDispatchStrategy dispatches tasks to a random eligible node
+type RandomTaskDispatchStrategy struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (s *RandomTaskDispatchStrategy) Select(t interfaces.Task, nodes []interfaces.Node) (n interfaces.Node, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return nodes[s.r.Intn(len(nodes))], nil
+}
+
+func NewRandomTaskDispatchStrategy() interfaces.TaskDispatchStrategy {
+	return &RandomTaskDispatchStrategy{
+		r: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// NewTaskDispatchStrategy returns the built-in strategy of the given name,
+// least-loaded if the name is empty or unknown
+func NewTaskDispatchStrategy(name string) interfaces.TaskDispatchStrategy {
+	switch name {
+	case constants.TaskDispatchStrategyRoundRobin:
+		return NewRoundRobinTaskDispatchStrategy()
+	case constants.TaskDispatchStrategyRandom:
+		return NewRandomTaskDispatchStrategy()
+	case constants.TaskDispatchStrategyLeastLoaded, "":
+		return NewLeastLoadedTaskDispatchStrategy()
+	default:
+		log.Warnf("[TaskSchedulerService] unknown dispatch strategy %s, fallback to %s", name, constants.TaskDispatchStrategyLeastLoaded)
+		return NewLeastLoadedTaskDispatchStrategy()
+	}
+}
+
+// getDefaultTaskDispatchStrategy returns the strategy configured by "task.scheduler.dispatchStrategy"
+func getDefaultTaskDispatchStrategy() interfaces.TaskDispatchStrategy {
+	return NewTaskDispatchStrategy(viper.GetString("task.scheduler.dispatchStrategy"))
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func newTestDispatchNodes() []interfaces.Node {
+	return []interfaces.Node{
+		&models.Node{Id: primitive.NewObjectID(), Key: "n1", MaxRunners: 8},
+		&models.Node{Id: primitive.NewObjectID(), Key: "n2", MaxRunners: 8},
+		&models.Node{Id: primitive.NewObjectID(), Key: "n3", MaxRunners: 2},
+	}
+}
+
+func TestRoundRobinTaskDispatchStrategy_Select(t *testing.T) {
+	nodes := newTestDispatchNodes()
+	s := NewRoundRobinTaskDispatchStrategy()
+	for i := 0; i < 6; i++ {
+		n, err := s.Select(&models.Task{}, nodes)
+		require.Nil(t, err)
+		require.Equal(t, nodes[i%3].GetKey(), n.GetKey())
+	}
+}
+
+func TestLeastLoadedTaskDispatchStrategy_Select(t *testing.T) {
+	nodes := newTestDispatchNodes()
+	loads := map[string]int{"n1": 4, "n2": 2, "n3": 1}
+	s := &LeastLoadedTaskDispatchStrategy{
+		getLoads: func(nodes []interfaces.Node) (res map[primitive.ObjectID]int, err error) {
+			res = map[primitive.ObjectID]int{}
+			for _, n := range nodes {
+				res[n.GetId()] = loads[n.GetKey()]
+			}
+			return res, nil
+		},
+	}
+
+	// n2 has the lowest load relative to max runners (2/8 < 1/2)
+	n, err := s.Select(&models.Task{}, nodes)
+	require.Nil(t, err)
+	require.Equal(t, "n2", n.GetKey())
+
+	loads["n2"] = 8
+	n, err = s.Select(&models.Task{}, nodes)
+	require.Nil(t, err)
+	require.Equal(t, "n1", n.GetKey())
+}
+
+func TestRandomTaskDispatchStrategy_Select(t *testing.T) {
+	nodes := newTestDispatchNodes()
+	s := NewRandomTaskDispatchStrategy()
+	for i := 0; i < 10; i++ {
+		n, err := s.Select(&models.Task{}, nodes)
+		require.Nil(t, err)
+		require.Contains(t, nodes, n)
+	}
+}
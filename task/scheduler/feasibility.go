@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"strings"
+)
+
+// Tasks requiring node tags or capabilities are checked on creation against
+// their node if assigned, or else all online nodes, so that tasks no node can
+// run are reported immediately instead of being left pending. Infeasible
+// tasks are rejected, or only warned about if "task.scheduler.feasibility" is
+// constants.TaskFeasibilityWarn, e.g. if the node is to be given the missing
+// capability. Tasks not assigned to a node are rejected either way, as they
+// cannot be dispatched.
+
+func getFeasibilityMode() (mode string) {
+	if mode = viper.GetString("task.scheduler.feasibility"); mode == constants.TaskFeasibilityWarn {
+		return mode
+	}
+	return constants.TaskFeasibilityReject
+}
+
+func (svc *Service) CheckFeasibility(t interfaces.Task) (warning string, err error) {
+	if len(t.GetNodeTags()) == 0 && len(t.GetCapabilities()) == 0 {
+		return "", nil
+	}
+
+	// nodes
+	var nodes []interfaces.Node
+	if !t.GetNodeId().IsZero() {
+		n, err := svc.modelSvc.GetNodeById(t.GetNodeId())
+		if err != nil {
+			return "", err
+		}
+		nodes = append(nodes, n)
+	} else {
+		list, err := svc.modelSvc.GetNodeList(bson.M{
+			"active":      true,
+			"enabled":     true,
+			"schedulable": true,
+			"status":      constants.NodeStatusOnline,
+		}, nil)
+		if err != nil && err != mongo2.ErrNoDocuments {
+			return "", err
+		}
+		for i := range list {
+			nodes = append(nodes, &list[i])
+		}
+	}
+
+	// unmet requirement
+	unmet, err := svc.getUnmetRequirement(t, nodes)
+	if err != nil || unmet == "" {
+		return "", err
+	}
+	if t.GetNodeId().IsZero() || getFeasibilityMode() == constants.TaskFeasibilityReject {
+		return "", fmt.Errorf("%w: %s", errors.ErrorTaskNoEligibleNode, unmet)
+	}
+	return fmt.Sprintf("no eligible node: %s", unmet), nil
+}
+
+// getUnmetRequirement returns the first node tag or capability of the task
+// none of the nodes has, or the requirements none of the nodes has all of.
+// It returns empty if any of the nodes satisfies the task.
+func (svc *Service) getUnmetRequirement(t interfaces.Task, nodes []interfaces.Node) (unmet string, err error) {
+	res := nodes
+
+	// node tags
+	if tags := t.GetNodeTags(); len(tags) > 0 {
+		for _, tag := range tags {
+			tagged, err := svc.filterNodesByTags(res, []string{tag})
+			if err != nil {
+				return "", err
+			}
+			if len(tagged) == 0 {
+				return fmt.Sprintf("node tag %q", tag), nil
+			}
+		}
+		res, err = svc.filterNodesByTags(res, tags)
+		if err != nil {
+			return "", err
+		}
+		if len(res) == 0 {
+			return fmt.Sprintf("node tags %s on the same node", quoteJoin(tags)), nil
+		}
+	}
+
+	// capabilities, of nodes having the node tags if any
+	if names := t.GetCapabilities(); len(names) > 0 {
+		var suffix string
+		if len(t.GetNodeTags()) > 0 {
+			suffix = fmt.Sprintf(" on nodes with node tags %s", quoteJoin(t.GetNodeTags()))
+		}
+		for _, name := range names {
+			if !hasNodeWithExecutables(res, []string{name}) {
+				return fmt.Sprintf("capability %q%s", name, suffix), nil
+			}
+		}
+		if !hasNodeWithExecutables(res, names) {
+			return fmt.Sprintf("capabilities %s on the same node%s", quoteJoin(names), suffix), nil
+		}
+	}
+
+	return "", nil
+}
+
+func hasNodeWithExecutables(nodes []interfaces.Node, names []string) (ok bool) {
+	for _, n := range nodes {
+		if n.GetCapabilities().HasExecutables(names) {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteJoin(items []string) (res string) {
+	var quoted []string
+	for _, item := range items {
+		quoted = append(quoted, fmt.Sprintf("%q", item))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetUnmetRequirement_Capabilities(t *testing.T) {
+	svc := &Service{}
+	nodes := []interfaces.Node{
+		&models.Node{Key: "w1", Capabilities: &interfaces.NodeCapabilities{Executables: map[string]string{"python": "3.9", "chromium": "100"}}},
+		&models.Node{Key: "w2", Capabilities: &interfaces.NodeCapabilities{Executables: map[string]string{"node": "16"}}},
+		&models.Node{Key: "w3"},
+	}
+
+	// satisfied
+	unmet, err := svc.getUnmetRequirement(&models.Task{Capabilities: []string{"python", "chromium"}}, nodes)
+	require.Nil(t, err)
+	require.Empty(t, unmet)
+
+	// no node has the capability
+	unmet, err = svc.getUnmetRequirement(&models.Task{Capabilities: []string{"python", "firefox"}}, nodes)
+	require.Nil(t, err)
+	require.Equal(t, `capability "firefox"`, unmet)
+
+	// no node has all capabilities
+	unmet, err = svc.getUnmetRequirement(&models.Task{Capabilities: []string{"chromium", "node"}}, nodes)
+	require.Nil(t, err)
+	require.Equal(t, `capabilities "chromium", "node" on the same node`, unmet)
+
+	// no node
+	unmet, err = svc.getUnmetRequirement(&models.Task{Capabilities: []string{"python"}}, nil)
+	require.Nil(t, err)
+	require.Equal(t, `capability "python"`, unmet)
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetRunningTasksByNode returns running tasks on the node of the key, oldest
+// first. It returns errors.ErrorTaskNodeNotFound if the node does not exist.
+func (svc *Service) GetRunningTasksByNode(nodeKey string) (tasks []interfaces.Task, err error) {
+	n, err := svc.modelSvc.GetNodeByKey(nodeKey, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, trace.TraceError(errors.ErrorTaskNodeNotFound)
+		}
+		return nil, trace.TraceError(err)
+	}
+	list, err := svc.modelSvc.GetTaskList(bson.M{
+		"node_id": n.Id,
+		"status":  constants.TaskStatusRunning,
+	}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	tasks = []interfaces.Task{}
+	for i := range list {
+		tasks = append(tasks, &list[i])
+	}
+	return tasks, nil
+}
+
+// CancelTasksByNode cancels all running tasks on the node of the key, e.g. to
+// clear the node quickly for maintenance, which unlike draining does not wait
+// for the tasks to finish. A cancel message is sent for each task, and failing
+// to cancel a task does not stop cancelling the others.
+func (svc *Service) CancelTasksByNode(nodeKey string, args ...interface{}) (results []interfaces.TaskCancelResult, err error) {
+	tasks, err := svc.GetRunningTasksByNode(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+	results, cancelled := svc.cancelTasks(tasks, args...)
+	log.Infof("[TaskScheduler] cancelled %d/%d running tasks on node[%s]", cancelled, len(results), nodeKey)
+	return results, nil
+}
+
+// cancelTasks cancels each of the tasks and returns the result of each and
+// the number of tasks cancelled
+func (svc *Service) cancelTasks(tasks []interfaces.Task, args ...interface{}) (results []interfaces.TaskCancelResult, cancelled int) {
+	results = []interfaces.TaskCancelResult{}
+	for _, t := range tasks {
+		res := interfaces.TaskCancelResult{TaskId: t.GetId()}
+		if err := svc.Cancel(t.GetId(), args...); err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Success = true
+			cancelled++
+		}
+		results = append(results, res)
+	}
+	return results, cancelled
+}
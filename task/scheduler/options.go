@@ -13,8 +13,20 @@ func WithConfigPath(path string) Option {
 	}
 }
 
+func WithDispatchStrategy(strategy interfaces.TaskDispatchStrategy) Option {
+	return func(svc interfaces.TaskSchedulerService) {
+		svc.SetDispatchStrategy(strategy)
+	}
+}
+
 func WithInterval(interval time.Duration) Option {
 	return func(svc interfaces.TaskSchedulerService) {
 		svc.SetInterval(interval)
 	}
 }
+
+func WithReconcileInterval(interval time.Duration) Option {
+	return func(svc interfaces.TaskSchedulerService) {
+		svc.SetReconcileInterval(interval)
+	}
+}
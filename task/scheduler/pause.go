@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/utils"
+)
+
+// Pause holds all tasks pending by stopping nodes from fetching tasks, e.g.
+// during maintenance, while master and the node monitor keep running. The
+// state is saved in a setting, so that it is kept across restarts of master.
+func (svc *Service) Pause(args ...interface{}) (err error) {
+	return svc.setPaused(true, args...)
+}
+
+func (svc *Service) Resume(args ...interface{}) (err error) {
+	return svc.setPaused(false, args...)
+}
+
+func (svc *Service) IsPaused() (paused bool) {
+	return task.IsSchedulerPaused()
+}
+
+func (svc *Service) setPaused(paused bool, args ...interface{}) (err error) {
+	u := utils.GetUserFromArgs(args...)
+	if _, err := task.SetSchedulerPaused(paused, u); err != nil {
+		return err
+	}
+	if paused {
+		log.Infof("[TaskScheduler] paused dispatching tasks")
+	} else {
+		log.Infof("[TaskScheduler] resumed dispatching tasks")
+	}
+	return nil
+}
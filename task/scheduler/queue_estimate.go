@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// queueEstimateSampleSize is the number of recent tasks to average durations
+const queueEstimateSampleSize = 20
+
+// GetQueueEstimate returns the position of the pending task among queued tasks
+// it competes with for the same nodes, ordered by priority as they are
+// fetched, and estimates its wait from recent durations of tasks of the same
+// spider (or any spider if none) and the capacity of the nodes.
+func (svc *Service) GetQueueEstimate(id primitive.ObjectID) (res *interfaces.TaskQueueEstimate, err error) {
+	// queue item
+	var tq models.TaskQueueItem
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).FindId(id).One(&tq); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, trace.TraceError(err)
+	}
+
+	// queued tasks ahead
+	query := bson.M{
+		"$or": []bson.M{
+			{"p": bson.M{"$lt": tq.Priority}},
+			{"p": tq.Priority, "_id": bson.M{"$lt": tq.Id}},
+		},
+	}
+	if !tq.NodeId.IsZero() {
+		query["nid"] = bson.M{"$in": []interface{}{tq.NodeId, nil}}
+	}
+	ahead, err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).Count(query)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	// capacity of nodes
+	nodeQuery := bson.M{
+		"active":      true,
+		"enabled":     true,
+		"schedulable": true,
+		"status":      constants.NodeStatusOnline,
+	}
+	if !tq.NodeId.IsZero() {
+		nodeQuery["_id"] = tq.NodeId
+	}
+	nodes, err := svc.modelSvc.GetNodeList(nodeQuery, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	var capacity, available int
+	for _, n := range nodes {
+		capacity += n.MaxRunners
+		if n.AvailableRunners > 0 {
+			available += n.AvailableRunners
+		}
+	}
+	if max := task.GetMaxConcurrentTasks(); max > 0 && tq.NodeId.IsZero() {
+		if capacity > max {
+			capacity = max
+		}
+		running, err := task.CountRunningTasks()
+		if err != nil {
+			return nil, err
+		}
+		if free := max - running; available > free {
+			available = free
+		}
+	}
+	if available < 0 {
+		available = 0
+	}
+
+	// average duration
+	avg, err := svc.getRecentAvgDuration(tq.SpiderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.TaskQueueEstimate{
+		Position:      ahead + 1,
+		Capacity:      capacity,
+		Available:     available,
+		AvgDuration:   avg,
+		EstimatedWait: estimateQueueWait(ahead, available, capacity, avg),
+		Estimated:     true,
+	}, nil
+}
+
+// getRecentAvgDuration returns the average runtime (ms) of recent finished
+// tasks of the spider, or of any spider if the spider has none
+func (svc *Service) getRecentAvgDuration(spiderId primitive.ObjectID) (avg int64, err error) {
+	if !spiderId.IsZero() {
+		var tasks []models.Task
+		if err := mongo.GetMongoCol(interfaces.ModelColNameTask).Find(bson.M{
+			"spider_id": spiderId,
+			"status":    constants.TaskStatusFinished,
+		}, &mongo.FindOptions{
+			Sort:  bson.D{{"_id", -1}},
+			Limit: queueEstimateSampleSize,
+		}).All(&tasks); err != nil && err != mongo2.ErrNoDocuments {
+			return 0, trace.TraceError(err)
+		}
+		if len(tasks) > 0 {
+			var ids []primitive.ObjectID
+			for _, t := range tasks {
+				ids = append(ids, t.Id)
+			}
+			avg, err = getAvgRuntimeDuration(bson.M{"_id": bson.M{"$in": ids}})
+			if err != nil || avg > 0 {
+				return avg, err
+			}
+		}
+	}
+	return getAvgRuntimeDuration(nil)
+}
+
+func getAvgRuntimeDuration(query bson.M) (avg int64, err error) {
+	if query == nil {
+		query = bson.M{}
+	}
+	query["runtime_duration"] = bson.M{"$gt": 0}
+	var stats []models.TaskStat
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTaskStat).Find(query, &mongo.FindOptions{
+		Sort:  bson.D{{"_id", -1}},
+		Limit: queueEstimateSampleSize,
+	}).All(&stats); err != nil && err != mongo2.ErrNoDocuments {
+		return 0, trace.TraceError(err)
+	}
+	if len(stats) == 0 {
+		return 0, nil
+	}
+	var total int64
+	for _, s := range stats {
+		total += s.RuntimeDuration
+	}
+	return total / int64(len(stats)), nil
+}
+
+// estimateQueueWait estimates the wait (ms) of a task with the given number of
+// tasks ahead in the queue. Tasks ahead take available runners first, and the
+// rest run in waves of the capacity, each of which takes the average
+// duration. It returns -1 if unknown.
+func estimateQueueWait(ahead, available, capacity int, avg int64) (wait int64) {
+	if ahead < available {
+		return 0
+	}
+	if capacity <= 0 || avg <= 0 {
+		return -1
+	}
+	waves := (ahead-available)/capacity + 1
+	return int64(waves) * avg
+}
@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEstimateQueueWait(t *testing.T) {
+	// runners available for the task
+	require.Equal(t, int64(0), estimateQueueWait(0, 1, 4, 1000))
+	require.Equal(t, int64(0), estimateQueueWait(2, 3, 4, 1000))
+
+	// waits for waves of tasks ahead
+	require.Equal(t, int64(1000), estimateQueueWait(0, 0, 4, 1000))
+	require.Equal(t, int64(1000), estimateQueueWait(3, 0, 4, 1000))
+	require.Equal(t, int64(2000), estimateQueueWait(4, 0, 4, 1000))
+	require.Equal(t, int64(2000), estimateQueueWait(5, 1, 4, 1000))
+
+	// unknown
+	require.Equal(t, int64(-1), estimateQueueWait(4, 0, 0, 1000))
+	require.Equal(t, int64(-1), estimateQueueWait(4, 0, 4, 0))
+}
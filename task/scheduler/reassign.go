@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	errors2 "errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/delegate"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/task"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReassignTask moves the task off its current node, e.g. when the node is
+// degraded. A pending task is pinned to the node of targetNodeKey, or returned
+// to the pending pool to be fetched by any node if targetNodeKey is empty. A
+// running task is cancelled and re-enqueued as a new task in the same way.
+// It returns the task to run, i.e. the new task if re-enqueued.
+func (svc *Service) ReassignTask(id primitive.ObjectID, targetNodeKey string, args ...interface{}) (t2 interfaces.Task, err error) {
+	// task
+	t, err := svc.modelSvc.GetTaskById(id)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if !utils.IsCancellable(t.Status) {
+		return nil, trace.TraceError(errors.ErrorTaskAlreadyFinished)
+	}
+
+	// target node
+	var target *models.Node
+	if targetNodeKey != "" {
+		target, err = svc.getReassignTargetNode(t, targetNodeKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// user
+	u := utils.GetUserFromArgs(args...)
+
+	if t.Status == constants.TaskStatusPending {
+		return svc.reassignPendingTask(t, target, u)
+	}
+	return svc.reassignRunningTask(t, target, u)
+}
+
+// getReassignTargetNode returns the node of the key if it is able to run the
+// task, i.e. online, schedulable and having all node tags and capabilities of
+// the task
+func (svc *Service) getReassignTargetNode(t *models.Task, nodeKey string) (n *models.Node, err error) {
+	n, err = svc.modelSvc.GetNodeByKey(nodeKey, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, trace.TraceError(errors.ErrorTaskNodeNotFound)
+		}
+		return nil, trace.TraceError(err)
+	}
+	if !n.Active || !n.Enabled || !n.Schedulable || n.Status != constants.NodeStatusOnline {
+		return nil, trace.TraceError(errors.ErrorTaskNodeNotEligible)
+	}
+	nodes, err := svc.filterEligibleNodes(t, []interfaces.Node{n})
+	if err != nil || len(nodes) == 0 {
+		return nil, trace.TraceError(errors.ErrorTaskNodeNotEligible)
+	}
+	return n, nil
+}
+
+// reassignPendingTask updates the node of the queued task. It returns
+// errors.ErrorTaskAlreadyDispatched if the task has been fetched by a node in
+// the meantime.
+func (svc *Service) reassignPendingTask(t *models.Task, target *models.Node, u interfaces.User) (t2 interfaces.Task, err error) {
+	var update bson.M
+	var msg string
+	if target != nil {
+		update = bson.M{"$set": bson.M{"nid": target.Id}}
+		msg = fmt.Sprintf("reassigned to node %s", target.Name)
+	} else {
+		update = bson.M{"$unset": bson.M{"nid": ""}}
+		msg = "returned to pending pool"
+	}
+	col := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue)
+	res, err := col.GetCollection().UpdateOne(col.GetContext(), bson.M{"_id": t.Id}, update)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, trace.TraceError(errors.ErrorTaskAlreadyDispatched)
+	}
+
+	// task
+	if target != nil {
+		t.NodeId = target.Id
+	} else {
+		t.NodeId = primitive.NilObjectID
+	}
+	if err := delegate.NewModelDelegate(t, u).Save(); err != nil {
+		return nil, err
+	}
+
+	// task event
+	e := task.NewTaskEvent(t.Id, constants.TaskEventReassigned, task.GetUserActor(u), msg)
+	e.NodeId = t.NodeId
+	task.AddTaskEvent(e)
+
+	return t, nil
+}
+
+// reassignRunningTask cancels the running task and re-enqueues it as a new
+// task on the target node, or any node selected by the scheduler if nil. The
+// new task is enqueued even if the current node cannot be reached to cancel
+// the task, as the node may be the reason of reassigning.
+func (svc *Service) reassignRunningTask(t *models.Task, target *models.Node, u interfaces.User) (t2 interfaces.Task, err error) {
+	// cancel on the current node
+	if err := svc.Cancel(t.Id, u, &interfaces.TaskCancelOptions{Mode: constants.TaskCancelModeForce}); err != nil {
+		trace.PrintError(err)
+	}
+
+	// re-enqueue
+	t3 := newTaskAttempt(t)
+	if target != nil {
+		t3.NodeId = target.Id
+	}
+	if u != nil {
+		t3.UserId = u.GetId()
+	}
+	t2, err = svc.Enqueue(t3)
+	if err != nil {
+		return nil, err
+	}
+
+	// task event
+	msg := fmt.Sprintf("re-enqueued as task %s", t2.GetId().Hex())
+	if target != nil {
+		msg += fmt.Sprintf(" on node %s", target.Name)
+	}
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventReassigned, task.GetUserActor(u), msg))
+
+	return t2, nil
+}
+
+// RedispatchTasksByNode dispatches again pending tasks pinned to the node by
+// the dispatch strategy, e.g. when the node goes offline, so that they are not
+// left pending until the node is back. Tasks are returned to the pending pool
+// if no other node is eligible and they require neither node tags nor
+// capabilities, otherwise they are kept on the node. Tasks assigned to the
+// node explicitly are kept as well.
+func (svc *Service) RedispatchTasksByNode(nodeId primitive.ObjectID) (err error) {
+	var items []models.TaskQueueItem
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).Find(bson.M{
+		"nid": nodeId,
+		"dp":  true,
+	}, nil).All(&items); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil
+		}
+		return trace.TraceError(err)
+	}
+
+	for _, tq := range items {
+		t, err := svc.modelSvc.GetTaskById(tq.Id)
+		if err != nil {
+			trace.PrintError(err)
+			continue
+		}
+		if t.Status != constants.TaskStatusPending {
+			continue
+		}
+
+		// select another node
+		t.NodeId = primitive.NilObjectID
+		if err := svc.dispatch(t); err != nil {
+			log.Warnf("[TaskSchedulerService] task[%s] kept on offline node[%s]: %v", t.Id.Hex(), nodeId.Hex(), err)
+			continue
+		}
+		var target *models.Node
+		if !t.NodeId.IsZero() {
+			target, err = svc.modelSvc.GetNodeById(t.NodeId)
+			if err != nil {
+				trace.PrintError(err)
+				continue
+			}
+		}
+		if _, err := svc.reassignPendingTask(t, target, nil); err != nil {
+			if !errors2.Is(err, errors.ErrorTaskAlreadyDispatched) {
+				trace.PrintError(err)
+			}
+			continue
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,17 @@
 package scheduler
 
 import (
+	"context"
+	errors2 "errors"
+	"fmt"
+	"github.com/apex/log"
 	config2 "github.com/crawlab-team/crawlab-core/config"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
 	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/event"
 	"github.com/crawlab-team/crawlab-core/grpc/server"
+	"github.com/crawlab-team/crawlab-core/inject"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	"github.com/crawlab-team/crawlab-core/models/delegate"
 	"github.com/crawlab-team/crawlab-core/models/models"
@@ -12,6 +19,9 @@ import (
 	"github.com/crawlab-team/crawlab-core/node/config"
 	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-core/task/handler"
+	"github.com/crawlab-team/crawlab-core/task/plugin"
+	"github.com/crawlab-team/crawlab-core/tracing"
+	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	grpc "github.com/crawlab-team/crawlab-grpc"
 	"github.com/crawlab-team/go-trace"
@@ -19,6 +29,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/dig"
 	"sync"
 	"time"
@@ -33,36 +45,119 @@ type Service struct {
 	handlerSvc interfaces.TaskHandlerService
 
 	// settings
-	interval time.Duration
+	interval          time.Duration
+	reconcileInterval time.Duration
+	dispatchStrategy  interfaces.TaskDispatchStrategy
+
+	// internals
+	retryFailures map[primitive.ObjectID]int // failed attempts to enqueue the retry of tasks
 }
 
 func (svc *Service) Start() {
 	go svc.initTaskStatus()
 	go svc.cleanupTasks()
+	go svc.retryTasks()
+	go svc.renewTaskLocks()
+	go svc.syncRunningTasks()
 	svc.Wait()
 	svc.Stop()
 }
 
 func (svc *Service) Enqueue(t interfaces.Task) (t2 interfaces.Task, err error) {
+	return svc.enqueue(t, nil)
+}
+
+// enqueue enqueues the task, running fn if not nil in the transaction adding
+// the task, so that changes of fn are made only if the task is added and
+// vice versa
+func (svc *Service) enqueue(t interfaces.Task, fn func(sc mongo2.SessionContext) error) (t2 interfaces.Task, err error) {
 	// set task status
 	t.SetStatus(constants.TaskStatusPending)
 
+	// trace, continued by the dispatch and the run on the node
+	if tracing.IsEnabled() {
+		ctx, span := tracing.Tracer().Start(tracing.Extract(context.Background(), t.GetTraceContext()), "task.enqueue",
+			oteltrace.WithAttributes(attribute.String("crawlab.spider_id", t.GetSpiderId().Hex())),
+		)
+		defer func() { tracing.EndSpan(span, err) }()
+		t.SetTraceContext(tracing.Inject(ctx))
+	}
+
+	// plugins
+	pluginCtx := &entity.TaskPluginContext{Task: t, Status: constants.TaskStatusPending}
+	if s, err := svc.modelSvc.GetSpiderById(t.GetSpiderId()); err == nil {
+		pluginCtx.Spider = s
+	}
+	if err := plugin.GetRegistry().BeforeDispatch(pluginCtx); err != nil {
+		return nil, err
+	}
+
+	// dispatch to a node if not assigned
+	var dispatched bool
+	if t.GetNodeId().IsZero() {
+		if err := svc.dispatch(t); err != nil {
+			return nil, err
+		}
+		dispatched = !t.GetNodeId().IsZero()
+	}
+
 	// user
 	var u *models.User
 	if !t.GetUserId().IsZero() {
 		u, _ = svc.modelSvc.GetUserById(t.GetUserId())
 	}
 
-	// add task
-	if err = delegate.NewModelDelegate(t, u).Add(); err != nil {
-		return nil, err
+	// add task with its event, queue item and stat in a transaction, so that
+	// no task is left without queue item or stat
+	if err := svc.modelSvc.WithTransaction(context.Background(), func(sc mongo2.SessionContext) error {
+		if err := svc.addTask(sc, t, u, dispatched); err != nil {
+			return err
+		}
+		if fn != nil {
+			return fn(sc)
+		}
+		return nil
+	}); err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	// success
+	return t, nil
+}
+
+// addTask adds the task, its created event, queue item and stat within the
+// transaction of sc. dispatched is whether the node of the task is selected by
+// the dispatch strategy.
+func (svc *Service) addTask(sc mongo2.SessionContext, t interfaces.Task, u *models.User, dispatched bool) (err error) {
+	// task
+	if err := delegate.NewModelDelegate(t, u, sc).Add(); err != nil {
+		return err
+	}
+
+	// task event
+	e := task.NewTaskEvent(t.GetId(), constants.TaskEventCreated, "", "")
+	if u != nil {
+		e.Actor = task.GetUserActor(u)
+	}
+	if !t.GetNodeId().IsZero() {
+		e.NodeId = t.GetNodeId()
+		e.Message = fmt.Sprintf("assigned to node %s", t.GetNodeId().Hex())
+	}
+	if err := delegate.NewModelDelegate(e, sc).Add(); err != nil {
+		return err
 	}
 
 	// task queue item
 	tq := &models.TaskQueueItem{
-		Id:       t.GetId(),
-		Priority: t.GetPriority(),
-		NodeId:   t.GetNodeId(),
+		Id:         t.GetId(),
+		Priority:   t.GetPriority(),
+		NodeId:     t.GetNodeId(),
+		SpiderId:   t.GetSpiderId(),
+		LockKey:    t.GetLockKey(),
+		Dispatched: dispatched,
+	}
+	if _, err := mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).GetCollection().InsertOne(sc, tq); err != nil {
+		return trace.TraceError(err)
 	}
 
 	// task stat
@@ -70,21 +165,11 @@ func (svc *Service) Enqueue(t interfaces.Task) (t2 interfaces.Task, err error) {
 		Id:       t.GetId(),
 		CreateTs: time.Now(),
 	}
-
-	// enqueue task
-	_, err = mongo.GetMongoCol(interfaces.ModelColNameTaskQueue).Insert(tq)
-	if err != nil {
-		return nil, trace.TraceError(err)
-	}
-
-	// add task stat
-	_, err = mongo.GetMongoCol(interfaces.ModelColNameTaskStat).Insert(ts)
-	if err != nil {
-		return nil, trace.TraceError(err)
+	if _, err := mongo.GetMongoCol(interfaces.ModelColNameTaskStat).GetCollection().InsertOne(sc, ts); err != nil {
+		return trace.TraceError(err)
 	}
 
-	// success
-	return t, nil
+	return nil
 }
 
 func (svc *Service) Cancel(id primitive.ObjectID, args ...interface{}) (err error) {
@@ -97,6 +182,16 @@ func (svc *Service) Cancel(id primitive.ObjectID, args ...interface{}) (err erro
 	// initial status
 	initialStatus := t.Status
 
+	// cancel mode
+	mode := utils.GetTaskCancelOptionsFromArgs(args...).Mode
+	if mode != constants.TaskCancelModeSoft {
+		mode = constants.TaskCancelModeForce
+	}
+	t.SetCancelMode(mode)
+
+	// task event
+	task.AddTaskEvent(task.NewTaskEvent(t.GetId(), constants.TaskEventCancelRequested, task.GetUserActor(utils.GetUserFromArgs(args...)), fmt.Sprintf("mode: %s", mode)))
+
 	// set task status as "cancelled"
 	_ = svc.SaveTask(t, constants.TaskStatusCancelled)
 
@@ -124,7 +219,7 @@ func (svc *Service) Cancel(id primitive.ObjectID, args ...interface{}) (err erro
 
 	if isMasterTask {
 		// cancel task on master
-		if err := svc.handlerSvc.Cancel(id); err != nil {
+		if err := svc.handlerSvc.CancelWithMode(id, mode); err != nil {
 			return trace.TraceError(err)
 		}
 		// cancel success
@@ -143,6 +238,303 @@ func (svc *Service) SetInterval(interval time.Duration) {
 	svc.interval = interval
 }
 
+func (svc *Service) SetDispatchStrategy(strategy interfaces.TaskDispatchStrategy) {
+	svc.dispatchStrategy = strategy
+}
+
+func (svc *Service) SetReconcileInterval(interval time.Duration) {
+	svc.reconcileInterval = interval
+}
+
+// dispatch assigns the task to a node selected by the dispatch strategy among
+// eligible nodes, i.e. online and schedulable nodes having all node tags and
+// capabilities of the task. The task is left unassigned, i.e. to be fetched by any node, if there is
+// no online node and the task requires neither node tags nor capabilities.
+// Tasks requiring neither are dispatched to the default node if configured
+// (see applyDefaultNode).
+func (svc *Service) dispatch(t interfaces.Task) (err error) {
+	nodes, err := svc.modelSvc.GetNodeList(bson.M{
+		"active":      true,
+		"enabled":     true,
+		"schedulable": true,
+		"status":      constants.NodeStatusOnline,
+	}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return trace.TraceError(err)
+	}
+	var candidates []interfaces.Node
+	for i := range nodes {
+		candidates = append(candidates, &nodes[i])
+	}
+	candidates, err = svc.filterEligibleNodes(t, candidates)
+	if err != nil {
+		return err
+	}
+	candidates, done, err := svc.applyDefaultNode(t, candidates)
+	if err != nil || done {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	n, err := svc.dispatchStrategy.Select(t, candidates)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		t.SetNodeId(n.GetId())
+	}
+	return nil
+}
+
+// filterEligibleNodes returns the nodes having all node tags and capabilities
+// of the task. It returns errors.ErrorTaskNoEligibleNode if the task requires
+// node tags or capabilities which none of the nodes has.
+func (svc *Service) filterEligibleNodes(t interfaces.Task, nodes []interfaces.Node) (res []interfaces.Node, err error) {
+	res = nodes
+	if len(t.GetNodeTags()) > 0 {
+		res, err = svc.filterNodesByTags(res, t.GetNodeTags())
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			return nil, trace.TraceError(errors.ErrorTaskNoEligibleNode)
+		}
+	}
+	if len(t.GetCapabilities()) > 0 {
+		var capable []interfaces.Node
+		for _, n := range res {
+			if n.GetCapabilities().HasExecutables(t.GetCapabilities()) {
+				capable = append(capable, n)
+			}
+		}
+		if len(capable) == 0 {
+			return nil, trace.TraceError(errors.ErrorTaskNoEligibleNode)
+		}
+		res = capable
+	}
+	return res, nil
+}
+
+// filterNodesByTags returns the nodes having all the tags
+func (svc *Service) filterNodesByTags(nodes []interfaces.Node, tagNames []string) (res []interfaces.Node, err error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	// tag ids
+	tags, err := svc.modelSvc.GetTagList(bson.M{
+		"col":  interfaces.ModelColNameNode,
+		"name": bson.M{"$in": tagNames},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var tagIds []primitive.ObjectID
+	names := map[string]bool{}
+	for _, tag := range tags {
+		tagIds = append(tagIds, tag.Id)
+		names[tag.Name] = true
+	}
+	for _, name := range tagNames {
+		if !names[name] {
+			// no node has the tag
+			return nil, nil
+		}
+	}
+
+	// node artifacts having all tags
+	var nodeIds []primitive.ObjectID
+	for _, n := range nodes {
+		nodeIds = append(nodeIds, n.GetId())
+	}
+	artifacts, err := svc.modelSvc.GetArtifactList(bson.M{
+		"_id":  bson.M{"$in": nodeIds},
+		"_tid": bson.M{"$all": tagIds},
+	}, nil)
+	if err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	matched := map[primitive.ObjectID]bool{}
+	for _, a := range artifacts {
+		matched[a.Id] = true
+	}
+	for _, n := range nodes {
+		if matched[n.GetId()] {
+			res = append(res, n)
+		}
+	}
+	return res, nil
+}
+
+// retryTasks periodically re-enqueues failed tasks which have not exhausted their
+// max retries. The retry inherits the config recorded on the failed task.
+// Failed tasks which have exhausted their max retries are dead-lettered.
+func (svc *Service) retryTasks() {
+	for {
+		if svc.IsStopped() {
+			return
+		}
+
+		query := getRetryCandidateQuery()
+		query["$expr"] = bson.M{"$lt": bson.A{"$retries", "$max_retries"}}
+		tasks, err := svc.modelSvc.GetTaskList(query, nil)
+		if err != nil && err != mongo2.ErrNoDocuments {
+			trace.PrintError(err)
+		}
+		for i := range tasks {
+			svc.tryRetryTask(&tasks[i])
+		}
+		svc.deadLetterTasks()
+
+		time.Sleep(svc.interval)
+	}
+}
+
+// renewTaskLocks periodically renews locks of tasks dispatched to master and
+// releases locks of tasks which have ended. Locks of tasks dispatched to
+// worker nodes are renewed on their heartbeats.
+func (svc *Service) renewTaskLocks() {
+	for {
+		if svc.IsStopped() {
+			return
+		}
+
+		if err := task.RenewTaskLocks(svc.nodeCfgSvc.GetNodeKey()); err != nil {
+			trace.PrintError(err)
+		}
+		if err := task.ReleaseEndedTaskLocks(); err != nil {
+			trace.PrintError(err)
+		}
+
+		time.Sleep(task.GetTaskLockRenewInterval())
+	}
+}
+
+// getRetryCandidateQuery returns the query of failed tasks which have retries
+// configured, and are neither retried nor dead-lettered yet, i.e. which are to
+// be retried or dead-lettered. It matches the partial index of these tasks (see
+// models/common/migrations.go), so that the scan is limited to them.
+func getRetryCandidateQuery() (query bson.M) {
+	return bson.M{
+		"status":      constants.TaskStatusError,
+		"max_retries": bson.M{"$gt": 0},
+		"dead_letter": false,
+		"retry_id":    bson.M{"$in": []interface{}{nil, primitive.NilObjectID}},
+	}
+}
+
+// tryRetryTask retries the failed task unless enqueuing its retry has failed
+// "task.scheduler.retryAttempts" times (3 by default), after which the task is
+// given up with a task event and no longer retried until master restarts.
+func (svc *Service) tryRetryTask(t *models.Task) {
+	maxAttempts := getRetryAttempts()
+	if svc.retryFailures[t.Id] >= maxAttempts {
+		return
+	}
+	err := svc.retryTask(t)
+	if err == nil || errors2.Is(err, errors.ErrorTaskAlreadyRetried) {
+		delete(svc.retryFailures, t.Id)
+		return
+	}
+	svc.retryFailures[t.Id]++
+	if svc.retryFailures[t.Id] < maxAttempts {
+		trace.PrintError(err)
+		return
+	}
+	log.Errorf("task[%s] retry not enqueued after %d attempts, giving up: %v", t.Id.Hex(), maxAttempts, err)
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventError, constants.TaskEventActorSystem, fmt.Sprintf("retry not enqueued after %d attempts: %v", maxAttempts, err)))
+}
+
+func getRetryAttempts() (n int) {
+	if n = viper.GetInt("task.scheduler.retryAttempts"); n > 0 {
+		return n
+	}
+	return 3
+}
+
+// retryTask enqueues the retry of the failed task and marks the task as
+// retried in the same transaction. It returns errors.ErrorTaskAlreadyRetried
+// if the task has been retried in the meantime.
+func (svc *Service) retryTask(t *models.Task) (err error) {
+	t2 := newTaskAttempt(t)
+	t2.Retries = t.Retries + 1
+	if t.Mode != constants.RunTypeRandom {
+		// keep the node selected by the user
+		t2.NodeId = t.NodeId
+	}
+	if _, err := svc.enqueue(t2, func(sc mongo2.SessionContext) error {
+		return setTaskRetryId(sc, t.Id, t2.Id, nil)
+	}); err != nil {
+		return err
+	}
+	t.RetryId = t2.Id
+
+	// task event
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventRetried, constants.TaskEventActorSystem, fmt.Sprintf("retried as task %s (%d/%d)", t2.Id.Hex(), t2.Retries, t2.MaxRetries)))
+
+	return nil
+}
+
+// setTaskRetryId links the task to its retry within the transaction of sc
+// unless retried already. The task must also match query if not nil.
+func setTaskRetryId(sc mongo2.SessionContext, id, retryId primitive.ObjectID, query bson.M) (err error) {
+	filter := bson.M{
+		"_id":      id,
+		"retry_id": bson.M{"$in": []interface{}{nil, primitive.NilObjectID}},
+	}
+	for k, v := range query {
+		filter[k] = v
+	}
+	col := mongo.GetMongoCol(interfaces.ModelColNameTask)
+	res, err := col.GetCollection().UpdateOne(sc, filter, bson.M{
+		"$set": bson.M{"retry_id": retryId},
+	})
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrorTaskAlreadyRetried
+	}
+	return nil
+}
+
+// newTaskAttempt returns a new task running again the task with the config
+// recorded on it
+func newTaskAttempt(t *models.Task) (t2 *models.Task) {
+	t2 = &models.Task{
+		SpiderId:         t.SpiderId,
+		Mode:             t.Mode,
+		NodeIds:          t.NodeIds,
+		Cmd:              t.Cmd,
+		Param:            t.Param,
+		ScheduleId:       t.ScheduleId,
+		Priority:         t.Priority,
+		Timeout:          t.Timeout,
+		MaxRetries:       t.MaxRetries,
+		MaxItems:         t.MaxItems,
+		Retries:          t.Retries,
+		NodeTags:         t.NodeTags,
+		Capabilities:     t.Capabilities,
+		Checkpoint:       t.Checkpoint,
+		GitRef:           t.GitRef,
+		InputId:          t.InputId,
+		Profile:          t.Profile,
+		Concurrency:      t.Concurrency,
+		LockKey:          t.LockKey,
+		CancelOnMaxItems: t.CancelOnMaxItems,
+		CreateTs:         time.Now(),
+	}
+	if t.GitCommit != "" {
+		// run the same commit as the previous attempt
+		t2.GitRef = t.GitCommit
+	}
+	return t2
+}
+
 // initTaskStatus initialize task status of existing tasks
 func (svc *Service) initTaskStatus() {
 	// set status of running tasks as TaskStatusAbnormal
@@ -160,18 +552,98 @@ func (svc *Service) initTaskStatus() {
 		}
 		trace.PrintError(err)
 	}
-	for _, t := range runningTasks {
+	for i := range runningTasks {
 		go func(t *models.Task) {
 			if err := svc.SaveTask(t, constants.TaskStatusAbnormal); err != nil {
 				trace.PrintError(err)
+				return
 			}
-		}(&t)
+			// ended event, e.g. to call the webhook of the spider
+			task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventError, constants.TaskEventActorSystem, "interrupted by restart of master"))
+		}(&runningTasks[i])
 	}
 	if err := svc.modelSvc.GetBaseService(interfaces.ModelIdTaskQueue).DeleteList(nil); err != nil {
 		return
 	}
 }
 
+// syncRunningTasks keeps the running task counter in sync with changes of
+// tasks, and reconciles it against the database periodically to correct drift,
+// e.g. of status updated without the model delegate. Changes are handled one
+// by one with the status read from the database, so that the counter does not
+// depend on the order of events.
+func (svc *Service) syncRunningTasks() {
+	c := task.GetRunningTaskCounter()
+
+	ch := make(chan interfaces.EventData)
+	key := "task-scheduler:running-tasks"
+	eventSvc := event.NewEventService()
+	eventSvc.Register(key, fmt.Sprintf("^model:%s:(add|save|change|delete)$", interfaces.ModelColNameTask), "^$", &ch)
+	defer eventSvc.Unregister(key)
+
+	svc.reconcileRunningTasks(c)
+	ticker := time.NewTicker(svc.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		if svc.IsStopped() {
+			return
+		}
+
+		select {
+		case e := <-ch:
+			doc, ok := e.GetData().(interfaces.Model)
+			if !ok {
+				continue
+			}
+			t, err := svc.modelSvc.GetTaskById(doc.GetId())
+			if err != nil {
+				if err == mongo2.ErrNoDocuments {
+					c.Remove(doc.GetId())
+					continue
+				}
+				trace.PrintError(err)
+				continue
+			}
+			c.Update(t)
+			svc.releaseTaskLock(t)
+		case <-ticker.C:
+			svc.reconcileRunningTasks(c)
+		}
+	}
+}
+
+// releaseTaskLock releases the lock of the task if it has ended, so that
+// pending tasks of the lock can be dispatched without waiting for the lease to
+// be renewed
+func (svc *Service) releaseTaskLock(t *models.Task) {
+	if t.LockKey == "" {
+		return
+	}
+	switch t.Status {
+	case constants.TaskStatusPending, constants.TaskStatusRunning:
+		return
+	}
+	if err := task.ReleaseTaskLock(t.LockKey, t.Id); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+func (svc *Service) reconcileRunningTasks(c *task.RunningTaskCounter) {
+	list, err := svc.modelSvc.GetTaskList(bson.M{"status": constants.TaskStatusRunning}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		trace.PrintError(err)
+		return
+	}
+	var tasks []interfaces.Task
+	for i := range list {
+		tasks = append(tasks, &list[i])
+	}
+	if drift := c.Reconcile(tasks); drift > 0 {
+		log.Warnf("[TaskSchedulerService] corrected %d running task counts", drift)
+	}
+}
+
 func (svc *Service) isMasterNode(t *models.Task) (ok bool, err error) {
 	if t.GetNodeId().IsZero() {
 		return false, trace.TraceError(errors.ErrorTaskNoNodeId)
@@ -234,10 +706,18 @@ func NewTaskSchedulerService(opts ...Option) (svc2 interfaces.TaskSchedulerServi
 
 	// service
 	svc := &Service{
-		TaskBaseService: baseSvc,
-		interval:        5 * time.Second,
+		TaskBaseService:   baseSvc,
+		interval:          5 * time.Second,
+		reconcileInterval: time.Minute,
+		dispatchStrategy:  getDefaultTaskDispatchStrategy(),
+		retryFailures:     map[primitive.ObjectID]int{},
 	}
 
+	// custom dispatch strategy provided by integrators
+	_ = inject.GetContainer().Invoke(func(strategy interfaces.TaskDispatchStrategy) {
+		svc.dispatchStrategy = strategy
+	})
+
 	// apply options
 	for _, opt := range opts {
 		opt(svc)
@@ -323,6 +803,12 @@ func ProvideGetTaskSchedulerService(path string, opts ...Option) func() (svr int
 		opts = append(opts, WithInterval(time.Duration(intervalSeconds)*time.Second))
 	}
 
+	// reconcile interval
+	reconcileIntervalSeconds := viper.GetInt("task.scheduler.reconcileInterval")
+	if reconcileIntervalSeconds > 0 {
+		opts = append(opts, WithReconcileInterval(time.Duration(reconcileIntervalSeconds)*time.Second))
+	}
+
 	return func() (svr interfaces.TaskSchedulerService, err error) {
 		return GetTaskSchedulerService(path, opts...)
 	}
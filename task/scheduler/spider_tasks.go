@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+)
+
+// CancelTasksBySpider cancels all running and pending tasks of the spider,
+// e.g. to stop a misbehaving spider at once. Pending tasks are cancelled as
+// well, as they would otherwise run as soon as the spider is enabled again.
+// Failing to cancel a task does not stop cancelling the others.
+func (svc *Service) CancelTasksBySpider(spiderId primitive.ObjectID, args ...interface{}) (results []interfaces.TaskCancelResult, err error) {
+	list, err := svc.modelSvc.GetTaskList(bson.M{
+		"spider_id": spiderId,
+		"status": bson.M{"$in": []string{
+			constants.TaskStatusPending,
+			constants.TaskStatusRunning,
+		}},
+	}, nil)
+	if err != nil && err != mongo2.ErrNoDocuments {
+		return nil, trace.TraceError(err)
+	}
+	var tasks []interfaces.Task
+	for i := range list {
+		tasks = append(tasks, &list[i])
+	}
+	results, cancelled := svc.cancelTasks(tasks, args...)
+	log.Infof("[TaskScheduler] cancelled %d/%d running and pending tasks of spider[%s]", cancelled, len(results), spiderId.Hex())
+	return results, nil
+}
@@ -1,13 +1,20 @@
 package stats
 
 import (
+	"fmt"
+	log2 "github.com/apex/log"
 	config2 "github.com/crawlab-team/crawlab-core/config"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/inject"
 	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/node/config"
 	"github.com/crawlab-team/crawlab-core/result"
 	"github.com/crawlab-team/crawlab-core/task"
 	"github.com/crawlab-team/crawlab-core/task/log"
+	"github.com/crawlab-team/crawlab-core/task/plugin"
 	"github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
 	"go.mongodb.org/mongo-driver/bson"
@@ -28,6 +35,7 @@ type Service struct {
 	resultServices sync.Map
 	rsTtl          time.Duration
 	logDriver      log.Driver
+	batcher        *result.Batcher // inserting result records in batches if enabled
 }
 
 func (svc *Service) Init() (err error) {
@@ -36,14 +44,43 @@ func (svc *Service) Init() (err error) {
 }
 
 func (svc *Service) InsertData(id primitive.ObjectID, records ...interface{}) (err error) {
+	if svc.batcher != nil {
+		return svc.batcher.Add(id, records...)
+	}
+	return svc.insertData(id, records)
+}
+
+// FlushData inserts result records of the task buffered for batches, e.g.
+// when the task ends
+func (svc *Service) FlushData(id primitive.ObjectID) (err error) {
+	if svc.batcher == nil {
+		return nil
+	}
+	return svc.batcher.Flush(id)
+}
+
+func (svc *Service) insertData(id primitive.ObjectID, records []interface{}) (err error) {
 	resultSvc, err := svc.getResultService(id)
 	if err != nil {
 		return err
 	}
-	if err := resultSvc.Insert(records...); err != nil {
+	var stats interfaces.ResultInsertStats
+	if rs, ok := resultSvc.(interfaces.ResultServiceWithStats); ok {
+		stats, err = rs.InsertWithStats(records...)
+	} else {
+		err = resultSvc.Insert(records...)
+		stats.Inserted = len(records)
+	}
+	if err != nil {
 		return err
 	}
-	go svc.updateTaskStats(id, len(records))
+	go svc.updateTaskStats(id, len(records), stats)
+
+	// records are stored already, so hook errors are only logged as returning
+	// them would make senders retry and insert the records again
+	if err := svc.onResultStored(id, records); err != nil {
+		trace.PrintError(err)
+	}
 	return nil
 }
 
@@ -51,6 +88,16 @@ func (svc *Service) InsertLogs(id primitive.ObjectID, logs ...string) (err error
 	return svc.logDriver.WriteLines(id.Hex(), logs)
 }
 
+func (svc *Service) InsertLogsWithLevels(id primitive.ObjectID, logs []string, levels []string) (err error) {
+	return svc.logDriver.WriteLinesWithLevels(id.Hex(), logs, levels)
+}
+
+func (svc *Service) ReleaseLogs(id primitive.ObjectID) {
+	if d, ok := svc.logDriver.(log.DriverWithRelease); ok {
+		d.Release(id.Hex())
+	}
+}
+
 func (svc *Service) getResultService(id primitive.ObjectID) (resultSvc interfaces.ResultService, err error) {
 	// atomic operation
 	svc.mu.Lock()
@@ -79,20 +126,119 @@ func (svc *Service) getResultService(id primitive.ObjectID) (resultSvc interface
 		return nil, err
 	}
 
+	// field mappings of the spider, applied to numbers converted in the
+	// number mode of the spider
+	var numberMode string
+	if s, err := svc.modelSvc.GetSpiderById(t.SpiderId); err == nil {
+		if len(s.FieldMappings) > 0 {
+			resultSvc = result.NewResultServiceFieldMapping(resultSvc, s.FieldMappings)
+		}
+		numberMode = s.ResultNumberMode
+	}
+	resultSvc = result.NewResultServiceNumber(resultSvc, numberMode)
+
+	// cap of result items of the task, continued from items accepted before
+	// the cache of the result service expired, e.g. on restart of master
+	if t.MaxItems > 0 {
+		if t.ItemsCapped {
+			resultSvc = result.NewResultServiceMaxItems(resultSvc, t.MaxItems, t.MaxItems, nil)
+		} else {
+			resultSvc = result.NewResultServiceMaxItems(resultSvc, t.MaxItems, svc.getAcceptedResultCount(id), func() {
+				svc.onItemsCapped(t)
+			})
+		}
+	}
+
 	// store in cache
 	svc.resultServices.Store(id.Hex(), resultSvc)
 
 	return resultSvc, nil
 }
 
-func (svc *Service) updateTaskStats(id primitive.ObjectID, resultCount int) {
+func (svc *Service) updateTaskStats(id primitive.ObjectID, resultCount int, stats interfaces.ResultInsertStats) {
 	_ = mongo.GetMongoCol(interfaces.ModelColNameTaskStat).UpdateId(id, bson.M{
 		"$inc": bson.M{
-			"result_count": resultCount,
+			"result_count":          resultCount,
+			"result_inserted_count": stats.Inserted,
+			"result_updated_count":  stats.Updated,
+			"result_skipped_count":  stats.Skipped,
 		},
 	})
 }
 
+// getAcceptedResultCount returns the number of result items of the task
+// accepted before, i.e. not skipped
+func (svc *Service) getAcceptedResultCount(id primitive.ObjectID) (n int) {
+	ts, err := svc.modelSvc.GetTaskStatById(id)
+	if err != nil {
+		return 0
+	}
+	return int(ts.ResultCount - ts.ResultSkippedCount)
+}
+
+// onItemsCapped marks the task whose result items reached its max items, and
+// cancels it if configured
+func (svc *Service) onItemsCapped(t *models.Task) {
+	log2.Warnf("[TaskStatsService] task[%s] reached max items %d, dropping further result items", t.Id.Hex(), t.MaxItems)
+	if err := mongo.GetMongoCol(interfaces.ModelColNameTask).UpdateId(t.Id, bson.M{
+		"$set": bson.M{"items_capped": true},
+	}); err != nil {
+		trace.PrintError(err)
+	}
+	task.AddTaskEvent(task.NewTaskEvent(t.Id, constants.TaskEventItemsCapped, constants.TaskEventActorSystem, fmt.Sprintf("reached max items %d", t.MaxItems)))
+
+	if !t.CancelOnMaxItems {
+		return
+	}
+	if err := inject.GetContainer().Invoke(func(schedulerSvc interfaces.TaskSchedulerService) {
+		if err := schedulerSvc.Cancel(t.Id); err != nil {
+			trace.PrintError(err)
+		}
+	}); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+func (svc *Service) onResultStored(id primitive.ObjectID, records []interface{}) (err error) {
+	// plugin registry
+	reg := plugin.GetRegistry()
+	if len(reg.GetAll()) == 0 {
+		return nil
+	}
+
+	// plugin context
+	ctx := &entity.TaskPluginContext{Records: records}
+	t, err := svc.modelSvc.GetTaskById(id)
+	if err != nil {
+		return err
+	}
+	ctx.Task = t
+	ctx.Status = t.Status
+	if s, err := svc.modelSvc.GetSpiderById(t.SpiderId); err == nil {
+		ctx.Spider = s
+	}
+
+	return reg.OnResultStored(ctx)
+}
+
+// resolveLogTtl returns the log ttl of the spider of the task, false if the
+// spider does not override the global log ttl
+func (svc *Service) resolveLogTtl(id string) (ttl time.Duration, ok bool) {
+	tid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, false
+	}
+	t, err := svc.modelSvc.GetTaskById(tid)
+	if err != nil || t.SpiderId.IsZero() {
+		return 0, false
+	}
+	s, err := svc.modelSvc.GetSpiderById(t.SpiderId)
+	if err != nil || s.LogTtl == "" {
+		return 0, false
+	}
+	return log.ParseLogTtl(s.LogTtl)
+}
+
 func (svc *Service) cleanup() {
 	for {
 		// atomic operation
@@ -150,10 +296,16 @@ func NewTaskStatsService(opts ...Option) (svc2 interfaces.TaskStatsService, err
 	}
 
 	// log driver
-	svc.logDriver, err = log.GetLogDriver(log.DriverTypeFile)
+	svc.logDriver, err = log.GetDefaultLogDriver()
 	if err != nil {
 		return nil, err
 	}
+	log.SetTtlResolver(svc.resolveLogTtl)
+
+	// result batches
+	if result.IsBatchEnabled() {
+		svc.batcher = result.NewBatcher(svc.insertData)
+	}
 
 	return svc, nil
 }
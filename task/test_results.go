@@ -0,0 +1,62 @@
+package task
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultMaxTestResults is the max number of result items captured by a test
+// run if "task.test.maxResults" is not set
+const defaultMaxTestResults = 100
+
+// GetMaxTestResults returns the max number of result items captured by a test
+// run, which is configured by "task.test.maxResults"
+func GetMaxTestResults() (n int) {
+	if viper.IsSet("task.test.maxResults") {
+		return viper.GetInt("task.test.maxResults")
+	}
+	return defaultMaxTestResults
+}
+
+// AppendTestResults captures result items of the test run in its TestResults
+// on master instead of storing them, up to GetMaxTestResults. Items beyond are
+// dropped and the task is marked with ItemsCapped.
+func AppendTestResults(id primitive.ObjectID, records []interface{}) (err error) {
+	if len(records) == 0 {
+		return nil
+	}
+	max := GetMaxTestResults()
+	col := mongo.GetMongoCol(interfaces.ModelColNameTask)
+
+	// capped if the captured items and the records exceed max, i.e. the item
+	// at index max-len(records) exists
+	capped := len(records) > max
+	if !capped {
+		n, err := col.Count(bson.M{
+			"_id": id,
+			fmt.Sprintf("test_results.%d", max-len(records)): bson.M{"$exists": true},
+		})
+		if err != nil {
+			return trace.TraceError(err)
+		}
+		capped = n > 0
+	}
+
+	update := bson.M{
+		"$push": bson.M{
+			"test_results": bson.M{"$each": records, "$slice": max},
+		},
+	}
+	if capped {
+		update["$set"] = bson.M{"items_capped": true}
+	}
+	if err := col.UpdateId(id, update); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
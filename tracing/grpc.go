@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataCarrier adapts grpc metadata to propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectMetadata sets the trace context of ctx in the grpc metadata
+func InjectMetadata(ctx context.Context, md metadata.MD) {
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+}
+
+// ExtractMetadata returns ctx with the trace context of the grpc metadata as
+// the parent of spans started with it
+func ExtractMetadata(ctx context.Context, md metadata.MD) (ctx2 context.Context) {
+	if len(md) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+}
@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/utils"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"sync/atomic"
+)
+
+// Tracing exports OpenTelemetry spans of tasks, from the API call through
+// dispatch to the run on the node, with the exporter configured by
+// "tracing.exporter". Without an exporter, the global tracer provider of
+// OpenTelemetry stays no-op, so spans cost nearly nothing and nothing is
+// propagated.
+
+const (
+	ExporterOtlp = "otlp"
+
+	OtlpProtocolGrpc = "grpc"
+	OtlpProtocolHttp = "http"
+
+	tracerName = "github.com/crawlab-team/crawlab-core"
+)
+
+var enabled int32
+
+// Init sets up the global tracer provider and propagator of OpenTelemetry
+// given the config, and returns the function to flush and stop exporting
+// spans on shutdown. It is a no-op if "tracing.exporter" is not set.
+//
+//   - tracing.exporter: "otlp" to export spans with OTLP, empty to disable
+//   - tracing.otlp.protocol: "grpc" (default) or "http"
+//   - tracing.otlp.endpoint: host:port of the collector, defaults of the
+//     OTLP exporter (or OTEL_EXPORTER_OTLP_ENDPOINT) if empty
+//   - tracing.otlp.insecure: whether to connect without TLS
+//   - tracing.sampleRatio: ratio of traces sampled, 1 by default
+//   - tracing.serviceName: service name of spans, "crawlab" by default
+func Init() (shutdown func(ctx context.Context) error, err error) {
+	shutdown = func(ctx context.Context) error { return nil }
+
+	exporterName := viper.GetString("tracing.exporter")
+	if exporterName == "" {
+		return shutdown, nil
+	}
+	if exporterName != ExporterOtlp {
+		log.Warnf("[Tracing] unsupported exporter %s, tracing disabled", exporterName)
+		return shutdown, nil
+	}
+
+	// exporter
+	exporter, err := newOtlpExporter()
+	if err != nil {
+		return shutdown, err
+	}
+
+	// resource
+	serviceName := viper.GetString("tracing.serviceName")
+	if serviceName == "" {
+		serviceName = "crawlab"
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("crawlab.node_type", utils.GetNodeType()),
+	)
+
+	// sampler
+	ratio := 1.0
+	if viper.IsSet("tracing.sampleRatio") {
+		ratio = viper.GetFloat64("tracing.sampleRatio")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	atomic.StoreInt32(&enabled, 1)
+
+	log.Infof("[Tracing] exporting spans with %s", exporterName)
+
+	return tp.Shutdown, nil
+}
+
+func newOtlpExporter() (exporter *otlptrace.Exporter, err error) {
+	endpoint := viper.GetString("tracing.otlp.endpoint")
+	insecure := viper.GetBool("tracing.otlp.insecure")
+	switch viper.GetString("tracing.otlp.protocol") {
+	case OtlpProtocolHttp:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptrace.New(context.Background(), otlptracehttp.NewClient(opts...))
+	default:
+		var opts []otlptracegrpc.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptrace.New(context.Background(), otlptracegrpc.NewClient(opts...))
+	}
+}
+
+// IsEnabled returns whether spans are exported
+func IsEnabled() (ok bool) {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Tracer returns the tracer of crawlab
+func Tracer() (tracer oteltrace.Tracer) {
+	return otel.Tracer(tracerName)
+}
+
+// Inject returns the trace context of ctx as a map to be stored or sent,
+// nil if there is none
+func Inject(ctx context.Context) (carrier map[string]string) {
+	c := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, c)
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+// Extract returns ctx with the trace context of the carrier as the parent of
+// spans started with it
+func Extract(ctx context.Context, carrier map[string]string) (ctx2 context.Context) {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// EndSpan records the error if any and ends the span
+func EndSpan(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
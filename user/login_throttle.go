@@ -0,0 +1,110 @@
+package user
+
+import (
+	"github.com/ReneKroon/ttlcache"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/spf13/viper"
+	"sync"
+	"time"
+)
+
+// loginThrottle tracks failed login attempts per username and per ip,
+// and locks them temporarily once the failures exceed the limit within the window.
+// Counters are stored in a ttl cache so that they expire automatically.
+type loginThrottle struct {
+	maxAttempts  int
+	window       time.Duration
+	lockDuration time.Duration
+	failures     *ttlcache.Cache
+	locks        *ttlcache.Cache
+	mu           sync.Mutex
+}
+
+type loginFailure struct {
+	count int
+}
+
+// Check returns an error if the username or ip is locked
+func (t *loginThrottle) Check(username, ip string) (err error) {
+	if t.maxAttempts <= 0 {
+		return nil
+	}
+	if _, ok := t.locks.Get(t.usernameKey(username)); ok {
+		return errors.ErrorUserLocked
+	}
+	if ip != "" {
+		if _, ok := t.locks.Get(t.ipKey(ip)); ok {
+			return errors.ErrorUserTooManyLoginAttempts
+		}
+	}
+	return nil
+}
+
+// Fail records a failed login attempt
+func (t *loginThrottle) Fail(username, ip string) {
+	if t.maxAttempts <= 0 {
+		return
+	}
+	t.fail(t.usernameKey(username))
+	if ip != "" {
+		t.fail(t.ipKey(ip))
+	}
+}
+
+// Reset clears failed login attempts of the username after a successful
+// login. Those of the ip are kept, so that logging in to an own account does
+// not clear the failures of guessing passwords of others from the same ip.
+func (t *loginThrottle) Reset(username string) {
+	t.failures.Remove(t.usernameKey(username))
+}
+
+func (t *loginThrottle) fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the counter is updated in place so that the window starts from the first failure
+	var f *loginFailure
+	if res, ok := t.failures.Get(key); ok {
+		f, _ = res.(*loginFailure)
+	}
+	if f == nil {
+		f = &loginFailure{}
+		t.failures.SetWithTTL(key, f, t.window)
+	}
+	f.count++
+
+	if f.count >= t.maxAttempts {
+		t.failures.Remove(key)
+		t.locks.SetWithTTL(key, true, t.lockDuration)
+	}
+}
+
+func (t *loginThrottle) usernameKey(username string) string {
+	return "username:" + username
+}
+
+func (t *loginThrottle) ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func newLoginThrottle() (t *loginThrottle) {
+	t = &loginThrottle{
+		maxAttempts:  5,
+		window:       5 * time.Minute,
+		lockDuration: 15 * time.Minute,
+		failures:     ttlcache.NewCache(),
+		locks:        ttlcache.NewCache(),
+	}
+	if viper.IsSet("user.login.maxAttempts") {
+		t.maxAttempts = viper.GetInt("user.login.maxAttempts")
+	}
+	if viper.GetInt("user.login.window") > 0 {
+		t.window = time.Duration(viper.GetInt("user.login.window")) * time.Second
+	}
+	if viper.GetInt("user.login.lockDuration") > 0 {
+		t.lockDuration = time.Duration(viper.GetInt("user.login.lockDuration")) * time.Second
+	}
+	t.failures.SkipTtlExtensionOnHit(true)
+	t.locks.SkipTtlExtensionOnHit(true)
+	return t
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	th := newLoginThrottle()
+	th.maxAttempts = 3
+	th.window = time.Minute
+	th.lockDuration = time.Minute
+
+	for i := 0; i < 2; i++ {
+		require.Nil(t, th.Check("user", "127.0.0.1"))
+		th.Fail("user", "127.0.0.1")
+	}
+
+	// successful login resets counters of the username only
+	th.Reset("user")
+	th.Fail("other", "127.0.0.1")
+	require.Equal(t, errors.ErrorUserTooManyLoginAttempts, th.Check("other", "127.0.0.1"))
+	require.Nil(t, th.Check("user", "127.0.0.2"))
+
+	// lock after max attempts
+	for i := 0; i < 3; i++ {
+		th.Fail("user", "127.0.0.2")
+	}
+	require.Equal(t, errors.ErrorUserLocked, th.Check("user", "127.0.0.3"))
+	require.Nil(t, th.Check("other", "127.0.0.3"))
+}
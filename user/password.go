@@ -0,0 +1,137 @@
+package user
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"golang.org/x/crypto/bcrypt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy defines the complexity rules of user passwords.
+// It can be configured by the setting with key constants.SettingKeyPasswordPolicy.
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length" bson:"min_length"`
+	RequireUppercase bool `json:"require_uppercase" bson:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase" bson:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit" bson:"require_digit"`
+	RequireSpecial   bool `json:"require_special" bson:"require_special"`
+}
+
+func (p PasswordPolicy) Validate(password string) (err error) {
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+
+	var reasons []string
+	if len(password) < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("length must be no less than %d", p.MinLength))
+	}
+	if p.RequireUppercase && !hasUpper {
+		reasons = append(reasons, "must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !hasLower {
+		reasons = append(reasons, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		reasons = append(reasons, "must contain a special character")
+	}
+	if len(reasons) > 0 {
+		return fmt.Errorf("%w (%s)", errors.ErrorUserInvalidPassword, strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 5,
+}
+
+func getPasswordPolicy(modelSvc service.ModelService) (p PasswordPolicy) {
+	p = DefaultPasswordPolicy
+	if _, err := modelSvc.GetSettingValue(constants.SettingKeyPasswordPolicy, &p); err != nil {
+		return DefaultPasswordPolicy
+	}
+	return p
+}
+
+func hashPassword(password string) (hash string, err error) {
+	data, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func checkPasswordHash(hash, password string) (ok bool) {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+const (
+	passwordCharsUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordCharsLower   = "abcdefghijkmnopqrstuvwxyz"
+	passwordCharsDigit   = "23456789"
+	passwordCharsSpecial = "!@#$%^&*"
+)
+
+// generatePassword generates a random password satisfying the given policy
+func generatePassword(p PasswordPolicy) (password string, err error) {
+	length := p.MinLength
+	if length < 12 {
+		length = 12
+	}
+	charsets := []string{passwordCharsUpper, passwordCharsLower, passwordCharsDigit, passwordCharsSpecial}
+	all := strings.Join(charsets, "")
+
+	// one character from each charset, the rest from all
+	var buf []byte
+	for _, chars := range charsets {
+		c, err := randomChar(chars)
+		if err != nil {
+			return "", err
+		}
+		buf = append(buf, c)
+	}
+	for len(buf) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		buf = append(buf, c)
+	}
+
+	// shuffle
+	for i := len(buf) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		buf[i], buf[j.Int64()] = buf[j.Int64()], buf[i]
+	}
+
+	return string(buf), nil
+}
+
+func randomChar(chars string) (c byte, err error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
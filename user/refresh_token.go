@@ -0,0 +1,145 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-db/mongo"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo2 "go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// refreshToken is the server-side record of an issued refresh token.
+// Only the hash of the token is stored. Tokens issued by rotation share
+// the same family id, so that the whole family can be revoked when reuse
+// of a rotated token is detected.
+type refreshToken struct {
+	Id        primitive.ObjectID `bson:"_id"`
+	TokenHash string             `bson:"token_hash"`
+	UserId    primitive.ObjectID `bson:"user_id"`
+	FamilyId  primitive.ObjectID `bson:"family_id"`
+	Used      bool               `bson:"used"`
+	Revoked   bool               `bson:"revoked"`
+	CreateTs  time.Time          `bson:"create_ts"`
+	ExpireTs  time.Time          `bson:"expire_ts"`
+}
+
+func (svc *Service) MakeRefreshToken(u interfaces.User) (refreshTokenStr string, err error) {
+	return svc.makeRefreshToken(u.GetId(), primitive.NewObjectID())
+}
+
+func (svc *Service) RefreshToken(refreshTokenStr string) (tokenStr string, newRefreshTokenStr string, err error) {
+	col := mongo.GetMongoCol(constants.RefreshTokenColName)
+	hash := hashRefreshToken(refreshTokenStr)
+
+	// rotate, i.e. mark the token as used in a single update, so that only one
+	// of concurrent refreshes with the same token gets new tokens
+	var rt refreshToken
+	if err := col.GetCollection().FindOneAndUpdate(col.GetContext(), bson.M{
+		"token_hash": hash,
+		"used":       false,
+		"revoked":    false,
+		"expire_ts":  bson.M{"$gt": time.Now()},
+	}, bson.M{
+		"$set": bson.M{"used": true},
+	}).Decode(&rt); err != nil {
+		if err != mongo2.ErrNoDocuments {
+			return "", "", trace.TraceError(err)
+		}
+		return "", "", svc.checkRefreshTokenReuse(hash)
+	}
+
+	// user
+	u, err := svc.modelSvc.GetUserById(rt.UserId)
+	if err != nil {
+		return "", "", errors.ErrorUserNotExists
+	}
+
+	// new tokens
+	tokenStr, err = svc.makeAccessToken(u)
+	if err != nil {
+		return "", "", err
+	}
+	newRefreshTokenStr, err = svc.makeRefreshToken(u.Id, rt.FamilyId)
+	if err != nil {
+		return "", "", err
+	}
+
+	return tokenStr, newRefreshTokenStr, nil
+}
+
+// checkRefreshTokenReuse returns the error of a refresh token failed to
+// rotate. A used or revoked token presented again indicates the token has been
+// leaked, so the whole token family is revoked.
+func (svc *Service) checkRefreshTokenReuse(hash string) (err error) {
+	var rt refreshToken
+	if err := mongo.GetMongoCol(constants.RefreshTokenColName).Find(bson.M{"token_hash": hash}, nil).One(&rt); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return errors.ErrorUserInvalidRefreshToken
+		}
+		return trace.TraceError(err)
+	}
+	if rt.Used || rt.Revoked {
+		if err := svc.revokeRefreshTokenFamily(rt.FamilyId); err != nil {
+			return err
+		}
+		return errors.ErrorUserRefreshTokenReused
+	}
+	return errors.ErrorUserInvalidRefreshToken
+}
+
+func (svc *Service) RevokeRefreshToken(refreshTokenStr string) (err error) {
+	col := mongo.GetMongoCol(constants.RefreshTokenColName)
+	var rt refreshToken
+	if err := col.Find(bson.M{"token_hash": hashRefreshToken(refreshTokenStr)}, nil).One(&rt); err != nil {
+		if err == mongo2.ErrNoDocuments {
+			return nil
+		}
+		return trace.TraceError(err)
+	}
+	return svc.revokeRefreshTokenFamily(rt.FamilyId)
+}
+
+func (svc *Service) makeRefreshToken(userId, familyId primitive.ObjectID) (refreshTokenStr string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", trace.TraceError(err)
+	}
+	refreshTokenStr = hex.EncodeToString(buf)
+
+	now := time.Now()
+	rt := refreshToken{
+		Id:        primitive.NewObjectID(),
+		TokenHash: hashRefreshToken(refreshTokenStr),
+		UserId:    userId,
+		FamilyId:  familyId,
+		CreateTs:  now,
+		ExpireTs:  now.Add(svc.refreshTokenExpire),
+	}
+	if _, err := mongo.GetMongoCol(constants.RefreshTokenColName).Insert(rt); err != nil {
+		return "", trace.TraceError(err)
+	}
+
+	return refreshTokenStr, nil
+}
+
+func (svc *Service) revokeRefreshTokenFamily(familyId primitive.ObjectID) (err error) {
+	if err := mongo.GetMongoCol(constants.RefreshTokenColName).Update(
+		bson.M{"family_id": familyId},
+		bson.M{"$set": bson.M{"revoked": true}},
+	); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func hashRefreshToken(refreshTokenStr string) (hash string) {
+	sum := sha256.Sum256([]byte(refreshTokenStr))
+	return hex.EncodeToString(sum[:])
+}
@@ -8,10 +8,10 @@ import (
 	"github.com/crawlab-team/crawlab-core/models/models"
 	"github.com/crawlab-team/crawlab-core/models/service"
 	"github.com/crawlab-team/crawlab-core/utils"
-	mongo2 "github.com/crawlab-team/crawlab-db/mongo"
 	"github.com/crawlab-team/go-trace"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/dig"
@@ -20,11 +20,16 @@ import (
 
 type Service struct {
 	// settings variables
-	jwtSecret        string
-	jwtSigningMethod jwt.SigningMethod
+	jwtSecret          string
+	jwtSigningMethod   jwt.SigningMethod
+	accessTokenExpire  time.Duration
+	refreshTokenExpire time.Duration
 
 	// dependencies
 	modelSvc service.ModelService
+
+	// internals
+	throttle *loginThrottle
 }
 
 func (svc *Service) Init() (err error) {
@@ -57,58 +62,58 @@ func (svc *Service) Create(opts *interfaces.UserCreateOptions, args ...interface
 	if opts.Username == "" || opts.Password == "" {
 		return trace.TraceError(errors.ErrorUserMissingRequiredFields)
 	}
-	if len(opts.Password) < 5 {
-		return trace.TraceError(errors.ErrorUserInvalidPassword)
+	if err := svc.ValidatePassword(opts.Password); err != nil {
+		return trace.TraceError(err)
 	}
 
 	// normalize options
 	if opts.Role == "" {
 		opts.Role = constants.RoleNormal
 	}
+	if len(opts.Roles) == 0 {
+		opts.Roles = []string{opts.Role}
+	}
 
 	// check if user exists
 	if u, err := svc.modelSvc.GetUserByUsername(opts.Username, nil); err == nil && u != nil && !u.Id.IsZero() {
 		return trace.TraceError(errors.ErrorUserAlreadyExists)
 	}
 
-	// transaction
-	return mongo2.RunTransaction(func(ctx mongo.SessionContext) error {
-		// add user
-		u := &models.User{
-			Username: opts.Username,
-			Role:     opts.Role,
-			Email:    opts.Email,
-		}
-		if err := delegate.NewModelDelegate(u, actor).Add(); err != nil {
-			return err
-		}
+	// password hash
+	hash, err := hashPassword(opts.Password)
+	if err != nil {
+		return trace.TraceError(err)
+	}
 
-		// add password
-		p := &models.Password{
-			Id:       u.Id,
-			Password: utils.EncryptMd5(opts.Password),
-		}
-		if err := delegate.NewModelDelegate(p, actor).Add(); err != nil {
-			return err
-		}
+	// add user
+	u := &models.User{
+		Username:     opts.Username,
+		PasswordHash: hash,
+		Role:         opts.Role,
+		Roles:        opts.Roles,
+		Email:        opts.Email,
+	}
+	if err := delegate.NewModelDelegate(u, actor).Add(); err != nil {
+		return err
+	}
 
-		return nil
-	})
+	return nil
 }
 
 func (svc *Service) Login(opts *interfaces.UserLoginOptions) (token string, u interfaces.User, err error) {
-	u, err = svc.modelSvc.GetUserByUsername(opts.Username, nil)
-	if err != nil {
+	// throttle
+	if err := svc.throttle.Check(opts.Username, opts.Ip); err != nil {
 		return "", nil, err
 	}
-	p, err := svc.modelSvc.GetPasswordById(u.GetId())
+
+	u, err = svc.login(opts)
 	if err != nil {
+		svc.throttle.Fail(opts.Username, opts.Ip)
 		return "", nil, err
 	}
-	if p.Password != utils.EncryptMd5(opts.Password) {
-		return "", nil, errors.ErrorUserMismatch
-	}
-	token, err = svc.makeToken(u)
+	svc.throttle.Reset(opts.Username)
+
+	token, err = svc.makeAccessToken(u)
 	if err != nil {
 		return "", nil, err
 	}
@@ -119,20 +124,82 @@ func (svc *Service) CheckToken(tokenStr string) (u interfaces.User, err error) {
 	return svc.checkToken(tokenStr)
 }
 
+func (svc *Service) Update(id primitive.ObjectID, opts *interfaces.UserUpdateOptions, args ...interface{}) (err error) {
+	actor := utils.GetUserFromArgs(args...)
+
+	u, err := svc.modelSvc.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if opts.Email != nil {
+		u.Email = *opts.Email
+	}
+	if opts.Role != "" {
+		u.Role = opts.Role
+	}
+	if opts.Roles != nil {
+		u.Roles = opts.Roles
+	}
+	return delegate.NewModelDelegate(u, actor).Save()
+}
+
+func (svc *Service) Delete(id primitive.ObjectID, args ...interface{}) (err error) {
+	actor := utils.GetUserFromArgs(args...)
+
+	u, err := svc.modelSvc.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if u.Username == constants.DefaultAdminUsername {
+		return trace.TraceError(errors.ErrorUserNotAllowed)
+	}
+	if err := delegate.NewModelDelegate(u, actor).Delete(); err != nil {
+		return err
+	}
+
+	// legacy password
+	if p, err := svc.modelSvc.GetPasswordById(id); err == nil {
+		_ = delegate.NewModelDelegate(p, actor).Delete()
+	}
+
+	return nil
+}
+
 func (svc *Service) ChangePassword(id primitive.ObjectID, password string, args ...interface{}) (err error) {
 	actor := utils.GetUserFromArgs(args...)
 
-	p, err := svc.modelSvc.GetPasswordById(id)
+	if err := svc.ValidatePassword(password); err != nil {
+		return trace.TraceError(err)
+	}
+	u, err := svc.modelSvc.GetUserById(id)
 	if err != nil {
 		return err
 	}
-	p.Password = utils.EncryptMd5(password)
-	if err := delegate.NewModelDelegate(p, actor).Save(); err != nil {
+	u.PasswordHash, err = hashPassword(password)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if err := delegate.NewModelDelegate(u, actor).Save(); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (svc *Service) ResetPassword(id primitive.ObjectID, args ...interface{}) (password string, err error) {
+	password, err = generatePassword(getPasswordPolicy(svc.modelSvc))
+	if err != nil {
+		return "", trace.TraceError(err)
+	}
+	if err := svc.ChangePassword(id, password, args...); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func (svc *Service) ValidatePassword(password string) (err error) {
+	return getPasswordPolicy(svc.modelSvc).Validate(password)
+}
+
 func (svc *Service) MakeToken(user interfaces.User) (tokenStr string, err error) {
 	return svc.makeToken(user)
 }
@@ -159,6 +226,17 @@ func (svc *Service) makeToken(user interfaces.User) (tokenStr string, err error)
 	return token.SignedString([]byte(svc.jwtSecret))
 }
 
+// makeAccessToken makes a login token which expires after accessTokenExpire
+func (svc *Service) makeAccessToken(user interfaces.User) (tokenStr string, err error) {
+	token := jwt.NewWithClaims(svc.jwtSigningMethod, jwt.MapClaims{
+		"id":       user.GetId(),
+		"username": user.GetUsername(),
+		"nbf":      time.Now().Unix(),
+		"exp":      time.Now().Add(svc.accessTokenExpire).Unix(),
+	})
+	return token.SignedString([]byte(svc.jwtSecret))
+}
+
 func (svc *Service) checkToken(tokenStr string) (user interfaces.User, err error) {
 	token, err := jwt.Parse(tokenStr, svc.getSecretFunc())
 	if err != nil {
@@ -195,6 +273,46 @@ func (svc *Service) checkToken(tokenStr string) (user interfaces.User, err error
 	return
 }
 
+func (svc *Service) login(opts *interfaces.UserLoginOptions) (u interfaces.User, err error) {
+	u, err = svc.modelSvc.GetUserByUsername(opts.Username, nil)
+	if err != nil {
+		return nil, err
+	}
+	if u.GetPasswordHash() != "" {
+		if !checkPasswordHash(u.GetPasswordHash(), opts.Password) {
+			return nil, errors.ErrorUserMismatch
+		}
+	} else if err := svc.loginLegacy(u, opts.Password); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// loginLegacy verifies the password stored in the legacy password collection
+// and migrates it to the password hash of the user once verified
+func (svc *Service) loginLegacy(u interfaces.User, password string) (err error) {
+	p, err := svc.modelSvc.GetPasswordById(u.GetId())
+	if err != nil {
+		return err
+	}
+	if p.Password != utils.EncryptMd5(password) {
+		return errors.ErrorUserMismatch
+	}
+	u2, err := svc.modelSvc.GetUserById(u.GetId())
+	if err != nil {
+		return err
+	}
+	u2.PasswordHash, err = hashPassword(password)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if err := delegate.NewModelDelegate(u2).Save(); err != nil {
+		return err
+	}
+	_ = delegate.NewModelDelegate(p).Delete()
+	return nil
+}
+
 func (svc *Service) getSecretFunc() jwt.Keyfunc {
 	return func(token *jwt.Token) (interface{}, error) {
 		return []byte(svc.jwtSecret), nil
@@ -204,8 +322,17 @@ func (svc *Service) getSecretFunc() jwt.Keyfunc {
 func NewUserService(opts ...Option) (svc2 interfaces.UserService, err error) {
 	// service
 	svc := &Service{
-		jwtSecret:        "crawlab",
-		jwtSigningMethod: jwt.SigningMethodHS256,
+		jwtSecret:          "crawlab",
+		jwtSigningMethod:   jwt.SigningMethodHS256,
+		accessTokenExpire:  2 * time.Hour,
+		refreshTokenExpire: 7 * 24 * time.Hour,
+		throttle:           newLoginThrottle(),
+	}
+	if viper.GetInt("user.token.accessExpire") > 0 {
+		svc.accessTokenExpire = time.Duration(viper.GetInt("user.token.accessExpire")) * time.Second
+	}
+	if viper.GetInt("user.token.refreshExpire") > 0 {
+		svc.refreshTokenExpire = time.Duration(viper.GetInt("user.token.refreshExpire")) * time.Second
 	}
 
 	// dependency injection
@@ -3,8 +3,8 @@ package test
 import (
 	"github.com/crawlab-team/crawlab-core/constants"
 	"github.com/crawlab-team/crawlab-core/interfaces"
-	"github.com/crawlab-team/crawlab-core/utils"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"testing"
 )
 
@@ -12,10 +12,10 @@ func TestUserService_Init(t *testing.T) {
 	var err error
 	T.Setup(t)
 
-	u, err := T.modelSvc.GetUserByUsernameWithPassword(constants.DefaultAdminUsername, nil)
+	u, err := T.modelSvc.GetUserByUsername(constants.DefaultAdminUsername, nil)
 	require.Nil(t, err)
 	require.Equal(t, constants.DefaultAdminUsername, u.Username)
-	require.Equal(t, utils.EncryptMd5(constants.DefaultAdminPassword), u.Password)
+	require.Nil(t, bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(constants.DefaultAdminPassword)))
 }
 
 func TestUserService_Create_Login_CheckToken(t *testing.T) {
@@ -28,10 +28,11 @@ func TestUserService_Create_Login_CheckToken(t *testing.T) {
 	})
 	require.Nil(t, err)
 
-	u, err := T.modelSvc.GetUserByUsernameWithPassword(T.TestUsername, nil)
+	u, err := T.modelSvc.GetUserByUsername(T.TestUsername, nil)
 	require.Nil(t, err)
 	require.Equal(t, T.TestUsername, u.Username)
-	require.Equal(t, utils.EncryptMd5(T.TestPassword), u.Password)
+	require.Equal(t, []string{constants.RoleNormal}, u.Roles)
+	require.Nil(t, bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(T.TestPassword)))
 
 	token, u2, err := T.userSvc.Login(&interfaces.UserLoginOptions{
 		Username: T.TestUsername,
@@ -44,18 +45,68 @@ func TestUserService_Create_Login_CheckToken(t *testing.T) {
 	u3, err := T.userSvc.CheckToken(token)
 	require.Nil(t, err)
 	require.Equal(t, u.Username, u3.GetUsername())
+
+	_, _, err = T.userSvc.Login(&interfaces.UserLoginOptions{
+		Username: T.TestUsername,
+		Password: T.TestNewPassword,
+	})
+	require.NotNil(t, err)
 }
 
 func TestUserService_ChangePassword(t *testing.T) {
 	var err error
 	T.Setup(t)
 
-	u, err := T.modelSvc.GetUserByUsernameWithPassword(constants.DefaultAdminUsername, nil)
+	u, err := T.modelSvc.GetUserByUsername(constants.DefaultAdminUsername, nil)
 	require.Nil(t, err)
 	err = T.userSvc.ChangePassword(u.Id, T.TestNewPassword)
 	require.Nil(t, err)
 
-	u2, err := T.modelSvc.GetUserByUsernameWithPassword(constants.DefaultAdminUsername, nil)
+	u2, err := T.modelSvc.GetUserByUsername(constants.DefaultAdminUsername, nil)
+	require.Nil(t, err)
+	require.Nil(t, bcrypt.CompareHashAndPassword([]byte(u2.PasswordHash), []byte(T.TestNewPassword)))
+
+	err = T.userSvc.ChangePassword(u.Id, "abc")
+	require.NotNil(t, err)
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	var err error
+	T.Setup(t)
+
+	u, err := T.modelSvc.GetUserByUsername(constants.DefaultAdminUsername, nil)
+	require.Nil(t, err)
+	password, err := T.userSvc.ResetPassword(u.Id)
+	require.Nil(t, err)
+	require.Nil(t, T.userSvc.ValidatePassword(password))
+
+	_, _, err = T.userSvc.Login(&interfaces.UserLoginOptions{
+		Username: constants.DefaultAdminUsername,
+		Password: password,
+	})
+	require.Nil(t, err)
+}
+
+func TestUserService_RefreshToken(t *testing.T) {
+	var err error
+	T.Setup(t)
+
+	u, err := T.modelSvc.GetUserByUsername(constants.DefaultAdminUsername, nil)
 	require.Nil(t, err)
-	require.Equal(t, utils.EncryptMd5(T.TestNewPassword), u2.Password)
+	refreshToken, err := T.userSvc.MakeRefreshToken(u)
+	require.Nil(t, err)
+
+	// rotate
+	token, refreshToken2, err := T.userSvc.RefreshToken(refreshToken)
+	require.Nil(t, err)
+	require.Greater(t, len(token), 10)
+	require.NotEqual(t, refreshToken, refreshToken2)
+	_, err = T.userSvc.CheckToken(token)
+	require.Nil(t, err)
+
+	// reuse of a rotated token revokes the family
+	_, _, err = T.userSvc.RefreshToken(refreshToken)
+	require.NotNil(t, err)
+	_, _, err = T.userSvc.RefreshToken(refreshToken2)
+	require.NotNil(t, err)
 }
@@ -1,6 +1,9 @@
 package utils
 
-import "github.com/crawlab-team/crawlab-core/interfaces"
+import (
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 func GetUserFromArgs(args ...interface{}) (u interfaces.User) {
 	for _, arg := range args {
@@ -15,3 +18,28 @@ func GetUserFromArgs(args ...interface{}) (u interfaces.User) {
 	}
 	return nil
 }
+
+func GetTaskCancelOptionsFromArgs(args ...interface{}) (opts *interfaces.TaskCancelOptions) {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *interfaces.TaskCancelOptions:
+			var ok bool
+			opts, ok = arg.(*interfaces.TaskCancelOptions)
+			if ok {
+				return opts
+			}
+		}
+	}
+	return &interfaces.TaskCancelOptions{}
+}
+
+// GetSessionContextFromArgs returns the session context of the transaction
+// in args, nil if not in a transaction
+func GetSessionContextFromArgs(args ...interface{}) (sc mongo.SessionContext) {
+	for _, arg := range args {
+		if sc, ok := arg.(mongo.SessionContext); ok {
+			return sc
+		}
+	}
+	return nil
+}
@@ -70,6 +70,8 @@ func (b *ColNameBinder) Bind() (res interface{}, err error) {
 		return interfaces.ModelColNameEnvironment, nil
 	case interfaces.ModelIdDependencySetting:
 		return interfaces.ModelColNameDependencySetting, nil
+	case interfaces.ModelIdTaskEvent:
+		return interfaces.ModelColNameTaskEvent, nil
 
 	// invalid
 	default:
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/spf13/viper"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// default executables to detect, overridden by "node.capabilities.executables"
+var defaultCapabilityExecutables = []string{
+	"python",
+	"python3",
+	"node",
+	"npm",
+	"java",
+	"go",
+	"php",
+	"ruby",
+	"scrapy",
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"firefox",
+	"docker",
+}
+
+// GetNodeCapabilities detects capabilities of the current node, i.e. os/arch and
+// versions of available executables
+func GetNodeCapabilities() (capabilities *interfaces.NodeCapabilities) {
+	capabilities = &interfaces.NodeCapabilities{
+		Os:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Executables: map[string]string{},
+		Probed:      GetCapabilityExecutableNames(),
+	}
+	for _, name := range capabilities.Probed {
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		capabilities.Executables[name] = getExecutableVersion(name)
+	}
+	return capabilities
+}
+
+// GetCapabilityExecutableNames returns names of executables to detect in the
+// capability manifest of the current node
+func GetCapabilityExecutableNames() (names []string) {
+	names = viper.GetStringSlice("node.capabilities.executables")
+	if len(names) == 0 {
+		names = defaultCapabilityExecutables
+	}
+	return names
+}
+
+// getExecutableVersion returns the first line of "<name> --version", empty if unavailable
+func getExecutableVersion(name string) (version string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	line, _ := bufio.NewReader(bytes.NewReader(out)).ReadString('\n')
+	return strings.TrimSpace(line)
+}
@@ -1,5 +1,75 @@
 package utils
 
+import (
+	"github.com/spf13/viper"
+	"regexp"
+	"strings"
+)
+
+var (
+	// reference of docker images by the grammar of the docker distribution,
+	// i.e. [domain[:port]/]name[/name...][:tag][@digest], with lowercase names
+	dockerImageReferenceRegexp = regexp.MustCompile(`^` +
+		// domain
+		`(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?/)?` +
+		// name
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		// tag
+		`(?::[\w][\w.-]{0,127})?` +
+		// digest
+		`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?` +
+		`$`)
+
+	// names of docker networks, or of containers of which to join the network
+	dockerNetworkRegexp = regexp.MustCompile(`^(?:container:)?[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
 func IsDocker() (ok bool) {
 	return EnvIsTrue("docker", false)
 }
+
+// IsValidDockerImage returns whether the image is a valid reference of docker
+// images, which cannot be taken as an option of the docker cli
+func IsValidDockerImage(image string) (ok bool) {
+	// name of 255 characters at most, i.e. before the tag or digest following
+	// the last path component
+	name := image
+	start := strings.LastIndex(image, "/") + 1
+	if i := strings.IndexAny(image[start:], ":@"); i >= 0 {
+		name = image[:start+i]
+	}
+	return len(name) <= 255 && dockerImageReferenceRegexp.MatchString(image)
+}
+
+// IsValidDockerNetwork returns whether the network is a valid name of docker
+// networks, e.g. host or container:<name>, which cannot be taken as an option
+// of the docker cli. Privileged networks are valid, see IsAllowedDockerNetwork.
+func IsValidDockerNetwork(network string) (ok bool) {
+	return dockerNetworkRegexp.MatchString(network)
+}
+
+// IsPrivilegedDockerNetwork returns whether the network shares the network
+// stack of the host or of another container
+func IsPrivilegedDockerNetwork(network string) (ok bool) {
+	return network == "host" || strings.HasPrefix(network, "container:")
+}
+
+// IsAllowedDockerNetwork returns whether containers of tasks may join the
+// network, i.e. it is valid and, if privileged, listed in
+// "task.container.allowedNetworks" (e.g. host or container:<name>), so that
+// spiders cannot reach services of the host or of other containers unless
+// allowed by admins of the node
+func IsAllowedDockerNetwork(network string) (ok bool) {
+	if !IsValidDockerNetwork(network) {
+		return false
+	}
+	if !IsPrivilegedDockerNetwork(network) {
+		return true
+	}
+	for _, n := range viper.GetStringSlice("task.container.allowedNetworks") {
+		if n == network {
+			return true
+		}
+	}
+	return false
+}
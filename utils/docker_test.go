@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestIsValidDockerImage(t *testing.T) {
+	for _, image := range []string{
+		"python",
+		"python:3.10",
+		"crawlabteam/crawlab:latest",
+		"registry.example.com:5000/team/spider:v1.2",
+		"ubuntu@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	} {
+		require.True(t, IsValidDockerImage(image), image)
+	}
+	for _, image := range []string{
+		"",
+		"-v",
+		"--privileged",
+		"Python",
+		"python 3",
+		"python:-x",
+		"foo//bar",
+		strings.Repeat("a", 256),
+	} {
+		require.False(t, IsValidDockerImage(image), image)
+	}
+}
+
+func TestIsValidDockerNetwork(t *testing.T) {
+	for _, network := range []string{"host", "bridge", "my_net.1", "container:spider"} {
+		require.True(t, IsValidDockerNetwork(network), network)
+	}
+	for _, network := range []string{"", "-x", "--privileged", "my net", "container:-x"} {
+		require.False(t, IsValidDockerNetwork(network), network)
+	}
+}
+
+func TestIsAllowedDockerNetwork(t *testing.T) {
+	require.True(t, IsAllowedDockerNetwork("bridge"))
+	require.False(t, IsAllowedDockerNetwork("-x"))
+	require.False(t, IsAllowedDockerNetwork("host"))
+	require.False(t, IsAllowedDockerNetwork("container:spider"))
+
+	viper.Set("task.container.allowedNetworks", []string{"host"})
+	defer viper.Set("task.container.allowedNetworks", nil)
+	require.True(t, IsAllowedDockerNetwork("host"))
+	require.False(t, IsAllowedDockerNetwork("container:spider"))
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"strings"
+)
+
+// GetEncoding returns the text encoding of the name, e.g. gbk, latin1 or
+// shift_jis, matched case-insensitively against labels of the WHATWG Encoding
+// Standard. It is UTF-8 if the name is empty.
+func GetEncoding(name string) (enc encoding.Encoding, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+	return htmlindex.Get(name)
+}
+
+// NewUtf8Reader returns a reader transcoding text of the encoding read from r
+// to UTF-8. Invalid bytes are replaced with U+FFFD.
+func NewUtf8Reader(r io.Reader, enc encoding.Encoding) (r2 io.Reader) {
+	return transform.NewReader(r, enc.NewDecoder())
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGetEncoding(t *testing.T) {
+	for _, name := range []string{"", "utf-8", "GBK", "gb18030", "latin1", "shift_jis", " big5 "} {
+		_, err := GetEncoding(name)
+		require.Nil(t, err, name)
+	}
+	_, err := GetEncoding("unknown")
+	require.NotNil(t, err)
+}
+
+func TestNewUtf8Reader(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"", "hello\n", "hello\n"},
+		{"", "a\xffb\n", "a�b\n"},
+		{"gbk", "\xc4\xe3\xba\xc3\n", "你好\n"},
+		{"latin1", "caf\xe9\n", "café\n"},
+	}
+	for _, c := range cases {
+		enc, err := GetEncoding(c.name)
+		require.Nil(t, err)
+		data, err := io.ReadAll(NewUtf8Reader(strings.NewReader(c.input), enc))
+		require.Nil(t, err)
+		require.Equal(t, c.expected, string(data))
+	}
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"archive/zip"
 	"bufio"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"github.com/apex/log"
 	"github.com/crawlab-team/crawlab-core/constants"
@@ -70,6 +72,37 @@ func Exists(path string) bool {
 	return true
 }
 
+// GetDirSize returns the total size of regular files in the directory (bytes)
+func GetDirSize(path string) (size int64, err error) {
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// GetFileHash returns the md5 hash of the file content
+func GetFileHash(path string) (hash string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func IsDir(path string) bool {
 	s, err := os.Stat(path)
 	if err != nil {
@@ -1,11 +1,39 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/errors"
 	"github.com/crawlab-team/crawlab-core/interfaces"
 	vcs "github.com/crawlab-team/crawlab-vcs"
+	"github.com/crawlab-team/go-trace"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"regexp"
+	"strings"
 )
 
+// characters and sequences not allowed in git refs, see git-check-ref-format
+var gitRefInvalidRegexp = regexp.MustCompile(`[\x00-\x20\x7f~^:?*\[\\]|\.\.|@\{|//|/\.|\.lock(?:/|$)`)
+
+// IsValidGitRef returns whether the ref is a valid name of git branches or
+// tags, or a commit hash, which cannot be taken as an option of git
+func IsValidGitRef(ref string) (ok bool) {
+	if ref == "" || ref == "@" || len(ref) > 255 {
+		return false
+	}
+	if strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, ".") {
+		return false
+	}
+	if strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") {
+		return false
+	}
+	return !gitRefInvalidRegexp.MatchString(ref)
+}
+
 func InitGitClientAuth(g interfaces.Git, gitClient *vcs.GitClient) {
 	// set auth
 	switch g.GetAuthType() {
@@ -19,3 +47,57 @@ func InitGitClientAuth(g interfaces.Git, gitClient *vcs.GitClient) {
 		gitClient.SetPrivateKey(g.GetPassword())
 	}
 }
+
+// GetGitAuth returns the auth method of the git for operations not covered by
+// vcs.GitClient, e.g. cloning. It is nil if no auth is needed.
+func GetGitAuth(g interfaces.Git) (auth transport.AuthMethod, err error) {
+	switch g.GetAuthType() {
+	case constants.GitAuthTypeHttp:
+		if g.GetUsername() == "" && g.GetPassword() == "" {
+			return nil, nil
+		}
+		return &http.BasicAuth{
+			Username: g.GetUsername(),
+			Password: g.GetPassword(),
+		}, nil
+	case constants.GitAuthTypeSsh:
+		if g.GetPassword() == "" {
+			return nil, nil
+		}
+		username := g.GetUsername()
+		if username == "" {
+			username = "git"
+		}
+		keys, err := gitssh.NewPublicKeys(username, []byte(g.GetPassword()), "")
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		keys.HostKeyCallback, err = getGitHostKeyCallback()
+		if err != nil {
+			return nil, err
+		}
+		return keys, nil
+	default:
+		return nil, nil
+	}
+}
+
+// getGitHostKeyCallback returns the callback verifying host keys of git
+// remotes over ssh against known hosts, i.e. the file "git.ssh.knownHosts" if
+// set, or else $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts. Verification can be
+// turned off by "git.ssh.insecureIgnoreHostKey", which exposes credentials to
+// man-in-the-middle attacks.
+func getGitHostKeyCallback() (cb ssh.HostKeyCallback, err error) {
+	if viper.GetBool("git.ssh.insecureIgnoreHostKey") {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	var files []string
+	if f := viper.GetString("git.ssh.knownHosts"); f != "" {
+		files = append(files, f)
+	}
+	cb, err = gitssh.NewKnownHostsCallback(files...)
+	if err != nil {
+		return nil, trace.TraceError(fmt.Errorf("%w: %v", errors.ErrorGitNoKnownHosts, err))
+	}
+	return cb, nil
+}
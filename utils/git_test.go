@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsValidGitRef(t *testing.T) {
+	for _, ref := range []string{
+		"main",
+		"feature/login",
+		"v1.2.0",
+		"3f2a1b9",
+	} {
+		require.True(t, IsValidGitRef(ref), ref)
+	}
+	for _, ref := range []string{
+		"",
+		"@",
+		"--upload-pack=touch",
+		"/main",
+		"main/",
+		"feature//login",
+		"main..dev",
+		"main@{1}",
+		"main~1",
+		"a b",
+		"refs/heads/.hidden",
+		"main.lock",
+	} {
+		require.False(t, IsValidGitRef(ref), ref)
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
 
 func JsonToBytes(d interface{}) (bytes []byte, err error) {
 	switch d.(type) {
@@ -10,3 +15,18 @@ func JsonToBytes(d interface{}) (bytes []byte, err error) {
 		return json.Marshal(d)
 	}
 }
+
+// UnmarshalJsonUseNumber is like json.Unmarshal, except that numbers in
+// interface{} values are decoded as json.Number instead of float64, so that
+// no precision is lost
+func UnmarshalJsonUseNumber(data []byte, v interface{}) (err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Webhooks are configured by editors of spiders, so that they may not reach
+// addresses internal to master, i.e. loopback, private, link-local, multicast
+// and unspecified addresses, unless allowed by "webhook.allowPrivateAddresses"
+// (default false), e.g. for receivers in the same private network. Addresses
+// are checked on dial once resolved, so that redirects or dns records changed
+// after the webhook is saved do not bypass the check. Webhooks are not called
+// through proxies of the environment, whose addresses are usually private.
+
+// IsAddressAllowed returns whether webhooks may be called at the ip
+func IsAddressAllowed(ip net.IP) (ok bool) {
+	if viper.GetBool("webhook.allowPrivateAddresses") {
+		return true
+	}
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// IsHostAllowed returns whether webhooks may be called at the host of their
+// urls, i.e. false for addresses and localhost not allowed. Other hosts are
+// checked once resolved on call.
+func IsHostAllowed(host string) (ok bool) {
+	if strings.EqualFold(host, "localhost") {
+		return IsAddressAllowed(net.IPv4(127, 0, 0, 1))
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsAddressAllowed(ip)
+	}
+	return true
+}
+
+// controlDial rejects connections to addresses not allowed
+func controlDial(network, address string, c syscall.RawConn) (err error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsAddressAllowed(ip) {
+		return fmt.Errorf("%w: %s", errors.ErrorHttpAddressNotAllowed, host)
+	}
+	return nil
+}
+
+func newHttpClient() (client *http.Client) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   controlDial,
+	}
+	return &http.Client{
+		Timeout: getTimeout(),
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:        10,
+		},
+	}
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/event"
+	"github.com/crawlab-team/crawlab-core/interfaces"
+	"github.com/crawlab-team/crawlab-core/job"
+	"github.com/crawlab-team/crawlab-core/models/models"
+	"github.com/crawlab-team/crawlab-core/models/service"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+)
+
+// Service calls webhooks of spiders on master when their tasks end, i.e. on
+// events of the final task status. Payloads are signed with the secret of the
+// webhook. Deliveries are background jobs (constants.JobTypeSpiderWebhook), so
+// that they survive restarts of master and failed calls are retried by the job
+// service with exponential backoff (see "job.maxAttempts").
+type Service struct {
+	modelSvc service.ModelService
+	jobSvc   interfaces.JobService
+	client   *http.Client
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// deliveryJob is the payload of a delivery job
+type deliveryJob struct {
+	TaskId   primitive.ObjectID `json:"task_id"`
+	Delivery string             `json:"delivery"` // id of the delivery, the same across retries
+}
+
+func (svc *Service) Start() {
+	ch := make(chan interfaces.EventData)
+	key := "webhook:task-ended"
+	eventSvc := event.NewEventService()
+	eventSvc.Register(key, fmt.Sprintf("^model:%s:add$", interfaces.ModelColNameTaskEvent), "^$", &ch)
+	defer eventSvc.Unregister(key)
+
+	for {
+		select {
+		case <-svc.stopCh:
+			return
+		case e := <-ch:
+			ev, ok := e.GetData().(*models.TaskEvent)
+			if !ok {
+				continue
+			}
+			switch ev.Type {
+			case constants.TaskEventFinished,
+				constants.TaskEventError,
+				constants.TaskEventCancelled:
+				go svc.enqueueDelivery(ev.TaskId)
+			}
+		}
+	}
+}
+
+func (svc *Service) Stop() {
+	svc.stopOnce.Do(func() {
+		close(svc.stopCh)
+	})
+}
+
+// getWebhook returns the task and the webhook of its spider, and ok if the
+// webhook is to be called for the task
+func (svc *Service) getWebhook(taskId primitive.ObjectID) (t *models.Task, s *models.Spider, ok bool) {
+	t, err := svc.modelSvc.GetTaskById(taskId)
+	if err != nil {
+		trace.PrintError(err)
+		return nil, nil, false
+	}
+	if t.Test {
+		return nil, nil, false
+	}
+	s, err = svc.modelSvc.GetSpiderById(t.SpiderId)
+	if err != nil {
+		return nil, nil, false
+	}
+	wh := s.Webhook
+	if wh == nil || wh.Url == "" || !MatchStatus(wh.On, t.Status) {
+		return nil, nil, false
+	}
+	return t, s, true
+}
+
+// enqueueDelivery enqueues the delivery job of the webhook of the spider of
+// the ended task, if any
+func (svc *Service) enqueueDelivery(taskId primitive.ObjectID) {
+	if _, _, ok := svc.getWebhook(taskId); !ok {
+		return
+	}
+	if _, err := svc.jobSvc.Enqueue(constants.JobTypeSpiderWebhook, deliveryJob{
+		TaskId:   taskId,
+		Delivery: primitive.NewObjectID().Hex(),
+	}); err != nil {
+		trace.PrintError(err)
+	}
+}
+
+// handleDeliveryJob calls the webhook of the delivery job once, an error
+// makes the job retried
+func (svc *Service) handleDeliveryJob(data []byte) (err error) {
+	var j deliveryJob
+	if err := json.Unmarshal(data, &j); err != nil {
+		return trace.TraceError(err)
+	}
+	t, s, ok := svc.getWebhook(j.TaskId)
+	if !ok {
+		// webhook removed or changed since
+		return nil
+	}
+	if err := svc.Send(s.Webhook, j.Delivery, svc.getPayload(t, s)); err != nil {
+		log.Warnf("[Webhook] task[%s] webhook of spider[%s] failed: %v", t.Id.Hex(), s.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (svc *Service) getPayload(t *models.Task, s *models.Spider) (payload *entity.SpiderWebhookPayload) {
+	payload = &entity.SpiderWebhookPayload{
+		Event:      constants.WebhookEventTaskEnded,
+		TaskId:     t.Id,
+		SpiderId:   s.Id,
+		SpiderName: s.Name,
+		NodeId:     t.NodeId,
+		Status:     t.Status,
+		Error:      t.Error,
+		Url:        getTaskUrl(t.Id),
+	}
+	if ts, err := svc.modelSvc.GetTaskStatById(t.Id); err == nil {
+		payload.ResultCount = ts.ResultCount
+		payload.ErrorLogCount = ts.ErrorLogCount
+		payload.StartTs = ts.StartTs
+		payload.EndTs = ts.EndTs
+		payload.RuntimeDuration = ts.RuntimeDuration
+		payload.TotalDuration = ts.TotalDuration
+	}
+	return payload
+}
+
+// Send posts the payload to the webhook as the delivery. The delivery id is
+// the same across retries, so that receivers may dedup.
+func (svc *Service) Send(wh *entity.SpiderWebhook, delivery string, payload *entity.SpiderWebhookPayload) (err error) {
+	payload.Ts = time.Now()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	return svc.post(wh, payload.Event, delivery, body)
+}
+
+func (svc *Service) post(wh *entity.SpiderWebhook, eventName, delivery string, body []byte) (err error) {
+	req, err := http.NewRequest(http.MethodPost, wh.Url, bytes.NewReader(body))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.WebhookHeaderEvent, eventName)
+	req.Header.Set(constants.WebhookHeaderDelivery, delivery)
+	if wh.Secret != "" {
+		req.Header.Set(constants.WebhookHeaderSignature, Sign(wh.Secret, body))
+	}
+	res, err := svc.client.Do(req)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return trace.TraceError(fmt.Errorf("webhook responded %s", res.Status))
+	}
+	return nil
+}
+
+// Sign returns the signature of the body with the secret, i.e.
+// "sha256=<hex of HMAC-SHA256>"
+func Sign(secret string, body []byte) (signature string) {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// MatchStatus returns whether a task ended with the status triggers a
+// webhook of the constants.WebhookOn* filter
+func MatchStatus(on, status string) (ok bool) {
+	switch on {
+	case constants.WebhookOnSuccess:
+		return status == constants.TaskStatusFinished
+	case constants.WebhookOnFailure:
+		return status == constants.TaskStatusError || status == constants.TaskStatusAbnormal
+	default:
+		return true
+	}
+}
+
+// getTaskUrl returns the link to the task, prefixed by "base.url" if set
+func getTaskUrl(id primitive.ObjectID) (url string) {
+	return strings.TrimSuffix(viper.GetString("base.url"), "/") + "/tasks/" + id.Hex()
+}
+
+func getTimeout() (timeout time.Duration) {
+	if seconds := viper.GetInt("webhook.timeout"); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+func NewService() (svc *Service, err error) {
+	svc = &Service{
+		jobSvc: job.GetService(),
+		client: newHttpClient(),
+		stopCh: make(chan struct{}),
+	}
+	svc.modelSvc, err = service.GetService()
+	if err != nil {
+		return nil, err
+	}
+	svc.jobSvc.RegisterHandler(constants.JobTypeSpiderWebhook, svc.handleDeliveryJob)
+	return svc, nil
+}
+
+var _service *Service
+
+func GetService() (svc *Service, err error) {
+	if _service != nil {
+		return _service, nil
+	}
+	_service, err = NewService()
+	if err != nil {
+		return nil, err
+	}
+	return _service, nil
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"github.com/crawlab-team/crawlab-core/constants"
+	"github.com/crawlab-team/crawlab-core/entity"
+	"github.com/crawlab-team/crawlab-core/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	require.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestMatchStatus(t *testing.T) {
+	require.True(t, MatchStatus("", constants.TaskStatusCancelled))
+	require.True(t, MatchStatus(constants.WebhookOnAll, constants.TaskStatusError))
+	require.True(t, MatchStatus(constants.WebhookOnSuccess, constants.TaskStatusFinished))
+	require.False(t, MatchStatus(constants.WebhookOnSuccess, constants.TaskStatusError))
+	require.True(t, MatchStatus(constants.WebhookOnFailure, constants.TaskStatusAbnormal))
+	require.False(t, MatchStatus(constants.WebhookOnFailure, constants.TaskStatusCancelled))
+}
+
+func TestIsAddressAllowed(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		require.True(t, IsAddressAllowed(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"127.0.0.1", "::1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::ffff:127.0.0.1"} {
+		require.False(t, IsAddressAllowed(net.ParseIP(ip)), ip)
+	}
+
+	// allowed by config
+	viper.Set("webhook.allowPrivateAddresses", true)
+	defer viper.Set("webhook.allowPrivateAddresses", nil)
+	require.True(t, IsAddressAllowed(net.ParseIP("127.0.0.1")))
+}
+
+func TestIsHostAllowed(t *testing.T) {
+	require.True(t, IsHostAllowed("example.com"))
+	require.True(t, IsHostAllowed("8.8.8.8"))
+	require.False(t, IsHostAllowed("LOCALHOST"))
+	require.False(t, IsHostAllowed("169.254.169.254"))
+	require.False(t, IsHostAllowed("::1"))
+}
+
+func TestService_post(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer svr.Close()
+	svc := &Service{client: newHttpClient()}
+	wh := &entity.SpiderWebhook{Url: svr.URL}
+
+	// loopback not allowed
+	err := svc.post(wh, constants.WebhookEventTaskEnded, "1", []byte("{}"))
+	require.ErrorIs(t, err, errors.ErrorHttpAddressNotAllowed)
+
+	// allowed by config
+	viper.Set("webhook.allowPrivateAddresses", true)
+	defer viper.Set("webhook.allowPrivateAddresses", nil)
+	require.Nil(t, svc.post(wh, constants.WebhookEventTaskEnded, "1", []byte("{}")))
+}